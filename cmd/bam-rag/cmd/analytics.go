@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/analytics"
+	"github.com/spf13/cobra"
+)
+
+var (
+	analyticsSince time.Duration
+	analyticsLimit int
+)
+
+var analyticsCmd = &cobra.Command{
+	Use:   "analytics",
+	Short: "Show what MCP clients search for",
+	Long: `Summarize the searches logged by 'bam-rag serve' when analytics.enabled
+is set in config: the most searched queries, queries that returned nothing,
+queries none of whose results were read, and the most read documents.
+
+Examples:
+  # What did users ask this week?
+  bam-rag analytics
+
+  # Where did retrieval fail today?
+  bam-rag analytics --since 24h --limit 50`,
+	RunE: runAnalytics,
+}
+
+func init() {
+	rootCmd.AddCommand(analyticsCmd)
+
+	analyticsCmd.Flags().DurationVar(&analyticsSince, "since", 7*24*time.Hour, "Only include searches this recent (0 for all)")
+	analyticsCmd.Flags().IntVar(&analyticsLimit, "limit", 10, "Maximum number of entries per list")
+}
+
+func runAnalytics(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if analyticsSince > 0 {
+		since = time.Now().Add(-analyticsSince)
+	}
+	summary, err := analytics.Summarize(ctx, esClient, cfg.Analytics.Index, since, analyticsLimit)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if summary.Searches == 0 {
+		if !cfg.Analytics.Enabled {
+			fmt.Println("No searches logged (analytics.enabled is off)")
+		} else {
+			fmt.Println("No searches logged")
+		}
+		return nil
+	}
+
+	fmt.Printf("Searches: %d, documents read: %d", summary.Searches, summary.Fetches)
+	if summary.MedianRank > 0 {
+		fmt.Printf(", median rank read: %.1f", summary.MedianRank)
+	}
+	fmt.Println()
+
+	fmt.Println("\nTop queries (searches / reads):")
+	for _, q := range summary.Queries {
+		fmt.Printf("  %5d %5d  %s\n", q.Searches, q.Fetches, q.Query)
+	}
+	printCounts("Queries without results", summary.NoResults)
+	printCounts("Queries whose results were not read", summary.Unfetched)
+	printCounts("Most read documents", summary.Documents)
+	return nil
+}
+
+// printCounts prints a titled list of counts, if it has any entries.
+func printCounts(title string, counts []analytics.Count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, c := range counts {
+		fmt.Printf("  %5d  %s\n", c.Count, c.Key)
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/audit"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/sourcematch"
+	"github.com/spf13/cobra"
+)
+
+// auditLog collects the audited events of the running command; nil when
+// auditing is disabled.
+var auditLog *audit.Log
+
+var (
+	auditSource string
+	auditActor  string
+	auditID     string
+	auditTypes  []string
+	auditSince  time.Duration
+	auditLimit  int
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of pipeline events",
+	Long: `Show recorded scrapes, ingestions, deletions, backfills, warnings, and
+errors, newest first. Events are recorded when audit.enabled is set in
+config, in an Elasticsearch index or in S3 (audit.store).
+
+Each record names the command and who ran it: $BAMRAG_ACTOR if set, such as
+a CI job or the service account of a deployment, or else the OS user.
+Deletions and embedding backfills also list the IDs of the documents they
+changed.
+
+Examples:
+  # When was this source last refreshed?
+  bam-rag audit --source go-docs --type ingest_complete --limit 1
+
+  # What failed in the last day?
+  bam-rag audit --type error,command_failed --since 24h
+
+  # Who removed or changed this document?
+  bam-rag audit --id 3f2a9c --since 0`,
+	RunE: runAudit,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVar(&auditSource, "source", "", "Only show events of this source")
+	auditCmd.Flags().StringVar(&auditActor, "actor", "", "Only show events of commands run by this actor")
+	auditCmd.Flags().StringVar(&auditID, "id", "", "Only show events that changed this document")
+	auditCmd.Flags().StringSliceVar(&auditTypes, "type", nil, "Only show these event types, e.g. scrape_complete,ingest_complete,delete,backfill,error")
+	auditCmd.Flags().DurationVar(&auditSince, "since", 7*24*time.Hour, "Only show events this recent (0 for all)")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 50, "Maximum number of events")
+
+	auditCmd.RegisterFlagCompletionFunc("source", completeSourceNames)
+}
+
+// newAuditStore creates the configured audit store.
+func newAuditStore(cfg *config.Config) (audit.Store, error) {
+	switch cfg.Audit.Store {
+	case "elasticsearch":
+		esClient, err := newESClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return audit.NewESStore(esClient, cfg.Audit.Index), nil
+	case "s3":
+		storageClient, err := newStorageClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return audit.NewS3Store(storageClient), nil
+	default:
+		return nil, fmt.Errorf("unknown audit store %q", cfg.Audit.Store)
+	}
+}
+
+// currentActor returns who runs the command: $BAMRAG_ACTOR, or else the
+// OS user.
+func currentActor() string {
+	if actor := os.Getenv("BAMRAG_ACTOR"); actor != "" {
+		return actor
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// recordAudit adds an event to the audit log without showing it, for
+// commands that print their own results.
+func recordAudit(e progress.Event) {
+	if auditLog != nil {
+		auditLog.Report(e)
+	}
+}
+
+// reportFor reports e on behalf of the actor set on ctx with
+// audit.WithActor, if any, such as the webhook that triggered the work.
+func reportFor(ctx context.Context, e progress.Event) {
+	e.Actor = cmp.Or(e.Actor, audit.ActorFrom(ctx))
+	reporter.Report(e)
+}
+
+// flushAuditLog writes the records collected so far, for long-running
+// commands.
+func flushAuditLog() {
+	if auditLog == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := auditLog.Flush(ctx); err != nil {
+		fmt.Fprintf(rootCmd.ErrOrStderr(), "Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// closeAuditLog writes the records of the finished command.
+func closeAuditLog(cmdErr error) {
+	if auditLog == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := auditLog.Close(ctx, cmdErr); err != nil {
+		fmt.Fprintf(rootCmd.ErrOrStderr(), "Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	store, err := newAuditStore(&cfg)
+	if err != nil {
+		return err
+	}
+
+	q := audit.Query{Actor: auditActor, ID: auditID, Limit: auditLimit}
+	if auditSince > 0 {
+		q.Since = time.Now().Add(-auditSince)
+	}
+	for _, t := range auditTypes {
+		q.Types = append(q.Types, progress.EventType(strings.TrimSpace(t)))
+	}
+	if auditSource != "" {
+		source, ok := cfg.SourceByName(auditSource)
+		if !ok {
+			return fmt.Errorf("source %q not found in config", auditSource)
+		}
+		storageClient, err := newStorageClient(&cfg)
+		if err != nil {
+			return err
+		}
+		scrapes, err := sourceScrapes(ctx, storageClient, []config.Source{source})
+		if err != nil {
+			return err
+		}
+		q.Source = &audit.SourceRecords{URL: cmp.Or(source.URL, sourcematch.DirURL(source)), URLPrefix: sourcematch.URLPrefix(source, cfg.Sources)}
+		for _, s := range scrapes[source.Name] {
+			q.Source.Prefixes = append(q.Source.Prefixes, s.Prefix)
+		}
+	}
+
+	records, err := store.Query(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(map[string]interface{}{"records": records}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(records) == 0 {
+		if !cfg.Audit.Enabled {
+			fmt.Println("No audit records (audit.enabled is off)")
+		} else {
+			fmt.Println("No audit records")
+		}
+		return nil
+	}
+	for _, r := range records {
+		fmt.Printf("%s  %-16s %-24s %s\n", r.Time.Local().Format(time.DateTime), r.Type, r.Host, auditDetail(r))
+	}
+	return nil
+}
+
+// auditDetail summarizes a record in one line.
+func auditDetail(r audit.Record) string {
+	var parts []string
+	if r.Prefix != "" {
+		parts = append(parts, r.Prefix)
+	} else if r.URL != "" {
+		parts = append(parts, r.URL)
+	}
+	if r.Pages > 0 {
+		parts = append(parts, fmt.Sprintf("%d pages", r.Pages))
+	}
+	if r.Docs > 0 {
+		parts = append(parts, fmt.Sprintf("%d docs", r.Docs))
+	}
+	if r.Message != "" {
+		parts = append(parts, r.Message)
+	}
+	if len(r.IDs) > 0 {
+		parts = append(parts, fmt.Sprintf("ids: %s", strings.Join(r.IDs, ",")))
+	}
+	if r.Actor != "" {
+		parts = append(parts, "by "+r.Actor)
+	}
+	return strings.Join(parts, "  ")
+}
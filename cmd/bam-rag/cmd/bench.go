@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/bench"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchQueries     string
+	benchConcurrency int
+	benchRounds      int
+	benchLimit       int
+	benchModes       []string
+)
+
+// benchModeNames are the search modes bench search can measure.
+var benchModeNames = []string{"bm25", "vector", "hybrid"}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark the search cluster",
+}
+
+var benchSearchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Measure search latency by replaying a query file",
+	Long: `Replay a query file against the index and report P50, P95, and P99
+latency and throughput for BM25, vector, and hybrid search, to validate
+cluster sizing.
+
+The query file has one query per line; blank lines and lines starting
+with # are ignored. A .yaml query set for 'bam-rag eval' works too.
+
+Vector and hybrid search need embeddings enabled. Queries are embedded
+once before timing starts, so latencies are those of Elasticsearch
+alone, not of the embedding model.
+
+Examples:
+  bam-rag bench search --queries queries.txt
+  bam-rag bench search --queries golden.yaml --concurrency 16 --rounds 5
+  bam-rag bench search --queries queries.txt --modes bm25,hybrid`,
+	RunE: runBenchSearch,
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.AddCommand(benchSearchCmd)
+
+	benchSearchCmd.Flags().StringVar(&benchQueries, "queries", "", "File of queries to replay (required)")
+	benchSearchCmd.Flags().IntVar(&benchConcurrency, "concurrency", 4, "Queries in flight at once")
+	benchSearchCmd.Flags().IntVar(&benchRounds, "rounds", 3, "Times the query file is replayed per mode")
+	benchSearchCmd.Flags().IntVar(&benchLimit, "limit", 10, "Results requested per query")
+	benchSearchCmd.Flags().StringSliceVar(&benchModes, "modes", benchModeNames, "Search modes to measure (bm25, vector, hybrid)")
+	benchSearchCmd.MarkFlagRequired("queries")
+}
+
+func runBenchSearch(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	for _, mode := range benchModes {
+		if !slices.Contains(benchModeNames, mode) {
+			return fmt.Errorf("unknown mode %q (expected bm25, vector, or hybrid)", mode)
+		}
+	}
+	if benchConcurrency < 1 || benchRounds < 1 || benchLimit < 1 {
+		return fmt.Errorf("--concurrency, --rounds, and --limit must be at least 1")
+	}
+
+	queries, err := bench.LoadQueries(benchQueries)
+	if err != nil {
+		return err
+	}
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	// Embed each query up front so only search latency is measured
+	var embeddings map[string][]float32
+	var skipped []string
+	if cfg.Embeddings.Enabled {
+		embedClient, err := newEmbeddingsClient(&cfg, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create embeddings client: %w", err)
+		}
+		embeddings = make(map[string][]float32, len(queries))
+		for _, q := range queries {
+			if _, ok := embeddings[q]; ok {
+				continue
+			}
+			embedding, err := embedClient.Embed(ctx, q)
+			if err != nil {
+				return fmt.Errorf("failed to embed query %q: %w", q, err)
+			}
+			embeddings[q] = embedding
+		}
+	}
+
+	searches := map[string]bench.SearchFunc{
+		"bm25": func(ctx context.Context, query string) error {
+			_, err := esClient.Search(ctx, query, benchLimit)
+			return err
+		},
+		"vector": func(ctx context.Context, query string) error {
+			_, err := esClient.VectorSearch(ctx, embeddings[query], benchLimit)
+			return err
+		},
+		"hybrid": func(ctx context.Context, query string) error {
+			_, err := esClient.HybridSearch(ctx, query, embeddings[query], benchLimit)
+			return err
+		},
+	}
+
+	opts := bench.Options{Concurrency: benchConcurrency, Rounds: benchRounds}
+	var reports []*bench.Report
+	for _, mode := range benchModes {
+		if mode != "bm25" && embeddings == nil {
+			skipped = append(skipped, mode)
+			continue
+		}
+		reports = append(reports, bench.Run(ctx, mode, queries, opts, searches[mode]))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Printf("%d queries x %d rounds, concurrency %d, limit %d\n\n", len(queries), benchRounds, benchConcurrency, benchLimit)
+	fmt.Printf("  %-7s  %8s  %6s  %8s  %8s  %8s  %8s  %8s\n", "mode", "requests", "errors", "qps", "p50", "p95", "p99", "max")
+	for _, r := range reports {
+		fmt.Printf("  %-7s  %8d  %6d  %8.1f  %8s  %8s  %8s  %8s\n", r.Mode, r.Requests, r.Errors, r.QPS,
+			benchDuration(r.P50), benchDuration(r.P95), benchDuration(r.P99), benchDuration(r.Max))
+	}
+	fmt.Println()
+	for _, r := range reports {
+		if r.FirstError != "" {
+			fmt.Printf("%s: first error: %s\n", r.Mode, r.FirstError)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("(%s skipped: embeddings not enabled)\n", strings.Join(skipped, ", "))
+	}
+
+	return nil
+}
+
+// benchDuration formats a latency for the bench table.
+func benchDuration(d time.Duration) string {
+	return d.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/audit"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/events"
+	"github.com/mfenderov/bam-rag/internal/ingestion"
+	"github.com/mfenderov/bam-rag/internal/jobs"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+)
+
+// newEventBus connects to the configured event bus, scoped to the
+// configured namespace.
+func newEventBus(ctx context.Context, cfg *config.Config) (events.Bus, error) {
+	bus, err := dialEventBus(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return events.Namespaced(bus, cfg.Namespace), nil
+}
+
+// dialEventBus connects to the configured event bus.
+func dialEventBus(ctx context.Context, cfg *config.Config) (events.Bus, error) {
+	switch cfg.Events.Bus {
+	case "memory":
+		return events.NewMemoryBus(), nil
+	case "nats":
+		bus, err := events.NewNATSBus(ctx, events.NATSConfig{
+			URL:      cfg.Events.NATS.URL,
+			Stream:   cfg.Events.NATS.Stream,
+			Username: cfg.Events.NATS.Username,
+			Password: cfg.Events.NATS.Password,
+			Token:    cfg.Events.NATS.Token,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to event bus: %w", err)
+		}
+		return bus, nil
+	case "kafka":
+		bus, err := events.NewKafkaBus(ctx, events.KafkaConfig{
+			Brokers:     cfg.Events.Kafka.Brokers,
+			Group:       cfg.Events.Kafka.Group,
+			TopicPrefix: cfg.Events.Kafka.TopicPrefix,
+			Username:    cfg.Events.Kafka.Username,
+			Password:    cfg.Events.Kafka.Password,
+			Mechanism:   cfg.Events.Kafka.SASLMechanism,
+			TLS:         cfg.Events.Kafka.TLS,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to event bus: %w", err)
+		}
+		return bus, nil
+	case "sqs":
+		bus, err := events.NewSQSBus(ctx, events.SQSConfig{
+			Region:          cfg.Events.SQS.Region,
+			Endpoint:        cfg.Events.SQS.Endpoint,
+			QueuePrefix:     cfg.Events.SQS.QueuePrefix,
+			AccessKeyID:     cfg.Events.SQS.AccessKeyID,
+			SecretAccessKey: cfg.Events.SQS.SecretAccessKey,
+			SessionToken:    cfg.Events.SQS.SessionToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to event bus: %w", err)
+		}
+		return bus, nil
+	default:
+		return nil, fmt.Errorf("unknown event bus %q", cfg.Events.Bus)
+	}
+}
+
+// externalBus reports whether events leave the process, so scrapes can be
+// ingested by other processes.
+func externalBus(cfg *config.Config) bool {
+	return cfg.Events.Bus != "memory"
+}
+
+// ingestTally accumulates the results of ingested scrape events.
+type ingestTally struct {
+	docs     int
+	duration time.Duration
+	stages   map[string]time.Duration
+}
+
+// add counts an ingestion result.
+func (t *ingestTally) add(result *ingestion.Result) {
+	t.docs += result.DocsIndexed
+	t.duration += result.Duration
+	t.stages = addStages(t.stages, result.Stages)
+}
+
+// addStages adds the stage timings of src to dst, which may be nil.
+func addStages(dst, src map[string]time.Duration) map[string]time.Duration {
+	for stage, d := range src {
+		if dst == nil {
+			dst = make(map[string]time.Duration)
+		}
+		dst[stage] += d
+	}
+	return dst
+}
+
+// ingestScrapeEvents returns a handler that ingests each scraped prefix with
+// the engine of the source that produced it. With announce set, an
+// IngestionCompleteEvent is published on bus for every ingested prefix.
+func ingestScrapeEvents(bus events.Bus, engines *sourceEngines, tally *ingestTally, announce bool) func(context.Context, events.ScrapeCompleteEvent) error {
+	return func(ctx context.Context, event events.ScrapeCompleteEvent) error {
+		ctx = telemetry.WithTraceparent(ctx, event.Traceparent)
+		ctx = audit.WithActor(ctx, event.Actor)
+		reportFor(ctx, progress.Event{Type: progress.EventIngestStart, Prefix: event.Prefix, Total: event.PageCount})
+
+		result, err := engines.ingest(ctx, event.Prefix, event.Source)
+		switch {
+		case errors.Is(err, jobs.ErrClaimed):
+			reportFor(ctx, progress.Event{Type: progress.EventInfo, Prefix: event.Prefix, Message: err.Error()})
+			return nil
+		case err != nil && ctx.Err() != nil:
+			// Interrupted; the bus hands the event to the next consumer
+			return err
+		case err != nil:
+			// The job records the failure and is retried from there, so the
+			// event is not redelivered on top
+			reportFor(ctx, progress.Event{Type: progress.EventError, Prefix: event.Prefix, Message: err.Error()})
+			return nil
+		}
+
+		tally.add(result)
+		reportIngestResult(ctx, result)
+
+		if announce {
+			err := events.PublishIngestionComplete(ctx, bus, events.IngestionCompleteEvent{
+				Prefix:      result.Prefix,
+				DocsIndexed: result.DocsIndexed,
+				Duration:    result.Duration,
+				Errors:      errorMessages(result.Errors),
+			})
+			if err != nil {
+				reportFor(ctx, progress.Event{Type: progress.EventWarning, Prefix: event.Prefix, Message: err.Error()})
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,466 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+
+	"github.com/mfenderov/bam-rag/internal/backend"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/embedded"
+	"github.com/mfenderov/bam-rag/internal/embeddings"
+	"github.com/mfenderov/bam-rag/internal/events"
+	"github.com/mfenderov/bam-rag/internal/ingestion"
+	"github.com/mfenderov/bam-rag/internal/jobs"
+	"github.com/mfenderov/bam-rag/internal/llm"
+	"github.com/mfenderov/bam-rag/internal/modelrunner"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/qdrant"
+	"github.com/mfenderov/bam-rag/internal/rerank"
+	"github.com/mfenderov/bam-rag/internal/scraper"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/webhook"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// newESClient creates an Elasticsearch client from the loaded configuration.
+func newESClient(cfg *config.Config) (*elasticsearch.Client, error) {
+	esClient, err := elasticsearch.New(elasticsearch.Config{
+		Addresses: cfg.Elasticsearch.Addresses,
+		CloudID:   cfg.Elasticsearch.CloudID,
+		Index:     cfg.Elasticsearch.Index,
+		Username:  cfg.Elasticsearch.Username,
+		Password:  cfg.Elasticsearch.Password,
+		APIKey:    cfg.Elasticsearch.APIKey,
+		Mapping:   esMapping(cfg),
+		Fusion:    esFusion(cfg),
+		Recency:   esRecency(cfg),
+		Dedup:     esDedup(cfg),
+
+		SourceIndices:     cfg.SourceIndices(),
+		Fuzziness:         cfg.Elasticsearch.Fuzziness,
+		FuzzyPrefixLength: cfg.Elasticsearch.FuzzyPrefixLength,
+
+		MaxIdleConns:    cfg.Elasticsearch.Transport.MaxIdleConns,
+		IdleConnTimeout: cfg.Elasticsearch.Transport.IdleConnTimeout,
+		Timeout:         cfg.Elasticsearch.Transport.RequestTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ES client: %w", err)
+	}
+	return esClient, nil
+}
+
+// newSearchBackend creates the search backend the configuration selects.
+func newSearchBackend(cfg *config.Config) (backend.SearchBackend, error) {
+	switch cfg.Backend.Type {
+	case "embedded":
+		store, err := embedded.Open(cfg.Backend.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedded index: %w", err)
+		}
+		return store, nil
+	case "qdrant":
+		client, err := qdrant.New(qdrant.Config{
+			URL:        cfg.Backend.Qdrant.URL,
+			APIKey:     cfg.Backend.Qdrant.APIKey,
+			Collection: cfg.Backend.Qdrant.Collection,
+			VectorSize: cfg.Backend.Qdrant.VectorSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Qdrant client: %w", err)
+		}
+		return client, nil
+	}
+	return newESClient(cfg)
+}
+
+// newPruner creates the client the delete and gc commands remove pages
+// with, for the configured backend.
+func newPruner(cfg *config.Config) (backend.Pruner, error) {
+	switch cfg.Backend.Type {
+	case "embedded":
+		store, err := embedded.Open(cfg.Backend.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedded index: %w", err)
+		}
+		return store, nil
+	case "qdrant":
+		return nil, fmt.Errorf("backend.type: qdrant does not support deleting by source or orphan collection")
+	}
+	return newESClient(cfg)
+}
+
+// indexName names the index the configuration selects, for messages.
+func indexName(cfg *config.Config) string {
+	if cfg.Backend.Type == "embedded" {
+		return cfg.Backend.Path
+	}
+	return cfg.Elasticsearch.Index
+}
+
+// newReranker creates the reranking client for a search surface, or returns
+// nil if results of that surface are not reranked.
+func newReranker(cfg *config.Config, surface string) (*rerank.Client, error) {
+	if !cfg.Rerank.Reranks(surface) {
+		return nil, nil
+	}
+	reranker, err := rerank.New(rerank.Config{
+		SocketPath: cfg.Rerank.SocketPath,
+		Model:      cfg.Rerank.Model,
+		Candidates: cfg.Rerank.Candidates,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rerank client: %w", err)
+	}
+	return reranker, nil
+}
+
+// esFusion converts the configured hybrid search fusion.
+func esFusion(cfg *config.Config) elasticsearch.Fusion {
+	return elasticsearch.Fusion{
+		Method:         cfg.Elasticsearch.Fusion.Method,
+		RankConstant:   cfg.Elasticsearch.Fusion.RankConstant,
+		RankWindowSize: cfg.Elasticsearch.Fusion.RankWindowSize,
+		BM25Weight:     cfg.Elasticsearch.Fusion.BM25Weight,
+		VectorWeight:   cfg.Elasticsearch.Fusion.VectorWeight,
+	}
+}
+
+// esRecency converts the configured recency boost.
+func esRecency(cfg *config.Config) elasticsearch.Recency {
+	return elasticsearch.Recency{
+		Weight: cfg.Elasticsearch.Recency.Weight,
+		Scale:  cfg.Elasticsearch.Recency.Scale,
+		Offset: cfg.Elasticsearch.Recency.Offset,
+		Decay:  cfg.Elasticsearch.Recency.Decay,
+	}
+}
+
+// esDedup converts the configured collapsing of duplicate results.
+func esDedup(cfg *config.Config) elasticsearch.Dedup {
+	return elasticsearch.Dedup{
+		URLs:       cfg.Elasticsearch.Dedup.URLs,
+		Similarity: cfg.Elasticsearch.Dedup.Similarity,
+		PerSource:  cfg.Elasticsearch.Dedup.PerSource,
+	}
+}
+
+// esMapping converts the configured index mapping customizations.
+func esMapping(cfg *config.Config) elasticsearch.Mapping {
+	return elasticsearch.Mapping{
+		Analyzer:         cfg.Elasticsearch.Mapping.Analyzer,
+		VectorSimilarity: cfg.Elasticsearch.Mapping.VectorSimilarity,
+		Fields:           cfg.Elasticsearch.Mapping.Fields,
+		Settings:         cfg.Elasticsearch.Mapping.Settings,
+	}
+}
+
+// newStorageClient creates an S3/MinIO client from the loaded configuration.
+func newStorageClient(cfg *config.Config) (*storage.Client, error) {
+	if cfg.Storage.Endpoint == "" {
+		return nil, fmt.Errorf("storage not configured - check config file")
+	}
+	storageClient, err := storage.New(storage.Config{
+		Endpoint:        cfg.Storage.Endpoint,
+		Bucket:          cfg.Storage.Bucket,
+		AccessKeyID:     cfg.Storage.AccessKeyID,
+		SecretAccessKey: cfg.Storage.SecretAccessKey,
+		UseSSL:          cfg.Storage.UseSSL,
+		MaxObjectSize:   cfg.Storage.MaxObjectSize,
+		Namespace:       cfg.Namespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	return storageClient, nil
+}
+
+// newScraper creates a scraper for a source's effective configuration.
+// It fails if the source's link patterns do not compile.
+// source is the config source name recorded in scrape metadata.
+func newScraper(cfg *config.Config, source config.Source) (*scraper.Scraper, error) {
+	include, err := compilePatterns(source.IncludePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("sources[%s].include_patterns: %w", source.Name, err)
+	}
+	exclude, err := compilePatterns(source.ExcludePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("sources[%s].exclude_patterns: %w", source.Name, err)
+	}
+	proxies, err := scraperProxies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := scraperAuth(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return scraper.New(scraper.Config{
+		Delay:            cfg.Scraper.Delay,
+		MaxDepth:         cfg.Scraper.MaxDepth,
+		FollowLinks:      cfg.Scraper.FollowLinks,
+		Timeout:          cfg.Scraper.Timeout,
+		UserAgent:        cfg.Scraper.UserAgent,
+		TryMarkdownFirst: cfg.Scraper.TryMarkdownFirst,
+		ContentSelector:  cfg.Scraper.ContentSelector,
+		MaxParallel:      cfg.Scraper.MaxParallel,
+		Source:           source.Name,
+		Auth:             auth,
+		Headers:          source.Headers,
+		Progress:         reporter,
+		SlowOps:          slowOps,
+		MemoryBudget:     cfg.Scraper.MemoryBudget,
+		CacheDir:         cfg.Scraper.CacheDir,
+		Uploads:          cfg.Scraper.Uploads,
+		RespectNoindex:   cfg.Scraper.RespectNoindex,
+		RespectRobots:    cfg.Scraper.RespectRobots,
+		Incremental:      cfg.Scraper.Incremental,
+		Retry:            scraperRetry(cfg),
+		Proxies:          proxies,
+		Include:          include,
+		Exclude:          exclude,
+		Redactor:         redactor,
+	}), nil
+}
+
+// scraperRetry converts the retry settings of the scraper config.
+func scraperRetry(cfg *config.Config) scraper.Retry {
+	return scraper.Retry{
+		MaxAttempts: cfg.Scraper.Retry.MaxAttempts,
+		Backoff:     cfg.Scraper.Retry.Backoff,
+		MaxBackoff:  cfg.Scraper.Retry.MaxBackoff,
+		Jitter:      cfg.Scraper.Retry.Jitter,
+	}
+}
+
+// scraperProxies parses the proxies of the scraper config.
+func scraperProxies(cfg *config.Config) ([]*url.URL, error) {
+	var proxies []*url.URL
+	for _, raw := range cfg.Scraper.ProxyURLs() {
+		// The parse error is left out, since it repeats any credentials
+		proxy, err := url.Parse(raw)
+		if err != nil {
+			return nil, errors.New("scraper: invalid proxy URL")
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// compilePatterns compiles regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = re
+	}
+	return res, nil
+}
+
+// scraperAuth converts the configured per-domain credentials for the
+// scraper, loading their cookies files.
+func scraperAuth(cfg *config.Config) ([]scraper.Auth, error) {
+	auths := make([]scraper.Auth, len(cfg.Auth))
+	for i, a := range cfg.Auth {
+		auths[i] = scraper.Auth{
+			Domain:   a.Domain,
+			Headers:  a.Headers,
+			Username: a.Username,
+			Password: a.Password,
+			Token:    a.Token,
+		}
+		if a.CookiesFile != "" {
+			cookies, err := scraper.LoadCookies(a.CookiesFile)
+			if err != nil {
+				return nil, fmt.Errorf("auth for %s: %w", a.Domain, err)
+			}
+			auths[i].Cookies = cookies
+		}
+	}
+	return auths, nil
+}
+
+// newEmbeddingsClient creates an embeddings client from the loaded
+// configuration. limiter may be nil.
+func newEmbeddingsClient(cfg *config.Config, limiter *modelrunner.Limiter) (*embeddings.Client, error) {
+	return embeddings.New(embeddings.Config{
+		SocketPath:      cfg.Embeddings.SocketPath,
+		Model:           cfg.Embeddings.Model,
+		MaxIdleConns:    cfg.Embeddings.Transport.MaxIdleConns,
+		IdleConnTimeout: cfg.Embeddings.Transport.IdleConnTimeout,
+		Timeout:         cfg.Embeddings.Transport.RequestTimeout,
+		Limiter:         limiter,
+	})
+}
+
+// newIngestionEngine creates an ingestion engine with the search backend,
+// embeddings, and LLM clients the configuration enables.
+func newIngestionEngine(cfg *config.Config, storageClient *storage.Client) (*ingestion.Engine, error) {
+	index, err := newSearchBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Embeddings and enrichment served by the same Docker Model Runner
+	// take turns, one request at a time
+	var limiter *modelrunner.Limiter
+	if cfg.Embeddings.Enabled && cfg.LLM.Enabled && cfg.Embeddings.SocketPath == cfg.LLM.SocketPath {
+		limiter = modelrunner.NewLimiter(1)
+	}
+
+	// Create optional embeddings client
+	var embedClient *embeddings.Client
+	if cfg.Embeddings.Enabled {
+		embedClient, err = newEmbeddingsClient(cfg, limiter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embeddings client: %w", err)
+		}
+		slog.Info("embeddings enabled", "model", cfg.Embeddings.Model)
+	}
+
+	// Create optional LLM client
+	var llmClient *llm.Client
+	if cfg.LLM.Enabled {
+		llmClient, err = llm.New(llm.Config{
+			SocketPath:      cfg.LLM.SocketPath,
+			Model:           cfg.LLM.Model,
+			MaxIdleConns:    cfg.LLM.Transport.MaxIdleConns,
+			IdleConnTimeout: cfg.LLM.Transport.IdleConnTimeout,
+			Timeout:         cfg.LLM.Transport.RequestTimeout,
+			Limiter:         limiter,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		slog.Info("LLM enrichment enabled", "model", cfg.LLM.Model)
+	}
+
+	engine := ingestion.New(storageClient, index, embedClient, llmClient)
+	engine.SetProgress(reporter)
+	engine.SetSlowOps(slowOps)
+	engine.SetRedactor(redactor)
+	engine.SetFull(ingestFull)
+	engine.SetStreamThreshold(cfg.Ingestion.StreamThreshold)
+	engine.SetMaxChunkSize(cfg.Ingestion.MaxChunkSize)
+	if !cfg.Elasticsearch.Serverless {
+		engine.SetRefreshInterval(cfg.Ingestion.RefreshInterval)
+	}
+	engine.SetSituateChunks(cfg.LLM.SituateChunks)
+	engine.SetWarmup(ingestion.Warmup{
+		Enabled:   cfg.Warmup.Enabled,
+		KeepAlive: cfg.Warmup.KeepAlive,
+	})
+	return engine, nil
+}
+
+// newNotifier creates a notifier for the configured webhooks.
+func newNotifier(cfg *config.Config) *webhook.Notifier {
+	hooks := make([]webhook.Hook, len(cfg.Webhooks))
+	for i, h := range cfg.Webhooks {
+		hooks[i] = webhook.Hook{
+			URL:     h.URL,
+			Secret:  h.Secret,
+			Events:  h.Events,
+			Headers: h.Headers,
+		}
+	}
+	return webhook.New(hooks)
+}
+
+// sourceEngines lazily creates one ingestion engine per config source, so
+// each source is ingested with its own overrides. Not safe for concurrent use.
+type sourceEngines struct {
+	cfg           *config.Config
+	storageClient *storage.Client
+	engines       map[string]*ingestion.Engine
+	jobs          *jobs.Queue
+	notifier      *webhook.Notifier
+}
+
+func newSourceEngines(cfg *config.Config, storageClient *storage.Client) *sourceEngines {
+	return &sourceEngines{
+		cfg:           cfg,
+		storageClient: storageClient,
+		engines:       make(map[string]*ingestion.Engine),
+		jobs:          jobs.New(storageClient, cfg.Jobs.MaxAttempts, cfg.Jobs.Backoff),
+		notifier:      newNotifier(cfg),
+	}
+}
+
+// ingest ingests prefix with the source's engine as a tracked job, retrying
+// failures with backoff. A run in which every document failed counts as a
+// failure, so the job is retried rather than reported done. Webhooks are
+// notified once the job is done.
+func (s *sourceEngines) ingest(ctx context.Context, prefix, source string) (*ingestion.Result, error) {
+	var result *ingestion.Result
+	err := s.jobs.Run(ctx, prefix, source, func(ctx context.Context) error {
+		engine, err := s.get(source)
+		if err != nil {
+			return err
+		}
+		result, err = engine.Ingest(ctx, prefix)
+		switch {
+		case err != nil:
+			return err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = s.notifier.Notify(ctx, webhook.EventIngestionComplete, events.IngestionCompleteEvent{
+		Prefix:      result.Prefix,
+		DocsIndexed: result.DocsIndexed,
+		Duration:    result.Duration,
+		Errors:      errorMessages(result.Errors),
+	})
+	if err != nil {
+		reporter.Report(progress.Event{Type: progress.EventWarning, Prefix: prefix, Message: err.Error()})
+	}
+	return result, nil
+}
+
+// errorMessages formats page errors for events, whose schema carries
+// them as strings.
+func errorMessages(errs []*models.PageError) []string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Error()
+	}
+	return messages
+}
+
+// get returns the engine for a source name. Unknown or empty names use the
+// global configuration.
+func (s *sourceEngines) get(source string) (*ingestion.Engine, error) {
+	if engine, ok := s.engines[source]; ok {
+		return engine, nil
+	}
+
+	eff := *s.cfg
+	src, ok := s.cfg.SourceByName(source)
+	if ok {
+		eff = s.cfg.ForSource(src)
+	} else if source != "" {
+		slog.Warn("source not found in config, using global settings", "source", source)
+	}
+
+	engine, err := newIngestionEngine(&eff, s.storageClient)
+	if err != nil {
+		return nil, err
+	}
+	engine.SetAccessLabels(src.AccessLabels)
+	s.engines[source] = engine
+	return engine, nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds lookups made while the shell waits for completions.
+const completionTimeout = 5 * time.Second
+
+// completeSourceNames completes source names from the config file.
+func completeSourceNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	// Config is loaded before flags are parsed; reload to honour --config
+	initConfig()
+
+	var names []cobra.Completion
+	for _, source := range GetConfig().Sources {
+		if !strings.HasPrefix(source.Name, toComplete) {
+			continue
+		}
+		desc := source.URL
+		if desc == "" {
+			desc = source.Path
+		}
+		names = append(names, cobra.CompletionWithDesc(source.Name, desc))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGroupNames completes source group names from the config file.
+func completeGroupNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	initConfig()
+
+	var groups []cobra.Completion
+	for _, source := range GetConfig().Sources {
+		if source.Group == "" || !strings.HasPrefix(source.Group, toComplete) || slices.Contains(groups, source.Group) {
+			continue
+		}
+		groups = append(groups, source.Group)
+	}
+	return groups, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeScrapePrefixes completes S3 scrape prefixes, newest first.
+func completeScrapePrefixes(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	initConfig()
+	cfg := GetConfig()
+
+	storageClient, err := newStorageClient(&cfg)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	scrapes, err := storageClient.ListScrapes(ctx)
+	if err != nil {
+		cobra.CompDebugln("failed to list scrapes: "+err.Error(), false)
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var prefixes []cobra.Completion
+	for i := len(scrapes) - 1; i >= 0; i-- {
+		s := scrapes[i]
+		if !strings.HasPrefix(s.Prefix, toComplete) {
+			continue
+		}
+		desc := s.CreatedAt.Local().Format(time.DateTime)
+		if s.Ingested {
+			desc += " (ingested)"
+		}
+		prefixes = append(prefixes, cobra.CompletionWithDesc(s.Prefix, desc))
+	}
+	return prefixes, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initFile    string
+	initForce   bool
+	initYes     bool
+	initSources []string
+	initOpts    = config.DefaultInitOptions()
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage configuration",
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate a starter config file",
+	Long: `Generate a starter config.yaml with storage, Elasticsearch, model,
+and source settings. Optional settings are included as commented defaults.
+
+When run in a terminal, prompts for values not given as flags.
+
+Examples:
+  # Interactive
+  bam-rag config init
+
+  # Non-interactive
+  bam-rag config init --yes --socket-path /var/run/docker.sock \
+    --source go-docs=https://go.dev/doc/ --source team-docs=./docs`,
+	RunE: runConfigInit,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd)
+
+	f := configInitCmd.Flags()
+	f.StringVar(&initFile, "file", "config/config.yaml", "Path of the config file to write")
+	f.BoolVar(&initForce, "force", false, "Overwrite an existing config file")
+	f.BoolVarP(&initYes, "yes", "y", false, "Do not prompt; use flags and defaults")
+	f.StringVar(&initOpts.ESAddress, "es-address", initOpts.ESAddress, "Elasticsearch address")
+	f.StringVar(&initOpts.ESIndex, "index", initOpts.ESIndex, "Elasticsearch index name")
+	f.StringVar(&initOpts.StorageEndpoint, "storage-endpoint", initOpts.StorageEndpoint, "S3/MinIO endpoint")
+	f.StringVar(&initOpts.Bucket, "bucket", initOpts.Bucket, "S3 bucket name")
+	f.StringVar(&initOpts.AccessKeyID, "access-key-id", initOpts.AccessKeyID, "S3 access key ID")
+	f.StringVar(&initOpts.SecretAccessKey, "secret-access-key", initOpts.SecretAccessKey, "S3 secret access key")
+	f.BoolVar(&initOpts.UseSSL, "use-ssl", initOpts.UseSSL, "Use SSL for S3")
+	f.StringVar(&initOpts.SocketPath, "socket-path", "", "Docker Model Runner socket (enables embeddings and LLM)")
+	f.StringArrayVar(&initSources, "source", nil, "Source as name=url or name=path (repeatable)")
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(initFile); err == nil && !initForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", initFile)
+	}
+
+	for _, spec := range initSources {
+		source, err := parseSourceSpec(spec)
+		if err != nil {
+			return err
+		}
+		initOpts.Sources = append(initOpts.Sources, source)
+	}
+
+	if !initYes && progress.IsTerminal(os.Stdin) {
+		if err := promptInitOptions(cmd, cmd.InOrStdin(), cmd.OutOrStdout()); err != nil {
+			return err
+		}
+	}
+
+	content, err := config.RenderTemplate(initOpts)
+	if err != nil {
+		return fmt.Errorf("failed to render config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(initFile), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(initFile, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", initFile)
+	return nil
+}
+
+// promptInitOptions asks for each value that was not set via flags.
+func promptInitOptions(cmd *cobra.Command, in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	flags := cmd.Flags()
+
+	ask := func(flag, label string, value *string) error {
+		if flags.Changed(flag) {
+			return nil
+		}
+		fmt.Fprintf(out, "%s [%s]: ", label, *value)
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			*value = line
+		}
+		return nil
+	}
+
+	prompts := []struct {
+		flag, label string
+		value       *string
+	}{
+		{"es-address", "Elasticsearch address", &initOpts.ESAddress},
+		{"index", "Elasticsearch index", &initOpts.ESIndex},
+		{"storage-endpoint", "S3/MinIO endpoint", &initOpts.StorageEndpoint},
+		{"bucket", "S3 bucket", &initOpts.Bucket},
+		{"access-key-id", "S3 access key ID", &initOpts.AccessKeyID},
+		{"secret-access-key", "S3 secret access key", &initOpts.SecretAccessKey},
+		{"socket-path", "Docker Model Runner socket (blank disables models)", &initOpts.SocketPath},
+	}
+	for _, p := range prompts {
+		if err := ask(p.flag, p.label, p.value); err != nil {
+			return err
+		}
+	}
+
+	if flags.Changed("source") {
+		return nil
+	}
+	for {
+		fmt.Fprint(out, "Add source as name=url or name=path (blank to finish): ")
+		line, err := r.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return nil
+		}
+		source, perr := parseSourceSpec(line)
+		if perr != nil {
+			fmt.Fprintf(out, "  %v\n", perr)
+		} else {
+			initOpts.Sources = append(initOpts.Sources, source)
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// parseSourceSpec parses "name=url" or "name=path" into a Source.
+func parseSourceSpec(spec string) (config.Source, error) {
+	name, target, ok := strings.Cut(spec, "=")
+	name, target = strings.TrimSpace(name), strings.TrimSpace(target)
+	if !ok || name == "" || target == "" {
+		return config.Source{}, fmt.Errorf("invalid source %q (want name=url or name=path)", spec)
+	}
+	if strings.Contains(target, "://") {
+		return config.Source{Name: name, URL: target}, nil
+	}
+	return config.Source{Name: name, Path: target}, nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/sourcematch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deleteSource string
+	deleteGroup  string
+	deleteDryRun bool
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Remove a source's documents from the index",
+	Long: `Delete every indexed document that belongs to a configured source
+or to all sources in a group. Scraped content in S3 is kept, so the
+documents can be restored with 'ingest'.
+
+Examples:
+  # Show how many documents would be deleted
+  bam-rag delete --group kubernetes --dry-run
+
+  # Remove one source's documents
+  bam-rag delete --source example-docs`,
+	RunE: runDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().StringVar(&deleteSource, "source", "", "Delete documents of this source")
+	deleteCmd.Flags().StringVar(&deleteGroup, "group", "", "Delete documents of all sources in this group")
+	deleteCmd.Flags().BoolVar(&deleteDryRun, "dry-run", false, "Count matching documents without deleting them")
+	deleteCmd.MarkFlagsMutuallyExclusive("source", "group")
+	deleteCmd.MarkFlagsOneRequired("source", "group")
+
+	deleteCmd.RegisterFlagCompletionFunc("source", completeSourceNames)
+	deleteCmd.RegisterFlagCompletionFunc("group", completeGroupNames)
+}
+
+// deleteResult reports the documents matched and deleted for one source.
+type deleteResult struct {
+	Source  string `json:"source"`
+	Index   string `json:"index"`
+	Matched int    `json:"matched"`
+	Deleted int    `json:"deleted"`
+}
+
+func runDelete(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	sources, err := selectSources(&cfg, deleteSource, deleteGroup)
+	if err != nil {
+		return err
+	}
+
+	var results []deleteResult
+	for _, source := range sources {
+		result, err := deleteSourceDocuments(ctx, &cfg, source)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(map[string]interface{}{
+			"sources": results,
+			"dry_run": deleteDryRun,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	total := 0
+	for _, r := range results {
+		fmt.Printf("  %-24s  %-20s  %d\n", r.Source, r.Index, r.Matched)
+		total += r.Deleted
+	}
+	if deleteDryRun {
+		matched := 0
+		for _, r := range results {
+			matched += r.Matched
+		}
+		fmt.Printf("\n%d documents match (dry run, nothing deleted)\n", matched)
+	} else {
+		fmt.Printf("\nDeleted %d documents\n", total)
+	}
+
+	return nil
+}
+
+// deleteSourceDocuments removes one source's documents from its target index.
+func deleteSourceDocuments(ctx context.Context, cfg *config.Config, source config.Source) (deleteResult, error) {
+	// Sources may override the target index
+	sourceCfg := cfg.ForSource(source)
+	result := deleteResult{Source: source.Name, Index: indexName(&sourceCfg)}
+
+	filter := sourcesFilter(cfg, []config.Source{source})
+	if filter.IsZero() {
+		return result, fmt.Errorf("source %q has no url or path", source.Name)
+	}
+
+	// The global client reads every source index, so documents indexed
+	// before the source's index changed are removed too
+	index, err := newPruner(cfg)
+	if err != nil {
+		return result, err
+	}
+
+	// Deleting by ID rather than by filter leaves an audit record of
+	// exactly the documents removed
+	ids, err := index.MatchingIDs(ctx, filter)
+	if err != nil {
+		return result, fmt.Errorf("failed to find documents of %s: %w", source.Name, err)
+	}
+	result.Matched = len(ids)
+	if deleteDryRun || result.Matched == 0 {
+		return result, nil
+	}
+
+	result.Deleted, err = index.DeleteDocuments(ctx, ids)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete documents of %s: %w", source.Name, err)
+	}
+	recordAudit(progress.Event{
+		Type:    progress.EventDelete,
+		URL:     cmp.Or(source.URL, sourcematch.DirURL(source)),
+		Docs:    result.Deleted,
+		IDs:     ids,
+		Message: fmt.Sprintf("deleted documents of source %s from %s", source.Name, result.Index),
+	})
+	return result, nil
+}
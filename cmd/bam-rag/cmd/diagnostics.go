@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mfenderov/bam-rag/internal/diagnostics"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// pprofAddr is where any command serves net/http/pprof; empty disables it.
+	pprofAddr string
+	// dumpDir is where long-running commands write profiles on SIGQUIT;
+	// empty leaves SIGQUIT to the Go runtime.
+	dumpDir string
+)
+
+// addDumpFlag registers --dump-dir on a long-running command.
+func addDumpFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&dumpDir, "dump-dir", "", "On SIGQUIT, write goroutine and heap profiles to this directory and keep running")
+}
+
+// startPprofServer serves pprof in the background for the rest of the
+// process, if --pprof is set. A failing server is reported but does not
+// stop the command.
+func startPprofServer() {
+	if pprofAddr == "" {
+		return
+	}
+	go func() {
+		if err := diagnostics.Serve(context.Background(), pprofAddr); err != nil {
+			slog.Warn("pprof disabled", "error", err)
+		}
+	}()
+	slog.Info("serving pprof", "addr", pprofAddr)
+}
+
+// startProfileDumps writes profiles on SIGQUIT until ctx is done, if
+// --dump-dir is set.
+func startProfileDumps(ctx context.Context) {
+	if dumpDir == "" {
+		return
+	}
+	diagnostics.DumpOnQuit(ctx, dumpDir)
+	slog.Info("dumping profiles on SIGQUIT", "dir", dumpDir)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	embedBatchSize int
+	embedLimit     int
+)
+
+var embedCmd = &cobra.Command{
+	Use:   "embed",
+	Short: "Generate embeddings for indexed documents that lack them",
+	Long: `Backfill embeddings for documents already in Elasticsearch.
+
+Useful when the corpus was ingested before embeddings were enabled,
+or when embedding generation failed for some documents. Only documents
+without an embedding are touched; content is read from the index, so
+nothing is re-scraped.
+
+Examples:
+  # Embed every document that is missing a vector
+  bam-rag embed
+
+  # Try it on a few documents first
+  bam-rag embed --limit 10`,
+	RunE: runEmbed,
+}
+
+func init() {
+	rootCmd.AddCommand(embedCmd)
+
+	embedCmd.Flags().IntVar(&embedBatchSize, "batch-size", 50, "Documents per bulk update")
+	embedCmd.Flags().IntVar(&embedLimit, "limit", 0, "Maximum number of documents to embed (0 = no limit)")
+}
+
+// errEmbedLimit stops the scroll once --limit documents have been embedded.
+var errEmbedLimit = errors.New("embed limit reached")
+
+func runEmbed(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	if !cfg.Embeddings.Enabled {
+		return fmt.Errorf("embeddings not enabled - set embeddings.enabled in config")
+	}
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	embedClient, err := newEmbeddingsClient(&cfg, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create embeddings client: %w", err)
+	}
+
+	stats, err := esClient.Stats(ctx, 1)
+	if err != nil {
+		return fmt.Errorf("failed to count documents: %w", err)
+	}
+	total := stats.DocCount - stats.WithEmbedding
+	if embedLimit > 0 {
+		total = min(total, embedLimit)
+	}
+	if total == 0 {
+		reporter.Report(progress.Event{Type: progress.EventInfo, Message: "all documents have embeddings"})
+		return nil
+	}
+
+	start := time.Now()
+	processed, updated, failed := 0, 0, 0
+	batch := make(map[string][]float32, embedBatchSize)
+
+	flush := func() error {
+		n, err := esClient.UpdateEmbeddings(ctx, batch)
+		updated += n
+		if n > 0 {
+			recordAudit(progress.Event{
+				Type:    progress.EventBackfill,
+				Docs:    n,
+				IDs:     slices.Sorted(maps.Keys(batch)),
+				Message: fmt.Sprintf("backfilled embeddings in %s", cfg.Elasticsearch.Index),
+			})
+		}
+		clear(batch)
+		if err != nil {
+			return fmt.Errorf("failed to update embeddings: %w", err)
+		}
+		return nil
+	}
+
+	err = esClient.ScrollDocumentsWithoutEmbedding(ctx, embedBatchSize, func(doc models.Document) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if embedLimit > 0 && processed >= embedLimit {
+			return errEmbedLimit
+		}
+		processed++
+
+		embedding, err := embedClient.Embed(ctx, doc.Content)
+		if err != nil {
+			failed++
+			slog.Warn("failed to generate embedding", "url", doc.URL, "error", err)
+			reporter.Report(progress.Event{Type: progress.EventWarning, URL: doc.URL, Message: err.Error()})
+			return nil
+		}
+		batch[doc.ID] = embedding
+
+		reporter.Report(progress.Event{Type: progress.EventDocument, URL: doc.URL, Current: processed, Total: total})
+
+		if len(batch) >= embedBatchSize {
+			return flush()
+		}
+		return nil
+	}, "id", "url", "content")
+	if err != nil && !errors.Is(err, errEmbedLimit) && ctx.Err() == nil {
+		return err
+	}
+
+	// Keep what was embedded before the scroll ended or was interrupted
+	ctx = context.WithoutCancel(ctx)
+	if err := flush(); err != nil {
+		return err
+	}
+	esClient.Refresh(ctx)
+
+	reporter.Report(progress.Event{
+		Type:     progress.EventSummary,
+		Docs:     updated,
+		Duration: time.Since(start),
+		Message:  fmt.Sprintf("embedded %d documents (%d failed)", updated, failed),
+	})
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+
+	"github.com/mfenderov/bam-rag/internal/eval"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	evalQueries  string
+	evalK        int
+	evalPerQuery bool
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Measure retrieval quality against labeled queries",
+	Long: `Run a golden query set against the index and report recall@k,
+MRR, and NDCG@k for BM25 and, when embeddings are enabled, hybrid search.
+
+The query file lists queries and the URLs (or document IDs) that
+should be retrieved for each:
+
+  queries:
+    - query: how to install go
+      relevant:
+        - https://go.dev/doc/install
+
+Examples:
+  bam-rag eval --queries golden.yaml
+  bam-rag eval --queries golden.yaml --k 5 --per-query`,
+	RunE: runEval,
+}
+
+func init() {
+	rootCmd.AddCommand(evalCmd)
+
+	evalCmd.Flags().StringVar(&evalQueries, "queries", "", "YAML file with labeled queries (required)")
+	evalCmd.Flags().IntVar(&evalK, "k", 10, "Number of results to evaluate per query")
+	evalCmd.Flags().BoolVar(&evalPerQuery, "per-query", false, "Show metrics for each query")
+	evalCmd.MarkFlagRequired("queries")
+}
+
+func runEval(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	queries, err := eval.LoadQueries(evalQueries)
+	if err != nil {
+		return err
+	}
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	bm25, err := eval.Run(ctx, "bm25", queries, evalK, esClient.Search)
+	if err != nil {
+		return err
+	}
+	reports := []*eval.Report{bm25}
+
+	if cfg.Embeddings.Enabled {
+		embedClient, err := newEmbeddingsClient(&cfg, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create embeddings client: %w", err)
+		}
+
+		hybridSearch := func(ctx context.Context, query string, limit int) ([]models.Document, error) {
+			embedding, err := embedClient.Embed(ctx, query)
+			if err != nil {
+				return nil, fmt.Errorf("failed to embed query: %w", err)
+			}
+			return esClient.HybridSearch(ctx, query, embedding, limit)
+		}
+
+		hybrid, err := eval.Run(ctx, "hybrid", queries, evalK, hybridSearch)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, hybrid)
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Printf("%d queries, k=%d\n", len(queries), evalK)
+	if corpus, err := esClient.Stats(ctx, 0); err != nil {
+		slog.Warn("skipping corpus size", "error", err)
+	} else {
+		fmt.Printf("Corpus: %d documents, %d words (~%d tokens)\n", corpus.DocCount, corpus.Words, corpus.Tokens)
+	}
+	fmt.Println()
+	fmt.Printf("  %-8s  %9s  %6s  %7s\n", "method", "recall@k", "MRR", "NDCG@k")
+	for _, r := range reports {
+		fmt.Printf("  %-8s  %9.3f  %6.3f  %7.3f\n", r.Method, r.Recall, r.MRR, r.NDCG)
+	}
+	if !cfg.Embeddings.Enabled {
+		fmt.Println("\n(hybrid skipped: embeddings not enabled)")
+	}
+
+	if evalPerQuery {
+		for _, r := range reports {
+			fmt.Printf("\n%s:\n", r.Method)
+			for _, q := range r.Queries {
+				rank := "-"
+				if q.FirstRank > 0 {
+					rank = fmt.Sprint(q.FirstRank)
+				}
+				fmt.Printf("  recall %.2f  ndcg %.2f  first %-3s  %s\n", q.Recall, q.NDCG, rank, q.Query)
+			}
+		}
+	}
+
+	return nil
+}
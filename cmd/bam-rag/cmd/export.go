@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/mfenderov/bam-rag/internal/export"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOut       string
+	exportBatchSize int
+	exportFormat    string
+	exportChunks    bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all indexed documents to NDJSON",
+	Long: `Stream every document in the index to a newline-delimited JSON file,
+including tags, summaries, and embeddings. Files ending in .gz are gzip-compressed.
+
+Use 'bam-rag import' to load the dump into another cluster or index.
+
+With --format langchain or llamaindex, each line is a record those frameworks
+load directly: a LangChain Document (page_content and metadata) or a
+LlamaIndex Document, with flat metadata that any vector store accepts.
+--chunks exports the retrieval chunks instead of whole pages; LlamaIndex
+chunks become TextNodes linked to their page.
+
+Examples:
+  bam-rag export --out dump.ndjson.gz
+  bam-rag export --out - | jq .url
+  bam-rag export --format langchain --chunks --out chunks.jsonl`,
+	RunE: runExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "Output file (.gz for gzip, - for stdout)")
+	exportCmd.Flags().IntVar(&exportBatchSize, "batch-size", 500, "Documents fetched per scroll request")
+	exportCmd.Flags().StringVar(&exportFormat, "format", export.FormatNDJSON, "Record format: ndjson (importable), langchain, or llamaindex")
+	exportCmd.Flags().BoolVar(&exportChunks, "chunks", false, "Export chunks instead of whole pages")
+	exportCmd.MarkFlagRequired("out")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := export.ValidFormat(exportFormat); err != nil {
+		return err
+	}
+
+	cfg := GetConfig()
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	// Closed explicitly once the export succeeds, so a failed flush fails
+	// the command; the deferred closes only clean up after errors
+	var w io.Writer = os.Stdout
+	var closers []io.Closer
+	if exportOut != "-" {
+		f, err := os.Create(exportOut)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+		closers = append(closers, f)
+	}
+
+	if strings.HasSuffix(exportOut, ".gz") {
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		w = gz
+		closers = append(closers, gz)
+	}
+
+	enc := json.NewEncoder(w)
+	count := 0
+	entries := "documents"
+	if exportChunks {
+		entries = "chunks"
+		err = esClient.ScrollChunks(ctx, exportBatchSize, func(chunk models.Chunk) error {
+			count++
+			return enc.Encode(export.Chunk(exportFormat, chunk))
+		})
+	} else {
+		err = esClient.ScrollDocuments(ctx, exportBatchSize, func(doc models.Document) error {
+			count++
+			return enc.Encode(export.Document(exportFormat, doc))
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("export failed after %d %s: %w", count, entries, err)
+	}
+
+	// The gzip stream is finished before the file under it is closed
+	for _, c := range slices.Backward(closers) {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", exportOut, err)
+		}
+	}
+
+	// Don't mix the summary into a dump written to stdout
+	if exportOut != "-" {
+		reporter.Report(progress.Event{
+			Type:    progress.EventSummary,
+			Docs:    count,
+			Message: fmt.Sprintf("Exported %d %s to %s", count, entries, exportOut),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/mfenderov/bam-rag/internal/gc"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcDryRun      bool
+	gcSkipStorage bool
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove orphaned documents from the index",
+	Long: `Find and delete indexed documents that are no longer backed by
+configuration or storage:
+
+  - the document's host (or local directory) is not a configured source
+  - no scrape in S3 contains the document's page any more
+
+Documents indexed with 'scrape --url' are not covered by a configured
+source and will be removed; add them to the config to keep them.
+
+Examples:
+  # Show what would be deleted
+  bam-rag gc --dry-run
+
+  # Only check against configured sources
+  bam-rag gc --skip-storage`,
+	RunE: runGC,
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "List orphaned documents without deleting them")
+	gcCmd.Flags().BoolVar(&gcSkipStorage, "skip-storage", false, "Do not check documents against pages stored in S3")
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	if len(cfg.Sources) == 0 {
+		return fmt.Errorf("no sources configured - refusing to treat every document as orphaned")
+	}
+
+	index, err := newPruner(&cfg)
+	if err != nil {
+		return err
+	}
+
+	var pageIDs map[string]bool
+	if !gcSkipStorage {
+		storageClient, err := newStorageClient(&cfg)
+		if err != nil {
+			return err
+		}
+		pageIDs, err = gc.StoredPageIDs(ctx, storageClient)
+		if err != nil {
+			return fmt.Errorf("failed to list stored pages: %w", err)
+		}
+	}
+
+	orphans, err := gc.Find(ctx, index, gc.NewChecker(cfg.Sources, pageIDs))
+	if err != nil {
+		return fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	deleted := 0
+	if !gcDryRun && len(orphans) > 0 {
+		ids := make([]string, len(orphans))
+		for i, o := range orphans {
+			ids[i] = o.ID
+		}
+		deleted, err = index.DeleteDocuments(ctx, ids)
+		if deleted > 0 {
+			recordAudit(progress.Event{
+				Type:    progress.EventDelete,
+				Docs:    deleted,
+				IDs:     ids,
+				Message: fmt.Sprintf("deleted orphaned documents from %s", indexName(&cfg)),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("deleted %d of %d orphans: %w", deleted, len(orphans), err)
+		}
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(map[string]interface{}{
+			"orphans": orphans,
+			"deleted": deleted,
+			"dry_run": gcDryRun,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	for _, o := range orphans {
+		fmt.Printf("  %-16s  %-22s  %s\n", o.ID, o.Reason, o.URL)
+	}
+	if gcDryRun {
+		fmt.Printf("\n%d orphaned documents (dry run, nothing deleted)\n", len(orphans))
+	} else {
+		fmt.Printf("\nDeleted %d orphaned documents\n", deleted)
+	}
+
+	return nil
+}
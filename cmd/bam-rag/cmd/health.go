@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mfenderov/bam-rag/internal/backend"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/health"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// healthAddr is where long-running commands serve health endpoints; empty
+// disables them.
+var healthAddr string
+
+// addHealthFlag registers --health-addr on a long-running command.
+func addHealthFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&healthAddr, "health-addr", "", "Serve /healthz and /readyz on this address, e.g. :8081 (empty to disable)")
+}
+
+// startHealthServer serves health endpoints in the background until ctx
+// is done, if --health-addr is set. A failing server is reported but does
+// not stop the command. Messages go to the log, since serve's stdout
+// carries the MCP protocol.
+func startHealthServer(ctx context.Context, checks ...health.Check) {
+	if healthAddr == "" {
+		return
+	}
+	go func() {
+		if err := health.Serve(ctx, healthAddr, health.Handler(checks...)); err != nil {
+			slog.Warn("health endpoints disabled", "error", err)
+		}
+	}()
+	slog.Info("serving health endpoints", "addr", healthAddr)
+}
+
+// indexCheck checks that the configured search backend answers.
+func indexCheck(cfg *config.Config, index backend.SearchBackend) health.Check {
+	name := cfg.Backend.Type
+	return health.Check{Name: name, Check: func(ctx context.Context) error {
+		if !index.Ping(ctx) {
+			return fmt.Errorf("%s is unreachable", name)
+		}
+		return nil
+	}}
+}
+
+// ingestionChecks checks the dependencies of ingestion: the search
+// backend, storage, and the sockets of the enabled models.
+func ingestionChecks(cfg *config.Config, index backend.SearchBackend, storageClient *storage.Client) []health.Check {
+	checks := []health.Check{
+		indexCheck(cfg, index),
+		{Name: "storage", Check: storageClient.BucketExists},
+	}
+	if cfg.Embeddings.Enabled {
+		checks = append(checks, health.Check{Name: "embeddings", Check: health.Socket(cfg.Embeddings.SocketPath)})
+	}
+	if cfg.LLM.Enabled {
+		checks = append(checks, health.Check{Name: "llm", Check: health.Socket(cfg.LLM.SocketPath)})
+	}
+	return checks
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importIn        string
+	importBatchSize int
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import documents from an NDJSON export",
+	Long: `Load documents written by 'bam-rag export' into the configured index.
+The index is created if needed; existing documents with the same ID are replaced.
+Files ending in .gz are decompressed.
+
+Examples:
+  bam-rag import --in dump.ndjson.gz
+  BAMRAG_ELASTICSEARCH_INDEX=restored bam-rag import --in dump.ndjson.gz`,
+	RunE: runImport,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&importIn, "in", "", "Input file (.gz for gzip, - for stdin)")
+	importCmd.Flags().IntVar(&importBatchSize, "batch-size", 500, "Documents per bulk request")
+	importCmd.MarkFlagRequired("in")
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+	if err := esClient.CreateIndex(ctx); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if importIn != "-" {
+		f, err := os.Open(importIn)
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if strings.HasSuffix(importIn, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	imported, err := importDocuments(ctx, esClient, r, importBatchSize)
+	if err != nil {
+		return fmt.Errorf("import failed after %d documents: %w", imported, err)
+	}
+
+	esClient.Refresh(ctx)
+
+	reporter.Report(progress.Event{
+		Type:    progress.EventSummary,
+		Docs:    imported,
+		Message: fmt.Sprintf("Imported %d documents into %s", imported, cfg.Elasticsearch.Index),
+	})
+	return nil
+}
+
+// importDocuments reads NDJSON documents from r and bulk-indexes them.
+func importDocuments(ctx context.Context, esClient *elasticsearch.Client, r io.Reader, batchSize int) (int, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	batch := make([]models.Document, 0, batchSize)
+	imported := 0
+
+	flush := func() error {
+		n, err := esClient.BulkIndex(ctx, batch)
+		imported += n
+		batch = batch[:0]
+		if err == nil {
+			reporter.Report(progress.Event{Type: progress.EventDocument, Current: imported})
+		}
+		return err
+	}
+
+	for {
+		var doc models.Document
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("invalid document: %w", err)
+		}
+
+		batch = append(batch, doc)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+
+	return imported, flush()
+}
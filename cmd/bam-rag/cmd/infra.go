@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/infra"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	infraPorts       = infra.DefaultPorts()
+	infraWait        time.Duration
+	infraModels      bool
+	infraSocketPath  string
+	infraVolumes     bool
+	infraWriteConfig bool
+)
+
+var infraCmd = &cobra.Command{
+	Use:   "infra",
+	Short: "Manage the local Elasticsearch and MinIO services",
+	Long: `Start, stop, and inspect the services bam-rag needs locally,
+Elasticsearch and MinIO, using Docker Compose with a definition built into
+the binary. Docker must be installed; no checkout of the repository is
+needed.`,
+}
+
+var infraUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Start the local services and wait until they are ready",
+	Long: `Start Elasticsearch and MinIO, wait until both answer, and write a
+config file pointing at them if there is none yet.
+
+With --models, the configured Docker Model Runner models are pulled too, and
+the written config enables embeddings and LLM enrichment.
+
+Examples:
+  # From nothing to a searchable corpus
+  bam-rag infra up
+  bam-rag scrape --url https://go.dev/doc/
+
+  # With models, on other ports
+  bam-rag infra up --models --es-port 19200 --storage-port 19002`,
+	RunE: runInfraUp,
+}
+
+var infraDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Stop the local services",
+	Long: `Stop and remove the containers started by 'infra up'. Indexed and
+scraped data is kept for the next 'infra up' unless --volumes is given.`,
+	RunE: runInfraDown,
+}
+
+var infraStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the local services",
+	RunE:  runInfraStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(infraCmd)
+	infraCmd.AddCommand(infraUpCmd, infraDownCmd, infraStatusCmd)
+
+	pf := infraCmd.PersistentFlags()
+	pf.IntVar(&infraPorts.Elasticsearch, "es-port", infraPorts.Elasticsearch, "Host port of Elasticsearch")
+	pf.IntVar(&infraPorts.Storage, "storage-port", infraPorts.Storage, "Host port of the MinIO S3 API")
+	pf.IntVar(&infraPorts.Console, "console-port", infraPorts.Console, "Host port of the MinIO console")
+
+	f := infraUpCmd.Flags()
+	f.DurationVar(&infraWait, "wait", 3*time.Minute, "How long to wait for the services to become ready")
+	f.BoolVar(&infraModels, "models", false, "Also pull the configured Docker Model Runner models")
+	f.StringVar(&infraSocketPath, "socket-path", "", "Docker Model Runner socket written to the config with --models (default: detected)")
+	f.BoolVar(&infraWriteConfig, "write-config", true, "Write a config file for the services if none exists")
+
+	infraDownCmd.Flags().BoolVar(&infraVolumes, "volumes", false, "Also delete the indexed and scraped data")
+}
+
+// newStack creates the stack managed by the infra commands.
+func newStack(cmd *cobra.Command) *infra.Stack {
+	return &infra.Stack{Ports: infraPorts, Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()}
+}
+
+func runInfraUp(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+	out := cmd.OutOrStdout()
+	stack := newStack(cmd)
+
+	if err := stack.Up(ctx); err != nil {
+		return err
+	}
+
+	endpoints := infraPorts.Endpoints()
+	fmt.Fprintln(out, "Waiting for services...")
+	waitCtx, cancel := context.WithTimeout(ctx, infraWait)
+	defer cancel()
+	if err := infra.WaitReady(waitCtx, endpoints, 2*time.Second); err != nil {
+		return fmt.Errorf("%w (see 'bam-rag infra status')", err)
+	}
+	fmt.Fprintf(out, "✓ Elasticsearch ready at %s\n", endpoints.Elasticsearch)
+	fmt.Fprintf(out, "✓ MinIO ready at %s (console: %s)\n", endpoints.Storage, endpoints.Console)
+
+	socketPath := ""
+	if infraModels {
+		if err := stack.PullModels(ctx, cfg.Embeddings.Model, cfg.LLM.Model); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✓ Models %s and %s pulled\n", cfg.Embeddings.Model, cfg.LLM.Model)
+		if socketPath = infraSocketPath; socketPath == "" {
+			socketPath = modelRunnerSocket()
+		}
+	}
+
+	if !infraWriteConfig {
+		return nil
+	}
+	return writeInfraConfig(out, &cfg, endpoints, socketPath)
+}
+
+// writeInfraConfig writes a config file pointing at endpoints if none
+// exists, or warns if the loaded config points elsewhere.
+func writeInfraConfig(out io.Writer, cfg *config.Config, endpoints infra.Endpoints, socketPath string) error {
+	if used := viper.ConfigFileUsed(); used != "" {
+		if _, err := os.Stat(used); err == nil {
+			if len(cfg.Elasticsearch.Addresses) == 0 || cfg.Elasticsearch.Addresses[0] != endpoints.Elasticsearch || cfg.Storage.Endpoint != endpoints.Storage {
+				fmt.Fprintf(out, "Note: %s points elsewhere; set elasticsearch.addresses to %s and storage.endpoint to %s to use these services\n",
+					used, endpoints.Elasticsearch, endpoints.Storage)
+			}
+			return nil
+		}
+	}
+
+	path := cfgFile
+	if path == "" {
+		path = "config/config.yaml"
+	}
+	opts := config.DefaultInitOptions()
+	opts.ESAddress = endpoints.Elasticsearch
+	opts.StorageEndpoint = endpoints.Storage
+	opts.AccessKeyID = infra.AccessKeyID
+	opts.SecretAccessKey = infra.SecretAccessKey
+	opts.SocketPath = socketPath
+
+	content, err := config.RenderTemplate(opts)
+	if err != nil {
+		return fmt.Errorf("failed to render config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Fprintf(out, "Wrote %s\n", path)
+	return nil
+}
+
+// modelRunnerSocket returns the Docker socket Model Runner is reached
+// through: Docker Desktop's per-user socket if it exists, the system one
+// otherwise.
+func modelRunnerSocket() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		desktop := filepath.Join(home, ".docker", "run", "docker.sock")
+		if _, err := os.Stat(desktop); err == nil {
+			return desktop
+		}
+	}
+	return "/var/run/docker.sock"
+}
+
+func runInfraDown(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return newStack(cmd).Down(ctx, infraVolumes)
+}
+
+func runInfraStatus(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return newStack(cmd).Status(ctx)
+}
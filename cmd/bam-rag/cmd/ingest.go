@@ -5,17 +5,25 @@ import (
 	"fmt"
 	"log/slog"
 	"os/signal"
+	"slices"
 	"syscall"
 
-	"github.com/mfenderov/bam-rag/internal/elasticsearch"
-	"github.com/mfenderov/bam-rag/internal/embeddings"
-	"github.com/mfenderov/bam-rag/internal/ingestion"
-	"github.com/mfenderov/bam-rag/internal/llm"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/events"
+	"github.com/mfenderov/bam-rag/internal/progress"
 	"github.com/mfenderov/bam-rag/internal/storage"
 	"github.com/spf13/cobra"
 )
 
-var ingestPrefix string
+var (
+	ingestPrefix string
+	ingestAll    bool
+	ingestLatest bool
+	ingestForce  bool
+	ingestGroup  string
+	ingestFollow bool
+	ingestFull   bool
+)
 
 var ingestCmd = &cobra.Command{
 	Use:   "ingest",
@@ -25,17 +33,46 @@ var ingestCmd = &cobra.Command{
 Use this command to re-run ingestion on existing scraped content,
 or to index scrapes that were created with --no-ingest.
 
+Each ingested prefix is marked in S3, so --all and --latest skip
+prefixes that have already been indexed unless --force is given.
+An interrupted ingestion resumes from where it stopped when run again.
+
 Examples:
   # Ingest a specific scrape by prefix
-  bam-rag ingest --prefix scrapes/go.dev/2024-12-04T17-30-00-abc123`,
+  bam-rag ingest --prefix scrapes/go.dev/2024-12-04T17-30-00-abc123
+
+  # Ingest every scrape that has not been ingested yet
+  bam-rag ingest --all
+
+  # Ingest the most recent scrape of each source
+  bam-rag ingest --latest
+
+  # Ingest pending scrapes of one source group
+  bam-rag ingest --all --group kubernetes
+
+  # Ingest scrapes as they are published on the event bus (nats, kafka, or sqs);
+  # see 'bam-rag worker' for a dedicated ingestion machine
+  bam-rag ingest --follow`,
 	RunE: runIngest,
 }
 
 func init() {
 	rootCmd.AddCommand(ingestCmd)
 
-	ingestCmd.Flags().StringVar(&ingestPrefix, "prefix", "", "S3 prefix to ingest (required)")
-	ingestCmd.MarkFlagRequired("prefix")
+	ingestCmd.Flags().StringVar(&ingestPrefix, "prefix", "", "S3 prefix to ingest")
+	ingestCmd.Flags().BoolVar(&ingestAll, "all", false, "Ingest every scrape that has not been ingested yet")
+	ingestCmd.Flags().BoolVar(&ingestLatest, "latest", false, "Ingest the latest scrape of each source")
+	ingestCmd.Flags().BoolVar(&ingestForce, "force", false, "Re-ingest scrapes that were already ingested")
+	ingestCmd.Flags().StringVar(&ingestGroup, "group", "", "With --all or --latest, only ingest scrapes of sources in this group")
+	ingestCmd.Flags().BoolVar(&ingestFull, "full", false, "Reprocess every page, including those unchanged since they were indexed or indexed by an interrupted run")
+	ingestCmd.Flags().BoolVar(&ingestFollow, "follow", false, "Keep ingesting scrapes published on the event bus until interrupted")
+	ingestCmd.MarkFlagsMutuallyExclusive("prefix", "all", "latest", "follow")
+	ingestCmd.MarkFlagsMutuallyExclusive("prefix", "group")
+	ingestCmd.MarkFlagsMutuallyExclusive("follow", "group")
+	ingestCmd.MarkFlagsOneRequired("prefix", "all", "latest", "follow")
+
+	ingestCmd.RegisterFlagCompletionFunc("prefix", completeScrapePrefixes)
+	ingestCmd.RegisterFlagCompletionFunc("group", completeGroupNames)
 }
 
 func runIngest(cmd *cobra.Command, args []string) error {
@@ -43,81 +80,165 @@ func runIngest(cmd *cobra.Command, args []string) error {
 	defer stop()
 
 	cfg := GetConfig()
-	slog.Debug("ingest command starting", "prefix", ingestPrefix)
+	slog.Debug("ingest command starting", "prefix", ingestPrefix, "all", ingestAll, "latest", ingestLatest)
 
-	if cfg.Storage.Endpoint == "" {
-		return fmt.Errorf("storage not configured - check config file")
+	storageClient, err := newStorageClient(&cfg)
+	if err != nil {
+		return err
 	}
 
-	// Create storage client
-	storageClient, err := storage.New(storage.Config{
-		Endpoint:        cfg.Storage.Endpoint,
-		Bucket:          cfg.Storage.Bucket,
-		AccessKeyID:     cfg.Storage.AccessKeyID,
-		SecretAccessKey: cfg.Storage.SecretAccessKey,
-		UseSSL:          cfg.Storage.UseSSL,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create storage client: %w", err)
+	if ingestFollow {
+		return followScrapeEvents(ctx, &cfg, storageClient)
 	}
 
-	// Create ES client
-	esClient, err := elasticsearch.New(elasticsearch.Config{
-		Addresses: cfg.Elasticsearch.Addresses,
-		Index:     cfg.Elasticsearch.Index,
-		Username:  cfg.Elasticsearch.Username,
-		Password:  cfg.Elasticsearch.Password,
-	})
+	prefixes, err := selectIngestPrefixes(ctx, &cfg, storageClient)
 	if err != nil {
-		return fmt.Errorf("failed to create ES client: %w", err)
+		return err
+	}
+	if len(prefixes) == 0 {
+		reporter.Report(progress.Event{Type: progress.EventInfo, Message: "nothing to ingest"})
+		return nil
 	}
 
-	// Create optional embeddings client
-	var embedClient *embeddings.Client
-	if cfg.Embeddings.Enabled {
-		embedClient, err = embeddings.New(embeddings.Config{
-			SocketPath: cfg.Embeddings.SocketPath,
-			Model:      cfg.Embeddings.Model,
-		})
+	// Each scrape is ingested with the overrides of the source that produced it
+	engines := newSourceEngines(&cfg, storageClient)
+
+	// Prefixes not started before an interrupt are left for the next run
+	interrupted := func(rest []string) {
+		for _, prefix := range rest {
+			unfinished.add("%s: not ingested; run 'bam-rag ingest --prefix %s'", prefix, prefix)
+		}
+	}
+
+	var failed []string
+	for i, prefix := range prefixes {
+		if ctx.Err() != nil {
+			interrupted(prefixes[i:])
+			break
+		}
+
+		meta, err := storageClient.GetMetadata(ctx, prefix)
+		if err != nil {
+			if ctx.Err() != nil {
+				interrupted(prefixes[i:])
+				break
+			}
+			return fmt.Errorf("failed to read metadata for %s: %w", prefix, err)
+		}
+
+		reporter.Report(progress.Event{Type: progress.EventIngestStart, Prefix: prefix})
+
+		result, err := engines.ingest(ctx, prefix, meta.Source)
 		if err != nil {
-			return fmt.Errorf("failed to create embeddings client: %w", err)
+			if ctx.Err() != nil {
+				interrupted(prefixes[i:])
+				break
+			}
+			// Keep going; the failed job stays recorded for 'jobs retry'
+			reporter.Report(progress.Event{Type: progress.EventError, Prefix: prefix, Message: err.Error()})
+			failed = append(failed, prefix)
+			continue
 		}
-		slog.Info("embeddings enabled", "model", cfg.Embeddings.Model)
+
+		reportIngestResult(ctx, result)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("ingestion of %d of %d prefixes failed - see 'bam-rag jobs'", len(failed), len(prefixes))
 	}
+	return nil
+}
 
-	// Create optional LLM client
-	var llmClient *llm.Client
-	if cfg.LLM.Enabled {
-		llmClient, err = llm.New(llm.Config{
-			SocketPath: cfg.LLM.SocketPath,
-			Model:      cfg.LLM.Model,
-		})
+// selectIngestPrefixes resolves --prefix, --all, or --latest into the
+// prefixes to ingest, narrowed to --group and dropping already-ingested
+// ones unless --force is set.
+func selectIngestPrefixes(ctx context.Context, cfg *config.Config, storageClient *storage.Client) ([]string, error) {
+	if ingestPrefix != "" {
+		return []string{ingestPrefix}, nil
+	}
+
+	scrapes, err := storageClient.ListScrapes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scrapes: %w", err)
+	}
+
+	// Scrapes are told apart by source rather than host, since sources
+	// can share a host
+	if ingestGroup != "" || ingestLatest {
+		sources := cfg.Sources
+		if ingestGroup != "" {
+			if sources, err = selectSources(cfg, "", ingestGroup); err != nil {
+				return nil, err
+			}
+		}
+		bySource, err := sourceScrapes(ctx, storageClient, sources)
 		if err != nil {
-			return fmt.Errorf("failed to create LLM client: %w", err)
+			return nil, err
 		}
-		slog.Info("LLM enrichment enabled", "model", cfg.LLM.Model)
+		var selected []storage.ScrapeInfo
+		claimed := make(map[string]bool)
+		for _, source := range sources {
+			own := bySource[source.Name]
+			for _, s := range own {
+				claimed[s.Prefix] = true
+			}
+			if ingestLatest && len(own) > 0 {
+				own = own[len(own)-1:]
+			}
+			selected = append(selected, own...)
+		}
+		// Scrapes of no configured source, such as scrape --url, keep the
+		// latest per host
+		if ingestGroup == "" {
+			others := slices.DeleteFunc(scrapes, func(s storage.ScrapeInfo) bool { return claimed[s.Prefix] })
+			selected = append(selected, storage.LatestPerHost(others)...)
+		}
+		scrapes = selected
+	}
+	if !ingestForce {
+		for _, s := range scrapes {
+			if s.Ingested {
+				slog.Debug("skipping ingested scrape", "prefix", s.Prefix)
+			}
+		}
+		scrapes = storage.Pending(scrapes)
 	}
 
-	// Create ingestion engine
-	engine := ingestion.New(storageClient, esClient, embedClient, llmClient)
+	prefixes := make([]string, len(scrapes))
+	for i, s := range scrapes {
+		prefixes[i] = s.Prefix
+	}
+	return prefixes, nil
+}
 
-	fmt.Printf("Ingesting: %s\n", ingestPrefix)
+// followScrapeEvents ingests scrapes announced on the event bus until ctx is
+// cancelled. Scrapes published while no consumer was running are delivered
+// when it starts.
+func followScrapeEvents(ctx context.Context, cfg *config.Config, storageClient *storage.Client) error {
+	if !externalBus(cfg) {
+		return fmt.Errorf("--follow requires an external event bus - set events.bus in config")
+	}
 
-	result, err := engine.Ingest(ctx, ingestPrefix)
+	bus, err := newEventBus(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("ingestion failed: %w", err)
+		return err
 	}
+	defer bus.Close()
 
-	fmt.Printf("\nIngestion complete:\n")
-	fmt.Printf("  Docs indexed: %d\n", result.DocsIndexed)
-	fmt.Printf("  Duration: %v\n", result.Duration)
+	reporter.Report(progress.Event{Type: progress.EventInfo, Message: fmt.Sprintf("Waiting for scrape events on %s bus (Ctrl+C to stop)...", cfg.Events.Bus)})
 
-	if len(result.Errors) > 0 {
-		fmt.Printf("  Warnings: %d\n", len(result.Errors))
-		for _, e := range result.Errors {
-			fmt.Printf("    - %s\n", e)
-		}
+	var tally ingestTally
+	err = events.SubscribeScrapeComplete(ctx, bus, ingestScrapeEvents(bus, newSourceEngines(cfg, storageClient), &tally, true))
+	if err != nil {
+		return fmt.Errorf("event subscription failed: %w", err)
 	}
 
+	reporter.Report(progress.Event{
+		Type:     progress.EventSummary,
+		Docs:     tally.docs,
+		Duration: tally.duration,
+		Stages:   tally.stages,
+		Message:  fmt.Sprintf("\nTotal: %d docs indexed in %v", tally.docs, tally.duration),
+	})
 	return nil
 }
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var inspectContent bool
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <id|url>",
+	Short: "Show everything stored for a single document",
+	Long: `Show a single indexed document with all stored fields: tags, summary,
+embedding dimensions, and the heading sections its content splits into.
+
+Useful for debugging why a query does or does not retrieve a page.
+
+Examples:
+  # By document ID
+  bam-rag inspect 3f2a9c1e5b7d4a60
+
+  # By URL (the ID is derived from it)
+  bam-rag inspect https://go.dev/doc/install
+
+  # Include the full markdown content
+  bam-rag inspect https://go.dev/doc/install --content`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInspect,
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().BoolVar(&inspectContent, "content", false, "Print the full document content")
+}
+
+// inspection is the inspect command's view of a document.
+// The embedding vector is replaced by its dimensions.
+type inspection struct {
+	ID            string              `json:"id"`
+	URL           string              `json:"url"`
+	Title         string              `json:"title"`
+	ContentType   string              `json:"content_type,omitempty"`
+	Language      string              `json:"language,omitempty"`
+	ScrapedAt     time.Time           `json:"scraped_at"`
+	Tags          []string            `json:"tags"`
+	Summary       string              `json:"summary"`
+	EmbeddingDims int                 `json:"embedding_dims"`
+	ContentBytes  int                 `json:"content_bytes"`
+	Sections      []markdown.Section  `json:"sections"`
+	Attachments   []models.Attachment `json:"attachments,omitempty"`
+	Content       string              `json:"content,omitempty"`
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	index, err := newSearchBackend(&cfg)
+	if err != nil {
+		return err
+	}
+
+	id := args[0]
+	if strings.Contains(id, "://") {
+		id = models.GenerateDocumentID(id)
+	}
+
+	doc, err := index.GetDocument(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get document: %w", err)
+	}
+	if doc == nil {
+		return fmt.Errorf("document not found: %s", args[0])
+	}
+
+	info := inspection{
+		ID:            doc.ID,
+		URL:           doc.URL,
+		Title:         doc.Title,
+		ContentType:   doc.ContentType,
+		Language:      doc.Language,
+		ScrapedAt:     doc.ScrapedAt,
+		Tags:          doc.Tags,
+		Summary:       doc.Summary,
+		EmbeddingDims: len(doc.Embedding),
+		ContentBytes:  len(doc.Content),
+		Sections:      markdown.Sections(doc.Content),
+		Attachments:   doc.Attachments,
+	}
+	if inspectContent {
+		info.Content = doc.Content
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Printf("ID:          %s\n", info.ID)
+	fmt.Printf("URL:         %s\n", info.URL)
+	fmt.Printf("Title:       %s\n", info.Title)
+	if info.ContentType != "" {
+		fmt.Printf("Type:        %s\n", info.ContentType)
+	}
+	if info.Language != "" {
+		fmt.Printf("Language:    %s\n", info.Language)
+	}
+	fmt.Printf("Scraped:     %s\n", info.ScrapedAt.Local().Format(time.DateTime))
+	fmt.Printf("Content:     %s\n", formatBytes(int64(info.ContentBytes)))
+	if info.EmbeddingDims > 0 {
+		fmt.Printf("Embedding:   %d dims\n", info.EmbeddingDims)
+	} else {
+		fmt.Printf("Embedding:   none\n")
+	}
+	fmt.Printf("Tags:        %s\n", strings.Join(info.Tags, ", "))
+	fmt.Printf("Summary:     %s\n", info.Summary)
+
+	fmt.Printf("\nSections (%d):\n", len(info.Sections))
+	for _, s := range info.Sections {
+		heading := s.Heading
+		if s.Level == 0 {
+			heading = "(preamble)"
+		}
+		fmt.Printf("  L%-5d %7s  %s%s\n", s.Line, formatBytes(int64(s.End-s.Start)), strings.Repeat("  ", max(s.Level-1, 0)), heading)
+	}
+
+	if len(info.Attachments) > 0 {
+		fmt.Printf("\nAttachments (%d):\n", len(info.Attachments))
+		for _, a := range info.Attachments {
+			fmt.Printf("  %-6s %s  %s\n", a.Kind, a.URL, a.Caption)
+		}
+	}
+
+	if inspectContent {
+		fmt.Printf("\n%s\n", info.Content)
+	}
+
+	return nil
+}
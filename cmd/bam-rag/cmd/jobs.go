@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var jobsStatus string
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "List ingestion jobs",
+	Long: `List ingestion jobs recorded in S3. Every ingested prefix gets a job
+that tracks its status (pending, running, failed, done), attempts, and
+last error, so failures survive the process that hit them.
+
+Failed attempts are retried with backoff (jobs.max_attempts and
+jobs.backoff in config). Jobs that still failed, or were interrupted,
+can be run again with 'jobs retry'.
+
+Examples:
+  # Show every job
+  bam-rag jobs
+
+  # Show failed jobs
+  bam-rag jobs --status failed`,
+	RunE: runJobs,
+}
+
+var jobsRetryCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "Run failed and interrupted ingestion jobs again",
+	Long: `Run every job that is pending, failed, or was left running by a
+process that exited more than an hour ago.`,
+	RunE: runJobsRetry,
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsRetryCmd)
+
+	jobsCmd.Flags().StringVar(&jobsStatus, "status", "", "Only list jobs with this status (pending, running, failed, done)")
+}
+
+func runJobs(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	storageClient, err := newStorageClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := storageClient.ListJobs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+	if jobsStatus != "" {
+		jobs = slices.DeleteFunc(jobs, func(j storage.Job) bool { return string(j.Status) != jobsStatus })
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(map[string]interface{}{"jobs": jobs}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("No jobs")
+		return nil
+	}
+	for _, j := range jobs {
+		fmt.Printf("  %-8s %d  %s  %s\n", j.Status, j.Attempts, j.UpdatedAt.Local().Format(time.DateTime), j.Prefix)
+		if j.LastError != "" && j.Status != storage.JobDone {
+			fmt.Printf("           %s\n", j.LastError)
+		}
+	}
+	return nil
+}
+
+func runJobsRetry(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	storageClient, err := newStorageClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	engines := newSourceEngines(&cfg, storageClient)
+	jobs, err := engines.jobs.Unfinished(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		reporter.Report(progress.Event{Type: progress.EventInfo, Message: "no jobs to retry"})
+		return nil
+	}
+
+	failed := 0
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		reporter.Report(progress.Event{Type: progress.EventIngestStart, Prefix: job.Prefix})
+
+		result, err := engines.ingest(ctx, job.Prefix, job.Source)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			reporter.Report(progress.Event{Type: progress.EventError, Prefix: job.Prefix, Message: err.Error()})
+			failed++
+			continue
+		}
+
+		reportIngestResult(ctx, result)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs failed again", failed, len(jobs))
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/openai"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	openaiVectorStore string
+	openaiName        string
+	openaiSource      string
+	openaiBatchSize   int
+	openaiNoWait      bool
+	openaiAccess      []string
+	openaiRestricted  bool
+)
+
+// openaiPollInterval is how often a file batch being processed is checked.
+const openaiPollInterval = 5 * time.Second
+
+var openaiUploadCmd = &cobra.Command{
+	Use:   "openai-upload",
+	Short: "Upload indexed documents to an OpenAI vector store",
+	Long: `Upload every indexed page to an OpenAI vector store as a markdown file led by
+front matter with its title, URL, source, tags, and summary, so the same corpus
+can back Assistants and Responses agents through file_search alongside the MCP
+server.
+
+Files are added to the store in batches, which OpenAI chunks and embeds; the
+command waits for them unless --no-wait is given. Requires openai.api_key in
+config (or BAMRAG_OPENAI_API_KEY).
+
+Pages restricted by access labels are left out, since the vector store does
+not enforce them; --access-label uploads those restricted to the given labels,
+and --include-restricted uploads every page.
+
+Examples:
+  # Create a vector store named after the index and fill it
+  bam-rag openai-upload
+
+  # Add one source's pages to an existing store
+  bam-rag openai-upload --vector-store vs_abc123 --source go-docs
+
+  # Also upload pages restricted to the internal label
+  bam-rag openai-upload --access-label internal`,
+	RunE: runOpenAIUpload,
+}
+
+func init() {
+	rootCmd.AddCommand(openaiUploadCmd)
+
+	openaiUploadCmd.Flags().StringVar(&openaiVectorStore, "vector-store", "", "ID of the vector store to add files to (default: create one)")
+	openaiUploadCmd.Flags().StringVar(&openaiName, "name", "", "Name of the vector store created (default: the index name)")
+	openaiUploadCmd.Flags().StringVar(&openaiSource, "source", "", "Only upload pages of this config source")
+	openaiUploadCmd.Flags().IntVar(&openaiBatchSize, "batch-size", 50, "Documents fetched per scroll request")
+	openaiUploadCmd.Flags().BoolVar(&openaiNoWait, "no-wait", false, "Return once files are added, without waiting for OpenAI to process them")
+	openaiUploadCmd.Flags().StringSliceVar(&openaiAccess, "access-label", nil, "Also upload pages restricted to these access labels")
+	openaiUploadCmd.Flags().BoolVar(&openaiRestricted, "include-restricted", false, "Upload pages whatever their access labels")
+}
+
+func runOpenAIUpload(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	if cfg.OpenAI.APIKey == "" {
+		return fmt.Errorf("openai.api_key is required - set it in config or BAMRAG_OPENAI_API_KEY")
+	}
+	client, err := openai.New(openai.Config{APIKey: cfg.OpenAI.APIKey, BaseURL: cfg.OpenAI.BaseURL})
+	if err != nil {
+		return err
+	}
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	storeID := openaiVectorStore
+	if storeID == "" {
+		name := openaiName
+		if name == "" {
+			name = cfg.Elasticsearch.Index
+		}
+		storeID, err = client.CreateVectorStore(ctx, name)
+		if err != nil {
+			return err
+		}
+		reporter.Report(progress.Event{Type: progress.EventInfo, Message: fmt.Sprintf("Created vector store %s (%s)", name, storeID)})
+	}
+
+	// Anyone with access to the store can read its files
+	access := elasticsearch.Filter{EnforceAccess: !openaiRestricted, AccessLabels: openaiAccess}
+
+	start := time.Now()
+	var fileIDs []string
+	failed, restricted := 0, 0
+	err = esClient.ScrollDocuments(ctx, openaiBatchSize, func(doc models.Document) error {
+		if openaiSource != "" && doc.Source.Name != openaiSource {
+			return nil
+		}
+		if !access.Allows(doc.AccessLabels) {
+			restricted++
+			return nil
+		}
+		fileID, err := client.UploadFile(ctx, openai.FileName(doc), openai.Markdown(doc))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failed++
+			slog.Warn("failed to upload document", "url", doc.URL, "error", err)
+			reporter.Report(progress.Event{Type: progress.EventWarning, URL: doc.URL, Message: err.Error()})
+			return nil
+		}
+		fileIDs = append(fileIDs, fileID)
+		reporter.Report(progress.Event{Type: progress.EventDocument, URL: doc.URL, Current: len(fileIDs)})
+		return nil
+	}, "id", "url", "title", "content", "source", "language", "scraped_at", "tags", "summary", "access_labels")
+	if err != nil {
+		return fmt.Errorf("upload failed after %d documents: %w", len(fileIDs), err)
+	}
+
+	// Uploaded files are added even if a batch before them failed
+	completed := 0
+	for batchStart := 0; batchStart < len(fileIDs); batchStart += openai.MaxBatchFiles {
+		ids := fileIDs[batchStart:min(batchStart+openai.MaxBatchFiles, len(fileIDs))]
+		batch, err := client.CreateFileBatch(ctx, storeID, ids)
+		if err != nil {
+			return err
+		}
+		if openaiNoWait {
+			continue
+		}
+		batch, err = client.WaitFileBatch(ctx, storeID, batch, openaiPollInterval)
+		if err != nil {
+			return err
+		}
+		completed += batch.FileCounts.Completed
+		failed += batch.FileCounts.Failed + batch.FileCounts.Cancelled
+	}
+
+	message := fmt.Sprintf("Uploaded %d documents to vector store %s (%d failed)", len(fileIDs), storeID, failed)
+	if !openaiNoWait {
+		message = fmt.Sprintf("Added %d documents to vector store %s (%d failed)", completed, storeID, failed)
+	}
+	if restricted > 0 {
+		message += fmt.Sprintf(", skipped %d restricted by access labels", restricted)
+	}
+	reporter.Report(progress.Event{
+		Type:     progress.EventSummary,
+		Docs:     len(fileIDs),
+		Duration: time.Since(start),
+		Message:  message,
+	})
+	return nil
+}
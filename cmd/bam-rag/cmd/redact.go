@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/processor"
+)
+
+// redactor masks sensitive strings in pages before they are stored and
+// indexed; nil when redaction is disabled.
+var redactor *processor.Redactor
+
+// newRedactor creates the configured redactor, or nil if redaction is
+// disabled.
+func newRedactor(c *config.Config) (*processor.Redactor, error) {
+	if !c.Redaction.Enabled {
+		return nil, nil
+	}
+	names := c.Redaction.Detectors
+	if len(names) == 0 {
+		names = processor.BuiltinDetectors()
+	}
+	var detectors []processor.Detector
+	for _, name := range names {
+		d, ok := processor.BuiltinDetector(name)
+		if !ok {
+			return nil, fmt.Errorf("redaction.detectors: unknown detector %q", name)
+		}
+		detectors = append(detectors, d)
+	}
+	for _, p := range c.Redaction.Patterns {
+		d, err := processor.NewDetector(p.Name, p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction.patterns: %w", err)
+		}
+		detectors = append(detectors, d)
+	}
+	return processor.NewRedactor(detectors), nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"log/slog"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/spf13/viper"
+)
+
+// watchConfig reloads the config file whenever it changes. Each reload is
+// validated; invalid reloads are rejected and the current configuration is
+// kept. apply is called with every accepted configuration and may reject it
+// by returning an error, e.g. if a client cannot be rebuilt.
+// Does nothing when no config file is in use.
+func watchConfig(apply func(next config.Config) error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		slog.Debug("no config file in use, hot reload disabled")
+		return
+	}
+
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		// viper keeps its previous values when the file fails to parse,
+		// so check the file on its own before decoding
+		check := viper.New()
+		check.SetConfigFile(path)
+		err := check.ReadInConfig()
+
+		// The re-read replaced the merged profile values; merge them again
+		if err == nil {
+			err = applyProfile()
+		}
+
+		var next config.Config
+		if err == nil {
+			next, err = decodeConfig()
+		}
+		if err == nil {
+			err = next.Validate()
+		}
+		if err != nil {
+			slog.Error("config reload rejected, keeping current configuration", "file", e.Name, "error", err)
+			return
+		}
+
+		if err := apply(next); err != nil {
+			slog.Error("config reload rejected, keeping current configuration", "file", e.Name, "error", err)
+			return
+		}
+
+		setConfig(next)
+		slog.Info("config reloaded", "file", e.Name, "sources", len(next.Sources))
+	})
+	viper.WatchConfig()
+
+	slog.Info("watching config for changes", "file", path)
+}
@@ -1,26 +1,50 @@
 package cmd
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/mfenderov/bam-rag/internal/audit"
 	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/progress"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
-	verbose bool
-	cfg     config.Config
+	cfgFile      string
+	profile      string
+	namespace    string
+	cfgErr       error // Fatal config loading error, reported before any command runs
+	verbose      bool
+	outputFormat string
+	cfg          config.Config
+	cfgMu        sync.RWMutex // Guards cfg, which is replaced on config reload
+	reporter     progress.Reporter
 )
 
 // GetConfig returns the loaded configuration.
 func GetConfig() config.Config {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
 	return cfg
 }
 
+// setConfig replaces the loaded configuration.
+func setConfig(c config.Config) {
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	cfg = c
+}
+
+// jsonOutput reports whether machine-readable output was requested.
+func jsonOutput() bool {
+	return outputFormat == "json"
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "bam-rag",
 	Short: "BAM-RAG: A documentation retrieval system",
@@ -30,17 +54,59 @@ chunks by headers, stores in Elasticsearch, and provides MCP tools for retrieval
 Commands:
   scrape  Scrape and index documentation from configured sources
   serve   Start the MCP server for document retrieval`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if cfgErr != nil {
+			return cfgErr
+		}
+		var err error
+		reporter, err = progress.New(outputFormat, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+
+		c := GetConfig()
+		if err := setupTracing(&c); err != nil {
+			return err
+		}
+
+		// Audited events are recorded alongside the normal output
+		if c.Audit.Enabled && cmd != auditCmd {
+			if store, err := newAuditStore(&c); err != nil {
+				slog.Warn("audit log disabled", "error", err)
+			} else {
+				auditLog = audit.NewLog(store, cmd.CommandPath())
+				auditLog.SetActor(currentActor())
+				reporter = progress.Tee(reporter, auditLog)
+			}
+		}
+
+		slowOps = newSlowOps(&c)
+		if redactor, err = newRedactor(&c); err != nil {
+			return err
+		}
+		startPprofServer()
+		return nil
+	},
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	reportSlowOps()
+	reportUnfinished()
+	closeAuditLog(err)
+	closeTracing()
+	return err
 }
 
 func init() {
 	cobra.OnInitialize(initConfig, initLogger)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&namespace, "namespace", "", "tenant namespace whose isolated corpus is used (env BAMRAG_NAMESPACE)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to apply, e.g. dev or prod (env BAMRAG_PROFILE)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose logging")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "output format: text or json (newline-delimited events)")
+	rootCmd.PersistentFlags().StringVar(&pprofAddr, "pprof", "", "serve net/http/pprof on this address, e.g. :6060 (empty to disable)")
 }
 
 func initLogger() {
@@ -56,9 +122,6 @@ func initLogger() {
 }
 
 func initConfig() {
-	// Start with defaults
-	cfg = config.Defaults()
-
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -76,10 +139,17 @@ func initConfig() {
 	viper.AutomaticEnv()
 
 	// Explicitly bind nested env vars
+	viper.BindEnv("namespace", "BAMRAG_NAMESPACE")
+	viper.BindEnv("backend.type", "BAMRAG_BACKEND_TYPE")
+	viper.BindEnv("backend.path", "BAMRAG_BACKEND_PATH")
+	viper.BindEnv("backend.qdrant.url", "BAMRAG_BACKEND_QDRANT_URL")
+	viper.BindEnv("backend.qdrant.api_key", "BAMRAG_BACKEND_QDRANT_API_KEY")
 	viper.BindEnv("elasticsearch.addresses", "BAMRAG_ELASTICSEARCH_ADDRESSES")
 	viper.BindEnv("elasticsearch.index", "BAMRAG_ELASTICSEARCH_INDEX")
 	viper.BindEnv("elasticsearch.username", "BAMRAG_ELASTICSEARCH_USERNAME")
 	viper.BindEnv("elasticsearch.password", "BAMRAG_ELASTICSEARCH_PASSWORD")
+	viper.BindEnv("elasticsearch.cloud_id", "BAMRAG_ELASTICSEARCH_CLOUD_ID")
+	viper.BindEnv("elasticsearch.api_key", "BAMRAG_ELASTICSEARCH_API_KEY")
 	viper.BindEnv("embeddings.enabled", "BAMRAG_EMBEDDINGS_ENABLED")
 	viper.BindEnv("embeddings.socket_path", "BAMRAG_EMBEDDINGS_SOCKET_PATH")
 	viper.BindEnv("embeddings.model", "BAMRAG_EMBEDDINGS_MODEL")
@@ -88,8 +158,10 @@ func initConfig() {
 	viper.BindEnv("llm.model", "BAMRAG_LLM_MODEL")
 	viper.BindEnv("scraper.delay", "BAMRAG_SCRAPER_DELAY")
 	viper.BindEnv("scraper.max_depth", "BAMRAG_SCRAPER_MAX_DEPTH")
+	viper.BindEnv("scraper.proxy_url", "BAMRAG_SCRAPER_PROXY_URL")
 	viper.BindEnv("mcp.name", "BAMRAG_MCP_NAME")
 	viper.BindEnv("mcp.version", "BAMRAG_MCP_VERSION")
+	viper.BindEnv("openai.api_key", "BAMRAG_OPENAI_API_KEY")
 
 	// Read config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -99,13 +171,56 @@ func initConfig() {
 		// No config file - use defaults + env vars
 	}
 
-	// Unmarshal into struct (merges config file with defaults)
-	if err := viper.Unmarshal(&cfg); err != nil {
-		slog.Warn("failed to parse config", "error", err)
+	if err := applyProfile(); err != nil {
+		cfgErr = err
 	}
 
+	// A value that fails to decode, such as an unset ${VAR}, would leave
+	// its field at the default, so it is as fatal as an invalid one
+	loaded, err := decodeConfig()
+	if err != nil {
+		if cfgErr == nil {
+			cfgErr = fmt.Errorf("failed to parse config: %w", err)
+		}
+	} else if err := loaded.Validate(); err != nil && cfgErr == nil {
+		// Commands refuse to run on values that would fail later, or end
+		// up in index names and storage keys
+		cfgErr = fmt.Errorf("invalid config:\n%w", err)
+	}
+	setConfig(loaded)
+}
+
+// applyProfile merges the profile selected by --profile or BAMRAG_PROFILE
+// over the config file.
+func applyProfile() error {
+	if profile == "" {
+		profile = os.Getenv("BAMRAG_PROFILE")
+	}
+	if profile == "" {
+		return nil
+	}
+	return config.ApplyProfile(viper.GetViper(), profile)
+}
+
+// decodeConfig builds a Config from the values viper has read,
+// layered over the defaults.
+func decodeConfig() (config.Config, error) {
+	c := config.Defaults()
+
+	// Unmarshal into struct (merges config file with defaults)
+	err := config.Decode(viper.GetViper(), &c)
+
 	// Handle special case: addresses as comma-separated string from env
 	if addrs := os.Getenv("BAMRAG_ELASTICSEARCH_ADDRESSES"); addrs != "" {
-		cfg.Elasticsearch.Addresses = strings.Split(addrs, ",")
+		c.Elasticsearch.Addresses = strings.Split(addrs, ",")
 	}
+
+	// --namespace wins over config and env; names are scoped once all
+	// sources are merged
+	if namespace != "" {
+		c.Namespace = namespace
+	}
+	c.ApplyNamespace()
+
+	return c, err
 }
@@ -4,26 +4,36 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/mfenderov/bam-rag/internal/audit"
+	"github.com/mfenderov/bam-rag/internal/backend"
 	"github.com/mfenderov/bam-rag/internal/config"
-	"github.com/mfenderov/bam-rag/internal/elasticsearch"
-	"github.com/mfenderov/bam-rag/internal/embeddings"
 	"github.com/mfenderov/bam-rag/internal/events"
 	"github.com/mfenderov/bam-rag/internal/ingestion"
-	"github.com/mfenderov/bam-rag/internal/llm"
 	"github.com/mfenderov/bam-rag/internal/pipeline"
+	"github.com/mfenderov/bam-rag/internal/progress"
 	"github.com/mfenderov/bam-rag/internal/scraper"
 	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/webhook"
+	"github.com/mfenderov/bam-rag/pkg/models"
 	"github.com/spf13/cobra"
 )
 
 var (
 	scrapeURL    string
 	scrapeSource string
+	scrapeGroup  string
 	noIngest     bool
+	scrapeWatch  bool
+	scrapeStale  string
 )
 
 var scrapeCmd = &cobra.Command{
@@ -38,11 +48,20 @@ Examples:
   # Scrape a specific source by name
   bam-rag scrape --source example-docs
 
+  # Scrape every source in a group
+  bam-rag scrape --group kubernetes
+
   # Scrape a specific URL directly
   bam-rag scrape --url https://example.com/docs
 
   # Scrape only (write to S3, no ingestion)
-  bam-rag scrape --url https://example.com/docs --no-ingest`,
+  bam-rag scrape --url https://example.com/docs --no-ingest
+
+  # Only scrape sources not scraped in the last 7 days
+  bam-rag scrape --stale 7d
+
+  # Keep re-ingesting local directory sources as files change
+  bam-rag scrape --source team-docs --watch`,
 	RunE: runScrape,
 }
 
@@ -51,7 +70,15 @@ func init() {
 
 	scrapeCmd.Flags().StringVar(&scrapeURL, "url", "", "URL to scrape directly")
 	scrapeCmd.Flags().StringVar(&scrapeSource, "source", "", "Source name from config to scrape")
+	scrapeCmd.Flags().StringVar(&scrapeGroup, "group", "", "Scrape all sources in this group")
 	scrapeCmd.Flags().BoolVar(&noIngest, "no-ingest", false, "Scrape to S3 only, skip ingestion")
+	scrapeCmd.Flags().BoolVar(&scrapeWatch, "watch", false, "Watch local directory sources and re-ingest changed files")
+	scrapeCmd.Flags().StringVar(&scrapeStale, "stale", "", "Only scrape sources whose last scrape is older than this (e.g. 12h, 7d)")
+
+	scrapeCmd.MarkFlagsMutuallyExclusive("url", "source", "group")
+
+	scrapeCmd.RegisterFlagCompletionFunc("source", completeSourceNames)
+	scrapeCmd.RegisterFlagCompletionFunc("group", completeGroupNames)
 }
 
 func runScrape(cmd *cobra.Command, args []string) error {
@@ -59,56 +86,115 @@ func runScrape(cmd *cobra.Command, args []string) error {
 	defer stop()
 
 	cfg := GetConfig()
-	slog.Debug("scrape command starting", "verbose", verbose, "no_ingest", noIngest)
+	slog.Debug("scrape command starting", "verbose", verbose, "no_ingest", noIngest, "watch", scrapeWatch)
+
+	if scrapeWatch && noIngest {
+		return fmt.Errorf("--watch cannot be combined with --no-ingest")
+	}
 
 	// Determine what to scrape
-	var urls []string
+	var selected []config.Source
 
 	if scrapeURL != "" {
-		urls = append(urls, scrapeURL)
+		// Ad-hoc URLs have no source name and use the global settings
+		selected = []config.Source{{URL: scrapeURL}}
 	} else {
 		if len(cfg.Sources) == 0 {
 			return fmt.Errorf("no sources configured and no --url provided")
 		}
 
-		for _, source := range cfg.Sources {
-			if scrapeSource != "" && source.Name != scrapeSource {
-				continue
-			}
-			if source.URL != "" {
-				urls = append(urls, source.URL)
+		sources, err := selectSources(&cfg, scrapeSource, scrapeGroup)
+		if err != nil {
+			return err
+		}
+		for _, source := range sources {
+			if source.URL != "" || source.Path != "" {
+				selected = append(selected, source)
 			}
 		}
 
-		if len(urls) == 0 {
-			if scrapeSource != "" {
-				return fmt.Errorf("source %q not found in config", scrapeSource)
-			}
+		if len(selected) == 0 {
 			return fmt.Errorf("no valid sources found in config")
 		}
+
+		if scrapeStale != "" {
+			maxAge, err := config.ParseDuration(scrapeStale)
+			if err != nil {
+				return fmt.Errorf("invalid --stale: %w", err)
+			}
+			selected, err = filterStaleSources(ctx, &cfg, selected, maxAge)
+			if err != nil {
+				return err
+			}
+			if len(selected) == 0 {
+				reporter.Report(progress.Event{Type: progress.EventInfo, Message: "All sources are fresh, nothing to scrape"})
+				return nil
+			}
+		}
+	}
+
+	// Critical documentation first
+	selected = config.SortByPriority(selected)
+
+	hasDirs := slices.ContainsFunc(selected, func(s config.Source) bool { return s.Path != "" })
+	if scrapeWatch && !hasDirs {
+		return fmt.Errorf("--watch requires at least one source with a local path")
 	}
 
 	// Use event-driven flow when S3 storage is configured
 	if cfg.Storage.Endpoint != "" {
-		return runEventDrivenScrape(ctx, &cfg, urls)
+		return runEventDrivenScrape(ctx, &cfg, selected)
+	}
+
+	if hasDirs {
+		return fmt.Errorf("local directory sources require storage to be configured")
 	}
 
 	// Fallback to legacy pipeline for backward compatibility
-	return runLegacyPipeline(ctx, &cfg, urls)
+	return runLegacyPipeline(ctx, &cfg, selected)
+}
+
+// filterStaleSources returns the sources whose most recent scrape in S3 is
+// older than maxAge, or that have never been scraped.
+func filterStaleSources(ctx context.Context, cfg *config.Config, sources []config.Source, maxAge time.Duration) ([]config.Source, error) {
+	storageClient, err := newStorageClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Matched by source rather than host, since sources can share a host
+	scrapes, err := sourceScrapes(ctx, storageClient, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var stale []config.Source
+	for _, source := range sources {
+		// Oldest first, so the last is the latest
+		var last time.Time
+		if own := scrapes[source.Name]; len(own) > 0 {
+			last = own[len(own)-1].CreatedAt
+		}
+		if last.After(cutoff) {
+			reporter.Report(progress.Event{
+				Type:    progress.EventInfo,
+				URL:     source.URL,
+				Message: fmt.Sprintf("Skipping %s: scraped %s ago", source.Name, time.Since(last).Round(time.Minute)),
+			})
+			continue
+		}
+		stale = append(stale, source)
+	}
+
+	return stale, nil
 }
 
 // runEventDrivenScrape uses the new event-driven architecture
-func runEventDrivenScrape(ctx context.Context, cfg *config.Config, urls []string) error {
-	// Create storage client
-	storageClient, err := storage.New(storage.Config{
-		Endpoint:        cfg.Storage.Endpoint,
-		Bucket:          cfg.Storage.Bucket,
-		AccessKeyID:     cfg.Storage.AccessKeyID,
-		SecretAccessKey: cfg.Storage.SecretAccessKey,
-		UseSSL:          cfg.Storage.UseSSL,
-	})
+func runEventDrivenScrape(ctx context.Context, cfg *config.Config, sources []config.Source) error {
+	storageClient, err := newStorageClient(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create storage client: %w", err)
+		return err
 	}
 
 	// Ensure bucket exists
@@ -116,168 +202,287 @@ func runEventDrivenScrape(ctx context.Context, cfg *config.Config, urls []string
 		return fmt.Errorf("failed to ensure bucket: %w", err)
 	}
 
-	// Create scraper
-	scraperInstance := scraper.New(scraper.Config{
-		Delay:            cfg.Scraper.Delay,
-		MaxDepth:         cfg.Scraper.MaxDepth,
-		FollowLinks:      cfg.Scraper.FollowLinks,
-		Timeout:          cfg.Scraper.Timeout,
-		UserAgent:        cfg.Scraper.UserAgent,
-		TryMarkdownFirst: cfg.Scraper.TryMarkdownFirst,
-	})
-
 	if noIngest {
 		// Scrape only mode - just write to S3
-		return runScrapeOnly(ctx, scraperInstance, storageClient, urls)
+		return runScrapeOnly(ctx, cfg, storageClient, sources)
 	}
 
 	// Full event-driven flow with ingestion
-	return runScrapeWithIngest(ctx, cfg, scraperInstance, storageClient, urls)
+	return runScrapeWithIngest(ctx, cfg, storageClient, sources)
+}
+
+// scrapeSourceToS3 scrapes a source with its effective settings.
+// Returns the results of the scrapes that succeeded.
+func scrapeSourceToS3(ctx context.Context, cfg *config.Config, storageClient *storage.Client, source config.Source) []*scraper.ScrapeResult {
+	if ctx.Err() != nil {
+		if source.Name != "" {
+			unfinished.add("%s: not scraped; run 'bam-rag scrape --source %s'", source.Name, source.Name)
+		} else {
+			unfinished.add("%s: not scraped; run 'bam-rag scrape --url %s'", source.URL, source.URL)
+		}
+		return nil
+	}
+	eff := cfg.ForSource(source)
+	s, err := newScraper(&eff, source)
+	if err != nil {
+		reportFor(ctx, progress.Event{Type: progress.EventError, URL: source.URL + source.Path, Message: err.Error()})
+		return nil
+	}
+
+	var results []*scraper.ScrapeResult
+	if source.URL != "" {
+		if result := scrapeURLToS3(ctx, s, storageClient, source.URL); result != nil {
+			results = append(results, result)
+		}
+	}
+	if source.Path != "" {
+		if result := scrapeDirToS3(ctx, s, storageClient, source.Path, nil); result != nil {
+			results = append(results, result)
+		}
+	}
+	return results
 }
 
 // runScrapeOnly writes scraped content to S3 without ingestion
-func runScrapeOnly(ctx context.Context, s *scraper.Scraper, storageClient *storage.Client, urls []string) error {
+func runScrapeOnly(ctx context.Context, cfg *config.Config, storageClient *storage.Client, sources []config.Source) error {
 	totalPages := 0
+	notifier := newNotifier(cfg)
 
-	for _, url := range urls {
-		fmt.Printf("Scraping to S3: %s\n", url)
-
-		result, err := s.ScrapeToS3(ctx, url, storageClient)
-		if err != nil {
-			fmt.Printf("  Error: %v\n", err)
-			continue
+	for _, source := range sources {
+		for _, result := range scrapeSourceToS3(ctx, cfg, storageClient, source) {
+			totalPages += result.PageCount
+			notifyScrapeComplete(ctx, notifier, newScrapeCompleteEvent(storageClient, result, source.Name))
 		}
-
-		totalPages += result.PageCount
-		fmt.Printf("  Pages: %d, Prefix: %s\n", result.PageCount, result.Prefix)
 	}
 
-	fmt.Printf("\nTotal: %d pages written to S3\n", totalPages)
-	fmt.Println("Run 'bam-rag ingest --prefix <prefix>' to index these documents")
+	reporter.Report(progress.Event{
+		Type:    progress.EventSummary,
+		Pages:   totalPages,
+		Message: fmt.Sprintf("\nTotal: %d pages written to S3\nRun 'bam-rag ingest --prefix <prefix>' to index these documents", totalPages),
+	})
 	return nil
 }
 
-// runScrapeWithIngest uses channels to coordinate scraping and ingestion
-func runScrapeWithIngest(ctx context.Context, cfg *config.Config, s *scraper.Scraper, storageClient *storage.Client, urls []string) error {
-	// Create ES client
-	esClient, err := elasticsearch.New(elasticsearch.Config{
-		Addresses: cfg.Elasticsearch.Addresses,
-		Index:     cfg.Elasticsearch.Index,
-		Username:  cfg.Elasticsearch.Username,
-		Password:  cfg.Elasticsearch.Password,
-	})
+// runScrapeWithIngest publishes a ScrapeCompleteEvent on the event bus for
+// every finished scrape. With the in-process bus the events are ingested
+// here; with an external bus they are left to 'bam-rag ingest --follow'.
+func runScrapeWithIngest(ctx context.Context, cfg *config.Config, storageClient *storage.Client, sources []config.Source) error {
+	bus, err := newEventBus(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create ES client: %w", err)
+		return err
 	}
-
-	// Create optional embeddings client
-	var embedClient *embeddings.Client
-	if cfg.Embeddings.Enabled {
-		embedClient, err = embeddings.New(embeddings.Config{
-			SocketPath: cfg.Embeddings.SocketPath,
-			Model:      cfg.Embeddings.Model,
+	defer bus.Close()
+
+	if externalBus(cfg) {
+		totalPages, queued := publishScrapes(ctx, cfg, storageClient, bus, sources)
+		reporter.Report(progress.Event{
+			Type:    progress.EventSummary,
+			Pages:   totalPages,
+			Message: fmt.Sprintf("\nTotal: %d pages scraped, %d scrapes queued for ingestion", totalPages, queued),
 		})
-		if err != nil {
-			return fmt.Errorf("failed to create embeddings client: %w", err)
-		}
-		slog.Info("embeddings enabled", "model", cfg.Embeddings.Model)
+		return nil
 	}
 
-	// Create optional LLM client
-	var llmClient *llm.Client
-	if cfg.LLM.Enabled {
-		llmClient, err = llm.New(llm.Config{
-			SocketPath: cfg.LLM.SocketPath,
-			Model:      cfg.LLM.Model,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create LLM client: %w", err)
+	// One engine per source, so each is ingested with its own overrides
+	engines := newSourceEngines(cfg, storageClient)
+	var tally ingestTally
+
+	// Start ingestion worker (consumer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		events.SubscribeScrapeComplete(ctx, bus, ingestScrapeEvents(bus, engines, &tally, false))
+	}()
+
+	// Scrape sources (producer)
+	totalPages, _ := publishScrapes(ctx, cfg, storageClient, bus, sources)
+
+	// Every event has been taken by the consumer; wait for it to finish
+	bus.Close()
+	<-done
+
+	reporter.Report(progress.Event{
+		Type:     progress.EventSummary,
+		Pages:    totalPages,
+		Docs:     tally.docs,
+		Duration: tally.duration,
+		Stages:   tally.stages,
+		Message: fmt.Sprintf("\nTotal: %d pages scraped, %d docs indexed in %v",
+			totalPages, tally.docs, tally.duration),
+	})
+
+	return nil
+}
+
+// publishScrapes scrapes each source to S3 and publishes a completion event
+// per scrape, then keeps watching directory sources if --watch is set.
+// Returns the number of pages scraped and events published.
+func publishScrapes(ctx context.Context, cfg *config.Config, storageClient *storage.Client, bus events.Bus, sources []config.Source) (int, int) {
+	totalPages := 0
+	var published atomic.Int64
+	notifier := newNotifier(cfg)
+
+	// Called concurrently by directory watchers
+	publish := func(result *scraper.ScrapeResult, source string) {
+		if publishScrape(ctx, notifier, storageClient, bus, result, source) {
+			published.Add(1)
 		}
-		slog.Info("LLM enrichment enabled", "model", cfg.LLM.Model)
 	}
 
-	// Create ingestion engine
-	engine := ingestion.New(storageClient, esClient, embedClient, llmClient)
+	for _, source := range sources {
+		for _, result := range scrapeSourceToS3(ctx, cfg, storageClient, source) {
+			totalPages += result.PageCount
+			publish(result, source.Name)
+		}
+	}
 
-	// Event channel for scrape completion
-	scrapeEvents := make(chan events.ScrapeCompleteEvent)
-	done := make(chan struct{})
+	if scrapeWatch {
+		watchDirs(ctx, cfg, storageClient, sources, publish)
+	}
 
-	// Track results
-	var totalDocsIndexed int
-	var totalDuration time.Duration
+	return totalPages, int(published.Load())
+}
 
-	// Start ingestion worker (consumer)
-	go func() {
-		defer close(done)
-		for event := range scrapeEvents {
-			fmt.Printf("Ingesting: %s (%d pages)\n", event.Prefix, event.PageCount)
+// publishScrape notifies webhooks of a finished scrape and publishes it for
+// ingestion. Reports whether it was published.
+func publishScrape(ctx context.Context, notifier *webhook.Notifier, storageClient *storage.Client, bus events.Bus, result *scraper.ScrapeResult, source string) bool {
+	event := newScrapeCompleteEvent(storageClient, result, source)
+	event.Actor = audit.ActorFrom(ctx)
+	notifyScrapeComplete(ctx, notifier, event)
+
+	if err := events.PublishScrapeComplete(ctx, bus, event); err != nil {
+		reportFor(ctx, progress.Event{
+			Type:    progress.EventError,
+			Prefix:  result.Prefix,
+			Message: fmt.Sprintf("failed to publish scrape event, run 'bam-rag ingest --prefix %s': %v", result.Prefix, err),
+		})
+		return false
+	}
+	return true
+}
 
-			result, err := engine.Ingest(ctx, event.Prefix)
-			if err != nil {
-				fmt.Printf("  Error: %v\n", err)
-				continue
-			}
+// newScrapeCompleteEvent builds the event sent to the ingestion worker for a finished scrape.
+func newScrapeCompleteEvent(storageClient *storage.Client, result *scraper.ScrapeResult, source string) events.ScrapeCompleteEvent {
+	return events.ScrapeCompleteEvent{
+		Bucket:      storageClient.Bucket(),
+		Prefix:      result.Prefix,
+		SourceURL:   result.SourceURL,
+		Source:      source,
+		PageCount:   result.PageCount,
+		Timestamp:   time.Now(),
+		Traceparent: result.Traceparent,
+	}
+}
 
-			totalDocsIndexed += result.DocsIndexed
-			totalDuration += result.Duration
+// notifyScrapeComplete sends a finished scrape to the configured webhooks.
+func notifyScrapeComplete(ctx context.Context, notifier *webhook.Notifier, event events.ScrapeCompleteEvent) {
+	if err := notifier.Notify(ctx, webhook.EventScrapeComplete, event); err != nil {
+		reportFor(ctx, progress.Event{Type: progress.EventWarning, Prefix: event.Prefix, Message: err.Error()})
+	}
+}
 
-			fmt.Printf("  Docs indexed: %d, Duration: %v\n", result.DocsIndexed, result.Duration)
-			if len(result.Errors) > 0 {
-				for _, e := range result.Errors {
-					fmt.Printf("  Warning: %s\n", e)
-				}
+// runLegacyPipeline uses the original direct pipeline for backward compatibility
+func runLegacyPipeline(ctx context.Context, cfg *config.Config, sources []config.Source) error {
+	totalPages := 0
+	totalDocs := 0
+	var totalDuration time.Duration
+	var totalStages map[string]time.Duration
+
+	for _, source := range sources {
+		url := source.URL
+
+		eff := cfg.ForSource(source)
+		pipelineConfig := legacyPipelineConfig(eff)
+		pipelineConfig.AccessLabels = source.AccessLabels
+		pipelineConfig.Redactor = redactor
+		var err error
+		if pipelineConfig.ScraperConfig.Include, err = compilePatterns(source.IncludePatterns); err != nil {
+			return fmt.Errorf("sources[%s].include_patterns: %w", source.Name, err)
+		}
+		if pipelineConfig.ScraperConfig.Exclude, err = compilePatterns(source.ExcludePatterns); err != nil {
+			return fmt.Errorf("sources[%s].exclude_patterns: %w", source.Name, err)
+		}
+		if pipelineConfig.ScraperConfig.Proxies, err = scraperProxies(&eff); err != nil {
+			return err
+		}
+		if pipelineConfig.ScraperConfig.Auth, err = scraperAuth(&eff); err != nil {
+			return err
+		}
+		pipelineConfig.ScraperConfig.Headers = source.Headers
+		if eff.Backend.Type != "elasticsearch" {
+			index, err := newSearchBackend(&eff)
+			if err != nil {
+				return err
 			}
+			pipelineConfig.Backend = index
+		}
+		p, err := pipeline.New(pipelineConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create pipeline: %w", err)
 		}
-	}()
 
-	// Scrape URLs (producer)
-	totalPages := 0
-	for _, url := range urls {
-		fmt.Printf("Scraping: %s\n", url)
+		reporter.Report(progress.Event{Type: progress.EventScrapeStart, URL: url})
 
-		result, err := s.ScrapeToS3(ctx, url, storageClient)
+		result, err := p.Run(ctx, url)
 		if err != nil {
-			fmt.Printf("  Error: %v\n", err)
+			reporter.Report(progress.Event{Type: progress.EventError, URL: url, Message: err.Error()})
 			continue
 		}
 
-		totalPages += result.PageCount
-		fmt.Printf("  Pages: %d, Prefix: %s\n", result.PageCount, result.Prefix)
+		totalPages += result.PagesScraped
+		totalDocs += result.DocsIndexed
+		totalDuration += result.Duration
+		totalStages = addStages(totalStages, result.Stages)
+
+		reporter.Report(progress.Event{
+			Type:     progress.EventIngestComplete,
+			URL:      url,
+			Pages:    result.PagesScraped,
+			Docs:     result.DocsIndexed,
+			Duration: result.Duration,
+			Stages:   result.Stages,
+		})
 
-		// Send event to ingestion worker
-		scrapeEvents <- events.ScrapeCompleteEvent{
-			Bucket:    storageClient.Bucket(),
-			Prefix:    result.Prefix,
-			SourceURL: result.SourceURL,
-			PageCount: result.PageCount,
-			Timestamp: time.Now(),
+		for _, e := range result.Errors {
+			reporter.Report(errorEvent(e))
 		}
 	}
 
-	// Close channel and wait for ingestion to complete
-	close(scrapeEvents)
-	<-done
-
-	fmt.Printf("\nTotal: %d pages scraped, %d docs indexed in %v\n",
-		totalPages, totalDocsIndexed, totalDuration)
+	reporter.Report(progress.Event{
+		Type:     progress.EventSummary,
+		Pages:    totalPages,
+		Docs:     totalDocs,
+		Duration: totalDuration,
+		Stages:   totalStages,
+		Message: fmt.Sprintf("\nTotal: %d pages, %d docs indexed in %v",
+			totalPages, totalDocs, totalDuration),
+	})
 
 	return nil
 }
 
-// runLegacyPipeline uses the original direct pipeline for backward compatibility
-func runLegacyPipeline(ctx context.Context, cfg *config.Config, urls []string) error {
-	pipelineConfig := pipeline.Config{
+// legacyPipelineConfig maps an effective configuration onto the legacy pipeline's settings.
+func legacyPipelineConfig(cfg config.Config) pipeline.Config {
+	return pipeline.Config{
 		ESAddresses: cfg.Elasticsearch.Addresses,
+		ESCloudID:   cfg.Elasticsearch.CloudID,
 		ESIndex:     cfg.Elasticsearch.Index,
 		ESUsername:  cfg.Elasticsearch.Username,
 		ESPassword:  cfg.Elasticsearch.Password,
+		ESAPIKey:    cfg.Elasticsearch.APIKey,
+		ESMapping:   esMapping(&cfg),
 		ScraperConfig: pipeline.ScraperConfig{
 			Delay:            cfg.Scraper.Delay,
 			MaxDepth:         cfg.Scraper.MaxDepth,
 			FollowLinks:      cfg.Scraper.FollowLinks,
 			UserAgent:        cfg.Scraper.UserAgent,
 			TryMarkdownFirst: cfg.Scraper.TryMarkdownFirst,
+			ContentSelector:  cfg.Scraper.ContentSelector,
+			MaxParallel:      cfg.Scraper.MaxParallel,
+			RespectNoindex:   cfg.Scraper.RespectNoindex,
+			RespectRobots:    cfg.Scraper.RespectRobots,
+			Incremental:      cfg.Scraper.Incremental,
+			Retry:            scraperRetry(&cfg),
 		},
 		EmbeddingsConfig: pipeline.EmbeddingsConfig{
 			Enabled:    cfg.Embeddings.Enabled,
@@ -289,42 +494,181 @@ func runLegacyPipeline(ctx context.Context, cfg *config.Config, urls []string) e
 			SocketPath: cfg.LLM.SocketPath,
 			Model:      cfg.LLM.Model,
 		},
+		MaxChunkSize: cfg.Ingestion.MaxChunkSize,
 	}
+}
 
-	p, err := pipeline.New(pipelineConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create pipeline: %w", err)
+// watchDirs re-scrapes changed files in local directory sources and sends them
+// for ingestion; removed files are deleted from the index. Blocks until ctx is done.
+func watchDirs(ctx context.Context, cfg *config.Config, storageClient *storage.Client, sources []config.Source, publish func(result *scraper.ScrapeResult, source string)) {
+	var dirSources []config.Source
+	for _, source := range sources {
+		if source.Path != "" {
+			dirSources = append(dirSources, source)
+		}
 	}
 
-	totalPages := 0
-	totalDocs := 0
-	var totalDuration time.Duration
+	reporter.Report(progress.Event{
+		Type:    progress.EventInfo,
+		Message: fmt.Sprintf("\nWatching %d director(ies) for changes (Ctrl+C to stop)...", len(dirSources)),
+	})
 
-	for _, url := range urls {
-		fmt.Printf("Scraping: %s\n", url)
+	var wg sync.WaitGroup
+	for _, source := range dirSources {
+		dir := source.Path
+		eff := cfg.ForSource(source)
+		s, err := newScraper(&eff, source)
+		if err != nil {
+			reporter.Report(progress.Event{Type: progress.EventError, URL: dir, Message: err.Error()})
+			continue
+		}
 
-		result, err := p.Run(ctx, url)
+		// Removed files are deleted from the index the source writes to
+		index, err := newSearchBackend(&eff)
 		if err != nil {
-			fmt.Printf("  Error: %v\n", err)
+			reporter.Report(progress.Event{Type: progress.EventError, URL: dir, Message: err.Error()})
 			continue
 		}
 
-		totalPages += result.PagesScraped
-		totalDocs += result.DocsIndexed
-		totalDuration += result.Duration
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := scraper.WatchDir(ctx, dir, scraper.DefaultWatchDebounce, func(changed, removed []string) {
+				deleteFiles(ctx, index, removed)
 
-		fmt.Printf("  Pages: %d, Docs indexed: %d, Duration: %v\n",
-			result.PagesScraped, result.DocsIndexed, result.Duration)
+				if len(changed) == 0 {
+					return
+				}
+
+				result := scrapeDirToS3(ctx, s, storageClient, dir, changed)
+				if result == nil {
+					return
+				}
 
-		if len(result.Errors) > 0 {
-			for _, e := range result.Errors {
-				fmt.Printf("  Warning: %v\n", e)
+				publish(result, source.Name)
+			})
+			if err != nil {
+				reporter.Report(progress.Event{Type: progress.EventError, URL: dir, Message: fmt.Sprintf("watch failed: %v", err)})
 			}
+		}()
+	}
+	wg.Wait()
+}
+
+// deleteFiles deletes the documents of removed local files from the index.
+func deleteFiles(ctx context.Context, index backend.SearchBackend, paths []string) {
+	for _, path := range paths {
+		fileURL := scraper.FileURL(path)
+		id := models.GenerateDocumentID(fileURL)
+		if err := index.DeleteDocument(ctx, id); err != nil {
+			reportFor(ctx, progress.Event{Type: progress.EventError, URL: fileURL, Message: err.Error()})
+			continue
 		}
+		reportFor(ctx, progress.Event{Type: progress.EventDelete, URL: fileURL, Docs: 1, IDs: []string{id}, Message: "Removed: " + path})
 	}
+}
 
-	fmt.Printf("\nTotal: %d pages, %d docs indexed in %v\n",
-		totalPages, totalDocs, totalDuration)
+// scrapeURLToS3 scrapes a URL to S3, reporting progress. Returns nil on failure.
+func scrapeURLToS3(ctx context.Context, s *scraper.Scraper, storageClient *storage.Client, url string) *scraper.ScrapeResult {
+	reportFor(ctx, progress.Event{Type: progress.EventScrapeStart, URL: url})
 
-	return nil
+	result, err := s.ScrapeToS3(ctx, url, storageClient)
+	if err != nil {
+		reportFor(ctx, progress.Event{Type: progress.EventError, URL: url, Message: err.Error()})
+		return nil
+	}
+
+	reportFor(ctx, progress.Event{Type: progress.EventScrapeComplete, URL: url, Prefix: result.Prefix, Pages: result.PageCount, Report: result.Report})
+	if result.Interrupted {
+		unfinished.scrapeInterrupted(result)
+	}
+	if summary := crawlSummary(result.Crawl); summary != "" {
+		reportFor(ctx, progress.Event{Type: progress.EventInfo, URL: url, Prefix: result.Prefix, Message: "  Crawl: " + summary})
+	}
+	return result
+}
+
+// crawlSummary describes the crawl quality of a scrape in one line, e.g.
+// "status 200 x40, 404 x2; 3 redirects; excluded 12 external, 5 depth; 2 duplicate pages".
+func crawlSummary(report *storage.ScrapeReport) string {
+	if report == nil {
+		return ""
+	}
+	var parts []string
+	if len(report.Statuses) > 0 {
+		var statuses []string
+		for _, code := range slices.Sorted(maps.Keys(report.Statuses)) {
+			statuses = append(statuses, fmt.Sprintf("%d x%d", code, report.Statuses[code]))
+		}
+		parts = append(parts, "status "+strings.Join(statuses, ", "))
+	}
+	if report.Redirects > 0 {
+		parts = append(parts, fmt.Sprintf("%d redirects", report.Redirects))
+	}
+	if len(report.Excluded) > 0 {
+		var excluded []string
+		for _, rule := range slices.Sorted(maps.Keys(report.Excluded)) {
+			excluded = append(excluded, fmt.Sprintf("%d %s", report.Excluded[rule], rule))
+		}
+		parts = append(parts, "excluded "+strings.Join(excluded, ", "))
+	}
+	if report.Noindex > 0 {
+		parts = append(parts, fmt.Sprintf("%d noindex pages", report.Noindex))
+	}
+	if report.Unchanged > 0 {
+		parts = append(parts, fmt.Sprintf("%d unchanged pages", report.Unchanged))
+	}
+	if len(report.Duplicates) > 0 {
+		parts = append(parts, fmt.Sprintf("%d duplicate pages", len(report.Duplicates)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// scrapeDirToS3 reads a local directory (or the given files within it) to S3,
+// reporting progress. Returns nil on failure.
+func scrapeDirToS3(ctx context.Context, s *scraper.Scraper, storageClient *storage.Client, dir string, files []string) *scraper.ScrapeResult {
+	dirURL := scraper.FileURL(dir)
+	reportFor(ctx, progress.Event{Type: progress.EventScrapeStart, URL: dirURL, Total: len(files)})
+
+	result, err := s.ScrapeDirToS3(ctx, dir, files, storageClient)
+	if err != nil {
+		reportFor(ctx, progress.Event{Type: progress.EventError, URL: dirURL, Message: err.Error()})
+		return nil
+	}
+
+	reportFor(ctx, progress.Event{Type: progress.EventScrapeComplete, URL: dirURL, Prefix: result.Prefix, Pages: result.PageCount, Report: result.Report})
+	if result.Interrupted {
+		unfinished.scrapeInterrupted(result)
+	}
+	return result
+}
+
+// reportIngestResult reports a completed ingestion and its non-fatal errors.
+func reportIngestResult(ctx context.Context, result *ingestion.Result) {
+	reportFor(ctx, progress.Event{
+		Type:      progress.EventIngestComplete,
+		Prefix:    result.Prefix,
+		Docs:      result.DocsIndexed,
+		Unchanged: result.Unchanged,
+		Duration:  result.Duration,
+		Stages:    result.Stages,
+		Report:    result.Report,
+	})
+	if result.Interrupted {
+		unfinished.ingestInterrupted(result)
+	}
+	for _, e := range result.Errors {
+		event := errorEvent(e)
+		event.Prefix = result.Prefix
+		reportFor(ctx, event)
+	}
+}
+
+// errorEvent reports a page that failed without failing the run.
+func errorEvent(e *models.PageError) progress.Event {
+	message := e.Error()
+	if e.Retryable {
+		message += " (retryable)"
+	}
+	return progress.Event{Type: progress.EventWarning, URL: e.URL, Message: message, Error: e}
 }
@@ -4,18 +4,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"github.com/mfenderov/bam-rag/internal/backend"
 	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/query"
+	"github.com/mfenderov/bam-rag/internal/rerank"
+	"github.com/mfenderov/bam-rag/internal/tui"
+	"github.com/mfenderov/bam-rag/pkg/models"
 	"github.com/spf13/cobra"
 )
 
 var (
-	searchLimit  int
-	searchFormat string
+	searchLimit       int
+	searchFormat      string
+	searchInteractive bool
+	searchSource      string
+	searchGroup       string
+	searchLanguages   []string
+	searchAccess      []string
+	searchScope       string
+	searchDocument    string
+	searchPerSource   int
 )
 
+// searchSnippetSize is the length in bytes of the excerpt shown for each
+// result.
+const searchSnippetSize = 300
+
 var searchCmd = &cobra.Command{
 	Use:   "search [query]",
 	Short: "Search indexed documentation",
@@ -28,9 +51,41 @@ Examples:
   # Limit results
   bam-rag search "error handling" --limit 5
 
+  # Leave out pages mentioning a term or phrase, with - or NOT
+  bam-rag search 'ingress -nginx NOT "service mesh"'
+
+  # Restrict by title, source name or host, and scrape date
+  bam-rag search 'title:install source:go.dev after:2025-01-01 modules'
+
+  # Only search pages from one source or group
+  bam-rag search "pod lifecycle" --group kubernetes
+
+  # Include pages of sources restricted to the "internal" label
+  bam-rag search "deploy runbook" --access-label internal
+
+  # Match titles, tags, and summaries only, for fewer but closer results
+  bam-rag search "garbage collector" --scope summary
+
+  # Find the sections of one long page that match, by page ID or URL
+  bam-rag search "rolling update" --document https://kubernetes.io/docs/reference/kubectl/
+
+  # Keep one large site from taking every result slot
+  bam-rag search "authentication" --per-source 2
+
+  # Only search pages in German or French
+  bam-rag search "Installation" --language de,fr
+
   # JSON output for scripting
-  bam-rag search "modules" --format json`,
-	Args: cobra.ExactArgs(1),
+  bam-rag search "modules" --format json
+
+  # Interactive search with live results and preview
+  bam-rag search --interactive`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if searchInteractive {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: runSearch,
 }
 
@@ -39,6 +94,20 @@ func init() {
 
 	searchCmd.Flags().IntVar(&searchLimit, "limit", 10, "Maximum number of results")
 	searchCmd.Flags().StringVar(&searchFormat, "format", "text", "Output format: text or json")
+	searchCmd.Flags().BoolVarP(&searchInteractive, "interactive", "i", false, "Interactive search with live results and preview")
+	searchCmd.Flags().StringVar(&searchSource, "source", "", "Only return pages from this source")
+	searchCmd.Flags().StringVar(&searchGroup, "group", "", "Only return pages from sources in this group")
+	searchCmd.Flags().StringSliceVar(&searchLanguages, "language", nil, "Only return pages in these languages (ISO 639-1 codes, e.g. en,de)")
+	searchCmd.Flags().StringSliceVar(&searchAccess, "access-label", nil, "Also return pages restricted to these access labels")
+	searchCmd.Flags().StringVar(&searchScope, "scope", "full", "Fields matched: full (content, title, tags, summary) or summary (title, tags, summary)")
+	searchCmd.Flags().IntVar(&searchPerSource, "per-source", 0, "Return at most this many pages from each source host (default: elasticsearch.dedup.per_source)")
+	searchCmd.Flags().StringVar(&searchDocument, "document", "", "Return the best-matching sections of this page, by ID or URL")
+	searchCmd.MarkFlagsMutuallyExclusive("source", "group")
+	searchCmd.MarkFlagsMutuallyExclusive("document", "interactive")
+
+	searchCmd.RegisterFlagCompletionFunc("source", completeSourceNames)
+	searchCmd.RegisterFlagCompletionFunc("group", completeGroupNames)
+	searchCmd.RegisterFlagCompletionFunc("scope", cobra.FixedCompletions([]string{"full", "summary"}, cobra.ShellCompDirectiveNoFileComp))
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
@@ -46,54 +115,152 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	query := args[0]
 	cfg := GetConfig()
 
-	// Create ES client
-	esClient, err := elasticsearch.New(elasticsearch.Config{
-		Addresses: cfg.Elasticsearch.Addresses,
-		Index:     cfg.Elasticsearch.Index,
-		Username:  cfg.Elasticsearch.Username,
-		Password:  cfg.Elasticsearch.Password,
-	})
+	index, err := newSearchBackend(&cfg)
 	if err != nil {
-		return fmt.Errorf("failed to connect to Elasticsearch: %w", err)
+		return err
 	}
 
-	// Perform search
-	docs, err := esClient.Search(ctx, query, searchLimit)
+	reranker, err := newReranker(&cfg, "cli")
 	if err != nil {
-		return fmt.Errorf("search failed: %w", err)
+		return err
 	}
 
-	if len(docs) == 0 {
-		fmt.Println("No results found.")
-		return nil
+	var filter elasticsearch.Filter
+	if searchSource != "" || searchGroup != "" {
+		sources, err := selectSources(&cfg, searchSource, searchGroup)
+		if err != nil {
+			return err
+		}
+		filter = sourcesFilter(&cfg, sources)
+	}
+	filter.Scope, err = elasticsearch.ParseScope(searchScope)
+	if err != nil {
+		return err
+	}
+	filter.EnforceAccess = true
+	filter.AccessLabels = searchAccess
+	filter.PerSource = searchPerSource
+	for _, lang := range searchLanguages {
+		filter.Languages = append(filter.Languages, processor.NormalizeLanguage(lang))
+	}
+
+	search := func(ctx context.Context, q query.Query) ([]models.Document, error) {
+		docs, err := index.SearchFiltered(ctx, q.Text, reranker.Candidates(searchLimit), q.Apply(filter))
+		if err != nil {
+			return nil, err
+		}
+		return reranker.Documents(ctx, q.Text, docs, searchLimit), nil
+	}
+
+	if searchInteractive {
+		initialQuery := ""
+		if len(args) > 0 {
+			initialQuery = args[0]
+		}
+		parsed := func(ctx context.Context, s string) ([]models.Document, error) {
+			q, err := query.Parse(s)
+			if err != nil {
+				return nil, err
+			}
+			return search(ctx, q)
+		}
+		return tui.RunSearch(ctx, os.Stdin, os.Stdout, parsed, initialQuery)
+	}
+
+	// Perform search
+	q, err := query.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	if searchDocument != "" {
+		return searchSections(ctx, index, reranker, q, filter)
+	}
+	docs, err := search(ctx, q)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
 	}
 
 	// Output results
-	if searchFormat == "json" {
+	if searchFormat == "json" || jsonOutput() {
 		output, err := json.MarshalIndent(docs, "", "  ")
 		if err != nil {
 			return err
 		}
 		fmt.Println(string(output))
+	} else if len(docs) == 0 {
+		fmt.Println("No results found.")
+		suggestion, err := index.Suggest(ctx, q.Text, filter)
+		if err != nil {
+			slog.Debug("failed to suggest a correction", "query", q.Text, "error", err)
+		}
+		if suggestion != "" {
+			fmt.Printf("Did you mean: %s\n", suggestion)
+		}
 	} else {
+		// Query terms are shown in bold on terminals
+		open, close := "", ""
+		if progress.IsTerminal(os.Stdout) {
+			open, close = "\x1b[1m", "\x1b[0m"
+		}
+
 		fmt.Printf("Found %d results:\n\n", len(docs))
 		for i, doc := range docs {
 			fmt.Printf("─── Result %d ───\n", i+1)
 			fmt.Printf("Title:   %s\n", doc.Title)
 			fmt.Printf("URL:     %s\n", doc.URL)
 			fmt.Printf("ID:      %s\n", doc.ID)
-
-			// Truncate content for display
-			content := doc.Content
-			if len(content) > 500 {
-				content = content[:500] + "..."
+			if doc.License != "" {
+				fmt.Printf("License: %s\n", doc.License)
 			}
-			fmt.Printf("Content:\n%s\n\n", content)
+
+			snippet := markdown.Excerpt(doc.Content, q.Text, searchSnippetSize)
+			fmt.Printf("Snippet:\n%s\n\n", snippet.Highlight(open, close))
 		}
 	}
 
 	return nil
 }
+
+// searchSections prints the sections of the --document page best matching
+// the query.
+func searchSections(ctx context.Context, index backend.SearchBackend, reranker *rerank.Client, q query.Query, filter elasticsearch.Filter) error {
+	id := searchDocument
+	if strings.Contains(id, "://") {
+		id = models.GenerateDocumentID(id)
+	}
+	sections, err := index.SearchSections(ctx, id, q.Text, reranker.Candidates(searchLimit), q.Apply(filter))
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	sections = reranker.Chunks(ctx, q.Text, sections, searchLimit)
+
+	if searchFormat == "json" || jsonOutput() {
+		for i := range sections {
+			sections[i].Embedding = nil
+		}
+		output, err := json.MarshalIndent(sections, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+	if len(sections) == 0 {
+		fmt.Println("No matching sections.")
+		return nil
+	}
+
+	open, close := "", ""
+	if progress.IsTerminal(os.Stdout) {
+		open, close = "\x1b[1m", "\x1b[0m"
+	}
+	fmt.Printf("%s\n%s\n\n", sections[0].Title, sections[0].URL)
+	for i, section := range sections {
+		fmt.Printf("─── Section %d: %s ───\n", i+1, strings.Join(section.HeadingPath, " › "))
+		snippet := markdown.Excerpt(section.Content, q.Text, searchSnippetSize)
+		fmt.Printf("%s\n\n", snippet.Highlight(open, close))
+	}
+	return nil
+}
@@ -1,9 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 
+	"github.com/mfenderov/bam-rag/internal/apikey"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/health"
 	"github.com/mfenderov/bam-rag/internal/mcp"
+	"github.com/mfenderov/bam-rag/internal/ratelimit"
 	"github.com/spf13/cobra"
 )
 
@@ -12,38 +24,211 @@ var serveCmd = &cobra.Command{
 	Short: "Start the MCP server",
 	Long: `Start the MCP server for document retrieval.
 
-The server communicates via stdio and provides two tools:
-  - search_documents: Search indexed chunks by query
+The server communicates via stdio and provides these tools:
+  - search_documents: Search indexed pages by query
+  - get_document: Get a specific page, or one of its sections, by ID
+  - search_chunks: Search indexed page sections (chunks) by query
   - get_chunk: Get a specific chunk by ID
 
-Example:
-  bam-rag serve`,
+With analytics.enabled, searches, their result counts, and the documents
+read after them are logged; see 'bam-rag analytics'.
+
+With --http-addr, the tools are served over the MCP streamable HTTP
+transport at /mcp instead of stdio, so remote agents can share one server.
+Clients then authenticate with a key from mcp.api_keys or mcp.api_keys_file,
+sent as "Authorization: Bearer <key>" or "X-API-Key: <key>"; a key bound to
+a namespace is only accepted when serving that namespace, and its access
+labels widen what its holder sees. Without any keys the transport is open.
+mcp.rate_limit caps the requests per second of each client and the requests
+handled at once, answering 429 or 503 beyond them.
+
+With --health-addr, /healthz and /readyz (which checks Elasticsearch)
+are served over HTTP for orchestrators. With --dump-dir, SIGQUIT writes
+goroutine and heap profiles there instead of stopping the server.
+
+Changes to the config file, including API keys and rate limits, are
+picked up while the server runs; invalid edits are rejected and the
+previous configuration is kept. So is an edit removing the last API key
+while serving HTTP, which would open the transport: restart the server
+to serve without keys.
+
+Examples:
+  bam-rag serve
+  bam-rag serve --http-addr :8080 --namespace payments`,
 	RunE: runServe,
 }
 
 func init() {
 	rootCmd.AddCommand(serveCmd)
+
+	addHealthFlag(serveCmd)
+	addDumpFlag(serveCmd)
+	serveCmd.Flags().StringVar(&serveHTTPAddr, "http-addr", "", "Serve MCP over streamable HTTP at /mcp on this address, e.g. :8080, instead of stdio")
 }
 
+var serveHTTPAddr string
+
 func runServe(cmd *cobra.Command, args []string) error {
 	cfg := GetConfig()
 
-	// Build MCP config from loaded configuration
-	mcpConfig := mcp.Config{
-		Name:        cfg.MCP.Name,
-		Version:     cfg.MCP.Version,
-		ESAddresses: cfg.Elasticsearch.Addresses,
-		ESIndex:     cfg.Elasticsearch.Index,
-		ESUsername:  cfg.Elasticsearch.Username,
-		ESPassword:  cfg.Elasticsearch.Password,
+	serverConfig, err := mcpConfig(cfg)
+	if err != nil {
+		return err
 	}
-
-	server, err := mcp.NewServer(mcpConfig)
+	server, err := mcp.NewServer(serverConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create MCP server: %w", err)
 	}
 
+	keys, err := apiKeys(&cfg)
+	if err != nil {
+		return err
+	}
+	keyring, err := apikey.NewKeyring(keys)
+	if err != nil {
+		return err
+	}
+
+	var limiter atomic.Pointer[ratelimit.Limiter]
+	limiter.Store(newRateLimiter(&cfg))
+	limits := cfg.MCP.RateLimit
+
+	// Apply config file edits without restarting the server
+	watchConfig(func(next config.Config) error {
+		serverConfig, err := mcpConfig(next)
+		if err != nil {
+			return err
+		}
+		keys, err := apiKeys(&next)
+		if err != nil {
+			return err
+		}
+		// An empty keyring admits everyone, which an edit must not do
+		// silently
+		if serveHTTPAddr != "" && len(keys) == 0 && keyring.Len() > 0 {
+			return errors.New("mcp.api_keys: removing every key would open the HTTP transport to anyone; restart the server to serve without keys")
+		}
+		if err := keyring.Set(keys); err != nil {
+			return err
+		}
+		if err := server.Reload(serverConfig); err != nil {
+			return err
+		}
+		// Changed limits start every client with a full bucket
+		if next.MCP.RateLimit != limits {
+			limits = next.MCP.RateLimit
+			limiter.Store(newRateLimiter(&next))
+		}
+		return nil
+	})
+
+	index, err := newSearchBackend(&cfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startHealthServer(ctx, indexCheck(&cfg, index))
+	startProfileDumps(ctx)
+
+	if serveHTTPAddr != "" {
+		return serveHTTP(ctx, cmd, server, keyring, &limiter, &cfg)
+	}
+
 	fmt.Fprintln(cmd.ErrOrStderr(), "Starting MCP server...")
 
 	return server.ServeStdio()
 }
+
+// serveHTTP serves the MCP server over HTTP on --http-addr until
+// interrupted, admitting only clients with a key of keyring if it has any,
+// within the limits of the current limiter.
+func serveHTTP(ctx context.Context, cmd *cobra.Command, server *mcp.Server, keyring *apikey.Keyring, limiter *atomic.Pointer[ratelimit.Limiter], cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if keyring.Len() == 0 {
+		slog.Warn("no mcp.api_keys configured, the HTTP transport is open to anyone who can reach it")
+	}
+	mcpHandler := server.HTTPHandler()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter.Load().Middleware(httpClient, mcpHandler).ServeHTTP(w, r)
+	})
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Starting MCP server on %s/mcp...\n", serveHTTPAddr)
+	return health.Serve(ctx, serveHTTPAddr, keyring.Middleware(cfg.Namespace, handler))
+}
+
+// httpClient names the client of a request for rate limiting: the API key
+// it was authenticated with, or its address.
+func httpClient(r *http.Request) string {
+	if key, ok := apikey.FromContext(r.Context()); ok {
+		return "key:" + key.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// apiKeys returns the keys of mcp.api_keys and mcp.api_keys_file.
+func apiKeys(cfg *config.Config) ([]apikey.Key, error) {
+	var keys []apikey.Key
+	for _, key := range cfg.MCP.APIKeys {
+		keys = append(keys, apikey.Key{Name: key.Name, Secret: key.Key, Namespace: key.Namespace, AccessLabels: key.AccessLabels})
+	}
+	if cfg.MCP.APIKeysFile != "" {
+		fileKeys, err := apikey.LoadFile(cfg.MCP.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	return keys, nil
+}
+
+// mcpConfig builds the MCP server config from the loaded configuration.
+func mcpConfig(cfg config.Config) (mcp.Config, error) {
+	reranker, err := newReranker(&cfg, "mcp")
+	if err != nil {
+		return mcp.Config{}, err
+	}
+
+	serverConfig := mcp.Config{
+		Name:                cfg.MCP.Name,
+		Version:             cfg.MCP.Version,
+		ESAddresses:         cfg.Elasticsearch.Addresses,
+		ESCloudID:           cfg.Elasticsearch.CloudID,
+		ESIndex:             cfg.Elasticsearch.Index,
+		ESUsername:          cfg.Elasticsearch.Username,
+		ESPassword:          cfg.Elasticsearch.Password,
+		ESAPIKey:            cfg.Elasticsearch.APIKey,
+		ESRecency:           esRecency(&cfg),
+		ESDedup:             esDedup(&cfg),
+		ESSourceIndices:     cfg.SourceIndices(),
+		ESFuzziness:         cfg.Elasticsearch.Fuzziness,
+		ESFuzzyPrefixLength: cfg.Elasticsearch.FuzzyPrefixLength,
+		AccessLabels:        cfg.MCP.AccessLabels,
+		Reranker:            reranker,
+	}
+	if cfg.Analytics.Enabled {
+		serverConfig.AnalyticsIndex = cfg.Analytics.Index
+	}
+	if cfg.Backend.Type != "elasticsearch" {
+		serverConfig.Backend, err = newSearchBackend(&cfg)
+		if err != nil {
+			return mcp.Config{}, err
+		}
+	}
+	return serverConfig, nil
+}
+
+// newRateLimiter creates a limiter enforcing mcp.rate_limit.
+func newRateLimiter(cfg *config.Config) *ratelimit.Limiter {
+	return ratelimit.New(ratelimit.Config{
+		Rate:          cfg.MCP.RateLimit.RequestsPerSecond,
+		Burst:         cfg.MCP.RateLimit.Burst,
+		MaxConcurrent: cfg.MCP.RateLimit.MaxConcurrent,
+	})
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mfenderov/bam-rag/internal/ingestion"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/scraper"
+)
+
+// unfinished collects the work an interrupted command left undone. Scrapes
+// keep the pages they fetched and ingestions their checkpoints, so on exit
+// reportUnfinished lists the commands picking up where it stopped.
+var unfinished resumeState
+
+// resumeState is the work left by an interrupted command. It is safe for
+// concurrent use.
+type resumeState struct {
+	mu    sync.Mutex
+	steps []string // What is left, and the command resuming it
+}
+
+// add records work left undone.
+func (r *resumeState) add(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, fmt.Sprintf(format, args...))
+}
+
+// scrapeInterrupted records the pages of an interrupted scrape, which can
+// be ingested while the rest is scraped again.
+func (r *resumeState) scrapeInterrupted(result *scraper.ScrapeResult) {
+	r.add("%s: scrape interrupted with %d pages saved to %s; run 'bam-rag ingest --prefix %s' to index them, and scrape again for the rest",
+		result.SourceURL, result.PageCount, result.Prefix, result.Prefix)
+}
+
+// ingestInterrupted records the pages an interrupted ingestion left.
+func (r *resumeState) ingestInterrupted(result *ingestion.Result) {
+	r.add("%s: ingestion interrupted with %d pages indexed and %d left; run 'bam-rag ingest --prefix %s' to resume from its checkpoint",
+		result.Prefix, result.DocsIndexed, result.Remaining, result.Prefix)
+}
+
+// reportUnfinished reports the work left by an interrupted command.
+func reportUnfinished() {
+	unfinished.mu.Lock()
+	defer unfinished.mu.Unlock()
+	if len(unfinished.steps) == 0 || reporter == nil {
+		return
+	}
+	reporter.Report(progress.Event{
+		Type:    progress.EventSummary,
+		Message: "\nInterrupted. To resume:\n  - " + strings.Join(unfinished.steps, "\n  - "),
+	})
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/slowops"
+)
+
+// slowOps tracks slow operations of the running command; nil until the
+// config is loaded.
+var slowOps *slowops.Tracker
+
+// newSlowOps creates a tracker that warns through the command's reporter.
+func newSlowOps(c *config.Config) *slowops.Tracker {
+	return slowops.New(slowops.Thresholds{
+		slowops.OpFetch:  c.SlowOps.Fetch,
+		slowops.OpEnrich: c.SlowOps.Enrich,
+		slowops.OpEmbed:  c.SlowOps.Embed,
+		slowops.OpIndex:  c.SlowOps.Index,
+	}, reporter, c.SlowOps.Summary)
+}
+
+// reportSlowOps lists the slowest operations of the finished command.
+func reportSlowOps() {
+	summary := slowOps.Summary()
+	if summary == "" || reporter == nil {
+		return
+	}
+	reporter.Report(progress.Event{Type: progress.EventInfo, Message: summary})
+}
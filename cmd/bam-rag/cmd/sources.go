@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/sourcematch"
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// selectSources returns the configured sources named by --source or --group.
+// With neither set, every configured source is returned.
+func selectSources(cfg *config.Config, name, group string) ([]config.Source, error) {
+	switch {
+	case name != "":
+		source, ok := cfg.SourceByName(name)
+		if !ok {
+			return nil, fmt.Errorf("source %q not found in config", name)
+		}
+		return []config.Source{source}, nil
+	case group != "":
+		sources := cfg.SourcesInGroup(group)
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("no sources in group %q", group)
+		}
+		return sources, nil
+	default:
+		return cfg.Sources, nil
+	}
+}
+
+// sourcesFilter returns a search filter matching documents from any of the
+// sources. Documents indexed before sources were recorded are matched by
+// URL, where that tells the sources configured in cfg apart.
+func sourcesFilter(cfg *config.Config, sources []config.Source) elasticsearch.Filter {
+	var filter elasticsearch.Filter
+	for _, source := range sources {
+		filter.Sources = append(filter.Sources, source.Name)
+		if prefix := sourcematch.URLPrefix(source, cfg.Sources); prefix != "" {
+			filter.URLPrefixes = append(filter.URLPrefixes, prefix)
+		}
+	}
+	return filter
+}
+
+// sourceScrapes returns the stored scrapes of each of the sources by
+// source name, oldest first.
+func sourceScrapes(ctx context.Context, storageClient *storage.Client, sources []config.Source) (map[string][]storage.ScrapeInfo, error) {
+	scrapes, err := storageClient.ListScrapes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scrapes: %w", err)
+	}
+	candidates := sourcematch.Candidates(sources, scrapes)
+	metas, err := storageClient.GetMetadataBatch(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	return sourcematch.Group(sources, candidates, metas), nil
+}
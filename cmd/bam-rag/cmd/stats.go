@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/spf13/cobra"
+)
+
+var statsTopTags int
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show corpus statistics",
+	Long: `Show statistics about the indexed corpus: documents per source,
+index size, word and estimated token counts, embedding coverage, most frequent tags, when the newest
+indexed page of each source was scraped, and the most recent scrape per source in S3.
+
+Tags are counted on the tags.keyword field. Ingestion adds it to indices
+created by older versions, but only documents indexed since are counted;
+run 'bam-rag ingest --full' to count the tags of the others.
+
+Examples:
+  bam-rag stats
+  bam-rag stats --top-tags 20 --output json`,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().IntVar(&statsTopTags, "top-tags", 10, "Number of most frequent tags to show")
+}
+
+// scrapeStats summarizes the scrapes stored in S3 for one host.
+type scrapeStats struct {
+	Host       string    `json:"host"`
+	Scrapes    int       `json:"scrapes"`
+	LastScrape time.Time `json:"last_scrape"`
+}
+
+// corpusStats is the combined ES and S3 statistics output.
+type corpusStats struct {
+	*elasticsearch.IndexStats
+	EmbeddingCoverage float64       `json:"embedding_coverage_pct"`
+	Scrapes           []scrapeStats `json:"scrapes,omitempty"`
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+
+	esClient, err := newESClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	indexStats, err := esClient.Stats(ctx, statsTopTags)
+	if err != nil {
+		return fmt.Errorf("failed to get index stats: %w", err)
+	}
+
+	stats := corpusStats{
+		IndexStats:        indexStats,
+		EmbeddingCoverage: indexStats.EmbeddingCoverage(),
+	}
+
+	// S3 statistics are best-effort: the index may outlive its scrapes
+	if storageClient, err := newStorageClient(&cfg); err != nil {
+		slog.Warn("skipping S3 stats", "error", err)
+	} else if scrapes, err := storageClient.ListScrapes(ctx); err != nil {
+		slog.Warn("skipping S3 stats", "error", err)
+	} else {
+		byHost := make(map[string]int)
+		for _, s := range scrapes {
+			i, ok := byHost[s.Host]
+			if !ok {
+				i = len(stats.Scrapes)
+				byHost[s.Host] = i
+				stats.Scrapes = append(stats.Scrapes, scrapeStats{Host: s.Host})
+			}
+			stats.Scrapes[i].Scrapes++
+			if s.CreatedAt.After(stats.Scrapes[i].LastScrape) {
+				stats.Scrapes[i].LastScrape = s.CreatedAt
+			}
+		}
+	}
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Printf("Index:              %s (%s)\n", stats.Index, formatBytes(stats.SizeBytes))
+	fmt.Printf("Documents:          %d\n", stats.DocCount)
+	fmt.Printf("Words:              %d (~%d tokens)\n", stats.Words, stats.Tokens)
+	fmt.Printf("Embedding coverage: %.1f%% (%d/%d)\n", stats.EmbeddingCoverage, stats.WithEmbedding, stats.DocCount)
+
+	if len(stats.Sources) > 0 {
+		fmt.Printf("\nDocuments per source:\n")
+		for _, s := range stats.Sources {
+			host := s.Host
+			if host == "" {
+				host = "(local files)"
+			}
+			fmt.Printf("  %-40s %6d  last scraped %s\n", host, s.Docs, s.LastScraped.Format(time.RFC3339))
+		}
+	}
+
+	if len(stats.Scrapes) > 0 {
+		fmt.Printf("\nScrapes in S3:\n")
+		for _, s := range stats.Scrapes {
+			fmt.Printf("  %-40s %6d  last scraped %s\n", s.Host, s.Scrapes, s.LastScrape.Format(time.RFC3339))
+		}
+	}
+
+	if len(stats.TopTags) > 0 {
+		fmt.Printf("\nTop tags:\n")
+		for _, t := range stats.TopTags {
+			fmt.Printf("  %-40s %6d\n", t.Term, t.Count)
+		}
+	}
+
+	return nil
+}
+
+// formatBytes renders a byte count in human-readable units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+)
+
+// shutdownTracing flushes the spans of the running command; nil until
+// tracing is set up.
+var shutdownTracing func(context.Context) error
+
+// setupTracing starts exporting traces if an endpoint is configured.
+func setupTracing(c *config.Config) error {
+	shutdown, err := telemetry.Setup(telemetry.Config{
+		Endpoint:    c.Telemetry.Endpoint,
+		ServiceName: c.Telemetry.ServiceName,
+		Headers:     c.Telemetry.Headers,
+		SampleRatio: c.Telemetry.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	shutdownTracing = shutdown
+	return nil
+}
+
+// closeTracing exports the spans of the finished command.
+func closeTracing() {
+	if shutdownTracing == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		fmt.Fprintf(rootCmd.ErrOrStderr(), "Warning: failed to export traces: %v\n", err)
+	}
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mfenderov/bam-rag/internal/audit"
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/events"
+	"github.com/mfenderov/bam-rag/internal/health"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/scraper"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+// triggerAddr is where the worker accepts scrape triggers; empty disables
+// them.
+var triggerAddr string
+
+// triggerQueueSize bounds the triggered scrapes waiting to run.
+const triggerQueueSize = 16
+
+// addTriggerFlag registers --trigger-addr on a long-running command.
+func addTriggerFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&triggerAddr, "trigger-addr", "", "Accept signed scrape triggers and GitHub pushes on this address, e.g. :8082 (empty to disable)")
+}
+
+// triggered is a scrape waiting to run: a whole source, or the files of a
+// directory source that a GitHub push changed.
+type triggered struct {
+	source config.Source
+	push   *webhook.Push
+}
+
+// triggerQueue holds the scrapes triggered until they run, one at a time.
+// A whole source already waiting is not queued twice.
+type triggerQueue struct {
+	cfg     atomic.Pointer[config.Config] // Swapped on config reload
+	mu      sync.Mutex
+	pending map[string]bool
+	queue   chan triggered
+}
+
+func newTriggerQueue(cfg *config.Config) *triggerQueue {
+	q := &triggerQueue{
+		pending: make(map[string]bool),
+		queue:   make(chan triggered, triggerQueueSize),
+	}
+	q.cfg.Store(cfg)
+	return q
+}
+
+// setConfig makes triggers resolve against cfg's sources from now on.
+// Scrapes already queued keep the source they were queued with.
+func (q *triggerQueue) setConfig(cfg *config.Config) {
+	q.cfg.Store(cfg)
+}
+
+// secret returns the key triggers are signed with.
+func (q *triggerQueue) secret() string {
+	return q.cfg.Load().Triggers.Secret
+}
+
+// resolve returns the source a trigger names. A URL is scraped with the
+// settings of the configured source it falls under.
+func (q *triggerQueue) resolve(trigger webhook.Trigger) (config.Source, error) {
+	if trigger.Source != "" {
+		source, ok := q.cfg.Load().SourceByName(trigger.Source)
+		if !ok {
+			return config.Source{}, fmt.Errorf("source %q not found in config", trigger.Source)
+		}
+		return source, nil
+	}
+	source, ok := q.cfg.Load().SourceByURL(trigger.URL)
+	if !ok {
+		return config.Source{}, fmt.Errorf("no configured source covers %s", trigger.URL)
+	}
+	source.URL, source.Path = trigger.URL, ""
+	return source, nil
+}
+
+// pendingKey identifies a whole-source scrape in the pending list.
+func pendingKey(source config.Source) string {
+	return source.Name + " " + source.URL + " " + source.Path
+}
+
+// enqueue queues the source a trigger names.
+func (q *triggerQueue) enqueue(trigger webhook.Trigger) error {
+	source, err := q.resolve(trigger)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := pendingKey(source)
+	if q.pending[key] {
+		return nil
+	}
+	if err := q.add(triggered{source: source}); err != nil {
+		return err
+	}
+	q.pending[key] = true
+	return nil
+}
+
+// enqueuePush queues the files a GitHub push changed in each source tied
+// to its repository and branch. Either every source is queued or, if the
+// queue has no room for all, none is, so the push can be retried as a
+// whole.
+func (q *triggerQueue) enqueuePush(push webhook.Push) error {
+	sources := q.cfg.Load().SourcesByRepo(push.Repo, push.Branch)
+	if len(sources) == 0 {
+		return fmt.Errorf("no source is tied to %s branch %s", push.Repo, push.Branch)
+	}
+
+	// Only run takes scrapes off the queue, so the room checked stays
+	// available while the queue is locked
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cap(q.queue)-len(q.queue) < len(sources) {
+		return webhook.ErrQueueFull
+	}
+	for _, source := range sources {
+		if err := q.add(triggered{source: source, push: &push}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add queues t, or returns webhook.ErrQueueFull.
+func (q *triggerQueue) add(t triggered) error {
+	select {
+	case q.queue <- t:
+		return nil
+	default:
+		return webhook.ErrQueueFull
+	}
+}
+
+// run scrapes queued sources and publishes the scrapes for ingestion until
+// ctx is done. A source is taken off the pending list before its scrape
+// starts, so a trigger arriving during the scrape runs it again.
+func (q *triggerQueue) run(ctx context.Context, storageClient *storage.Client, bus events.Bus) {
+	for {
+		select {
+		case t := <-q.queue:
+			// Audited as the webhook's work, not the worker's user
+			work := audit.WithActor(ctx, "webhook:"+t.source.Name)
+			if t.push != nil {
+				q.refresh(work, storageClient, bus, t.source, t.push)
+				continue
+			}
+
+			q.mu.Lock()
+			delete(q.pending, pendingKey(t.source))
+			q.mu.Unlock()
+
+			pages, queued := publishScrapes(work, q.cfg.Load(), storageClient, bus, []config.Source{t.source})
+			reportFor(work, progress.Event{
+				Type:    progress.EventInfo,
+				URL:     t.source.URL,
+				Pages:   pages,
+				Message: fmt.Sprintf("Triggered scrape of %s: %d pages, %d scrapes queued for ingestion", t.source.Name, pages, queued),
+			})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh brings a directory source up to date with a push: it pulls the
+// checkout, deletes the removed files from the index, and publishes a
+// scrape of the changed ones for ingestion.
+func (q *triggerQueue) refresh(ctx context.Context, storageClient *storage.Client, bus events.Bus, source config.Source, push *webhook.Push) {
+	dirURL := scraper.FileURL(source.Path)
+	root, err := scraper.PullCheckout(ctx, source.Path)
+	if err != nil {
+		reportFor(ctx, progress.Event{Type: progress.EventError, URL: dirURL, Message: err.Error()})
+		return
+	}
+	changed := scraper.CheckoutFiles(root, source.Path, push.Changed)
+	removed := scraper.CheckoutFiles(root, source.Path, push.Removed)
+	reportFor(ctx, progress.Event{
+		Type:    progress.EventInfo,
+		URL:     dirURL,
+		Message: fmt.Sprintf("Push to %s: %d changed and %d removed files in %s", push.Repo, len(changed), len(removed), source.Name),
+	})
+
+	cfg := q.cfg.Load()
+	eff := cfg.ForSource(source)
+	if len(removed) > 0 {
+		index, err := newSearchBackend(&eff)
+		if err != nil {
+			reportFor(ctx, progress.Event{Type: progress.EventError, URL: dirURL, Message: err.Error()})
+		} else {
+			defer index.Close()
+			deleteFiles(ctx, index, removed)
+		}
+	}
+
+	if len(changed) == 0 {
+		return
+	}
+	s, err := newScraper(&eff, source)
+	if err != nil {
+		reportFor(ctx, progress.Event{Type: progress.EventError, URL: dirURL, Message: err.Error()})
+		return
+	}
+	result := scrapeDirToS3(ctx, s, storageClient, source.Path, changed)
+	if result != nil {
+		publishScrape(ctx, newNotifier(cfg), storageClient, bus, result, source.Name)
+	}
+}
+
+// startTriggerServer accepts scrape triggers in the background until ctx
+// is done, if --trigger-addr is set, and runs them on wg. Like the health
+// endpoints, a failing server is reported but does not stop the command.
+// Returns the queue the triggers wait in, or nil if they are disabled.
+func startTriggerServer(ctx context.Context, wg *sync.WaitGroup, cfg *config.Config, storageClient *storage.Client, bus events.Bus) (*triggerQueue, error) {
+	if triggerAddr == "" {
+		return nil, nil
+	}
+	if cfg.Triggers.Secret == "" {
+		return nil, fmt.Errorf("--trigger-addr requires triggers.secret in config")
+	}
+
+	queue := newTriggerQueue(cfg)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		queue.run(ctx, storageClient, bus)
+	}()
+	go func() {
+		if err := health.Serve(ctx, triggerAddr, webhook.Receiver(queue.secret, queue.enqueue, queue.enqueuePush)); err != nil {
+			slog.Warn("trigger endpoint disabled", "error", err)
+		}
+	}()
+	slog.Info("accepting scrape triggers", "addr", triggerAddr)
+	return queue, nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mfenderov/bam-rag/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Long: `Print the bam-rag version, commit, build date, and the Go and
+Elasticsearch client versions. Include this output in bug reports.`,
+	Args: cobra.NoArgs,
+	RunE: runVersion,
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.Version = version.Get().String()
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	info := version.Get()
+
+	if jsonOutput() {
+		output, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Printf("bam-rag %s\n", info.Version)
+	fmt.Printf("  Commit:     %s\n", valueOr(info.Commit, "unknown"))
+	fmt.Printf("  Built:      %s\n", valueOr(info.Date, "unknown"))
+	fmt.Printf("  Go:         %s %s\n", info.GoVersion, info.Platform)
+	fmt.Printf("  ES client:  %s\n", info.ESClientVersion)
+
+	return nil
+}
+
+// valueOr returns s, or fallback if s is empty.
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
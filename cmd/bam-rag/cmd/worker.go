@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/events"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+var workerRetryInterval time.Duration
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run a long-lived ingestion worker",
+	Long: `Run as a dedicated ingestion worker: ingest every scrape published on
+the event bus, and periodically pick up jobs left pending or failed by
+interrupted runs, until interrupted.
+
+This decouples scraping from ingestion. Scrape machines run
+'bam-rag scrape --no-ingest' (or 'bam-rag schedule') and publish their
+scrapes; the machine with the GPU runs 'bam-rag worker' and does the
+embeddings and LLM enrichment. Both need the same storage and events
+config; requires an external event bus (nats, kafka, or sqs).
+
+With --health-addr, /healthz and /readyz (which checks Elasticsearch,
+storage, and the model sockets) are served over HTTP for orchestrators.
+With --trigger-addr, signed POST /trigger requests naming a configured
+source, or a URL under one, scrape it and queue the scrape for ingestion,
+so publishing docs can refresh the corpus; set triggers.secret in config.
+GitHub push webhooks sent to /github refresh the files they change in
+directory sources tied to the repository with a github block.
+With --dump-dir, SIGQUIT writes goroutine and heap profiles there instead
+of stopping the worker; --pprof serves live profiles.
+
+Changes to the config file are picked up while the worker runs: scrapes
+and jobs started after an edit use its sources, models, and job settings,
+and triggers resolve against its sources and are verified with its
+secret. Storage and the event bus are read once at start. Invalid edits,
+and edits removing the trigger secret, are rejected and the previous
+configuration is kept.
+
+Examples:
+  # Ingest published scrapes, retrying unfinished jobs every 5 minutes
+  bam-rag worker
+
+  # Only ingest published scrapes
+  bam-rag worker --retry-interval 0
+
+  # Also scrape sources when a docs pipeline asks
+  bam-rag worker --trigger-addr :8082`,
+	RunE: runWorker,
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+
+	workerCmd.Flags().DurationVar(&workerRetryInterval, "retry-interval", 5*time.Minute, "How often to run due pending and failed jobs (0 to disable)")
+	addHealthFlag(workerCmd)
+	addTriggerFlag(workerCmd)
+	addDumpFlag(workerCmd)
+}
+
+func runWorker(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := GetConfig()
+	if !externalBus(&cfg) {
+		return fmt.Errorf("worker requires an external event bus - set events.bus in config")
+	}
+
+	storageClient, err := newStorageClient(&cfg)
+	if err != nil {
+		return err
+	}
+
+	index, err := newSearchBackend(&cfg)
+	if err != nil {
+		return err
+	}
+	startHealthServer(ctx, ingestionChecks(&cfg, index, storageClient)...)
+	startProfileDumps(ctx)
+
+	bus, err := newEventBus(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	defer bus.Close()
+
+	// The engines and the tally are shared by the subscription and the
+	// retry loop, which take turns ingesting. The engines are swapped on
+	// config reload; an ingestion already running finishes with the old ones.
+	var mu sync.Mutex
+	var tally ingestTally
+	var engines atomic.Pointer[sourceEngines]
+	engines.Store(newSourceEngines(&cfg, storageClient))
+
+	var wg sync.WaitGroup
+	triggers, err := startTriggerServer(ctx, &wg, &cfg, storageClient, bus)
+	if err != nil {
+		return err
+	}
+
+	// Apply config file edits without restarting the worker
+	watchConfig(func(next config.Config) error {
+		if triggers != nil && next.Triggers.Secret == "" {
+			return errors.New("triggers.secret: must be set while --trigger-addr accepts triggers")
+		}
+		reloaded := newSourceEngines(&next, storageClient)
+		// Build the global engine now, so clients that cannot be created
+		// reject the reload instead of failing the next ingestion
+		if _, err := reloaded.get(""); err != nil {
+			return err
+		}
+		engines.Store(reloaded)
+		if triggers != nil {
+			triggers.setConfig(&next)
+		}
+		return nil
+	})
+	if workerRetryInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(workerRetryInterval)
+			defer ticker.Stop()
+			for {
+				mu.Lock()
+				retryDueJobs(ctx, engines.Load(), &tally)
+				mu.Unlock()
+				flushAuditLog()
+
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	reporter.Report(progress.Event{Type: progress.EventInfo, Message: fmt.Sprintf("Worker waiting for scrape events on %s bus (Ctrl+C to stop)...", cfg.Events.Bus)})
+
+	err = events.SubscribeScrapeComplete(ctx, bus, func(ctx context.Context, event events.ScrapeCompleteEvent) error {
+		mu.Lock()
+		err := ingestScrapeEvents(bus, engines.Load(), &tally, true)(ctx, event)
+		mu.Unlock()
+		flushAuditLog()
+		return err
+	})
+	stop()
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("event subscription failed: %w", err)
+	}
+
+	reporter.Report(progress.Event{
+		Type:     progress.EventSummary,
+		Docs:     tally.docs,
+		Duration: tally.duration,
+		Stages:   tally.stages,
+		Message:  fmt.Sprintf("\nTotal: %d docs indexed in %v", tally.docs, tally.duration),
+	})
+	return nil
+}
+
+// retryDueJobs ingests the jobs that are due to run again. Failures are
+// reported and stay recorded for the next round.
+func retryDueJobs(ctx context.Context, engines *sourceEngines, tally *ingestTally) {
+	jobs, err := engines.jobs.Due(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			reporter.Report(progress.Event{Type: progress.EventWarning, Message: fmt.Sprintf("failed to list jobs: %v", err)})
+		}
+		return
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		reporter.Report(progress.Event{Type: progress.EventIngestStart, Prefix: job.Prefix})
+
+		result, err := engines.ingest(ctx, job.Prefix, job.Source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			reporter.Report(progress.Event{Type: progress.EventError, Prefix: job.Prefix, Message: err.Error()})
+			continue
+		}
+
+		tally.add(result)
+		reportIngestResult(ctx, result)
+	}
+}
@@ -0,0 +1,157 @@
+// Package analytics records what MCP clients search for and which results
+// they go on to read, so corpus owners can see what users ask and where
+// retrieval fails.
+package analytics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+)
+
+// Type distinguishes searches from document fetches.
+type Type string
+
+const (
+	TypeSearch Type = "search"
+	TypeFetch  Type = "fetch"
+)
+
+// Record is one logged search or fetch.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session"` // Identifies the server process, and so the client, that logged the record
+	Type       Type      `json:"type"`
+	Tool       string    `json:"tool"`                  // MCP tool that was called
+	Query      string    `json:"query,omitempty"`       // Fetches: the search that returned the document, if any
+	Results    int       `json:"results"`               // Searches: number of results
+	ResultIDs  []string  `json:"result_ids,omitempty"`  // Searches: IDs of the documents returned, in rank order
+	DocumentID string    `json:"document_id,omitempty"` // Fetches: the document read
+	Rank       int       `json:"rank,omitempty"`        // Fetches: 1-based position of the document in the search results
+}
+
+// recentSearches is how many searches a fetch is matched against.
+const recentSearches = 20
+
+// Log writes records to an Elasticsearch index. It is safe for concurrent
+// use. Failures to write are logged and never fail the calling tool.
+type Log struct {
+	client  *elasticsearch.Client
+	index   string
+	session string
+	created atomic.Bool // Whether the index is known to exist
+
+	mu     sync.Mutex
+	recent []Record // Latest searches, oldest first
+}
+
+// New creates a log writing to index through client.
+func New(client *elasticsearch.Client, index string) *Log {
+	return &Log{
+		client:  client,
+		index:   index,
+		session: time.Now().UTC().Format("2006-01-02T15-04-05") + "-" + randomSuffix(),
+	}
+}
+
+// esProperties maps record fields for filtering and aggregation.
+var esProperties = map[string]interface{}{
+	"time":        map[string]interface{}{"type": "date"},
+	"session":     map[string]interface{}{"type": "keyword"},
+	"type":        map[string]interface{}{"type": "keyword"},
+	"tool":        map[string]interface{}{"type": "keyword"},
+	"query":       map[string]interface{}{"type": "keyword"},
+	"results":     map[string]interface{}{"type": "integer"},
+	"result_ids":  map[string]interface{}{"type": "keyword"},
+	"document_id": map[string]interface{}{"type": "keyword"},
+	"rank":        map[string]interface{}{"type": "integer"},
+}
+
+// Search records a search and the IDs of the documents it returned, in
+// rank order.
+func (l *Log) Search(ctx context.Context, tool, query string, ids []string) {
+	r := Record{
+		Time:      time.Now().UTC(),
+		Session:   l.session,
+		Type:      TypeSearch,
+		Tool:      tool,
+		Query:     normalize(query),
+		Results:   len(ids),
+		ResultIDs: ids,
+	}
+
+	l.remember(r)
+	l.append(ctx, r)
+}
+
+// remember keeps a search for attributing later fetches.
+func (l *Log) remember(r Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recent = append(l.recent, r)
+	if len(l.recent) > recentSearches {
+		l.recent = l.recent[1:]
+	}
+}
+
+// Fetch records that a document was read. A fetch of a document returned
+// by a recent search is attributed to the latest such search.
+func (l *Log) Fetch(ctx context.Context, tool, id string) {
+	r := Record{
+		Time:       time.Now().UTC(),
+		Session:    l.session,
+		Type:       TypeFetch,
+		Tool:       tool,
+		DocumentID: id,
+	}
+	r.Query, r.Rank = l.attribute(id)
+	l.append(ctx, r)
+}
+
+// attribute returns the query and rank of the latest recent search that
+// returned document id, or "" and 0 if none did.
+func (l *Log) attribute(id string) (string, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.recent) - 1; i >= 0; i-- {
+		if rank := slices.Index(l.recent[i].ResultIDs, id); rank >= 0 {
+			return l.recent[i].Query, rank + 1
+		}
+	}
+	return "", 0
+}
+
+// append writes a record, creating the index on first use.
+func (l *Log) append(ctx context.Context, r Record) {
+	if !l.created.Load() {
+		if err := l.client.CreateLogIndex(ctx, l.index, esProperties); err != nil {
+			slog.Warn("failed to create analytics index", "index", l.index, "error", err)
+			return
+		}
+		l.created.Store(true)
+	}
+	if err := l.client.AppendLog(ctx, l.index, []any{r}); err != nil {
+		slog.Warn("failed to write analytics record", "index", l.index, "error", err)
+	}
+}
+
+// normalize collapses whitespace and case, so repeats of a query are
+// counted together.
+func normalize(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// randomSuffix returns a short random hex string that keeps session IDs unique.
+func randomSuffix() string {
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLog_Attribute(t *testing.T) {
+	l := &Log{}
+	l.remember(Record{Query: "goroutine leak", ResultIDs: []string{"a", "b", "c"}})
+	l.remember(Record{Query: "context cancel", ResultIDs: []string{"c", "d"}})
+
+	tests := []struct {
+		id        string
+		wantQuery string
+		wantRank  int
+	}{
+		{"b", "goroutine leak", 2},
+		{"c", "context cancel", 1}, // latest search wins
+		{"z", "", 0},
+	}
+	for _, tt := range tests {
+		query, rank := l.attribute(tt.id)
+		if query != tt.wantQuery || rank != tt.wantRank {
+			t.Errorf("attribute(%q) = %q, %d, want %q, %d", tt.id, query, rank, tt.wantQuery, tt.wantRank)
+		}
+	}
+}
+
+func TestLog_RememberKeepsRecentSearches(t *testing.T) {
+	l := &Log{}
+	for i := range recentSearches + 5 {
+		l.remember(Record{Query: fmt.Sprint(i), ResultIDs: []string{fmt.Sprint("doc", i)}})
+	}
+	if len(l.recent) != recentSearches {
+		t.Fatalf("kept %d searches, want %d", len(l.recent), recentSearches)
+	}
+	if query, _ := l.attribute("doc0"); query != "" {
+		t.Errorf("attribute(doc0) = %q, want oldest search forgotten", query)
+	}
+	if query, _ := l.attribute(fmt.Sprint("doc", recentSearches+4)); query != fmt.Sprint(recentSearches+4) {
+		t.Errorf("attribute() of latest search = %q", query)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := normalize("  How to  CANCEL\ta context "); got != "how to cancel a context" {
+		t.Errorf("normalize() = %q", got)
+	}
+}
+
+func TestParseSummary(t *testing.T) {
+	aggs := map[string]json.RawMessage{
+		"searches": json.RawMessage(`{"doc_count": 5}`),
+		"fetches":  json.RawMessage(`{"doc_count": 2}`),
+		"queries": json.RawMessage(`{"buckets": [
+			{"key": "http server", "doc_count": 5, "searches": {"doc_count": 3}, "fetches": {"doc_count": 2}},
+			{"key": "orphan", "doc_count": 1, "searches": {"doc_count": 0}, "fetches": {"doc_count": 1}}
+		]}`),
+		"unfetched": json.RawMessage(`{"buckets": [
+			{"key": "http server", "doc_count": 5, "fetches": {"doc_count": 2}},
+			{"key": "wasm", "doc_count": 2, "fetches": {"doc_count": 0}}
+		]}`),
+		"no_results": json.RawMessage(`{"doc_count": 2, "queries": {"buckets": [{"key": "wasm", "doc_count": 2}]}}`),
+		"documents": json.RawMessage(`{"doc_count": 2, "ids": {"buckets": [{"key": "doc1", "doc_count": 2}]},
+			"rank": {"values": {"50.0": 1.5}}}`),
+	}
+
+	got, err := parseSummary(aggs, 10)
+	if err != nil {
+		t.Fatalf("parseSummary() error = %v", err)
+	}
+	want := &Summary{
+		Searches:   5,
+		Fetches:    2,
+		Queries:    []QueryCount{{Query: "http server", Searches: 3, Fetches: 2}},
+		NoResults:  []Count{{Key: "wasm", Count: 2}},
+		Unfetched:  []Count{{Key: "wasm", Count: 2}},
+		Documents:  []Count{{Key: "doc1", Count: 2}},
+		MedianRank: 1.5,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSummary() = %+v, want %+v", got, want)
+	}
+
+	empty, err := parseSummary(nil, 10)
+	if err != nil || empty.Searches != 0 {
+		t.Errorf("parseSummary(nil) = %+v, %v, want empty summary", empty, err)
+	}
+}
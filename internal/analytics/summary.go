@@ -0,0 +1,175 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+)
+
+// Count is a value and how often it was seen.
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// QueryCount is how often a query was searched and how many fetches
+// followed it.
+type QueryCount struct {
+	Query    string `json:"query"`
+	Searches int    `json:"searches"`
+	Fetches  int    `json:"fetches"`
+}
+
+// Summary aggregates the records of a period.
+type Summary struct {
+	Searches   int          `json:"searches"`
+	Fetches    int          `json:"fetches"`
+	Queries    []QueryCount `json:"queries"`    // Most searched queries
+	NoResults  []Count      `json:"no_results"` // Most searched queries that returned nothing
+	Unfetched  []Count      `json:"unfetched"`  // Most searched queries none of whose results were read
+	Documents  []Count      `json:"documents"`  // Most fetched documents
+	MedianRank float64      `json:"median_rank,omitempty"`
+}
+
+// Summarize aggregates the records in index logged since the given time,
+// keeping the top limit entries of each list.
+func Summarize(ctx context.Context, client *elasticsearch.Client, index string, since time.Time, limit int) (*Summary, error) {
+	var filters []map[string]interface{}
+	if !since.IsZero() {
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"time": map[string]interface{}{"gte": since}},
+		})
+	}
+	isType := func(t Type) map[string]interface{} {
+		return map[string]interface{}{"term": map[string]interface{}{"type": t}}
+	}
+	terms := func(field string, size int) map[string]interface{} {
+		return map[string]interface{}{"terms": map[string]interface{}{"field": field, "size": size}}
+	}
+
+	queries := terms("query", limit)
+	queries["terms"].(map[string]interface{})["order"] = map[string]interface{}{"searches": "desc"}
+	queries["aggs"] = map[string]interface{}{
+		"searches": map[string]interface{}{"filter": isType(TypeSearch)},
+		"fetches":  map[string]interface{}{"filter": isType(TypeFetch)},
+	}
+
+	// Unfetched queries need every query's fetch count, so more are
+	// collected than are kept
+	unfetched := terms("query", limit*10)
+	unfetched["aggs"] = map[string]interface{}{
+		"fetches": map[string]interface{}{"filter": isType(TypeFetch)},
+	}
+
+	aggs, err := client.AggregateLog(ctx, index, filters, map[string]interface{}{
+		"searches":  map[string]interface{}{"filter": isType(TypeSearch)},
+		"fetches":   map[string]interface{}{"filter": isType(TypeFetch)},
+		"queries":   queries,
+		"unfetched": unfetched,
+		"no_results": map[string]interface{}{
+			"filter": map[string]interface{}{"bool": map[string]interface{}{"filter": []map[string]interface{}{
+				isType(TypeSearch),
+				{"term": map[string]interface{}{"results": 0}},
+			}}},
+			"aggs": map[string]interface{}{"queries": terms("query", limit)},
+		},
+		"documents": map[string]interface{}{
+			"filter": isType(TypeFetch),
+			"aggs": map[string]interface{}{
+				"ids":  terms("document_id", limit),
+				"rank": map[string]interface{}{"percentiles": map[string]interface{}{"field": "rank", "percents": []float64{50}}},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate analytics: %w", err)
+	}
+	return parseSummary(aggs, limit)
+}
+
+// bucket is a terms aggregation bucket with optional fetch count.
+type bucket struct {
+	Key      string `json:"key"`
+	DocCount int    `json:"doc_count"`
+	Searches struct {
+		DocCount int `json:"doc_count"`
+	} `json:"searches"`
+	Fetches struct {
+		DocCount int `json:"doc_count"`
+	} `json:"fetches"`
+}
+
+// parseSummary decodes the aggregations run by Summarize. A missing index
+// yields an empty summary.
+func parseSummary(aggs map[string]json.RawMessage, limit int) (*Summary, error) {
+	summary := &Summary{}
+	if aggs == nil {
+		return summary, nil
+	}
+
+	var parsed struct {
+		Searches struct {
+			DocCount int `json:"doc_count"`
+		} `json:"searches"`
+		Fetches struct {
+			DocCount int `json:"doc_count"`
+		} `json:"fetches"`
+		Queries struct {
+			Buckets []bucket `json:"buckets"`
+		} `json:"queries"`
+		Unfetched struct {
+			Buckets []bucket `json:"buckets"`
+		} `json:"unfetched"`
+		NoResults struct {
+			Queries struct {
+				Buckets []bucket `json:"buckets"`
+			} `json:"queries"`
+		} `json:"no_results"`
+		Documents struct {
+			IDs struct {
+				Buckets []bucket `json:"buckets"`
+			} `json:"ids"`
+			Rank struct {
+				Values map[string]*float64 `json:"values"`
+			} `json:"rank"`
+		} `json:"documents"`
+	}
+	for name, target := range map[string]any{
+		"searches":   &parsed.Searches,
+		"fetches":    &parsed.Fetches,
+		"queries":    &parsed.Queries,
+		"unfetched":  &parsed.Unfetched,
+		"no_results": &parsed.NoResults,
+		"documents":  &parsed.Documents,
+	} {
+		if err := json.Unmarshal(aggs[name], target); err != nil {
+			return nil, fmt.Errorf("failed to decode %s aggregation: %w", name, err)
+		}
+	}
+
+	summary.Searches = parsed.Searches.DocCount
+	summary.Fetches = parsed.Fetches.DocCount
+	for _, b := range parsed.Queries.Buckets {
+		if b.Searches.DocCount > 0 {
+			summary.Queries = append(summary.Queries, QueryCount{Query: b.Key, Searches: b.Searches.DocCount, Fetches: b.Fetches.DocCount})
+		}
+	}
+	for _, b := range parsed.Unfetched.Buckets {
+		if b.Fetches.DocCount == 0 && len(summary.Unfetched) < limit {
+			summary.Unfetched = append(summary.Unfetched, Count{Key: b.Key, Count: b.DocCount})
+		}
+	}
+	for _, b := range parsed.NoResults.Queries.Buckets {
+		summary.NoResults = append(summary.NoResults, Count{Key: b.Key, Count: b.DocCount})
+	}
+	for _, b := range parsed.Documents.IDs.Buckets {
+		summary.Documents = append(summary.Documents, Count{Key: b.Key, Count: b.DocCount})
+	}
+	if median := parsed.Documents.Rank.Values["50.0"]; median != nil {
+		summary.MedianRank = *median
+	}
+	return summary, nil
+}
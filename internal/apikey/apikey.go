@@ -0,0 +1,158 @@
+// Package apikey authenticates clients of the HTTP servers with API keys.
+// Each key can be bound to a namespace and carry access labels, which
+// scope the documents its holder can see.
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Key is an API key clients present as "Authorization: Bearer <key>" or
+// "X-API-Key: <key>".
+type Key struct {
+	Name         string   `yaml:"name"`          // Shown in logs instead of the key
+	Secret       string   `yaml:"key"`           // The key itself
+	Namespace    string   `yaml:"namespace"`     // Only accepted by servers of this namespace; empty for any
+	AccessLabels []string `yaml:"access_labels"` // Granted on top of those every client has
+}
+
+// Keyring holds the accepted keys. It is safe for concurrent use, and its
+// keys can be replaced while requests are served.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []hashedKey
+}
+
+// hashedKey is a key with the digest its secret is compared by, so
+// comparisons take the same time whatever the secret's length.
+type hashedKey struct {
+	Key
+	digest [sha256.Size]byte
+}
+
+// NewKeyring creates a keyring accepting keys.
+func NewKeyring(keys []Key) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.Set(keys); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Set replaces the accepted keys. On error the previous keys are kept.
+func (k *Keyring) Set(keys []Key) error {
+	hashed := make([]hashedKey, 0, len(keys))
+	seen := make(map[[sha256.Size]byte]bool)
+	for i, key := range keys {
+		if key.Secret == "" {
+			return fmt.Errorf("api key %d (%s) has no key", i, key.Name)
+		}
+		digest := sha256.Sum256([]byte(key.Secret))
+		if seen[digest] {
+			return fmt.Errorf("api key %d (%s) duplicates another key", i, key.Name)
+		}
+		seen[digest] = true
+		hashed = append(hashed, hashedKey{Key: key, digest: digest})
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = hashed
+	return nil
+}
+
+// Len returns the number of accepted keys.
+func (k *Keyring) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys)
+}
+
+// Lookup returns the key with the given secret. Every key is compared, so
+// the time taken does not reveal which one matched.
+func (k *Keyring) Lookup(secret string) (Key, bool) {
+	digest := sha256.Sum256([]byte(secret))
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	var found Key
+	ok := false
+	for _, key := range k.keys {
+		if subtle.ConstantTimeCompare(digest[:], key.digest[:]) == 1 {
+			found, ok = key.Key, true
+		}
+	}
+	return found, ok
+}
+
+// Middleware rejects requests without a key of the keyring with 401, and
+// those whose key is bound to a namespace other than the server's with
+// 403. The key of accepted requests is added to their context. While the
+// keyring is empty, every request is let through.
+func (k *Keyring) Middleware(namespace string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if k.Len() == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, ok := k.Lookup(secret(r))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bam-rag"`)
+			http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if key.Namespace != "" && key.Namespace != namespace {
+			http.Error(w, fmt.Sprintf("API key %s is not valid for this namespace", key.Name), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), key)))
+	})
+}
+
+// secret returns the key a request carries, or "".
+func secret(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying the key a request was authenticated with.
+func NewContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key a request was authenticated with, if any.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
+
+// LoadFile reads keys from a YAML list of entries with the fields of Key,
+// such as a mounted secret.
+func LoadFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys: %w", err)
+	}
+	var keys []Key
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys in %s: %w", path, err)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no api keys in " + path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	keyring, err := NewKeyring([]Key{
+		{Name: "any", Secret: "k-any"},
+		{Name: "payments", Secret: "k-payments", Namespace: "payments", AccessLabels: []string{"finance"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen Key
+	handler := keyring.Middleware("search", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no key", "", "", http.StatusUnauthorized},
+		{"unknown key", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"bearer", "Authorization", "Bearer k-any", http.StatusOK},
+		{"header", "X-API-Key", "k-any", http.StatusOK},
+		{"other namespace", "X-API-Key", "k-payments", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	if seen.Name != "any" {
+		t.Errorf("key in context = %+v, want any", seen)
+	}
+
+	payments := keyring.Middleware("payments", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("X-API-Key", "k-payments")
+	payments.ServeHTTP(httptest.NewRecorder(), req)
+	if !slices.Equal(seen.AccessLabels, []string{"finance"}) {
+		t.Errorf("key in context = %+v, want payments with its labels", seen)
+	}
+}
+
+func TestKeyring_Set(t *testing.T) {
+	keyring, err := NewKeyring([]Key{{Name: "a", Secret: "k1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Set([]Key{{Name: "a", Secret: "k2"}, {Name: "b", Secret: "k2"}}); err == nil {
+		t.Error("Set() with duplicate keys = nil, want an error")
+	}
+	if _, ok := keyring.Lookup("k1"); !ok {
+		t.Error("previous keys not kept after a failed Set()")
+	}
+	if err := keyring.Set([]Key{{Name: "empty"}}); err == nil {
+		t.Error("Set() with an empty key = nil, want an error")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	content := "- name: bot\n  key: s3cr3t\n  namespace: payments\n  access_labels: [finance]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Secret != "s3cr3t" || keys[0].Namespace != "payments" || keys[0].AccessLabels[0] != "finance" {
+		t.Errorf("LoadFile() = %+v", keys)
+	}
+}
+
+func TestMiddleware_NoKeys(t *testing.T) {
+	keyring, _ := NewKeyring(nil)
+	handler := keyring.Middleware("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status without keys = %d, want 200", rec.Code)
+	}
+}
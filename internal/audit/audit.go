@@ -0,0 +1,253 @@
+package audit
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/scraper"
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// Record is one audited pipeline event.
+type Record struct {
+	Time     time.Time          `json:"time"`
+	Run      string             `json:"run"`             // Identifies the command run that produced the record
+	Command  string             `json:"command"`         // e.g. "bam-rag scrape"
+	Actor    string             `json:"actor,omitempty"` // Who ran the command, e.g. a user name
+	Type     progress.EventType `json:"type"`
+	Host     string             `json:"host,omitempty"` // Source host, or local/<dir> for local sources
+	URL      string             `json:"url,omitempty"`
+	Prefix   string             `json:"prefix,omitempty"`
+	Pages    int                `json:"pages,omitempty"`
+	Docs     int                `json:"docs,omitempty"`
+	Duration time.Duration      `json:"duration_ns,omitempty"`
+	Message  string             `json:"message,omitempty"`
+	IDs      []string           `json:"ids,omitempty"` // Documents affected, if known
+}
+
+// EventCommandFailed records a command that exited with an error.
+const EventCommandFailed progress.EventType = "command_failed"
+
+// Query selects records.
+type Query struct {
+	Host  string               // Only records of this host
+	Actor string               // Only records of this actor
+	ID    string               // Only records affecting this document
+	Types []progress.EventType // Only records of these types; empty for all
+	Since time.Time            // Only records at or after this time
+	Limit int                  // Maximum number of records, newest first
+
+	// Source, if set, selects the records of one config source
+	Source *SourceRecords
+}
+
+// SourceRecords identifies the records of a config source. Records of a
+// scrape are matched by its prefix, since several sources can share a
+// host; records without one by the source's URLs.
+type SourceRecords struct {
+	Prefixes  []string // The source's scrape prefixes
+	URL       string   // Its start URL, recorded by source-wide events such as deletes
+	URLPrefix string   // Covers its pages and no other source's; empty if there is none
+}
+
+// matches reports whether r is a record of the source.
+func (s SourceRecords) matches(r Record) bool {
+	if r.Prefix != "" {
+		return slices.Contains(s.Prefixes, r.Prefix)
+	}
+	return r.URL != "" && (r.URL == s.URL || s.URLPrefix != "" && strings.HasPrefix(r.URL, s.URLPrefix))
+}
+
+// matches reports whether r is selected by q, ignoring Limit.
+func (q Query) matches(r Record) bool {
+	if q.Host != "" && r.Host != q.Host {
+		return false
+	}
+	if q.Source != nil && !q.Source.matches(r) {
+		return false
+	}
+	if q.Actor != "" && r.Actor != q.Actor {
+		return false
+	}
+	if q.ID != "" && !slices.Contains(r.IDs, q.ID) {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if r.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Store persists audit records. Append is called once per batch, named
+// after the run that produced it.
+type Store interface {
+	Append(ctx context.Context, batch string, records []Record) error
+	Query(ctx context.Context, q Query) ([]Record, error)
+}
+
+// audited lists the event types worth keeping. Per-page and per-document
+// progress and informational messages are left out.
+var audited = map[progress.EventType]bool{
+	progress.EventScrapeStart:    true,
+	progress.EventScrapeComplete: true,
+	progress.EventIngestStart:    true,
+	progress.EventIngestComplete: true,
+	progress.EventDelete:         true,
+	progress.EventBackfill:       true,
+	progress.EventWarning:        true,
+	progress.EventError:          true,
+	progress.EventSummary:        true,
+	EventCommandFailed:           true,
+}
+
+// Log is a progress.Reporter that collects the audited events of one
+// command run and writes them to a Store when closed.
+type Log struct {
+	store   Store
+	run     string
+	command string
+	actor   string
+
+	mu      sync.Mutex
+	records []Record
+	batches int // Batches written so far
+}
+
+// NewLog creates a log for a run of command.
+func NewLog(store Store, command string) *Log {
+	now := time.Now().UTC()
+	return &Log{
+		store:   store,
+		run:     now.Format("2006-01-02T15-04-05") + "-" + randomSuffix(),
+		command: command,
+	}
+}
+
+// SetActor records who runs the command, e.g. a user name, with every
+// record whose event names no actor of its own.
+func (l *Log) SetActor(actor string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.actor = actor
+}
+
+// actorKey is the context key of the actor set by WithActor.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx whose work is done on behalf of actor,
+// e.g. webhook:<source> for work a webhook triggered, rather than whoever
+// runs the command.
+func WithActor(ctx context.Context, actor string) context.Context {
+	if actor == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set on ctx by WithActor, or "" if there is
+// none.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Report records e if its type is audited. The event's actor, if set,
+// takes precedence over the log's.
+func (l *Log) Report(e progress.Event) {
+	if !audited[e.Type] {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, Record{
+		Time:     e.Time.UTC(),
+		Run:      l.run,
+		Command:  l.command,
+		Actor:    cmp.Or(e.Actor, l.actor),
+		Type:     e.Type,
+		Host:     hostOf(e.Prefix, e.URL),
+		URL:      e.URL,
+		Prefix:   e.Prefix,
+		Pages:    e.Pages,
+		Docs:     e.Docs,
+		Duration: e.Duration,
+		Message:  strings.TrimSpace(e.Message),
+		IDs:      e.IDs,
+	})
+}
+
+// Flush writes the records collected so far, so long-running commands need
+// not wait until they exit. Each flush is stored as a separate batch of the
+// run; nothing is written if no records were collected.
+func (l *Log) Flush(ctx context.Context) error {
+	l.mu.Lock()
+	records := l.records
+	l.records = nil
+	batch := l.run
+	if l.batches > 0 {
+		batch = fmt.Sprintf("%s-%d", l.run, l.batches)
+	}
+	if len(records) > 0 {
+		l.batches++
+	}
+	l.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+	slog.Debug("writing audit records", "batch", batch, "count", len(records))
+	return l.store.Append(ctx, batch, records)
+}
+
+// Close records cmdErr, if any, and writes the remaining records.
+func (l *Log) Close(ctx context.Context, cmdErr error) error {
+	if cmdErr != nil {
+		l.Report(progress.Event{Type: EventCommandFailed, Message: cmdErr.Error()})
+	}
+	return l.Flush(ctx)
+}
+
+// hostOf returns the host segment of a scrape prefix
+// (scrapes/<host>/<timestamp>), or the one a URL would be scraped under.
+func hostOf(prefix, rawURL string) string {
+	if host := storage.HostFromPrefix(prefix); host != "" {
+		return host
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if u.Scheme == "file" {
+		return scraper.DirPrefixHost(u.Path)
+	}
+	return scraper.PrefixHost(u)
+}
+
+// randomSuffix returns a short random hex string that keeps run IDs unique.
+func randomSuffix() string {
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
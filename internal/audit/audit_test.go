@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/progress"
+)
+
+// memStore keeps appended records in memory.
+type memStore struct {
+	runs    []string
+	records []Record
+}
+
+func (s *memStore) Append(ctx context.Context, batch string, records []Record) error {
+	s.runs = append(s.runs, batch)
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memStore) Query(ctx context.Context, q Query) ([]Record, error) {
+	var records []Record
+	for _, r := range s.records {
+		if q.matches(r) {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func TestLog(t *testing.T) {
+	store := &memStore{}
+	log := NewLog(store, "bam-rag scrape")
+	log.SetActor("alice")
+
+	log.Report(progress.Event{Type: progress.EventScrapeStart, URL: "https://go.dev/doc/"})
+	log.Report(progress.Event{Type: progress.EventPage, URL: "https://go.dev/doc/a"})
+	log.Report(progress.Event{Type: progress.EventIngestComplete, Prefix: "scrapes/go.dev/2024-12-04T17-30-00-abc", Docs: 3})
+	log.Report(progress.Event{Type: progress.EventDelete, URL: "file:///home/me/team-docs/", Docs: 2, IDs: []string{"a", "b"}})
+	log.Report(progress.Event{Type: progress.EventInfo, Message: "not audited"})
+
+	if err := log.Close(t.Context(), errors.New("2 sources failed")); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []struct {
+		typ  progress.EventType
+		host string
+	}{
+		{progress.EventScrapeStart, "go.dev"},
+		{progress.EventIngestComplete, "go.dev"},
+		{progress.EventDelete, "local/team-docs"},
+		{EventCommandFailed, ""},
+	}
+	if len(store.records) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(store.records), len(want), store.records)
+	}
+	for i, w := range want {
+		r := store.records[i]
+		if r.Type != w.typ || r.Host != w.host {
+			t.Errorf("record %d = %s/%q, want %s/%q", i, r.Type, r.Host, w.typ, w.host)
+		}
+		if r.Command != "bam-rag scrape" || r.Actor != "alice" || r.Run != store.runs[0] || r.Time.IsZero() {
+			t.Errorf("record %d = %+v, want command, actor, run, and time set", i, r)
+		}
+	}
+	if got := store.records[2].IDs; len(got) != 2 {
+		t.Errorf("delete record IDs = %v, want [a b]", got)
+	}
+	if got := store.records[3].Message; got != "2 sources failed" {
+		t.Errorf("failure message = %q", got)
+	}
+}
+
+func TestLog_EventActor(t *testing.T) {
+	store := &memStore{}
+	log := NewLog(store, "bam-rag worker")
+	log.SetActor("alice")
+
+	ctx := WithActor(t.Context(), "webhook:docs")
+	log.Report(progress.Event{Type: progress.EventDelete, URL: "file:///docs/a.md", Actor: ActorFrom(ctx)})
+	log.Report(progress.Event{Type: progress.EventDelete, URL: "file:///docs/b.md", Actor: ActorFrom(t.Context())})
+	if err := log.Close(t.Context(), nil); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(store.records) != 2 {
+		t.Fatalf("got %d records, want 2", len(store.records))
+	}
+	if got := store.records[0].Actor; got != "webhook:docs" {
+		t.Errorf("actor of a triggered event = %q, want webhook:docs", got)
+	}
+	if got := store.records[1].Actor; got != "alice" {
+		t.Errorf("actor of an event without one = %q, want the log's", got)
+	}
+}
+
+func TestLog_CloseWithoutRecords(t *testing.T) {
+	store := &memStore{}
+	log := NewLog(store, "bam-rag search")
+	log.Report(progress.Event{Type: progress.EventInfo, Message: "searching"})
+
+	if err := log.Close(t.Context(), nil); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(store.runs) != 0 {
+		t.Errorf("runs without audited events should not be written, got %v", store.runs)
+	}
+}
+
+func TestLog_Flush(t *testing.T) {
+	store := &memStore{}
+	log := NewLog(store, "bam-rag worker")
+
+	log.Report(progress.Event{Type: progress.EventIngestComplete, Prefix: "scrapes/go.dev/1"})
+	if err := log.Flush(t.Context()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	log.Flush(t.Context()) // Nothing new; writes nothing
+	log.Report(progress.Event{Type: progress.EventIngestComplete, Prefix: "scrapes/go.dev/2"})
+	log.Close(t.Context(), nil)
+
+	if len(store.runs) != 2 || store.runs[1] != store.runs[0]+"-1" {
+		t.Errorf("batches = %v, want the run followed by <run>-1", store.runs)
+	}
+	if len(store.records) != 2 || store.records[0].Run != store.records[1].Run {
+		t.Errorf("records = %+v, want two records of one run", store.records)
+	}
+}
+
+func TestQuery_Matches(t *testing.T) {
+	now := time.Date(2024, 12, 4, 17, 30, 0, 0, time.UTC)
+	r := Record{Time: now, Type: progress.EventError, Host: "go.dev", Actor: "alice", IDs: []string{"abc"}}
+
+	tests := []struct {
+		name string
+		q    Query
+		want bool
+	}{
+		{"empty", Query{}, true},
+		{"host", Query{Host: "go.dev"}, true},
+		{"other host", Query{Host: "example.com"}, false},
+		{"actor", Query{Actor: "alice"}, true},
+		{"other actor", Query{Actor: "bob"}, false},
+		{"id", Query{ID: "abc"}, true},
+		{"other id", Query{ID: "def"}, false},
+		{"type", Query{Types: []progress.EventType{progress.EventWarning, progress.EventError}}, true},
+		{"other type", Query{Types: []progress.EventType{progress.EventScrapeComplete}}, false},
+		{"since before", Query{Since: now.Add(-time.Hour)}, true},
+		{"since after", Query{Since: now.Add(time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.q.matches(r); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceRecords_Matches(t *testing.T) {
+	source := SourceRecords{
+		Prefixes:  []string{"scrapes/example.com/1"},
+		URL:       "https://example.com/changelog",
+		URLPrefix: "https://example.com/changelog/",
+	}
+
+	tests := []struct {
+		name string
+		r    Record
+		want bool
+	}{
+		{"own scrape", Record{Prefix: "scrapes/example.com/1", URL: "https://example.com/docs/"}, true},
+		{"other scrape on the host", Record{Prefix: "scrapes/example.com/2", URL: "https://example.com/changelog"}, false},
+		{"source-wide event", Record{URL: "https://example.com/changelog"}, true},
+		{"page under the prefix", Record{URL: "https://example.com/changelog/v2"}, true},
+		{"other page on the host", Record{URL: "https://example.com/docs/intro"}, false},
+		{"no URL", Record{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := source.matches(tt.r); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	if got := objectName("2024-12-04T17-30-00-abc123"); got != "2024-12-04/17-30-00-abc123.ndjson" {
+		t.Errorf("objectName() = %q", got)
+	}
+}
@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// ESStore keeps records in an Elasticsearch index.
+type ESStore struct {
+	client *elasticsearch.Client
+	index  string
+}
+
+// NewESStore creates a store writing to index through client.
+func NewESStore(client *elasticsearch.Client, index string) *ESStore {
+	return &ESStore{client: client, index: index}
+}
+
+// esProperties maps record fields for filtering and sorting.
+var esProperties = map[string]interface{}{
+	"time":        map[string]interface{}{"type": "date"},
+	"run":         map[string]interface{}{"type": "keyword"},
+	"command":     map[string]interface{}{"type": "keyword"},
+	"actor":       map[string]interface{}{"type": "keyword"},
+	"ids":         map[string]interface{}{"type": "keyword"},
+	"type":        map[string]interface{}{"type": "keyword"},
+	"host":        map[string]interface{}{"type": "keyword"},
+	"url":         map[string]interface{}{"type": "keyword"},
+	"prefix":      map[string]interface{}{"type": "keyword"},
+	"pages":       map[string]interface{}{"type": "integer"},
+	"docs":        map[string]interface{}{"type": "integer"},
+	"duration_ns": map[string]interface{}{"type": "long"},
+	"message":     map[string]interface{}{"type": "text"},
+}
+
+// Append indexes records, creating the index on first use.
+func (s *ESStore) Append(ctx context.Context, batch string, records []Record) error {
+	if err := s.client.CreateLogIndex(ctx, s.index, esProperties); err != nil {
+		return fmt.Errorf("failed to create audit index: %w", err)
+	}
+	entries := make([]any, len(records))
+	for i, r := range records {
+		entries[i] = r
+	}
+	if err := s.client.AppendLog(ctx, s.index, entries); err != nil {
+		return fmt.Errorf("failed to write audit records: %w", err)
+	}
+	return nil
+}
+
+// Query searches the index for matching records.
+func (s *ESStore) Query(ctx context.Context, q Query) ([]Record, error) {
+	var filters []map[string]interface{}
+	if q.Host != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"host": q.Host}})
+	}
+	if q.Source != nil {
+		filters = append(filters, q.Source.clause())
+	}
+	if q.Actor != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"actor": q.Actor}})
+	}
+	if q.ID != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"ids": q.ID}})
+	}
+	if len(q.Types) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"type": q.Types}})
+	}
+	if !q.Since.IsZero() {
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"time": map[string]interface{}{"gte": q.Since}},
+		})
+	}
+
+	entries, err := s.client.SearchLog(ctx, s.index, filters, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search audit records: %w", err)
+	}
+	records := make([]Record, 0, len(entries))
+	for _, entry := range entries {
+		var r Record
+		if err := json.Unmarshal(entry, &r); err != nil {
+			return nil, fmt.Errorf("failed to decode audit record: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// clause returns the ES filter clause matching the records of the source,
+// as matches does.
+func (s SourceRecords) clause() map[string]interface{} {
+	urls := []map[string]interface{}{{"term": map[string]interface{}{"url": s.URL}}}
+	if s.URLPrefix != "" {
+		urls = append(urls, map[string]interface{}{"prefix": map[string]interface{}{"url": s.URLPrefix}})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []map[string]interface{}{
+				{"terms": map[string]interface{}{"prefix": s.Prefixes}},
+				{"bool": map[string]interface{}{
+					"must_not":             map[string]interface{}{"exists": map[string]interface{}{"field": "prefix"}},
+					"should":               urls,
+					"minimum_should_match": 1,
+				}},
+			},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// S3Store keeps records as one NDJSON object per batch in the bucket.
+type S3Store struct {
+	client *storage.Client
+}
+
+// NewS3Store creates a store writing to the bucket of client.
+func NewS3Store(client *storage.Client) *S3Store {
+	return &S3Store{client: client}
+}
+
+// Append writes records as the batch's log object.
+func (s *S3Store) Append(ctx context.Context, batch string, records []Record) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to marshal audit record: %w", err)
+		}
+	}
+	return s.client.PutAuditLog(ctx, objectName(batch), buf.Bytes())
+}
+
+// Query reads the log objects written since q.Since and filters them.
+func (s *S3Store) Query(ctx context.Context, q Query) ([]Record, error) {
+	after := ""
+	if !q.Since.IsZero() {
+		// Day directories sort before their objects, so this lists the whole day
+		after = q.Since.UTC().Format("2006-01-02")
+	}
+	names, err := s.client.ListAuditLogs(ctx, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, name := range names {
+		data, err := s.client.GetAuditLog(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			var r Record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				return nil, fmt.Errorf("failed to decode audit record in %s: %w", name, err)
+			}
+			if q.matches(r) {
+				records = append(records, r)
+			}
+		}
+		// Objects are newest first; stop once enough records are found
+		if q.Limit > 0 && len(records) >= q.Limit {
+			break
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.After(records[j].Time) })
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[:q.Limit]
+	}
+	return records, nil
+}
+
+// objectName returns the log object name of a batch, grouped by day:
+// 2024-12-04T17-30-00-abc123 is stored as 2024-12-04/17-30-00-abc123.ndjson.
+func objectName(batch string) string {
+	day, rest, ok := strings.Cut(batch, "T")
+	if !ok {
+		return batch + ".ndjson"
+	}
+	return day + "/" + rest + ".ndjson"
+}
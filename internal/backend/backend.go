@@ -0,0 +1,92 @@
+// Package backend defines the interface between bam-rag and the index it
+// stores pages and chunks in, so ingestion, search, and the MCP server run
+// against Elasticsearch, the embedded store, or Qdrant alike.
+package backend
+
+import (
+	"context"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/embedded"
+	"github.com/mfenderov/bam-rag/internal/qdrant"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// SearchBackend indexes pages and their chunks and searches them.
+// Filters restrict results as documented on elasticsearch.Filter.
+type SearchBackend interface {
+	// Ping reports whether the backend is reachable.
+	Ping(ctx context.Context) bool
+	// CreateIndex prepares the backend for indexing; existing indexes are
+	// kept.
+	CreateIndex(ctx context.Context) error
+	// DeleteIndex removes every page and chunk.
+	DeleteIndex(ctx context.Context) error
+	// Refresh makes everything indexed so far searchable.
+	Refresh(ctx context.Context) error
+	// SetRefreshInterval changes how often indexed entries become
+	// searchable until restore is called; "" leaves it unchanged.
+	SetRefreshInterval(ctx context.Context, interval string) (restore func(context.Context) error, err error)
+
+	IndexDocument(ctx context.Context, doc models.Document) error
+	// IndexChunks replaces the chunks of a document.
+	IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error
+	// DeleteDocument removes a document and its chunks.
+	DeleteDocument(ctx context.Context, id string) error
+	// ContentHashes returns the content hash of each indexed document
+	// among ids, leaving out those not indexed or indexed without one.
+	ContentHashes(ctx context.Context, ids []string) (map[string]string, error)
+
+	// GetDocument and GetChunk return nil if the entry does not exist;
+	// GetDocuments and GetChunks leave missing entries out.
+	GetDocument(ctx context.Context, id string) (*models.Document, error)
+	GetDocuments(ctx context.Context, ids []string) ([]models.Document, error)
+	GetChunk(ctx context.Context, id string) (*models.Chunk, error)
+	GetChunks(ctx context.Context, ids []string) ([]models.Chunk, error)
+
+	// SearchFiltered returns the pages best matching query.
+	SearchFiltered(ctx context.Context, query string, limit int, filter elasticsearch.Filter) ([]models.Document, error)
+	// HybridSearchFiltered also ranks pages by the similarity of their
+	// embeddings to queryEmbedding.
+	HybridSearchFiltered(ctx context.Context, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter) ([]models.Document, error)
+	// SearchChunks returns the chunks best matching query, also ranked by
+	// embedding similarity if queryEmbedding is set.
+	SearchChunks(ctx context.Context, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter) ([]models.Chunk, error)
+	// SearchSections returns the chunks of one document best matching query.
+	SearchSections(ctx context.Context, documentID, query string, limit int, filter elasticsearch.Filter) ([]models.Chunk, error)
+	// Suggest returns query with its unknown terms corrected from the
+	// titles of documents matching filter, or "".
+	Suggest(ctx context.Context, query string, filter elasticsearch.Filter) (string, error)
+
+	// Close releases the connections or files the backend holds; it must
+	// not be used afterwards.
+	Close() error
+}
+
+// Pruner removes pages and their chunks from an index, by filter or by
+// ID, for the delete and gc commands. Qdrant does not support it.
+type Pruner interface {
+	// Count returns the number of pages matching filter.
+	Count(ctx context.Context, filter elasticsearch.Filter) (int, error)
+	// MatchingIDs returns the IDs of the pages matching filter.
+	MatchingIDs(ctx context.Context, filter elasticsearch.Filter) ([]string, error)
+	// DeleteByFilter removes the pages matching filter, refusing an empty
+	// one, and returns how many were deleted.
+	DeleteByFilter(ctx context.Context, filter elasticsearch.Filter) (int, error)
+	// DeleteDocuments removes pages by ID and returns how many existed.
+	DeleteDocuments(ctx context.Context, ids []string) (int, error)
+	// ScrollDocuments streams every page to fn, batchSize at a time,
+	// fetching at least the given fields.
+	ScrollDocuments(ctx context.Context, batchSize int, fn func(models.Document) error, fields ...string) error
+}
+
+var (
+	_ Pruner = (*elasticsearch.Client)(nil)
+	_ Pruner = (*embedded.Store)(nil)
+)
+
+var (
+	_ SearchBackend = (*elasticsearch.Client)(nil)
+	_ SearchBackend = (*embedded.Store)(nil)
+	_ SearchBackend = (*qdrant.Client)(nil)
+)
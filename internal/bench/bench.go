@@ -0,0 +1,182 @@
+// Package bench measures search latency by replaying queries against the
+// index.
+package bench
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadQueries reads a query file: one query per line, ignoring blank lines
+// and lines starting with #. A .yaml or .yml file is read as an eval query
+// set instead, so the queries of 'bam-rag eval' can be replayed as is.
+func LoadQueries(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries: %w", err)
+	}
+
+	var queries []string
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		var file struct {
+			Queries []struct {
+				Query string `yaml:"query"`
+			} `yaml:"queries"`
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse queries: %w", err)
+		}
+		for _, q := range file.Queries {
+			if q := strings.TrimSpace(q.Query); q != "" {
+				queries = append(queries, q)
+			}
+		}
+	default:
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				queries = append(queries, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read queries: %w", err)
+		}
+	}
+
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no queries in %s", path)
+	}
+	return queries, nil
+}
+
+// SearchFunc runs one query.
+type SearchFunc func(ctx context.Context, query string) error
+
+// Options controls how queries are replayed.
+type Options struct {
+	Concurrency int // Queries in flight at once; at least 1
+	Rounds      int // Times the query set is replayed; at least 1
+}
+
+// Report summarizes the latency of one search mode.
+type Report struct {
+	Mode        string        `json:"mode"`
+	Requests    int           `json:"requests"`
+	Errors      int           `json:"errors"`
+	Concurrency int           `json:"concurrency"`
+	Duration    time.Duration `json:"duration"` // Nanoseconds, wall clock for all requests
+	QPS         float64       `json:"qps"`
+	Mean        time.Duration `json:"mean"` // Latencies in nanoseconds, of successful requests
+	P50         time.Duration `json:"p50"`
+	P95         time.Duration `json:"p95"`
+	P99         time.Duration `json:"p99"`
+	Max         time.Duration `json:"max"`
+	FirstError  string        `json:"first_error,omitempty"`
+}
+
+// Run replays queries against search with opts.Concurrency workers and
+// reports their latency. Failed requests are counted but not timed. Run
+// stops early, with the requests made so far, once ctx is done.
+func Run(ctx context.Context, mode string, queries []string, opts Options, search SearchFunc) *Report {
+	concurrency := max(opts.Concurrency, 1)
+	rounds := max(opts.Rounds, 1)
+
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		for range rounds {
+			for _, q := range queries {
+				select {
+				case jobs <- q:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		errs      int
+		firstErr  error
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range jobs {
+				reqStart := time.Now()
+				err := search(ctx, q)
+				elapsed := time.Since(reqStart)
+
+				mu.Lock()
+				if err != nil {
+					errs++
+					if firstErr == nil {
+						firstErr = fmt.Errorf("%q: %w", q, err)
+					}
+				} else {
+					latencies = append(latencies, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := summarize(latencies)
+	report.Mode = mode
+	report.Requests = len(latencies) + errs
+	report.Errors = errs
+	report.Concurrency = concurrency
+	report.Duration = time.Since(start)
+	if report.Duration > 0 {
+		report.QPS = float64(report.Requests) / report.Duration.Seconds()
+	}
+	if firstErr != nil {
+		report.FirstError = firstErr.Error()
+	}
+	return report
+}
+
+// summarize computes the latency statistics of a report.
+func summarize(latencies []time.Duration) *Report {
+	report := &Report{}
+	if len(latencies) == 0 {
+		return report
+	}
+
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	report.Mean = total / time.Duration(len(latencies))
+	report.P50 = percentile(latencies, 50)
+	report.P95 = percentile(latencies, 95)
+	report.P99 = percentile(latencies, 99)
+	report.Max = latencies[len(latencies)-1]
+	return report
+}
+
+// percentile returns the p-th percentile of sorted latencies by the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
@@ -0,0 +1,93 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadQueries(t *testing.T) {
+	dir := t.TempDir()
+
+	text := filepath.Join(dir, "queries.txt")
+	os.WriteFile(text, []byte("# warm cache\ninstall go\n\n  goroutines  \n"), 0o644)
+	got, err := LoadQueries(text)
+	if err != nil {
+		t.Fatalf("LoadQueries() error = %v", err)
+	}
+	if want := []string{"install go", "goroutines"}; !slices.Equal(got, want) {
+		t.Errorf("LoadQueries(text) = %q, want %q", got, want)
+	}
+
+	golden := filepath.Join(dir, "golden.yaml")
+	os.WriteFile(golden, []byte("queries:\n  - query: install go\n    relevant: [https://go.dev/doc/install]\n"), 0o644)
+	got, err = LoadQueries(golden)
+	if err != nil {
+		t.Fatalf("LoadQueries() error = %v", err)
+	}
+	if want := []string{"install go"}; !slices.Equal(got, want) {
+		t.Errorf("LoadQueries(yaml) = %q, want %q", got, want)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	os.WriteFile(empty, []byte("# nothing\n"), 0o644)
+	if _, err := LoadQueries(empty); err == nil {
+		t.Error("LoadQueries(empty) should fail")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	search := func(ctx context.Context, query string) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if query == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	report := Run(context.Background(), "bm25", []string{"a", "b", "bad"}, Options{Concurrency: 2, Rounds: 4}, search)
+	if report.Requests != 12 || report.Errors != 4 {
+		t.Errorf("requests = %d, errors = %d, want 12 and 4", report.Requests, report.Errors)
+	}
+	if report.FirstError == "" {
+		t.Error("FirstError should be set")
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("%d queries in flight, want at most 2", got)
+	}
+	if report.P50 <= 0 || report.P50 > report.P95 || report.P95 > report.P99 || report.P99 > report.Max {
+		t.Errorf("latencies not ordered: p50 %v, p95 %v, p99 %v, max %v", report.P50, report.P95, report.P99, report.Max)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 50}, {95, 95}, {99, 99}, {100, 100}, {0, 1}} {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]time.Duration{7}, 99); got != 7 {
+		t.Errorf("percentile of one = %v, want 7", got)
+	}
+}
@@ -1,38 +1,151 @@
 package config
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // Config holds all application configuration.
 type Config struct {
+	Namespace     string        `mapstructure:"namespace"` // Tenant whose isolated corpus is served; empty for the shared one
+	Backend       Backend       `mapstructure:"backend"`
 	Elasticsearch Elasticsearch `mapstructure:"elasticsearch"`
 	Embeddings    Embeddings    `mapstructure:"embeddings"`
 	LLM           LLM           `mapstructure:"llm"`
+	Rerank        Rerank        `mapstructure:"rerank"`
 	Scraper       Scraper       `mapstructure:"scraper"`
 	Storage       Storage       `mapstructure:"storage"`
 	MCP           MCP           `mapstructure:"mcp"`
+	Events        Events        `mapstructure:"events"`
+	Jobs          Jobs          `mapstructure:"jobs"`
+	Audit         Audit         `mapstructure:"audit"`
+	Analytics     Analytics     `mapstructure:"analytics"`
+	SlowOps       SlowOps       `mapstructure:"slow_ops"`
+	Warmup        Warmup        `mapstructure:"warmup"`
+	Ingestion     Ingestion     `mapstructure:"ingestion"`
+	Redaction     Redaction     `mapstructure:"redaction"`
+	Telemetry     Telemetry     `mapstructure:"telemetry"`
 	Sources       []Source      `mapstructure:"sources"`
+	Auth          []DomainAuth  `mapstructure:"auth"`
+	Webhooks      []Webhook     `mapstructure:"webhooks"`
+	Triggers      Triggers      `mapstructure:"triggers"`
+	OpenAI        OpenAI        `mapstructure:"openai"`
+}
+
+// Backend selects the index pages and chunks are stored in and searched.
+type Backend struct {
+	Type   string `mapstructure:"type"` // elasticsearch, embedded, or qdrant
+	Path   string `mapstructure:"path"` // File the embedded backend keeps its index in
+	Qdrant Qdrant `mapstructure:"qdrant"`
+}
+
+// Qdrant holds connection configuration for the qdrant backend.
+type Qdrant struct {
+	URL        string `mapstructure:"url"`         // REST API base URL
+	APIKey     string `mapstructure:"api_key"`     // Optional
+	Collection string `mapstructure:"collection"`  // Chunks go to <collection>_chunks
+	VectorSize int    `mapstructure:"vector_size"` // Dimensions of the embedding model
 }
 
 // Elasticsearch holds ES connection configuration.
 type Elasticsearch struct {
-	Addresses []string `mapstructure:"addresses"`
-	Index     string   `mapstructure:"index"`
-	Username  string   `mapstructure:"username"`
-	Password  string   `mapstructure:"password"`
+	Addresses []string  `mapstructure:"addresses"`
+	CloudID   string    `mapstructure:"cloud_id"` // Elastic Cloud deployment to connect to instead of addresses
+	Index     string    `mapstructure:"index"`
+	Username  string    `mapstructure:"username"`
+	Password  string    `mapstructure:"password"`
+	APIKey    string    `mapstructure:"api_key"` // Base64-encoded API key, used instead of username and password
+	Mapping   Mapping   `mapstructure:"mapping"`
+	Fusion    Fusion    `mapstructure:"fusion"`
+	Recency   Recency   `mapstructure:"recency"`
+	Dedup     Dedup     `mapstructure:"dedup"`
+	Transport Transport `mapstructure:"transport"`
+
+	Fuzziness         string `mapstructure:"fuzziness"`           // Typos tolerated per term: AUTO, 0, 1, 2, or AUTO:low,high; empty for exact matching
+	FuzzyPrefixLength int    `mapstructure:"fuzzy_prefix_length"` // Leading characters that must match exactly
+
+	// Serverless marks an Elasticsearch Serverless project, which manages
+	// index settings such as the refresh interval itself; they are left alone
+	Serverless bool `mapstructure:"serverless"`
+}
+
+// Fusion controls how hybrid searches combine BM25 and vector results.
+type Fusion struct {
+	Method         string  `mapstructure:"method"`           // rrf or linear
+	RankConstant   int     `mapstructure:"rank_constant"`    // rrf: higher values flatten the gap between ranks
+	RankWindowSize int     `mapstructure:"rank_window_size"` // Results of each retriever considered; 0 for the number requested
+	BM25Weight     float64 `mapstructure:"bm25_weight"`      // linear: weight of the normalized BM25 score
+	VectorWeight   float64 `mapstructure:"vector_weight"`    // linear: weight of the normalized vector score
+}
+
+// Transport tunes the HTTP connections a client keeps to its server.
+// Zero fields use the client's defaults.
+type Transport struct {
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`    // Idle connections kept open for reuse
+	IdleConnTimeout time.Duration `mapstructure:"idle_conn_timeout"` // How long an unused connection is kept open
+	RequestTimeout  time.Duration `mapstructure:"request_timeout"`   // Limit on waiting for a single response; Elasticsearch deletes and refreshes have none
+}
+
+// Mapping customizes the index mapping generated when an index is created.
+// Existing indexes keep the mapping they were created with.
+type Mapping struct {
+	Analyzer         string                 `mapstructure:"analyzer"`          // Analyzer for content, tags, and summary
+	VectorSimilarity string                 `mapstructure:"vector_similarity"` // cosine, dot_product, l2_norm, or max_inner_product
+	Fields           map[string]interface{} `mapstructure:"fields"`            // Extra properties, merged over the defaults
+	Settings         map[string]interface{} `mapstructure:"settings"`          // Index settings, e.g. analysis or similarity
+}
+
+// Recency favors recently scraped pages in searches.
+type Recency struct {
+	Weight float64 `mapstructure:"weight"` // Share of a page's score that decays with age, 0 to 1; 0 disables
+	Scale  string  `mapstructure:"scale"`  // Age at which that share has decayed to decay, e.g. 180d
+	Offset string  `mapstructure:"offset"` // Age before pages start to decay
+	Decay  float64 `mapstructure:"decay"`  // Remaining share at offset+scale, between 0 and 1
+}
+
+// Dedup collapses search results that are copies of a better-ranked result.
+type Dedup struct {
+	URLs       bool    `mapstructure:"urls"`       // Collapse URLs differing only in scheme, www., trailing slash, index.html, or fragment
+	Similarity float64 `mapstructure:"similarity"` // Collapse pages sharing at least this share of their content, 0 to 1; 0 disables
+	PerSource  int     `mapstructure:"per_source"` // Keep at most this many results per source host; 0 for no limit
 }
 
 // Embeddings holds embeddings generation configuration.
 type Embeddings struct {
-	Enabled    bool   `mapstructure:"enabled"`
-	SocketPath string `mapstructure:"socket_path"`
-	Model      string `mapstructure:"model"`
+	Enabled    bool      `mapstructure:"enabled"`
+	SocketPath string    `mapstructure:"socket_path"`
+	Model      string    `mapstructure:"model"`
+	Transport  Transport `mapstructure:"transport"`
 }
 
 // LLM holds LLM enrichment configuration for tag/summary generation.
 type LLM struct {
-	Enabled    bool   `mapstructure:"enabled"`
-	SocketPath string `mapstructure:"socket_path"`
-	Model      string `mapstructure:"model"`
+	Enabled    bool      `mapstructure:"enabled"`
+	SocketPath string    `mapstructure:"socket_path"`
+	Model      string    `mapstructure:"model"`
+	Transport  Transport `mapstructure:"transport"`
+
+	// SituateChunks prepends a generated sentence situating each chunk
+	// within its page before it is embedded and indexed; one request per chunk
+	SituateChunks bool `mapstructure:"situate_chunks"`
+}
+
+// Rerank holds configuration for reordering search results with a
+// cross-encoder model.
+type Rerank struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	SocketPath string   `mapstructure:"socket_path"`
+	Model      string   `mapstructure:"model"`
+	Candidates int      `mapstructure:"candidates"` // Results scored per search before the requested number is kept
+	Surfaces   []string `mapstructure:"surfaces"`   // Where results are reranked: cli, mcp
+}
+
+// RerankSurfaces are the search surfaces reranking can be enabled for.
+var RerankSurfaces = []string{"cli", "mcp"}
+
+// Reranks reports whether results of the search surface are reranked.
+func (r Rerank) Reranks(surface string) bool {
+	return r.Enabled && slices.Contains(r.Surfaces, surface)
 }
 
 // Scraper holds web scraping configuration.
@@ -43,6 +156,34 @@ type Scraper struct {
 	Timeout          time.Duration `mapstructure:"timeout"`
 	UserAgent        string        `mapstructure:"user_agent"`
 	TryMarkdownFirst bool          `mapstructure:"try_markdown_first"`
+	ContentSelector  string        `mapstructure:"content_selector"`      // CSS selector for the main content; empty keeps the whole page
+	MaxParallel      int           `mapstructure:"max_parallel_requests"` // Concurrent requests per origin
+	MemoryBudget     int64         `mapstructure:"memory_budget"`         // Bytes of pages held awaiting S3 writes; 0 for no limit
+	CacheDir         string        `mapstructure:"cache_dir"`             // On-disk HTTP cache of fetched pages; empty disables it
+	Uploads          int           `mapstructure:"uploads"`               // Pages written to S3 at once
+	RespectNoindex   bool          `mapstructure:"respect_noindex"`       // Skip pages marked noindex by a robots meta tag or X-Robots-Tag header
+	RespectRobots    bool          `mapstructure:"respect_robots"`        // Skip paths robots.txt disallows and honor its Crawl-delay
+	Incremental      bool          `mapstructure:"incremental"`           // Revalidate pages of the previous scrape with ETag/Last-Modified, copying unchanged ones
+	Retry            ScraperRetry  `mapstructure:"retry"`                 // Retries of requests failing with 429, 5xx, or a network error
+	ProxyURL         string        `mapstructure:"proxy_url"`             // HTTP(S) or SOCKS5 proxy for all requests; empty uses HTTP_PROXY/HTTPS_PROXY
+	Proxies          []string      `mapstructure:"proxies"`               // Proxies requests go through in turn, instead of proxy_url
+}
+
+// ProxyURLs returns the proxies requests go through, none if the proxy
+// environment variables apply.
+func (s Scraper) ProxyURLs() []string {
+	if s.ProxyURL != "" {
+		return append([]string{s.ProxyURL}, s.Proxies...)
+	}
+	return s.Proxies
+}
+
+// ScraperRetry holds retry settings for scraper requests.
+type ScraperRetry struct {
+	MaxAttempts int           `mapstructure:"max_attempts"` // Tries per request, including the first
+	Backoff     time.Duration `mapstructure:"backoff"`      // Wait before the first retry; doubles after each failure
+	MaxBackoff  time.Duration `mapstructure:"max_backoff"`  // Longest wait, and the longest Retry-After honored; 0 for no limit
+	Jitter      float64       `mapstructure:"jitter"`       // Fraction of each wait randomized, from 0 to 1
 }
 
 // Storage holds S3/MinIO storage configuration.
@@ -52,26 +193,251 @@ type Storage struct {
 	AccessKeyID     string `mapstructure:"access_key_id"`
 	SecretAccessKey string `mapstructure:"secret_access_key"`
 	UseSSL          bool   `mapstructure:"use_ssl"`
+	MaxObjectSize   int64  `mapstructure:"max_object_size"` // Largest page ingested, in bytes; 0 for no limit
 }
 
 // MCP holds MCP server configuration.
 type MCP struct {
+	Name         string    `mapstructure:"name"`
+	Version      string    `mapstructure:"version"`
+	AccessLabels []string  `mapstructure:"access_labels"` // Labels granted to MCP clients
+	APIKeys      []APIKey  `mapstructure:"api_keys"`      // Keys the HTTP transport accepts; it is open without any
+	APIKeysFile  string    `mapstructure:"api_keys_file"` // YAML list of more keys, e.g. a mounted secret
+	RateLimit    RateLimit `mapstructure:"rate_limit"`    // Limits of the HTTP transport
+}
+
+// RateLimit caps the requests of an HTTP server. Clients are told apart by
+// API key, or by address without one. Zero values disable their limit.
+type RateLimit struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"` // Average rate allowed per client
+	Burst             int     `mapstructure:"burst"`               // Requests a client may make at once; defaults to the rate rounded up
+	MaxConcurrent     int     `mapstructure:"max_concurrent"`      // Requests handled at once across all clients
+}
+
+// APIKey is a key clients of the MCP HTTP transport authenticate with.
+type APIKey struct {
+	Name         string   `mapstructure:"name"`
+	Key          string   `mapstructure:"key"`
+	Namespace    string   `mapstructure:"namespace"`     // Only accepted when serving this namespace; empty for any
+	AccessLabels []string `mapstructure:"access_labels"` // Granted on top of mcp.access_labels
+}
+
+// Jobs holds retry settings for ingestion jobs.
+type Jobs struct {
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	Backoff     time.Duration `mapstructure:"backoff"` // Wait before the first retry; doubles after each failure
+}
+
+// Audit holds configuration for the durable log of pipeline events.
+type Audit struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Store   string `mapstructure:"store"` // elasticsearch or s3
+	Index   string `mapstructure:"index"` // Elasticsearch index for the elasticsearch store
+}
+
+// SlowOps holds the durations above which a single operation is reported
+// as slow. Zero disables the warning for its operation.
+type SlowOps struct {
+	Fetch   time.Duration `mapstructure:"fetch"`   // Fetching a page
+	Enrich  time.Duration `mapstructure:"enrich"`  // LLM enrichment of a document
+	Embed   time.Duration `mapstructure:"embed"`   // A single embedding call
+	Index   time.Duration `mapstructure:"index"`   // Indexing a document and its chunks
+	Summary int           `mapstructure:"summary"` // Slowest operations listed at the end of a run; 0 disables the list
+}
+
+// Redaction holds the detectors masking sensitive strings, such as email
+// addresses and API keys, in pages before they are stored and indexed.
+type Redaction struct {
+	Enabled   bool               `mapstructure:"enabled"`
+	Detectors []string           `mapstructure:"detectors"` // Built-in detectors to apply; empty applies all
+	Patterns  []RedactionPattern `mapstructure:"patterns"`  // Custom detectors applied after the built-in ones
+}
+
+// RedactionPattern is a custom detector. Matches are masked with
+// [REDACTED:<name>], or only a group named secret if the pattern has one.
+type RedactionPattern struct {
 	Name    string `mapstructure:"name"`
-	Version string `mapstructure:"version"`
+	Pattern string `mapstructure:"pattern"` // Go regular expression
+}
+
+// Warmup holds configuration for preparing the embedding and LLM models
+// before an ingestion run.
+type Warmup struct {
+	Enabled   bool          `mapstructure:"enabled"`    // Load the models before processing begins
+	KeepAlive time.Duration `mapstructure:"keep_alive"` // Interval between pings during a run; 0 disables
+}
+
+// Ingestion holds settings for converting and indexing scraped pages.
+type Ingestion struct {
+	// HTML pages larger than this many bytes are converted in a single
+	// streaming pass, which handles less markup; 0 converts all in full
+	StreamThreshold int64 `mapstructure:"stream_threshold"`
+
+	// Refresh interval of the indices while a run indexes documents, such
+	// as "-1" to refresh once at the end; empty leaves it unchanged
+	RefreshInterval string `mapstructure:"refresh_interval"`
+
+	// Sections longer than this many bytes are split into several chunks
+	// at paragraph breaks
+	MaxChunkSize int `mapstructure:"max_chunk_size"`
+}
+
+// Analytics holds configuration for logging the searches of MCP clients.
+type Analytics struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Index   string `mapstructure:"index"` // Elasticsearch index queries and fetches are logged to
+}
+
+// Telemetry holds OpenTelemetry trace export configuration.
+type Telemetry struct {
+	Endpoint    string            `mapstructure:"endpoint"` // OTLP/HTTP base URL; defaults to $OTEL_EXPORTER_OTLP_ENDPOINT, tracing is off without one
+	ServiceName string            `mapstructure:"service_name"`
+	Headers     map[string]string `mapstructure:"headers"`      // Sent with every export
+	SampleRatio float64           `mapstructure:"sample_ratio"` // Share of traces recorded; traces continued from a scrape event follow its decision
+}
+
+// Events holds the event bus configuration that connects scraping to ingestion.
+type Events struct {
+	Bus   string `mapstructure:"bus"` // memory (in-process), nats, kafka, or sqs
+	NATS  NATS   `mapstructure:"nats"`
+	Kafka Kafka  `mapstructure:"kafka"`
+	SQS   SQS    `mapstructure:"sqs"`
+}
+
+// NATS holds NATS JetStream configuration for the nats event bus.
+type NATS struct {
+	URL      string `mapstructure:"url"`
+	Stream   string `mapstructure:"stream"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Token    string `mapstructure:"token"`
+}
+
+// Kafka holds configuration for the kafka event bus.
+type Kafka struct {
+	Brokers       []string `mapstructure:"brokers"`        // Seed brokers, host:port
+	Group         string   `mapstructure:"group"`          // Consumer group prefix
+	TopicPrefix   string   `mapstructure:"topic_prefix"`   // Prepended to event topic names
+	Username      string   `mapstructure:"username"`       // Enables SASL auth
+	Password      string   `mapstructure:"password"`       //
+	SASLMechanism string   `mapstructure:"sasl_mechanism"` // plain, scram-sha-256, or scram-sha-512
+	TLS           bool     `mapstructure:"tls"`
+}
+
+// SQS holds configuration for the sqs event bus. Empty region and
+// credentials fall back to the AWS SDK's default chain (environment, shared
+// config, web identity, and ECS or EC2 instance roles).
+type SQS struct {
+	Region          string `mapstructure:"region"`
+	Endpoint        string `mapstructure:"endpoint"`     // Custom endpoint, e.g. LocalStack
+	QueuePrefix     string `mapstructure:"queue_prefix"` // Prepended to queue names
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	SessionToken    string `mapstructure:"session_token"`
+}
+
+// Webhook is an HTTP endpoint notified when scrapes and ingestions complete.
+type Webhook struct {
+	URL     string            `mapstructure:"url"`
+	Secret  string            `mapstructure:"secret"`  // HMAC-SHA256 signing key
+	Events  []string          `mapstructure:"events"`  // scrape.complete, ingestion.complete; empty for all
+	Headers map[string]string `mapstructure:"headers"` // Extra request headers
+}
+
+// Triggers holds configuration for the endpoint that accepts requests to
+// scrape and ingest a source again, served by 'bam-rag worker --trigger-addr'.
+type Triggers struct {
+	Secret string `mapstructure:"secret"` // HMAC-SHA256 key requests are signed with
+}
+
+// OpenAI holds the account 'bam-rag openai-upload' uploads pages to.
+type OpenAI struct {
+	APIKey  string `mapstructure:"api_key"`
+	BaseURL string `mapstructure:"base_url"` // API base URL, for proxies and compatible services
 }
 
 // Source defines a documentation source to scrape.
+// Either URL (a website) or Path (a local directory of markdown files) is set.
+// The optional override blocks replace the global settings for this source only.
 type Source struct {
-	Name string `mapstructure:"name"`
-	URL  string `mapstructure:"url"`
+	Name     string `mapstructure:"name"`
+	URL      string `mapstructure:"url"`
+	Path     string `mapstructure:"path"`
+	Priority int    `mapstructure:"priority"` // Higher priorities are scraped first; default 0
+	Group    string `mapstructure:"group"`    // Optional group for acting on related sources together
+
+	// AccessLabels restrict the source's documents to searches carrying
+	// one of the labels; unlabeled sources are visible to every search.
+	AccessLabels []string `mapstructure:"access_labels"`
+
+	// IncludePatterns and ExcludePatterns are regular expressions matched
+	// against the absolute URLs of links. A link is followed if it matches
+	// an include pattern, or there are none, and no exclude pattern.
+	IncludePatterns []string `mapstructure:"include_patterns"`
+	ExcludePatterns []string `mapstructure:"exclude_patterns"`
+
+	// Headers are sent with every request scraping the source, including
+	// markdown variants. Unlike auth headers, they follow redirects to other
+	// hosts.
+	Headers map[string]string `mapstructure:"headers"`
+
+	Scraper       SourceScraper       `mapstructure:"scraper"`
+	LLM           SourceLLM           `mapstructure:"llm"`
+	Embeddings    SourceModel         `mapstructure:"embeddings"`
+	Chunking      SourceChunking      `mapstructure:"chunking"`
+	Elasticsearch SourceElasticsearch `mapstructure:"elasticsearch"`
+	GitHub        SourceGitHub        `mapstructure:"github"`
+
+	// Auth holds credentials for the source's pages, taking precedence
+	// over the global auth entries. Its domain defaults to the URL's host.
+	Auth *DomainAuth `mapstructure:"auth"`
+}
+
+// DomainAuth holds credentials the scraper sends with every request to a
+// domain and its subdomains, whichever source the URL came from.
+type DomainAuth struct {
+	Domain   string            `mapstructure:"domain"`
+	Headers  map[string]string `mapstructure:"headers"`
+	Username string            `mapstructure:"username"` // Basic auth
+	Password string            `mapstructure:"password"`
+	Token    string            `mapstructure:"token"` // Bearer token
+
+	// CookiesFile is a cookies.txt in the Netscape format browser
+	// extensions export and curl writes, e.g. holding an SSO session
+	CookiesFile string `mapstructure:"cookies_file"`
 }
 
 // Defaults returns a Config with sensible default values.
 func Defaults() Config {
 	return Config{
+		Backend: Backend{
+			Type: "elasticsearch",
+			Path: "data/bam-rag-index.db",
+			Qdrant: Qdrant{
+				URL:        "http://localhost:6333",
+				Collection: "bam-rag",
+				VectorSize: 2560,
+			},
+		},
 		Elasticsearch: Elasticsearch{
 			Addresses: []string{"http://localhost:9200"},
 			Index:     "bam-rag-chunks",
+			Fusion: Fusion{
+				Method:       "rrf",
+				RankConstant: 60,
+				BM25Weight:   1,
+				VectorWeight: 1,
+			},
+			Recency: Recency{
+				Scale: "180d",
+				Decay: 0.5,
+			},
+			Dedup: Dedup{
+				URLs:       true,
+				Similarity: 0.9,
+			},
+			Fuzziness:         "AUTO",
+			FuzzyPrefixLength: 1,
 		},
 		Embeddings: Embeddings{
 			Enabled:    false, // Disabled by default, requires DMR setup
@@ -83,6 +449,11 @@ func Defaults() Config {
 			SocketPath: "",    // User must provide their Docker socket path
 			Model:      "ai/gemma3",
 		},
+		Rerank: Rerank{
+			Model:      "ai/qwen3-reranker",
+			Candidates: 30,
+			Surfaces:   []string{"cli", "mcp"},
+		},
 		Scraper: Scraper{
 			Delay:            1 * time.Second,
 			MaxDepth:         3,
@@ -90,6 +461,17 @@ func Defaults() Config {
 			Timeout:          30 * time.Second,
 			UserAgent:        "bam-rag/1.0",
 			TryMarkdownFirst: true, // Try markdown versions of pages first
+			MaxParallel:      2,
+			MemoryBudget:     64 << 20,
+			Uploads:          4,
+			RespectNoindex:   true,
+			RespectRobots:    true,
+			Retry: ScraperRetry{
+				MaxAttempts: 3,
+				Backoff:     time.Second,
+				MaxBackoff:  30 * time.Second,
+				Jitter:      0.5,
+			},
 		},
 		Storage: Storage{
 			Endpoint:        "localhost:9002",
@@ -97,10 +479,54 @@ func Defaults() Config {
 			AccessKeyID:     "minioadmin",
 			SecretAccessKey: "minioadmin",
 			UseSSL:          false,
+			MaxObjectSize:   10 << 20,
 		},
 		MCP: MCP{
 			Name:    "bam-rag",
 			Version: "1.0.0",
 		},
+		Jobs: Jobs{
+			MaxAttempts: 3,
+			Backoff:     30 * time.Second,
+		},
+		Audit: Audit{
+			Store: "elasticsearch",
+			Index: "bam-rag-audit",
+		},
+		Analytics: Analytics{
+			Index: "bam-rag-analytics",
+		},
+		SlowOps: SlowOps{
+			Fetch:   10 * time.Second,
+			Enrich:  2 * time.Minute,
+			Embed:   30 * time.Second,
+			Index:   10 * time.Second,
+			Summary: 5,
+		},
+		Warmup: Warmup{
+			Enabled: true,
+		},
+		Ingestion: Ingestion{
+			StreamThreshold: 2 << 20,
+			MaxChunkSize:    4000,
+		},
+		Telemetry: Telemetry{
+			ServiceName: "bam-rag",
+			SampleRatio: 1,
+		},
+		OpenAI: OpenAI{
+			BaseURL: "https://api.openai.com/v1",
+		},
+		Events: Events{
+			Bus: "memory",
+			NATS: NATS{
+				URL:    "nats://localhost:4222",
+				Stream: "BAM_RAG",
+			},
+			Kafka: Kafka{
+				Brokers: []string{"localhost:9092"},
+				Group:   "bam-rag",
+			},
+		},
 	}
 }
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// envRef matches $${ (a literal ${) and ${VAR} or ${VAR:-default}
+// references.
+var envRef = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces ${VAR} references in s with the variable's value.
+// ${VAR:-default} falls back to default when VAR is unset or empty, and
+// $${ produces a literal ${. Any other $ is kept as it is, so values
+// written before references existed, such as passwords, read the same.
+// Referencing an unset variable without a default is an error, so typos
+// do not silently become empty values.
+func ExpandEnv(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var missing []string
+	expanded := envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		m := envRef.FindStringSubmatch(ref)
+		if value := os.Getenv(m[1]); value != "" {
+			return value
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		if _, ok := os.LookupEnv(m[1]); !ok {
+			missing = append(missing, m[1])
+		}
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// expandEnvHook expands environment references in every string value
+// before it is converted to its target type.
+func expandEnvHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String {
+		return data, nil
+	}
+	return ExpandEnv(data.(string))
+}
+
+// Decode unmarshals the values read by v over c, expanding ${VAR}
+// references in string values.
+func Decode(v *viper.Viper, c *Config) error {
+	return v.Unmarshal(c, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		expandEnvHook,
+		// viper's defaults
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToWeakSliceHookFunc(","),
+	)))
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("BAMRAG_TEST_HOST", "minio.stage")
+	t.Setenv("BAMRAG_TEST_EMPTY", "")
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "localhost:9002", want: "localhost:9002"},
+		{in: "${BAMRAG_TEST_HOST}:9000", want: "minio.stage:9000"},
+		{in: "http://${BAMRAG_TEST_HOST}/${BAMRAG_TEST_HOST}", want: "http://minio.stage/minio.stage"},
+		{in: "${BAMRAG_TEST_UNSET:-bam-rag}", want: "bam-rag"},
+		{in: "${BAMRAG_TEST_EMPTY:-fallback}", want: "fallback"},
+		{in: "${BAMRAG_TEST_EMPTY}", want: ""},
+		{in: "pa$$word", want: "pa$$word"},
+		{in: "$${BAMRAG_TEST_HOST}", want: "${BAMRAG_TEST_HOST}"},
+		{in: "$$$${BAMRAG_TEST_UNSET}", want: "$$${BAMRAG_TEST_UNSET}"},
+		{in: "$HOME stays", want: "$HOME stays"},
+		{in: "${BAMRAG_TEST_UNSET}", wantErr: "BAMRAG_TEST_UNSET is not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ExpandEnv(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExpandEnv(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandEnv(%q) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode_ExpandsEnv(t *testing.T) {
+	t.Setenv("BAMRAG_TEST_ES", "http://es.stage:9200")
+	t.Setenv("BAMRAG_TEST_BUCKET", "docs-stage")
+	t.Setenv("BAMRAG_TEST_DELAY", "250ms")
+
+	cfg := parse(t, `
+elasticsearch:
+  addresses:
+    - ${BAMRAG_TEST_ES}
+storage:
+  bucket: ${BAMRAG_TEST_BUCKET}
+  endpoint: ${BAMRAG_TEST_MINIO:-localhost:9002}
+scraper:
+  delay: ${BAMRAG_TEST_DELAY}
+`)
+
+	if got := cfg.Elasticsearch.Addresses; len(got) != 1 || got[0] != "http://es.stage:9200" {
+		t.Errorf("Addresses = %v, want [http://es.stage:9200]", got)
+	}
+	if cfg.Storage.Bucket != "docs-stage" {
+		t.Errorf("Bucket = %q, want docs-stage", cfg.Storage.Bucket)
+	}
+	if cfg.Storage.Endpoint != "localhost:9002" {
+		t.Errorf("Endpoint = %q, want default localhost:9002", cfg.Storage.Endpoint)
+	}
+	if cfg.Scraper.Delay != 250*time.Millisecond {
+		t.Errorf("Delay = %v, want 250ms", cfg.Scraper.Delay)
+	}
+}
@@ -0,0 +1,387 @@
+package config
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// InitOptions holds the values written into a starter config file.
+type InitOptions struct {
+	ESAddress       string
+	ESIndex         string
+	StorageEndpoint string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+	SocketPath      string // Docker socket; models are enabled when set
+	Sources         []Source
+}
+
+// DefaultInitOptions returns InitOptions populated from Defaults.
+func DefaultInitOptions() InitOptions {
+	d := Defaults()
+	return InitOptions{
+		ESAddress:       d.Elasticsearch.Addresses[0],
+		ESIndex:         d.Elasticsearch.Index,
+		StorageEndpoint: d.Storage.Endpoint,
+		Bucket:          d.Storage.Bucket,
+		AccessKeyID:     d.Storage.AccessKeyID,
+		SecretAccessKey: d.Storage.SecretAccessKey,
+		UseSSL:          d.Storage.UseSSL,
+	}
+}
+
+// configTemplate is the starter config.yaml. Optional settings are left
+// commented out with their default values.
+var configTemplate = template.Must(template.New("config").Funcs(template.FuncMap{"quote": quote}).Parse(`# bam-rag configuration
+# Any value can be overridden with BAMRAG_<SECTION>_<KEY> environment variables,
+# e.g. BAMRAG_ELASTICSEARCH_ADDRESSES=http://es:9200
+# String values may also reference variables as ${VAR} or ${VAR:-default}; $${ is a literal ${.
+
+# Tenant whose corpus is used, so teams sharing a deployment stay isolated.
+# Also set with --namespace or BAMRAG_NAMESPACE.
+# namespace: team-a
+
+# Index pages are stored in and searched. The embedded backend keeps a small
+# corpus in a local file, with no Elasticsearch to run; qdrant stores it in a
+# Qdrant vector database. Both skip fuzzy matching, recency boosts, and
+# deduplication, and do not log analytics.
+# backend:
+#   type: elasticsearch  # or embedded, qdrant
+#   path: data/bam-rag-index.db
+#   qdrant:
+#     url: http://localhost:6333
+#     api_key: ""
+#     collection: bam-rag
+#     vector_size: 2560  # dimensions of the embedding model
+
+elasticsearch:
+  addresses:
+    - {{quote .Opts.ESAddress}}
+  index: {{quote .Opts.ESIndex}}
+  # username: elastic
+  # password: changeme
+  # Elastic Cloud: the deployment's cloud ID replaces addresses, and an API
+  # key replaces username and password. Set serverless for Serverless
+  # projects, which manage the refresh interval themselves.
+  # cloud_id: my-deployment:ZXUtY2VudHJhbC0xLmF3cy5jbG91ZC5lcy5pbyRhYmMkZGVm
+  # api_key: changeme
+  # serverless: false
+  # Applied when the index is created; existing indexes keep their mapping.
+  # mapping:
+  #   analyzer: english            # content, tags, and summary
+  #   vector_similarity: cosine
+  #   fields:                      # extra properties, merged over the defaults
+  #     product: { type: keyword }
+  #   settings: {}                 # index settings, e.g. analysis or similarity
+  # How hybrid search combines BM25 and vector results: rrf (reciprocal rank
+  # fusion) or linear (weighted sum of min-max normalized scores).
+  # fusion:
+  #   method: {{.Defaults.Elasticsearch.Fusion.Method}}
+  #   rank_constant: {{.Defaults.Elasticsearch.Fusion.RankConstant}}             # rrf: higher flattens the gap between ranks
+  #   rank_window_size: {{.Defaults.Elasticsearch.Fusion.RankWindowSize}}           # results per retriever; 0 for the number requested
+  #   bm25_weight: {{.Defaults.Elasticsearch.Fusion.BM25Weight}}                # linear only
+  #   vector_weight: {{.Defaults.Elasticsearch.Fusion.VectorWeight}}
+  # Typos tolerated per query term (AUTO: none for 1-2 letters, one for 3-5,
+  # two for longer terms; empty for exact matching). The first
+  # fuzzy_prefix_length characters must match exactly.
+  # fuzziness: {{.Defaults.Elasticsearch.Fuzziness}}
+  # fuzzy_prefix_length: {{.Defaults.Elasticsearch.FuzzyPrefixLength}}
+  # Favor recently scraped pages: weight is the share of a page's score that
+  # decays with the age of its scrape (0 disables, 1 lets old pages fall to
+  # nothing), reaching decay of that share at offset + scale.
+  # recency:
+  #   weight: 0.3
+  #   scale: {{.Defaults.Elasticsearch.Recency.Scale}}
+  #   offset: 7d
+  #   decay: {{.Defaults.Elasticsearch.Recency.Decay}}
+  # Leave copies of a better-ranked result out of searches: urls collapses
+  # URLs differing only in scheme, www., trailing slash, or index.html, and
+  # similarity collapses pages sharing that share of their content (0
+  # disables), such as the same docs page published for several versions.
+  # per_source keeps one large site from taking every result slot.
+  # dedup:
+  #   urls: {{.Defaults.Elasticsearch.Dedup.URLs}}
+  #   similarity: {{.Defaults.Elasticsearch.Dedup.Similarity}}
+  #   per_source: 3      # results kept per source host; 0 for no limit
+  # Connection reuse; embeddings and llm accept the same block
+  # (defaults there: 8 connections, 2m and 10m request timeouts).
+  # transport:
+  #   max_idle_conns: 16
+  #   idle_conn_timeout: 90s
+  #   request_timeout: 1m            # waiting for a response, except deletes and refreshes
+
+storage:
+  endpoint: {{quote .Opts.StorageEndpoint}}
+  bucket: {{quote .Opts.Bucket}}
+  access_key_id: {{quote .Opts.AccessKeyID}}
+  secret_access_key: {{quote .Opts.SecretAccessKey}}
+  use_ssl: {{.Opts.UseSSL}}
+  # Larger pages are skipped during ingestion (bytes; 0 for no limit)
+  max_object_size: {{.Defaults.Storage.MaxObjectSize}}
+
+# Docker Model Runner socket
+#   Mac:   ~/.docker/run/docker.sock
+#   Linux: /var/run/docker.sock
+embeddings:
+  enabled: {{.ModelsEnabled}}
+  socket_path: {{quote .Opts.SocketPath}}
+  model: {{.Defaults.Embeddings.Model}}
+
+llm:
+  enabled: {{.ModelsEnabled}}
+  socket_path: {{quote .Opts.SocketPath}}
+  model: {{.Defaults.LLM.Model}}
+  # Prepend a generated sentence situating each chunk within its page before
+  # embedding and indexing it, so sections that never name their subject are
+  # still found. One extra request per chunk; sources may turn it on alone.
+  # situate_chunks: true
+
+# Rerank the top results of each search with a cross-encoder model before
+# returning them, on the listed surfaces (cli: bam-rag search, mcp: serve).
+# rerank:
+#   enabled: false
+#   socket_path: {{quote .Opts.SocketPath}}
+#   model: {{.Defaults.Rerank.Model}}
+#   candidates: {{.Defaults.Rerank.Candidates}}      # results scored per search
+#   surfaces: [cli, mcp]
+
+scraper:
+  # delay: {{.Defaults.Scraper.Delay}}
+  # max_depth: {{.Defaults.Scraper.MaxDepth}}
+  # follow_links: {{.Defaults.Scraper.FollowLinks}}
+  # timeout: {{.Defaults.Scraper.Timeout}}
+  # user_agent: {{.Defaults.Scraper.UserAgent}}
+  # try_markdown_first: {{.Defaults.Scraper.TryMarkdownFirst}}
+  # memory_budget: {{.Defaults.Scraper.MemoryBudget}}   # bytes of pages held awaiting S3 writes
+  # uploads: {{.Defaults.Scraper.Uploads}}   # pages written to S3 at once
+  # cache_dir: .cache/http   # keep fetched pages and revalidate them on later runs
+  # respect_noindex: {{.Defaults.Scraper.RespectNoindex}}   # skip pages marked noindex by a robots meta tag or X-Robots-Tag header
+  # respect_robots: {{.Defaults.Scraper.RespectRobots}}   # skip paths robots.txt disallows and wait its Crawl-delay between requests
+  # incremental: {{.Defaults.Scraper.Incremental}}   # revalidate pages of the previous scrape, copying unchanged ones instead of fetching them
+  # Requests answered with 429 or 5xx, or failing with a network error or
+  # timeout, are retried with exponential backoff; Retry-After is honored.
+  # retry:
+  #   max_attempts: {{.Defaults.Scraper.Retry.MaxAttempts}}   # 1 disables retries
+  #   backoff: {{.Defaults.Scraper.Retry.Backoff}}
+  #   max_backoff: {{.Defaults.Scraper.Retry.MaxBackoff}}   # also the longest Retry-After waited for
+  #   jitter: {{.Defaults.Scraper.Retry.Jitter}}   # fraction of each wait randomized
+  # Without a proxy, HTTP_PROXY, HTTPS_PROXY, and NO_PROXY apply.
+  # proxy_url: http://proxy.corp.example.com:3128   # or socks5://host:1080
+  # proxies: [http://proxy-a:3128, http://proxy-b:3128]   # rotated per request, instead of proxy_url
+
+mcp:
+  name: {{.Defaults.MCP.Name}}
+  version: {{.Defaults.MCP.Version}}
+  # access_labels: [internal]   # labels granted to MCP clients
+  # Keys clients of 'bam-rag serve --http-addr' must send as a bearer token;
+  # without any, the HTTP transport is open.
+  # api_keys:
+  #   - name: payments-bot
+  #     key: ${PAYMENTS_BOT_KEY}
+  #     namespace: payments          # only accepted when serving this namespace
+  #     access_labels: [finance]     # granted on top of access_labels
+  # api_keys_file: /run/secrets/bam-rag-api-keys.yaml
+  # Limits of the HTTP transport, so a runaway agent can't overwhelm the
+  # index or the local models; 0 disables a limit.
+  # rate_limit:
+  #   requests_per_second: 5   # per API key, or per address without one
+  #   burst: 10
+  #   max_concurrent: 16       # across all clients
+
+# Failed ingestions are retried with exponential backoff; see 'bam-rag jobs'.
+jobs:
+  # max_attempts: {{.Defaults.Jobs.MaxAttempts}}
+  # backoff: {{.Defaults.Jobs.Backoff}}
+
+# Durable log of scrapes, ingestions, deletions, and errors; see 'bam-rag audit'.
+audit:
+  enabled: false
+  # store: {{.Defaults.Audit.Store}}   # or s3
+  # index: {{.Defaults.Audit.Index}}
+
+# Log MCP searches, their result counts, and the documents read after them
+# to an Elasticsearch index; see 'bam-rag analytics'.
+#
+# analytics:
+#   enabled: true
+#   index: {{.Defaults.Analytics.Index}}
+
+# Single operations slower than these are reported as warnings, and the
+# slowest are listed when a run finishes. 0 disables a warning.
+#
+# slow_ops:
+#   fetch: {{.Defaults.SlowOps.Fetch}}
+#   enrich: {{.Defaults.SlowOps.Enrich}}
+#   embed: {{.Defaults.SlowOps.Embed}}
+#   index: {{.Defaults.SlowOps.Index}}
+#   summary: {{.Defaults.SlowOps.Summary}}
+
+# The embedding and LLM models are loaded before each ingestion, and can be
+# pinged during long runs so Docker Model Runner doesn't evict them.
+#
+# warmup:
+#   enabled: {{.Defaults.Warmup.Enabled}}
+#   keep_alive: {{.Defaults.Warmup.KeepAlive}}   # 0 disables pings
+
+# HTML pages larger than stream_threshold bytes are converted to markdown in
+# one streaming pass, which keeps memory flat but handles less markup (0 to
+# convert every page in full).
+#
+# While a run indexes documents, the indices' refresh_interval can be set to
+# refresh_interval ("-1" stops periodic refreshes, so new pages only become
+# searchable when the run ends), then restored, and the indices are
+# refreshed once at the end. Empty, the default, leaves the interval alone.
+#
+# Sections longer than max_chunk_size bytes are split into several chunks at
+# paragraph breaks.
+#
+# ingestion:
+#   stream_threshold: {{.Defaults.Ingestion.StreamThreshold}}
+#   refresh_interval: "-1"
+#   max_chunk_size: {{.Defaults.Ingestion.MaxChunkSize}}
+
+# Sensitive strings are masked as [REDACTED:<detector>] before pages are
+# stored in S3 and indexed. Built-in detectors: email, aws_access_key,
+# github_token, slack_token, jwt, private_key, api_key (all if none are
+# listed). Custom patterns are Go regular expressions; if one has a group
+# named secret, only that group is masked.
+#
+# redaction:
+#   enabled: true
+#   detectors: [email, api_key]
+#   patterns:
+#     - name: employee_id
+#       pattern: 'EMP-\d{6}'
+
+# OpenTelemetry traces of scraping and ingestion, exported over OTLP/HTTP
+# (e.g. to Jaeger or an OpenTelemetry Collector on port 4318).
+#
+# telemetry:
+#   endpoint: http://localhost:4318
+#   service_name: {{.Defaults.Telemetry.ServiceName}}
+#   sample_ratio: {{.Defaults.Telemetry.SampleRatio}}   # share of traces recorded
+
+# Event bus connecting scraping to ingestion. memory keeps both in one process;
+# nats (JetStream), kafka, and sqs queue scrape events for
+# 'bam-rag ingest --follow' consumers.
+events:
+  bus: {{.Defaults.Events.Bus}}
+  # nats:
+  #   url: {{.Defaults.Events.NATS.URL}}
+  #   stream: {{.Defaults.Events.NATS.Stream}}
+  # kafka:
+  #   brokers: [{{index .Defaults.Events.Kafka.Brokers 0}}]
+  #   group: {{.Defaults.Events.Kafka.Group}}
+  #   topic_prefix: ""
+  #   username: ""       # enables SASL; sasl_mechanism: plain, scram-sha-256, or scram-sha-512
+  #   tls: false
+  # sqs:                 # region and credentials default to the AWS SDK chain
+  #   region: us-east-1
+  #   queue_prefix: ""
+
+# Documentation sources: set url for a website or path for a local markdown directory.
+# Each source may override scraper, llm, embeddings, chunking, and elasticsearch
+# settings; searches cover per-source indices along with the global one:
+#
+#   - name: changelog
+#     url: https://example.com/changelog
+#     priority: 10   # higher priorities are scraped first
+#     group: releases   # scrape, ingest, search or delete with --group releases
+#     access_labels: [internal]   # only searches granted a label see its pages
+#     include_patterns: ['/changelog/']   # only follow links whose URL matches one of these
+#     exclude_patterns: ['/changelog/(de|fr)/', '\?page=']   # never follow links matching these
+#     headers: { Accept-Language: en }   # sent with every request of the source
+#     scraper: { max_depth: 0, max_parallel_requests: 1, content_selector: article, respect_noindex: false }
+#     llm: { enabled: false }   # or { situate_chunks: true }
+#     chunking: { max_size: 1500 }
+#     elasticsearch: { index: changelog }
+sources:
+{{- range .Opts.Sources}}
+  - name: {{quote .Name}}
+{{- if .URL}}
+    url: {{quote .URL}}
+{{- end}}
+{{- if .Path}}
+    path: {{quote .Path}}
+{{- end}}
+{{- else}}
+  # - name: go-docs
+  #   url: https://go.dev/doc/
+  # - name: team-docs
+  #   path: ./docs
+{{- end}}
+
+# Credentials sent with every request to a domain and its subdomains,
+# whichever source the page came from:
+#
+# auth:
+#   - domain: docs.internal.example.com
+#     token: changeme                      # Authorization: Bearer
+#   - domain: wiki.example.com
+#     username: bot                        # or basic auth
+#     password: changeme
+#     headers: { X-Api-Key: changeme }
+#   - domain: sso.example.com
+#     cookies_file: ./cookies.txt          # Netscape format, e.g. exported from a browser
+#
+# A source can carry its own auth, for its URL's host unless a domain is set:
+#
+#   - name: portal
+#     url: https://portal.internal.example.com/docs
+#     auth: { cookies_file: ./portal-cookies.txt }
+
+# Endpoints POSTed a JSON payload when scrapes and ingestions complete.
+# With a secret, requests carry X-BamRag-Signature: sha256=<HMAC of the body>.
+#
+# webhooks:
+#   - url: https://ci.example.com/hooks/docs
+#     secret: changeme
+#     events: [scrape.complete, ingestion.complete]
+
+# Requests to 'bam-rag worker --trigger-addr' naming a source or URL to
+# scrape and ingest again, signed with this secret over X-BamRag-Timestamp
+# and the body; requests over 5 minutes old or already accepted are refused.
+# GitHub push webhooks sent to /github with the same secret refresh the
+# files they change in sources with a github block:
+#
+#   - name: team-docs
+#     path: ./checkout/docs               # Within a clone of the repository
+#     github: { repo: acme/docs, branch: main }
+#
+# triggers:
+#   secret: changeme
+
+# Account 'bam-rag openai-upload' uploads pages to, for OpenAI vector stores.
+#
+# openai:
+#   api_key: ${OPENAI_API_KEY}
+#   base_url: https://api.openai.com/v1
+`))
+
+// quote returns s as a YAML double-quoted string that reads back as s,
+// with ${ escaped so ExpandEnv leaves it as written.
+func quote(s string) string {
+	return strconv.Quote(strings.ReplaceAll(s, "${", "$${"))
+}
+
+// RenderTemplate renders a starter config.yaml from the given options.
+func RenderTemplate(opts InitOptions) (string, error) {
+	var buf bytes.Buffer
+	err := configTemplate.Execute(&buf, struct {
+		Opts          InitOptions
+		Defaults      Config
+		ModelsEnabled bool
+	}{
+		Opts:          opts,
+		Defaults:      Defaults(),
+		ModelsEnabled: opts.SocketPath != "",
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// parse unmarshals rendered YAML the same way the CLI loads config files.
+func parse(t *testing.T, content string) Config {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatalf("rendered config is not valid YAML: %v\n%s", err, content)
+	}
+	cfg := Defaults()
+	if err := Decode(v, &cfg); err != nil {
+		t.Fatalf("failed to unmarshal rendered config: %v", err)
+	}
+	return cfg
+}
+
+func TestRenderTemplate_Defaults(t *testing.T) {
+	content, err := RenderTemplate(DefaultInitOptions())
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+
+	cfg := parse(t, content)
+	defaults := Defaults()
+
+	if cfg.Elasticsearch.Index != defaults.Elasticsearch.Index {
+		t.Errorf("Index = %q, want %q", cfg.Elasticsearch.Index, defaults.Elasticsearch.Index)
+	}
+	if cfg.Storage.Bucket != defaults.Storage.Bucket {
+		t.Errorf("Bucket = %q, want %q", cfg.Storage.Bucket, defaults.Storage.Bucket)
+	}
+	if cfg.Embeddings.Enabled || cfg.LLM.Enabled {
+		t.Error("models should be disabled without a socket path")
+	}
+	if cfg.Scraper.Delay != defaults.Scraper.Delay {
+		t.Errorf("Scraper.Delay = %v, want default %v", cfg.Scraper.Delay, defaults.Scraper.Delay)
+	}
+	if len(cfg.Sources) != 0 {
+		t.Errorf("Sources = %v, want none", cfg.Sources)
+	}
+	if !strings.Contains(content, "# - name: go-docs") {
+		t.Error("expected commented example sources")
+	}
+}
+
+func TestRenderTemplate_WithOptions(t *testing.T) {
+	opts := DefaultInitOptions()
+	opts.ESAddress = "http://es.internal:9200"
+	opts.SocketPath = "/var/run/docker.sock"
+	opts.Sources = []Source{
+		{Name: "go-docs", URL: "https://go.dev/doc/"},
+		{Name: "team-docs", Path: "./docs"},
+	}
+
+	content, err := RenderTemplate(opts)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+
+	cfg := parse(t, content)
+
+	if len(cfg.Elasticsearch.Addresses) != 1 || cfg.Elasticsearch.Addresses[0] != "http://es.internal:9200" {
+		t.Errorf("Addresses = %v", cfg.Elasticsearch.Addresses)
+	}
+	if !cfg.Embeddings.Enabled || cfg.Embeddings.SocketPath != "/var/run/docker.sock" {
+		t.Errorf("Embeddings = %+v, want enabled with socket path", cfg.Embeddings)
+	}
+	if len(cfg.Sources) != 2 {
+		t.Fatalf("Sources = %v, want 2", cfg.Sources)
+	}
+	if cfg.Sources[0].URL != "https://go.dev/doc/" || cfg.Sources[1].Path != "./docs" {
+		t.Errorf("Sources = %+v", cfg.Sources)
+	}
+}
+
+func TestRenderTemplate_QuotesValues(t *testing.T) {
+	opts := DefaultInitOptions()
+	opts.ESIndex = "docs #1"
+	opts.Bucket = "yes"
+	opts.SecretAccessKey = `a"b\c: d`
+	opts.Sources = []Source{{Name: "- team: docs", Path: "./docs/${HOME} [old]"}}
+
+	content, err := RenderTemplate(opts)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+
+	cfg := parse(t, content)
+
+	if cfg.Elasticsearch.Index != opts.ESIndex {
+		t.Errorf("Index = %q, want %q", cfg.Elasticsearch.Index, opts.ESIndex)
+	}
+	if cfg.Storage.Bucket != opts.Bucket {
+		t.Errorf("Bucket = %q, want %q", cfg.Storage.Bucket, opts.Bucket)
+	}
+	if cfg.Storage.SecretAccessKey != opts.SecretAccessKey {
+		t.Errorf("SecretAccessKey = %q, want %q", cfg.Storage.SecretAccessKey, opts.SecretAccessKey)
+	}
+	if len(cfg.Sources) != 1 || cfg.Sources[0].Name != opts.Sources[0].Name || cfg.Sources[0].Path != opts.Sources[0].Path {
+		t.Errorf("Sources = %+v, want %+v", cfg.Sources, opts.Sources)
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// namespacePattern matches namespace names, which end up in index names,
+// object keys, and event subjects.
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ApplyNamespace isolates the corpus of c.Namespace: Elasticsearch indices
+// (including per-source ones), the embedded index file, and the Qdrant
+// collection get the namespace appended to their names. Storage keys and
+// event subjects are scoped by the clients built from the config. Does
+// nothing without a namespace.
+func (c *Config) ApplyNamespace() {
+	ns := c.Namespace
+	if ns == "" {
+		return
+	}
+	c.Elasticsearch.Index = namespaced(c.Elasticsearch.Index, ns)
+	c.Audit.Index = namespaced(c.Audit.Index, ns)
+	c.Analytics.Index = namespaced(c.Analytics.Index, ns)
+	c.Backend.Qdrant.Collection = namespaced(c.Backend.Qdrant.Collection, ns)
+	if c.Backend.Path != "" {
+		ext := filepath.Ext(c.Backend.Path)
+		c.Backend.Path = namespaced(strings.TrimSuffix(c.Backend.Path, ext), ns) + ext
+	}
+	for i := range c.Sources {
+		c.Sources[i].Elasticsearch.Index = namespaced(c.Sources[i].Elasticsearch.Index, ns)
+	}
+}
+
+// namespaced appends ns to a non-empty name.
+func namespaced(name, ns string) string {
+	if name == "" {
+		return ""
+	}
+	return name + "-" + ns
+}
@@ -0,0 +1,49 @@
+package config
+
+import "testing"
+
+func TestApplyNamespace(t *testing.T) {
+	cfg := parse(t, `
+namespace: team-a
+backend:
+  path: data/index.json
+sources:
+  - name: changelog
+    url: https://example.com/changelog
+    elasticsearch:
+      index: changelog
+  - name: api
+    url: https://example.com/api
+`)
+	cfg.ApplyNamespace()
+
+	got := map[string]string{
+		"elasticsearch.index":       cfg.Elasticsearch.Index,
+		"audit.index":               cfg.Audit.Index,
+		"analytics.index":           cfg.Analytics.Index,
+		"backend.path":              cfg.Backend.Path,
+		"backend.qdrant.collection": cfg.Backend.Qdrant.Collection,
+		"sources[changelog].index":  cfg.Sources[0].Elasticsearch.Index,
+		"sources[api].index":        cfg.Sources[1].Elasticsearch.Index,
+	}
+	want := map[string]string{
+		"elasticsearch.index":       "bam-rag-chunks-team-a",
+		"audit.index":               "bam-rag-audit-team-a",
+		"analytics.index":           "bam-rag-analytics-team-a",
+		"backend.path":              "data/index-team-a.json",
+		"backend.qdrant.collection": "bam-rag-team-a",
+		"sources[changelog].index":  "changelog-team-a",
+		"sources[api].index":        "",
+	}
+	for key, w := range want {
+		if got[key] != w {
+			t.Errorf("%s = %q, want %q", key, got[key], w)
+		}
+	}
+
+	shared := Defaults()
+	shared.ApplyNamespace()
+	if shared.Elasticsearch.Index != "bam-rag-chunks" {
+		t.Errorf("elasticsearch.index without a namespace = %q, want it unchanged", shared.Elasticsearch.Index)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ApplyProfile merges a named profile over the configuration v has read.
+// A profile is either a section under "profiles" in the config file:
+//
+//	profiles:
+//	  prod:
+//	    elasticsearch:
+//	      addresses: [https://es.prod:9200]
+//
+// or a file next to the config file named config.<profile>.<ext>
+// (e.g. config/config.prod.yaml). If both exist, the file wins.
+// Maps are merged key by key; lists such as sources are replaced.
+func ApplyProfile(v *viper.Viper, name string) error {
+	found := false
+
+	if section := v.Sub("profiles." + name); section != nil {
+		if err := v.MergeConfigMap(section.AllSettings()); err != nil {
+			return fmt.Errorf("failed to apply profile %q: %w", name, err)
+		}
+		found = true
+	}
+
+	if path := profileFile(v.ConfigFileUsed(), name); path != "" {
+		pv := viper.New()
+		pv.SetConfigFile(path)
+		if err := pv.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read profile %q: %w", name, err)
+		}
+		if err := v.MergeConfigMap(pv.AllSettings()); err != nil {
+			return fmt.Errorf("failed to apply profile %q: %w", name, err)
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	return nil
+}
+
+// profileFile returns the path of the profile file that sits next to
+// configFile, or "" if there is none.
+func profileFile(configFile, name string) string {
+	if configFile == "" {
+		return ""
+	}
+	ext := filepath.Ext(configFile)
+	base := strings.TrimSuffix(filepath.Base(configFile), ext)
+	path := filepath.Join(filepath.Dir(configFile), base+"."+name+ext)
+
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// loadProfile reads configFile, applies profile, and decodes the result.
+func loadProfile(t *testing.T, configFile, profile string) (Config, error) {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("ReadInConfig() error = %v", err)
+	}
+	if err := ApplyProfile(v, profile); err != nil {
+		return Config{}, err
+	}
+	cfg := Defaults()
+	if err := v.Unmarshal(&cfg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	return cfg, nil
+}
+
+func TestApplyProfile_Section(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	writeConfig(t, configFile, `
+elasticsearch:
+  addresses: [http://localhost:9200]
+  index: docs
+storage:
+  endpoint: localhost:9002
+profiles:
+  prod:
+    elasticsearch:
+      addresses: [https://es.prod:9200]
+    storage:
+      endpoint: s3.amazonaws.com
+      use_ssl: true
+`)
+
+	cfg, err := loadProfile(t, configFile, "prod")
+	if err != nil {
+		t.Fatalf("ApplyProfile() error = %v", err)
+	}
+
+	if cfg.Elasticsearch.Addresses[0] != "https://es.prod:9200" {
+		t.Errorf("Addresses = %v, want prod cluster", cfg.Elasticsearch.Addresses)
+	}
+	if cfg.Elasticsearch.Index != "docs" {
+		t.Errorf("Index = %q, want base value docs", cfg.Elasticsearch.Index)
+	}
+	if cfg.Storage.Endpoint != "s3.amazonaws.com" || !cfg.Storage.UseSSL {
+		t.Errorf("Storage = %+v, want prod S3", cfg.Storage)
+	}
+}
+
+func TestApplyProfile_File(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	writeConfig(t, configFile, `
+elasticsearch:
+  index: docs
+profiles:
+  staging:
+    elasticsearch:
+      index: from-section
+`)
+	writeConfig(t, filepath.Join(dir, "config.staging.yaml"), `
+elasticsearch:
+  index: from-file
+`)
+
+	cfg, err := loadProfile(t, configFile, "staging")
+	if err != nil {
+		t.Fatalf("ApplyProfile() error = %v", err)
+	}
+	if cfg.Elasticsearch.Index != "from-file" {
+		t.Errorf("Index = %q, want the profile file to win", cfg.Elasticsearch.Index)
+	}
+}
+
+func TestApplyProfile_Unknown(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, configFile, "elasticsearch:\n  index: docs\n")
+
+	if _, err := loadProfile(t, configFile, "missing"); err == nil {
+		t.Error("ApplyProfile() should fail for an unknown profile")
+	}
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SourceScraper overrides scraper settings for one source.
+// Nil or empty fields inherit the global scraper settings.
+type SourceScraper struct {
+	Delay            *time.Duration `mapstructure:"delay"`
+	MaxDepth         *int           `mapstructure:"max_depth"`
+	FollowLinks      *bool          `mapstructure:"follow_links"`
+	Timeout          *time.Duration `mapstructure:"timeout"`
+	UserAgent        string         `mapstructure:"user_agent"`
+	TryMarkdownFirst *bool          `mapstructure:"try_markdown_first"`
+	ContentSelector  string         `mapstructure:"content_selector"`
+	MaxParallel      *int           `mapstructure:"max_parallel_requests"`
+	RespectNoindex   *bool          `mapstructure:"respect_noindex"`
+	RespectRobots    *bool          `mapstructure:"respect_robots"`
+	Incremental      *bool          `mapstructure:"incremental"`
+}
+
+// SourceModel overrides LLM or embeddings settings for one source.
+// Enabled=false turns the stage off for the source; Model swaps the model.
+type SourceModel struct {
+	Enabled *bool  `mapstructure:"enabled"`
+	Model   string `mapstructure:"model"`
+}
+
+// SourceLLM overrides LLM settings for one source, like SourceModel, and
+// whether its chunks are situated within their pages.
+type SourceLLM struct {
+	Enabled       *bool  `mapstructure:"enabled"`
+	Model         string `mapstructure:"model"`
+	SituateChunks *bool  `mapstructure:"situate_chunks"`
+}
+
+// SourceChunking overrides how a source's pages are split into chunks.
+type SourceChunking struct {
+	MaxSize *int `mapstructure:"max_size"` // Replaces ingestion.max_chunk_size
+}
+
+// SourceElasticsearch overrides where a source's documents are indexed.
+// Searches cover the source indices along with the global index.
+type SourceElasticsearch struct {
+	Index string `mapstructure:"index"`
+}
+
+// SourceGitHub ties a local directory source to the GitHub repository it
+// is a checkout of, so pushes to the repository refresh the files they
+// change.
+type SourceGitHub struct {
+	Repo   string `mapstructure:"repo"`   // owner/name
+	Branch string `mapstructure:"branch"` // Branch whose pushes count; default main
+}
+
+// ForSource returns the effective configuration for a source: the global
+// configuration with the source's overrides applied.
+func (c Config) ForSource(source Source) Config {
+	eff := c
+
+	o := source.Scraper
+	if o.Delay != nil {
+		eff.Scraper.Delay = *o.Delay
+	}
+	if o.MaxDepth != nil {
+		eff.Scraper.MaxDepth = *o.MaxDepth
+	}
+	if o.FollowLinks != nil {
+		eff.Scraper.FollowLinks = *o.FollowLinks
+	}
+	if o.Timeout != nil {
+		eff.Scraper.Timeout = *o.Timeout
+	}
+	if o.UserAgent != "" {
+		eff.Scraper.UserAgent = o.UserAgent
+	}
+	if o.TryMarkdownFirst != nil {
+		eff.Scraper.TryMarkdownFirst = *o.TryMarkdownFirst
+	}
+	if o.ContentSelector != "" {
+		eff.Scraper.ContentSelector = o.ContentSelector
+	}
+	if o.MaxParallel != nil {
+		eff.Scraper.MaxParallel = *o.MaxParallel
+	}
+	if o.RespectNoindex != nil {
+		eff.Scraper.RespectNoindex = *o.RespectNoindex
+	}
+	if o.RespectRobots != nil {
+		eff.Scraper.RespectRobots = *o.RespectRobots
+	}
+	if o.Incremental != nil {
+		eff.Scraper.Incremental = *o.Incremental
+	}
+
+	if source.LLM.Enabled != nil {
+		eff.LLM.Enabled = *source.LLM.Enabled
+	}
+	if source.LLM.Model != "" {
+		eff.LLM.Model = source.LLM.Model
+	}
+	if source.LLM.SituateChunks != nil {
+		eff.LLM.SituateChunks = *source.LLM.SituateChunks
+	}
+
+	if source.Embeddings.Enabled != nil {
+		eff.Embeddings.Enabled = *source.Embeddings.Enabled
+	}
+	if source.Embeddings.Model != "" {
+		eff.Embeddings.Model = source.Embeddings.Model
+	}
+
+	if source.Chunking.MaxSize != nil {
+		eff.Ingestion.MaxChunkSize = *source.Chunking.MaxSize
+	}
+
+	if source.Elasticsearch.Index != "" {
+		eff.Elasticsearch.Index = source.Elasticsearch.Index
+	}
+
+	// Listed first, so it wins over a global entry for the same domain
+	if source.Auth != nil {
+		auth := *source.Auth
+		if auth.Domain == "" {
+			if u, err := url.Parse(source.URL); err == nil {
+				auth.Domain = u.Hostname()
+			}
+		}
+		eff.Auth = append([]DomainAuth{auth}, c.Auth...)
+	}
+
+	return eff
+}
+
+// SourceIndices returns the Elasticsearch indices sources are indexed into
+// instead of the global index, in configured order without duplicates.
+func (c Config) SourceIndices() []string {
+	var indices []string
+	for _, source := range c.Sources {
+		if index := source.Elasticsearch.Index; index != "" && !slices.Contains(indices, index) {
+			indices = append(indices, index)
+		}
+	}
+	return indices
+}
+
+// SortByPriority returns the sources ordered by descending priority.
+// Sources with equal priority keep their configured order.
+func SortByPriority(sources []Source) []Source {
+	sorted := slices.Clone(sources)
+	slices.SortStableFunc(sorted, func(a, b Source) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+	return sorted
+}
+
+// SourcesInGroup returns the configured sources in the given group.
+func (c Config) SourcesInGroup(group string) []Source {
+	var sources []Source
+	for _, source := range c.Sources {
+		if source.Group == group {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// SourceByName returns the configured source with the given name.
+func (c Config) SourceByName(name string) (Source, bool) {
+	for _, source := range c.Sources {
+		if source.Name == name {
+			return source, true
+		}
+	}
+	return Source{}, false
+}
+
+// SourceByURL returns the website source whose URL rawURL falls under,
+// the most specific one if several do.
+func (c Config) SourceByURL(rawURL string) (Source, bool) {
+	rawURL = strings.TrimRight(rawURL, "/")
+	var best Source
+	for _, source := range c.Sources {
+		base := strings.TrimRight(source.URL, "/")
+		if base == "" || (rawURL != base && !strings.HasPrefix(rawURL, base+"/")) {
+			continue
+		}
+		if len(source.URL) > len(best.URL) {
+			best = source
+		}
+	}
+	return best, best.URL != ""
+}
+
+// SourcesByRepo returns the directory sources tied to a GitHub repository
+// and branch.
+func (c Config) SourcesByRepo(repo, branch string) []Source {
+	var sources []Source
+	for _, source := range c.Sources {
+		g := source.GitHub
+		if source.Path != "" && strings.EqualFold(g.Repo, repo) && cmp.Or(g.Branch, "main") == branch {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
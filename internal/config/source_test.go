@@ -0,0 +1,286 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForSource(t *testing.T) {
+	cfg := parse(t, `
+llm:
+  enabled: true
+embeddings:
+  enabled: true
+  model: ai/embeddinggemma
+scraper:
+  delay: 1s
+  max_depth: 3
+sources:
+  - name: changelog
+    url: https://example.com/changelog
+    scraper:
+      max_depth: 0
+      delay: 250ms
+      content_selector: article
+    llm:
+      enabled: false
+    chunking:
+      max_size: 1500
+    elasticsearch:
+      index: changelog
+  - name: api
+    url: https://example.com/api
+    embeddings:
+      model: ai/qwen3-embedding
+    llm:
+      situate_chunks: true
+`)
+
+	changelog, ok := cfg.SourceByName("changelog")
+	if !ok {
+		t.Fatal("SourceByName(changelog) not found")
+	}
+	eff := cfg.ForSource(changelog)
+
+	if eff.Scraper.MaxDepth != 0 {
+		t.Errorf("MaxDepth = %d, want 0 (explicit zero override)", eff.Scraper.MaxDepth)
+	}
+	if eff.Scraper.Delay != 250*time.Millisecond {
+		t.Errorf("Delay = %v, want 250ms", eff.Scraper.Delay)
+	}
+	if eff.Scraper.ContentSelector != "article" {
+		t.Errorf("ContentSelector = %q, want article", eff.Scraper.ContentSelector)
+	}
+	if eff.LLM.Enabled {
+		t.Error("LLM should be disabled for changelog")
+	}
+	if !eff.Embeddings.Enabled {
+		t.Error("embeddings should inherit enabled=true")
+	}
+	if eff.Elasticsearch.Index != "changelog" {
+		t.Errorf("Index = %q, want changelog", eff.Elasticsearch.Index)
+	}
+	if eff.Ingestion.MaxChunkSize != 1500 {
+		t.Errorf("MaxChunkSize = %d, want 1500", eff.Ingestion.MaxChunkSize)
+	}
+
+	api, _ := cfg.SourceByName("api")
+	eff = cfg.ForSource(api)
+
+	if eff.Embeddings.Model != "ai/qwen3-embedding" {
+		t.Errorf("Embeddings.Model = %q, want ai/qwen3-embedding", eff.Embeddings.Model)
+	}
+	if eff.Scraper.MaxDepth != 3 || !eff.LLM.Enabled {
+		t.Error("unset overrides should inherit global settings")
+	}
+	if !eff.LLM.SituateChunks || cfg.LLM.SituateChunks {
+		t.Error("situate_chunks should apply to the api source only")
+	}
+	if eff.Elasticsearch.Index != cfg.Elasticsearch.Index {
+		t.Errorf("Index = %q, want global %q", eff.Elasticsearch.Index, cfg.Elasticsearch.Index)
+	}
+	if eff.Ingestion.MaxChunkSize != 4000 {
+		t.Errorf("MaxChunkSize = %d, want default 4000", eff.Ingestion.MaxChunkSize)
+	}
+	if got := cfg.SourceIndices(); len(got) != 1 || got[0] != "changelog" {
+		t.Errorf("SourceIndices() = %v, want [changelog]", got)
+	}
+
+	// Overrides must not leak into the global configuration
+	if cfg.Scraper.MaxDepth != 3 || cfg.Elasticsearch.Index == "changelog" {
+		t.Error("ForSource modified the global configuration")
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	sources := []Source{
+		{Name: "blog"},
+		{Name: "api", Priority: 10},
+		{Name: "guides"},
+		{Name: "reference", Priority: 10},
+		{Name: "archive", Priority: -1},
+	}
+
+	sorted := SortByPriority(sources)
+
+	want := []string{"api", "reference", "blog", "guides", "archive"}
+	for i, name := range want {
+		if sorted[i].Name != name {
+			t.Errorf("sorted[%d] = %q, want %q", i, sorted[i].Name, name)
+		}
+	}
+	if sources[0].Name != "blog" {
+		t.Error("SortByPriority should not reorder its input")
+	}
+}
+
+func TestForSource_MaxParallel(t *testing.T) {
+	cfg := parse(t, `
+sources:
+  - name: fragile
+    url: https://fragile.example.com
+    scraper:
+      max_parallel_requests: 1
+`)
+
+	if cfg.Scraper.MaxParallel != 2 {
+		t.Errorf("global MaxParallel = %d, want default 2", cfg.Scraper.MaxParallel)
+	}
+	if eff := cfg.ForSource(cfg.Sources[0]); eff.Scraper.MaxParallel != 1 {
+		t.Errorf("MaxParallel = %d, want 1", eff.Scraper.MaxParallel)
+	}
+}
+
+func TestForSource_RespectNoindex(t *testing.T) {
+	cfg := parse(t, `
+sources:
+  - name: own-wiki
+    url: https://wiki.example.com
+    scraper:
+      respect_noindex: false
+`)
+
+	if !cfg.Scraper.RespectNoindex {
+		t.Error("global RespectNoindex = false, want default true")
+	}
+	if eff := cfg.ForSource(cfg.Sources[0]); eff.Scraper.RespectNoindex {
+		t.Error("RespectNoindex = true, want the source's false")
+	}
+}
+
+func TestForSource_RespectRobots(t *testing.T) {
+	cfg := parse(t, `
+sources:
+  - name: own-docs
+    url: https://docs.internal.example.com
+    scraper:
+      respect_robots: false
+`)
+
+	if !cfg.Scraper.RespectRobots {
+		t.Error("global RespectRobots = false, want default true")
+	}
+	if eff := cfg.ForSource(cfg.Sources[0]); eff.Scraper.RespectRobots {
+		t.Error("RespectRobots = true, want the source's false")
+	}
+}
+
+func TestForSource_Incremental(t *testing.T) {
+	cfg := parse(t, `
+scraper:
+  incremental: true
+sources:
+  - name: changelog
+    url: https://example.com/changelog
+    scraper:
+      incremental: false
+`)
+
+	if !cfg.Scraper.Incremental {
+		t.Error("global Incremental = false, want true")
+	}
+	if eff := cfg.ForSource(cfg.Sources[0]); eff.Scraper.Incremental {
+		t.Error("Incremental = true, want the source's false")
+	}
+}
+
+func TestForSource_Auth(t *testing.T) {
+	cfg := parse(t, `
+auth:
+  - domain: portal.example.com
+    token: global
+sources:
+  - name: portal
+    url: https://portal.example.com/docs
+    auth:
+      cookies_file: cookies.txt
+  - name: public
+    url: https://example.org
+`)
+
+	eff := cfg.ForSource(cfg.Sources[0])
+	if len(eff.Auth) != 2 || eff.Auth[0].Domain != "portal.example.com" || eff.Auth[0].CookiesFile != "cookies.txt" {
+		t.Errorf("Auth = %+v, want the source's entry for its host first", eff.Auth)
+	}
+	if len(cfg.Auth) != 1 {
+		t.Errorf("global Auth = %+v, want it unchanged", cfg.Auth)
+	}
+	if eff := cfg.ForSource(cfg.Sources[1]); len(eff.Auth) != 1 {
+		t.Errorf("Auth = %+v, want only the global entry", eff.Auth)
+	}
+}
+
+func TestSourcesInGroup(t *testing.T) {
+	cfg := parse(t, `
+sources:
+  - name: k8s
+    url: https://kubernetes.io/docs
+    group: kubernetes
+  - name: go
+    url: https://go.dev/doc
+  - name: helm
+    url: https://helm.sh/docs
+    group: kubernetes
+`)
+
+	sources := cfg.SourcesInGroup("kubernetes")
+	if len(sources) != 2 || sources[0].Name != "k8s" || sources[1].Name != "helm" {
+		t.Errorf("SourcesInGroup(kubernetes) = %v, want [k8s helm]", sources)
+	}
+	if sources := cfg.SourcesInGroup("missing"); len(sources) != 0 {
+		t.Errorf("SourcesInGroup(missing) = %v, want none", sources)
+	}
+}
+
+func TestSourceByURL(t *testing.T) {
+	cfg := parse(t, `
+sources:
+  - name: k8s
+    url: https://kubernetes.io/docs/
+  - name: k8s-tasks
+    url: https://kubernetes.io/docs/tasks
+  - name: team
+    path: ./docs
+`)
+
+	tests := map[string]string{
+		"https://kubernetes.io/docs":                 "k8s",
+		"https://kubernetes.io/docs/concepts/pods/":  "k8s",
+		"https://kubernetes.io/docs/tasks/configure": "k8s-tasks",
+		"https://kubernetes.io/docsets":              "",
+		"https://go.dev/doc":                         "",
+	}
+	for rawURL, want := range tests {
+		source, ok := cfg.SourceByURL(rawURL)
+		if source.Name != want || ok != (want != "") {
+			t.Errorf("SourceByURL(%q) = %q, %v; want %q", rawURL, source.Name, ok, want)
+		}
+	}
+}
+
+func TestSourcesByRepo(t *testing.T) {
+	cfg := parse(t, `
+sources:
+  - name: docs
+    path: ./docs
+    github:
+      repo: acme/docs
+  - name: release-notes
+    path: ./notes
+    github:
+      repo: acme/docs
+      branch: release
+  - name: site
+    url: https://acme.dev/docs
+`)
+
+	if sources := cfg.SourcesByRepo("Acme/Docs", "main"); len(sources) != 1 || sources[0].Name != "docs" {
+		t.Errorf("SourcesByRepo(main) = %v, want [docs]", sources)
+	}
+	if sources := cfg.SourcesByRepo("acme/docs", "release"); len(sources) != 1 || sources[0].Name != "release-notes" {
+		t.Errorf("SourcesByRepo(release) = %v, want [release-notes]", sources)
+	}
+	if sources := cfg.SourcesByRepo("acme/site", "main"); len(sources) != 0 {
+		t.Errorf("SourcesByRepo(acme/site) = %v, want none", sources)
+	}
+}
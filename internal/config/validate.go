@@ -0,0 +1,382 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// Validate checks the configuration for values that would fail at runtime.
+// All problems are reported together.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Namespace != "" && !namespacePattern.MatchString(c.Namespace) {
+		errs = append(errs, errors.New("namespace: must contain only lowercase letters, digits, and dashes"))
+	}
+
+	switch c.Backend.Type {
+	case "elasticsearch":
+	case "embedded":
+		if c.Backend.Path == "" {
+			errs = append(errs, errors.New("backend.path: required when backend.type is embedded"))
+		}
+	case "qdrant":
+		if c.Backend.Qdrant.URL == "" {
+			errs = append(errs, errors.New("backend.qdrant.url: required when backend.type is qdrant"))
+		}
+		if c.Backend.Qdrant.Collection == "" {
+			errs = append(errs, errors.New("backend.qdrant.collection: required when backend.type is qdrant"))
+		}
+		if c.Backend.Qdrant.VectorSize <= 0 {
+			errs = append(errs, errors.New("backend.qdrant.vector_size: must be positive"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("backend.type: unknown backend %q (want elasticsearch, embedded, or qdrant)", c.Backend.Type))
+	}
+	if c.Backend.Type != "elasticsearch" && c.Analytics.Enabled {
+		errs = append(errs, errors.New("analytics.enabled: requires backend.type elasticsearch"))
+	}
+
+	if len(c.Elasticsearch.Addresses) == 0 && c.Elasticsearch.CloudID == "" {
+		errs = append(errs, errors.New("elasticsearch.addresses: at least one address is required"))
+	}
+	if c.Elasticsearch.APIKey != "" && c.Elasticsearch.Username != "" {
+		errs = append(errs, errors.New("elasticsearch: api_key and username are mutually exclusive"))
+	}
+	if c.Elasticsearch.Index == "" {
+		errs = append(errs, errors.New("elasticsearch.index: required"))
+	}
+	switch c.Elasticsearch.Mapping.VectorSimilarity {
+	case "", "cosine", "dot_product", "l2_norm", "max_inner_product":
+	default:
+		errs = append(errs, errors.New("elasticsearch.mapping.vector_similarity: must be one of cosine, dot_product, l2_norm, max_inner_product"))
+	}
+	switch c.Elasticsearch.Fusion.Method {
+	case "", "rrf", "linear":
+	default:
+		errs = append(errs, errors.New("elasticsearch.fusion.method: must be rrf or linear"))
+	}
+	if f := c.Elasticsearch.Fusion; f.RankConstant < 0 || f.RankWindowSize < 0 || f.BM25Weight < 0 || f.VectorWeight < 0 {
+		errs = append(errs, errors.New("elasticsearch.fusion: rank_constant, rank_window_size, and weights must not be negative"))
+	}
+	if r := c.Elasticsearch.Recency; r.Weight < 0 || r.Weight > 1 {
+		errs = append(errs, errors.New("elasticsearch.recency.weight: must be between 0 and 1"))
+	}
+	if r := c.Elasticsearch.Recency; r.Decay < 0 || r.Decay >= 1 {
+		errs = append(errs, errors.New("elasticsearch.recency.decay: must be between 0 and 1"))
+	}
+	if s := c.Elasticsearch.Dedup.Similarity; s < 0 || s > 1 {
+		errs = append(errs, errors.New("elasticsearch.dedup.similarity: must be between 0 and 1"))
+	}
+	if c.Elasticsearch.Dedup.PerSource < 0 {
+		errs = append(errs, errors.New("elasticsearch.dedup.per_source: must not be negative"))
+	}
+	if !validFuzziness(c.Elasticsearch.Fuzziness) {
+		errs = append(errs, fmt.Errorf("elasticsearch.fuzziness: must be AUTO, 0, 1, 2, or AUTO:low,high, got %q", c.Elasticsearch.Fuzziness))
+	}
+	if c.Elasticsearch.FuzzyPrefixLength < 0 {
+		errs = append(errs, errors.New("elasticsearch.fuzzy_prefix_length: must not be negative"))
+	}
+	if c.Embeddings.Enabled && c.Embeddings.SocketPath == "" {
+		errs = append(errs, errors.New("embeddings.socket_path: required when embeddings are enabled"))
+	}
+	if c.LLM.Enabled && c.LLM.SocketPath == "" {
+		errs = append(errs, errors.New("llm.socket_path: required when LLM enrichment is enabled"))
+	}
+	if c.Rerank.Enabled && c.Rerank.SocketPath == "" {
+		errs = append(errs, errors.New("rerank.socket_path: required when reranking is enabled"))
+	}
+	if c.Rerank.Candidates < 0 {
+		errs = append(errs, errors.New("rerank.candidates: must not be negative"))
+	}
+	for _, surface := range c.Rerank.Surfaces {
+		if !slices.Contains(RerankSurfaces, surface) {
+			errs = append(errs, fmt.Errorf("rerank.surfaces: unknown surface %q (expected %s)", surface, strings.Join(RerankSurfaces, ", ")))
+		}
+	}
+	if c.Scraper.Delay < 0 {
+		errs = append(errs, errors.New("scraper.delay: must not be negative"))
+	}
+	if c.Scraper.MaxDepth < 0 {
+		errs = append(errs, errors.New("scraper.max_depth: must not be negative"))
+	}
+	if c.Scraper.MaxParallel < 0 {
+		errs = append(errs, errors.New("scraper.max_parallel_requests: must not be negative"))
+	}
+	if c.Scraper.Uploads < 0 {
+		errs = append(errs, errors.New("scraper.uploads: must not be negative"))
+	}
+	if c.Scraper.MemoryBudget < 0 {
+		errs = append(errs, errors.New("scraper.memory_budget: must not be negative"))
+	}
+	if c.Scraper.Retry.MaxAttempts < 1 {
+		errs = append(errs, errors.New("scraper.retry.max_attempts: must be at least 1"))
+	}
+	if c.Scraper.Retry.Backoff < 0 || c.Scraper.Retry.MaxBackoff < 0 {
+		errs = append(errs, errors.New("scraper.retry: backoff and max_backoff must not be negative"))
+	}
+	if j := c.Scraper.Retry.Jitter; j < 0 || j > 1 {
+		errs = append(errs, errors.New("scraper.retry.jitter: must be between 0 and 1"))
+	}
+	if c.Scraper.ProxyURL != "" && len(c.Scraper.Proxies) > 0 {
+		errs = append(errs, errors.New("scraper: proxy_url and proxies are mutually exclusive"))
+	}
+	if c.Scraper.ProxyURL != "" && !validProxy(c.Scraper.ProxyURL) {
+		errs = append(errs, errors.New("scraper.proxy_url: must be an http, https, socks5, or socks5h URL"))
+	}
+	for i, proxy := range c.Scraper.Proxies {
+		if !validProxy(proxy) {
+			errs = append(errs, fmt.Errorf("scraper.proxies[%d]: must be an http, https, socks5, or socks5h URL", i))
+		}
+	}
+	if c.Jobs.MaxAttempts < 1 {
+		errs = append(errs, errors.New("jobs.max_attempts: must be at least 1"))
+	}
+	if c.Jobs.Backoff < 0 {
+		errs = append(errs, errors.New("jobs.backoff: must not be negative"))
+	}
+	if e := c.Telemetry.Endpoint; e != "" && !strings.HasPrefix(e, "http://") && !strings.HasPrefix(e, "https://") {
+		errs = append(errs, fmt.Errorf("telemetry.endpoint: must be an http(s) URL, got %q", e))
+	}
+	if r := c.Telemetry.SampleRatio; r <= 0 || r > 1 {
+		errs = append(errs, errors.New("telemetry.sample_ratio: must be above 0 and at most 1"))
+	}
+
+	if c.Audit.Enabled {
+		switch c.Audit.Store {
+		case "elasticsearch":
+			if c.Audit.Index == "" {
+				errs = append(errs, errors.New("audit.index: required when audit.store is elasticsearch"))
+			}
+		case "s3":
+		default:
+			errs = append(errs, fmt.Errorf("audit.store: unknown store %q (want elasticsearch or s3)", c.Audit.Store))
+		}
+	}
+
+	if c.Storage.MaxObjectSize < 0 {
+		errs = append(errs, errors.New("storage.max_object_size: must not be negative"))
+	}
+
+	for name, d := range map[string]time.Duration{
+		"fetch":  c.SlowOps.Fetch,
+		"enrich": c.SlowOps.Enrich,
+		"embed":  c.SlowOps.Embed,
+		"index":  c.SlowOps.Index,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("slow_ops.%s: must not be negative", name))
+		}
+	}
+	if c.SlowOps.Summary < 0 {
+		errs = append(errs, errors.New("slow_ops.summary: must not be negative"))
+	}
+
+	for name, t := range map[string]Transport{
+		"elasticsearch": c.Elasticsearch.Transport,
+		"embeddings":    c.Embeddings.Transport,
+		"llm":           c.LLM.Transport,
+	} {
+		if t.MaxIdleConns < 0 {
+			errs = append(errs, fmt.Errorf("%s.transport.max_idle_conns: must not be negative", name))
+		}
+		if t.IdleConnTimeout < 0 {
+			errs = append(errs, fmt.Errorf("%s.transport.idle_conn_timeout: must not be negative", name))
+		}
+		if t.RequestTimeout < 0 {
+			errs = append(errs, fmt.Errorf("%s.transport.request_timeout: must not be negative", name))
+		}
+	}
+
+	if c.Ingestion.StreamThreshold < 0 {
+		errs = append(errs, errors.New("ingestion.stream_threshold: must not be negative"))
+	}
+	if c.Ingestion.MaxChunkSize < 0 {
+		errs = append(errs, errors.New("ingestion.max_chunk_size: must not be negative"))
+	}
+
+	for i, p := range c.Redaction.Patterns {
+		field := fmt.Sprintf("redaction.patterns[%d]", i)
+		if p.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name: required", field))
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("%s.pattern: %w", field, err))
+		}
+	}
+
+	if c.Warmup.KeepAlive < 0 {
+		errs = append(errs, errors.New("warmup.keep_alive: must not be negative"))
+	}
+
+	if c.Analytics.Enabled && c.Analytics.Index == "" {
+		errs = append(errs, errors.New("analytics.index: required when analytics is enabled"))
+	}
+
+	switch c.Events.Bus {
+	case "memory":
+	case "nats":
+		if c.Events.NATS.URL == "" {
+			errs = append(errs, errors.New("events.nats.url: required when events.bus is nats"))
+		}
+	case "kafka":
+		if len(c.Events.Kafka.Brokers) == 0 {
+			errs = append(errs, errors.New("events.kafka.brokers: required when events.bus is kafka"))
+		}
+		switch c.Events.Kafka.SASLMechanism {
+		case "", "plain", "scram-sha-256", "scram-sha-512":
+		default:
+			errs = append(errs, fmt.Errorf("events.kafka.sasl_mechanism: unknown mechanism %q (want plain, scram-sha-256, or scram-sha-512)", c.Events.Kafka.SASLMechanism))
+		}
+	case "sqs":
+	default:
+		errs = append(errs, fmt.Errorf("events.bus: unknown bus %q (want memory, nats, kafka, or sqs)", c.Events.Bus))
+	}
+
+	names := make(map[string]bool)
+	for i, source := range c.Sources {
+		field := fmt.Sprintf("sources[%d]", i)
+		if source.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name: required", field))
+		} else {
+			field = fmt.Sprintf("sources[%s]", source.Name)
+			if names[source.Name] {
+				errs = append(errs, fmt.Errorf("%s: duplicate source name", field))
+			}
+			names[source.Name] = true
+		}
+
+		switch {
+		case source.URL == "" && source.Path == "":
+			errs = append(errs, fmt.Errorf("%s: one of url or path is required", field))
+		case source.URL != "" && source.Path != "":
+			errs = append(errs, fmt.Errorf("%s: url and path are mutually exclusive", field))
+		case source.URL != "":
+			if u, err := url.Parse(source.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("%s.url: must be an absolute http(s) URL", field))
+			}
+		}
+
+		for j, pattern := range source.IncludePatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, fmt.Errorf("%s.include_patterns[%d]: %w", field, j, err))
+			}
+		}
+		for j, pattern := range source.ExcludePatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, fmt.Errorf("%s.exclude_patterns[%d]: %w", field, j, err))
+			}
+		}
+		if size := source.Chunking.MaxSize; size != nil && *size < 1 {
+			errs = append(errs, fmt.Errorf("%s.chunking.max_size: must be at least 1", field))
+		}
+		if p := source.Scraper.MaxParallel; p != nil && *p < 1 {
+			errs = append(errs, fmt.Errorf("%s.scraper.max_parallel_requests: must be at least 1", field))
+		}
+		if d := source.Scraper.MaxDepth; d != nil && *d < 0 {
+			errs = append(errs, fmt.Errorf("%s.scraper.max_depth: must not be negative", field))
+		}
+		for _, name := range slices.Sorted(maps.Keys(source.Headers)) {
+			if !httpguts.ValidHeaderFieldName(name) {
+				errs = append(errs, fmt.Errorf("%s.headers: invalid header name %q", field, name))
+			} else if !httpguts.ValidHeaderFieldValue(source.Headers[name]) {
+				errs = append(errs, fmt.Errorf("%s.headers.%s: invalid header value", field, name))
+			}
+		}
+		if source.Auth != nil {
+			if source.URL == "" {
+				errs = append(errs, fmt.Errorf("%s.auth: requires a url", field))
+			}
+			errs = append(errs, authErrors(field+".auth", *source.Auth)...)
+		}
+		if repo := source.GitHub.Repo; repo != "" {
+			if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+				errs = append(errs, fmt.Errorf("%s.github.repo: must be owner/name", field))
+			}
+			if source.Path == "" {
+				errs = append(errs, fmt.Errorf("%s.github: requires a path to a checkout of the repository", field))
+			}
+		}
+	}
+
+	for i, auth := range c.Auth {
+		field := fmt.Sprintf("auth[%d]", i)
+		if auth.Domain == "" {
+			errs = append(errs, fmt.Errorf("%s.domain: required", field))
+		}
+		errs = append(errs, authErrors(field, auth)...)
+	}
+
+	if r := c.MCP.RateLimit; r.RequestsPerSecond < 0 || r.Burst < 0 || r.MaxConcurrent < 0 {
+		errs = append(errs, errors.New("mcp.rate_limit: requests_per_second, burst, and max_concurrent must not be negative"))
+	}
+	for i, key := range c.MCP.APIKeys {
+		field := fmt.Sprintf("mcp.api_keys[%d]", i)
+		if key.Key == "" {
+			errs = append(errs, fmt.Errorf("%s.key: required", field))
+		}
+		if key.Namespace != "" && !namespacePattern.MatchString(key.Namespace) {
+			errs = append(errs, fmt.Errorf("%s.namespace: must contain only lowercase letters, digits, and dashes", field))
+		}
+	}
+
+	for i, hook := range c.Webhooks {
+		field := fmt.Sprintf("webhooks[%d]", i)
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.url: must be an absolute http(s) URL", field))
+		}
+		for _, event := range hook.Events {
+			if event != "scrape.complete" && event != "ingestion.complete" {
+				errs = append(errs, fmt.Errorf("%s.events: unknown event %q (want scrape.complete or ingestion.complete)", field, event))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validFuzziness reports whether f is a fuzziness Elasticsearch accepts, or
+// empty.
+func validFuzziness(f string) bool {
+	switch f {
+	case "", "AUTO", "0", "1", "2":
+		return true
+	}
+	bounds, ok := strings.CutPrefix(f, "AUTO:")
+	if !ok {
+		return false
+	}
+	low, high, ok := strings.Cut(bounds, ",")
+	var l, h int
+	_, errLow := fmt.Sscan(low, &l)
+	_, errHigh := fmt.Sscan(high, &h)
+	return ok && errLow == nil && errHigh == nil && l >= 0 && l <= h
+}
+
+// validProxy reports whether proxy is a URL the scraper can send requests
+// through.
+func validProxy(proxy string) bool {
+	u, err := url.Parse(proxy)
+	return err == nil && u.Host != "" && slices.Contains([]string{"http", "https", "socks5", "socks5h"}, u.Scheme)
+}
+
+// authErrors checks the credentials configured under field. An empty
+// domain is left to the caller, since a source's defaults to its host.
+func authErrors(field string, auth DomainAuth) []error {
+	var errs []error
+	if strings.ContainsAny(auth.Domain, "/:") {
+		errs = append(errs, fmt.Errorf("%s.domain: must be a host name, not a URL", field))
+	}
+	if auth.Token != "" && auth.Username != "" {
+		errs = append(errs, fmt.Errorf("%s: token and username are mutually exclusive", field))
+	}
+	return errs
+}
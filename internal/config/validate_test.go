@@ -0,0 +1,290 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr []string
+	}{
+		{
+			name: "defaults with sources",
+			yaml: `
+sources:
+  - name: go
+    url: https://go.dev/doc/
+  - name: local
+    path: ./docs
+`,
+		},
+		{
+			name: "source problems",
+			yaml: `
+sources:
+  - url: https://go.dev/doc/
+  - name: dup
+    url: https://a.example.com
+  - name: dup
+    path: ./docs
+  - name: both
+    url: https://b.example.com
+    path: ./docs
+  - name: relative
+    url: /docs
+  - name: fragile
+    url: https://c.example.com
+    scraper:
+      max_parallel_requests: 0
+    chunking:
+      max_size: 0
+  - name: pushed
+    url: https://d.example.com
+    github:
+      repo: acme
+`,
+			wantErr: []string{
+				"sources[0].name: required",
+				"sources[dup]: duplicate source name",
+				"sources[both]: url and path are mutually exclusive",
+				"sources[relative].url: must be an absolute http(s) URL",
+				"sources[fragile].scraper.max_parallel_requests: must be at least 1",
+				"sources[fragile].chunking.max_size: must be at least 1",
+				"sources[pushed].github.repo: must be owner/name",
+				"sources[pushed].github: requires a path to a checkout of the repository",
+			},
+		},
+		{
+			name: "global problems",
+			yaml: `
+namespace: Team_A
+backend:
+  type: sqlite
+elasticsearch:
+  index: ""
+  username: elastic
+  api_key: abc
+  mapping:
+    vector_similarity: euclid
+  fusion:
+    method: borda
+    bm25_weight: -1
+  recency:
+    weight: 2
+  dedup:
+    similarity: 1.5
+    per_source: -1
+  fuzziness: 3
+events:
+  bus: rabbitmq
+embeddings:
+  enabled: true
+rerank:
+  surfaces: [cli, http]
+scraper:
+  max_depth: -1
+  memory_budget: -1
+  proxy_url: ftp://proxy.example.com
+  proxies: [http://proxy.example.com:3128, proxy.example.com]
+  retry:
+    max_attempts: 0
+    backoff: -1s
+    jitter: 2
+analytics:
+  enabled: true
+  index: ""
+slow_ops:
+  embed: -1s
+warmup:
+  keep_alive: -1m
+llm:
+  transport:
+    request_timeout: -5s
+storage:
+  max_object_size: -1
+telemetry:
+  sample_ratio: 1.5
+`,
+			wantErr: []string{
+				"namespace: must contain only lowercase letters, digits, and dashes",
+				`backend.type: unknown backend "sqlite"`,
+				"elasticsearch.index: required",
+				"elasticsearch: api_key and username are mutually exclusive",
+				"analytics.index: required",
+				"elasticsearch.mapping.vector_similarity: must be one of",
+				"elasticsearch.fusion.method: must be rrf or linear",
+				`rerank.surfaces: unknown surface "http"`,
+				"elasticsearch.recency.weight: must be between 0 and 1",
+				"elasticsearch.dedup.similarity: must be between 0 and 1",
+				"elasticsearch.dedup.per_source: must not be negative",
+				"elasticsearch.fuzziness: must be AUTO, 0, 1, 2, or AUTO:low,high",
+				"elasticsearch.fusion: rank_constant, rank_window_size, and weights must not be negative",
+				"events.bus: unknown bus",
+				"embeddings.socket_path: required",
+				"scraper.max_depth: must not be negative",
+				"scraper.memory_budget: must not be negative",
+				"scraper.retry.max_attempts: must be at least 1",
+				"scraper.retry: backoff and max_backoff must not be negative",
+				"scraper.retry.jitter: must be between 0 and 1",
+				"scraper: proxy_url and proxies are mutually exclusive",
+				"scraper.proxy_url: must be an http, https, socks5, or socks5h URL",
+				"scraper.proxies[1]: must be an http, https, socks5, or socks5h URL",
+				"slow_ops.embed: must not be negative",
+				"warmup.keep_alive: must not be negative",
+				"llm.transport.request_timeout: must not be negative",
+				"storage.max_object_size: must not be negative",
+				"telemetry.sample_ratio: must be above 0 and at most 1",
+			},
+		},
+		{
+			name: "embedded backend problems",
+			yaml: `
+backend:
+  type: embedded
+  path: ""
+analytics:
+  enabled: true
+`,
+			wantErr: []string{
+				"backend.path: required when backend.type is embedded",
+				"analytics.enabled: requires backend.type elasticsearch",
+			},
+		},
+		{
+			name: "qdrant backend problems",
+			yaml: `
+backend:
+  type: qdrant
+  qdrant:
+    url: ""
+    vector_size: -1
+`,
+			wantErr: []string{
+				"backend.qdrant.url: required when backend.type is qdrant",
+				"backend.qdrant.vector_size: must be positive",
+			},
+		},
+		{
+			name: "kafka bus problems",
+			yaml: `
+events:
+  bus: kafka
+  kafka:
+    brokers: []
+    sasl_mechanism: gssapi
+`,
+			wantErr: []string{
+				"events.kafka.brokers: required when events.bus is kafka",
+				`events.kafka.sasl_mechanism: unknown mechanism "gssapi"`,
+			},
+		},
+		{
+			name: "auth problems",
+			yaml: `
+auth:
+  - token: secret
+  - domain: https://docs.example.com
+  - domain: wiki.example.com
+    token: secret
+    username: bot
+sources:
+  - name: handbook
+    path: ./handbook
+    auth:
+      domain: handbook.example.com:443
+      token: secret
+    headers:
+      "X Api Key": secret
+`,
+			wantErr: []string{
+				"auth[0].domain: required",
+				"auth[1].domain: must be a host name",
+				"auth[2]: token and username are mutually exclusive",
+				"sources[handbook].auth: requires a url",
+				"sources[handbook].auth.domain: must be a host name",
+				`sources[handbook].headers: invalid header name "x api key"`,
+			},
+		},
+		{
+			name: "webhook problems",
+			yaml: `
+webhooks:
+  - url: hooks.slack.com/services/x
+  - url: https://ci.example.com/hook
+    events: [scrape.complete, index.updated]
+`,
+			wantErr: []string{
+				"webhooks[0].url: must be an absolute http(s) URL",
+				`webhooks[1].events: unknown event "index.updated"`,
+			},
+		},
+		{
+			name: "api key problems",
+			yaml: `
+mcp:
+  rate_limit:
+    max_concurrent: -1
+  api_keys:
+    - name: bot
+    - name: payments
+      key: abc
+      namespace: Payments
+`,
+			wantErr: []string{
+				"mcp.rate_limit: requests_per_second, burst, and max_concurrent must not be negative",
+				"mcp.api_keys[0].key: required",
+				"mcp.api_keys[1].namespace: must contain only lowercase letters, digits, and dashes",
+			},
+		},
+		{
+			name: "source link patterns",
+			yaml: `
+sources:
+  - name: docs
+    url: https://example.com/docs/
+    include_patterns: ['^https://example\.com/docs/']
+    exclude_patterns: ['/blog/', '/(de|fr/']
+`,
+			wantErr: []string{"sources[docs].exclude_patterns[1]: error parsing regexp"},
+		},
+		{
+			name: "redaction patterns",
+			yaml: `
+redaction:
+  enabled: true
+  patterns:
+    - pattern: 'EMP-\d{6}'
+    - name: ticket
+      pattern: 'TKT-(\d+'
+`,
+			wantErr: []string{
+				"redaction.patterns[0].name: required",
+				"redaction.patterns[1].pattern: error parsing regexp",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parse(t, tt.yaml).Validate()
+
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() error = nil, want errors")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error missing %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package diagnostics exposes the Go runtime's profiles of a running
+// process, to investigate memory growth and stuck goroutines during long
+// crawls and ingestions.
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"syscall"
+	"time"
+)
+
+// Handler serves the net/http/pprof endpoints under /debug/pprof/.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Serve serves Handler on addr until ctx is done.
+func Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	// No write timeout: CPU profiles and traces stream for as long as requested
+	server := &http.Server{Handler: Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("pprof server failed: %w", err)
+	}
+	return nil
+}
+
+// WriteProfiles writes a goroutine dump (goroutine-<time>.txt) and a heap
+// profile (heap-<time>.pprof) to dir, creating it if needed. Returns the
+// paths written.
+func WriteProfiles(dir string, now time.Time) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	profiles := []struct {
+		name  string
+		file  string
+		debug int // Text stack traces for goroutines, the binary format for the heap
+	}{
+		{"goroutine", "goroutine-" + stamp + ".txt", 2},
+		{"heap", "heap-" + stamp + ".pprof", 0},
+	}
+
+	// Collect garbage first so the heap profile reflects live objects
+	runtime.GC()
+
+	var paths []string
+	for _, p := range profiles {
+		path := filepath.Join(dir, p.file)
+		if err := writeProfile(p.name, path, p.debug); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// writeProfile writes the named runtime profile to path.
+func writeProfile(name, path string, debug int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s profile: %w", name, err)
+	}
+	if err := rpprof.Lookup(name).WriteTo(f, debug); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s profile: %w", name, err)
+	}
+	return f.Close()
+}
+
+// DumpOnQuit writes profiles to dir each time the process receives SIGQUIT,
+// until ctx is done. The process keeps running instead of exiting with the
+// Go runtime's stack dump.
+func DumpOnQuit(ctx context.Context, dir string) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGQUIT)
+	go func() {
+		defer signal.Stop(quit)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-quit:
+				paths, err := WriteProfiles(dir, time.Now())
+				if err != nil {
+					slog.Warn("failed to dump profiles", "dir", dir, "error", err)
+					continue
+				}
+				slog.Warn("dumped profiles", "files", paths)
+			}
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package diagnostics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestWriteProfiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+	now := time.Date(2024, 12, 4, 17, 30, 0, 0, time.UTC)
+
+	paths, err := WriteProfiles(dir, now)
+	if err != nil {
+		t.Fatalf("WriteProfiles() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "goroutine-20241204T173000Z.txt"),
+		filepath.Join(dir, "heap-20241204T173000Z.pprof"),
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("WriteProfiles() = %v, want %v", paths, want)
+	}
+	for i, path := range paths {
+		if path != want[i] {
+			t.Errorf("path[%d] = %q, want %q", i, path, want[i])
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Size() == 0 {
+			t.Errorf("%s: not written (%v)", path, err)
+		}
+	}
+
+	dump, _ := os.ReadFile(paths[0])
+	if !strings.Contains(string(dump), "TestWriteProfiles") {
+		t.Error("goroutine dump should include the test's stack")
+	}
+}
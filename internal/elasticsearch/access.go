@@ -0,0 +1,136 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// accessLabelsField is the document and chunk field holding access labels.
+const accessLabelsField = "access_labels"
+
+// ensureAccessMapping maps access_labels as a keyword in an index created
+// before the field existed. Left unmapped, the first labeled document would
+// map it dynamically as analyzed text, and label terms would then match
+// the words of other labels.
+func (c *Client) ensureAccessMapping(ctx context.Context, index string) error {
+	mapping, err := c.accessMapping(ctx, index)
+	if err != nil || mapping != nil {
+		return err
+	}
+
+	body := []byte(`{"properties":{"access_labels":{"type":"keyword"}}}`)
+	res, err := c.es.Indices.PutMapping(
+		[]string{index},
+		bytes.NewReader(body),
+		c.es.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to map %s: %w", accessLabelsField, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error mapping %s in index %s: %s", accessLabelsField, index, res.String())
+	}
+	return nil
+}
+
+// withAccessField returns filter with the field its access restriction is
+// enforced on, checked against the mappings of every index read. The
+// field is access_labels when mapped as a keyword, or its keyword
+// subfield when an older index mapped it dynamically as text; any other
+// mapping is refused rather than enforcing access on analyzed text.
+func (c *Client) withAccessField(ctx context.Context, filter Filter) (Filter, error) {
+	if !filter.EnforceAccess {
+		return filter, nil
+	}
+	if field := c.accessField.Load(); field != nil {
+		filter.accessField = *field
+		return filter, nil
+	}
+
+	var resolved, resolvedIn string
+	for _, index := range slices.Concat(c.indices, c.chunkIndices) {
+		mapping, err := c.accessMapping(ctx, index)
+		if err != nil {
+			return filter, err
+		}
+		if mapping == nil {
+			// No labeled entry has been indexed, so any field works
+			continue
+		}
+		field, err := mapping.accessField(index)
+		if err != nil {
+			return filter, err
+		}
+		if resolved != "" && field != resolved {
+			return filter, fmt.Errorf("refusing to enforce access: %s is mapped differently in %s and %s; reindex one of them", accessLabelsField, resolvedIn, index)
+		}
+		resolved, resolvedIn = field, index
+	}
+	if resolved == "" {
+		// Not cached, since the first labeled entry indexed maps the field
+		filter.accessField = accessLabelsField
+		return filter, nil
+	}
+	c.accessField.Store(&resolved)
+	filter.accessField = resolved
+	return filter, nil
+}
+
+// accessMapping returns the mapping of access_labels in index, or nil if
+// it is not mapped there. A missing index maps nothing.
+func (c *Client) accessMapping(ctx context.Context, index string) (*fieldMapping, error) {
+	res, err := c.es.Indices.GetFieldMapping(
+		[]string{accessLabelsField},
+		c.es.Indices.GetFieldMapping.WithContext(ctx),
+		c.es.Indices.GetFieldMapping.WithIndex(index),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s mapping: %w", accessLabelsField, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting %s mapping of index %s: %s", accessLabelsField, index, res.String())
+	}
+
+	// Keyed by index name, which differs from index when it is an alias
+	var body map[string]struct {
+		Mappings map[string]struct {
+			Mapping map[string]fieldMapping `json:"mapping"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode %s mapping: %w", accessLabelsField, err)
+	}
+	for _, idx := range body {
+		if mapping, ok := idx.Mappings[accessLabelsField].Mapping[accessLabelsField]; ok {
+			return &mapping, nil
+		}
+	}
+	return nil, nil
+}
+
+// fieldMapping is the mapping of a single field.
+type fieldMapping struct {
+	Type   string                  `json:"type"`
+	Fields map[string]fieldMapping `json:"fields"`
+}
+
+// accessField returns the keyword field access labels mapped as m are
+// matched on in index.
+func (m fieldMapping) accessField(index string) (string, error) {
+	switch {
+	case m.Type == "keyword":
+		return accessLabelsField, nil
+	case m.Fields["keyword"].Type == "keyword":
+		return accessLabelsField + ".keyword", nil
+	}
+	return "", fmt.Errorf("refusing to enforce access: %s in index %s is mapped as %s, not keyword; reindex it", accessLabelsField, index, m.Type)
+}
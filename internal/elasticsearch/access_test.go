@@ -0,0 +1,120 @@
+package elasticsearch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestFieldMapping_AccessField(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping fieldMapping
+		want    string
+		wantErr bool
+	}{
+		{"keyword", fieldMapping{Type: "keyword"}, "access_labels", false},
+		{"dynamic text", fieldMapping{Type: "text", Fields: map[string]fieldMapping{"keyword": {Type: "keyword"}}}, "access_labels.keyword", false},
+		{"text only", fieldMapping{Type: "text"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.mapping.accessField("docs")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("accessField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("accessField() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_AccessField(t *testing.T) {
+	f := Filter{EnforceAccess: true, AccessLabels: []string{"ops"}, accessField: "access_labels.keyword"}
+	clause := f.clauses()[0]["bool"].(map[string]interface{})["should"].([]map[string]interface{})[1]
+	if _, ok := clause["terms"].(map[string]interface{})["access_labels.keyword"]; !ok {
+		t.Errorf("clauses() = %v, want terms on access_labels.keyword", clause)
+	}
+}
+
+func TestClient_AccessMappingMigration(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-access-migration",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	// An index created before access labels, without the field mapped
+	client.DeleteIndex(ctx)
+	for _, index := range []string{client.index, client.chunkIndex} {
+		if err := client.createIndex(ctx, index, []byte(`{"mappings":{"properties":{"url":{"type":"keyword"}}}}`)); err != nil {
+			t.Fatalf("createIndex() error = %v", err)
+		}
+	}
+	defer client.DeleteIndex(ctx)
+
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	mapping, err := client.accessMapping(ctx, client.index)
+	if err != nil {
+		t.Fatalf("accessMapping() error = %v", err)
+	}
+	if mapping == nil || mapping.Type != "keyword" {
+		t.Fatalf("access_labels mapped as %+v, want keyword", mapping)
+	}
+
+	docs := []models.Document{
+		{ID: "public", URL: "https://example.com/public", Content: "runbook"},
+		{ID: "team", URL: "https://example.com/team", Content: "runbook", AccessLabels: []string{"finance team"}},
+	}
+	if _, err := client.BulkIndex(ctx, docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	// Analyzed as text, the label would match the word "team"
+	count, err := client.Count(ctx, Filter{EnforceAccess: true, AccessLabels: []string{"team"}})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Count() = %d, want 1", count)
+	}
+}
+
+func TestClient_AccessMappingRefused(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-access-refused",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	for _, index := range []string{client.index, client.chunkIndex} {
+		if err := client.createIndex(ctx, index, []byte(`{"mappings":{"properties":{"access_labels":{"type":"text"}}}}`)); err != nil {
+			t.Fatalf("createIndex() error = %v", err)
+		}
+	}
+	defer client.DeleteIndex(ctx)
+
+	_, err = client.Count(ctx, Filter{EnforceAccess: true, AccessLabels: []string{"ops"}})
+	if err == nil || !strings.Contains(err.Error(), "refusing to enforce access") {
+		t.Errorf("Count() error = %v, want access enforcement refused", err)
+	}
+}
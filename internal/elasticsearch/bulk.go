@@ -0,0 +1,494 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// scrollKeepAlive is how long ES keeps a scroll context between batches.
+const scrollKeepAlive = time.Minute
+
+// scrollResponse represents an ES search/scroll response page of
+// documents or chunks.
+type scrollResponse[T any] struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			Source T `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// ScrollDocuments streams every document in the indices to fn, batchSize at a time.
+// If fields are given, only those source fields are fetched.
+// Iteration stops at the first error returned by fn.
+func (c *Client) ScrollDocuments(ctx context.Context, batchSize int, fn func(models.Document) error, fields ...string) error {
+	return scroll(ctx, c, c.indices, nil, batchSize, fn, fields...)
+}
+
+// ScrollChunks streams every chunk in the chunk indices to fn, batchSize
+// at a time, like ScrollDocuments.
+func (c *Client) ScrollChunks(ctx context.Context, batchSize int, fn func(models.Chunk) error, fields ...string) error {
+	return scroll(ctx, c, c.chunkIndices, nil, batchSize, fn, fields...)
+}
+
+// ScrollDocumentsWithoutEmbedding streams every document that has no embedding to fn.
+func (c *Client) ScrollDocumentsWithoutEmbedding(ctx context.Context, batchSize int, fn func(models.Document) error, fields ...string) error {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must_not": map[string]interface{}{
+					"exists": map[string]interface{}{"field": "embedding"},
+				},
+			},
+		},
+	}
+	return scroll(ctx, c, c.indices, query, batchSize, fn, fields...)
+}
+
+// scroll streams the entries of indices matching query (all entries if
+// nil) to fn. Missing indices have no entries.
+func scroll[T any](ctx context.Context, c *Client, indices []string, query map[string]interface{}, batchSize int, fn func(T) error, fields ...string) error {
+	opts := []func(*esapi.SearchRequest){
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(indices...),
+		c.es.Search.WithIgnoreUnavailable(true),
+		c.es.Search.WithSize(batchSize),
+		c.es.Search.WithSort("_doc"),
+		c.es.Search.WithScroll(scrollKeepAlive),
+	}
+	if len(fields) > 0 {
+		opts = append(opts, c.es.Search.WithSourceIncludes(fields...))
+	}
+	if query != nil {
+		body, err := json.Marshal(query)
+		if err != nil {
+			return fmt.Errorf("failed to marshal query: %w", err)
+		}
+		opts = append(opts, c.es.Search.WithBody(bytes.NewReader(body)))
+	}
+
+	res, err := c.es.Search(opts...)
+	if err != nil {
+		return fmt.Errorf("scroll search failed: %w", err)
+	}
+
+	var scrollID string
+	defer func() {
+		if scrollID != "" {
+			c.clearScroll(scrollID)
+		}
+	}()
+
+	for {
+		page, err := decodeScrollPage[T](res)
+		if err != nil {
+			return err
+		}
+		scrollID = page.ScrollID
+
+		if len(page.Hits.Hits) == 0 {
+			return nil
+		}
+		for _, hit := range page.Hits.Hits {
+			if err := fn(hit.Source); err != nil {
+				return err
+			}
+		}
+
+		res, err = c.es.Scroll(
+			c.es.Scroll.WithContext(ctx),
+			c.es.Scroll.WithScrollID(scrollID),
+			c.es.Scroll.WithScroll(scrollKeepAlive),
+		)
+		if err != nil {
+			return fmt.Errorf("scroll failed: %w", err)
+		}
+	}
+}
+
+// decodeScrollPage decodes and closes a search/scroll response.
+func decodeScrollPage[T any](res *esapi.Response) (*scrollResponse[T], error) {
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("scroll error: %s", res.String())
+	}
+
+	var page scrollResponse[T]
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &page, nil
+}
+
+// clearScroll releases a scroll context. Errors are ignored; contexts expire anyway.
+func (c *Client) clearScroll(scrollID string) {
+	res, err := c.es.ClearScroll(c.es.ClearScroll.WithScrollID(scrollID))
+	if err == nil {
+		res.Body.Close()
+	}
+}
+
+// bulkResponse represents the ES bulk API response.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error,omitempty"`
+	} `json:"items"`
+}
+
+// BulkIndex indexes documents in a single bulk request.
+// Returns the number of documents indexed; per-document failures are
+// reported in the returned error.
+func (c *Client) BulkIndex(ctx context.Context, docs []models.Document) (int, error) {
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range docs {
+		action := map[string]interface{}{
+			"index": map[string]interface{}{"_index": c.index, "_id": doc.ID},
+		}
+		if err := enc.Encode(action); err != nil {
+			return 0, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := enc.Encode(c.indexable(doc)); err != nil {
+			return 0, fmt.Errorf("failed to marshal document: %w", err)
+		}
+	}
+
+	res, err := c.es.Bulk(
+		&body,
+		c.es.Bulk.WithContext(ctx),
+		c.es.Bulk.WithIndex(c.index),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("bulk request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("bulk error: %s", res.String())
+	}
+
+	var br bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	indexed := len(docs)
+	if !br.Errors {
+		return indexed, nil
+	}
+
+	var failures []string
+	for _, item := range br.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				indexed--
+				failures = append(failures, fmt.Sprintf("%s: %s", result.ID, result.Error.Reason))
+			}
+		}
+	}
+	return indexed, fmt.Errorf("%d documents failed to index: %s", len(failures), strings.Join(failures, "; "))
+}
+
+// UpdateEmbeddings sets the embedding of existing documents, in whichever
+// index holds them, in a single bulk request.
+// Returns the number of documents updated; per-document failures are
+// reported in the returned error.
+func (c *Client) UpdateEmbeddings(ctx context.Context, embeddings map[string][]float32) (int, error) {
+	if len(embeddings) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, 0, len(embeddings))
+	for id := range embeddings {
+		ids = append(ids, id)
+	}
+	located, err := c.locate(ctx, c.indices, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for id, embedding := range embeddings {
+		action := map[string]interface{}{
+			"update": map[string]interface{}{"_index": cmp.Or(located[id], c.index), "_id": id},
+		}
+		if err := enc.Encode(action); err != nil {
+			return 0, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		update := map[string]interface{}{
+			"doc": map[string]interface{}{"embedding": embedding},
+		}
+		if err := enc.Encode(update); err != nil {
+			return 0, fmt.Errorf("failed to marshal update: %w", err)
+		}
+	}
+
+	res, err := c.es.Bulk(
+		&body,
+		c.es.Bulk.WithContext(ctx),
+		c.es.Bulk.WithIndex(c.index),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("bulk update failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("bulk update error: %s", res.String())
+	}
+
+	var br bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	updated := len(embeddings)
+	if !br.Errors {
+		return updated, nil
+	}
+
+	var failures []string
+	for _, item := range br.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				updated--
+				failures = append(failures, fmt.Sprintf("%s: %s", result.ID, result.Error.Reason))
+			}
+		}
+	}
+	return updated, fmt.Errorf("%d documents failed to update: %s", len(failures), strings.Join(failures, "; "))
+}
+
+// DeleteDocuments removes documents and their chunks by ID, from
+// whichever index holds them, in a single bulk request. Returns the number
+// of documents deleted; IDs that do not exist are not errors.
+func (c *Client) DeleteDocuments(ctx context.Context, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := c.deleteChunks(ctx, c.chunkIndices, ids); err != nil {
+		return 0, err
+	}
+	located, err := c.locate(ctx, c.indices, ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(located) == 0 {
+		return 0, nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for id, index := range located {
+		action := map[string]interface{}{
+			"delete": map[string]interface{}{"_index": index, "_id": id},
+		}
+		if err := enc.Encode(action); err != nil {
+			return 0, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+	}
+
+	res, err := c.es.Bulk(
+		&body,
+		c.es.Bulk.WithContext(untimed(ctx)),
+		c.es.Bulk.WithIndex(c.index),
+		c.es.Bulk.WithRefresh("true"),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("bulk delete failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("bulk delete error: %s", res.String())
+	}
+
+	var br bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	deleted := 0
+	var failures []string
+	for _, item := range br.Items {
+		for _, result := range item {
+			switch {
+			case result.Error != nil:
+				failures = append(failures, fmt.Sprintf("%s: %s", result.ID, result.Error.Reason))
+			case result.Status < 300:
+				deleted++
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return deleted, fmt.Errorf("%d documents failed to delete: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return deleted, nil
+}
+
+// hashBatchSize is the number of documents fetched per request by
+// ContentHashes.
+const hashBatchSize = 1000
+
+// mgetResponse represents an ES multi-get response.
+type mgetResponse struct {
+	Docs []struct {
+		ID     string          `json:"_id"`
+		Found  bool            `json:"found"`
+		Source models.Document `json:"_source"`
+	} `json:"docs"`
+}
+
+// ContentHashes returns the content hash of each indexed document among
+// ids. Documents that are not indexed, or were indexed without a hash, are
+// left out.
+func (c *Client) ContentHashes(ctx context.Context, ids []string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for batch := range slices.Chunk(ids, hashBatchSize) {
+		body, err := json.Marshal(map[string]interface{}{"ids": batch})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		res, err := c.es.Mget(
+			bytes.NewReader(body),
+			c.es.Mget.WithContext(ctx),
+			c.es.Mget.WithIndex(c.index),
+			c.es.Mget.WithSourceIncludes("content_hash"),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("mget failed: %w", err)
+		}
+
+		var mr mgetResponse
+		if res.IsError() {
+			err = fmt.Errorf("mget error: %s", res.String())
+		} else if err = json.NewDecoder(res.Body).Decode(&mr); err != nil {
+			err = fmt.Errorf("failed to decode response: %w", err)
+		}
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range mr.Docs {
+			if doc.Found && doc.Source.ContentHash != "" {
+				hashes[doc.ID] = doc.Source.ContentHash
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// GetDocuments retrieves the documents with the given IDs, in order.
+// Documents that do not exist are left out.
+func (c *Client) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	return mget[models.Document](ctx, c, c.indices, ids)
+}
+
+// GetChunks retrieves the chunks with the given IDs, in order. Chunks that
+// do not exist are left out.
+func (c *Client) GetChunks(ctx context.Context, ids []string) ([]models.Chunk, error) {
+	return mget[models.Chunk](ctx, c, c.chunkIndices, ids)
+}
+
+// mgetEntry is an entry of an ES multi-get response.
+type mgetEntry[T any] struct {
+	Index  string `json:"_index"`
+	ID     string `json:"_id"`
+	Found  bool   `json:"found"`
+	Source T      `json:"_source"`
+}
+
+// mget retrieves the entries with the given IDs from indices, in order,
+// leaving out those that do not exist. An entry in several indices is
+// taken from the first.
+func mget[T any](ctx context.Context, c *Client, indices []string, ids []string) ([]T, error) {
+	var found []T
+	err := mgetEach(ctx, c, indices, ids, true, func(entry mgetEntry[T]) {
+		found = append(found, entry.Source)
+	})
+	return found, err
+}
+
+// locate returns the index among indices holding each of ids, leaving
+// out those in none.
+func (c *Client) locate(ctx context.Context, indices []string, ids []string) (map[string]string, error) {
+	located := make(map[string]string)
+	err := mgetEach(ctx, c, indices, ids, false, func(entry mgetEntry[struct{}]) {
+		located[entry.ID] = entry.Index
+	})
+	return located, err
+}
+
+// mgetEach looks up ids in indices and calls fn with each entry found, in
+// the order of ids and the first index holding it. Sources are fetched
+// only if withSource is set; missing indices hold nothing.
+func mgetEach[T any](ctx context.Context, c *Client, indices []string, ids []string, withSource bool, fn func(mgetEntry[T])) error {
+	for batch := range slices.Chunk(ids, max(hashBatchSize/len(indices), 1)) {
+		var docs []map[string]string
+		for _, id := range batch {
+			for _, index := range indices {
+				docs = append(docs, map[string]string{"_index": index, "_id": id})
+			}
+		}
+		body, err := json.Marshal(map[string]interface{}{"docs": docs})
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		res, err := c.es.Mget(
+			bytes.NewReader(body),
+			c.es.Mget.WithContext(ctx),
+			c.es.Mget.WithSource(strconv.FormatBool(withSource)),
+		)
+		if err != nil {
+			return fmt.Errorf("mget failed: %w", err)
+		}
+
+		// Entries of missing indices come back with an error and not found
+		var mr struct {
+			Docs []mgetEntry[T] `json:"docs"`
+		}
+		if res.IsError() {
+			err = fmt.Errorf("mget error: %s", res.String())
+		} else if err = json.NewDecoder(res.Body).Decode(&mr); err != nil {
+			err = fmt.Errorf("failed to decode response: %w", err)
+		}
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]bool)
+		for _, entry := range mr.Docs {
+			if entry.Found && !seen[entry.ID] {
+				seen[entry.ID] = true
+				fn(entry)
+			}
+		}
+	}
+	return nil
+}
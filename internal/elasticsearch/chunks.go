@@ -0,0 +1,230 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// IndexChunks replaces the chunks of a document with chunks in a single
+// bulk request. Chunks left over from a longer earlier version of the
+// document are removed, as are those in the other chunk indices, left
+// from when the document was indexed elsewhere.
+func (c *Client) IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, chunk := range chunks {
+		action := map[string]interface{}{
+			"index": map[string]interface{}{"_index": c.chunkIndex, "_id": chunk.ID},
+		}
+		if err := enc.Encode(action); err != nil {
+			return fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := enc.Encode(c.indexableChunk(chunk)); err != nil {
+			return fmt.Errorf("failed to marshal chunk: %w", err)
+		}
+	}
+
+	// Drop stale chunks past the new last position
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []map[string]interface{}{
+					{"term": map[string]interface{}{"document_id": documentID}},
+					{"range": map[string]interface{}{"position": map[string]interface{}{"gte": len(chunks)}}},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+	if _, err := c.deleteByQuery(ctx, []string{c.chunkIndex}, data); err != nil {
+		return fmt.Errorf("failed to delete stale chunks: %w", err)
+	}
+	others := slices.DeleteFunc(slices.Clone(c.chunkIndices), func(index string) bool {
+		return index == c.chunkIndex
+	})
+	if len(others) > 0 {
+		if err := c.deleteChunks(ctx, others, []string{documentID}); err != nil {
+			return err
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	res, err := c.es.Bulk(
+		&body,
+		c.es.Bulk.WithContext(ctx),
+		c.es.Bulk.WithIndex(c.chunkIndex),
+	)
+	if err != nil {
+		return fmt.Errorf("bulk request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("bulk error: %s", res.String())
+	}
+
+	var br bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !br.Errors {
+		return nil
+	}
+
+	var failures []string
+	for _, item := range br.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				failures = append(failures, fmt.Sprintf("%s: %s", result.ID, result.Error.Reason))
+			}
+		}
+	}
+	return fmt.Errorf("%d chunks failed to index: %s", len(failures), strings.Join(failures, "; "))
+}
+
+// deleteChunks removes every chunk of the given documents from the chunk
+// indices.
+func (c *Client) deleteChunks(ctx context.Context, indices []string, documentIDs []string) error {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"terms": map[string]interface{}{"document_id": documentIDs},
+		},
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+	if _, err := c.deleteByQuery(ctx, indices, data); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return nil
+}
+
+// chunkSearchResponse represents an ES search response over chunks.
+type chunkSearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source models.Chunk `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// chunkFields are the chunk fields searches match.
+var chunkFields = append([]string{"content", "context", "title", "heading_path^2"}, localizedFields...)
+
+// SearchChunks performs a hybrid BM25 + vector search over chunks restricted
+// to filter. If queryEmbedding is nil, falls back to BM25 only.
+func (c *Client) SearchChunks(ctx context.Context, query string, queryEmbedding []float32, limit int, filter Filter) ([]models.Chunk, error) {
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	// Chunks have no summary; their scope is the page title and headings
+	fields := filter.fields(chunkFields, []string{"title", "heading_path^2"})
+	textQuery := filter.apply(c.textMatch(query, fields))
+
+	// Only the per-source limit applies to chunks
+	dedup := Dedup{PerSource: c.dedup.withFilter(filter).PerSource}
+	candidates := dedup.candidates(limit)
+
+	searchQuery := map[string]interface{}{
+		"query": textQuery,
+		"size":  candidates,
+	}
+	if queryEmbedding != nil {
+		knn := map[string]interface{}{
+			"field":          "embedding",
+			"query_vector":   queryEmbedding,
+			"k":              candidates,
+			"num_candidates": candidates * 2,
+		}
+		filter.filterKNN(knn)
+
+		// Combine BM25 and vector results as configured
+		searchQuery = map[string]interface{}{
+			"retriever": c.fusion.retriever(textQuery, knn, candidates),
+			"size":      candidates,
+		}
+	}
+	chunks, err := c.searchChunks(ctx, searchQuery)
+	if err != nil {
+		return nil, err
+	}
+	return dedup.limitChunks(chunks, limit), nil
+}
+
+// SearchSections returns the sections of one document that best match
+// query, in order, to find the relevant part of a long page. Sections are
+// the document's chunks; filter restricts them as in SearchChunks, apart
+// from Scope, since every chunk is of the same page.
+func (c *Client) SearchSections(ctx context.Context, documentID, query string, limit int, filter Filter) ([]models.Chunk, error) {
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	searchQuery := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":   filter.apply(c.textMatch(query, chunkFields)),
+				"filter": map[string]interface{}{"term": map[string]interface{}{"document_id": documentID}},
+			},
+		},
+		"size": limit,
+	}
+	return c.searchChunks(ctx, searchQuery)
+}
+
+// searchChunks runs a search request against the chunk index.
+func (c *Client) searchChunks(ctx context.Context, searchQuery map[string]interface{}) ([]models.Chunk, error) {
+	data, err := json.Marshal(searchQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(c.chunkIndices...),
+		c.es.Search.WithIgnoreUnavailable(true),
+		c.es.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("chunk search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("chunk search error: %s", res.String())
+	}
+
+	var sr chunkSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	chunks := make([]models.Chunk, len(sr.Hits.Hits))
+	for i, hit := range sr.Hits.Hits {
+		chunks[i] = hit.Source
+	}
+	return chunks, nil
+}
+
+// GetChunk retrieves a chunk by ID. Returns nil if it does not exist.
+func (c *Client) GetChunk(ctx context.Context, id string) (*models.Chunk, error) {
+	chunks, err := c.GetChunks(ctx, []string{id})
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	return &chunks[0], nil
+}
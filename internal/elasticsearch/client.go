@@ -4,32 +4,154 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel"
 )
 
 // Config holds Elasticsearch client configuration.
 type Config struct {
 	Addresses []string
+	CloudID   string // Elastic Cloud deployment; replaces Addresses when set
 	Index     string
 	Username  string
 	Password  string
+	APIKey    string  // Base64-encoded API key; replaces Username and Password when set
+	Mapping   Mapping // Index mapping customizations applied by CreateIndex
+	Fusion    Fusion  // How hybrid searches combine BM25 and vector results
+	Recency   Recency // How much newer pages are favored by searches
+	Dedup     Dedup   // Which copies of a page searches leave out
+
+	// SourceIndices are further indices searched and read along with
+	// Index, those sources are indexed into instead of it; each has its
+	// chunks in <index>_chunks. Writes go to Index alone
+	SourceIndices []string
+
+	// Fuzziness is the edit distance tolerated between query and indexed
+	// terms, as "AUTO", "0", "1", "2", or "AUTO:low,high"; empty matches
+	// terms exactly
+	Fuzziness string
+	// FuzzyPrefixLength is how many leading characters of a term must
+	// match exactly when Fuzziness is set
+	FuzzyPrefixLength int
+
+	// Connection tuning; zero values use the defaults below
+	MaxIdleConns    int           // Idle connections kept open for reuse
+	IdleConnTimeout time.Duration // How long an unused connection is kept open
+	Timeout         time.Duration // Limit on waiting for a response to a single request, except deletes and refreshes
+}
+
+// Connection defaults. Ingestion indexes documents and chunks while other
+// stages run, so more connections are kept open than net/http's default of
+// two idle connections per host, which leads to redialing on long runs.
+const (
+	defaultMaxIdleConns    = 16
+	defaultIdleConnTimeout = 90 * time.Second
+	defaultTimeout         = time.Minute
+)
+
+// untimedKey marks the context of requests exempt from the response timeout.
+type untimedKey struct{}
+
+// untimed returns ctx with its requests exempt from the response timeout.
+// Synchronous deletes and refreshes take as long as the data they cover,
+// which has no bound, so they are limited by ctx alone.
+func untimed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untimedKey{}, true)
+}
+
+// timeoutTransport gives up on responses slower than the configured
+// timeout, except for requests of untimed contexts.
+type timeoutTransport struct {
+	limited   http.RoundTripper
+	unlimited http.RoundTripper
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(untimedKey{}) != nil {
+		return t.unlimited.RoundTrip(req)
+	}
+	return t.limited.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of both transports.
+func (t *timeoutTransport) CloseIdleConnections() {
+	for _, rt := range []http.RoundTripper{t.limited, t.unlimited} {
+		if c, ok := rt.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+	}
 }
 
 // Client wraps the Elasticsearch client with RAG-specific operations.
+// Documents are kept in the configured index and their chunks in a
+// companion index named <index>_chunks. Searches and reads also cover the
+// source indices.
 type Client struct {
-	es    *elasticsearch.Client
-	index string
+	es           *elasticsearch.Client
+	transport    *timeoutTransport
+	index        string
+	chunkIndex   string
+	indices      []string // Document indices read: index, then the source indices
+	chunkIndices []string // Chunk indices of indices
+	mapping      Mapping
+	fusion       Fusion
+	recency      Recency
+	dedup        Dedup
+	fuzziness    string
+	prefixLen    int
+
+	// accessField caches the field access labels are enforced on, once
+	// the indices map it
+	accessField atomic.Pointer[string]
 }
 
 // New creates a new Elasticsearch client.
 func New(config Config) (*Client, error) {
+	if config.MaxIdleConns == 0 {
+		config.MaxIdleConns = defaultMaxIdleConns
+	}
+	if config.IdleConnTimeout == 0 {
+		config.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	// TCP keep-alive and dial timeouts are those of net/http's default
+	unlimited := http.DefaultTransport.(*http.Transport).Clone()
+	unlimited.MaxIdleConns = config.MaxIdleConns
+	unlimited.MaxIdleConnsPerHost = config.MaxIdleConns
+	unlimited.IdleConnTimeout = config.IdleConnTimeout
+	limited := unlimited.Clone()
+	limited.ResponseHeaderTimeout = config.Timeout
+	transport := &timeoutTransport{limited: limited, unlimited: unlimited}
+
+	// The client refuses both addresses and a cloud ID, and addresses
+	// always have a default
+	addresses := config.Addresses
+	if config.CloudID != "" {
+		addresses = nil
+	}
+
 	cfg := elasticsearch.Config{
-		Addresses: config.Addresses,
+		Addresses: addresses,
+		CloudID:   config.CloudID,
 		Username:  config.Username,
 		Password:  config.Password,
+		APIKey:    config.APIKey,
+		Transport: transport,
+		// Requests become spans of the global tracer provider, a no-op
+		// unless tracing is configured
+		Instrumentation: elasticsearch.NewOpenTelemetryInstrumentation(otel.GetTracerProvider(), false),
 	}
 
 	es, err := elasticsearch.NewClient(cfg)
@@ -37,9 +159,30 @@ func New(config Config) (*Client, error) {
 		return nil, fmt.Errorf("failed to create ES client: %w", err)
 	}
 
+	indices := []string{config.Index}
+	for _, index := range config.SourceIndices {
+		if index != "" && !slices.Contains(indices, index) {
+			indices = append(indices, index)
+		}
+	}
+	chunkIndices := make([]string, len(indices))
+	for i, index := range indices {
+		chunkIndices[i] = index + "_chunks"
+	}
+
 	return &Client{
-		es:    es,
-		index: config.Index,
+		es:           es,
+		transport:    transport,
+		index:        config.Index,
+		chunkIndex:   config.Index + "_chunks",
+		indices:      indices,
+		chunkIndices: chunkIndices,
+		mapping:      config.Mapping,
+		fusion:       config.Fusion,
+		recency:      config.Recency,
+		dedup:        config.Dedup,
+		fuzziness:    config.Fuzziness,
+		prefixLen:    config.FuzzyPrefixLength,
 	}, nil
 }
 
@@ -53,48 +196,45 @@ func (c *Client) Ping(ctx context.Context) bool {
 	return !res.IsError()
 }
 
-// indexMapping defines the ES index mapping for documents.
-// Supports LLM-generated tags/summary and optional vector embeddings.
-var indexMapping = `{
-	"mappings": {
-		"properties": {
-			"id": { "type": "keyword" },
-			"url": { "type": "keyword" },
-			"title": { "type": "text" },
-			"content": { "type": "text", "analyzer": "english" },
-			"content_type": { "type": "keyword" },
-			"scraped_at": { "type": "date" },
-			"tags": { "type": "text", "analyzer": "english" },
-			"summary": { "type": "text", "analyzer": "english" },
-			"embedding": {
-				"type": "dense_vector",
-				"dims": 2560,
-				"index": true,
-				"similarity": "cosine"
-			}
-		}
+// CreateIndex creates the document and chunk indices with proper mappings.
+func (c *Client) CreateIndex(ctx context.Context) error {
+	body, err := c.mapping.body()
+	if err != nil {
+		return err
+	}
+	if err := c.createIndex(ctx, c.index, body); err != nil {
+		return err
+	}
+	if err := c.ensureTagsKeyword(ctx, c.index); err != nil {
+		return err
 	}
-}`
 
-// CreateIndex creates the index with proper mapping.
-func (c *Client) CreateIndex(ctx context.Context) error {
+	chunkBody, err := c.mapping.chunkBody()
+	if err != nil {
+		return err
+	}
+	return c.createIndex(ctx, c.chunkIndex, chunkBody)
+}
+
+// createIndex creates index with body unless it already exists.
+func (c *Client) createIndex(ctx context.Context, index string, body []byte) error {
 	// Check if index exists
-	res, err := c.es.Indices.Exists([]string{c.index}, c.es.Indices.Exists.WithContext(ctx))
+	res, err := c.es.Indices.Exists([]string{index}, c.es.Indices.Exists.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to check index: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode == 200 {
-		// Index already exists
-		return nil
+		// Index already exists, perhaps from before access labels
+		return c.ensureAccessMapping(ctx, index)
 	}
 
 	// Create index
 	res, err = c.es.Indices.Create(
-		c.index,
+		index,
 		c.es.Indices.Create.WithContext(ctx),
-		c.es.Indices.Create.WithBody(bytes.NewReader([]byte(indexMapping))),
+		c.es.Indices.Create.WithBody(bytes.NewReader(body)),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create index: %w", err)
@@ -102,15 +242,26 @@ func (c *Client) CreateIndex(ctx context.Context) error {
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return fmt.Errorf("error creating index: %s", res.String())
+		return fmt.Errorf("error creating index %s: %s", index, res.String())
 	}
 
 	return nil
 }
 
-// DeleteIndex removes the index (for testing/cleanup).
+// Close closes the idle connections of the client; requests made
+// afterwards open new ones.
+func (c *Client) Close() error {
+	c.transport.CloseIdleConnections()
+	return nil
+}
+
+// DeleteIndex removes the document and chunk indices (for testing/cleanup).
 func (c *Client) DeleteIndex(ctx context.Context) error {
-	res, err := c.es.Indices.Delete([]string{c.index}, c.es.Indices.Delete.WithContext(ctx))
+	res, err := c.es.Indices.Delete(
+		[]string{c.index, c.chunkIndex},
+		c.es.Indices.Delete.WithContext(untimed(ctx)),
+		c.es.Indices.Delete.WithIgnoreUnavailable(true),
+	)
 	if err != nil {
 		return err
 	}
@@ -120,7 +271,7 @@ func (c *Client) DeleteIndex(ctx context.Context) error {
 
 // IndexDocument indexes a single document.
 func (c *Client) IndexDocument(ctx context.Context, doc models.Document) error {
-	data, err := json.Marshal(doc)
+	data, err := json.Marshal(c.indexable(doc))
 	if err != nil {
 		return fmt.Errorf("failed to marshal document: %w", err)
 	}
@@ -143,11 +294,45 @@ func (c *Client) IndexDocument(ctx context.Context, doc models.Document) error {
 	return nil
 }
 
+// DeleteDocument removes a single document and its chunks by ID, from
+// whichever index holds them. Deleting a document that does not exist is
+// not an error.
+func (c *Client) DeleteDocument(ctx context.Context, id string) error {
+	if err := c.deleteChunks(ctx, c.chunkIndices, []string{id}); err != nil {
+		return err
+	}
+	located, err := c.locate(ctx, c.indices, []string{id})
+	if err != nil {
+		return err
+	}
+	index, ok := located[id]
+	if !ok {
+		return nil
+	}
+
+	res, err := c.es.Delete(
+		index,
+		id,
+		c.es.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("error deleting document (status %d): %s", res.StatusCode, res.String())
+	}
+
+	return nil
+}
+
 // Refresh forces an index refresh (useful for testing).
 func (c *Client) Refresh(ctx context.Context) error {
 	res, err := c.es.Indices.Refresh(
-		c.es.Indices.Refresh.WithContext(ctx),
-		c.es.Indices.Refresh.WithIndex(c.index),
+		c.es.Indices.Refresh.WithContext(untimed(ctx)),
+		c.es.Indices.Refresh.WithIndex(c.index, c.chunkIndex),
+		c.es.Indices.Refresh.WithIgnoreUnavailable(true),
 	)
 	if err != nil {
 		return err
@@ -156,6 +341,186 @@ func (c *Client) Refresh(ctx context.Context) error {
 	return nil
 }
 
+// refreshMetaKey is the key of an index mapping's _meta holding the
+// index's own refresh interval while ingestion runs have changed it; ""
+// stands for the default.
+const refreshMetaKey = "bam_rag_refresh_interval"
+
+// SetRefreshInterval sets the refresh interval of the document and chunk
+// indices, such as "-1" to stop periodic refreshes while documents are
+// bulk indexed. It returns a function restoring the intervals in effect
+// before, which should be followed by a Refresh. The intervals are kept
+// in the indices' _meta, so concurrent runs restore the same ones, and the
+// first run to finish restores them for all.
+func (c *Client) SetRefreshInterval(ctx context.Context, interval string) (restore func(context.Context) error, err error) {
+	indices := []string{c.index, c.chunkIndex}
+	for _, index := range indices {
+		if err := c.suspendRefresh(ctx, index, interval); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, index := range indices {
+			errs = append(errs, c.resumeRefresh(ctx, index))
+		}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// suspendRefresh sets the refresh interval of index, first recording its
+// own interval in its _meta unless a concurrent run already has.
+func (c *Client) suspendRefresh(ctx context.Context, index, interval string) error {
+	// The interval is read before the _meta: a run that changed it has
+	// recorded the one it replaced by then
+	current, err := c.refreshInterval(ctx, index)
+	if err != nil {
+		return err
+	}
+	meta, err := c.indexMeta(ctx, index)
+	if err != nil {
+		return err
+	}
+	if _, ok := meta[refreshMetaKey]; !ok {
+		meta[refreshMetaKey] = current
+		if err := c.putIndexMeta(ctx, index, meta); err != nil {
+			return err
+		}
+	}
+	return c.putRefreshInterval(ctx, index, &interval)
+}
+
+// resumeRefresh restores the refresh interval recorded in the _meta of
+// index and removes the record. Does nothing if another run restored it.
+func (c *Client) resumeRefresh(ctx context.Context, index string) error {
+	meta, err := c.indexMeta(ctx, index)
+	if err != nil {
+		return err
+	}
+	saved, ok := meta[refreshMetaKey].(string)
+	if !ok {
+		return nil
+	}
+
+	var interval *string
+	if saved != "" {
+		interval = &saved
+	}
+	if err := c.putRefreshInterval(ctx, index, interval); err != nil {
+		return err
+	}
+	delete(meta, refreshMetaKey)
+	return c.putIndexMeta(ctx, index, meta)
+}
+
+// refreshInterval returns the refresh interval set on index, or "" if it
+// uses the default.
+func (c *Client) refreshInterval(ctx context.Context, index string) (string, error) {
+	res, err := c.es.Indices.GetSettings(
+		c.es.Indices.GetSettings.WithContext(ctx),
+		c.es.Indices.GetSettings.WithIndex(index),
+		c.es.Indices.GetSettings.WithName("index.refresh_interval"),
+		c.es.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to get index settings: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return "", fmt.Errorf("error getting settings of %s: %s", index, res.String())
+	}
+
+	// Keyed by index name, which differs from index when it is an alias
+	var current map[string]struct {
+		Settings map[string]string `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&current); err != nil {
+		return "", fmt.Errorf("failed to decode index settings: %w", err)
+	}
+	for _, idx := range current {
+		return idx.Settings["index.refresh_interval"], nil
+	}
+	return "", nil
+}
+
+// indexMeta returns the _meta of the mapping of index, empty if it has none.
+func (c *Client) indexMeta(ctx context.Context, index string) (map[string]any, error) {
+	res, err := c.es.Indices.GetMapping(
+		c.es.Indices.GetMapping.WithContext(ctx),
+		c.es.Indices.GetMapping.WithIndex(index),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mapping: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting mapping of %s: %s", index, res.String())
+	}
+
+	// Keyed by index name, which differs from index when it is an alias
+	var mappings map[string]struct {
+		Mappings struct {
+			Meta map[string]any `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&mappings); err != nil {
+		return nil, fmt.Errorf("failed to decode mapping: %w", err)
+	}
+	for _, idx := range mappings {
+		if idx.Mappings.Meta != nil {
+			return idx.Mappings.Meta, nil
+		}
+	}
+	return make(map[string]any), nil
+}
+
+// putIndexMeta replaces the _meta of the mapping of index.
+func (c *Client) putIndexMeta(ctx context.Context, index string, meta map[string]any) error {
+	body, err := json.Marshal(map[string]any{"_meta": meta})
+	if err != nil {
+		return err
+	}
+
+	res, err := c.es.Indices.PutMapping(
+		[]string{index},
+		bytes.NewReader(body),
+		c.es.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update mapping: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error updating _meta of %s: %s", index, res.String())
+	}
+	return nil
+}
+
+// putRefreshInterval sets the refresh interval of index; nil resets it to
+// the default.
+func (c *Client) putRefreshInterval(ctx context.Context, index string, interval *string) error {
+	body, err := json.Marshal(map[string]any{"index": map[string]any{"refresh_interval": interval}})
+	if err != nil {
+		return err
+	}
+
+	res, err := c.es.Indices.PutSettings(
+		bytes.NewReader(body),
+		c.es.Indices.PutSettings.WithContext(ctx),
+		c.es.Indices.PutSettings.WithIndex(index),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set refresh interval: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error setting refresh interval of %s: %s", index, res.String())
+	}
+	return nil
+}
+
 // searchResponse represents ES search response structure.
 type searchResponse struct {
 	Hits struct {
@@ -165,16 +530,84 @@ type searchResponse struct {
 	} `json:"hits"`
 }
 
-// Search performs a BM25 text search on document content, title, tags, and summary.
+// outlineFields are the heading texts of each outline level, boosted so
+// queries naming a section rank the page that has it.
+var outlineFields = []string{"outline.text^2", "outline.children.text^2", "outline.children.children.text^2"}
+
+// localizedFields are the copies of content analyzed for its language.
+var localizedFields = []string{localizedField + ".*"}
+
+// Pages with fewer than shortPageWords words score shortPageWeight times
+// as much, so stubs and navigation pages rank below pages with content.
+// Documents indexed without a word count are left alone.
+const (
+	shortPageWords  = 50
+	shortPageWeight = 0.5
+)
+
+// textMatch returns a multi_match query for query on fields, tolerating
+// typos if fuzziness is configured. Results containing a quoted phrase or
+// code identifier of the query as written score higher. An empty query
+// matches everything, so a search made only of exclusions lists what
+// remains.
+func (c *Client) textMatch(query string, fields []string) map[string]interface{} {
+	if strings.TrimSpace(query) == "" {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+	match := map[string]interface{}{
+		"query":  query,
+		"fields": fields,
+	}
+	if c.fuzziness != "" {
+		match["fuzziness"] = c.fuzziness
+		match["prefix_length"] = c.prefixLen
+	}
+	multiMatch := map[string]interface{}{"multi_match": match}
+
+	exact := exactClauses(query)
+	if len(exact) == 0 {
+		return multiMatch
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":   multiMatch,
+			"should": exact,
+		},
+	}
+}
+
+// downweightShort wraps query to lower the score of short pages.
+func downweightShort(query map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"function_score": map[string]interface{}{
+			"query": query,
+			"functions": []map[string]interface{}{{
+				"filter": map[string]interface{}{
+					"range": map[string]interface{}{"word_count": map[string]interface{}{"lt": shortPageWords}},
+				},
+				"weight": shortPageWeight,
+			}},
+			"boost_mode": "multiply",
+		},
+	}
+}
+
+// Search performs a BM25 text search on document content, title, tags, summary, and headings.
 func (c *Client) Search(ctx context.Context, query string, limit int) ([]models.Document, error) {
+	return c.SearchFiltered(ctx, query, limit, Filter{})
+}
+
+// SearchFiltered performs a BM25 search restricted to documents matching filter.
+func (c *Client) SearchFiltered(ctx context.Context, query string, limit int, filter Filter) ([]models.Document, error) {
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
 	searchQuery := map[string]interface{}{
-		"query": map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query":  query,
-				"fields": []string{"content", "title", "tags^2", "summary"},
-			},
-		},
-		"size": limit,
+		"query": c.recency.apply(downweightShort(filter.apply(
+			c.textMatch(query, filter.fields(slices.Concat([]string{"content", "title", "tags^2", "summary"}, localizedFields, outlineFields), summaryFields)),
+		))),
+		"size": c.dedup.withFilter(filter).candidates(limit),
 	}
 
 	data, err := json.Marshal(searchQuery)
@@ -184,7 +617,8 @@ func (c *Client) Search(ctx context.Context, query string, limit int) ([]models.
 
 	res, err := c.es.Search(
 		c.es.Search.WithContext(ctx),
-		c.es.Search.WithIndex(c.index),
+		c.es.Search.WithIndex(c.indices...),
+		c.es.Search.WithIgnoreUnavailable(true),
 		c.es.Search.WithBody(bytes.NewReader(data)),
 	)
 	if err != nil {
@@ -206,49 +640,44 @@ func (c *Client) Search(ctx context.Context, query string, limit int) ([]models.
 		docs[i] = hit.Source
 	}
 
-	return docs, nil
-}
-
-// getResponse represents ES get response structure.
-type getResponse struct {
-	Found  bool            `json:"found"`
-	Source models.Document `json:"_source"`
+	return c.dedup.withFilter(filter).collapse(docs, limit), nil
 }
 
 // HybridSearch performs a combined BM25 + vector search.
 // If queryEmbedding is nil, falls back to BM25 only.
 func (c *Client) HybridSearch(ctx context.Context, query string, queryEmbedding []float32, limit int) ([]models.Document, error) {
+	return c.HybridSearchFiltered(ctx, query, queryEmbedding, limit, Filter{})
+}
+
+// HybridSearchFiltered performs a hybrid search restricted to documents matching filter.
+func (c *Client) HybridSearchFiltered(ctx context.Context, query string, queryEmbedding []float32, limit int, filter Filter) ([]models.Document, error) {
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
 	if queryEmbedding == nil {
-		return c.Search(ctx, query, limit)
+		return c.SearchFiltered(ctx, query, limit, filter)
 	}
 
-	// Use reciprocal rank fusion (RRF) to combine BM25 and vector results
+	dedup := c.dedup.withFilter(filter)
+	candidates := dedup.candidates(limit)
+	knn := map[string]interface{}{
+		"field":          "embedding",
+		"query_vector":   queryEmbedding,
+		"k":              candidates,
+		"num_candidates": candidates * 2,
+	}
+	filter.filterKNN(knn)
+
+	textQuery := c.recency.apply(downweightShort(filter.apply(
+		c.textMatch(query, filter.fields(slices.Concat([]string{"content", "title"}, localizedFields, outlineFields), summaryFields)),
+	)))
+
+	// Combine BM25 and vector results as configured, by default with
+	// reciprocal rank fusion (RRF)
 	searchQuery := map[string]interface{}{
-		"retriever": map[string]interface{}{
-			"rrf": map[string]interface{}{
-				"retrievers": []map[string]interface{}{
-					{
-						"standard": map[string]interface{}{
-							"query": map[string]interface{}{
-								"multi_match": map[string]interface{}{
-									"query":  query,
-									"fields": []string{"content", "title"},
-								},
-							},
-						},
-					},
-					{
-						"knn": map[string]interface{}{
-							"field":           "embedding",
-							"query_vector":    queryEmbedding,
-							"k":               limit,
-							"num_candidates":  limit * 2,
-						},
-					},
-				},
-			},
-		},
-		"size": limit,
+		"retriever": c.fusion.retriever(textQuery, knn, candidates),
+		"size":      candidates,
 	}
 
 	data, err := json.Marshal(searchQuery)
@@ -258,7 +687,8 @@ func (c *Client) HybridSearch(ctx context.Context, query string, queryEmbedding
 
 	res, err := c.es.Search(
 		c.es.Search.WithContext(ctx),
-		c.es.Search.WithIndex(c.index),
+		c.es.Search.WithIndex(c.indices...),
+		c.es.Search.WithIgnoreUnavailable(true),
 		c.es.Search.WithBody(bytes.NewReader(data)),
 	)
 	if err != nil {
@@ -280,37 +710,60 @@ func (c *Client) HybridSearch(ctx context.Context, query string, queryEmbedding
 		docs[i] = hit.Source
 	}
 
-	return docs, nil
+	return dedup.collapse(docs, limit), nil
 }
 
-// GetDocument retrieves a document by ID.
-func (c *Client) GetDocument(ctx context.Context, id string) (*models.Document, error) {
-	res, err := c.es.Get(
-		c.index,
-		id,
-		c.es.Get.WithContext(ctx),
-	)
+// VectorSearch performs a kNN search on document embeddings alone.
+func (c *Client) VectorSearch(ctx context.Context, queryEmbedding []float32, limit int) ([]models.Document, error) {
+	candidates := c.dedup.candidates(limit)
+	searchQuery := map[string]interface{}{
+		"knn": map[string]interface{}{
+			"field":          "embedding",
+			"query_vector":   queryEmbedding,
+			"k":              candidates,
+			"num_candidates": candidates * 2,
+		},
+		"size": candidates,
+	}
+
+	data, err := json.Marshal(searchQuery)
 	if err != nil {
-		return nil, fmt.Errorf("get failed: %w", err)
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode == 404 {
-		return nil, nil
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(c.indices...),
+		c.es.Search.WithIgnoreUnavailable(true),
+		c.es.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("vector search failed: %w", err)
 	}
+	defer res.Body.Close()
 
 	if res.IsError() {
-		return nil, fmt.Errorf("get error: %s", res.String())
+		return nil, fmt.Errorf("vector search error: %s", res.String())
 	}
 
-	var gr getResponse
-	if err := json.NewDecoder(res.Body).Decode(&gr); err != nil {
+	var sr searchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if !gr.Found {
-		return nil, nil
+	docs := make([]models.Document, len(sr.Hits.Hits))
+	for i, hit := range sr.Hits.Hits {
+		docs[i] = hit.Source
 	}
 
-	return &gr.Source, nil
+	return c.dedup.collapse(docs, limit), nil
+}
+
+// GetDocument retrieves a document by ID. Returns nil if it does not exist.
+func (c *Client) GetDocument(ctx context.Context, id string) (*models.Document, error) {
+	docs, err := c.GetDocuments(ctx, []string{id})
+	if err != nil || len(docs) == 0 {
+		return nil, err
+	}
+	return &docs[0], nil
 }
@@ -2,10 +2,18 @@ package elasticsearch
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
 	"github.com/mfenderov/bam-rag/pkg/models"
 )
 
@@ -46,6 +54,54 @@ func TestClient_Connect(t *testing.T) {
 	}
 }
 
+func TestNew_CloudID(t *testing.T) {
+	// The default addresses give way to the cloud ID
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		CloudID:   "docs:ZXUtY2VudHJhbC0xLmF3cy5jbG91ZC5lcy5pbyRhYmMkZGVm",
+		APIKey:    "a2V5OnNlY3JldA==",
+		Index:     "bam-rag-test",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := client.es.Transport.(*elastictransport.Client).URLs(); len(got) != 1 || got[0].Host != "abc.eu-central-1.aws.cloud.es.io" {
+		t.Errorf("URLs() = %v, want the cloud deployment", got)
+	}
+
+	if _, err := New(Config{CloudID: "not-a-cloud-id", Index: "bam-rag-test"}); err == nil {
+		t.Error("New() should reject an invalid cloud ID")
+	}
+}
+
+func TestClient_UntimedRequests(t *testing.T) {
+	// A server whose every response is slower than the timeout
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	client, err := New(Config{
+		Addresses: []string{server.URL},
+		Index:     "bam-rag-test",
+		Timeout:   50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.es.Info(client.es.Info.WithContext(ctx)); err == nil {
+		t.Error("Info() error = nil, want the response timeout")
+	}
+	if err := client.Refresh(ctx); err != nil {
+		t.Errorf("Refresh() error = %v, want no response timeout", err)
+	}
+}
+
 func TestClient_CreateIndex(t *testing.T) {
 	skipIfNoES(t)
 
@@ -78,6 +134,63 @@ func TestClient_CreateIndex(t *testing.T) {
 	client.DeleteIndex(ctx)
 }
 
+func TestClient_SetRefreshInterval(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-refresh",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	own := "30s"
+	if err := client.putRefreshInterval(ctx, client.index, &own); err != nil {
+		t.Fatalf("putRefreshInterval() error = %v", err)
+	}
+
+	// Two overlapping runs: the second finds the index already at -1
+	first, err := client.SetRefreshInterval(ctx, "-1")
+	if err != nil {
+		t.Fatalf("SetRefreshInterval() error = %v", err)
+	}
+	second, err := client.SetRefreshInterval(ctx, "-1")
+	if err != nil {
+		t.Fatalf("SetRefreshInterval() second call error = %v", err)
+	}
+	if got, _ := client.refreshInterval(ctx, client.index); got != "-1" {
+		t.Errorf("refresh interval during the runs = %q, want -1", got)
+	}
+
+	for _, restore := range []func(context.Context) error{first, second} {
+		if err := restore(ctx); err != nil {
+			t.Fatalf("restore() error = %v", err)
+		}
+		if got, _ := client.refreshInterval(ctx, client.index); got != own {
+			t.Errorf("refresh interval after restore = %q, want %q", got, own)
+		}
+		if got, _ := client.refreshInterval(ctx, client.chunkIndex); got != "" {
+			t.Errorf("chunk index refresh interval after restore = %q, want the default", got)
+		}
+	}
+
+	meta, err := client.indexMeta(ctx, client.index)
+	if err != nil {
+		t.Fatalf("indexMeta() error = %v", err)
+	}
+	if _, ok := meta[refreshMetaKey]; ok {
+		t.Errorf("_meta = %v, want the recorded interval removed", meta)
+	}
+}
+
 func TestClient_IndexAndSearch(t *testing.T) {
 	skipIfNoES(t)
 
@@ -222,3 +335,781 @@ func TestClient_GetDocument(t *testing.T) {
 	// Cleanup
 	client.DeleteIndex(ctx)
 }
+
+func TestClient_Chunks(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-chunks",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	// Setup
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	doc := models.Document{ID: "doc-chunks", URL: "https://example.com/guide", Title: "Guide"}
+	chunk := func(position int, heading, content string) models.Chunk {
+		return models.Chunk{
+			ID:          models.GenerateChunkID(doc.ID, position),
+			DocumentID:  doc.ID,
+			URL:         doc.URL,
+			Title:       doc.Title,
+			HeadingPath: []string{"Guide", heading},
+			Position:    position,
+			Content:     content,
+		}
+	}
+
+	if err := client.IndexDocument(ctx, doc); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	chunks := []models.Chunk{
+		chunk(0, "Install", "Run the installer."),
+		chunk(1, "Configure", "Edit the config file."),
+		chunk(2, "Upgrade", "Replace the binary."),
+	}
+	if err := client.IndexChunks(ctx, doc.ID, chunks); err != nil {
+		t.Fatalf("IndexChunks() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	results, err := client.SearchChunks(ctx, "installer", nil, 10, Filter{})
+	if err != nil {
+		t.Fatalf("SearchChunks() error = %v", err)
+	}
+	if len(results) != 1 || results[0].ID != chunks[0].ID || results[0].DocumentID != doc.ID {
+		t.Errorf("SearchChunks() = %+v, want the install chunk", results)
+	}
+
+	sections, err := client.SearchSections(ctx, doc.ID, "config file", 10, Filter{})
+	if err != nil {
+		t.Fatalf("SearchSections() error = %v", err)
+	}
+	if len(sections) != 1 || sections[0].ID != chunks[1].ID {
+		t.Errorf("SearchSections() = %+v, want the configure chunk", sections)
+	}
+	if sections, _ := client.SearchSections(ctx, "other-doc", "config file", 10, Filter{}); len(sections) != 0 {
+		t.Errorf("SearchSections() of another document = %+v, want none", sections)
+	}
+
+	// Re-indexing a shorter document drops the chunks past its end
+	if err := client.IndexChunks(ctx, doc.ID, chunks[:1]); err != nil {
+		t.Fatalf("IndexChunks() error = %v", err)
+	}
+	client.Refresh(ctx)
+	if got, _ := client.GetChunk(ctx, chunks[2].ID); got != nil {
+		t.Errorf("GetChunk() = %+v, want stale chunk removed", got)
+	}
+	if got, _ := client.GetChunk(ctx, chunks[0].ID); got == nil || got.Content != chunks[0].Content {
+		t.Errorf("GetChunk() = %+v, want the first chunk", got)
+	}
+
+	// Deleting the document deletes its chunks
+	if err := client.DeleteDocument(ctx, doc.ID); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if got, _ := client.GetChunk(ctx, chunks[0].ID); got != nil {
+		t.Errorf("GetChunk() = %+v after DeleteDocument, want nil", got)
+	}
+}
+
+func TestClient_StatsTagsMigration(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-stats-tags",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	// An index created before tags had a keyword sub-field
+	client.DeleteIndex(ctx)
+	if err := client.createIndex(ctx, client.index, []byte(`{"mappings":{"properties":{"url":{"type":"keyword"},"tags":{"type":"text","analyzer":"english"}}}}`)); err != nil {
+		t.Fatalf("createIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	doc := models.Document{ID: "a", URL: "https://example.com/a", Content: "A", Tags: []string{"install"}}
+	if err := client.IndexDocument(ctx, doc); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	stats, err := client.Stats(ctx, 10)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if len(stats.TopTags) != 1 || stats.TopTags[0].Term != "install" {
+		t.Errorf("TopTags = %+v, want install", stats.TopTags)
+	}
+}
+
+func TestClient_Stats(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-stats",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+
+	docs := []models.Document{
+		{ID: "a", URL: "https://example.com/a", Content: "A", Tags: []string{"install", "setup"}, ScrapedAt: time.Now(), WordCount: 120, TokenCount: 160},
+		{ID: "b", URL: "https://example.com/b", Content: "B", Tags: []string{"install"}, ScrapedAt: time.Now(), WordCount: 30, TokenCount: 40},
+		{ID: "c", URL: "https://other.org/c", Content: "C", ScrapedAt: time.Now()},
+	}
+	for _, doc := range docs {
+		if err := client.IndexDocument(ctx, doc); err != nil {
+			t.Fatalf("IndexDocument() error = %v", err)
+		}
+	}
+	client.Refresh(ctx)
+
+	stats, err := client.Stats(ctx, 10)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	if stats.DocCount != 3 {
+		t.Errorf("DocCount = %d, want 3", stats.DocCount)
+	}
+	if len(stats.Sources) != 2 || stats.Sources[0].Host != "example.com" || stats.Sources[0].Docs != 2 {
+		t.Errorf("Sources = %+v, want example.com with 2 docs first", stats.Sources)
+	}
+	if len(stats.TopTags) == 0 || stats.TopTags[0].Term != "install" || stats.TopTags[0].Count != 2 {
+		t.Errorf("TopTags = %+v, want install (2) first", stats.TopTags)
+	}
+	if stats.Words != 150 || stats.Tokens != 200 {
+		t.Errorf("Words, Tokens = %d, %d, want 150, 200", stats.Words, stats.Tokens)
+	}
+
+	client.DeleteIndex(ctx)
+}
+
+func TestClient_BulkIndexAndScroll(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-bulk",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+
+	var docs []models.Document
+	for i := range 25 {
+		url := fmt.Sprintf("https://example.com/page%d", i)
+		docs = append(docs, models.Document{ID: models.GenerateDocumentID(url), URL: url, Content: "content"})
+	}
+
+	indexed, err := client.BulkIndex(ctx, docs)
+	if err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	if indexed != len(docs) {
+		t.Errorf("BulkIndex() indexed %d, want %d", indexed, len(docs))
+	}
+	client.Refresh(ctx)
+
+	seen := make(map[string]bool)
+	err = client.ScrollDocuments(ctx, 10, func(doc models.Document) error {
+		seen[doc.ID] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScrollDocuments() error = %v", err)
+	}
+	if len(seen) != len(docs) {
+		t.Errorf("ScrollDocuments() returned %d docs, want %d", len(seen), len(docs))
+	}
+
+	client.DeleteIndex(ctx)
+}
+
+func TestClient_UpdateEmbeddings(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-embed",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	embedding := make([]float32, 2560)
+	embedding[0] = 1
+
+	docs := []models.Document{
+		{ID: "with", URL: "https://example.com/with", Content: "content", Embedding: embedding},
+		{ID: "without", URL: "https://example.com/without", Content: "content"},
+	}
+	if _, err := client.BulkIndex(ctx, docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	var missing []string
+	collect := func(doc models.Document) error {
+		missing = append(missing, doc.ID)
+		return nil
+	}
+	if err := client.ScrollDocumentsWithoutEmbedding(ctx, 10, collect, "id"); err != nil {
+		t.Fatalf("ScrollDocumentsWithoutEmbedding() error = %v", err)
+	}
+	if len(missing) != 1 || missing[0] != "without" {
+		t.Fatalf("ScrollDocumentsWithoutEmbedding() = %v, want [without]", missing)
+	}
+
+	updated, err := client.UpdateEmbeddings(ctx, map[string][]float32{"without": embedding})
+	if err != nil {
+		t.Fatalf("UpdateEmbeddings() error = %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("UpdateEmbeddings() updated %d, want 1", updated)
+	}
+	client.Refresh(ctx)
+
+	missing = nil
+	if err := client.ScrollDocumentsWithoutEmbedding(ctx, 10, collect, "id"); err != nil {
+		t.Fatalf("ScrollDocumentsWithoutEmbedding() error = %v", err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("documents still missing embeddings after update: %v", missing)
+	}
+}
+
+func TestClient_SourceIndices(t *testing.T) {
+	skipIfNoES(t)
+
+	ctx := context.Background()
+	newClient := func(index string, sourceIndices ...string) *Client {
+		client, err := New(Config{
+			Addresses:     []string{"http://localhost:9200"},
+			Index:         index,
+			SourceIndices: sourceIndices,
+		})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		client.DeleteIndex(ctx)
+		if err := client.CreateIndex(ctx); err != nil {
+			t.Fatalf("CreateIndex() error = %v", err)
+		}
+		t.Cleanup(func() { client.DeleteIndex(context.Background()) })
+		return client
+	}
+
+	source := newClient("bam-rag-test-source-index")
+	global := newClient("bam-rag-test-global-index", "bam-rag-test-source-index", "bam-rag-test-missing-index")
+
+	docs := []models.Document{{ID: "runbook", URL: "https://example.com/runbook", Content: "restart the broker"}}
+	if _, err := source.BulkIndex(ctx, docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	source.Refresh(ctx)
+
+	// The global client reads documents written to the source index
+	results, err := global.SearchFiltered(ctx, "broker", 10, Filter{})
+	if err != nil {
+		t.Fatalf("SearchFiltered() error = %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "runbook" {
+		t.Errorf("SearchFiltered() = %v, want runbook", results)
+	}
+	if doc, err := global.GetDocument(ctx, "runbook"); err != nil || doc == nil {
+		t.Errorf("GetDocument() = %v, %v, want runbook", doc, err)
+	}
+
+	deleted, err := global.DeleteDocuments(ctx, []string{"runbook"})
+	if err != nil {
+		t.Fatalf("DeleteDocuments() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteDocuments() = %d, want 1", deleted)
+	}
+	source.Refresh(ctx)
+	count, err := global.Count(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Count() after delete = %d, want 0", count)
+	}
+
+	chunk := func(documentID string) []models.Chunk {
+		return []models.Chunk{{ID: models.GenerateChunkID(documentID, 0), DocumentID: documentID, Content: "restart the broker"}}
+	}
+
+	// Deleting a document removes its chunks from the source chunk index
+	guide := models.Document{ID: "guide", URL: "https://example.com/guide"}
+	if err := source.IndexDocument(ctx, guide); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	if err := source.IndexChunks(ctx, guide.ID, chunk(guide.ID)); err != nil {
+		t.Fatalf("IndexChunks() error = %v", err)
+	}
+	source.Refresh(ctx)
+	if err := global.DeleteDocument(ctx, guide.ID); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if doc, _ := source.GetDocument(ctx, guide.ID); doc != nil {
+		t.Errorf("GetDocument() after delete = %+v, want none", doc)
+	}
+	if got, _ := source.GetChunk(ctx, chunk(guide.ID)[0].ID); got != nil {
+		t.Errorf("GetChunk() after delete = %+v, want none", got)
+	}
+
+	// Indexing chunks elsewhere removes those left in the source chunk index
+	if err := source.IndexChunks(ctx, "faq", chunk("faq")); err != nil {
+		t.Fatalf("IndexChunks() error = %v", err)
+	}
+	source.Refresh(ctx)
+	if err := global.IndexChunks(ctx, "faq", chunk("faq")); err != nil {
+		t.Fatalf("IndexChunks() error = %v", err)
+	}
+	if got, _ := source.GetChunk(ctx, chunk("faq")[0].ID); got != nil {
+		t.Errorf("GetChunk() of the source index = %+v, want stale chunk removed", got)
+	}
+	if got, _ := global.GetChunk(ctx, chunk("faq")[0].ID); got == nil {
+		t.Error("GetChunk() = nil, want the re-indexed chunk")
+	}
+}
+
+func TestClient_Filter(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-filter",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	var docs []models.Document
+	for _, url := range []string{
+		"https://kubernetes.io/docs/pods",
+		"https://kubernetes.io/docs/services",
+		"https://helm.sh/docs/charts",
+		"https://go.dev/doc/install",
+	} {
+		docs = append(docs, models.Document{ID: models.GenerateDocumentID(url), URL: url, Title: "docs", Content: "docs content"})
+	}
+	if _, err := client.BulkIndex(ctx, docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	group := Filter{URLPrefixes: []string{"https://kubernetes.io/", "https://helm.sh/"}}
+
+	count, err := client.Count(ctx, group)
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Count() = %d, want 3", count)
+	}
+
+	results, err := client.SearchFiltered(ctx, "docs", 10, group)
+	if err != nil {
+		t.Fatalf("SearchFiltered() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("SearchFiltered() returned %d results, want 3", len(results))
+	}
+
+	if _, err := client.DeleteByFilter(ctx, Filter{}); err == nil {
+		t.Error("DeleteByFilter() with empty filter should fail")
+	}
+
+	deleted, err := client.DeleteByFilter(ctx, group)
+	if err != nil {
+		t.Fatalf("DeleteByFilter() error = %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("DeleteByFilter() deleted %d, want 3", deleted)
+	}
+
+	remaining, err := client.Count(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if remaining != 1 {
+		t.Errorf("Count() after delete = %d, want 1", remaining)
+	}
+}
+
+func TestClient_FilterSources(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-filter-sources",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	var docs []models.Document
+	for _, d := range []struct{ url, source string }{
+		{"https://go.dev/doc/install", "go-docs"},
+		{"https://go.dev/blog/loops", "go-blog"},
+		{"https://go.dev/doc/legacy", ""}, // Indexed before sources were recorded
+	} {
+		docs = append(docs, models.Document{
+			ID:      models.GenerateDocumentID(d.url),
+			URL:     d.url,
+			Title:   "docs",
+			Content: "docs content",
+			Source:  models.Source{Name: d.source, Host: "go.dev"},
+		})
+	}
+	if _, err := client.BulkIndex(ctx, docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"by name", Filter{Sources: []string{"go-docs"}}, 1},
+		{"by name with URL fallback", Filter{Sources: []string{"go-docs"}, URLPrefixes: []string{"https://go.dev/"}}, 2},
+		{"by URL", Filter{URLPrefixes: []string{"https://go.dev/"}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := client.Count(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Count() = %d, want %d", count, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_SearchLanguage(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-language",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	docs := []models.Document{
+		{ID: "de", URL: "https://example.com/de", Title: "Anleitung", Content: "Die Installation der Pakete dauert lange.", Language: "de"},
+		{ID: "en", URL: "https://example.com/en", Title: "Guide", Content: "Installing the packages takes a while.", Language: "en"},
+	}
+	if _, err := client.BulkIndex(ctx, docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	// The german analyzer stems "Installationen" to match "Installation"
+	results, err := client.SearchFiltered(ctx, "Installationen", 10, Filter{Languages: []string{"de"}})
+	if err != nil {
+		t.Fatalf("SearchFiltered() error = %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "de" {
+		t.Errorf("SearchFiltered() = %+v, want the German document", results)
+	}
+
+	count, err := client.Count(ctx, Filter{Languages: []string{"en"}})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Count() = %d, want 1", count)
+	}
+}
+
+func TestClient_FilterAccess(t *testing.T) {
+	skipIfNoES(t)
+
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     "bam-rag-test-filter-access",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	client.DeleteIndex(ctx)
+	if err := client.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	defer client.DeleteIndex(ctx)
+
+	docs := []models.Document{
+		{ID: "public", URL: "https://example.com/public", Content: "runbook"},
+		{ID: "internal", URL: "https://example.com/internal", Content: "runbook", AccessLabels: []string{"internal"}},
+		{ID: "secret", URL: "https://example.com/secret", Content: "runbook", AccessLabels: []string{"secret", "ops"}},
+	}
+	if _, err := client.BulkIndex(ctx, docs); err != nil {
+		t.Fatalf("BulkIndex() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"unrestricted", Filter{}, 3},
+		{"no labels", Filter{EnforceAccess: true}, 1},
+		{"one label", Filter{EnforceAccess: true, AccessLabels: []string{"internal"}}, 2},
+		{"any matching label", Filter{EnforceAccess: true, AccessLabels: []string{"ops", "internal"}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := client.Count(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Count() = %d, want %d", count, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		labels []string
+		want   bool
+	}{
+		{"public document", Filter{EnforceAccess: true}, nil, true},
+		{"not enforced", Filter{}, []string{"internal"}, true},
+		{"missing label", Filter{EnforceAccess: true, AccessLabels: []string{"ops"}}, []string{"internal"}, false},
+		{"matching label", Filter{EnforceAccess: true, AccessLabels: []string{"ops"}}, []string{"internal", "ops"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Allows(tt.labels); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Log(t *testing.T) {
+	skipIfNoES(t)
+
+	const index = "bam-rag-test-log"
+	client, err := New(Config{
+		Addresses: []string{"http://localhost:9200"},
+		Index:     index,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+	client.DeleteIndex(ctx)
+	defer client.DeleteIndex(ctx)
+
+	properties := map[string]interface{}{
+		"time": map[string]interface{}{"type": "date"},
+		"kind": map[string]interface{}{"type": "keyword"},
+	}
+	if err := client.CreateLogIndex(ctx, index, properties); err != nil {
+		t.Fatalf("CreateLogIndex() error = %v", err)
+	}
+	if err := client.CreateLogIndex(ctx, index, properties); err != nil {
+		t.Fatalf("CreateLogIndex() second call error = %v", err)
+	}
+
+	now := time.Now().UTC()
+	err = client.AppendLog(ctx, index, []any{
+		map[string]interface{}{"time": now.Add(-time.Minute), "kind": "a"},
+		map[string]interface{}{"time": now, "kind": "b"},
+		map[string]interface{}{"time": now.Add(-2 * time.Minute), "kind": "b"},
+	})
+	if err != nil {
+		t.Fatalf("AppendLog() error = %v", err)
+	}
+	client.Refresh(ctx)
+
+	entries, err := client.SearchLog(ctx, index, []map[string]interface{}{
+		{"term": map[string]interface{}{"kind": "b"}},
+	}, 10)
+	if err != nil {
+		t.Fatalf("SearchLog() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("SearchLog() returned %d entries, want 2", len(entries))
+	}
+	if want := fmt.Sprintf(`"time":"%s"`, now.Format(time.RFC3339Nano)); !strings.Contains(string(entries[0]), want) {
+		t.Errorf("SearchLog()[0] = %s, want newest entry first", entries[0])
+	}
+
+	aggs, err := client.AggregateLog(ctx, index, nil, map[string]interface{}{
+		"kinds": map[string]interface{}{"terms": map[string]interface{}{"field": "kind"}},
+	})
+	if err != nil {
+		t.Fatalf("AggregateLog() error = %v", err)
+	}
+	if !strings.Contains(string(aggs["kinds"]), `{"key":"b","doc_count":2}`) {
+		t.Errorf("AggregateLog() kinds = %s, want b counted twice", aggs["kinds"])
+	}
+}
+
+func TestClient_TextMatch(t *testing.T) {
+	exact := &Client{}
+	match := exact.textMatch("kubernetes", []string{"content"})["multi_match"].(map[string]interface{})
+	if _, ok := match["fuzziness"]; ok {
+		t.Errorf("textMatch() without fuzziness = %v, want exact matching", match)
+	}
+
+	fuzzy := &Client{fuzziness: "AUTO", prefixLen: 1}
+	match = fuzzy.textMatch("kuberntes", []string{"content"})["multi_match"].(map[string]interface{})
+	if match["fuzziness"] != "AUTO" || match["prefix_length"] != 1 || match["query"] != "kuberntes" {
+		t.Errorf("textMatch() = %v, want fuzzy matching with prefix length 1", match)
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	for in, want := range map[string]Scope{"": ScopeFull, "full": ScopeFull, "summary": ScopeSummary} {
+		if got, err := ParseScope(in); err != nil || got != want {
+			t.Errorf("ParseScope(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseScope("title"); err == nil {
+		t.Error("ParseScope(title) should fail")
+	}
+
+	summary := Filter{Scope: ScopeSummary}
+	if got := summary.fields([]string{"content"}, summaryFields); !slices.Equal(got, summaryFields) {
+		t.Errorf("fields() = %v, want %v", got, summaryFields)
+	}
+}
+
+func TestFilter_Exclude(t *testing.T) {
+	match := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if got := (Filter{}).apply(match); !reflect.DeepEqual(got, match) {
+		t.Errorf("apply() = %v, want the query unchanged", got)
+	}
+
+	filter := Filter{Exclude: []string{"nginx"}}
+	if !filter.IsZero() {
+		t.Error("IsZero() = false; exclusions should not restrict deletes")
+	}
+	data, _ := json.Marshal(filter.apply(match))
+	if !strings.Contains(string(data), `"must_not":[{"multi_match":{"fields"`) || strings.Contains(string(data), `"filter"`) {
+		t.Errorf("apply() = %s, want only a must_not clause", data)
+	}
+
+	knn := map[string]interface{}{}
+	filter.filterKNN(knn)
+	if _, ok := knn["filter"]; !ok {
+		t.Error("filterKNN() did not filter out excluded terms")
+	}
+}
+
+func TestExactTerms(t *testing.T) {
+	phrases, identifiers := exactTerms(`use "rolling update" with http.HandlerFunc, max_idle_conns and getUser() e.g. Kubernetes.`)
+	if !slices.Equal(phrases, []string{"rolling update"}) {
+		t.Errorf("phrases = %v, want [rolling update]", phrases)
+	}
+	if want := []string{"http.HandlerFunc", "max_idle_conns", "getUser"}; !slices.Equal(identifiers, want) {
+		t.Errorf("identifiers = %v, want %v", identifiers, want)
+	}
+
+	client := &Client{}
+	if _, ok := client.textMatch("install guide", []string{"content"})["multi_match"]; !ok {
+		t.Error("textMatch() of prose should be a plain multi_match")
+	}
+	boosted := client.textMatch("os.Getenv", []string{"content"})["bool"].(map[string]interface{})
+	if should := boosted["should"].([]map[string]interface{}); len(should) != 1 {
+		t.Errorf("textMatch() should clauses = %v, want one for the identifier", should)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	got := correct("kubernets deploymnt guide", []correction{{0, 9, "kubernetes"}, {10, 9, "deployment"}})
+	if got != "kubernetes deployment guide" {
+		t.Errorf("correct() = %q", got)
+	}
+	if got := correct("ok", nil); got != "" {
+		t.Errorf("correct() without corrections = %q, want empty", got)
+	}
+	if got := correct("short", []correction{{3, 10, "x"}}); got != "" {
+		t.Errorf("correct() out of range = %q, want empty", got)
+	}
+}
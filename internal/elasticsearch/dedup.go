@@ -0,0 +1,198 @@
+package elasticsearch
+
+import (
+	"hash/fnv"
+	"net/url"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Dedup collapses search results that are copies of a better-ranked
+// result, such as a page scraped under two URLs or the same docs page
+// published for several versions, and limits how many results come from
+// one source. The zero value keeps every result.
+type Dedup struct {
+	// URLs collapses results whose URLs differ only in scheme, a leading
+	// "www.", a trailing slash or index.html, or the fragment
+	URLs bool
+	// Similarity collapses results whose content overlaps a better-ranked
+	// result by at least this share of their word shingles, from 0
+	// (disabled) to 1 (identical content only)
+	Similarity float64
+	// PerSource keeps at most this many results from each source host, so
+	// one exhaustively scraped site does not take every result slot; 0
+	// for no limit. Searches may then return fewer results than requested.
+	PerSource int
+}
+
+// Results fetched per result requested when collapsing, so enough remain
+// after copies are dropped.
+const dedupOverfetch = 3
+
+// Shingles compare runs of shingleWords words, over at most
+// shingleMaxWords words of a page.
+const (
+	shingleWords    = 4
+	shingleMaxWords = 2000
+)
+
+// enabled reports whether d collapses anything.
+func (d Dedup) enabled() bool {
+	return d.URLs || d.Similarity > 0 || d.PerSource > 0
+}
+
+// withFilter returns d with the per-source limit of a search's filter, if
+// it sets one.
+func (d Dedup) withFilter(f Filter) Dedup {
+	if f.PerSource > 0 {
+		d.PerSource = f.PerSource
+	}
+	return d
+}
+
+// candidates returns how many results to fetch for limit.
+func (d Dedup) candidates(limit int) int {
+	if !d.enabled() {
+		return limit
+	}
+	return limit * dedupOverfetch
+}
+
+// collapse returns up to limit of docs, in order, without the copies of
+// earlier documents.
+func (d Dedup) collapse(docs []models.Document, limit int) []models.Document {
+	if !d.enabled() {
+		return docs
+	}
+
+	var kept []models.Document
+	urls := make(map[string]bool)
+	var shingles []map[uint64]bool
+	sources := make(map[string]int)
+	for _, doc := range docs {
+		if len(kept) == limit {
+			break
+		}
+		source := sourceHost(doc.Source, doc.URL)
+		if d.PerSource > 0 && sources[source] >= d.PerSource {
+			continue
+		}
+		key := canonicalURL(doc.URL)
+		if d.URLs && urls[key] {
+			continue
+		}
+		var set map[uint64]bool
+		if d.Similarity > 0 {
+			set = shingleSet(doc.Content)
+			if duplicatesAny(set, shingles, d.Similarity) {
+				continue
+			}
+		}
+		kept = append(kept, doc)
+		urls[key] = true
+		shingles = append(shingles, set)
+		sources[source]++
+	}
+	return kept
+}
+
+// limitChunks returns up to limit of chunks, in order, keeping at most
+// PerSource from each source host. Chunks of one page are not copies of
+// each other, so nothing else is collapsed.
+func (d Dedup) limitChunks(chunks []models.Chunk, limit int) []models.Chunk {
+	if d.PerSource <= 0 {
+		return chunks
+	}
+	var kept []models.Chunk
+	sources := make(map[string]int)
+	for _, chunk := range chunks {
+		if len(kept) == limit {
+			break
+		}
+		source := sourceHost(chunk.Source, chunk.URL)
+		if sources[source] < d.PerSource {
+			kept = append(kept, chunk)
+			sources[source]++
+		}
+	}
+	return kept
+}
+
+// sourceHost returns the host a result was scraped from: that of its
+// source, or of its URL for results indexed without one.
+func sourceHost(source models.Source, rawURL string) string {
+	if source.Host != "" {
+		return source.Host
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		return strings.ToLower(u.Host)
+	}
+	return rawURL
+}
+
+// canonicalURL returns rawURL reduced to what identifies a page: host
+// without "www.", path without a trailing slash or index.html, and query.
+func canonicalURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	path := strings.TrimSuffix(u.Path, "index.html")
+	path = strings.TrimRight(path, "/")
+	key := host + path
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// shingleSet returns the hashes of the overlapping runs of words in
+// content, ignoring case and punctuation.
+func shingleSet(content string) map[uint64]bool {
+	words := strings.FieldsFunc(strings.ToLower(content), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r > 0x7f)
+	})
+	words = words[:min(len(words), shingleMaxWords)]
+
+	// Pages shorter than a shingle are one shingle
+	set := make(map[uint64]bool)
+	for i := range max(len(words)-shingleWords+1, min(len(words), 1)) {
+		h := fnv.New64a()
+		for _, w := range words[i:min(i+shingleWords, len(words))] {
+			h.Write([]byte(w))
+			h.Write([]byte{0})
+		}
+		set[h.Sum64()] = true
+	}
+	return set
+}
+
+// duplicatesAny reports whether set has a Jaccard similarity of at least
+// threshold with any of others. Empty sets duplicate nothing.
+func duplicatesAny(set map[uint64]bool, others []map[uint64]bool, threshold float64) bool {
+	if len(set) == 0 {
+		return false
+	}
+	for _, other := range others {
+		if len(other) == 0 {
+			continue
+		}
+		// Sets too different in size cannot reach the threshold
+		small, large := min(len(set), len(other)), max(len(set), len(other))
+		if float64(small) < threshold*float64(large) {
+			continue
+		}
+		shared := 0
+		for h := range set {
+			if other[h] {
+				shared++
+			}
+		}
+		if float64(shared) >= threshold*float64(len(set)+len(other)-shared) {
+			return true
+		}
+	}
+	return false
+}
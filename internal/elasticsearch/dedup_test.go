@@ -0,0 +1,82 @@
+package elasticsearch
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestDedup_Collapse(t *testing.T) {
+	var words []string
+	for i := range 200 {
+		words = append(words, "word"+strconv.Itoa(i))
+	}
+	body := strings.Join(words, " ")
+	docs := []models.Document{
+		{ID: "a", URL: "https://docs.example.com/v2/client/", Content: body + " since v2"},
+		{ID: "b", URL: "http://www.docs.example.com/v2/client#usage", Content: "moved"},
+		{ID: "c", URL: "https://docs.example.com/v1/client", Content: body + " since v1"},
+		{ID: "d", URL: "https://docs.example.com/v2/server", Content: "servers listen on a port and accept connections"},
+		{ID: "e", URL: "https://docs.example.com/v2/other", Content: "other"},
+	}
+
+	tests := []struct {
+		name  string
+		dedup Dedup
+		limit int
+		want  string
+	}{
+		{"disabled", Dedup{}, 5, "abcde"},
+		{"urls", Dedup{URLs: true}, 5, "acde"},
+		{"content", Dedup{Similarity: 0.9}, 5, "abde"},
+		{"both", Dedup{URLs: true, Similarity: 0.9}, 5, "ade"},
+		{"limit", Dedup{URLs: true, Similarity: 0.9}, 2, "ad"},
+		{"per source", Dedup{PerSource: 2}, 5, "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			for _, doc := range tt.dedup.collapse(docs, tt.limit) {
+				got.WriteString(doc.ID)
+			}
+			if got.String() != tt.want {
+				t.Errorf("collapse() = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	same := []string{
+		"https://go.dev/doc",
+		"http://www.go.dev/doc/",
+		"https://GO.dev/doc/index.html",
+		"https://go.dev/doc#install",
+	}
+	for _, u := range same {
+		if got := canonicalURL(u); got != "go.dev/doc" {
+			t.Errorf("canonicalURL(%q) = %q, want go.dev/doc", u, got)
+		}
+	}
+	if canonicalURL("https://go.dev/doc?page=2") == canonicalURL("https://go.dev/doc") {
+		t.Error("canonicalURL() dropped the query")
+	}
+}
+
+func TestDedup_LimitChunks(t *testing.T) {
+	chunks := []models.Chunk{
+		{ID: "a1", URL: "https://big.example.com/a", Source: models.Source{Host: "big.example.com"}},
+		{ID: "a2", URL: "https://big.example.com/b", Source: models.Source{Host: "big.example.com"}},
+		{ID: "b1", URL: "https://small.example.com/a"},
+		{ID: "a3", URL: "https://big.example.com/c"},
+	}
+	var got []string
+	for _, chunk := range (Dedup{PerSource: 1}).limitChunks(chunks, 10) {
+		got = append(got, chunk.ID)
+	}
+	if strings.Join(got, ",") != "a1,b1" {
+		t.Errorf("limitChunks() = %v, want one chunk per host", got)
+	}
+}
@@ -0,0 +1,434 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Filter restricts which documents a search or delete applies to.
+// The zero value matches every document.
+type Filter struct {
+	URLPrefixes []string  // Match documents whose URL starts with any of these
+	Sources     []string  // Match documents of any of these config sources
+	Languages   []string  // Match documents in any of these languages (ISO 639-1 codes)
+	Origins     []string  // Match documents whose source name or host is any of these
+	After       time.Time // Match documents scraped at or after this time
+	Before      time.Time // Match documents scraped before this time
+
+	// EnforceAccess limits matches to documents without access labels and
+	// those sharing one of AccessLabels, the labels granted to the caller.
+	EnforceAccess bool
+	AccessLabels  []string
+	// accessField is the keyword field access is enforced on, set by the
+	// client from the index mapping; empty is access_labels
+	accessField string
+
+	// Scope is the part of documents a search matches the query against;
+	// it does not restrict deletes and counts
+	Scope Scope
+	// Exclude drops search results containing any of these terms or
+	// phrases, and Titles keeps those whose title contains all the words
+	// of each entry; they do not restrict deletes and counts
+	Exclude []string
+	Titles  []string
+
+	// PerSource keeps at most this many search results from each source
+	// host, overriding the client's Dedup.PerSource; 0 keeps the client's
+	PerSource int
+}
+
+// Scope is the part of documents a search matches the query against.
+type Scope string
+
+const (
+	// ScopeFull matches the content, title, tags, summary, and headings:
+	// high recall. It is the default.
+	ScopeFull Scope = "full"
+	// ScopeSummary matches the title, tags, and summary only: faster and
+	// more precise, but pages are found only by what they are about.
+	ScopeSummary Scope = "summary"
+)
+
+// ParseScope returns the scope named s; "" is ScopeFull.
+func ParseScope(s string) (Scope, error) {
+	switch Scope(s) {
+	case "", ScopeFull:
+		return ScopeFull, nil
+	case ScopeSummary:
+		return ScopeSummary, nil
+	}
+	return "", fmt.Errorf("unknown search scope %q (expected full or summary)", s)
+}
+
+// summaryFields are the document fields matched with ScopeSummary.
+var summaryFields = []string{"title", "tags^2", "summary"}
+
+// fields returns the fields a search in the filter's scope matches: full
+// for ScopeFull, and summary otherwise.
+func (f Filter) fields(full, summary []string) []string {
+	if f.Scope == ScopeSummary {
+		return summary
+	}
+	return full
+}
+
+// excludeFields are the fields searched for excluded terms, in documents
+// and chunks.
+var excludeFields = []string{"title", "content", "tags", "summary", "heading_path"}
+
+// IsZero reports whether the filter matches every document. Exclude is not
+// considered, since it only applies to searches.
+func (f Filter) IsZero() bool {
+	return len(f.URLPrefixes) == 0 && len(f.Sources) == 0 && len(f.Languages) == 0 && len(f.Origins) == 0 &&
+		f.After.IsZero() && f.Before.IsZero() && !f.EnforceAccess
+}
+
+// Allows reports whether a document with the given access labels is
+// visible under the filter's access restriction.
+func (f Filter) Allows(labels []string) bool {
+	if !f.EnforceAccess || len(labels) == 0 {
+		return true
+	}
+	for _, label := range labels {
+		if slices.Contains(f.AccessLabels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether an entry with the given URL, source, language,
+// scrape time, and access labels passes the filter, as its clauses would
+// in a query. It lets backends that search in memory apply filters the
+// same way; Scope, Exclude, Titles, and PerSource are left to them.
+func (f Filter) Match(url string, source models.Source, language string, scrapedAt time.Time, labels []string) bool {
+	urlMatch := len(f.URLPrefixes) == 0 || slices.ContainsFunc(f.URLPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(url, prefix)
+	})
+	switch {
+	case len(f.Sources) > 0 && len(f.URLPrefixes) > 0:
+		if !slices.Contains(f.Sources, source.Name) && (source.Name != "" || !urlMatch) {
+			return false
+		}
+	case len(f.Sources) > 0:
+		if !slices.Contains(f.Sources, source.Name) {
+			return false
+		}
+	case !urlMatch:
+		return false
+	}
+
+	if len(f.Languages) > 0 && !slices.Contains(f.Languages, language) {
+		return false
+	}
+	if len(f.Origins) > 0 && !slices.Contains(f.Origins, source.Name) && !slices.Contains(f.Origins, source.Host) {
+		return false
+	}
+	if !f.After.IsZero() && scrapedAt.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !scrapedAt.Before(f.Before) {
+		return false
+	}
+	return f.Allows(labels)
+}
+
+// clauses returns the filter as ES bool filter clauses.
+//
+// With both Sources and URLPrefixes set, documents are matched by source
+// name, and URLPrefixes only apply to documents indexed without a source,
+// so per-source filters still find documents from before sources were
+// recorded.
+func (f Filter) clauses() []map[string]interface{} {
+	clauses := f.originClauses()
+	if len(f.Languages) > 0 {
+		clauses = append(clauses, map[string]interface{}{
+			"terms": map[string]interface{}{"language": f.Languages},
+		})
+	}
+	if len(f.Origins) > 0 {
+		clauses = append(clauses, map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []map[string]interface{}{
+					{"terms": map[string]interface{}{"source.name": f.Origins}},
+					{"terms": map[string]interface{}{"source.host": f.Origins}},
+				},
+				"minimum_should_match": 1,
+			},
+		})
+	}
+	if !f.After.IsZero() || !f.Before.IsZero() {
+		scraped := map[string]interface{}{}
+		if !f.After.IsZero() {
+			scraped["gte"] = f.After.UTC().Format(time.RFC3339)
+		}
+		if !f.Before.IsZero() {
+			scraped["lt"] = f.Before.UTC().Format(time.RFC3339)
+		}
+		clauses = append(clauses, map[string]interface{}{
+			"range": map[string]interface{}{"scraped_at": scraped},
+		})
+	}
+	if f.EnforceAccess {
+		field := cmp.Or(f.accessField, accessLabelsField)
+		should := []map[string]interface{}{
+			{"bool": map[string]interface{}{
+				"must_not": map[string]interface{}{"exists": map[string]interface{}{"field": field}},
+			}},
+		}
+		if len(f.AccessLabels) > 0 {
+			should = append(should, map[string]interface{}{
+				"terms": map[string]interface{}{field: f.AccessLabels},
+			})
+		}
+		clauses = append(clauses, map[string]interface{}{
+			"bool": map[string]interface{}{"should": should, "minimum_should_match": 1},
+		})
+	}
+	return clauses
+}
+
+// originClauses returns the clauses matching Sources and URLPrefixes.
+func (f Filter) originClauses() []map[string]interface{} {
+	var urls map[string]interface{}
+	if len(f.URLPrefixes) > 0 {
+		should := make([]map[string]interface{}, len(f.URLPrefixes))
+		for i, prefix := range f.URLPrefixes {
+			should[i] = map[string]interface{}{
+				"prefix": map[string]interface{}{"url": prefix},
+			}
+		}
+		urls = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should":               should,
+				"minimum_should_match": 1,
+			},
+		}
+	}
+
+	switch {
+	case len(f.Sources) > 0 && urls != nil:
+		return []map[string]interface{}{{
+			"bool": map[string]interface{}{
+				"should": []map[string]interface{}{
+					{"terms": map[string]interface{}{"source.name": f.Sources}},
+					{"bool": map[string]interface{}{
+						"must_not": map[string]interface{}{"exists": map[string]interface{}{"field": "source.name"}},
+						"filter":   urls,
+					}},
+				},
+				"minimum_should_match": 1,
+			},
+		}}
+	case len(f.Sources) > 0:
+		return []map[string]interface{}{{"terms": map[string]interface{}{"source.name": f.Sources}}}
+	case urls != nil:
+		return []map[string]interface{}{urls}
+	default:
+		return nil
+	}
+}
+
+// exclusions returns the bool must_not clauses matching Exclude.
+func (f Filter) exclusions() []map[string]interface{} {
+	clauses := make([]map[string]interface{}, len(f.Exclude))
+	for i, term := range f.Exclude {
+		clauses[i] = map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  term,
+				"type":   "phrase",
+				"fields": excludeFields,
+			},
+		}
+	}
+	return clauses
+}
+
+// searchClauses returns the filter clauses, along with those restricting
+// searches only.
+func (f Filter) searchClauses() []map[string]interface{} {
+	clauses := f.clauses()
+	for _, title := range f.Titles {
+		clauses = append(clauses, map[string]interface{}{
+			"match": map[string]interface{}{
+				"title": map[string]interface{}{"query": title, "operator": "and"},
+			},
+		})
+	}
+	return clauses
+}
+
+// apply wraps query in a bool query carrying the search clauses and
+// exclusions. Returns query unchanged if the filter is empty.
+func (f Filter) apply(query map[string]interface{}) map[string]interface{} {
+	filter := f.searchClauses()
+	if len(filter) == 0 && len(f.Exclude) == 0 {
+		return query
+	}
+	clauses := map[string]interface{}{"must": query}
+	if len(filter) > 0 {
+		clauses["filter"] = filter
+	}
+	if len(f.Exclude) > 0 {
+		clauses["must_not"] = f.exclusions()
+	}
+	return map[string]interface{}{"bool": clauses}
+}
+
+// filterKNN restricts the results of a kNN search to the documents the
+// filter matches.
+func (f Filter) filterKNN(knn map[string]interface{}) {
+	if len(f.Exclude) > 0 || len(f.Titles) > 0 {
+		knn["filter"] = f.apply(map[string]interface{}{"match_all": map[string]interface{}{}})
+	} else if !f.IsZero() {
+		knn["filter"] = f.clauses()
+	}
+}
+
+// countResponse represents the ES count response.
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+// Count returns the number of documents matching the filter.
+func (c *Client) Count(ctx context.Context, filter Filter) (int, error) {
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return c.countQuery(ctx, map[string]interface{}{
+		"bool": map[string]interface{}{"filter": filter.clauses()},
+	})
+}
+
+// countQuery returns the number of documents matching query.
+func (c *Client) countQuery(ctx context.Context, query map[string]interface{}) (int, error) {
+	data, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := c.es.Count(
+		c.es.Count.WithContext(ctx),
+		c.es.Count.WithIndex(c.indices...),
+		c.es.Count.WithIgnoreUnavailable(true),
+		c.es.Count.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("count failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return 0, nil
+	}
+	if res.IsError() {
+		return 0, fmt.Errorf("count error: %s", res.String())
+	}
+
+	var cr countResponse
+	if err := json.NewDecoder(res.Body).Decode(&cr); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return cr.Count, nil
+}
+
+// MatchingIDs returns the IDs of the documents matching the filter.
+func (c *Client) MatchingIDs(ctx context.Context, filter Filter) ([]string, error) {
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filter.clauses()},
+		},
+	}
+	var ids []string
+	err = scroll(ctx, c, c.indices, query, 1000, func(doc models.Document) error {
+		ids = append(ids, doc.ID)
+		return nil
+	}, "id")
+	return ids, err
+}
+
+// deleteByQueryResponse represents the ES delete-by-query response.
+type deleteByQueryResponse struct {
+	Deleted  int `json:"deleted"`
+	Failures []struct {
+		ID    string `json:"id"`
+		Cause struct {
+			Reason string `json:"reason"`
+		} `json:"cause"`
+	} `json:"failures"`
+}
+
+// DeleteByFilter removes every document matching the filter, along with
+// its chunks, and returns how many documents were deleted. An empty filter
+// is rejected rather than deleting the whole index.
+func (c *Client) DeleteByFilter(ctx context.Context, filter Filter) (int, error) {
+	if filter.IsZero() {
+		return 0, fmt.Errorf("refusing to delete with an empty filter")
+	}
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filter.clauses()},
+		},
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	// Chunks carry their document's URL, so the same filter selects them
+	if _, err := c.deleteByQuery(ctx, c.chunkIndices, data); err != nil {
+		return 0, fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return c.deleteByQuery(ctx, c.indices, data)
+}
+
+// deleteByQuery runs a delete-by-query request against indices and returns
+// how many entries were deleted. Missing indices delete nothing.
+func (c *Client) deleteByQuery(ctx context.Context, indices []string, data []byte) (int, error) {
+	res, err := c.es.DeleteByQuery(
+		indices,
+		bytes.NewReader(data),
+		c.es.DeleteByQuery.WithContext(untimed(ctx)),
+		c.es.DeleteByQuery.WithRefresh(true),
+		c.es.DeleteByQuery.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("delete by query failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return 0, nil
+	}
+	if res.IsError() {
+		return 0, fmt.Errorf("delete by query error: %s", res.String())
+	}
+
+	var dr deleteByQueryResponse
+	if err := json.NewDecoder(res.Body).Decode(&dr); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(dr.Failures) > 0 {
+		return dr.Deleted, fmt.Errorf("%d documents failed to delete: %s", len(dr.Failures), dr.Failures[0].Cause.Reason)
+	}
+
+	return dr.Deleted, nil
+}
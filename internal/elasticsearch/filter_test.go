@@ -0,0 +1,41 @@
+package elasticsearch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestFilter_Match(t *testing.T) {
+	scraped := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	goDocs := models.Source{Name: "go", Host: "go.dev"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		url    string
+		source models.Source
+		labels []string
+		want   bool
+	}{
+		{name: "zero filter", url: "https://go.dev/doc", source: goDocs, want: true},
+		{name: "source", filter: Filter{Sources: []string{"go"}}, url: "https://go.dev/doc", source: goDocs, want: true},
+		{name: "other source", filter: Filter{Sources: []string{"k8s"}}, url: "https://go.dev/doc", source: goDocs},
+		{name: "url prefix without source", filter: Filter{Sources: []string{"go"}, URLPrefixes: []string{"https://go.dev/"}}, url: "https://go.dev/doc", want: true},
+		{name: "url prefix of other source", filter: Filter{Sources: []string{"k8s"}, URLPrefixes: []string{"https://go.dev/"}}, url: "https://go.dev/doc", source: goDocs},
+		{name: "origin host", filter: Filter{Origins: []string{"go.dev"}}, url: "https://go.dev/doc", source: goDocs, want: true},
+		{name: "language", filter: Filter{Languages: []string{"de"}}, url: "https://go.dev/doc", source: goDocs},
+		{name: "date range", filter: Filter{After: scraped, Before: scraped.AddDate(0, 1, 0)}, url: "https://go.dev/doc", want: true},
+		{name: "before range", filter: Filter{Before: scraped}, url: "https://go.dev/doc"},
+		{name: "access denied", filter: Filter{EnforceAccess: true}, url: "https://wiki", labels: []string{"internal"}},
+		{name: "access granted", filter: Filter{EnforceAccess: true, AccessLabels: []string{"internal"}}, url: "https://wiki", labels: []string{"internal"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.url, tt.source, "en", scraped, tt.labels); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
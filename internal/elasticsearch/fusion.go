@@ -0,0 +1,67 @@
+package elasticsearch
+
+// Fusion methods combining the BM25 and vector results of a hybrid search.
+const (
+	FusionRRF    = "rrf"    // Reciprocal rank fusion: ranks count, scores don't
+	FusionLinear = "linear" // Weighted sum of min-max normalized scores
+)
+
+// Fusion controls how hybrid searches combine BM25 and vector results.
+// The zero value uses reciprocal rank fusion with Elasticsearch's defaults.
+type Fusion struct {
+	Method         string  // FusionRRF or FusionLinear; default FusionRRF
+	RankConstant   int     // RRF: higher values flatten the gap between ranks; 0 uses Elasticsearch's 60
+	RankWindowSize int     // Results of each retriever considered; 0 uses the number requested
+	BM25Weight     float64 // Linear: weight of the BM25 score; 0 means 1
+	VectorWeight   float64 // Linear: weight of the vector score; 0 means 1
+}
+
+// retriever returns the retriever combining a text query and a kNN search
+// for a search returning size results. The kNN search is widened to return
+// as many results as the rank window.
+func (f Fusion) retriever(textQuery, knn map[string]interface{}, size int) map[string]interface{} {
+	windowSize := max(f.RankWindowSize, size)
+	if windowSize > size {
+		knn["k"] = windowSize
+		knn["num_candidates"] = windowSize * 2
+	}
+
+	if f.Method == FusionLinear {
+		return map[string]interface{}{
+			"linear": map[string]interface{}{
+				"retrievers": []map[string]interface{}{
+					{
+						"retriever":  map[string]interface{}{"standard": map[string]interface{}{"query": textQuery}},
+						"weight":     weightOrOne(f.BM25Weight),
+						"normalizer": "minmax",
+					},
+					{
+						"retriever":  map[string]interface{}{"knn": knn},
+						"weight":     weightOrOne(f.VectorWeight),
+						"normalizer": "minmax",
+					},
+				},
+				"rank_window_size": windowSize,
+			},
+		}
+	}
+
+	rrf := map[string]interface{}{
+		"retrievers": []map[string]interface{}{
+			{"standard": map[string]interface{}{"query": textQuery}},
+			{"knn": knn},
+		},
+		"rank_window_size": windowSize,
+	}
+	if f.RankConstant > 0 {
+		rrf["rank_constant"] = f.RankConstant
+	}
+	return map[string]interface{}{"rrf": rrf}
+}
+
+func weightOrOne(w float64) float64 {
+	if w == 0 {
+		return 1
+	}
+	return w
+}
@@ -0,0 +1,49 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFusion_Retriever(t *testing.T) {
+	text := map[string]interface{}{"match": map[string]interface{}{"content": "go"}}
+
+	tests := []struct {
+		name   string
+		fusion Fusion
+		want   []string
+	}{
+		{
+			name: "default rrf",
+			want: []string{`"rrf":`, `"rank_window_size":10`, `"k":10`},
+		},
+		{
+			name:   "tuned rrf",
+			fusion: Fusion{RankConstant: 20, RankWindowSize: 50},
+			want:   []string{`"rank_constant":20`, `"rank_window_size":50`, `"k":50`, `"num_candidates":100`},
+		},
+		{
+			name:   "linear",
+			fusion: Fusion{Method: FusionLinear, BM25Weight: 2},
+			want:   []string{`"linear":`, `"weight":2`, `"weight":1`, `"normalizer":"minmax"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			knn := map[string]interface{}{"field": "embedding", "k": 10, "num_candidates": 20}
+			data, err := json.Marshal(tt.fusion.retriever(text, knn, 10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("retriever = %s, want it to contain %s", data, want)
+				}
+			}
+			if tt.fusion.Method != FusionLinear && strings.Contains(string(data), "rank_constant") != (tt.fusion.RankConstant > 0) {
+				t.Errorf("retriever = %s: rank_constant set unexpectedly", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package elasticsearch
+
+import (
+	"strings"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// languageAnalyzers maps ISO 639-1 codes to the built-in Elasticsearch
+// analyzer for the language.
+var languageAnalyzers = map[string]string{
+	"ar": "arabic", "bg": "bulgarian", "bn": "bengali", "ca": "catalan",
+	"cs": "czech", "da": "danish", "de": "german", "el": "greek",
+	"en": "english", "es": "spanish", "eu": "basque", "fa": "persian",
+	"fi": "finnish", "fr": "french", "ga": "irish", "gl": "galician",
+	"hi": "hindi", "hu": "hungarian", "hy": "armenian", "id": "indonesian",
+	"it": "italian", "ja": "cjk", "ko": "cjk", "lt": "lithuanian",
+	"lv": "latvian", "nb": "norwegian", "nl": "dutch", "no": "norwegian",
+	"pt": "portuguese", "ro": "romanian", "ru": "russian", "sv": "swedish",
+	"th": "thai", "tr": "turkish", "zh": "cjk",
+}
+
+// localizedField holds a copy of the content of documents and chunks whose
+// language has an analyzer other than the index default, with one subfield
+// per analyzer.
+const localizedField = "content_localized"
+
+// localizedProperty maps localizedField.
+func localizedProperty() map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, analyzer := range languageAnalyzers {
+		properties[analyzer] = map[string]interface{}{"type": "text", "analyzer": analyzer}
+	}
+	return map[string]interface{}{"properties": properties}
+}
+
+// localize returns the localizedField value for content in lang, or nil
+// if the default analyzer already fits or lang has no analyzer.
+func (m Mapping) localize(lang, content string) map[string]string {
+	primary, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	analyzer, ok := languageAnalyzers[primary]
+	if defaultAnalyzer, _ := m.defaults(); !ok || analyzer == defaultAnalyzer {
+		return nil
+	}
+	return map[string]string{analyzer: content}
+}
+
+// indexedDocument is a document as sent to Elasticsearch.
+type indexedDocument struct {
+	models.Document
+	Localized map[string]string `json:"content_localized,omitempty"`
+}
+
+// indexedChunk is a chunk as sent to Elasticsearch.
+type indexedChunk struct {
+	models.Chunk
+	Localized map[string]string `json:"content_localized,omitempty"`
+}
+
+// indexable routes doc's content to the analyzer for its language.
+func (c *Client) indexable(doc models.Document) indexedDocument {
+	return indexedDocument{Document: doc, Localized: c.mapping.localize(doc.Language, doc.Content)}
+}
+
+// indexableChunk routes chunk's content to the analyzer for its language.
+func (c *Client) indexableChunk(chunk models.Chunk) indexedChunk {
+	return indexedChunk{Chunk: chunk, Localized: c.mapping.localize(chunk.Language, chunk.Content)}
+}
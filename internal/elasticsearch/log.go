@@ -0,0 +1,184 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CreateLogIndex creates a log index with the given field mappings if it
+// does not exist. Log indexes hold append-only JSON entries with a "time"
+// field, such as audit records, next to the document index.
+func (c *Client) CreateLogIndex(ctx context.Context, index string, properties map[string]interface{}) error {
+	res, err := c.es.Indices.Exists([]string{index}, c.es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to check index: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode == 200 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": properties},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+
+	res, err = c.es.Indices.Create(
+		index,
+		c.es.Indices.Create.WithContext(ctx),
+		c.es.Indices.Create.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Another process may have created it in the meantime
+	if res.IsError() && res.StatusCode != 400 {
+		return fmt.Errorf("error creating index: %s", res.String())
+	}
+	return nil
+}
+
+// AppendLog adds entries to a log index in a single bulk request.
+func (c *Client) AppendLog(ctx context.Context, index string, entries []any) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, entry := range entries {
+		if err := enc.Encode(map[string]interface{}{"create": map[string]interface{}{}}); err != nil {
+			return fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to marshal log entry: %w", err)
+		}
+	}
+
+	res, err := c.es.Bulk(
+		&body,
+		c.es.Bulk.WithContext(ctx),
+		c.es.Bulk.WithIndex(index),
+	)
+	if err != nil {
+		return fmt.Errorf("bulk request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("bulk error: %s", res.String())
+	}
+
+	var br bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if br.Errors {
+		for _, item := range br.Items {
+			for _, result := range item {
+				if result.Error != nil {
+					return fmt.Errorf("failed to append log entry: %s", result.Error.Reason)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SearchLog returns the raw entries of a log index matching the filter
+// clauses, newest first. A missing index has no entries.
+func (c *Client) SearchLog(ctx context.Context, index string, filters []map[string]interface{}, size int) ([]json.RawMessage, error) {
+	query := map[string]interface{}{
+		"size": size,
+		"sort": []map[string]interface{}{{"time": "desc"}},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filters},
+		},
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(index),
+		c.es.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("search error: %s", res.String())
+	}
+
+	var sr struct {
+		Hits struct {
+			Hits []struct {
+				Source json.RawMessage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	entries := make([]json.RawMessage, len(sr.Hits.Hits))
+	for i, hit := range sr.Hits.Hits {
+		entries[i] = hit.Source
+	}
+	return entries, nil
+}
+
+// AggregateLog runs aggregations over the entries of a log index matching
+// filters. Returns the aggregation results by name, or nil if the index
+// does not exist.
+func (c *Client) AggregateLog(ctx context.Context, index string, filters []map[string]interface{}, aggs map[string]interface{}) (map[string]json.RawMessage, error) {
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filters},
+		},
+		"aggs": aggs,
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(index),
+		c.es.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("search error: %s", res.String())
+	}
+
+	var sr struct {
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return sr.Aggregations, nil
+}
@@ -0,0 +1,179 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+)
+
+// Mapping customizes the index created by CreateIndex.
+// The zero value produces the default mapping.
+type Mapping struct {
+	Analyzer         string                 // Analyzer for content, tags, and summary; default "english"
+	VectorSimilarity string                 // Embedding similarity; default "cosine"
+	Fields           map[string]interface{} // Extra properties, or overrides merged into the default ones
+	Settings         map[string]interface{} // Index settings, e.g. custom analyzers or similarity modules
+}
+
+// body returns the document index creation request body.
+func (m Mapping) body() ([]byte, error) {
+	analyzer, similarity := m.defaults()
+
+	// Supports LLM-generated tags/summary and optional vector embeddings
+	properties := map[string]interface{}{
+		"id":            map[string]interface{}{"type": "keyword"},
+		"url":           map[string]interface{}{"type": "keyword"},
+		"title":         map[string]interface{}{"type": "text"},
+		"content":       map[string]interface{}{"type": "text", "analyzer": analyzer},
+		"content_type":  map[string]interface{}{"type": "keyword"},
+		"language":      map[string]interface{}{"type": "keyword"},
+		"access_labels": map[string]interface{}{"type": "keyword"},
+		localizedField:  localizedProperty(),
+		"scraped_at":    map[string]interface{}{"type": "date"},
+		"outline":       outlineProperty(analyzer, 3),
+		"word_count":    map[string]interface{}{"type": "integer"},
+		"token_count":   map[string]interface{}{"type": "integer"},
+		"content_hash":  map[string]interface{}{"type": "keyword"},
+		"attachments": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"url":     map[string]interface{}{"type": "keyword"},
+				"kind":    map[string]interface{}{"type": "keyword"},
+				"caption": map[string]interface{}{"type": "text", "analyzer": analyzer},
+			},
+		},
+		"site_name": map[string]interface{}{"type": "keyword"},
+		"favicon":   map[string]interface{}{"type": "keyword", "index": false},
+		"license":   map[string]interface{}{"type": "keyword"},
+		"source":    sourceProperty(),
+		"tags": map[string]interface{}{
+			"type":     "text",
+			"analyzer": analyzer,
+			"fields":   map[string]interface{}{"keyword": tagsKeyword},
+		},
+		"summary":   map[string]interface{}{"type": "text", "analyzer": analyzer},
+		"embedding": embeddingProperty(similarity),
+	}
+	mergeMaps(properties, m.Fields)
+
+	return m.indexBody(properties)
+}
+
+// chunkBody returns the chunk index creation request body. Fields only
+// override properties chunks share with documents, such as the embedding
+// dimensions; extra document properties are not added.
+func (m Mapping) chunkBody() ([]byte, error) {
+	analyzer, similarity := m.defaults()
+
+	properties := map[string]interface{}{
+		"id":            map[string]interface{}{"type": "keyword"},
+		"document_id":   map[string]interface{}{"type": "keyword"},
+		"url":           map[string]interface{}{"type": "keyword"},
+		"title":         map[string]interface{}{"type": "text"},
+		"source":        sourceProperty(),
+		"language":      map[string]interface{}{"type": "keyword"},
+		"scraped_at":    map[string]interface{}{"type": "date"},
+		"access_labels": map[string]interface{}{"type": "keyword"},
+		"license":       map[string]interface{}{"type": "keyword"},
+		localizedField:  localizedProperty(),
+		"heading_path": map[string]interface{}{
+			"type":     "text",
+			"analyzer": analyzer,
+			"fields": map[string]interface{}{
+				"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+			},
+		},
+		"position":    map[string]interface{}{"type": "integer"},
+		"word_count":  map[string]interface{}{"type": "integer"},
+		"token_count": map[string]interface{}{"type": "integer"},
+		"content":     map[string]interface{}{"type": "text", "analyzer": analyzer},
+		"context":     map[string]interface{}{"type": "text", "analyzer": analyzer},
+		"embedding":   embeddingProperty(similarity),
+	}
+	overrides := make(map[string]interface{})
+	for key, value := range m.Fields {
+		if _, ok := properties[key]; ok {
+			overrides[key] = value
+		}
+	}
+	mergeMaps(properties, overrides)
+
+	return m.indexBody(properties)
+}
+
+// defaults returns the analyzer and vector similarity, defaulted.
+func (m Mapping) defaults() (analyzer, similarity string) {
+	analyzer = m.Analyzer
+	if analyzer == "" {
+		analyzer = "english"
+	}
+	similarity = m.VectorSimilarity
+	if similarity == "" {
+		similarity = "cosine"
+	}
+	return analyzer, similarity
+}
+
+// indexBody wraps properties and the index settings into a request body.
+func (m Mapping) indexBody(properties map[string]interface{}) ([]byte, error) {
+	body := map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": properties},
+	}
+	if len(m.Settings) > 0 {
+		body["settings"] = m.Settings
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal index mapping: %w", err)
+	}
+	return data, nil
+}
+
+// sourceProperty maps the source object shared by documents and chunks.
+func sourceProperty() map[string]interface{} {
+	return map[string]interface{}{
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "keyword"},
+			"host": map[string]interface{}{"type": "keyword"},
+		},
+	}
+}
+
+// embeddingProperty maps the dense vector field shared by documents and chunks.
+func embeddingProperty(similarity string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "dense_vector",
+		"dims":       2560,
+		"index":      true,
+		"similarity": similarity,
+	}
+}
+
+// mergeMaps deep-merges src into dst. Nested maps are merged key by key;
+// any other value in src replaces the one in dst.
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcOK := value.(map[string]interface{})
+		dstMap, dstOK := dst[key].(map[string]interface{})
+		if srcOK && dstOK {
+			// Copy so shared defaults are never modified
+			merged := maps.Clone(dstMap)
+			mergeMaps(merged, srcMap)
+			dst[key] = merged
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// outlineProperty maps a heading tree the given number of levels deep.
+func outlineProperty(analyzer string, levels int) map[string]interface{} {
+	properties := map[string]interface{}{
+		"text":  map[string]interface{}{"type": "text", "analyzer": analyzer},
+		"level": map[string]interface{}{"type": "byte"},
+	}
+	if levels > 1 {
+		properties["children"] = outlineProperty(analyzer, levels-1)
+	}
+	return map[string]interface{}{"properties": properties}
+}
@@ -0,0 +1,138 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+)
+
+func TestMapping_Body(t *testing.T) {
+	m := Mapping{
+		Analyzer:         "standard",
+		VectorSimilarity: "dot_product",
+		Fields: map[string]interface{}{
+			"product": map[string]interface{}{"type": "keyword"},
+			"title":   map[string]interface{}{"analyzer": "docs_text"},
+		},
+		Settings: map[string]interface{}{
+			"similarity": map[string]interface{}{
+				"default": map[string]interface{}{"type": "BM25", "b": 0.5},
+			},
+		},
+	}
+
+	data, err := m.body()
+	if err != nil {
+		t.Fatalf("body() error = %v", err)
+	}
+
+	var body struct {
+		Mappings struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		} `json:"mappings"`
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("body() is not valid JSON: %v", err)
+	}
+	props := body.Mappings.Properties
+
+	if got := props["content"]["analyzer"]; got != "standard" {
+		t.Errorf("content analyzer = %v, want standard", got)
+	}
+	if got := props["embedding"]["similarity"]; got != "dot_product" {
+		t.Errorf("embedding similarity = %v, want dot_product", got)
+	}
+	if got := props["product"]["type"]; got != "keyword" {
+		t.Errorf("product type = %v, want keyword", got)
+	}
+	// Overrides merge into the default property rather than replacing it
+	if props["title"]["type"] != "text" || props["title"]["analyzer"] != "docs_text" {
+		t.Errorf("title = %v, want text with docs_text analyzer", props["title"])
+	}
+	if body.Settings["similarity"] == nil {
+		t.Error("settings should include the custom similarity")
+	}
+}
+
+func TestMapping_BodyDefaults(t *testing.T) {
+	data, err := Mapping{}.body()
+	if err != nil {
+		t.Fatalf("body() error = %v", err)
+	}
+
+	var body map[string]map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("body() is not valid JSON: %v", err)
+	}
+	props := body["mappings"]["properties"]
+
+	if got := props["content"]["analyzer"]; got != "english" {
+		t.Errorf("content analyzer = %v, want english", got)
+	}
+	if got := props["embedding"]["similarity"]; got != "cosine" {
+		t.Errorf("embedding similarity = %v, want cosine", got)
+	}
+	h3 := props["outline"]["properties"].(map[string]interface{})["children"].(map[string]interface{})["properties"].(map[string]interface{})["children"].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := h3["text"]; !ok {
+		t.Error("outline should map heading text three levels deep")
+	}
+	if _, ok := h3["children"]; ok {
+		t.Error("outline should stop at the third level")
+	}
+	if _, ok := body["settings"]; ok {
+		t.Error("default mapping should not set index settings")
+	}
+}
+
+func TestMapping_ChunkBody(t *testing.T) {
+	m := Mapping{
+		Fields: map[string]interface{}{
+			"product":   map[string]interface{}{"type": "keyword"},
+			"embedding": map[string]interface{}{"dims": 768},
+		},
+	}
+
+	data, err := m.chunkBody()
+	if err != nil {
+		t.Fatalf("chunkBody() error = %v", err)
+	}
+
+	var body map[string]map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("chunkBody() is not valid JSON: %v", err)
+	}
+	props := body["mappings"]["properties"]
+
+	if got := props["document_id"]["type"]; got != "keyword" {
+		t.Errorf("document_id type = %v, want keyword", got)
+	}
+	if got := props["embedding"]["dims"]; got != float64(768) {
+		t.Errorf("embedding dims = %v, want the 768 override", got)
+	}
+	if _, ok := props["product"]; ok {
+		t.Error("document-only fields should not be added to chunks")
+	}
+}
+
+func TestMapping_Localize(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  Mapping
+		language string
+		want     map[string]string
+	}{
+		{"other language", Mapping{}, "de-AT", map[string]string{"german": "text"}},
+		{"default analyzer fits", Mapping{}, "en", nil},
+		{"standard default", Mapping{Analyzer: "standard"}, "en", map[string]string{"english": "text"}},
+		{"unknown language", Mapping{}, "xx", nil},
+		{"no language", Mapping{}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.localize(tt.language, "text"); !maps.Equal(got, tt.want) {
+				t.Errorf("localize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package elasticsearch
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Boosts of results containing a quoted phrase or code identifier of the
+// query as written, over those matching its words apart.
+const (
+	phraseBoost     = 2
+	identifierBoost = 3
+)
+
+// exactFields are the fields searched for exact phrases and identifiers.
+var exactFields = []string{"title^3", "heading_path^2", "content"}
+
+// identifierPattern matches code-like tokens: CamelCase, snake_case, and
+// dotted paths such as http.HandlerFunc.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// exactTerms returns the quoted phrases and code identifiers in query.
+func exactTerms(query string) (phrases, identifiers []string) {
+	rest := query
+	for {
+		_, after, ok := strings.Cut(rest, `"`)
+		if !ok {
+			break
+		}
+		phrase, after, ok := strings.Cut(after, `"`)
+		if !ok {
+			break
+		}
+		if phrase = strings.TrimSpace(phrase); strings.Contains(phrase, " ") {
+			phrases = append(phrases, phrase)
+		}
+		rest = after
+	}
+
+	for _, word := range strings.Fields(query) {
+		word = strings.Trim(word, `"'`+"`,;:!?()[]{}")
+		word = strings.TrimSuffix(word, ".")
+		if isIdentifier(word) {
+			identifiers = append(identifiers, word)
+		}
+	}
+	return phrases, identifiers
+}
+
+// isIdentifier reports whether word looks like code rather than prose.
+func isIdentifier(word string) bool {
+	if !identifierPattern.MatchString(word) {
+		return false
+	}
+	if strings.Contains(word, "_") {
+		return strings.Trim(word, "_") != ""
+	}
+	if strings.Contains(word, ".") {
+		// Abbreviations such as e.g have one letter per part
+		for _, part := range strings.Split(word, ".") {
+			if len(part) > 1 {
+				return true
+			}
+		}
+		return false
+	}
+	// CamelCase: a lowercase letter followed by an uppercase one
+	var prev rune
+	for _, r := range word {
+		if unicode.IsLower(prev) && unicode.IsUpper(r) {
+			return true
+		}
+		prev = r
+	}
+	return false
+}
+
+// exactClauses returns should clauses raising the score of results that
+// contain the phrases and identifiers of query as written.
+func exactClauses(query string) []map[string]interface{} {
+	phrases, identifiers := exactTerms(query)
+	var clauses []map[string]interface{}
+	for _, phrase := range phrases {
+		clauses = append(clauses, phraseMatch(phrase, phraseBoost))
+	}
+	for _, identifier := range identifiers {
+		clauses = append(clauses, phraseMatch(identifier, identifierBoost))
+	}
+	return clauses
+}
+
+// phraseMatch returns a query matching text as a phrase in exactFields.
+func phraseMatch(text string, boost float64) map[string]interface{} {
+	return map[string]interface{}{
+		"multi_match": map[string]interface{}{
+			"query":  text,
+			"type":   "phrase",
+			"fields": exactFields,
+			"boost":  boost,
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package elasticsearch
+
+// Recency favors recently scraped pages, so a newer copy of a page
+// outranks a stale one covering the same topic. The zero value leaves
+// scores alone.
+type Recency struct {
+	// Weight is the share of a page's score that depends on its age, from
+	// 0 (none) to 1: a page whose age has fully decayed keeps 1-Weight of
+	// its score
+	Weight float64
+	Scale  string  // Age at which the age-dependent share is multiplied by Decay, e.g. "90d"; default "180d"
+	Offset string  // Age before which pages are not penalized, e.g. "7d"
+	Decay  float64 // Multiplier reached at Offset+Scale, between 0 and 1; default 0.5
+}
+
+// apply wraps query to scale scores by the age of each page's scrape,
+// following a Gaussian curve.
+func (r Recency) apply(query map[string]interface{}) map[string]interface{} {
+	if r.Weight <= 0 {
+		return query
+	}
+
+	weight := min(r.Weight, 1)
+	scale := r.Scale
+	if scale == "" {
+		scale = "180d"
+	}
+	decay := r.Decay
+	if decay <= 0 || decay >= 1 {
+		decay = 0.5
+	}
+	gauss := map[string]interface{}{"origin": "now", "scale": scale, "decay": decay}
+	if r.Offset != "" {
+		gauss["offset"] = r.Offset
+	}
+
+	// Summed, the functions give weight*decay + (1-weight): pages keep at
+	// least 1-weight of their score however old. Pages indexed without a
+	// scrape time get the full score.
+	functions := []map[string]interface{}{
+		{"gauss": map[string]interface{}{"scraped_at": gauss}, "weight": weight},
+	}
+	if weight < 1 {
+		functions = append(functions, map[string]interface{}{"weight": 1 - weight})
+	}
+	return map[string]interface{}{
+		"function_score": map[string]interface{}{
+			"query":      query,
+			"functions":  functions,
+			"score_mode": "sum",
+			"boost_mode": "multiply",
+		},
+	}
+}
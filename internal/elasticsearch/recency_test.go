@@ -0,0 +1,31 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRecency_Apply(t *testing.T) {
+	query := map[string]interface{}{"match_all": map[string]interface{}{}}
+
+	if got := (Recency{}).apply(query); got["match_all"] == nil {
+		t.Errorf("zero Recency changed the query: %v", got)
+	}
+
+	data, err := json.Marshal(Recency{Weight: 0.3, Scale: "90d", Offset: "7d"}.apply(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"scraped_at":{"decay":0.5,"offset":"7d","origin":"now","scale":"90d"}`,
+		`"weight":0.3`,
+		`"weight":0.7`,
+		`"score_mode":"sum"`,
+		`"match_all":{}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("apply() = %s, want it to contain %s", data, want)
+		}
+	}
+}
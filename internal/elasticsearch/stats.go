@@ -0,0 +1,294 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IndexStats holds corpus statistics for the index.
+type IndexStats struct {
+	Index         string        `json:"index"`
+	DocCount      int           `json:"doc_count"`
+	SizeBytes     int64         `json:"size_bytes"`
+	WithEmbedding int           `json:"with_embedding"`
+	Words         int64         `json:"words"`
+	Tokens        int64         `json:"tokens"` // Estimated
+	Sources       []SourceStats `json:"sources"`
+	TopTags       []TermCount   `json:"top_tags"`
+}
+
+// EmbeddingCoverage returns the percentage of documents that have an embedding.
+func (s *IndexStats) EmbeddingCoverage() float64 {
+	if s.DocCount == 0 {
+		return 0
+	}
+	return float64(s.WithEmbedding) * 100 / float64(s.DocCount)
+}
+
+// SourceStats holds per-host document statistics.
+type SourceStats struct {
+	Host        string    `json:"host"`
+	Docs        int       `json:"docs"`
+	LastScraped time.Time `json:"last_scraped"` // When the newest indexed page was scraped
+}
+
+// TermCount is a term and its document frequency.
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// hostScript extracts the host from the url keyword field at query time.
+// Local file:// documents have an empty host.
+const hostScript = `
+String u = doc['url'].value;
+int start = u.indexOf('://');
+start = start < 0 ? 0 : start + 3;
+int end = u.indexOf('/', start);
+emit(end < 0 ? u.substring(start) : u.substring(start, end));
+`
+
+// statsResponse represents the aggregation response used by Stats.
+type statsResponse struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+	} `json:"hits"`
+	Aggregations struct {
+		WithEmbedding struct {
+			DocCount int `json:"doc_count"`
+		} `json:"with_embedding"`
+		Words struct {
+			Value float64 `json:"value"`
+		} `json:"words"`
+		Tokens struct {
+			Value float64 `json:"value"`
+		} `json:"tokens"`
+		Hosts struct {
+			Buckets []struct {
+				Key         string `json:"key"`
+				DocCount    int    `json:"doc_count"`
+				LastScraped struct {
+					Value float64 `json:"value"`
+				} `json:"last_scraped"`
+			} `json:"buckets"`
+		} `json:"hosts"`
+		Tags struct {
+			Buckets []struct {
+				Key      string `json:"key"`
+				DocCount int    `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"tags"`
+	} `json:"aggregations"`
+}
+
+// Stats gathers document counts, corpus size, embedding coverage, per-host counts, and tag frequency.
+// A topTags of 0 skips tag frequency.
+func (c *Client) Stats(ctx context.Context, topTags int) (*IndexStats, error) {
+	query := map[string]interface{}{
+		"size":             0,
+		"track_total_hits": true,
+		"runtime_mappings": map[string]interface{}{
+			"host": map[string]interface{}{
+				"type":   "keyword",
+				"script": map[string]interface{}{"source": hostScript},
+			},
+		},
+		"aggs": map[string]interface{}{
+			"with_embedding": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"exists": map[string]interface{}{"field": "embedding"},
+				},
+			},
+			"words":  map[string]interface{}{"sum": map[string]interface{}{"field": "word_count"}},
+			"tokens": map[string]interface{}{"sum": map[string]interface{}{"field": "token_count"}},
+			"hosts": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "host", "size": 1000},
+				"aggs": map[string]interface{}{
+					"last_scraped": map[string]interface{}{
+						"max": map[string]interface{}{"field": "scraped_at"},
+					},
+				},
+			},
+		},
+	}
+	if topTags > 0 {
+		query["aggs"].(map[string]interface{})["tags"] = map[string]interface{}{
+			"terms": map[string]interface{}{"field": "tags.keyword", "size": topTags},
+		}
+	}
+
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(c.indices...),
+		c.es.Search.WithIgnoreUnavailable(true),
+		c.es.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("stats query failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("stats query error: %s", res.String())
+	}
+
+	var sr statsResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	stats := &IndexStats{
+		Index:         strings.Join(c.indices, ","),
+		DocCount:      sr.Hits.Total.Value,
+		WithEmbedding: sr.Aggregations.WithEmbedding.DocCount,
+		Words:         int64(sr.Aggregations.Words.Value),
+		Tokens:        int64(sr.Aggregations.Tokens.Value),
+	}
+	for _, b := range sr.Aggregations.Hosts.Buckets {
+		stats.Sources = append(stats.Sources, SourceStats{
+			Host:        b.Key,
+			Docs:        b.DocCount,
+			LastScraped: time.UnixMilli(int64(b.LastScraped.Value)).UTC(),
+		})
+	}
+	for _, b := range sr.Aggregations.Tags.Buckets {
+		stats.TopTags = append(stats.TopTags, TermCount{Term: b.Key, Count: b.DocCount})
+	}
+
+	size, err := c.storeSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.SizeBytes = size
+
+	return stats, nil
+}
+
+// tagsKeyword is the mapping of the tags.keyword sub-field Stats counts
+// tags on.
+var tagsKeyword = map[string]interface{}{"type": "keyword", "ignore_above": 256}
+
+// ensureTagsKeyword adds the tags.keyword sub-field to the tags of an
+// index created before it existed. Only documents indexed afterwards are
+// indexed into it: the tags of earlier documents are counted once they are
+// indexed again, by a full ingestion or an _update_by_query of the index.
+func (c *Client) ensureTagsKeyword(ctx context.Context, index string) error {
+	res, err := c.es.Indices.GetFieldMapping(
+		[]string{"tags"},
+		c.es.Indices.GetFieldMapping.WithContext(ctx),
+		c.es.Indices.GetFieldMapping.WithIndex(index),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get tags mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil
+	}
+	if res.IsError() {
+		return fmt.Errorf("error getting tags mapping of index %s: %s", index, res.String())
+	}
+
+	// Keyed by index name, which differs from index when it is an alias
+	var body map[string]struct {
+		Mappings map[string]struct {
+			Mapping map[string]map[string]interface{} `json:"mapping"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode tags mapping: %w", err)
+	}
+	for name, idx := range body {
+		// Unmapped tags are mapped with a keyword sub-field by the first
+		// tagged document
+		tags, ok := idx.Mappings["tags"].Mapping["tags"]
+		if !ok || tags["type"] != "text" {
+			continue
+		}
+		fields, _ := tags["fields"].(map[string]interface{})
+		if _, ok := fields["keyword"]; ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		fields["keyword"] = tagsKeyword
+		tags["fields"] = fields
+
+		// The other settings of tags are sent back unchanged, as put
+		// mapping requires
+		data, err := json.Marshal(map[string]interface{}{"properties": map[string]interface{}{"tags": tags}})
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags mapping: %w", err)
+		}
+		res, err := c.es.Indices.PutMapping(
+			[]string{name},
+			bytes.NewReader(data),
+			c.es.Indices.PutMapping.WithContext(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to map tags.keyword: %w", err)
+		}
+		res.Body.Close()
+		if res.IsError() {
+			return fmt.Errorf("error mapping tags.keyword in index %s: %s", name, res.String())
+		}
+	}
+	return nil
+}
+
+// storeSizeResponse represents the index stats API response.
+type storeSizeResponse struct {
+	All struct {
+		Primaries struct {
+			Store struct {
+				SizeInBytes int64 `json:"size_in_bytes"`
+			} `json:"store"`
+		} `json:"primaries"`
+	} `json:"_all"`
+}
+
+// storeSize returns the on-disk size of the primary shards of the
+// document indices. Missing source indices take no space.
+func (c *Client) storeSize(ctx context.Context) (int64, error) {
+	var total int64
+	for i, index := range c.indices {
+		res, err := c.es.Indices.Stats(
+			c.es.Indices.Stats.WithContext(ctx),
+			c.es.Indices.Stats.WithIndex(index),
+			c.es.Indices.Stats.WithMetric("store"),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("index stats failed: %w", err)
+		}
+
+		var sr storeSizeResponse
+		switch {
+		case res.StatusCode == 404 && i > 0:
+		case res.IsError():
+			err = fmt.Errorf("index stats error: %s", res.String())
+		default:
+			if err = json.NewDecoder(res.Body).Decode(&sr); err != nil {
+				err = fmt.Errorf("failed to decode response: %w", err)
+			}
+		}
+		res.Body.Close()
+		if err != nil {
+			return 0, err
+		}
+		total += sr.All.Primaries.Store.SizeInBytes
+	}
+	return total, nil
+}
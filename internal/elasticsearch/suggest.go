@@ -0,0 +1,138 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// suggestField is the field whose terms corrections are drawn from. Titles
+// are indexed without stemming, so their terms read as words.
+const suggestField = "title"
+
+// suggestResponse represents an ES search response carrying term
+// suggestions.
+type suggestResponse struct {
+	Suggest map[string][]struct {
+		Offset  int `json:"offset"`
+		Length  int `json:"length"`
+		Options []struct {
+			Text string `json:"text"`
+		} `json:"options"`
+	} `json:"suggest"`
+}
+
+// Suggest returns query with its terms that appear in no page title
+// replaced by the closest terms that do, for offering a correction when a
+// search finds nothing. Returns "" if there is no correction, or if no
+// page the filter matches has a title with every corrected term, so terms
+// of pages the caller may not see are never suggested.
+func (c *Client) Suggest(ctx context.Context, query string, filter Filter) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return "", nil
+	}
+	filter, err := c.withAccessField(ctx, filter)
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]interface{}{
+		"size": 0,
+		"suggest": map[string]interface{}{
+			"text": query,
+			"terms": map[string]interface{}{
+				"term": map[string]interface{}{
+					"field":        suggestField,
+					"suggest_mode": "missing",
+					"size":         1,
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(c.indices...),
+		c.es.Search.WithIgnoreUnavailable(true),
+		c.es.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return "", fmt.Errorf("suggest failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return "", nil
+	}
+	if res.IsError() {
+		return "", fmt.Errorf("suggest error: %s", res.String())
+	}
+
+	var sr suggestResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var corrections []correction
+	for _, entry := range sr.Suggest["terms"] {
+		if len(entry.Options) > 0 {
+			corrections = append(corrections, correction{entry.Offset, entry.Length, entry.Options[0].Text})
+		}
+	}
+	corrected := correct(query, corrections)
+	if corrected == "" || filter.IsZero() {
+		return corrected, nil
+	}
+
+	// Suggestions are drawn from every title, so checked against the filter
+	count, err := c.countQuery(ctx, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": map[string]interface{}{
+				"match": map[string]interface{}{
+					suggestField: map[string]interface{}{"query": corrected, "operator": "and"},
+				},
+			},
+			"filter": filter.clauses(),
+		},
+	})
+	if err != nil || count == 0 {
+		return "", err
+	}
+	return corrected, nil
+}
+
+// correction replaces the length characters of a query at offset with
+// text.
+type correction struct {
+	offset, length int
+	text           string
+}
+
+// correct applies corrections to query, or returns "" if there are none.
+// Corrections outside the query are ignored.
+func correct(query string, corrections []correction) string {
+	if len(corrections) == 0 {
+		return ""
+	}
+	// Applied from the end, so earlier offsets stay valid
+	sort.Slice(corrections, func(i, j int) bool { return corrections[i].offset > corrections[j].offset })
+	runes := []rune(query)
+	for _, c := range corrections {
+		if c.offset < 0 || c.length < 0 || c.offset+c.length > len(runes) {
+			continue
+		}
+		runes = slices.Concat(runes[:c.offset], []rune(c.text), runes[c.offset+c.length:])
+	}
+	if corrected := string(runes); corrected != query {
+		return corrected
+	}
+	return ""
+}
@@ -0,0 +1,288 @@
+package embedded
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// rrfRankConstant damps the contribution of lower ranks when text and
+// vector rankings are fused, as in Elasticsearch's default RRF.
+const rrfRankConstant = 60
+
+// FTS5 bm25 column weights, boosting fields as the Elasticsearch queries
+// do. Columns left out of a summary-scope search weigh nothing.
+const (
+	documentWeights = "3.0, 1.0, 2.0, 1.0, 2.0" // title, content, tags, summary, headings
+	summaryWeights  = "1.0, 0.0, 2.0, 1.0, 0.0"
+	chunkWeights    = "1.0, 1.0, 1.0, 2.0" // content, context, title, headings
+)
+
+// search describes a ranking of the pages or chunks of a store.
+type search struct {
+	table   string // "documents" or "chunks"
+	columns string // FTS column filter the query is restricted to, e.g. "{title summary}"; "" for all
+	weights string // bm25 weights of the FTS columns
+	where   string // Further condition on the table's rows, aliased t
+	args    []any
+	// text returns the title and text of an entry's JSON, which the
+	// filter's Exclude and Titles are matched against
+	text func(data string) (title, text string, err error)
+}
+
+// entry is a row of the documents or chunks table, as read for ranking.
+type entry struct {
+	id        string
+	url       string
+	source    models.Source
+	language  string
+	scrapedAt time.Time
+	labels    []string
+	data      string // JSON of the page or chunk, read only for text filters
+	embedding []float32
+}
+
+// SearchFiltered returns the documents best matching query, by BM25.
+func (s *Store) SearchFiltered(ctx context.Context, query string, limit int, filter elasticsearch.Filter) ([]models.Document, error) {
+	return s.HybridSearchFiltered(ctx, query, nil, limit, filter)
+}
+
+// HybridSearchFiltered returns the documents best matching query, fusing
+// the BM25 ranking with the ranking by cosine similarity of their
+// embeddings to queryEmbedding, if set, with reciprocal rank fusion.
+func (s *Store) HybridSearchFiltered(ctx context.Context, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter) ([]models.Document, error) {
+	ranked, err := s.rank(ctx, documentSearch(filter), query, queryEmbedding, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDocuments(ctx, ranked[:min(limit, len(ranked))])
+}
+
+// documentSearch returns the search of pages in the filter's scope.
+func documentSearch(filter elasticsearch.Filter) search {
+	q := search{table: "documents", weights: documentWeights, text: func(data string) (string, string, error) {
+		var doc models.Document
+		err := json.Unmarshal([]byte(data), &doc)
+		return doc.Title, documentText(doc), err
+	}}
+	if filter.Scope == elasticsearch.ScopeSummary {
+		q.columns, q.weights = "{title tags summary}", summaryWeights
+	}
+	return q
+}
+
+// SearchChunks returns the chunks best matching query, fused with their
+// ranking by embedding similarity if queryEmbedding is set.
+func (s *Store) SearchChunks(ctx context.Context, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter) ([]models.Chunk, error) {
+	return s.searchChunks(ctx, chunkSearch("", nil), query, queryEmbedding, limit, filter)
+}
+
+// SearchSections returns the chunks of one document best matching query.
+func (s *Store) SearchSections(ctx context.Context, documentID, query string, limit int, filter elasticsearch.Filter) ([]models.Chunk, error) {
+	return s.searchChunks(ctx, chunkSearch("t.document_id = ?", []any{documentID}), query, nil, limit, filter)
+}
+
+// chunkSearch returns the search of the chunks matching where.
+func chunkSearch(where string, args []any) search {
+	return search{table: "chunks", weights: chunkWeights, where: where, args: args, text: func(data string) (string, string, error) {
+		var chunk models.Chunk
+		err := json.Unmarshal([]byte(data), &chunk)
+		return chunk.Title, chunkText(chunk), err
+	}}
+}
+
+// searchChunks returns the chunks best matching query.
+func (s *Store) searchChunks(ctx context.Context, q search, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter) ([]models.Chunk, error) {
+	ranked, err := s.rank(ctx, q, query, queryEmbedding, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetChunks(ctx, ranked[:min(limit, len(ranked))])
+}
+
+// rank returns the IDs of the entries q covers that the filter accepts, by
+// BM25 score for query, fused with their ranking by embedding similarity
+// if queryEmbedding is set. An empty query ranks every accepted entry, in
+// ID order, so a search made only of exclusions lists what remains.
+func (s *Store) rank(ctx context.Context, q search, query string, queryEmbedding []float32, filter elasticsearch.Filter) ([]string, error) {
+	data := "''"
+	if len(filter.Exclude) > 0 || len(filter.Titles) > 0 {
+		data = "t.data"
+	}
+	columns := "t.id, t.url, t.source_name, t.source_host, t.language, t.scraped_at, t.access_labels, " + data
+	where := ""
+	if q.where != "" {
+		where = " AND " + q.where
+	}
+
+	var stmt string
+	var args []any
+	if expr := matchExpr(query); expr != "" {
+		if q.columns != "" {
+			expr = q.columns + " : (" + expr + ")"
+		}
+		stmt = "SELECT " + columns + ", NULL FROM " + q.table + "_fts JOIN " + q.table + " t ON t.rowid = " + q.table + "_fts.rowid" +
+			" WHERE " + q.table + "_fts MATCH ?" + where + " ORDER BY bm25(" + q.table + "_fts, " + q.weights + "), t.id"
+		args = append([]any{expr}, q.args...)
+	} else {
+		stmt = "SELECT " + columns + ", NULL FROM " + q.table + " t WHERE 1" + where + " ORDER BY t.id"
+		args = q.args
+	}
+
+	var ranked []string
+	err := s.scan(ctx, stmt, args, q, filter, func(e entry) {
+		ranked = append(ranked, e.id)
+	})
+	if err != nil || len(queryEmbedding) == 0 {
+		return ranked, err
+	}
+
+	similarity := make(map[string]float64)
+	stmt = "SELECT " + columns + ", t.embedding FROM " + q.table + " t WHERE t.embedding IS NOT NULL" + where
+	err = s.scan(ctx, stmt, q.args, q, filter, func(e entry) {
+		if len(e.embedding) == len(queryEmbedding) {
+			similarity[e.id] = cosine(queryEmbedding, e.embedding)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	byVector := make([]string, 0, len(similarity))
+	for id := range similarity {
+		byVector = append(byVector, id)
+	}
+	slices.SortFunc(byVector, func(a, b string) int {
+		return cmp.Or(cmp.Compare(similarity[b], similarity[a]), strings.Compare(a, b))
+	})
+	return fuse(ranked, byVector), nil
+}
+
+// scan runs stmt, which selects the columns of an entry, and calls fn
+// with each entry the filter accepts.
+func (s *Store) scan(ctx context.Context, stmt string, args []any, q search, filter elasticsearch.Filter, fn func(entry)) error {
+	return s.each(ctx, stmt, args, func(rows *sql.Rows) error {
+		var e entry
+		var scrapedAt, labels string
+		var embedding []byte
+		if err := rows.Scan(&e.id, &e.url, &e.source.Name, &e.source.Host, &e.language, &scrapedAt, &labels, &e.data, &embedding); err != nil {
+			return fmt.Errorf("failed to read store: %w", err)
+		}
+		e.scrapedAt = parseTime(scrapedAt)
+		json.Unmarshal([]byte(labels), &e.labels)
+		if !filter.Match(e.url, e.source, e.language, e.scrapedAt, e.labels) {
+			return nil
+		}
+		if e.data != "" {
+			title, text, err := q.text(e.data)
+			if err != nil {
+				return fmt.Errorf("failed to decode %s entry %s: %w", q.table, e.id, err)
+			}
+			if !matchesText(filter, title, text) {
+				return nil
+			}
+		}
+		if embedding != nil {
+			e.embedding = decodeVector(embedding)
+		}
+		fn(e)
+		return nil
+	})
+}
+
+// matchExpr returns the FTS5 query matching entries containing any word
+// of query, or "" if it has none. Words are quoted so none is read as an
+// FTS5 operator.
+func matchExpr(query string) string {
+	terms := tokenize(query)
+	for i, term := range terms {
+		terms[i] = `"` + term + `"`
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// tokenize returns the lowercase words of s.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// cosine returns the cosine similarity of a and b, which have the same
+// length.
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// fuse merges rankings with reciprocal rank fusion.
+func fuse(rankings ...[]string) []string {
+	scores := make(map[string]float64)
+	for _, ranking := range rankings {
+		for i, id := range ranking {
+			scores[id] += 1 / float64(rrfRankConstant+i+1)
+		}
+	}
+	fused := make([]string, 0, len(scores))
+	for id := range scores {
+		fused = append(fused, id)
+	}
+	slices.SortFunc(fused, func(a, b string) int {
+		return cmp.Or(cmp.Compare(scores[b], scores[a]), strings.Compare(a, b))
+	})
+	return fused
+}
+
+// documentText returns the text of a document that exclusions are
+// matched against.
+func documentText(doc models.Document) string {
+	return strings.Join([]string{doc.Title, doc.Content, strings.Join(doc.Tags, " "), doc.Summary}, "\n")
+}
+
+// chunkText returns the text of a chunk that exclusions are matched
+// against.
+func chunkText(chunk models.Chunk) string {
+	return strings.Join([]string{chunk.Title, chunk.Content, strings.Join(chunk.HeadingPath, " ")}, "\n")
+}
+
+// matchesText reports whether an entry passes the filter's Exclude and
+// Titles: it contains none of the excluded terms or phrases, and its title
+// contains every word of each title entry.
+func matchesText(filter elasticsearch.Filter, title, text string) bool {
+	if len(filter.Exclude) > 0 {
+		words := " " + strings.Join(tokenize(text), " ") + " "
+		for _, term := range filter.Exclude {
+			if phrase := strings.Join(tokenize(term), " "); phrase != "" && strings.Contains(words, " "+phrase+" ") {
+				return false
+			}
+		}
+	}
+	if len(filter.Titles) > 0 {
+		titleWords := tokenize(title)
+		for _, entry := range filter.Titles {
+			for _, word := range tokenize(entry) {
+				if !slices.Contains(titleWords, word) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
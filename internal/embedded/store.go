@@ -0,0 +1,528 @@
+// Package embedded is a search backend kept in a single local SQLite
+// database, for running bam-rag on small corpora without Elasticsearch.
+// Pages and chunks are matched with SQLite's FTS5 full-text index, ranked
+// by BM25, and compared by embedding similarity with a brute-force scan.
+//
+// FTS5 is compiled into SQLite with the sqlite_fts5 build tag, and the
+// driver needs cgo: build with CGO_ENABLED=1 and -tags sqlite_fts5.
+package embedded
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // registers the sqlite3 driver
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// ErrNoFTS5 is returned by Open when bam-rag was built without SQLite's
+// full-text index.
+var ErrNoFTS5 = errors.New("the embedded backend needs SQLite FTS5: build bam-rag with CGO_ENABLED=1 and -tags sqlite_fts5")
+
+// Store is an embedded index of pages and chunks. Every write is committed
+// to the database before it returns, and SQLite locks the file, so several
+// processes can share a store. Safe for concurrent use.
+type Store struct {
+	path string
+	db   *sql.DB
+	refs int // Callers of Open sharing the store; guarded by storesMu
+}
+
+// schema creates the tables of a new store. Entries are kept as JSON,
+// along with the fields filters apply to, and their text is indexed in
+// FTS5 tables sharing their rowid.
+const schema = `
+CREATE TABLE IF NOT EXISTS documents (
+	id            TEXT PRIMARY KEY,
+	url           TEXT NOT NULL,
+	title         TEXT NOT NULL,
+	source_name   TEXT NOT NULL,
+	source_host   TEXT NOT NULL,
+	language      TEXT NOT NULL,
+	scraped_at    TEXT NOT NULL,
+	access_labels TEXT NOT NULL,
+	content_hash  TEXT NOT NULL,
+	embedding     BLOB,
+	data          TEXT NOT NULL
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(title, content, tags, summary, headings);
+CREATE TABLE IF NOT EXISTS chunks (
+	id            TEXT PRIMARY KEY,
+	document_id   TEXT NOT NULL,
+	url           TEXT NOT NULL,
+	title         TEXT NOT NULL,
+	source_name   TEXT NOT NULL,
+	source_host   TEXT NOT NULL,
+	language      TEXT NOT NULL,
+	scraped_at    TEXT NOT NULL,
+	access_labels TEXT NOT NULL,
+	embedding     BLOB,
+	data          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS chunks_document_id ON chunks (document_id);
+CREATE VIRTUAL TABLE IF NOT EXISTS chunks_fts USING fts5(content, context, title, headings);
+`
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store)
+)
+
+// Open returns the store kept in the database at path, creating it if it
+// does not exist. Callers in one process share a store per path.
+func Open(path string) (*Store, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid store path %s: %w", path, err)
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[abs]; ok {
+		s.refs++
+		return s, nil
+	}
+
+	s, err := open(abs)
+	if err != nil {
+		return nil, err
+	}
+	s.refs = 1
+	stores[abs] = s
+	return s, nil
+}
+
+// Close releases the store. The database is closed once every caller of
+// Open sharing the store has closed it.
+func (s *Store) Close() error {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s.refs--; s.refs > 0 {
+		return nil
+	}
+	if stores[s.path] == s {
+		delete(stores, s.path)
+	}
+	return s.db.Close()
+}
+
+// open opens the database at path, with a connection of its own.
+func open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	// WAL lets searches read while another process writes; writers wait
+	// for each other rather than failing
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=10000&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return nil, ErrNoFTS5
+		}
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+
+	return &Store{path: path, db: db}, nil
+}
+
+// Ping reports whether the store's database can be reached.
+func (s *Store) Ping(ctx context.Context) bool {
+	return s.db.PingContext(ctx) == nil
+}
+
+// CreateIndex does nothing: Open creates the tables.
+func (s *Store) CreateIndex(ctx context.Context) error {
+	return nil
+}
+
+// DeleteIndex removes every page and chunk.
+func (s *Store) DeleteIndex(ctx context.Context) error {
+	return s.write(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"documents", "documents_fts", "chunks", "chunks_fts"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Refresh does nothing: writes are committed as they are made.
+func (s *Store) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// SetRefreshInterval does nothing: writes are searchable once committed.
+func (s *Store) SetRefreshInterval(ctx context.Context, interval string) (func(context.Context) error, error) {
+	return func(context.Context) error { return nil }, nil
+}
+
+// write runs fn in a transaction, committing it if fn succeeds.
+func (s *Store) write(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	return nil
+}
+
+// IndexDocument adds or replaces a page.
+func (s *Store) IndexDocument(ctx context.Context, doc models.Document) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+	labels, _ := json.Marshal(doc.AccessLabels)
+
+	return s.write(ctx, func(tx *sql.Tx) error {
+		if err := deleteEntries(ctx, tx, "documents", "id = ?", doc.ID); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO documents (id, url, title, source_name, source_host, language, scraped_at, access_labels, content_hash, embedding, data)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			doc.ID, doc.URL, doc.Title, doc.Source.Name, doc.Source.Host, doc.Language, formatTime(doc.ScrapedAt),
+			string(labels), doc.ContentHash, encodeVector(doc.Embedding), string(data))
+		if err != nil {
+			return err
+		}
+		rowid, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO documents_fts (rowid, title, content, tags, summary, headings) VALUES (?, ?, ?, ?, ?, ?)",
+			rowid, doc.Title, doc.Content, strings.Join(doc.Tags, " "), doc.Summary, headingText(doc.Outline))
+		return err
+	})
+}
+
+// IndexChunks replaces the chunks of a document.
+func (s *Store) IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error {
+	return s.write(ctx, func(tx *sql.Tx) error {
+		if err := deleteEntries(ctx, tx, "chunks", "document_id = ?", documentID); err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			data, err := json.Marshal(chunk)
+			if err != nil {
+				return fmt.Errorf("failed to encode chunk: %w", err)
+			}
+			labels, _ := json.Marshal(chunk.AccessLabels)
+			res, err := tx.ExecContext(ctx,
+				`INSERT OR REPLACE INTO chunks (id, document_id, url, title, source_name, source_host, language, scraped_at, access_labels, embedding, data)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				chunk.ID, chunk.DocumentID, chunk.URL, chunk.Title, chunk.Source.Name, chunk.Source.Host, chunk.Language,
+				formatTime(chunk.ScrapedAt), string(labels), encodeVector(chunk.Embedding), string(data))
+			if err != nil {
+				return err
+			}
+			rowid, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO chunks_fts (rowid, content, context, title, headings) VALUES (?, ?, ?, ?, ?)",
+				rowid, chunk.Content, chunk.Context, chunk.Title, strings.Join(chunk.HeadingPath, " "))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteDocument removes a document and its chunks.
+func (s *Store) DeleteDocument(ctx context.Context, id string) error {
+	_, err := s.DeleteDocuments(ctx, []string{id})
+	return err
+}
+
+// DeleteDocuments removes documents and their chunks, and returns how
+// many documents there were.
+func (s *Store) DeleteDocuments(ctx context.Context, ids []string) (int, error) {
+	deleted := 0
+	err := s.write(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM documents WHERE id = ?)", id).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				continue
+			}
+			if err := deleteEntries(ctx, tx, "documents", "id = ?", id); err != nil {
+				return err
+			}
+			if err := deleteEntries(ctx, tx, "chunks", "document_id = ?", id); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// Count returns the number of documents matching the filter.
+func (s *Store) Count(ctx context.Context, filter elasticsearch.Filter) (int, error) {
+	ids, err := s.rank(ctx, documentSearch(filter), "", nil, filter)
+	return len(ids), err
+}
+
+// MatchingIDs returns the IDs of the documents matching the filter.
+func (s *Store) MatchingIDs(ctx context.Context, filter elasticsearch.Filter) ([]string, error) {
+	return s.rank(ctx, documentSearch(filter), "", nil, filter)
+}
+
+// DeleteByFilter removes every document matching the filter, along with
+// its chunks, and returns how many documents were deleted. An empty filter
+// is rejected rather than deleting the whole store.
+func (s *Store) DeleteByFilter(ctx context.Context, filter elasticsearch.Filter) (int, error) {
+	if filter.IsZero() {
+		return 0, fmt.Errorf("refusing to delete with an empty filter")
+	}
+	ids, err := s.rank(ctx, documentSearch(filter), "", nil, filter)
+	if err != nil {
+		return 0, err
+	}
+	return s.DeleteDocuments(ctx, ids)
+}
+
+// ScrollDocuments streams every document in the store to fn, batchSize at
+// a time, in ID order. Documents are read whole; fields are accepted for
+// compatibility with the Elasticsearch client. Iteration stops at the
+// first error returned by fn.
+func (s *Store) ScrollDocuments(ctx context.Context, batchSize int, fn func(models.Document) error, fields ...string) error {
+	after := ""
+	for {
+		var batch []models.Document
+		err := s.each(ctx, "SELECT id, data FROM documents WHERE id > ? ORDER BY id LIMIT ?", []any{after, batchSize}, func(rows *sql.Rows) error {
+			var id, data string
+			if err := rows.Scan(&id, &data); err != nil {
+				return err
+			}
+			var doc models.Document
+			if err := json.Unmarshal([]byte(data), &doc); err != nil {
+				return fmt.Errorf("failed to decode document %s: %w", id, err)
+			}
+			batch = append(batch, doc)
+			after = id
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, doc := range batch {
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
+
+// deleteEntries removes the rows of table matching where, and their text
+// from its FTS table.
+func deleteEntries(ctx context.Context, tx *sql.Tx, table, where string, args ...any) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM "+table+"_fts WHERE rowid IN (SELECT rowid FROM "+table+" WHERE "+where+")", args...)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE "+where, args...)
+	return err
+}
+
+// ContentHashes returns the content hash of each stored document among
+// ids. Documents that are not stored, or were stored without a hash, are
+// left out.
+func (s *Store) ContentHashes(ctx context.Context, ids []string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := s.byIDs(ctx, "SELECT id, content_hash FROM documents", ids, func(rows *sql.Rows) error {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return err
+		}
+		if hash != "" {
+			hashes[id] = hash
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// GetDocument returns a document by ID, or nil if it does not exist.
+func (s *Store) GetDocument(ctx context.Context, id string) (*models.Document, error) {
+	docs, err := s.GetDocuments(ctx, []string{id})
+	if err != nil || len(docs) == 0 {
+		return nil, err
+	}
+	return &docs[0], nil
+}
+
+// GetDocuments returns the documents with the given IDs, in order.
+// Documents that do not exist are left out.
+func (s *Store) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	return lookup[models.Document](ctx, s, "documents", ids)
+}
+
+// GetChunk returns a chunk by ID, or nil if it does not exist.
+func (s *Store) GetChunk(ctx context.Context, id string) (*models.Chunk, error) {
+	chunks, err := s.GetChunks(ctx, []string{id})
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	return &chunks[0], nil
+}
+
+// GetChunks returns the chunks with the given IDs, in order. Chunks that
+// do not exist are left out.
+func (s *Store) GetChunks(ctx context.Context, ids []string) ([]models.Chunk, error) {
+	return lookup[models.Chunk](ctx, s, "chunks", ids)
+}
+
+// lookup returns the entries of table with the given IDs, in order.
+func lookup[T any](ctx context.Context, s *Store, table string, ids []string) ([]T, error) {
+	byID := make(map[string]T, len(ids))
+	err := s.byIDs(ctx, "SELECT id, data FROM "+table, ids, func(rows *sql.Rows) error {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return err
+		}
+		var entry T
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return fmt.Errorf("failed to decode %s entry %s: %w", table, id, err)
+		}
+		byID[id] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []T
+	for _, id := range ids {
+		if entry, ok := byID[id]; ok {
+			found = append(found, entry)
+		}
+	}
+	return found, nil
+}
+
+// maxVariables is how many IDs are looked up per query, below SQLite's
+// limit on bound parameters.
+const maxVariables = 500
+
+// byIDs runs query, a SELECT without a WHERE clause, for the rows with the
+// given IDs, calling fn for each.
+func (s *Store) byIDs(ctx context.Context, query string, ids []string, fn func(rows *sql.Rows) error) error {
+	for start := 0; start < len(ids); start += maxVariables {
+		batch := ids[start:min(start+maxVariables, len(ids))]
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		where := " WHERE id IN (?" + strings.Repeat(", ?", len(batch)-1) + ")"
+		if err := s.each(ctx, query+where, args, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// each runs query and calls fn for each row.
+func (s *Store) each(ctx context.Context, query string, args []any, fn func(rows *sql.Rows) error) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+	return nil
+}
+
+// headingText returns the text of every heading of an outline.
+func headingText(outline []models.Heading) string {
+	var texts []string
+	var walk func([]models.Heading)
+	walk = func(headings []models.Heading) {
+		for _, h := range headings {
+			texts = append(texts, h.Text)
+			walk(h.Children)
+		}
+	}
+	walk(outline)
+	return strings.Join(texts, "\n")
+}
+
+// formatTime encodes a scrape time; the zero time is "".
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTime decodes a time encoded by formatTime.
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// encodeVector encodes an embedding as little-endian float32s, or nil if
+// there is none.
+func encodeVector(v []float32) []byte {
+	if len(v) == 0 {
+		return nil
+	}
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+// decodeVector decodes an embedding encoded by encodeVector.
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
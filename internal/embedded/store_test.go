@@ -0,0 +1,300 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	ctx := context.Background()
+	s, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if errors.Is(err, ErrNoFTS5) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := []models.Document{
+		{ID: "goroutines", URL: "https://go.dev/doc/goroutines", Title: "Goroutines", Content: "Goroutines are lightweight threads.", Source: models.Source{Name: "go"}, Embedding: []float32{1, 0}},
+		{ID: "channels", URL: "https://go.dev/doc/channels", Title: "Channels", Content: "Channels connect goroutines.", Source: models.Source{Name: "go"}, Embedding: []float32{0, 1}},
+		{ID: "pods", URL: "https://k8s.io/docs/pods", Title: "Pods", Content: "Pods run containers.", Source: models.Source{Name: "k8s"}, ContentHash: "abc"},
+	}
+	for _, doc := range docs {
+		if err := s.IndexDocument(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunks := []models.Chunk{
+		{ID: "goroutines-0", DocumentID: "goroutines", Content: "Start a goroutine with go.", HeadingPath: []string{"Starting"}},
+		{ID: "goroutines-1", DocumentID: "goroutines", Content: "Wait for goroutines with a WaitGroup.", HeadingPath: []string{"Waiting"}},
+	}
+	if err := s.IndexChunks(ctx, "goroutines", chunks); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func ids(docs []models.Document) []string {
+	var ids []string
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+func TestStore_Search(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		query  string
+		filter elasticsearch.Filter
+		want   []string
+	}{
+		{name: "title ranks first", query: "goroutines", want: []string{"goroutines", "channels"}},
+		{name: "source filter", query: "goroutines", filter: elasticsearch.Filter{Sources: []string{"k8s"}}},
+		{name: "exclude", query: "goroutines", filter: elasticsearch.Filter{Exclude: []string{"lightweight threads"}}, want: []string{"channels"}},
+		{name: "title filter", query: "goroutines", filter: elasticsearch.Filter{Titles: []string{"channels"}}, want: []string{"channels"}},
+		{name: "no match", query: "kafka"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := s.SearchFiltered(ctx, tt.query, 10, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(docs); len(got) != len(tt.want) || len(got) > 0 && got[0] != tt.want[0] {
+				t.Errorf("SearchFiltered(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_HybridSearch(t *testing.T) {
+	s := testStore(t)
+
+	// Without text, pages rank by embedding similarity alone
+	docs, err := s.HybridSearchFiltered(context.Background(), "", []float32{0, 1}, 10, elasticsearch.Filter{Sources: []string{"go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(docs); len(got) != 2 || got[0] != "channels" {
+		t.Errorf("HybridSearchFiltered() without text = %v, want channels first", got)
+	}
+}
+
+func TestStore_Chunks(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	chunks, err := s.SearchSections(ctx, "goroutines", "waiting", 5, elasticsearch.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].ID != "goroutines-1" {
+		t.Errorf("SearchSections() = %+v, want goroutines-1", chunks)
+	}
+
+	// Replacing a document's chunks drops the old ones
+	if err := s.IndexChunks(ctx, "goroutines", []models.Chunk{{ID: "goroutines-new", DocumentID: "goroutines", Content: "Rewritten."}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetChunks(ctx, []string{"goroutines-0", "goroutines-new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "goroutines-new" {
+		t.Errorf("GetChunks() after reindex = %+v", got)
+	}
+}
+
+func TestStore_Persistence(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	if err := s.DeleteDocument(ctx, "channels"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening the database afresh, as another process would
+	reopened, err := open(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.db.Close()
+	docs, err := reopened.GetDocuments(ctx, []string{"goroutines", "channels", "pods"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(docs); len(got) != 2 || got[0] != "goroutines" || got[1] != "pods" {
+		t.Errorf("GetDocuments() after reload = %v, want [goroutines pods]", got)
+	}
+	hashes, err := reopened.ContentHashes(ctx, []string{"pods", "goroutines"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 || hashes["pods"] != "abc" {
+		t.Errorf("ContentHashes() = %v, want only pods", hashes)
+	}
+
+	if err := s.DeleteIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := s.GetDocument(ctx, "pods"); err != nil || doc != nil {
+		t.Errorf("GetDocument() after DeleteIndex = %v, %v", doc, err)
+	}
+}
+
+func TestStore_Suggest(t *testing.T) {
+	s := testStore(t)
+
+	tests := map[string]string{
+		"gorutines":          "goroutines",
+		"chanels and pods":   "channels and pods",
+		"Pods":               "",
+		"completely unknown": "",
+	}
+	for query, want := range tests {
+		got, err := s.Suggest(context.Background(), query, elasticsearch.Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Suggest(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	// Titles of pages the filter leaves out are not suggested
+	got, err := s.Suggest(context.Background(), "gorutines", elasticsearch.Filter{Sources: []string{"k8s"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("Suggest() outside the filter = %q, want none", got)
+	}
+}
+
+func TestStore_ConcurrentWriters(t *testing.T) {
+	s := testStore(t)
+	other, err := open(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.db.Close()
+	ctx := context.Background()
+
+	// Two handles on one file, as two processes sharing a store
+	var wg sync.WaitGroup
+	for i, store := range []*Store{s, other} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				doc := models.Document{ID: fmt.Sprintf("doc-%d-%d", i, j), URL: "https://example.com/", Title: "Concurrent"}
+				if err := store.IndexDocument(ctx, doc); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	count, err := s.Count(ctx, elasticsearch.Filter{URLPrefixes: []string{"https://example.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 40 {
+		t.Errorf("Count() = %d, want 40", count)
+	}
+}
+
+func TestStore_DeleteByFilter(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	if _, err := s.DeleteByFilter(ctx, elasticsearch.Filter{}); err == nil {
+		t.Error("DeleteByFilter() with an empty filter succeeded")
+	}
+	ids, err := s.MatchingIDs(ctx, elasticsearch.Filter{Sources: []string{"go"}})
+	if err != nil || len(ids) != 2 {
+		t.Errorf("MatchingIDs() = %v, %v, want 2 IDs", ids, err)
+	}
+	deleted, err := s.DeleteByFilter(ctx, elasticsearch.Filter{Sources: []string{"go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteByFilter() = %d, want 2", deleted)
+	}
+	if chunk, err := s.GetChunk(ctx, "goroutines-0"); err != nil || chunk != nil {
+		t.Errorf("GetChunk() after deleting its document = %v, %v", chunk, err)
+	}
+
+	var scrolled []string
+	err = s.ScrollDocuments(ctx, 1, func(doc models.Document) error {
+		scrolled = append(scrolled, doc.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scrolled) != 1 || scrolled[0] != "pods" {
+		t.Errorf("ScrollDocuments() = %v, want [pods]", scrolled)
+	}
+}
+
+func TestStore_Close(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.db")
+	first, err := Open(path)
+	if errors.Is(err, ErrNoFTS5) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("Open() of the same path returned different stores")
+	}
+
+	// The store stays open until every caller has closed it
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !second.Ping(ctx) {
+		t.Error("Ping() after one of two Close() calls = false, want true")
+	}
+	if err := second.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if second.Ping(ctx) {
+		t.Error("Ping() after the last Close() = true, want false")
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if reopened == first || !reopened.Ping(ctx) {
+		t.Error("Open() after Close() returned the closed store")
+	}
+}
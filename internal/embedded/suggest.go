@@ -0,0 +1,128 @@
+package embedded
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// maxEdits is the most edits a suggested term may be from the one it
+// replaces, as in Elasticsearch's term suggester.
+const maxEdits = 2
+
+// Suggest returns query with its terms that appear in no page title
+// replaced by the closest terms that do, for offering a correction when a
+// search finds nothing. Only titles of documents the filter matches are
+// drawn from. Returns "" if there is no correction.
+func (s *Store) Suggest(ctx context.Context, query string, filter elasticsearch.Filter) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return "", nil
+	}
+
+	vocabulary := make(map[string]bool)
+	stmt := "SELECT url, source_name, source_host, language, scraped_at, access_labels, title FROM documents"
+	err := s.each(ctx, stmt, nil, func(rows *sql.Rows) error {
+		var url, language, scrapedAt, labels, title string
+		var source models.Source
+		if err := rows.Scan(&url, &source.Name, &source.Host, &language, &scrapedAt, &labels, &title); err != nil {
+			return fmt.Errorf("failed to read store: %w", err)
+		}
+		var accessLabels []string
+		json.Unmarshal([]byte(labels), &accessLabels)
+		if !filter.Match(url, source, language, parseTime(scrapedAt), accessLabels) {
+			return nil
+		}
+		for _, term := range tokenize(title) {
+			vocabulary[term] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	corrected, changed := replaceWords(query, func(word string) string {
+		term := strings.ToLower(word)
+		if vocabulary[term] {
+			return word
+		}
+		return closest(term, vocabulary)
+	})
+	if !changed {
+		return "", nil
+	}
+	return corrected, nil
+}
+
+// replaceWords replaces each word of s with what replace returns for it,
+// keeping the text between words, and reports whether any word changed.
+func replaceWords(s string, replace func(word string) string) (string, bool) {
+	var b strings.Builder
+	changed := false
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if !isWord(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWord(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if r := replace(word); r != word && r != "" {
+			b.WriteString(r)
+			changed = true
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+	return b.String(), changed
+}
+
+// closest returns the term of vocabulary fewest edits from term, within
+// maxEdits, preferring the alphabetically first on ties, or "".
+func closest(term string, vocabulary map[string]bool) string {
+	best, bestEdits := "", maxEdits+1
+	for candidate := range vocabulary {
+		edits := levenshtein(term, candidate)
+		if edits < bestEdits || edits == bestEdits && candidate < best {
+			best, bestEdits = candidate, edits
+		}
+	}
+	if bestEdits > maxEdits {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -9,18 +9,45 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/modelrunner"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // Config holds embeddings client configuration.
 type Config struct {
 	SocketPath string // Unix socket path for Docker Model Runner
 	Model      string // Model name (e.g., "ai/embeddinggemma")
+
+	// Connection tuning; zero values use the defaults below
+	MaxIdleConns    int           // Idle connections kept open for reuse
+	IdleConnTimeout time.Duration // How long an unused connection is kept open
+	Timeout         time.Duration // Limit on a single request, including reading the response
+
+	// Limiter is shared with the other clients of the same Docker Model
+	// Runner so their requests take turns; nil sends requests unrestricted
+	Limiter *modelrunner.Limiter
 }
 
+// Connection defaults. Long ingestions keep reusing connections to the
+// socket rather than redialing, which net/http's default of two idle
+// connections per host does not allow once requests overlap.
+const (
+	defaultMaxIdleConns    = 8
+	defaultIdleConnTimeout = 90 * time.Second
+	defaultTimeout         = 2 * time.Minute
+)
+
 // Client wraps the Docker Model Runner embeddings API.
 type Client struct {
 	httpClient *http.Client
 	model      string
+	limiter    *modelrunner.Limiter
+	tokens     atomic.Int64 // Input tokens reported by the model
 }
 
 // New creates a new embeddings client.
@@ -32,15 +59,30 @@ func New(config Config) (*Client, error) {
 		return nil, fmt.Errorf("model is required")
 	}
 
+	if config.MaxIdleConns == 0 {
+		config.MaxIdleConns = defaultMaxIdleConns
+	}
+	if config.IdleConnTimeout == 0 {
+		config.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	var dialer net.Dialer
 	transport := &http.Transport{
 		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-			return net.Dial("unix", config.SocketPath)
+			return dialer.DialContext(ctx, "unix", config.SocketPath)
 		},
+		MaxIdleConns:        config.MaxIdleConns,
+		MaxIdleConnsPerHost: config.MaxIdleConns,
+		IdleConnTimeout:     config.IdleConnTimeout,
 	}
 
 	return &Client{
-		httpClient: &http.Client{Transport: transport},
+		httpClient: &http.Client{Transport: transport, Timeout: config.Timeout},
 		model:      config.Model,
+		limiter:    config.Limiter,
 	}, nil
 }
 
@@ -55,6 +97,9 @@ type embeddingResponse struct {
 	Data []struct {
 		Embedding []float32 `json:"embedding"`
 	} `json:"data"`
+	Usage *struct {
+		PromptTokens int `json:"prompt_tokens"`
+	} `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
@@ -67,7 +112,10 @@ const MaxInputChars = 20000
 
 // Embed generates an embedding vector for the given text.
 // Text exceeding MaxInputChars is truncated from the end.
-func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+func (c *Client) Embed(ctx context.Context, text string) (embedding []float32, err error) {
+	ctx, span := telemetry.Start(ctx, "embeddings.embed", attribute.String("model", c.model), attribute.Int("input_chars", len(text)))
+	defer func() { telemetry.End(span, err) }()
+
 	originalLen := len(text)
 	// Truncate to avoid context window overflow
 	if len(text) > MaxInputChars {
@@ -88,6 +136,12 @@ func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	telemetry.InjectHeader(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	if err := c.limiter.Acquire(ctx, c.model); err != nil {
+		return nil, err
+	}
+	defer c.limiter.Release()
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -113,6 +167,10 @@ func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 		return nil, fmt.Errorf("API error: %s", embResp.Error.Message)
 	}
 
+	if embResp.Usage != nil {
+		c.tokens.Add(int64(embResp.Usage.PromptTokens))
+	}
+
 	if len(embResp.Data) == 0 {
 		return nil, fmt.Errorf("no embedding returned")
 	}
@@ -120,6 +178,20 @@ func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 	return embResp.Data[0].Embedding, nil
 }
 
+// Warmup sends a minimal embedding request so the model is loaded before
+// the first real document arrives. Repeated calls keep an idle model from
+// being evicted.
+func (c *Client) Warmup(ctx context.Context) error {
+	_, err := c.Embed(ctx, "warmup")
+	return err
+}
+
+// Usage returns the input tokens the model reported embedding since the
+// client was created. Responses without usage information are not counted.
+func (c *Client) Usage() int {
+	return int(c.tokens.Load())
+}
+
 // Dimensions returns the expected embedding dimensions for common models.
 func Dimensions(model string) int {
 	switch model {
@@ -8,7 +8,9 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNew_Validation(t *testing.T) {
@@ -83,6 +85,9 @@ func TestEmbed_Success(t *testing.T) {
 		}{
 			{Embedding: mockEmbedding},
 		},
+		Usage: &struct {
+			PromptTokens int `json:"prompt_tokens"`
+		}{PromptTokens: 3},
 	}
 
 	// Start mock server
@@ -125,6 +130,10 @@ func TestEmbed_Success(t *testing.T) {
 			t.Errorf("Embed()[%d] = %v, want %v", i, v, mockEmbedding[i])
 		}
 	}
+
+	if got := client.Usage(); got != 3 {
+		t.Errorf("Usage() = %d, want 3", got)
+	}
 }
 
 func TestEmbed_ServerError(t *testing.T) {
@@ -231,3 +240,66 @@ func TestEmbed_Integration(t *testing.T) {
 
 // Unused but kept for reference
 var _ = httptest.NewServer
+
+func TestEmbed_ReusesConnections(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create Unix socket: %v", err)
+	}
+	defer listener.Close()
+
+	var conns atomic.Int32
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"data": [{"embedding": [0.1]}]}`))
+		}),
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		},
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client, err := New(Config{SocketPath: socketPath, Model: "test-model"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for range 5 {
+		if _, err := client.Embed(context.Background(), "test text"); err != nil {
+			t.Fatalf("Embed() error = %v", err)
+		}
+	}
+	if got := conns.Load(); got != 1 {
+		t.Errorf("opened %d connections for sequential requests, want 1", got)
+	}
+}
+
+func TestEmbed_Timeout(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create Unix socket: %v", err)
+	}
+	defer listener.Close()
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client, err := New(Config{SocketPath: socketPath, Model: "test-model", Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.Embed(context.Background(), "test text"); err == nil {
+		t.Error("Embed() expected error when the request times out")
+	}
+}
@@ -0,0 +1,176 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Query is a labeled query: the documents a good search should return.
+type Query struct {
+	Query    string   `yaml:"query" json:"query"`
+	Relevant []string `yaml:"relevant" json:"relevant"` // Relevant document URLs or IDs
+}
+
+// LoadQueries reads a golden query file.
+//
+//	queries:
+//	  - query: how to install go
+//	    relevant:
+//	      - https://go.dev/doc/install
+func LoadQueries(path string) ([]Query, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries: %w", err)
+	}
+
+	var file struct {
+		Queries []Query `yaml:"queries"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse queries: %w", err)
+	}
+
+	for i, q := range file.Queries {
+		if q.Query == "" {
+			return nil, fmt.Errorf("query %d: query text is required", i+1)
+		}
+		if len(q.Relevant) == 0 {
+			return nil, fmt.Errorf("query %d (%q): at least one relevant document is required", i+1, q.Query)
+		}
+	}
+
+	return file.Queries, nil
+}
+
+// SearchFunc runs a query and returns ranked documents.
+type SearchFunc func(ctx context.Context, query string, limit int) ([]models.Document, error)
+
+// QueryResult holds the metrics for a single query.
+type QueryResult struct {
+	Query     string  `json:"query"`
+	Recall    float64 `json:"recall"`
+	RR        float64 `json:"reciprocal_rank"`
+	NDCG      float64 `json:"ndcg"`
+	FirstRank int     `json:"first_relevant_rank"` // 1-based, 0 if none retrieved
+}
+
+// Report aggregates metrics over a query set for one search method.
+type Report struct {
+	Method  string        `json:"method"`
+	K       int           `json:"k"`
+	Recall  float64       `json:"recall_at_k"`
+	MRR     float64       `json:"mrr"`
+	NDCG    float64       `json:"ndcg_at_k"`
+	Queries []QueryResult `json:"queries"`
+}
+
+// Run evaluates search over every query, retrieving the top k results.
+func Run(ctx context.Context, method string, queries []Query, k int, search SearchFunc) (*Report, error) {
+	report := &Report{Method: method, K: k}
+
+	for _, q := range queries {
+		docs, err := search(ctx, q.Query, k)
+		if err != nil {
+			return nil, fmt.Errorf("search %q failed: %w", q.Query, err)
+		}
+
+		hits := Judge(docs, q.Relevant)
+		result := QueryResult{
+			Query:     q.Query,
+			Recall:    RecallAtK(hits, len(q.Relevant), k),
+			RR:        ReciprocalRank(hits),
+			NDCG:      NDCGAtK(hits, len(q.Relevant), k),
+			FirstRank: firstRank(hits),
+		}
+		report.Queries = append(report.Queries, result)
+
+		report.Recall += result.Recall
+		report.MRR += result.RR
+		report.NDCG += result.NDCG
+	}
+
+	if n := float64(len(queries)); n > 0 {
+		report.Recall /= n
+		report.MRR /= n
+		report.NDCG /= n
+	}
+
+	return report, nil
+}
+
+// Judge marks each ranked document as relevant or not. A document matches
+// a label if the label equals its URL or ID. Each label counts once.
+func Judge(docs []models.Document, relevant []string) []bool {
+	remaining := make(map[string]bool, len(relevant))
+	for _, r := range relevant {
+		remaining[r] = true
+	}
+
+	hits := make([]bool, len(docs))
+	for i, doc := range docs {
+		for _, key := range []string{doc.URL, doc.ID} {
+			if remaining[key] {
+				hits[i] = true
+				delete(remaining, key)
+				break
+			}
+		}
+	}
+	return hits
+}
+
+// RecallAtK is the fraction of relevant documents found in the top k.
+func RecallAtK(hits []bool, relevant, k int) float64 {
+	if relevant == 0 {
+		return 0
+	}
+	found := 0
+	for i := 0; i < len(hits) && i < k; i++ {
+		if hits[i] {
+			found++
+		}
+	}
+	return float64(found) / float64(relevant)
+}
+
+// ReciprocalRank is 1/rank of the first relevant document, or 0 if none.
+func ReciprocalRank(hits []bool) float64 {
+	if rank := firstRank(hits); rank > 0 {
+		return 1 / float64(rank)
+	}
+	return 0
+}
+
+// NDCGAtK is the binary-relevance normalized discounted cumulative gain of the top k.
+func NDCGAtK(hits []bool, relevant, k int) float64 {
+	var dcg float64
+	for i := 0; i < len(hits) && i < k; i++ {
+		if hits[i] {
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+
+	var ideal float64
+	for i := 0; i < relevant && i < k; i++ {
+		ideal += 1 / math.Log2(float64(i+2))
+	}
+	if ideal == 0 {
+		return 0
+	}
+	return dcg / ideal
+}
+
+// firstRank returns the 1-based rank of the first hit, or 0 if there is none.
+func firstRank(hits []bool) int {
+	for i, hit := range hits {
+		if hit {
+			return i + 1
+		}
+	}
+	return 0
+}
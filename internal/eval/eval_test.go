@@ -0,0 +1,111 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name       string
+		hits       []bool
+		relevant   int
+		k          int
+		wantRecall float64
+		wantRR     float64
+		wantNDCG   float64
+	}{
+		{"perfect", []bool{true, true, false}, 2, 3, 1, 1, 1},
+		{"none found", []bool{false, false}, 1, 2, 0, 0, 0},
+		{"second rank", []bool{false, true}, 1, 2, 1, 0.5, 1 / math.Log2(3)},
+		{"beyond k", []bool{false, false, true}, 1, 2, 0, 1.0 / 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecallAtK(tt.hits, tt.relevant, tt.k); !almostEqual(got, tt.wantRecall) {
+				t.Errorf("RecallAtK() = %v, want %v", got, tt.wantRecall)
+			}
+			if got := ReciprocalRank(tt.hits); !almostEqual(got, tt.wantRR) {
+				t.Errorf("ReciprocalRank() = %v, want %v", got, tt.wantRR)
+			}
+			if got := NDCGAtK(tt.hits, tt.relevant, tt.k); !almostEqual(got, tt.wantNDCG) {
+				t.Errorf("NDCGAtK() = %v, want %v", got, tt.wantNDCG)
+			}
+		})
+	}
+}
+
+func TestJudge_MatchesURLOrIDOnce(t *testing.T) {
+	docs := []models.Document{
+		{ID: "a", URL: "https://example.com/a"},
+		{ID: "b", URL: "https://example.com/b"},
+		{ID: "a", URL: "https://example.com/a"}, // duplicate must not count twice
+	}
+
+	hits := Judge(docs, []string{"https://example.com/a", "b"})
+
+	want := []bool{true, true, false}
+	for i := range want {
+		if hits[i] != want[i] {
+			t.Errorf("Judge()[%d] = %v, want %v", i, hits[i], want[i])
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	queries := []Query{
+		{Query: "install", Relevant: []string{"https://example.com/install"}},
+		{Query: "missing", Relevant: []string{"https://example.com/nowhere"}},
+	}
+	search := func(ctx context.Context, query string, limit int) ([]models.Document, error) {
+		return []models.Document{{URL: "https://example.com/other"}, {URL: "https://example.com/install"}}, nil
+	}
+
+	report, err := Run(t.Context(), "bm25", queries, 10, search)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !almostEqual(report.Recall, 0.5) {
+		t.Errorf("Recall = %v, want 0.5", report.Recall)
+	}
+	if !almostEqual(report.MRR, 0.25) {
+		t.Errorf("MRR = %v, want 0.25", report.MRR)
+	}
+	if report.Queries[0].FirstRank != 2 {
+		t.Errorf("FirstRank = %d, want 2", report.Queries[0].FirstRank)
+	}
+}
+
+func TestLoadQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.yaml")
+	data := "queries:\n  - query: how to install\n    relevant:\n      - https://go.dev/doc/install\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	queries, err := LoadQueries(path)
+	if err != nil {
+		t.Fatalf("LoadQueries() error = %v", err)
+	}
+	if len(queries) != 1 || queries[0].Relevant[0] != "https://go.dev/doc/install" {
+		t.Errorf("LoadQueries() = %+v", queries)
+	}
+
+	if err := os.WriteFile(path, []byte("queries:\n  - query: no labels\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadQueries(path); err == nil {
+		t.Error("LoadQueries() should reject queries without relevant documents")
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
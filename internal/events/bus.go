@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Subjects events are published on.
+const (
+	SubjectScrapeComplete    = "bam-rag.scrape.complete"
+	SubjectIngestionComplete = "bam-rag.ingestion.complete"
+)
+
+// Handler processes one message. Returning an error asks the bus to
+// redeliver the message if it supports redelivery.
+type Handler func(ctx context.Context, data []byte) error
+
+// Bus carries encoded events between producers and consumers, which may
+// live in separate processes depending on the implementation.
+type Bus interface {
+	// Publish sends data on subject.
+	Publish(ctx context.Context, subject string, data []byte) error
+
+	// Subscribe calls handler for every message on subject until ctx is
+	// cancelled or the bus is closed. Consumers subscribed to the same
+	// subject share its messages, so each is handled once.
+	Subscribe(ctx context.Context, subject string, handler Handler) error
+
+	// Close releases the bus. Running subscriptions return.
+	Close() error
+}
+
+// Namespaced returns a bus that scopes the subjects of bus to a namespace:
+// bam-rag.scrape.complete becomes bam-rag.<ns>.scrape.complete. Workers of
+// one namespace then never consume another's events. An empty ns returns
+// bus itself.
+func Namespaced(bus Bus, ns string) Bus {
+	if ns == "" {
+		return bus
+	}
+	return &namespacedBus{Bus: bus, ns: ns}
+}
+
+// namespacedBus rewrites subjects into its namespace.
+type namespacedBus struct {
+	Bus
+	ns string
+}
+
+// subject returns subject scoped to the namespace.
+func (b *namespacedBus) subject(subject string) string {
+	if rest, ok := strings.CutPrefix(subject, "bam-rag."); ok {
+		return "bam-rag." + b.ns + "." + rest
+	}
+	return b.ns + "." + subject
+}
+
+func (b *namespacedBus) Publish(ctx context.Context, subject string, data []byte) error {
+	return b.Bus.Publish(ctx, b.subject(subject), data)
+}
+
+func (b *namespacedBus) Subscribe(ctx context.Context, subject string, handler Handler) error {
+	return b.Bus.Subscribe(ctx, b.subject(subject), handler)
+}
+
+// PublishScrapeComplete publishes a ScrapeCompleteEvent.
+func PublishScrapeComplete(ctx context.Context, bus Bus, event ScrapeCompleteEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return bus.Publish(ctx, SubjectScrapeComplete, data)
+}
+
+// SubscribeScrapeComplete calls handler for every ScrapeCompleteEvent.
+// Malformed messages are logged and dropped. Events of a newer schema
+// version fail with ErrUnsupportedSchema instead, so the bus keeps them for
+// a consumer that can read them, e.g. during a rolling upgrade.
+func SubscribeScrapeComplete(ctx context.Context, bus Bus, handler func(context.Context, ScrapeCompleteEvent) error) error {
+	return bus.Subscribe(ctx, SubjectScrapeComplete, func(ctx context.Context, data []byte) error {
+		var event ScrapeCompleteEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			if errors.Is(err, ErrUnsupportedSchema) {
+				return err
+			}
+			slog.Warn("dropping malformed scrape event", "error", err)
+			return nil
+		}
+		return handler(ctx, event)
+	})
+}
+
+// PublishIngestionComplete publishes an IngestionCompleteEvent.
+func PublishIngestionComplete(ctx context.Context, bus Bus, event IngestionCompleteEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return bus.Publish(ctx, SubjectIngestionComplete, data)
+}
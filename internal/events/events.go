@@ -1,20 +1,107 @@
 package events
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the event JSON written by this build.
+// Every encoded event carries it as "schema_version". Adding optional
+// fields keeps the version; renaming, removing, or changing the meaning
+// of a field bumps it, so consumers in other processes or languages can
+// tell which layout they were sent.
+const SchemaVersion = 1
+
+// ErrUnsupportedSchema is returned when decoding an event written with a
+// newer schema version than this build understands.
+var ErrUnsupportedSchema = errors.New("unsupported event schema version")
 
 // ScrapeCompleteEvent is sent when scraper finishes writing to S3.
 type ScrapeCompleteEvent struct {
-	Bucket    string    // S3 bucket name (e.g., "bam-rag")
-	Prefix    string    // S3 prefix (e.g., "scrapes/go.dev/2024-12-04T17-30-00-abc123")
-	SourceURL string    // Original URL that was scraped
-	PageCount int       // Number of pages scraped
-	Timestamp time.Time // When the scrape completed
+	Bucket    string    `json:"bucket"`           // S3 bucket name (e.g., "bam-rag")
+	Prefix    string    `json:"prefix"`           // S3 prefix (e.g., "scrapes/go.dev/2024-12-04T17-30-00-abc123")
+	SourceURL string    `json:"source_url"`       // Original URL that was scraped
+	Source    string    `json:"source,omitempty"` // Config source name; empty for ad-hoc --url scrapes
+	PageCount int       `json:"page_count"`       // Number of pages scraped
+	Timestamp time.Time `json:"timestamp"`        // When the scrape completed (RFC 3339)
+
+	// Traceparent is the W3C trace context of the scrape, so ingestion
+	// spans join its trace. Empty when tracing is disabled.
+	Traceparent string `json:"traceparent,omitempty"`
+
+	// Actor is who the scrape was run for when not whoever runs the
+	// scraper, e.g. webhook:<source> for a triggered scrape, so the
+	// ingestion is audited as theirs too.
+	Actor string `json:"actor,omitempty"`
 }
 
 // IngestionCompleteEvent is sent when ingestion finishes indexing.
 type IngestionCompleteEvent struct {
-	Prefix      string        // S3 prefix that was ingested
-	DocsIndexed int           // Number of documents indexed
-	Duration    time.Duration // How long ingestion took
-	Errors      []string      // Any errors encountered (non-fatal)
+	Prefix      string        `json:"prefix"`           // S3 prefix that was ingested
+	DocsIndexed int           `json:"docs_indexed"`     // Number of documents indexed
+	Duration    time.Duration `json:"duration"`         // How long ingestion took, in nanoseconds
+	Errors      []string      `json:"errors,omitempty"` // Any errors encountered (non-fatal)
+}
+
+// MarshalJSON encodes the event with its schema version.
+func (e ScrapeCompleteEvent) MarshalJSON() ([]byte, error) {
+	type plain ScrapeCompleteEvent
+	return json.Marshal(struct {
+		SchemaVersion int `json:"schema_version"`
+		plain
+	}{SchemaVersion, plain(e)})
+}
+
+// UnmarshalJSON decodes an event of a supported schema version.
+func (e *ScrapeCompleteEvent) UnmarshalJSON(data []byte) error {
+	type plain ScrapeCompleteEvent
+	var v struct {
+		SchemaVersion int `json:"schema_version"`
+		plain
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(v.SchemaVersion); err != nil {
+		return err
+	}
+	*e = ScrapeCompleteEvent(v.plain)
+	return nil
+}
+
+// MarshalJSON encodes the event with its schema version.
+func (e IngestionCompleteEvent) MarshalJSON() ([]byte, error) {
+	type plain IngestionCompleteEvent
+	return json.Marshal(struct {
+		SchemaVersion int `json:"schema_version"`
+		plain
+	}{SchemaVersion, plain(e)})
+}
+
+// UnmarshalJSON decodes an event of a supported schema version.
+func (e *IngestionCompleteEvent) UnmarshalJSON(data []byte) error {
+	type plain IngestionCompleteEvent
+	var v struct {
+		SchemaVersion int `json:"schema_version"`
+		plain
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(v.SchemaVersion); err != nil {
+		return err
+	}
+	*e = IngestionCompleteEvent(v.plain)
+	return nil
+}
+
+// checkSchemaVersion accepts versions up to SchemaVersion. Events without
+// a version predate versioning and share the layout of version 1.
+func checkSchemaVersion(version int) error {
+	if version > SchemaVersion {
+		return fmt.Errorf("%w %d (this build reads up to %d)", ErrUnsupportedSchema, version, SchemaVersion)
+	}
+	return nil
 }
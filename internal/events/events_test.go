@@ -0,0 +1,94 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScrapeCompleteEvent_JSON(t *testing.T) {
+	event := ScrapeCompleteEvent{
+		Bucket:    "bam-rag",
+		Prefix:    "scrapes/go.dev/2024-12-04T17-30-00-abc123",
+		SourceURL: "https://go.dev/doc/",
+		Source:    "go-docs",
+		PageCount: 42,
+		Timestamp: time.Date(2024, 12, 4, 17, 30, 0, 0, time.UTC),
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"schema_version":1,"bucket":"bam-rag","prefix":"scrapes/go.dev/2024-12-04T17-30-00-abc123",` +
+		`"source_url":"https://go.dev/doc/","source":"go-docs","page_count":42,"timestamp":"2024-12-04T17:30:00Z"}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s\nwant %s", data, want)
+	}
+
+	var got ScrapeCompleteEvent
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got != event {
+		t.Errorf("round trip = %+v, want %+v", got, event)
+	}
+}
+
+func TestIngestionCompleteEvent_JSON(t *testing.T) {
+	event := IngestionCompleteEvent{Prefix: "scrapes/go.dev/1", DocsIndexed: 3, Duration: 2 * time.Second, Errors: []string{"a.md: empty"}}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"schema_version":1,"prefix":"scrapes/go.dev/1","docs_indexed":3,"duration":2000000000,"errors":["a.md: empty"]}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s\nwant %s", data, want)
+	}
+
+	var got IngestionCompleteEvent
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Prefix != event.Prefix || got.DocsIndexed != 3 || got.Duration != event.Duration || len(got.Errors) != 1 {
+		t.Errorf("round trip = %+v, want %+v", got, event)
+	}
+}
+
+func TestEventSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"current", `{"schema_version":1,"prefix":"scrapes/a/1"}`, nil},
+		{"unversioned", `{"prefix":"scrapes/a/1"}`, nil},
+		{"newer", `{"schema_version":2,"prefix":"scrapes/a/1"}`, ErrUnsupportedSchema},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scrape ScrapeCompleteEvent
+			err := json.Unmarshal([]byte(tt.data), &scrape)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unmarshal(ScrapeCompleteEvent) error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && scrape.Prefix != "scrapes/a/1" {
+				t.Errorf("Prefix = %q", scrape.Prefix)
+			}
+
+			var ingestion IngestionCompleteEvent
+			if err := json.Unmarshal([]byte(tt.data), &ingestion); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Unmarshal(IngestionCompleteEvent) error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var event ScrapeCompleteEvent
+	err := json.Unmarshal([]byte(`{"schema_version":2}`), &event)
+	if err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("error = %v, want it to name the version", err)
+	}
+}
@@ -0,0 +1,247 @@
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// KafkaConfig holds Kafka connection configuration.
+type KafkaConfig struct {
+	Brokers     []string      // Seed brokers, e.g. localhost:9092
+	Group       string        // Consumer group prefix; defaults to "bam-rag"
+	TopicPrefix string        // Prepended to event subjects to form topic names
+	Username    string        // Optional SASL auth
+	Password    string        //
+	Mechanism   string        // SASL mechanism: plain (default), scram-sha-256, or scram-sha-512
+	TLS         bool          // Connect to the brokers over TLS
+	Timeout     time.Duration // Dial and request timeout; defaults to 30s
+}
+
+// kafkaMaxAttempts is how often a failing record is handled before it is
+// skipped.
+const kafkaMaxAttempts = 5
+
+// kafkaRetryBackoff is the delay before the first retry of a failed record;
+// later retries wait proportionally longer.
+var kafkaRetryBackoff = time.Second
+
+// KafkaBus is a Bus backed by Kafka topics. Each subject maps to a topic.
+// Subscribers of a subject join one consumer group, so each record is
+// handled by one of them. Offsets are committed only after the handler
+// succeeds, so records are redelivered if the process exits first; a
+// failing record is retried with backoff and skipped after kafkaMaxAttempts.
+// A record of a newer schema version ends the subscription instead, so it
+// stays in the partition for an upgraded consumer.
+type KafkaBus struct {
+	config   KafkaConfig
+	opts     []kgo.Opt
+	producer *kgo.Client
+
+	closed    chan struct{} // Closed by Close; running subscriptions return
+	closeOnce sync.Once
+}
+
+// NewKafkaBus connects to the brokers and checks that they are reachable.
+func NewKafkaBus(ctx context.Context, config KafkaConfig) (*KafkaBus, error) {
+	if config.Group == "" {
+		config.Group = "bam-rag"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	opts, err := kafkaOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := kgo.NewClient(append(opts, kgo.AllowAutoTopicCreation())...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	if err := producer.Ping(pingCtx); err != nil {
+		producer.Close()
+		return nil, fmt.Errorf("failed to reach Kafka brokers: %w", err)
+	}
+	return &KafkaBus{config: config, opts: opts, producer: producer, closed: make(chan struct{})}, nil
+}
+
+// kafkaOptions returns the client options shared by producers and consumers.
+func kafkaOptions(config KafkaConfig) ([]kgo.Opt, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("no Kafka brokers configured")
+	}
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.ClientID("bam-rag"),
+		kgo.DialTimeout(config.Timeout),
+		kgo.RequestTimeoutOverhead(config.Timeout),
+	}
+	if config.TLS {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+	if config.Username != "" {
+		mechanism, err := kafkaMechanism(config)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	return opts, nil
+}
+
+// kafkaMechanism returns the SASL mechanism authenticating with the
+// configured credentials.
+func kafkaMechanism(config KafkaConfig) (sasl.Mechanism, error) {
+	switch config.Mechanism {
+	case "", "plain":
+		return plain.Auth{User: config.Username, Pass: config.Password}.AsMechanism(), nil
+	case "scram-sha-256":
+		return scram.Auth{User: config.Username, Pass: config.Password}.AsSha256Mechanism(), nil
+	case "scram-sha-512":
+		return scram.Auth{User: config.Username, Pass: config.Password}.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("unknown SASL mechanism %q", config.Mechanism)
+	}
+}
+
+// topic returns the topic carrying subject.
+func (b *KafkaBus) topic(subject string) string {
+	return b.config.TopicPrefix + subject
+}
+
+// Publish appends data to the subject's topic and waits until the brokers
+// have stored it.
+func (b *KafkaBus) Publish(ctx context.Context, subject string, data []byte) error {
+	select {
+	case <-b.closed:
+		return ErrClosed
+	default:
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+
+	record, err := b.producer.ProduceSync(ctx, &kgo.Record{Topic: b.topic(subject), Value: data}).First()
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", b.topic(subject), err)
+	}
+	slog.Debug("published event", "topic", record.Topic, "partition", record.Partition, "offset", record.Offset)
+	return nil
+}
+
+// Subscribe consumes the subject's topic as a member of the subject's
+// consumer group. Returns nil when ctx is cancelled or the bus closed.
+func (b *KafkaBus) Subscribe(ctx context.Context, subject string, handler Handler) error {
+	group := b.config.Group + "-" + consumerName(subject)
+	consumer, err := kgo.NewClient(append(b.opts,
+		kgo.ConsumerGroup(group),
+		kgo.ConsumeTopics(b.topic(subject)),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.DisableAutoCommit(),
+		kgo.AllowAutoTopicCreation(),
+	)...)
+	if err != nil {
+		return fmt.Errorf("failed to join consumer group %s: %w", group, err)
+	}
+	// Closing leaves the group so its partitions are reassigned right away
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-b.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for ctx.Err() == nil {
+		fetches := consumer.PollFetches(ctx)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			break
+		}
+		for _, fetchErr := range fetches.Errors() {
+			if errors.Is(fetchErr.Err, context.Canceled) {
+				continue
+			}
+			return fmt.Errorf("failed to poll %s: %w", fetchErr.Topic, fetchErr.Err)
+		}
+
+		for _, record := range fetches.Records() {
+			if err := b.handle(ctx, record, handler); err != nil {
+				if ctx.Err() != nil {
+					// Interrupted; leave the record to the next consumer
+					return nil
+				}
+				// Records of a partition are handled in order, so stop
+				// consuming rather than skip it
+				return fmt.Errorf("record %d of %s: %w", record.Offset, record.Topic, err)
+			}
+			b.commit(ctx, consumer, record)
+		}
+	}
+	return nil
+}
+
+// handle runs handler for record, retrying failures with backoff. A record
+// that keeps failing is skipped, except one of a newer schema version, which
+// is left uncommitted for an upgraded consumer: that error is returned, as
+// is ctx's if it is cancelled first.
+func (b *KafkaBus) handle(ctx context.Context, record *kgo.Record, handler Handler) error {
+	for attempt := 1; ; attempt++ {
+		err := handler(ctx, record.Value)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrUnsupportedSchema) {
+			return err
+		}
+		if attempt >= kafkaMaxAttempts {
+			slog.Error("event handler failed, skipping record", "topic", record.Topic, "partition", record.Partition, "offset", record.Offset, "attempts", attempt, "error", err)
+			return nil
+		}
+		slog.Warn("event handler failed, record will be retried", "topic", record.Topic, "partition", record.Partition, "offset", record.Offset, "error", err)
+		select {
+		case <-time.After(time.Duration(attempt) * kafkaRetryBackoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// commit marks every record of the partition up to and including record as
+// handled. A failed commit is logged: the record is handled again by the
+// next consumer of the partition.
+func (b *KafkaBus) commit(ctx context.Context, consumer *kgo.Client, record *kgo.Record) {
+	// The commit must land even if ctx was cancelled during the handler
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.config.Timeout)
+	defer cancel()
+	if err := consumer.CommitRecords(ctx, record); err != nil {
+		slog.Warn("failed to commit offset", "topic", record.Topic, "partition", record.Partition, "offset", record.Offset, "error", err)
+	}
+}
+
+// Close flushes and closes the producer. Running subscriptions return.
+func (b *KafkaBus) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		b.producer.Close()
+	})
+	return nil
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// kafkaBroker is the broker integration tests run against
+// (docker compose --profile kafka up -d).
+const kafkaBroker = "localhost:9092"
+
+func skipIfNoKafka(t *testing.T) {
+	if os.Getenv("SKIP_KAFKA_TESTS") == "1" {
+		t.Skip("Skipping Kafka tests (SKIP_KAFKA_TESTS=1)")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	bus, err := NewKafkaBus(ctx, KafkaConfig{Brokers: []string{kafkaBroker}, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Skipf("Kafka not available: %v", err)
+	}
+	bus.Close()
+}
+
+func TestKafkaOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  KafkaConfig
+		wantErr string
+	}{
+		{name: "plain", config: KafkaConfig{Brokers: []string{kafkaBroker}, Username: "bam", Password: "secret"}},
+		{name: "scram", config: KafkaConfig{Brokers: []string{kafkaBroker}, Username: "bam", Mechanism: "scram-sha-512", TLS: true}},
+		{name: "no brokers", config: KafkaConfig{}, wantErr: "no Kafka brokers"},
+		{name: "unknown mechanism", config: KafkaConfig{Brokers: []string{kafkaBroker}, Username: "bam", Mechanism: "gssapi"}, wantErr: "unknown SASL mechanism"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := kafkaOptions(tt.config)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("kafkaOptions() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("kafkaOptions() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKafkaBus(t *testing.T) {
+	skipIfNoKafka(t)
+	kafkaRetryBackoff = time.Millisecond
+	t.Cleanup(func() { kafkaRetryBackoff = time.Second })
+
+	ctx := t.Context()
+	config := KafkaConfig{
+		Brokers:     []string{kafkaBroker},
+		Group:       "bam-rag-test-" + time.Now().Format("150405.000000"),
+		TopicPrefix: "test-" + time.Now().Format("150405.000000") + ".",
+	}
+	bus, err := NewKafkaBus(ctx, config)
+	if err != nil {
+		t.Fatalf("NewKafkaBus() error = %v", err)
+	}
+	defer bus.Close()
+
+	for _, prefix := range []string{"scrapes/a/1", "scrapes/b/1"} {
+		if err := PublishScrapeComplete(ctx, bus, ScrapeCompleteEvent{Prefix: prefix}); err != nil {
+			t.Fatalf("PublishScrapeComplete() error = %v", err)
+		}
+	}
+
+	subCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var got []string
+	failed := false
+	err = SubscribeScrapeComplete(subCtx, bus, func(ctx context.Context, e ScrapeCompleteEvent) error {
+		if !failed {
+			failed = true
+			return errors.New("transient failure")
+		}
+		got = append(got, e.Prefix)
+		if len(got) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+	if strings.Join(got, ",") != "scrapes/a/1,scrapes/b/1" {
+		t.Errorf("handled %v, want both prefixes in order (failed record retried)", got)
+	}
+
+	// Handled records were committed, so the group does not see them again
+	again, cancelAgain := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelAgain()
+	err = SubscribeScrapeComplete(again, bus, func(ctx context.Context, e ScrapeCompleteEvent) error {
+		t.Errorf("redelivered %s after commit", e.Prefix)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// ErrClosed is returned when publishing on a closed bus.
+var ErrClosed = errors.New("event bus closed")
+
+// MemoryBus is an in-process Bus. Publish blocks until a subscriber has
+// taken the message, so producers are paced by their consumers. Messages
+// do not outlive the process.
+type MemoryBus struct {
+	mu       sync.Mutex
+	subjects map[string]chan []byte
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// NewMemoryBus creates an in-process bus.
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		subjects: make(map[string]chan []byte),
+		closed:   make(chan struct{}),
+	}
+}
+
+// queue returns the channel carrying subject's messages.
+func (b *MemoryBus) queue(subject string) chan []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch, ok := b.subjects[subject]
+	if !ok {
+		ch = make(chan []byte)
+		b.subjects[subject] = ch
+	}
+	return ch
+}
+
+// Publish hands data to a subscriber of subject, waiting until one takes it.
+func (b *MemoryBus) Publish(ctx context.Context, subject string, data []byte) error {
+	select {
+	case b.queue(subject) <- data:
+		return nil
+	case <-b.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe handles messages on subject until ctx is cancelled or the bus
+// is closed. Messages are not redelivered when handler fails.
+func (b *MemoryBus) Subscribe(ctx context.Context, subject string, handler Handler) error {
+	ch := b.queue(subject)
+	for {
+		select {
+		case data := <-ch:
+			if err := handler(ctx, data); err != nil {
+				slog.Debug("event handler failed", "subject", subject, "error", err)
+			}
+		case <-b.closed:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Close stops all subscriptions. Messages already taken by a subscriber
+// are handled before its Subscribe returns.
+func (b *MemoryBus) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
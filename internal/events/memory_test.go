@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryBus(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx := t.Context()
+
+	var got []ScrapeCompleteEvent
+	done := make(chan error)
+	go func() {
+		done <- SubscribeScrapeComplete(ctx, bus, func(ctx context.Context, e ScrapeCompleteEvent) error {
+			got = append(got, e)
+			return nil
+		})
+	}()
+
+	for _, prefix := range []string{"scrapes/a/1", "scrapes/b/1"} {
+		if err := PublishScrapeComplete(ctx, bus, ScrapeCompleteEvent{Prefix: prefix}); err != nil {
+			t.Fatalf("PublishScrapeComplete() error = %v", err)
+		}
+	}
+
+	// Every published event was taken by the subscriber, so closing loses nothing
+	bus.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+
+	if len(got) != 2 || got[0].Prefix != "scrapes/a/1" || got[1].Prefix != "scrapes/b/1" {
+		t.Errorf("received %+v, want both events in order", got)
+	}
+
+	if err := PublishScrapeComplete(ctx, bus, ScrapeCompleteEvent{}); err != ErrClosed {
+		t.Errorf("Publish() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestNamespaced(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	ctx := t.Context()
+
+	got := make(chan []byte, 1)
+	go bus.Subscribe(ctx, "bam-rag.team-a.scrape.complete", func(ctx context.Context, data []byte) error {
+		got <- data
+		return nil
+	})
+
+	if err := PublishScrapeComplete(ctx, Namespaced(bus, "team-a"), ScrapeCompleteEvent{Prefix: "scrapes/a/1"}); err != nil {
+		t.Fatalf("PublishScrapeComplete() error = %v", err)
+	}
+	if data := <-got; len(data) == 0 {
+		t.Error("namespaced event not delivered on the namespaced subject")
+	}
+
+	if Namespaced(bus, "") != Bus(bus) {
+		t.Error("Namespaced() without a namespace should return the bus itself")
+	}
+}
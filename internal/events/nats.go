@@ -0,0 +1,303 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSConfig holds NATS JetStream connection configuration.
+type NATSConfig struct {
+	URL      string        // e.g. nats://localhost:4222; tls:// enables TLS
+	Stream   string        // JetStream stream holding bam-rag events; created if missing
+	Username string        // Optional user/password auth
+	Password string        //
+	Token    string        // Optional token auth
+	Timeout  time.Duration // Connect and request timeout; defaults to 10s
+}
+
+// JetStream consumer settings. Ingesting a large scrape can take minutes,
+// so handlers keep their message alive with progress acks.
+const (
+	natsAckWait  = time.Minute
+	natsPullWait = 30 * time.Second
+	natsMaxAge   = 7 * 24 * time.Hour
+)
+
+// natsMaxAttempts is how often a failing message is handled before it is
+// dropped. Deliveries of messages of a newer schema version don't count,
+// so the consumer itself redelivers without limit.
+const natsMaxAttempts = 5
+
+// NATSBus is a Bus backed by NATS JetStream. Published events are stored
+// in a stream, so they survive the publishing process exiting and are
+// delivered to consumers that subscribe later. Subscribers of a subject
+// share a durable consumer: each message is handled by one of them and
+// redelivered if its handler fails or the process dies before finishing.
+// A failing message is dropped after natsMaxAttempts failures in this
+// process; one of a newer schema version is kept for an upgraded consumer.
+type NATSBus struct {
+	config NATSConfig
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	stream jetstream.Stream
+
+	mu       sync.Mutex
+	failures map[uint64]int // Handler failures per stream sequence
+
+	closed    chan struct{} // Closed by Close, to tell it from a lost connection
+	closeOnce sync.Once
+}
+
+// NewNATSBus connects to NATS and ensures the configured stream exists.
+func NewNATSBus(ctx context.Context, config NATSConfig) (*NATSBus, error) {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.Stream == "" {
+		config.Stream = "BAM_RAG"
+	}
+
+	opts := []nats.Option{nats.Name("bam-rag"), nats.Timeout(config.Timeout)}
+	if config.Username != "" {
+		opts = append(opts, nats.UserInfo(config.Username, config.Password))
+	}
+	if config.Token != "" {
+		opts = append(opts, nats.Token(config.Token))
+	}
+	nc, err := nats.Connect(config.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+	b := &NATSBus{config: config, nc: nc, js: js, failures: make(map[uint64]int), closed: make(chan struct{})}
+
+	if err := b.ensureStream(ctx); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// ensureStream looks up the events stream, creating it if it does not
+// exist. An existing stream is used as configured.
+func (b *NATSBus) ensureStream(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+
+	stream, err := b.js.Stream(ctx, b.config.Stream)
+	if err == nil {
+		b.stream = stream
+		return nil
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("failed to look up stream %s: %w", b.config.Stream, natsErr(err))
+	}
+
+	stream, err = b.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:      b.config.Stream,
+		Subjects:  []string{"bam-rag.>"},
+		Retention: jetstream.LimitsPolicy,
+		Storage:   jetstream.FileStorage,
+		MaxAge:    natsMaxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stream %s: %w", b.config.Stream, natsErr(err))
+	}
+	b.stream = stream
+	slog.Info("created NATS stream", "stream", b.config.Stream)
+	return nil
+}
+
+// natsErr explains the error JetStream requests fail with when the server
+// does not have JetStream enabled.
+func natsErr(err error) error {
+	if errors.Is(err, nats.ErrNoResponders) || errors.Is(err, jetstream.ErrNoStreamResponse) {
+		return fmt.Errorf("%w - is JetStream enabled?", err)
+	}
+	return err
+}
+
+// Publish stores data in the stream and waits for the server's acknowledgement.
+func (b *NATSBus) Publish(ctx context.Context, subject string, data []byte) error {
+	select {
+	case <-b.closed:
+		return ErrClosed
+	default:
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+
+	ack, err := b.js.Publish(ctx, subject, data)
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", subject, natsErr(err))
+	}
+	slog.Debug("published event", "subject", subject, "stream", ack.Stream, "seq", ack.Sequence)
+	return nil
+}
+
+// Subscribe pulls messages for subject from a durable consumer shared by
+// every subscriber of the subject. A message is acknowledged when handler
+// succeeds and redelivered (up to natsMaxAttempts) when it fails. Returns nil when
+// ctx is cancelled or the bus closed, and an error if the connection is
+// lost for good.
+func (b *NATSBus) Subscribe(ctx context.Context, subject string, handler Handler) error {
+	durable := consumerName(subject)
+	createCtx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	consumer, err := b.stream.CreateOrUpdateConsumer(createCtx, jetstream.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subject,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       natsAckWait,
+		// Unlimited: newer-schema deliveries must not use up the budget
+		// of failures, which handle counts instead
+		MaxDeliver: -1,
+	})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to create consumer %s: %w", durable, natsErr(err))
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		batch, err := consumer.Fetch(1, jetstream.FetchMaxWait(natsPullWait))
+		if err != nil {
+			return b.subscribeErr(subject, err)
+		}
+
+		// Wait for a message or the end of the pull request
+		select {
+		case msg, ok := <-batch.Messages():
+			if ok {
+				b.handle(ctx, msg, handler)
+			} else if err := batch.Error(); err != nil {
+				return b.subscribeErr(subject, err)
+			}
+		case <-ctx.Done():
+			return nil
+		case <-b.closed:
+			return nil
+		}
+	}
+}
+
+// subscribeErr is returned by Subscribe when pulling fails: nil after
+// Close, and err otherwise.
+func (b *NATSBus) subscribeErr(subject string, err error) error {
+	select {
+	case <-b.closed:
+		return nil
+	default:
+	}
+	if b.nc.IsClosed() {
+		if last := b.nc.LastError(); last != nil {
+			err = last
+		}
+		return fmt.Errorf("NATS connection lost: %w", err)
+	}
+	return fmt.Errorf("failed to pull %s: %w", subject, err)
+}
+
+// handle runs handler for a pulled message and acknowledges the outcome.
+// The message is kept alive while the handler runs.
+func (b *NATSBus) handle(ctx context.Context, msg jetstream.Msg, handler Handler) {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(natsAckWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				msg.InProgress()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	err := handler(ctx, msg.Data())
+	close(stop)
+	wg.Wait()
+
+	ack := msg.Ack
+	switch {
+	case errors.Is(err, ErrUnsupportedSchema):
+		// Never dropped; give an upgraded consumer time to take it
+		slog.Warn("event uses a newer schema, message will be redelivered", "subject", msg.Subject(), "error", err)
+		ack = func() error { return msg.NakWithDelay(natsAckWait) }
+	case err != nil && ctx.Err() != nil:
+		// Interrupted; hand the message to the next consumer right away
+		ack = msg.Nak
+	case err != nil:
+		attempts := b.failed(msg)
+		if attempts < natsMaxAttempts {
+			slog.Warn("event handler failed, message will be redelivered", "subject", msg.Subject(), "error", err)
+			ack = msg.Nak
+			break
+		}
+		slog.Error("event handler failed, dropping message", "subject", msg.Subject(), "attempts", attempts, "error", err)
+		ack = msg.Term
+		b.forget(msg)
+	default:
+		b.forget(msg)
+	}
+	if err := ack(); err != nil {
+		slog.Warn("failed to acknowledge event", "subject", msg.Subject(), "error", err)
+	}
+}
+
+// failed counts a handler failure of msg and returns how many it has had.
+func (b *NATSBus) failed(msg jetstream.Msg) int {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[meta.Sequence.Stream]++
+	return b.failures[meta.Sequence.Stream]
+}
+
+// forget drops the failures counted for msg once it is done with.
+func (b *NATSBus) forget(msg jetstream.Msg) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, meta.Sequence.Stream)
+}
+
+// Close closes the connection. Running subscriptions return.
+func (b *NATSBus) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		b.nc.Close()
+	})
+	return nil
+}
+
+// consumerName derives a durable consumer name from a subject,
+// e.g. "bam-rag.scrape.complete" -> "bam-rag-scrape-complete".
+func consumerName(subject string) string {
+	return strings.NewReplacer(".", "-", "*", "any", ">", "all").Replace(subject)
+}
@@ -0,0 +1,381 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeJetStream is a minimal in-memory NATS server speaking enough of the
+// core protocol and JetStream API for NATSBus.
+type fakeJetStream struct {
+	t  *testing.T
+	ln net.Listener
+
+	mu      sync.Mutex
+	stream  bool
+	stored  []*storedMsg // Messages awaiting delivery
+	acks    []string     // Acknowledgements received, in order
+	waiting []*pullRequest
+	seq     int
+	nextAck int
+	pending map[string]*storedMsg // Ack subject -> delivered message
+}
+
+// storedMsg is a message of the stream with its delivery count.
+type storedMsg struct {
+	seq       int
+	delivered int
+	data      []byte
+}
+
+// pullRequest is a pull waiting for a message to be published.
+type pullRequest struct {
+	deliver func(data []byte, ackSubject string)
+}
+
+func newFakeJetStream(t *testing.T) *fakeJetStream {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	f := &fakeJetStream{t: t, ln: ln, pending: make(map[string]*storedMsg)}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeJetStream) url() string {
+	return "nats://" + f.ln.Addr().String()
+}
+
+func (f *fakeJetStream) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeJetStream) handle(conn net.Conn) {
+	defer conn.Close()
+	var wmu sync.Mutex
+	send := func(s string) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		io.WriteString(conn, s)
+	}
+
+	// Messages go to the subscription of the connection matching their subject
+	var smu sync.Mutex
+	subs := make(map[string]string) // sid -> subject
+	sid := func(subject string) string {
+		smu.Lock()
+		defer smu.Unlock()
+		for sid, pattern := range subs {
+			if subjectMatches(pattern, subject) {
+				return sid
+			}
+		}
+		return ""
+	}
+	msg := func(subject, reply string, data []byte) {
+		if reply != "" {
+			send(fmt.Sprintf("MSG %s %s %s %d\r\n%s\r\n", subject, sid(subject), reply, len(data), data))
+		} else {
+			send(fmt.Sprintf("MSG %s %s %d\r\n%s\r\n", subject, sid(subject), len(data), data))
+		}
+	}
+	status := func(subject string, code int) {
+		hdr := fmt.Sprintf("NATS/1.0 %d\r\n\r\n", code)
+		send(fmt.Sprintf("HMSG %s %s %d %d\r\n%s\r\n", subject, sid(subject), len(hdr), len(hdr), hdr))
+	}
+
+	send(`INFO {"server_id":"fake","headers":true,"proto":1,"max_payload":1048576}` + "\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			send("PONG\r\n")
+		case "SUB":
+			smu.Lock()
+			subs[fields[len(fields)-1]] = fields[1]
+			smu.Unlock()
+		case "UNSUB":
+			smu.Lock()
+			delete(subs, fields[1])
+			smu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			data = data[:size]
+			reply := ""
+			if len(fields) == 4 {
+				reply = fields[2]
+			}
+			f.pub(fields[1], reply, data, msg, status)
+		}
+	}
+}
+
+// subjectMatches reports whether subject matches pattern, which may hold
+// the * and > wildcards.
+func subjectMatches(pattern, subject string) bool {
+	p, s := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, token := range p {
+		switch {
+		case token == ">":
+			return len(s) > i
+		case i >= len(s) || token != "*" && token != s[i]:
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
+
+func (f *fakeJetStream) pub(subject, reply string, data []byte, msg func(string, string, []byte), status func(string, int)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(subject, "$JS.API.STREAM.INFO."):
+		if !f.stream {
+			msg(reply, "", []byte(`{"error":{"code":404,"err_code":10059,"description":"stream not found"}}`))
+			return
+		}
+		msg(reply, "", []byte(`{"config":{}}`))
+	case strings.HasPrefix(subject, "$JS.API.STREAM.CREATE."):
+		f.stream = true
+		msg(reply, "", []byte(`{"config":{}}`))
+	case strings.HasPrefix(subject, "$JS.API.CONSUMER.CREATE."):
+		msg(reply, "", []byte(`{"stream_name":"BAM_RAG","name":"consumer","config":{}}`))
+	case strings.HasPrefix(subject, "$JS.API.CONSUMER.MSG.NEXT."):
+		pull := &pullRequest{deliver: func(data []byte, ackSubject string) { msg(reply, ackSubject, data) }}
+		if len(f.stored) == 0 {
+			f.waiting = append(f.waiting, pull)
+			// Expire the pull like a real server would
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				f.mu.Lock()
+				defer f.mu.Unlock()
+				if i := slices.Index(f.waiting, pull); i >= 0 {
+					f.waiting = slices.Delete(f.waiting, i, i+1)
+					status(reply, 408)
+				}
+			}()
+			return
+		}
+		f.deliver(pull)
+	case strings.HasPrefix(subject, "$JS.ACK."):
+		f.acks = append(f.acks, string(data))
+		// Naks are redelivered right away, whatever their delay
+		if strings.HasPrefix(string(data), "-NAK") {
+			f.stored = append(f.stored, f.pending[subject])
+		}
+		if string(data) != "+WPI" {
+			delete(f.pending, subject)
+		}
+		f.flush()
+	case strings.HasPrefix(subject, "bam-rag."):
+		if !f.stream {
+			status(reply, 503)
+			return
+		}
+		f.seq++
+		f.stored = append(f.stored, &storedMsg{seq: f.seq, data: data})
+		msg(reply, "", []byte(fmt.Sprintf(`{"stream":"BAM_RAG","seq":%d}`, f.seq)))
+		f.flush()
+	}
+}
+
+// deliver hands the oldest stored message to a pull request. Called with mu held.
+func (f *fakeJetStream) deliver(to *pullRequest) {
+	stored := f.stored[0]
+	f.stored = f.stored[1:]
+	stored.delivered++
+	f.nextAck++
+	// Delivered, stream and consumer sequences, timestamp, and pending
+	ackSubject := fmt.Sprintf("$JS.ACK.BAM_RAG.consumer.%d.%d.%d.0.0", stored.delivered, stored.seq, f.nextAck)
+	f.pending[ackSubject] = stored
+	to.deliver(stored.data, ackSubject)
+}
+
+// flush serves waiting pull requests. Called with mu held.
+func (f *fakeJetStream) flush() {
+	for len(f.waiting) > 0 && len(f.stored) > 0 {
+		to := f.waiting[0]
+		f.waiting = f.waiting[1:]
+		f.deliver(to)
+	}
+}
+
+func (f *fakeJetStream) ackLog() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acks...)
+}
+
+func TestNATSBus_SurvivesPublisherExit(t *testing.T) {
+	server := newFakeJetStream(t)
+	ctx := t.Context()
+
+	// Publish and disconnect before anyone subscribes
+	publisher, err := NewNATSBus(ctx, NATSConfig{URL: server.url(), Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewNATSBus() error = %v", err)
+	}
+	event := ScrapeCompleteEvent{Prefix: "scrapes/go.dev/1", Source: "go", PageCount: 3}
+	if err := PublishScrapeComplete(ctx, publisher, event); err != nil {
+		t.Fatalf("PublishScrapeComplete() error = %v", err)
+	}
+	publisher.Close()
+
+	consumer, err := NewNATSBus(ctx, NATSConfig{URL: server.url(), Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewNATSBus() error = %v", err)
+	}
+	defer consumer.Close()
+
+	subCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	attempts := 0
+	var got ScrapeCompleteEvent
+	err = SubscribeScrapeComplete(subCtx, consumer, func(ctx context.Context, e ScrapeCompleteEvent) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("transient failure")
+		}
+		got = e
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+
+	if got.Prefix != event.Prefix || got.Source != event.Source || got.PageCount != event.PageCount {
+		t.Errorf("received %+v, want %+v", got, event)
+	}
+	if attempts != 2 {
+		t.Errorf("handler called %d times, want 2 (failure is redelivered)", attempts)
+	}
+
+	// The final ack is sent after the handler returns
+	deadline := time.Now().Add(time.Second)
+	for len(server.ackLog()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if acks := server.ackLog(); len(acks) != 2 || acks[0] != "-NAK" || acks[1] != "+ACK" {
+		t.Errorf("acks = %v, want [-NAK +ACK]", acks)
+	}
+}
+
+func TestNATSBus_DropsAfterMaxAttempts(t *testing.T) {
+	server := newFakeJetStream(t)
+	ctx := t.Context()
+
+	bus, err := NewNATSBus(ctx, NATSConfig{URL: server.url(), Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewNATSBus() error = %v", err)
+	}
+	defer bus.Close()
+	if err := PublishScrapeComplete(ctx, bus, ScrapeCompleteEvent{Prefix: "scrapes/go.dev/1"}); err != nil {
+		t.Fatalf("PublishScrapeComplete() error = %v", err)
+	}
+
+	subCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	attempts := 0
+	err = SubscribeScrapeComplete(subCtx, bus, func(ctx context.Context, e ScrapeCompleteEvent) error {
+		attempts++
+		return errors.New("permanent failure")
+	})
+	if err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+
+	if attempts != natsMaxAttempts {
+		t.Errorf("handler called %d times, want %d", attempts, natsMaxAttempts)
+	}
+	if acks := server.ackLog(); len(acks) == 0 || acks[len(acks)-1] != "+TERM" {
+		t.Errorf("acks = %v, want the message terminated last", acks)
+	}
+}
+
+func TestNATSBus_NewerSchemaKept(t *testing.T) {
+	server := newFakeJetStream(t)
+	ctx := t.Context()
+
+	bus, err := NewNATSBus(ctx, NATSConfig{URL: server.url(), Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewNATSBus() error = %v", err)
+	}
+	defer bus.Close()
+	if err := bus.Publish(ctx, SubjectScrapeComplete, []byte(`{"schema_version":99,"prefix":"scrapes/a/1"}`)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	// Redelivered well past the failure budget, until the test gives up
+	deliveries := func() int {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.nextAck
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for deliveries() <= 3*natsMaxAttempts && subCtx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	err = SubscribeScrapeComplete(subCtx, bus, func(ctx context.Context, e ScrapeCompleteEvent) error {
+		t.Errorf("handled event of a newer schema: %+v", e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+
+	for _, ack := range server.ackLog() {
+		if !strings.HasPrefix(ack, "-NAK") {
+			t.Fatalf("acks = %v, want only naks", server.ackLog())
+		}
+	}
+}
+
+func TestNATSBus_ConnectFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	if _, err := NewNATSBus(t.Context(), NATSConfig{URL: "nats://" + addr, Timeout: time.Second}); err == nil {
+		t.Error("NewNATSBus() should fail when no server is listening")
+	}
+}
@@ -0,0 +1,274 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// SQSConfig holds configuration for the SQS bus. Region and credentials
+// left empty are resolved by the AWS SDK's default chain: environment
+// variables, shared config and credentials files, web identity (IRSA),
+// and ECS or EC2 instance roles.
+type SQSConfig struct {
+	Region          string        // AWS region; defaults to the SDK's region chain
+	Endpoint        string        // Optional, e.g. http://localhost:4566 for LocalStack
+	QueuePrefix     string        // Prepended to queue names
+	AccessKeyID     string        // Optional static credentials
+	SecretAccessKey string        //
+	SessionToken    string        //
+	Timeout         time.Duration // Request timeout; defaults to 30s
+}
+
+// SQS consumer settings.
+const (
+	sqsWaitTime          = 20 * time.Second // Long polling
+	sqsVisibilityTimeout = time.Minute      // Extended while a handler runs
+	sqsMaxAttempts       = 5
+)
+
+// sqsRetryBackoff is the delay before the first redelivery of a failed
+// message; later redeliveries wait proportionally longer.
+var sqsRetryBackoff = time.Second
+
+// SQSBus is a Bus backed by SQS queues, one per subject, named after the
+// subject with dots replaced by dashes (bam-rag-scrape-complete). Queues are
+// created if missing. Subscribers of a subject compete for its messages.
+// Messages are deleted only after the handler succeeds; a failing message is
+// made visible again with backoff and dropped after sqsMaxAttempts, unless
+// the queue's redrive policy moves it to a dead-letter queue first. Messages
+// of a newer schema version are never dropped.
+type SQSBus struct {
+	config SQSConfig
+	client *sqs.Client
+
+	mu     sync.Mutex
+	queues map[string]string // Subject to queue URL
+
+	closed context.Context    // Done once Close is called
+	close  context.CancelFunc // Makes closed done
+}
+
+// NewSQSBus creates an SQS bus and checks that the credentials are accepted.
+func NewSQSBus(ctx context.Context, config SQSConfig) (*SQSBus, error) {
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if config.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(config.Region))
+	}
+	if config.AccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(config.AccessKeyID, config.SecretAccessKey, config.SessionToken)))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, errors.New("SQS region is required")
+	}
+
+	client := sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+	})
+	b := &SQSBus{config: config, client: client, queues: make(map[string]string)}
+	b.closed, b.close = context.WithCancel(context.Background())
+
+	listCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	if _, err := client.ListQueues(listCtx, &sqs.ListQueuesInput{MaxResults: aws.Int32(1)}); err != nil {
+		return nil, fmt.Errorf("failed to reach SQS: %w", err)
+	}
+	return b, nil
+}
+
+// queueURL returns the URL of the subject's queue, creating the queue if
+// it does not exist.
+func (b *SQSBus) queueURL(ctx context.Context, subject string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if u, ok := b.queues[subject]; ok {
+		return u, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+
+	name := b.config.QueuePrefix + consumerName(subject)
+	var queue *string
+	resp, err := b.client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(name)})
+	if err == nil {
+		queue = resp.QueueUrl
+	}
+	var notFound *types.QueueDoesNotExist
+	if errors.As(err, &notFound) {
+		var created *sqs.CreateQueueOutput
+		created, err = b.client.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String(name)})
+		if err == nil {
+			queue = created.QueueUrl
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up queue %s: %w", name, err)
+	}
+	b.queues[subject] = aws.ToString(queue)
+	return b.queues[subject], nil
+}
+
+// Publish sends data to the subject's queue.
+func (b *SQSBus) Publish(ctx context.Context, subject string, data []byte) error {
+	queue, err := b.queueURL(ctx, subject)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+
+	resp, err := b.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(queue),
+		MessageBody: aws.String(string(data)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", subject, err)
+	}
+	slog.Debug("published event", "subject", subject, "message_id", aws.ToString(resp.MessageId))
+	return nil
+}
+
+// Subscribe receives messages from the subject's queue until ctx is
+// cancelled or the bus is closed.
+func (b *SQSBus) Subscribe(ctx context.Context, subject string, handler Handler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(b.closed, cancel)
+	defer stop()
+
+	queue, err := b.queueURL(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		receiveCtx, cancel := context.WithTimeout(ctx, b.config.Timeout+sqsWaitTime)
+		resp, err := b.client.ReceiveMessage(receiveCtx, &sqs.ReceiveMessageInput{
+			QueueUrl:                    aws.String(queue),
+			MaxNumberOfMessages:         1,
+			WaitTimeSeconds:             int32(sqsWaitTime.Seconds()),
+			VisibilityTimeout:           int32(sqsVisibilityTimeout.Seconds()),
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount},
+		})
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("failed to receive from %s: %w", subject, err)
+		}
+
+		for _, msg := range resp.Messages {
+			if err := b.handle(ctx, queue, msg, handler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handle runs handler on msg, keeping the message invisible to other
+// consumers while it runs, then deletes it or schedules its redelivery.
+func (b *SQSBus) handle(ctx context.Context, queue string, msg types.Message, handler Handler) error {
+	id := aws.ToString(msg.MessageId)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(sqsVisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := b.setVisibility(ctx, queue, msg, sqsVisibilityTimeout); err != nil {
+					slog.Warn("failed to extend event visibility", "message_id", id, "error", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	err := handler(ctx, []byte(aws.ToString(msg.Body)))
+	close(stop)
+	wg.Wait()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			// Interrupted; hand the message to the next consumer right away
+			b.setVisibility(ctx, queue, msg, 0)
+			return nil
+		}
+		if errors.Is(err, ErrUnsupportedSchema) {
+			// Never dropped; an upgraded consumer takes it
+			slog.Warn("event uses a newer schema, message will be redelivered", "message_id", id, "error", err)
+			if err := b.setVisibility(ctx, queue, msg, sqsVisibilityTimeout); err != nil {
+				slog.Warn("failed to schedule event redelivery", "message_id", id, "error", err)
+			}
+			return nil
+		}
+		attempts, _ := strconv.Atoi(msg.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+		if attempts < sqsMaxAttempts {
+			slog.Warn("event handler failed, message will be redelivered", "message_id", id, "error", err)
+			if err := b.setVisibility(ctx, queue, msg, time.Duration(attempts)*sqsRetryBackoff); err != nil {
+				slog.Warn("failed to schedule event redelivery", "message_id", id, "error", err)
+			}
+			return nil
+		}
+		slog.Error("event handler failed, dropping message", "message_id", id, "attempts", attempts, "error", err)
+	}
+
+	// The delete must land even if ctx was cancelled during the handler
+	delCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.config.Timeout)
+	defer cancel()
+	_, err = b.client.DeleteMessage(delCtx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(queue),
+		ReceiptHandle: msg.ReceiptHandle,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete message %s: %w", id, err)
+	}
+	return nil
+}
+
+// setVisibility makes msg visible to consumers again after d.
+func (b *SQSBus) setVisibility(ctx context.Context, queue string, msg types.Message, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.config.Timeout)
+	defer cancel()
+	_, err := b.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queue),
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: int32(d.Seconds()),
+	})
+	return err
+}
+
+// Close ends the subscriptions of the bus. A message being handled is
+// interrupted and made visible to the next consumer right away.
+func (b *SQSBus) Close() error {
+	b.close()
+	return nil
+}
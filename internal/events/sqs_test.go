@@ -0,0 +1,265 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSQS is an in-memory SQS endpoint speaking the JSON protocol.
+type fakeSQS struct {
+	mu       sync.Mutex
+	queues   map[string][]*fakeSQSMessage
+	nextID   int
+	deleted  []string // Bodies of deleted messages, in order
+	unsigned int      // Requests without a SigV4 Authorization header
+	lastAuth string   // Authorization header of the last request
+}
+
+// fakeSQSError is an error response of the SQS JSON protocol.
+type fakeSQSError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+type fakeSQSMessage struct {
+	id        string
+	body      string
+	receives  int
+	invisible bool
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastAuth = r.Header.Get("Authorization")
+	if !strings.HasPrefix(f.lastAuth, "AWS4-HMAC-SHA256 Credential=AKID/") {
+		f.unsigned++
+	}
+
+	var req struct {
+		QueueName         string
+		QueueURL          string `json:"QueueUrl"`
+		MessageBody       string
+		ReceiptHandle     string
+		VisibilityTimeout int
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	queue := strings.TrimPrefix(req.QueueURL, "http://sqs.local/")
+
+	find := func() *fakeSQSMessage {
+		for _, m := range f.queues[queue] {
+			if m.id == req.ReceiptHandle {
+				return m
+			}
+		}
+		return nil
+	}
+
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.") {
+	case "ListQueues":
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	case "GetQueueUrl":
+		if _, ok := f.queues[req.QueueName]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(fakeSQSError{Type: "com.amazonaws.sqs#QueueDoesNotExist", Message: "no such queue"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"QueueUrl": "http://sqs.local/" + req.QueueName})
+	case "CreateQueue":
+		f.queues[req.QueueName] = nil
+		json.NewEncoder(w).Encode(map[string]string{"QueueUrl": "http://sqs.local/" + req.QueueName})
+	case "SendMessage":
+		f.nextID++
+		id := fmt.Sprint(f.nextID)
+		f.queues[queue] = append(f.queues[queue], &fakeSQSMessage{id: id, body: req.MessageBody})
+		json.NewEncoder(w).Encode(map[string]string{"MessageId": id})
+	case "ReceiveMessage":
+		var messages []map[string]interface{}
+		for _, m := range f.queues[queue] {
+			if !m.invisible {
+				m.invisible = true
+				m.receives++
+				messages = append(messages, map[string]interface{}{
+					"MessageId":     m.id,
+					"ReceiptHandle": m.id,
+					"Body":          m.body,
+					"Attributes":    map[string]string{"ApproximateReceiveCount": fmt.Sprint(m.receives)},
+				})
+				break
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Messages": messages})
+	case "ChangeMessageVisibility":
+		if m := find(); m != nil && req.VisibilityTimeout == 0 {
+			m.invisible = false
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	case "DeleteMessage":
+		if m := find(); m != nil {
+			f.deleted = append(f.deleted, m.body)
+			m.body = ""
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(fakeSQSError{Type: "InvalidAction", Message: r.Header.Get("X-Amz-Target")})
+	}
+}
+
+func TestSQSBus(t *testing.T) {
+	sqsRetryBackoff = 0
+	t.Cleanup(func() { sqsRetryBackoff = time.Second })
+
+	fake := &fakeSQS{queues: make(map[string][]*fakeSQSMessage)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := t.Context()
+	bus, err := NewSQSBus(ctx, SQSConfig{
+		Region:          "eu-west-1",
+		Endpoint:        server.URL,
+		QueuePrefix:     "stage-",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("NewSQSBus() error = %v", err)
+	}
+
+	for _, prefix := range []string{"scrapes/a/1", "scrapes/b/1"} {
+		if err := PublishScrapeComplete(ctx, bus, ScrapeCompleteEvent{Prefix: prefix}); err != nil {
+			t.Fatalf("PublishScrapeComplete() error = %v", err)
+		}
+	}
+
+	subCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var got []string
+	failed := false
+	err = SubscribeScrapeComplete(subCtx, bus, func(ctx context.Context, e ScrapeCompleteEvent) error {
+		if !failed {
+			failed = true
+			return errors.New("transient failure")
+		}
+		got = append(got, e.Prefix)
+		if len(got) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+
+	if strings.Join(got, ",") != "scrapes/a/1,scrapes/b/1" {
+		t.Errorf("handled %v, want both prefixes in order", got)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if _, ok := fake.queues["stage-bam-rag-scrape-complete"]; !ok {
+		t.Errorf("queues = %v, want stage-bam-rag-scrape-complete created", fake.queues)
+	}
+	if len(fake.deleted) != 2 {
+		t.Errorf("deleted %d messages, want 2", len(fake.deleted))
+	}
+	if m := fake.queues["stage-bam-rag-scrape-complete"][0]; m.receives != 2 {
+		t.Errorf("failed message received %d times, want 2", m.receives)
+	}
+	if fake.unsigned != 0 {
+		t.Errorf("%d requests were not signed", fake.unsigned)
+	}
+}
+
+func TestSQSBus_NewerSchemaKept(t *testing.T) {
+	fake := &fakeSQS{queues: make(map[string][]*fakeSQSMessage)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := t.Context()
+	bus, err := NewSQSBus(ctx, SQSConfig{Region: "eu-west-1", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatalf("NewSQSBus() error = %v", err)
+	}
+	if err := bus.Publish(ctx, SubjectScrapeComplete, []byte(`{"schema_version":99,"prefix":"scrapes/a/1"}`)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	subCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	err = SubscribeScrapeComplete(subCtx, bus, func(ctx context.Context, e ScrapeCompleteEvent) error {
+		t.Errorf("handled event of a newer schema: %+v", e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeScrapeComplete() error = %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.deleted) != 0 {
+		t.Errorf("deleted %v, want the event left on the queue", fake.deleted)
+	}
+}
+
+func TestSQSBus_DefaultCredentials(t *testing.T) {
+	fake := &fakeSQS{queues: make(map[string][]*fakeSQSMessage)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	// Without configured credentials, the SDK's default chain applies
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	if _, err := NewSQSBus(t.Context(), SQSConfig{Endpoint: server.URL}); err != nil {
+		t.Fatalf("NewSQSBus() error = %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if !strings.Contains(fake.lastAuth, "Credential=ENVKEY/") || !strings.Contains(fake.lastAuth, "/us-east-1/sqs/aws4_request") {
+		t.Errorf("Authorization = %q, want signed with environment credentials", fake.lastAuth)
+	}
+}
+
+func TestSQSBus_CloseEndsSubscriptions(t *testing.T) {
+	fake := &fakeSQS{queues: make(map[string][]*fakeSQSMessage)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := t.Context()
+	bus, err := NewSQSBus(ctx, SQSConfig{Region: "eu-west-1", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatalf("NewSQSBus() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Subscribe(ctx, SubjectScrapeComplete, func(context.Context, []byte) error { return nil })
+	}()
+	time.Sleep(50 * time.Millisecond)
+	bus.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Subscribe() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe() still running after Close()")
+	}
+}
@@ -0,0 +1,155 @@
+// Package export converts indexed pages and chunks into the records other
+// RAG frameworks load, so a corpus built by bam-rag can feed them directly.
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Formats of exported records.
+const (
+	FormatNDJSON     = "ndjson"     // bam-rag documents and chunks, as indexed
+	FormatLangChain  = "langchain"  // LangChain Document: page_content and metadata
+	FormatLlamaIndex = "llamaindex" // LlamaIndex Document and TextNode: text, metadata, and relationships
+)
+
+// ValidFormat returns an error if format is not one of the export formats.
+func ValidFormat(format string) error {
+	switch format {
+	case FormatNDJSON, FormatLangChain, FormatLlamaIndex:
+		return nil
+	}
+	return fmt.Errorf("unknown export format %q (expected ndjson, langchain, or llamaindex)", format)
+}
+
+// Metadata is flat: vector stores behind both frameworks, such as Chroma,
+// reject nested values and lists, so lists are joined into strings.
+type Metadata map[string]interface{}
+
+// LangChainDocument is a langchain_core.documents.Document, loadable with
+// Document(**record).
+type LangChainDocument struct {
+	ID          string   `json:"id"`
+	PageContent string   `json:"page_content"`
+	Metadata    Metadata `json:"metadata"`
+	Type        string   `json:"type"` // Always "Document"
+}
+
+// LlamaIndexNode is a llama_index.core Document or TextNode, loadable with
+// Document.from_dict(record) or TextNode.from_dict(record).
+type LlamaIndexNode struct {
+	ID            string                     `json:"id_"`
+	Text          string                     `json:"text"`
+	Metadata      Metadata                   `json:"metadata"`
+	Embedding     []float32                  `json:"embedding,omitempty"`
+	Relationships map[string]RelatedNodeInfo `json:"relationships,omitempty"`
+	ClassName     string                     `json:"class_name"` // "Document" or "TextNode"
+}
+
+// RelatedNodeInfo points a LlamaIndex node at another node.
+type RelatedNodeInfo struct {
+	NodeID   string `json:"node_id"`
+	NodeType string `json:"node_type"`
+}
+
+// LlamaIndex relationship and node type codes (NodeRelationship and
+// ObjectType in llama_index.core.schema).
+const (
+	llamaSourceRelationship = "1"
+	llamaDocumentType       = "4"
+)
+
+// Document converts a page into a record of the given format; the
+// NDJSON format is the page itself. Embeddings are kept only where the
+// format has a place for them.
+func Document(format string, doc models.Document) interface{} {
+	switch format {
+	case FormatLangChain:
+		return LangChainDocument{ID: doc.ID, PageContent: doc.Content, Metadata: documentMetadata(doc), Type: "Document"}
+	case FormatLlamaIndex:
+		return LlamaIndexNode{ID: doc.ID, Text: doc.Content, Metadata: documentMetadata(doc), Embedding: doc.Embedding, ClassName: "Document"}
+	}
+	return doc
+}
+
+// Chunk converts a chunk into a record of the given format. A LlamaIndex
+// node names its page as its source document.
+func Chunk(format string, chunk models.Chunk) interface{} {
+	switch format {
+	case FormatLangChain:
+		return LangChainDocument{ID: chunk.ID, PageContent: chunkText(chunk), Metadata: chunkMetadata(chunk), Type: "Document"}
+	case FormatLlamaIndex:
+		return LlamaIndexNode{
+			ID:        chunk.ID,
+			Text:      chunkText(chunk),
+			Metadata:  chunkMetadata(chunk),
+			Embedding: chunk.Embedding,
+			Relationships: map[string]RelatedNodeInfo{
+				llamaSourceRelationship: {NodeID: chunk.DocumentID, NodeType: llamaDocumentType},
+			},
+			ClassName: "TextNode",
+		}
+	}
+	return chunk
+}
+
+// chunkText returns the text of a chunk, led by its LLM-generated context
+// as it is embedded.
+func chunkText(chunk models.Chunk) string {
+	if chunk.Context == "" {
+		return chunk.Content
+	}
+	return chunk.Context + "\n\n" + chunk.Content
+}
+
+// documentMetadata returns the metadata of a page.
+func documentMetadata(doc models.Document) Metadata {
+	m := Metadata{
+		"id":    doc.ID,
+		"url":   doc.URL,
+		"title": doc.Title,
+	}
+	setSource(m, doc.Source, doc.Language, doc.ScrapedAt, doc.AccessLabels)
+	setString(m, "site_name", doc.SiteName)
+	setString(m, "license", doc.License)
+	setString(m, "summary", doc.Summary)
+	setString(m, "tags", strings.Join(doc.Tags, ", "))
+	return m
+}
+
+// chunkMetadata returns the metadata of a chunk.
+func chunkMetadata(chunk models.Chunk) Metadata {
+	m := Metadata{
+		"id":          chunk.ID,
+		"document_id": chunk.DocumentID,
+		"url":         chunk.URL,
+		"title":       chunk.Title,
+		"position":    chunk.Position,
+	}
+	setSource(m, chunk.Source, chunk.Language, chunk.ScrapedAt, chunk.AccessLabels)
+	setString(m, "heading_path", strings.Join(chunk.HeadingPath, " > "))
+	setString(m, "license", chunk.License)
+	return m
+}
+
+// setSource sets the origin metadata pages and chunks share.
+func setSource(m Metadata, source models.Source, language string, scrapedAt time.Time, labels []string) {
+	setString(m, "source", source.Name)
+	setString(m, "host", source.Host)
+	setString(m, "language", language)
+	if !scrapedAt.IsZero() {
+		m["scraped_at"] = scrapedAt.UTC().Format(time.RFC3339)
+	}
+	setString(m, "access_labels", strings.Join(labels, ", "))
+}
+
+// setString sets key to value unless it is empty.
+func setString(m Metadata, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
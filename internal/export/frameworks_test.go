@@ -0,0 +1,78 @@
+package export
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestDocument(t *testing.T) {
+	doc := models.Document{
+		ID:        "abc",
+		URL:       "https://go.dev/doc",
+		Title:     "Docs",
+		Content:   "# Docs",
+		ScrapedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Source:    models.Source{Name: "go", Host: "go.dev"},
+		Tags:      []string{"go", "docs"},
+		Embedding: []float32{0.5},
+	}
+
+	tests := map[string]string{
+		FormatLangChain:  `{"id":"abc","page_content":"# Docs","metadata":{"host":"go.dev","id":"abc","scraped_at":"2025-03-01T12:00:00Z","source":"go","tags":"go, docs","title":"Docs","url":"https://go.dev/doc"},"type":"Document"}`,
+		FormatLlamaIndex: `{"id_":"abc","text":"# Docs","metadata":{"host":"go.dev","id":"abc","scraped_at":"2025-03-01T12:00:00Z","source":"go","tags":"go, docs","title":"Docs","url":"https://go.dev/doc"},"embedding":[0.5],"class_name":"Document"}`,
+	}
+	for format, want := range tests {
+		got, err := json.Marshal(Document(format, doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Document(%s) = %s\nwant %s", format, got, want)
+		}
+	}
+
+	if got, ok := Document(FormatNDJSON, doc).(models.Document); !ok || got.ID != doc.ID {
+		t.Errorf("Document(ndjson) = %#v, want the document itself", got)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	chunk := models.Chunk{
+		ID:          "abc-1",
+		DocumentID:  "abc",
+		URL:         "https://go.dev/doc",
+		Title:       "Docs",
+		HeadingPath: []string{"Install", "Linux"},
+		Position:    1,
+		Content:     "Run the installer.",
+		Context:     "From the Linux install steps.",
+	}
+
+	got, err := json.Marshal(Chunk(FormatLlamaIndex, chunk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id_":"abc-1","text":"From the Linux install steps.\n\nRun the installer.","metadata":{"document_id":"abc","heading_path":"Install \u003e Linux","id":"abc-1","position":1,"title":"Docs","url":"https://go.dev/doc"},"relationships":{"1":{"node_id":"abc","node_type":"4"}},"class_name":"TextNode"}`
+	if string(got) != want {
+		t.Errorf("Chunk(llamaindex) = %s\nwant %s", got, want)
+	}
+
+	lc, ok := Chunk(FormatLangChain, chunk).(LangChainDocument)
+	if !ok || lc.Metadata["document_id"] != "abc" || lc.Type != "Document" {
+		t.Errorf("Chunk(langchain) = %#v", lc)
+	}
+}
+
+func TestValidFormat(t *testing.T) {
+	for _, format := range []string{FormatNDJSON, FormatLangChain, FormatLlamaIndex} {
+		if err := ValidFormat(format); err != nil {
+			t.Errorf("ValidFormat(%q) = %v", format, err)
+		}
+	}
+	if ValidFormat("haystack") == nil {
+		t.Error("ValidFormat(haystack) = nil, want an error")
+	}
+}
@@ -0,0 +1,133 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Reason explains why a document is considered orphaned.
+type Reason string
+
+const (
+	ReasonUnconfiguredSource Reason = "source_not_configured" // No configured source covers the URL
+	ReasonMissingPage        Reason = "page_not_in_storage"   // No scrape in S3 contains the page
+)
+
+// Orphan is an indexed document that no longer has a backing source or page.
+type Orphan struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Reason Reason `json:"reason"`
+}
+
+// Checker decides whether an indexed document is orphaned.
+type Checker struct {
+	hosts   map[string]bool // Hosts of configured URL sources
+	dirs    []string        // Absolute paths of configured local sources
+	pageIDs map[string]bool // Document IDs with a page in S3; nil skips the check
+}
+
+// NewChecker creates a Checker for the configured sources.
+// If pageIDs is nil, documents are not checked against storage.
+func NewChecker(sources []config.Source, pageIDs map[string]bool) *Checker {
+	c := &Checker{
+		hosts:   make(map[string]bool),
+		pageIDs: pageIDs,
+	}
+	for _, source := range sources {
+		if source.URL != "" {
+			if u, err := url.Parse(source.URL); err == nil {
+				c.hosts[u.Host] = true
+			}
+		}
+		if source.Path != "" {
+			if abs, err := filepath.Abs(source.Path); err == nil {
+				c.dirs = append(c.dirs, filepath.ToSlash(abs))
+			}
+		}
+	}
+	return c
+}
+
+// Check returns the reason a document is orphaned, or false if it is not.
+func (c *Checker) Check(doc models.Document) (Reason, bool) {
+	if !c.coveredBySource(doc.URL) {
+		return ReasonUnconfiguredSource, true
+	}
+	if c.pageIDs != nil && !c.pageIDs[doc.ID] {
+		return ReasonMissingPage, true
+	}
+	return "", false
+}
+
+// coveredBySource reports whether any configured source could have produced the URL.
+func (c *Checker) coveredBySource(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "file" {
+		for _, dir := range c.dirs {
+			if u.Path == dir || strings.HasPrefix(u.Path, dir+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	return c.hosts[u.Host]
+}
+
+// StoredPageIDs returns the document IDs of every page stored in any scrape.
+// Page filenames are the document ID plus ".md".
+func StoredPageIDs(ctx context.Context, storageClient *storage.Client) (map[string]bool, error) {
+	scrapes, err := storageClient.ListScrapes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool)
+	for _, scrape := range scrapes {
+		files, err := storageClient.ListMarkdownFiles(ctx, scrape.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", scrape.Prefix, err)
+		}
+		for _, f := range files {
+			ids[strings.TrimSuffix(f, ".md")] = true
+		}
+	}
+
+	slog.Debug("collected stored page IDs", "scrapes", len(scrapes), "pages", len(ids))
+	return ids, nil
+}
+
+// Index is the index orphans are looked for in.
+type Index interface {
+	// ScrollDocuments streams every document to fn, batchSize at a time,
+	// fetching at least the given fields.
+	ScrollDocuments(ctx context.Context, batchSize int, fn func(models.Document) error, fields ...string) error
+}
+
+// Find scans the index and returns all orphaned documents.
+func Find(ctx context.Context, index Index, checker *Checker) ([]Orphan, error) {
+	var orphans []Orphan
+
+	err := index.ScrollDocuments(ctx, 1000, func(doc models.Document) error {
+		if reason, ok := checker.Check(doc); ok {
+			orphans = append(orphans, Orphan{ID: doc.ID, URL: doc.URL, Reason: reason})
+		}
+		return nil
+	}, "id", "url")
+	if err != nil {
+		return nil, err
+	}
+
+	return orphans, nil
+}
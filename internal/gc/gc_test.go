@@ -0,0 +1,55 @@
+package gc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestChecker_Check(t *testing.T) {
+	docsDir := t.TempDir()
+	localFile := "file://" + filepath.ToSlash(filepath.Join(docsDir, "guide.md"))
+
+	sources := []config.Source{
+		{Name: "go", URL: "https://go.dev/doc/"},
+		{Name: "local", Path: docsDir},
+	}
+	pageIDs := map[string]bool{
+		models.GenerateDocumentID("https://go.dev/doc/install"): true,
+		models.GenerateDocumentID(localFile):                    true,
+	}
+	checker := NewChecker(sources, pageIDs)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantOrphan bool
+		wantReason Reason
+	}{
+		{"configured and stored", "https://go.dev/doc/install", false, ""},
+		{"local and stored", localFile, false, ""},
+		{"configured but pruned", "https://go.dev/doc/gone", true, ReasonMissingPage},
+		{"unconfigured host", "https://example.com/page", true, ReasonUnconfiguredSource},
+		{"local outside source dir", "file:///elsewhere/guide.md", true, ReasonUnconfiguredSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := models.Document{ID: models.GenerateDocumentID(tt.url), URL: tt.url}
+			reason, orphan := checker.Check(doc)
+			if orphan != tt.wantOrphan || reason != tt.wantReason {
+				t.Errorf("Check() = (%q, %v), want (%q, %v)", reason, orphan, tt.wantReason, tt.wantOrphan)
+			}
+		})
+	}
+}
+
+func TestChecker_SkipsStorageCheckWithoutPageIDs(t *testing.T) {
+	checker := NewChecker([]config.Source{{Name: "go", URL: "https://go.dev/doc/"}}, nil)
+
+	if _, orphan := checker.Check(models.Document{ID: "any", URL: "https://go.dev/doc/x"}); orphan {
+		t.Error("document on a configured host should not be orphaned when storage is not checked")
+	}
+}
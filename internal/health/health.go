@@ -0,0 +1,112 @@
+// Package health serves liveness and readiness endpoints for long-running
+// commands, so orchestrators can restart a stuck process and hold traffic
+// until its dependencies are reachable.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds each readiness check.
+const checkTimeout = 5 * time.Second
+
+// Check reports whether a dependency is reachable.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Status is the body of a readiness response.
+type Status struct {
+	Status string            `json:"status"` // "ok" or "unavailable"
+	Checks map[string]string `json:"checks"` // Check name to "ok" or its error
+}
+
+// Handler serves /healthz, which succeeds while the process runs, and
+// /readyz, which succeeds when every check passes and returns 503 otherwise.
+func Handler(checks ...Check) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := Ready(r.Context(), checks)
+		w.Header().Set("Content-Type", "application/json")
+		if status.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+	return mux
+}
+
+// Ready runs checks concurrently and reports their results.
+func Ready(ctx context.Context, checks []Check) Status {
+	status := Status{Status: "ok", Checks: make(map[string]string, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			result := "ok"
+			if err := check.Check(ctx); err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			status.Checks[check.Name] = result
+			if result != "ok" {
+				status.Status = "unavailable"
+			}
+		}()
+	}
+	wg.Wait()
+	return status
+}
+
+// Serve serves handler on addr until ctx is done.
+func Serve(ctx context.Context, addr string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server on %s failed: %w", addr, err)
+	}
+	return nil
+}
+
+// Socket checks that a Unix socket, such as a model runner's, accepts
+// connections.
+func Socket(path string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "unix", path)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", path, err)
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var failing bool
+	handler := Handler(
+		Check{Name: "elasticsearch", Check: func(ctx context.Context) error { return nil }},
+		Check{Name: "storage", Check: func(ctx context.Context) error {
+			if failing {
+				return errors.New("bucket missing")
+			}
+			return nil
+		}},
+	)
+
+	tests := []struct {
+		name       string
+		path       string
+		failing    bool
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{name: "live", path: "/healthz", failing: true, wantStatus: http.StatusOK},
+		{name: "ready", path: "/readyz", wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"elasticsearch": "ok", "storage": "ok"}},
+		{name: "not ready", path: "/readyz", failing: true, wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"elasticsearch": "ok", "storage": "bucket missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing = tt.failing
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantChecks == nil {
+				return
+			}
+			var status Status
+			if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			for name, want := range tt.wantChecks {
+				if status.Checks[name] != want {
+					t.Errorf("checks[%s] = %q, want %q", name, status.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.sock")
+	if err := Socket(path)(context.Background()); err == nil {
+		t.Error("Socket() on missing socket should fail")
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	if err := Socket(path)(context.Background()); err != nil {
+		t.Errorf("Socket() error = %v", err)
+	}
+}
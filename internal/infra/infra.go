@@ -0,0 +1,214 @@
+// Package infra runs the local services bam-rag depends on, Elasticsearch
+// and MinIO, with Docker Compose from a definition built into the binary,
+// and pulls Docker Model Runner models.
+package infra
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// composeFile defines the services; its ports come from the environment.
+//
+//go:embed compose.yaml
+var composeFile []byte
+
+// MinIO credentials set in compose.yaml.
+const (
+	AccessKeyID     = "minioadmin"
+	SecretAccessKey = "minioadmin"
+)
+
+// Ports holds the host ports the services listen on.
+type Ports struct {
+	Elasticsearch int
+	Storage       int // MinIO S3 API
+	Console       int // MinIO web console
+}
+
+// DefaultPorts returns the ports matching the default config.
+func DefaultPorts() Ports {
+	return Ports{Elasticsearch: 9200, Storage: 9002, Console: 9003}
+}
+
+// Endpoints holds the addresses the services are reached at.
+type Endpoints struct {
+	Elasticsearch string // URL, as in elasticsearch.addresses
+	Storage       string // host:port, as in storage.endpoint
+	Console       string // URL of the MinIO console
+}
+
+// Endpoints returns the addresses of services listening on p on this host.
+func (p Ports) Endpoints() Endpoints {
+	return Endpoints{
+		Elasticsearch: fmt.Sprintf("http://localhost:%d", p.Elasticsearch),
+		Storage:       fmt.Sprintf("localhost:%d", p.Storage),
+		Console:       fmt.Sprintf("http://localhost:%d", p.Console),
+	}
+}
+
+// Stack manages the services through the docker CLI.
+type Stack struct {
+	Ports  Ports
+	Docker string    // docker binary; defaults to "docker" on PATH
+	Stdout io.Writer // Output of docker commands; nil discards it
+	Stderr io.Writer
+}
+
+// Up starts the services, creating their containers and volumes if
+// needed. It returns once the containers run, before the services are
+// ready; see WaitReady.
+func (s *Stack) Up(ctx context.Context) error {
+	return s.compose(ctx, "up", "--detach")
+}
+
+// Down stops and removes the containers. Indexed data is kept in volumes
+// unless removeVolumes is set.
+func (s *Stack) Down(ctx context.Context, removeVolumes bool) error {
+	args := []string{"down"}
+	if removeVolumes {
+		args = append(args, "--volumes")
+	}
+	return s.compose(ctx, args...)
+}
+
+// Status writes the state of the containers to Stdout.
+func (s *Stack) Status(ctx context.Context) error {
+	return s.compose(ctx, "ps", "--all")
+}
+
+// PullModels pulls Docker Model Runner models, such as "ai/gemma3".
+// Models already pulled are checked for updates.
+func (s *Stack) PullModels(ctx context.Context, models ...string) error {
+	for _, model := range models {
+		if err := s.run(ctx, nil, "model pull", "model", "pull", model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compose runs a docker compose command on the built-in definition.
+func (s *Stack) compose(ctx context.Context, args ...string) error {
+	return s.run(ctx, composeFile, "compose "+args[0], append([]string{"compose", "--file", "-"}, args...)...)
+}
+
+// run runs a docker command with stdin as its input. Errors name the
+// command as given.
+func (s *Stack) run(ctx context.Context, stdin []byte, name string, args ...string) error {
+	docker := s.Docker
+	if docker == "" {
+		docker = "docker"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, docker, args...)
+	cmd.Env = append(os.Environ(),
+		"BAMRAG_INFRA_ES_PORT="+strconv.Itoa(s.Ports.Elasticsearch),
+		"BAMRAG_INFRA_STORAGE_PORT="+strconv.Itoa(s.Ports.Storage),
+		"BAMRAG_INFRA_CONSOLE_PORT="+strconv.Itoa(s.Ports.Console),
+	)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = s.Stdout
+	cmd.Stderr = &stderr
+	if s.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(s.Stderr, &stderr)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker %s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// WaitReady polls the services at endpoints every interval until both
+// answer, or returns an error naming those that do not once ctx is done.
+func WaitReady(ctx context.Context, endpoints Endpoints, interval time.Duration) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	checks := []struct {
+		name  string
+		check func(context.Context) error
+	}{
+		{"elasticsearch", func(ctx context.Context) error {
+			return elasticsearchReady(ctx, client, endpoints.Elasticsearch)
+		}},
+		{"minio", func(ctx context.Context) error {
+			return get(ctx, client, "http://"+endpoints.Storage+"/minio/health/live", nil)
+		}},
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var notReady error
+	for {
+		var failed []string
+		var lastErr error
+		for _, c := range checks {
+			if err := c.check(ctx); err != nil {
+				failed = append(failed, c.name)
+				lastErr = err
+			}
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		// Checks cut short by ctx say nothing about the services
+		if ctx.Err() == nil {
+			notReady = fmt.Errorf("%s not ready: %w", strings.Join(failed, " and "), lastErr)
+		}
+
+		select {
+		case <-ctx.Done():
+			if notReady == nil {
+				return ctx.Err()
+			}
+			return notReady
+		case <-ticker.C:
+		}
+	}
+}
+
+// elasticsearchReady checks that the cluster at address can serve
+// requests, i.e. its health is yellow or green.
+func elasticsearchReady(ctx context.Context, client *http.Client, address string) error {
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := get(ctx, client, address+"/_cluster/health", &health); err != nil {
+		return err
+	}
+	if health.Status != "green" && health.Status != "yellow" {
+		return fmt.Errorf("cluster health is %q", health.Status)
+	}
+	return nil
+}
+
+// get requests url and decodes its JSON body into v if set.
+func get(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,54 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitReady(t *testing.T) {
+	var health atomic.Value
+	health.Store("red")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /_cluster/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"` + health.Load().(string) + `"}`))
+	})
+	mux.HandleFunc("GET /minio/health/live", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	endpoints := Endpoints{
+		Elasticsearch: server.URL,
+		Storage:       strings.TrimPrefix(server.URL, "http://"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := WaitReady(ctx, endpoints, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "elasticsearch not ready") {
+		t.Fatalf("WaitReady() with a red cluster = %v", err)
+	}
+
+	health.Store("yellow")
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := WaitReady(ctx, endpoints, 10*time.Millisecond); err != nil {
+		t.Fatalf("WaitReady() = %v", err)
+	}
+}
+
+func TestPorts_Endpoints(t *testing.T) {
+	got := Ports{Elasticsearch: 19200, Storage: 19002, Console: 19003}.Endpoints()
+	want := Endpoints{
+		Elasticsearch: "http://localhost:19200",
+		Storage:       "localhost:19002",
+		Console:       "http://localhost:19003",
+	}
+	if got != want {
+		t.Errorf("Endpoints() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package ingestion
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// markdownLink matches inline markdown links and images, capturing the
+// image marker, the text, the destination, and the optional title.
+var markdownLink = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"([^"]*)")?\s*\)`)
+
+// attachmentKinds maps file extensions of linked files to their kind.
+// Links to other extensions are treated as pages, not attachments.
+var attachmentKinds = map[string]models.AttachmentKind{
+	".png": models.AttachmentImage, ".jpg": models.AttachmentImage, ".jpeg": models.AttachmentImage,
+	".gif": models.AttachmentImage, ".svg": models.AttachmentImage, ".webp": models.AttachmentImage,
+	".pdf": models.AttachmentPDF,
+	".zip": models.AttachmentFile, ".gz": models.AttachmentFile, ".tgz": models.AttachmentFile,
+	".csv": models.AttachmentFile, ".doc": models.AttachmentFile, ".docx": models.AttachmentFile,
+	".xls": models.AttachmentFile, ".xlsx": models.AttachmentFile, ".ppt": models.AttachmentFile,
+	".pptx": models.AttachmentFile, ".epub": models.AttachmentFile,
+}
+
+// Attachments returns the images and downloadable files that markdown
+// content links to, resolved against pageURL, in order of first reference.
+// Links inside fenced code blocks and data: URLs are ignored.
+func Attachments(content, pageURL string) []models.Attachment {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var attachments []models.Attachment
+	seen := make(map[string]bool)
+	inCode := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+
+		for _, m := range markdownLink.FindAllStringSubmatch(line, -1) {
+			ref, err := url.Parse(m[3])
+			if err != nil || ref.Scheme == "data" {
+				continue
+			}
+			target := base.ResolveReference(ref)
+
+			kind, ok := attachmentKinds[strings.ToLower(path.Ext(target.Path))]
+			if m[1] == "!" {
+				kind, ok = models.AttachmentImage, true
+			}
+			if !ok || seen[target.String()] {
+				continue
+			}
+			seen[target.String()] = true
+
+			caption := strings.TrimSpace(m[2])
+			if caption == "" {
+				caption = strings.TrimSpace(m[4])
+			}
+			attachments = append(attachments, models.Attachment{
+				URL:     target.String(),
+				Kind:    kind,
+				Caption: caption,
+			})
+		}
+	}
+
+	return attachments
+}
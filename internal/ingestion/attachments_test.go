@@ -0,0 +1,27 @@
+package ingestion
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestAttachments(t *testing.T) {
+	content := "# Guide\n\n" +
+		"![Architecture diagram](/img/arch.png \"Overview\")\n\n" +
+		"Read the [spec](files/spec.PDF) or download [the archive](https://cdn.example.com/v1.zip).\n" +
+		"See [the next page](next.html) and ![](/img/arch.png) again.\n" +
+		"![](data:image/png;base64,AAAA) ![](icons/logo.svg \"Logo\")\n\n" +
+		"```\n![not an image](skip.png)\n```\n"
+
+	want := []models.Attachment{
+		{URL: "https://example.com/img/arch.png", Kind: models.AttachmentImage, Caption: "Architecture diagram"},
+		{URL: "https://example.com/docs/files/spec.PDF", Kind: models.AttachmentPDF, Caption: "spec"},
+		{URL: "https://cdn.example.com/v1.zip", Kind: models.AttachmentFile, Caption: "the archive"},
+		{URL: "https://example.com/docs/icons/logo.svg", Kind: models.AttachmentImage, Caption: "Logo"},
+	}
+	if got := Attachments(content, "https://example.com/docs/guide"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Attachments() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package ingestion
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// checkpointEvery is how many indexed documents are recorded in memory
+// before the checkpoint is written to S3. A crash loses at most this many
+// documents of progress.
+const checkpointEvery = 25
+
+// checkpoint tracks the files of a prefix indexed by the current run and
+// writes them under the prefix, so a crashed or cancelled run can be
+// resumed without redoing their enrichment and embedding.
+type checkpoint struct {
+	storage *storage.Client
+	prefix  string
+	files   []string // Indexed by this run or the runs it resumes
+	unsaved int
+}
+
+// resume loads the prefix's checkpoint and returns the files it has not
+// recorded as indexed, counting the others as resumed in report. With
+// SetFull, the checkpoint is discarded and every file is processed.
+func (e *Engine) resume(ctx context.Context, prefix string, files []string, report *storage.IngestReport) ([]string, *checkpoint) {
+	cp := &checkpoint{storage: e.storage, prefix: prefix}
+	if e.full {
+		return files, cp
+	}
+
+	saved, err := e.storage.GetCheckpoint(ctx, prefix)
+	if err != nil {
+		slog.Warn("failed to read checkpoint, processing all", "prefix", prefix, "error", err)
+		return files, cp
+	}
+	if saved == nil {
+		return files, cp
+	}
+
+	remaining, done := unfinished(files, saved.Files)
+	cp.files = done
+	report.Resumed = len(done)
+	slog.Info("resuming interrupted ingestion", "prefix", prefix, "done", len(done), "remaining", len(remaining))
+	return remaining, cp
+}
+
+// unfinished splits files into those not recorded in indexed and those
+// that are, keeping their order. Recorded files no longer in the prefix
+// are dropped.
+func unfinished(files, indexed []string) (remaining, done []string) {
+	recorded := make(map[string]bool, len(indexed))
+	for _, f := range indexed {
+		recorded[f] = true
+	}
+
+	for _, f := range files {
+		if recorded[f] {
+			done = append(done, f)
+		} else {
+			remaining = append(remaining, f)
+		}
+	}
+	return remaining, done
+}
+
+// add records filename as indexed, writing the checkpoint every
+// checkpointEvery files.
+func (c *checkpoint) add(ctx context.Context, filename string) {
+	c.files = append(c.files, filename)
+	c.unsaved++
+	if c.unsaved >= checkpointEvery {
+		c.save(ctx)
+	}
+}
+
+// save writes the files recorded so far, if any are unsaved. It is written
+// even if ctx is cancelled, since that is when a run needs it most.
+func (c *checkpoint) save(ctx context.Context) {
+	if c.unsaved == 0 {
+		return
+	}
+	err := c.storage.PutCheckpoint(context.WithoutCancel(ctx), c.prefix, storage.Checkpoint{
+		Files:     c.files,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Warn("failed to write checkpoint", "prefix", c.prefix, "error", err)
+		return
+	}
+	c.unsaved = 0
+}
+
+// finish removes the checkpoint of a run that went through all its files.
+func (c *checkpoint) finish(ctx context.Context) {
+	if err := c.storage.DeleteCheckpoint(ctx, c.prefix); err != nil {
+		slog.Warn("failed to delete checkpoint", "prefix", c.prefix, "error", err)
+	}
+}
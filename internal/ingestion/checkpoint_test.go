@@ -0,0 +1,19 @@
+package ingestion
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnfinished(t *testing.T) {
+	files := []string{"a.md", "b.md", "c.md", "d.md"}
+	indexed := []string{"c.md", "gone.md", "a.md"}
+
+	remaining, done := unfinished(files, indexed)
+	if want := []string{"b.md", "d.md"}; !slices.Equal(remaining, want) {
+		t.Errorf("remaining = %v, want %v", remaining, want)
+	}
+	if want := []string{"a.md", "c.md"}; !slices.Equal(done, want) {
+		t.Errorf("done = %v, want %v", done, want)
+	}
+}
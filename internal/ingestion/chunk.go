@@ -0,0 +1,99 @@
+package ingestion
+
+import (
+	"strings"
+
+	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// DefaultMaxChunkSize is the size in bytes above which a section is split
+// at paragraph breaks, keeping chunks within what embedding models take in.
+const DefaultMaxChunkSize = 4000
+
+// ChunkDocument splits the content of doc at its headings. Each chunk keeps
+// the path of headings enclosing it; sections without text below their
+// heading are left out, and sections larger than maxSize bytes are split
+// at paragraphs. A maxSize of 0 uses DefaultMaxChunkSize.
+func ChunkDocument(doc *models.Document, maxSize int) []models.Chunk {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxChunkSize
+	}
+
+	var chunks []models.Chunk
+	var path []markdown.Section // Enclosing headings, outermost first
+
+	for _, section := range markdown.Sections(doc.Content) {
+		if section.Level > 0 {
+			for len(path) > 0 && path[len(path)-1].Level >= section.Level {
+				path = path[:len(path)-1]
+			}
+			path = append(path, section)
+		}
+
+		content := doc.Content[section.Start:section.End]
+		if !hasBody(content, section.Level > 0) {
+			continue
+		}
+
+		headings := make([]string, len(path))
+		for i, s := range path {
+			headings[i] = s.Heading
+		}
+
+		for _, part := range splitParagraphs(strings.TrimSpace(content), maxSize) {
+			position := len(chunks)
+			chunks = append(chunks, models.Chunk{
+				ID:           models.GenerateChunkID(doc.ID, position),
+				DocumentID:   doc.ID,
+				URL:          doc.URL,
+				Title:        doc.Title,
+				Source:       doc.Source,
+				Language:     doc.Language,
+				ScrapedAt:    doc.ScrapedAt,
+				AccessLabels: doc.AccessLabels,
+				License:      doc.License,
+				HeadingPath:  headings,
+				Position:     position,
+				Content:      part,
+				WordCount:    CountWords(part),
+				TokenCount:   EstimateTokens(part),
+			})
+		}
+	}
+
+	return chunks
+}
+
+// hasBody reports whether a section has text besides its heading line.
+func hasBody(content string, headed bool) bool {
+	if headed {
+		_, content, _ = strings.Cut(content, "\n")
+	}
+	return strings.TrimSpace(content) != ""
+}
+
+// splitParagraphs splits text at blank lines into parts of at most size
+// bytes where possible. A single paragraph larger than size stays whole.
+func splitParagraphs(text string, size int) []string {
+	if len(text) <= size {
+		return []string{text}
+	}
+
+	var parts []string
+	var current strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if current.Len() > 0 && current.Len()+len(paragraph)+2 > size {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
@@ -0,0 +1,78 @@
+package ingestion
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestChunkDocument(t *testing.T) {
+	doc := &models.Document{
+		ID:    "abc",
+		URL:   "https://example.com/guide",
+		Title: "Guide",
+		Content: "Intro text.\n\n" +
+			"# Guide\n\n" +
+			"## Install\n\nRun the installer.\n\n" +
+			"### macOS\n\nUse brew.\n\n" +
+			"## Usage\n\n```\n# not a heading\n```\n",
+	}
+
+	chunks := ChunkDocument(doc, 0)
+
+	want := []struct {
+		path    []string
+		content string
+	}{
+		{nil, "Intro text."},
+		{[]string{"Guide", "Install"}, "## Install\n\nRun the installer."},
+		{[]string{"Guide", "Install", "macOS"}, "### macOS\n\nUse brew."},
+		{[]string{"Guide", "Usage"}, "## Usage\n\n```\n# not a heading\n```"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if !slices.Equal(c.HeadingPath, w.path) || c.Content != w.content {
+			t.Errorf("chunk %d = %q %q, want %q %q", i, c.HeadingPath, c.Content, w.path, w.content)
+		}
+		if c.Position != i || c.ID != models.GenerateChunkID("abc", i) {
+			t.Errorf("chunk %d has position %d and ID %q", i, c.Position, c.ID)
+		}
+		if c.WordCount != CountWords(c.Content) || c.TokenCount != EstimateTokens(c.Content) {
+			t.Errorf("chunk %d counts = %d words, %d tokens", i, c.WordCount, c.TokenCount)
+		}
+		if c.DocumentID != "abc" || c.URL != doc.URL || c.Title != "Guide" {
+			t.Errorf("chunk %d = %+v, want parent document fields", i, c)
+		}
+	}
+}
+
+func TestChunkDocument_MaxSize(t *testing.T) {
+	paragraph := strings.Repeat("x", 40)
+	doc := &models.Document{ID: "abc", Content: "## Notes\n\n" + paragraph + "\n\n" + paragraph}
+
+	if chunks := ChunkDocument(doc, 0); len(chunks) != 1 {
+		t.Errorf("ChunkDocument() with the default size = %d chunks, want 1", len(chunks))
+	}
+	if chunks := ChunkDocument(doc, 60); len(chunks) != 2 {
+		t.Errorf("ChunkDocument() with size 60 = %d chunks, want 2", len(chunks))
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	paragraph := strings.Repeat("x", 40)
+	text := strings.Join([]string{paragraph, paragraph, paragraph}, "\n\n")
+
+	parts := splitParagraphs(text, 100)
+	if len(parts) != 2 || parts[0] != paragraph+"\n\n"+paragraph || parts[1] != paragraph {
+		t.Errorf("splitParagraphs() = %q", parts)
+	}
+
+	if parts := splitParagraphs("short", 100); len(parts) != 1 || parts[0] != "short" {
+		t.Errorf("splitParagraphs() = %q, want the text unchanged", parts)
+	}
+}
@@ -0,0 +1,32 @@
+package ingestion
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// charsPerToken is the average number of characters per token assumed by
+// EstimateTokens, typical of BPE tokenizers on English prose.
+const charsPerToken = 4
+
+// CountWords returns the number of words in markdown text. Markup such as
+// heading markers and list bullets is not counted.
+func CountWords(text string) int {
+	n := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r)
+		}) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// EstimateTokens approximates the number of model tokens in text from its
+// length. Models tokenize differently, so the result is only good for
+// sizing and comparison.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
@@ -2,17 +2,22 @@ package ingestion
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
-	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/backend"
 	"github.com/mfenderov/bam-rag/internal/embeddings"
 	"github.com/mfenderov/bam-rag/internal/llm"
-	"github.com/mfenderov/bam-rag/internal/markdown"
 	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/slowops"
 	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
 	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Config holds ingestion engine configuration.
@@ -27,44 +32,143 @@ type Config struct {
 type Result struct {
 	Prefix      string
 	DocsIndexed int
+	Unchanged   int // Pages already indexed with the same content, not reprocessed
+	Resumed     int // Pages indexed by an interrupted earlier run, not reprocessed
 	Duration    time.Duration
-	Errors      []string
+	Errors      []*models.PageError
+	Warnings    []*models.PageError      // Pages indexed without their enrichment or some embeddings
+	Stages      map[string]time.Duration // Time spent in each stage, summed over documents
+	Report      string                   // Location of the run report; empty if it could not be written
+
+	// Interrupted is set if the run was cancelled before it went through
+	// every page. Its checkpoint lets the next run resume with the
+	// Remaining pages.
+	Interrupted bool
+	Remaining   int
 }
 
-// Engine reads scraped content from S3, enriches it, and indexes to Elasticsearch.
+// Stages of a document's ingestion, as named in run reports.
+const (
+	stageRead    = "read"
+	stageConvert = "convert"
+	stageEnrich  = "enrich"
+	stageSituate = "situate"
+	stageEmbed   = "embed"
+	stageIndex   = "index"
+)
+
+// Engine reads scraped content from S3, enriches it, and indexes it to the
+// search backend.
 type Engine struct {
-	storage     *storage.Client
-	esClient    *elasticsearch.Client
-	processor   *processor.Processor
-	embedClient *embeddings.Client // nil if embeddings disabled
-	llmClient   *llm.Client        // nil if LLM enrichment disabled
+	storage   *storage.Client       // nil for engines created by NewPages
+	index     backend.SearchBackend // nil for engines created by NewPages
+	indexer   Indexer               // Where the index stage writes
+	processor *processor.Processor
+	embedder  Embedder          // nil if embeddings disabled
+	enricher  Enricher          // nil if LLM enrichment disabled
+	progress  progress.Reporter // nil if progress reporting disabled
+	access    []string          // Access labels set on every document
+	slow      *slowops.Tracker  // nil if operation durations are not tracked
+
+	warmupConfig Warmup
+	full         bool // Reprocess pages even if unchanged since indexed
+	situate      bool // Prepend LLM-generated context to chunks
+	maxChunkSize int  // Size in bytes above which sections are split; 0 uses the default
+
+	refreshInterval string // Index refresh interval during a run; empty leaves it unchanged
 }
 
 // New creates a new ingestion engine.
 func New(
 	storageClient *storage.Client,
-	esClient *elasticsearch.Client,
+	index backend.SearchBackend,
 	embedClient *embeddings.Client,
 	llmClient *llm.Client,
 ) *Engine {
-	return &Engine{
-		storage:     storageClient,
-		esClient:    esClient,
-		processor:   processor.New(),
-		embedClient: embedClient,
-		llmClient:   llmClient,
+	e := &Engine{
+		storage:   storageClient,
+		index:     index,
+		indexer:   index,
+		processor: processor.New(),
+	}
+	// Nil clients must stay nil interfaces, so the stages are skipped
+	if embedClient != nil {
+		e.embedder = embedClient
 	}
+	if llmClient != nil {
+		e.enricher = llmClient
+	}
+	return e
+}
+
+// SetProgress sets a reporter that receives a document event for each
+// ingestion stage a document completes.
+func (e *Engine) SetProgress(r progress.Reporter) {
+	e.progress = r
+}
+
+// SetAccessLabels sets the access labels recorded on every document the
+// engine indexes.
+func (e *Engine) SetAccessLabels(labels []string) {
+	e.access = labels
+}
+
+// SetStreamThreshold sets the size in bytes above which HTML pages are
+// converted in a single streaming pass; 0 converts every page in full.
+func (e *Engine) SetStreamThreshold(n int64) {
+	e.processor.SetStreamThreshold(n)
+}
+
+// SetRedactor sets a redactor masking sensitive strings in documents once
+// converted to markdown, before they are chunked and indexed. Nil, the
+// default, indexes content unchanged.
+func (e *Engine) SetRedactor(r *processor.Redactor) {
+	e.processor.SetRedactor(r)
+}
+
+// SetRefreshInterval sets the refresh interval of the indices while
+// documents are indexed, such as "-1" to refresh only once at the end of a
+// run. Empty, the default, leaves the indices' interval unchanged.
+func (e *Engine) SetRefreshInterval(interval string) {
+	e.refreshInterval = interval
+}
+
+// SetSituateChunks sets whether chunks are given an LLM-generated
+// sentence situating them within their document before they are embedded
+// and indexed, which helps chunks that don't name their subject be found.
+// It costs one LLM request per chunk, and has no effect without an LLM
+// client.
+func (e *Engine) SetSituateChunks(situate bool) {
+	e.situate = situate
+}
+
+// SetMaxChunkSize sets the size in bytes above which a section of a page
+// is split into several chunks at paragraph breaks. 0, the default, uses
+// DefaultMaxChunkSize.
+func (e *Engine) SetMaxChunkSize(n int) {
+	e.maxChunkSize = n
+}
+
+// SetSlowOps sets a tracker that observes the duration of each enrichment,
+// embedding, and index call.
+func (e *Engine) SetSlowOps(t *slowops.Tracker) {
+	e.slow = t
 }
 
 // Ingest processes all documents from an S3 prefix and indexes them.
-func (e *Engine) Ingest(ctx context.Context, prefix string) (*Result, error) {
+// Returns an error along with the result if pages failed and none was
+// indexed; the prefix is only recorded as ingested if no page failed.
+func (e *Engine) Ingest(ctx context.Context, prefix string) (_ *Result, err error) {
+	ctx, span := telemetry.Start(ctx, "ingest", attribute.String("prefix", prefix))
+	defer func() { telemetry.End(span, err) }()
+
 	start := time.Now()
 	result := &Result{Prefix: prefix}
 
 	slog.Info("starting ingestion", "prefix", prefix)
 
-	// Ensure ES index exists
-	if err := e.esClient.CreateIndex(ctx); err != nil {
+	// Ensure the index exists
+	if err := e.index.CreateIndex(ctx); err != nil {
 		return nil, err
 	}
 
@@ -74,6 +178,15 @@ func (e *Engine) Ingest(ctx context.Context, prefix string) (*Result, error) {
 		return nil, err
 	}
 
+	// Fields shared by every document of the scrape
+	base := models.Document{
+		SiteName:     meta.SiteName,
+		Favicon:      meta.Favicon,
+		License:      meta.License,
+		Source:       models.Source{Name: meta.Source, Host: storage.HostFromPrefix(prefix)},
+		AccessLabels: e.access,
+	}
+
 	// Build URL -> filename mapping from metadata
 	urlToFile := make(map[string]string)
 	for _, pageURL := range meta.Pages {
@@ -89,115 +202,174 @@ func (e *Engine) Ingest(ctx context.Context, prefix string) (*Result, error) {
 
 	slog.Info("found files to ingest", "count", len(files))
 
-	// Process each file
-	for _, filename := range files {
-		if ctx.Err() != nil {
-			result.Errors = append(result.Errors, "context cancelled")
-			break
-		}
+	report := &storage.IngestReport{StartedAt: start.UTC(), Documents: len(files)}
+	files, cp := e.resume(ctx, prefix, files, report)
+	files = e.changedFiles(ctx, files, urlToFile, meta, base, report)
+	result.Unchanged = report.Unchanged
+	result.Resumed = report.Resumed
+	if len(files) > 0 {
+		e.warmup(ctx, prefix)
+	}
+	usage := e.usage()
 
-		// Get the original URL from metadata
-		pageURL, ok := urlToFile[filename]
-		if !ok {
-			slog.Warn("no URL found for file", "filename", filename)
-			pageURL = filename // fallback
-		}
+	restoreRefresh := e.suspendRefresh(ctx, len(files))
+	keepAliveCtx, stopKeepAlive := context.WithCancel(ctx)
+	go e.keepAlive(keepAliveCtx)
+	e.ingestFiles(ctx, prefix, files, urlToFile, meta, base, report, result, cp)
+	stopKeepAlive()
+	restoreRefresh()
 
-		// Read content from S3
-		content, err := e.storage.GetMarkdown(ctx, prefix, filename)
-		if err != nil {
-			result.Errors = append(result.Errors, err.Error())
-			continue
-		}
+	// Refresh index to make documents searchable immediately
+	if err := e.index.Refresh(context.WithoutCancel(ctx)); err != nil {
+		slog.Warn("failed to refresh index", "error", err)
+	}
 
-		// Process the content
-		doc, err := e.processDocument(ctx, pageURL, content)
-		if err != nil {
-			result.Errors = append(result.Errors, err.Error())
-			continue
+	// Record the prefix as ingested so --all can skip it next time.
+	// An interrupted run, or one in which pages failed, is left unmarked
+	// so it is picked up again, and resumed from its checkpoint: only the
+	// pages not indexed yet are retried.
+	switch {
+	case ctx.Err() != nil:
+	case len(result.Errors) > 0:
+		cp.save(ctx)
+	default:
+		cp.finish(ctx)
+		state := storage.IngestionState{
+			IngestedAt:  time.Now().UTC().Format(time.RFC3339),
+			DocsIndexed: result.DocsIndexed,
+			Errors:      len(result.Errors),
 		}
-
-		// Index to Elasticsearch
-		slog.Debug("indexing document", "id", doc.ID, "url", doc.URL, "tags", len(doc.Tags))
-		if err := e.esClient.IndexDocument(ctx, *doc); err != nil {
-			slog.Error("failed to index document", "id", doc.ID, "error", err)
-			result.Errors = append(result.Errors, err.Error())
-		} else {
-			slog.Debug("document indexed successfully", "id", doc.ID)
-			result.DocsIndexed++
+		if err := e.storage.PutIngestionState(ctx, prefix, state); err != nil {
+			slog.Warn("failed to record ingestion state", "prefix", prefix, "error", err)
 		}
 	}
 
-	// Refresh index to make documents searchable immediately
-	e.esClient.Refresh(ctx)
-
 	result.Duration = time.Since(start)
+	report.Indexed = result.DocsIndexed
+	report.Remaining = result.Remaining
+	result.Stages = report.Stages
+	report.Duration = result.Duration
+	report.Tokens.Embedding, report.Tokens.Prompt, report.Tokens.Completion = e.usage().since(usage)
+	// Written even if ctx is cancelled, so an interrupted run is reported
+	result.Report = e.writeReport(context.WithoutCancel(ctx), prefix, meta, report)
+
 	slog.Info("ingestion complete",
 		"prefix", prefix,
 		"docs_indexed", result.DocsIndexed,
 		"duration", result.Duration,
 		"errors", len(result.Errors))
 
+	// A run that indexed nothing but failed fails, so jobs retry it
+	if result.DocsIndexed == 0 && len(result.Errors) > 0 {
+		return result, fmt.Errorf("all %d documents failed: %s", len(result.Errors), result.Errors[0])
+	}
 	return result, nil
 }
 
-// processDocument converts content to markdown, enriches with LLM/embeddings.
-func (e *Engine) processDocument(ctx context.Context, pageURL, content string) (*models.Document, error) {
-	var mdContent string
-	var title string
-
-	// Check if content is already markdown
-	isMarkdown := markdown.Detect(pageURL, "", content)
-
-	if isMarkdown {
-		mdContent = content
-		title = extractMarkdownTitle(content)
-	} else {
-		// Content is HTML - extract title and convert
-		title = e.processor.ExtractTitle(content)
-		var err error
-		mdContent, err = e.processor.Convert(content)
-		if err != nil {
-			return nil, err
+// suspendRefresh sets the configured refresh interval for a run indexing
+// files documents and returns a function restoring the previous one. The
+// interval is restored even if ctx is cancelled, so an interrupted run
+// doesn't leave the index without refreshes.
+func (e *Engine) suspendRefresh(ctx context.Context, files int) (restore func()) {
+	if e.refreshInterval == "" || files == 0 {
+		return func() {}
+	}
+
+	reset, err := e.index.SetRefreshInterval(ctx, e.refreshInterval)
+	if err != nil {
+		slog.Warn("failed to set refresh interval", "interval", e.refreshInterval, "error", err)
+		return func() {}
+	}
+	slog.Debug("set refresh interval for ingestion", "interval", e.refreshInterval)
+
+	return func() {
+		if err := reset(context.WithoutCancel(ctx)); err != nil {
+			slog.Warn("failed to restore refresh interval", "error", err)
 		}
 	}
+}
 
-	if title == "" {
-		title = pageURL
+// writeReport adds report to the prefix's run report, keeping the section
+// of the scrape that produced it. Returns the report's location, or "" if
+// it could not be written.
+func (e *Engine) writeReport(ctx context.Context, prefix string, meta *storage.ScrapeMetadata, report *storage.IngestReport) string {
+	run, err := e.storage.GetRunReport(ctx, prefix)
+	if err != nil {
+		slog.Warn("failed to read run report", "prefix", prefix, "error", err)
 	}
+	if run == nil {
+		run = &storage.RunReport{Prefix: prefix, SourceURL: meta.SourceURL, Source: meta.Source}
+	}
+	run.Ingest = report
 
-	// Create document
-	doc := models.Document{
-		ID:        models.GenerateDocumentID(pageURL),
-		URL:       pageURL,
-		Title:     title,
-		Content:   mdContent,
-		ScrapedAt: time.Now(),
+	location, err := e.storage.PutRunReport(ctx, *run)
+	if err != nil {
+		slog.Warn("failed to write run report", "prefix", prefix, "error", err)
 	}
+	return location
+}
 
-	// Generate tags and summary using LLM if enabled
-	if e.llmClient != nil {
-		enrichment, err := e.llmClient.EnrichDocument(ctx, title, mdContent)
-		if err != nil {
-			slog.Warn("failed to enrich document", "url", pageURL, "error", err)
-		} else {
-			doc.Tags = enrichment.Tags
-			doc.Summary = enrichment.Summary
-			slog.Debug("document enriched", "url", pageURL, "tags", len(doc.Tags))
-		}
+// modelUsage is a snapshot of the tokens reported by the engine's models.
+type modelUsage struct {
+	embedding, prompt, completion int
+}
+
+// usage returns the tokens the engine's models have reported so far.
+func (e *Engine) usage() modelUsage {
+	var u modelUsage
+	if client, ok := e.embedder.(*embeddings.Client); ok {
+		u.embedding = client.Usage()
 	}
+	if client, ok := e.enricher.(*llm.Client); ok {
+		llmUsage := client.Usage()
+		u.prompt, u.completion = llmUsage.PromptTokens, llmUsage.CompletionTokens
+	}
+	return u
+}
 
-	// Generate embedding if enabled
-	if e.embedClient != nil {
-		embedding, err := e.embedClient.Embed(ctx, mdContent)
-		if err != nil {
-			slog.Warn("failed to generate embedding", "url", pageURL, "error", err)
-		} else {
-			doc.Embedding = embedding
-		}
+// since returns the embedding, prompt, and completion tokens reported
+// after the earlier snapshot.
+func (u modelUsage) since(earlier modelUsage) (embedding, prompt, completion int) {
+	return u.embedding - earlier.embedding, u.prompt - earlier.prompt, u.completion - earlier.completion
+}
+
+// reportDocument reports that a document reached stage, when current of
+// total documents are finished, including this one if stage finishes it.
+func (e *Engine) reportDocument(prefix, pageURL string, current, total int, stage progress.Stage, err error) {
+	if e.progress == nil {
+		return
+	}
+	event := progress.Event{
+		Type:    progress.EventDocument,
+		URL:     pageURL,
+		Prefix:  prefix,
+		Stage:   stage,
+		Current: current,
+		Total:   total,
 	}
+	if err != nil {
+		event.Error = pageError(pageURL, err)
+		event.Message = event.Error.Message
+	}
+	e.progress.Report(event)
+}
 
-	return &doc, nil
+// warn reports a non-fatal problem with a page.
+func (e *Engine) warn(prefix, pageURL string, err error) {
+	if e.progress == nil {
+		return
+	}
+	e.progress.Report(progress.Event{Type: progress.EventWarning, URL: pageURL, Prefix: prefix, Message: err.Error(), Error: pageError(pageURL, err)})
+}
+
+// pageError returns err as a page error, attributing it to pageURL if it
+// is not one already.
+func pageError(pageURL string, err error) *models.PageError {
+	var pageErr *models.PageError
+	if errors.As(err, &pageErr) {
+		return pageErr
+	}
+	return models.NewPageError(pageURL, "", err)
 }
 
 // extractMarkdownTitle extracts the first H1 heading from markdown content.
@@ -0,0 +1,73 @@
+package ingestion
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// SetFull sets whether every page is reprocessed. By default, pages already
+// indexed with the content hash recorded by the scrape, and with the same
+// fields from their source, are skipped.
+func (e *Engine) SetFull(full bool) {
+	e.full = full
+}
+
+// changedFiles returns the files whose page is not indexed with the
+// content hash recorded in the scrape's metadata and the fields of base,
+// counting the others as unchanged in report. Scrapes without hashes are
+// processed in full.
+func (e *Engine) changedFiles(ctx context.Context, files []string, urls map[string]string, meta *storage.ScrapeMetadata, base models.Document, report *storage.IngestReport) []string {
+	hashes := meta.Hashes
+	if e.full || len(hashes) == 0 {
+		return files
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, filename := range files {
+		if pageURL, ok := urls[filename]; ok && hashes[pageURL] != "" {
+			ids = append(ids, models.GenerateDocumentID(pageURL))
+		}
+	}
+	indexed, err := e.index.ContentHashes(ctx, ids)
+	if err != nil {
+		slog.Warn("failed to look up indexed pages, processing all", "error", err)
+		return files
+	}
+
+	changed := files[:0:0]
+	for _, filename := range files {
+		pageURL, ok := urls[filename]
+		if hash := indexedHash(hashes[pageURL], base, meta.Licenses[pageURL]); ok && hash != "" && indexed[models.GenerateDocumentID(pageURL)] == hash {
+			report.Unchanged++
+			continue
+		}
+		changed = append(changed, filename)
+	}
+	slog.Info("skipping unchanged pages", "unchanged", report.Unchanged, "changed", len(changed))
+	return changed
+}
+
+// indexedHash returns the content hash recorded on a document indexed from
+// a page with the given scrape hash and license: the page hash combined
+// with the fields every document of the scrape shares, so a page is
+// reprocessed when its source changes them, as when access labels are
+// granted, even if the page did not change. Returns "" without a hash.
+func indexedHash(hash string, base models.Document, license string) string {
+	if hash == "" {
+		return ""
+	}
+	fields, _ := json.Marshal(struct {
+		Hash         string
+		Source       models.Source
+		AccessLabels []string
+		SiteName     string
+		Favicon      string
+		License      string
+	}{hash, base.Source, base.AccessLabels, base.SiteName, base.Favicon, cmp.Or(license, base.License)})
+	return models.ContentHash(string(fields))
+}
@@ -0,0 +1,33 @@
+package ingestion
+
+import (
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestIndexedHash(t *testing.T) {
+	base := models.Document{Source: models.Source{Name: "docs", Host: "example.com"}}
+	hash := indexedHash("abc", base, "")
+
+	if got := indexedHash("", base, ""); got != "" {
+		t.Errorf("indexedHash() without a page hash = %q, want empty", got)
+	}
+	if got := indexedHash("abc", base, ""); got != hash {
+		t.Errorf("indexedHash() = %q, want the same hash again %q", got, hash)
+	}
+
+	labeled := base
+	labeled.AccessLabels = []string{"internal"}
+	changed := map[string]string{
+		"page":          indexedHash("def", base, ""),
+		"access labels": indexedHash("abc", labeled, ""),
+		"source":        indexedHash("abc", models.Document{Source: models.Source{Name: "other", Host: "example.com"}}, ""),
+		"license":       indexedHash("abc", base, "MIT"),
+	}
+	for name, got := range changed {
+		if got == hash {
+			t.Errorf("indexedHash() unchanged by a different %s", name)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package ingestion
+
+import (
+	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// outlineDepth is the deepest heading level kept in a document outline.
+const outlineDepth = 3
+
+// Outline returns the tree of H1-H3 headings in content. Each heading is
+// nested under the closest preceding heading of a lower level, so content
+// starting at H2 has its H2 headings at the top.
+func Outline(content string) []models.Heading {
+	var outline []models.Heading
+	var path []*models.Heading // Enclosing headings, outermost first
+
+	for _, section := range markdown.Sections(content) {
+		if section.Level == 0 || section.Level > outlineDepth {
+			continue
+		}
+		for len(path) > 0 && path[len(path)-1].Level >= section.Level {
+			path = path[:len(path)-1]
+		}
+
+		siblings := &outline
+		if len(path) > 0 {
+			siblings = &path[len(path)-1].Children
+		}
+		*siblings = append(*siblings, models.Heading{Text: section.Heading, Level: section.Level})
+		path = append(path, &(*siblings)[len(*siblings)-1])
+	}
+
+	return outline
+}
@@ -0,0 +1,37 @@
+package ingestion
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestOutline(t *testing.T) {
+	content := "Intro.\n\n" +
+		"## Overview\n\n" +
+		"# Guide\n\n" +
+		"## Install\n\n" +
+		"### macOS\n\n" +
+		"#### Homebrew\n\n" +
+		"### Linux\n\n" +
+		"## Usage\n\n```\n# not a heading\n```\n"
+
+	want := []models.Heading{
+		{Text: "Overview", Level: 2},
+		{Text: "Guide", Level: 1, Children: []models.Heading{
+			{Text: "Install", Level: 2, Children: []models.Heading{
+				{Text: "macOS", Level: 3},
+				{Text: "Linux", Level: 3},
+			}},
+			{Text: "Usage", Level: 2},
+		}},
+	}
+	if got := Outline(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("Outline() = %+v, want %+v", got, want)
+	}
+
+	if got := Outline("no headings"); got != nil {
+		t.Errorf("Outline() = %+v, want nil", got)
+	}
+}
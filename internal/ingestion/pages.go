@@ -0,0 +1,68 @@
+package ingestion
+
+import (
+	"context"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// NewPages creates an engine that ingests the pages handed to IngestPages
+// rather than the prefixes of a storage client, for programs embedding the
+// pipeline through pkg/bamrag. embedder and enricher may be nil.
+func NewPages(indexer Indexer, embedder Embedder, enricher Enricher) *Engine {
+	return &Engine{
+		indexer:   indexer,
+		processor: processor.New(),
+		embedder:  embedder,
+		enricher:  enricher,
+	}
+}
+
+// IngestPages runs scraped pages through the stages Ingest runs the pages
+// of a prefix through. Each page carries its fetched HTML or markdown as
+// Content, and may carry fields its scrape shares, such as SiteName,
+// Source, and License. Pages that fail are recorded in the result; the
+// error is only set if the index could not be prepared.
+func (e *Engine) IngestPages(ctx context.Context, pages []models.Document) (_ *Result, err error) {
+	ctx, span := telemetry.Start(ctx, "ingest", attribute.Int("pages", len(pages)))
+	defer func() { telemetry.End(span, err) }()
+
+	start := time.Now()
+	if err := e.indexer.CreateIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	report := &storage.IngestReport{StartedAt: start.UTC(), Documents: len(pages)}
+	run := &ingestRun{engine: e, report: report, total: len(pages)}
+	result := &Result{}
+
+	docs := make(chan *document, queueSize)
+	go func() {
+		defer close(docs)
+		for i, page := range pages {
+			base := page
+			base.Content = ""
+			base.AccessLabels = e.access
+			docCtx, span := telemetry.Start(ctx, "ingest.document", attribute.String("url", page.URL))
+			docs <- &document{
+				i: i, pageURL: page.URL, contentType: page.ContentType, ctx: docCtx, span: span,
+				base: base, hash: models.ContentHash(page.Content), license: page.License,
+				content: page.Content,
+			}
+		}
+	}()
+	run.collect(ctx, run.process(ctx, docs), result, nil)
+	result.Interrupted = ctx.Err() != nil
+
+	if err := e.indexer.Refresh(context.WithoutCancel(ctx)); err != nil {
+		result.Errors = append(result.Errors, models.NewPageError("", stageIndex, err))
+	}
+	result.Duration = time.Since(start)
+	result.Stages = report.Stages
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/internal/llm"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+type memIndexer struct {
+	docs   map[string]models.Document
+	chunks map[string][]models.Chunk
+}
+
+func (m *memIndexer) CreateIndex(ctx context.Context) error { return nil }
+func (m *memIndexer) Refresh(ctx context.Context) error     { return nil }
+
+func (m *memIndexer) IndexDocument(ctx context.Context, doc models.Document) error {
+	m.docs[doc.ID] = doc
+	return nil
+}
+
+func (m *memIndexer) IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error {
+	m.chunks[documentID] = chunks
+	return nil
+}
+
+type failingEnricher struct{}
+
+func (failingEnricher) EnrichDocument(ctx context.Context, title, content string) (*llm.EnrichmentResult, error) {
+	return nil, errors.New("model unavailable")
+}
+
+func (failingEnricher) SituateChunk(ctx context.Context, title, document, chunk string) (string, error) {
+	return "", errors.New("model unavailable")
+}
+
+func TestEngine_IngestPages(t *testing.T) {
+	index := &memIndexer{docs: map[string]models.Document{}, chunks: map[string][]models.Chunk{}}
+	e := NewPages(index, nil, failingEnricher{})
+	e.SetAccessLabels([]string{"team-docs"})
+
+	pages := []models.Document{
+		{URL: "https://example.com/install", ContentType: "text/html", Content: "<html><head><title>Install</title></head><body><p>Run the installer.</p></body></html>"},
+		{URL: "https://example.com/usage", ContentType: "text/markdown", Content: "# Usage\n\nSearch the index.", License: "CC-BY-4.0"},
+	}
+	result, err := e.IngestPages(context.Background(), pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DocsIndexed != 2 || len(result.Errors) != 0 {
+		t.Fatalf("IngestPages() = %+v", result)
+	}
+	// Enrichment failures leave the pages indexed, with a warning each
+	if len(result.Warnings) != 2 || result.Warnings[0].Stage != stageEnrich {
+		t.Errorf("Warnings = %v, want an enrich warning per page", result.Warnings)
+	}
+
+	install := index.docs[models.GenerateDocumentID("https://example.com/install")]
+	if install.Title != "Install" || install.Content == "" || install.Content == pages[0].Content {
+		t.Errorf("install page = %+v, want its HTML converted", install)
+	}
+	usage := index.docs[models.GenerateDocumentID("https://example.com/usage")]
+	if usage.Title != "Usage" || usage.License != "CC-BY-4.0" || len(usage.AccessLabels) != 1 {
+		t.Errorf("usage page = %+v", usage)
+	}
+	if len(index.chunks[usage.ID]) == 0 {
+		t.Error("usage page has no chunks")
+	}
+	// Failed enrichment clears the hash, so the next run reprocesses the page
+	if usage.ContentHash != "" {
+		t.Errorf("ContentHash = %q after failed enrichment, want empty", usage.ContentHash)
+	}
+}
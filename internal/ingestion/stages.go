@@ -0,0 +1,386 @@
+package ingestion
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/llm"
+	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/slowops"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queueSize is how many documents may wait between two stages. It bounds
+// the documents held in memory while a slow stage catches up.
+const queueSize = 4
+
+// Embedder generates vector embeddings. *embeddings.Client implements it.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// Enricher generates the tags and summary of a document, and the context
+// of its chunks. *llm.Client implements it.
+type Enricher interface {
+	EnrichDocument(ctx context.Context, title, content string) (*llm.EnrichmentResult, error)
+	SituateChunk(ctx context.Context, title, document, chunk string) (string, error)
+}
+
+// Indexer stores documents and their chunks. Every search backend
+// implements it.
+type Indexer interface {
+	CreateIndex(ctx context.Context) error
+	IndexDocument(ctx context.Context, doc models.Document) error
+	IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error
+	Refresh(ctx context.Context) error
+}
+
+// document is a scraped file moving through the ingestion stages.
+type document struct {
+	i           int
+	filename    string
+	pageURL     string
+	contentType string          // As served, if known; detected from the content otherwise
+	ctx         context.Context // Carries the document's span
+	span        trace.Span
+
+	base    models.Document // Fields shared by every document of the scrape
+	hash    string          // Content hash of the scraped page; empty if unknown
+	license string          // License the page declares, overriding the scrape's
+
+	content  string
+	doc      models.Document
+	chunks   []models.Chunk
+	warnings []*models.PageError // Parts the document is indexed without
+
+	err     error // Set by the stage that failed; later stages pass the document on
+	skipped bool  // Not attempted because the run was cancelled
+}
+
+// warn records that the document is indexed without what stage failed
+// to add. Only the first failure of a stage is kept.
+func (d *document) warn(stage string, err error) {
+	for _, w := range d.warnings {
+		if w.Stage == stage {
+			return
+		}
+	}
+	d.warnings = append(d.warnings, models.NewPageError(d.pageURL, stage, err))
+}
+
+// ingestRun is the state shared by the stages of one Ingest or
+// IngestPages call.
+type ingestRun struct {
+	engine   *Engine
+	prefix   string // Empty for IngestPages
+	report   *storage.IngestReport
+	total    int
+	finished atomic.Int64 // Documents indexed or failed so far
+}
+
+// ingestFiles runs files through the read, convert, enrich, embed, and
+// index stages, each working on one document while the next stage works
+// on the one before, and records the outcome of each in result. Indexed
+// files are recorded in cp.
+func (e *Engine) ingestFiles(ctx context.Context, prefix string, files []string, urls map[string]string, meta *storage.ScrapeMetadata, base models.Document, report *storage.IngestReport, result *Result, cp *checkpoint) {
+	run := &ingestRun{engine: e, prefix: prefix, report: report, total: len(files)}
+
+	docs := make(chan *document, queueSize)
+	go func() {
+		defer close(docs)
+		for i, filename := range files {
+			// Get the original URL from metadata
+			pageURL, ok := urls[filename]
+			if !ok {
+				slog.Warn("no URL found for file", "filename", filename)
+				pageURL = filename // fallback
+			}
+			docCtx, span := telemetry.Start(ctx, "ingest.document", attribute.String("url", pageURL))
+			docs <- &document{
+				i: i, filename: filename, pageURL: pageURL, ctx: docCtx, span: span,
+				base: base, hash: meta.Hashes[pageURL], license: meta.Licenses[pageURL],
+			}
+		}
+	}()
+
+	out := run.process(ctx, run.stage(ctx, docs, run.read))
+	run.collect(ctx, out, result, func(d *document) { cp.add(ctx, d.filename) })
+	if ctx.Err() != nil {
+		cp.save(ctx) // A finished run deletes its checkpoint instead
+		result.Interrupted = true
+	}
+}
+
+// process runs documents whose content is loaded through the convert,
+// enrich, embed, and index stages.
+func (r *ingestRun) process(ctx context.Context, docs <-chan *document) <-chan *document {
+	out := r.stage(ctx, docs, r.convert)
+	if r.engine.enricher != nil {
+		out = r.stage(ctx, out, r.enrich)
+		if r.engine.situate {
+			out = r.stage(ctx, out, r.situate)
+		}
+	}
+	if r.engine.embedder != nil {
+		out = r.stage(ctx, out, r.embed)
+	}
+	return r.stage(ctx, out, r.index)
+}
+
+// collect records the outcome of each document from out in result,
+// calling indexed for each document that was indexed.
+func (r *ingestRun) collect(ctx context.Context, out <-chan *document, result *Result, indexed func(*document)) {
+	var cancelled bool
+	for d := range out {
+		switch {
+		case d.skipped:
+			cancelled = true
+			r.report.Skipped++
+			result.Remaining++
+		case errors.Is(d.err, storage.ErrObjectTooLarge):
+			// Skipped rather than failed: retrying would not help
+			slog.Warn("skipping oversized page", "url", d.pageURL, "error", d.err)
+			r.report.Skipped++
+			r.engine.warn(r.prefix, d.pageURL, d.err)
+			r.done(d, progress.StageFailed)
+		case d.err != nil:
+			result.Errors = append(result.Errors, pageError(d.pageURL, d.err))
+			r.done(d, progress.StageFailed)
+		default:
+			result.DocsIndexed++
+			result.Warnings = append(result.Warnings, d.warnings...)
+			if indexed != nil {
+				indexed(d)
+			}
+			r.done(d, progress.StageIndexed)
+		}
+		telemetry.End(d.span, d.err)
+	}
+	if cancelled {
+		result.Errors = append(result.Errors, models.NewPageError("", "", ctx.Err()))
+	}
+}
+
+// stage runs fn on each document from in that is still being ingested,
+// in order, and passes every document on. Once ctx is done, documents are
+// passed on as skipped.
+func (r *ingestRun) stage(ctx context.Context, in <-chan *document, fn func(*document)) <-chan *document {
+	out := make(chan *document, queueSize)
+	go func() {
+		defer close(out)
+		for d := range in {
+			switch {
+			case d.err != nil || d.skipped:
+			case ctx.Err() != nil:
+				d.skipped = true
+			default:
+				fn(d)
+			}
+			out <- d
+		}
+	}()
+	return out
+}
+
+// progress reports that a document reached a stage it does not finish on.
+func (r *ingestRun) progress(d *document, stage progress.Stage) {
+	r.engine.reportDocument(r.prefix, d.pageURL, int(r.finished.Load()), r.total, stage, nil)
+}
+
+// done reports that a document finished on stage.
+func (r *ingestRun) done(d *document, stage progress.Stage) {
+	r.engine.reportDocument(r.prefix, d.pageURL, int(r.finished.Add(1)), r.total, stage, d.err)
+}
+
+// read loads a document's content from S3.
+func (r *ingestRun) read(d *document) {
+	start := time.Now()
+	content, err := r.engine.storage.GetMarkdown(d.ctx, r.prefix, d.filename)
+	r.report.Track(stageRead, start)
+	if err != nil {
+		if !errors.Is(err, storage.ErrObjectTooLarge) {
+			r.report.Fail(stageRead)
+		}
+		d.err = models.NewPageError(d.pageURL, stageRead, err)
+		return
+	}
+	d.content = content
+}
+
+// convert turns a document's content into markdown, builds the document
+// from the run's base, and splits it into chunks.
+func (r *ingestRun) convert(d *document) {
+	start := time.Now()
+	var mdContent, title, language string
+
+	if markdown.Detect(d.pageURL, d.contentType, d.content) {
+		mdContent = d.content
+		title = extractMarkdownTitle(d.content)
+	} else {
+		// Content is HTML - extract title and language, and convert
+		_, span := telemetry.Start(d.ctx, "ingest.convert", attribute.Int("html_chars", len(d.content)))
+		page, err := r.engine.processor.ConvertPage(d.content)
+		telemetry.End(span, err)
+		if err != nil {
+			r.report.Fail(stageConvert)
+			d.err = models.NewPageError(d.pageURL, stageConvert, err)
+			return
+		}
+		mdContent, title, language = page.Markdown, page.Title, page.Language
+	}
+	d.content = "" // Only the markdown is needed from here on
+	mdContent = r.engine.processor.Redact(mdContent)
+	title = r.engine.processor.Redact(title)
+
+	title = cmp.Or(title, d.base.Title, d.pageURL)
+	language = cmp.Or(language, d.base.Language)
+	if language == "" {
+		language = processor.DetectLanguage(mdContent)
+	}
+
+	doc := d.base
+	doc.ID = models.GenerateDocumentID(d.pageURL)
+	doc.URL = d.pageURL
+	doc.Title = title
+	doc.Content = mdContent
+	doc.Language = language
+	doc.Outline = Outline(mdContent)
+	doc.WordCount = CountWords(mdContent)
+	doc.TokenCount = EstimateTokens(mdContent)
+	doc.Attachments = Attachments(mdContent, d.pageURL)
+	doc.ContentHash = indexedHash(d.hash, d.base, d.license)
+	if d.license != "" {
+		doc.License = d.license
+	}
+	if doc.ScrapedAt.IsZero() {
+		doc.ScrapedAt = time.Now()
+	}
+	d.doc = doc
+	d.chunks = ChunkDocument(&d.doc, r.engine.maxChunkSize)
+
+	r.report.Track(stageConvert, start)
+	r.progress(d, progress.StageProcessed)
+}
+
+// enrich generates tags and a summary with the LLM. Failures leave the
+// document unenriched; BM25 search still works without them.
+func (r *ingestRun) enrich(d *document) {
+	start := time.Now()
+	enrichment, err := r.engine.enricher.EnrichDocument(d.ctx, d.doc.Title, d.doc.Content)
+	r.report.Track(stageEnrich, start)
+	r.engine.slow.Since(slowops.OpEnrich, d.pageURL, start)
+	if err != nil {
+		slog.Warn("failed to enrich document", "url", d.pageURL, "error", err)
+		r.report.Fail(stageEnrich)
+		d.warn(stageEnrich, err)
+		d.doc.ContentHash = "" // Reprocess on the next run rather than keep it unenriched
+		return
+	}
+	d.doc.Tags = enrichment.Tags
+	d.doc.Summary = enrichment.Summary
+	slog.Debug("document enriched", "url", d.pageURL, "tags", len(d.doc.Tags))
+	r.progress(d, progress.StageEnriched)
+}
+
+// situate generates the context of each chunk within its document.
+// Failures leave the chunk without context.
+func (r *ingestRun) situate(d *document) {
+	ctx, span := telemetry.Start(d.ctx, "ingest.situate", attribute.Int("chunks", len(d.chunks)))
+	defer span.End()
+
+	start := time.Now()
+	for i := range d.chunks {
+		chunkStart := time.Now()
+		situated, err := r.engine.enricher.SituateChunk(ctx, d.doc.Title, d.doc.Content, d.chunks[i].Content)
+		r.engine.slow.Since(slowops.OpEnrich, d.pageURL, chunkStart)
+		if err != nil {
+			slog.Warn("failed to situate chunk", "url", d.pageURL, "position", i, "error", err)
+			r.report.Fail(stageSituate)
+			d.warn(stageSituate, err)
+			d.doc.ContentHash = ""
+			continue
+		}
+		d.chunks[i].Context = situated
+	}
+	r.report.Track(stageSituate, start)
+}
+
+// embed generates embeddings of the document and its chunks. Failures
+// leave the affected parts without an embedding.
+func (r *ingestRun) embed(d *document) {
+	ctx, span := telemetry.Start(d.ctx, "ingest.embed", attribute.Int("chunks", len(d.chunks)))
+	defer span.End()
+
+	start := time.Now()
+	embedding, err := r.engine.embedder.Embed(ctx, d.doc.Content)
+	r.engine.slow.Since(slowops.OpEmbed, d.pageURL, start)
+	if err != nil {
+		slog.Warn("failed to generate embedding", "url", d.pageURL, "error", err)
+		r.report.Fail(stageEmbed)
+		d.warn(stageEmbed, err)
+		d.doc.ContentHash = ""
+	} else {
+		d.doc.Embedding = embedding
+	}
+	for i := range d.chunks {
+		chunkStart := time.Now()
+		embedding, err := r.engine.embedder.Embed(ctx, embeddingText(d.chunks[i]))
+		r.engine.slow.Since(slowops.OpEmbed, d.pageURL, chunkStart)
+		if err != nil {
+			slog.Warn("failed to generate chunk embedding", "url", d.pageURL, "position", i, "error", err)
+			r.report.Fail(stageEmbed)
+			d.warn(stageEmbed, err)
+			d.doc.ContentHash = ""
+			continue
+		}
+		d.chunks[i].Embedding = embedding
+	}
+	r.report.Track(stageEmbed, start)
+	if d.doc.Embedding != nil {
+		r.progress(d, progress.StageEmbedded)
+	}
+}
+
+// embeddingText returns the text embedded for a chunk: its content,
+// preceded by its context if it has one.
+func embeddingText(chunk models.Chunk) string {
+	if chunk.Context == "" {
+		return chunk.Content
+	}
+	return chunk.Context + "\n\n" + chunk.Content
+}
+
+// index writes the document, then its chunks, to the search backend.
+func (r *ingestRun) index(d *document) {
+	slog.Debug("indexing document", "id", d.doc.ID, "url", d.pageURL, "tags", len(d.doc.Tags), "chunks", len(d.chunks))
+	ctx, span := telemetry.Start(d.ctx, "ingest.index", attribute.Int("chunks", len(d.chunks)))
+	start := time.Now()
+	err := r.engine.indexer.IndexDocument(ctx, d.doc)
+	if err == nil {
+		err = r.engine.indexer.IndexChunks(ctx, d.doc.ID, d.chunks)
+	}
+	r.report.Track(stageIndex, start)
+	r.engine.slow.Since(slowops.OpIndex, d.pageURL, start)
+	telemetry.End(span, err)
+	if err != nil {
+		slog.Error("failed to index document", "id", d.doc.ID, "error", err)
+		r.report.Fail(stageIndex)
+		d.err = models.NewPageError(d.pageURL, stageIndex, err)
+		return
+	}
+	r.report.Tokens.Indexed += d.doc.TokenCount
+	slog.Debug("document indexed successfully", "id", d.doc.ID)
+
+	// Release the content; the document waits for the collector only to be counted
+	d.doc, d.chunks = models.Document{}, nil
+}
@@ -0,0 +1,74 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestIngestRun_Stage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run := &ingestRun{}
+
+	in := make(chan *document)
+	go func() {
+		defer close(in)
+		for i := range 6 {
+			in <- &document{i: i}
+		}
+	}()
+
+	// The first stage fails document 1 and cancels the run after document 3
+	var seen []int
+	out := run.stage(ctx, in, func(d *document) {
+		if d.i == 1 {
+			d.err = errors.New("convert failed")
+		}
+		if d.i == 3 {
+			cancel()
+		}
+	})
+	out = run.stage(ctx, out, func(d *document) { seen = append(seen, d.i) })
+
+	var order []int
+	var skipped int
+	for d := range out {
+		order = append(order, d.i)
+		if d.skipped {
+			skipped++
+		}
+	}
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("documents came out as %v, want in order", order)
+		}
+	}
+	if len(order) != 6 {
+		t.Errorf("got %d documents, want 6", len(order))
+	}
+	// The second stage may or may not have run on document 3 before the
+	// cancellation; it never sees the failed document or those after it
+	for _, i := range seen {
+		if i == 1 || i > 3 {
+			t.Errorf("second stage ran on document %d", i)
+		}
+	}
+	if skipped < 2 {
+		t.Errorf("skipped %d documents, want at least 2", skipped)
+	}
+}
+
+func TestEmbeddingText(t *testing.T) {
+	chunk := models.Chunk{Content: "Set replicas to 3."}
+	if got := embeddingText(chunk); got != chunk.Content {
+		t.Errorf("embeddingText() = %q, want the content", got)
+	}
+	chunk.Context = "Scaling a Deployment in the Kubernetes docs."
+	if got := embeddingText(chunk); got != chunk.Context+"\n\n"+chunk.Content {
+		t.Errorf("embeddingText() = %q, want the context then the content", got)
+	}
+}
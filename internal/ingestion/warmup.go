@@ -0,0 +1,87 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/embeddings"
+	"github.com/mfenderov/bam-rag/internal/llm"
+)
+
+// Warmup configures preparing models for a run, so first-document latency
+// and model eviction don't distort run times.
+type Warmup struct {
+	Enabled   bool          // Load each model before processing begins
+	KeepAlive time.Duration // Interval between pings to each model during a run; 0 disables
+}
+
+// SetWarmup sets how models are prepared for each ingestion.
+func (e *Engine) SetWarmup(w Warmup) {
+	e.warmupConfig = w
+}
+
+// model is a model client that can be warmed up.
+type model struct {
+	name   string
+	warmup func(context.Context) error
+}
+
+// models returns the models the engine uses.
+func (e *Engine) models() []model {
+	var models []model
+	if client, ok := e.embedder.(*embeddings.Client); ok {
+		models = append(models, model{name: "embeddings", warmup: client.Warmup})
+	}
+	if client, ok := e.enricher.(*llm.Client); ok {
+		models = append(models, model{name: "llm", warmup: client.Warmup})
+	}
+	return models
+}
+
+// warmup loads every model concurrently. Failures are reported as warnings;
+// the run goes on and surfaces any lasting problem per document.
+func (e *Engine) warmup(ctx context.Context, prefix string) {
+	models := e.models()
+	if !e.warmupConfig.Enabled || len(models) == 0 {
+		return
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, m := range models {
+		wg.Go(func() {
+			if err := m.warmup(ctx); err != nil {
+				slog.Warn("model warmup failed", "model", m.name, "error", err)
+				e.warn(prefix, "", fmt.Errorf("%s warmup: %w", m.name, err))
+			}
+		})
+	}
+	wg.Wait()
+	slog.Info("models warmed up", "duration", time.Since(start))
+}
+
+// keepAlive pings every model at the configured interval until ctx is done.
+func (e *Engine) keepAlive(ctx context.Context) {
+	models := e.models()
+	if e.warmupConfig.KeepAlive <= 0 || len(models) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.warmupConfig.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, m := range models {
+				if err := m.warmup(ctx); err != nil && ctx.Err() == nil {
+					slog.Debug("model keep-alive failed", "model", m.name, "error", err)
+				}
+			}
+		}
+	}
+}
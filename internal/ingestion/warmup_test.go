@@ -0,0 +1,66 @@
+package ingestion
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/embeddings"
+)
+
+// newCountingEmbedder returns an embeddings client whose model server counts
+// the requests it receives.
+func newCountingEmbedder(t *testing.T, requests *atomic.Int64) *embeddings.Client {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "model.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create Unix socket: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"data":[{"embedding":[0.1]}]}`))
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	client, err := embeddings.New(embeddings.Config{SocketPath: socketPath, Model: "test-model"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestEngine_Warmup(t *testing.T) {
+	var requests atomic.Int64
+	e := New(nil, nil, newCountingEmbedder(t, &requests), nil)
+
+	e.warmup(t.Context(), "prefix")
+	if got := requests.Load(); got != 0 {
+		t.Errorf("disabled warmup sent %d requests, want 0", got)
+	}
+
+	e.SetWarmup(Warmup{Enabled: true})
+	e.warmup(t.Context(), "prefix")
+	if got := requests.Load(); got != 1 {
+		t.Errorf("warmup sent %d requests, want 1", got)
+	}
+}
+
+func TestEngine_KeepAlive(t *testing.T) {
+	var requests atomic.Int64
+	e := New(nil, nil, newCountingEmbedder(t, &requests), nil)
+	e.SetWarmup(Warmup{KeepAlive: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 55*time.Millisecond)
+	defer cancel()
+	e.keepAlive(ctx)
+
+	if got := requests.Load(); got < 2 {
+		t.Errorf("keep-alive sent %d pings, want at least 2", got)
+	}
+}
@@ -0,0 +1,258 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// leaseDuration is how long a running job stays claimed without a renewal.
+// The process running it renews the lease every third of that, so a job
+// whose lease ran out belongs to a process that exited.
+const leaseDuration = 5 * time.Minute
+
+// ErrClaimed is returned by Run when another process is running the job.
+var ErrClaimed = errors.New("job is running in another process")
+
+// Store persists jobs. It is implemented by *storage.Client.
+type Store interface {
+	GetJob(ctx context.Context, prefix string) (*storage.Job, error)
+	UpdateJob(ctx context.Context, job storage.Job) (storage.Job, error)
+	ListJobs(ctx context.Context) ([]storage.Job, error)
+}
+
+// Queue runs ingestion jobs, recording each attempt in a Store so failed
+// and interrupted jobs outlive the process and can be retried. A job is
+// claimed with a lease before it runs, so only one process runs it at a time.
+type Queue struct {
+	store       Store
+	maxAttempts int
+	backoff     time.Duration
+	owner       string
+	lease       time.Duration
+	now         func() time.Time
+}
+
+// New creates a queue that tries each job up to maxAttempts times per run,
+// waiting backoff before the first retry and doubling the wait after each
+// failure.
+func New(store Store, maxAttempts int, backoff time.Duration) *Queue {
+	host, _ := os.Hostname()
+	return &Queue{
+		store:       store,
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     backoff,
+		owner:       fmt.Sprintf("%s/%d", cmp.Or(host, "unknown"), os.Getpid()),
+		lease:       leaseDuration,
+		now:         time.Now,
+	}
+}
+
+// Run ingests prefix with fn, retrying with backoff until it succeeds or
+// the run's attempts are used up. The stored job is claimed first and its
+// attempts keep counting from earlier runs; ErrClaimed is returned if
+// another process holds it. The job is left failed if every attempt fails,
+// and pending if ctx is cancelled, so a later run picks it up either way.
+func (q *Queue) Run(ctx context.Context, prefix, source string, fn func(context.Context) error) error {
+	job, err := q.load(ctx, prefix, source)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		job.Attempts++
+		job.Status = storage.JobRunning
+		job.NextAttempt = time.Time{}
+		if err := q.claim(ctx, &job); err != nil {
+			return err
+		}
+
+		err := q.runLeased(ctx, &job, fn)
+		if errors.Is(err, ErrClaimed) {
+			return err
+		}
+		if err == nil {
+			job.Status = storage.JobDone
+			job.LastError = ""
+			q.release(ctx, &job)
+			return nil
+		}
+
+		job.LastError = err.Error()
+		if ctx.Err() != nil {
+			job.Status = storage.JobPending
+			q.release(ctx, &job)
+			return err
+		}
+
+		job.Status = storage.JobFailed
+		if attempt >= q.maxAttempts {
+			q.release(ctx, &job)
+			return err
+		}
+
+		delay := q.backoff << (attempt - 1)
+		job.NextAttempt = q.now().Add(delay)
+		q.release(ctx, &job)
+		slog.Warn("ingestion failed, retrying", "prefix", prefix, "attempt", job.Attempts, "delay", delay, "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			job.Status = storage.JobPending
+			q.release(ctx, &job)
+			return err
+		}
+	}
+}
+
+// load reads the stored job for prefix, or starts a new one. Fails with
+// ErrClaimed if another process holds an unexpired lease on it.
+func (q *Queue) load(ctx context.Context, prefix, source string) (storage.Job, error) {
+	stored, err := q.store.GetJob(ctx, prefix)
+	if err != nil {
+		return storage.Job{}, fmt.Errorf("failed to read job: %w", err)
+	}
+	if stored == nil {
+		return storage.Job{Prefix: prefix, Source: source}, nil
+	}
+	if q.held(*stored) && stored.Owner != q.owner {
+		return storage.Job{}, fmt.Errorf("%w (%s)", ErrClaimed, stored.Owner)
+	}
+	job := *stored
+	job.Source = cmp.Or(source, job.Source)
+	return job, nil
+}
+
+// claim records job as running under a fresh lease held by this process.
+func (q *Queue) claim(ctx context.Context, job *storage.Job) error {
+	job.Owner = q.owner
+	job.LeaseUntil = q.now().Add(q.lease)
+	return q.put(ctx, job)
+}
+
+// release records job's outcome and gives up its lease.
+func (q *Queue) release(ctx context.Context, job *storage.Job) {
+	job.Owner = ""
+	job.LeaseUntil = time.Time{}
+	if err := q.put(ctx, job); err != nil {
+		slog.Warn("job was taken over by another process", "prefix", job.Prefix, "error", err)
+	}
+}
+
+// runLeased runs fn while renewing job's lease. If another process takes the
+// job over, fn's context is cancelled and ErrClaimed returned.
+func (q *Queue) runLeased(ctx context.Context, job *storage.Job, fn func(context.Context) error) error {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(q.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := q.claim(runCtx, job); errors.Is(err, ErrClaimed) {
+					cancel(err)
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	err := fn(runCtx)
+	close(stop)
+	wg.Wait()
+
+	if cause := context.Cause(runCtx); errors.Is(cause, ErrClaimed) {
+		return cause
+	}
+	return err
+}
+
+// put records job if it is still the version this process last wrote,
+// failing with ErrClaimed otherwise. Other failures are logged rather than
+// returned so that a storage hiccup does not fail an otherwise successful
+// ingestion.
+func (q *Queue) put(ctx context.Context, job *storage.Job) error {
+	job.UpdatedAt = q.now()
+	// The final state must land even if ctx was cancelled
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	updated, err := q.store.UpdateJob(ctx, *job)
+	if errors.Is(err, storage.ErrJobChanged) {
+		return fmt.Errorf("%w: %s changed while claimed", ErrClaimed, job.Prefix)
+	}
+	if err != nil {
+		slog.Warn("failed to record ingestion job", "prefix", job.Prefix, "status", job.Status, "error", err)
+		return nil
+	}
+	*job = updated
+	return nil
+}
+
+// held reports whether job is running under a lease that has not expired.
+// Jobs recorded before leases existed count from their last update.
+func (q *Queue) held(job storage.Job) bool {
+	if job.Status != storage.JobRunning {
+		return false
+	}
+	until := job.LeaseUntil
+	if until.IsZero() {
+		until = job.UpdatedAt.Add(q.lease)
+	}
+	return q.now().Before(until)
+}
+
+// Unfinished returns the stored jobs that still need to run: pending and
+// failed jobs, and running jobs whose lease expired.
+func (q *Queue) Unfinished(ctx context.Context) ([]storage.Job, error) {
+	return q.list(ctx, func(storage.Job) bool { return true })
+}
+
+// Due returns the unfinished jobs that are ready to run again without
+// operator action: interrupted jobs, failed jobs whose retry time has
+// passed, and running jobs whose lease expired. Jobs that used up their
+// attempts are left for 'jobs retry'.
+func (q *Queue) Due(ctx context.Context) ([]storage.Job, error) {
+	now := q.now()
+	return q.list(ctx, func(job storage.Job) bool {
+		if job.Status != storage.JobFailed {
+			return true
+		}
+		return !job.NextAttempt.IsZero() && !job.NextAttempt.After(now)
+	})
+}
+
+// list returns the unfinished jobs accepted by keep.
+func (q *Queue) list(ctx context.Context, keep func(storage.Job) bool) ([]storage.Job, error) {
+	jobs, err := q.store.ListJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var unfinished []storage.Job
+	for _, job := range jobs {
+		if job.Status == storage.JobDone || q.held(job) {
+			continue
+		}
+		if keep(job) {
+			unfinished = append(unfinished, job)
+		}
+	}
+	return unfinished, nil
+}
@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// memStore records every job write.
+type memStore struct {
+	mu       sync.Mutex
+	history  []storage.Job
+	jobs     map[string]storage.Job
+	versions int
+}
+
+func newMemStore() *memStore {
+	return &memStore{jobs: make(map[string]storage.Job)}
+}
+
+func (s *memStore) GetJob(ctx context.Context, prefix string) (*storage.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[prefix]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (s *memStore) UpdateJob(ctx context.Context, job storage.Job) (storage.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs[job.Prefix].Version != job.Version {
+		return job, storage.ErrJobChanged
+	}
+	s.versions++
+	job.Version = fmt.Sprint(s.versions)
+	s.history = append(s.history, job)
+	s.jobs[job.Prefix] = job
+	return job, nil
+}
+
+func (s *memStore) ListJobs(ctx context.Context) ([]storage.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []storage.Job
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func statuses(history []storage.Job) []storage.JobStatus {
+	var s []storage.JobStatus
+	for _, job := range history {
+		s = append(s, job.Status)
+	}
+	return s
+}
+
+func TestQueue_Run(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		failures     int // Attempts that fail before fn succeeds
+		wantErr      bool
+		wantStatus   storage.JobStatus
+		wantAttempts int
+		wantHistory  []storage.JobStatus
+	}{
+		{
+			name:         "succeeds first time",
+			wantStatus:   storage.JobDone,
+			wantAttempts: 1,
+			wantHistory:  []storage.JobStatus{storage.JobRunning, storage.JobDone},
+		},
+		{
+			name:         "succeeds after retry",
+			failures:     1,
+			wantStatus:   storage.JobDone,
+			wantAttempts: 2,
+			wantHistory:  []storage.JobStatus{storage.JobRunning, storage.JobFailed, storage.JobRunning, storage.JobDone},
+		},
+		{
+			name:         "gives up after max attempts",
+			failures:     5,
+			wantErr:      true,
+			wantStatus:   storage.JobFailed,
+			wantAttempts: 3,
+			wantHistory: []storage.JobStatus{
+				storage.JobRunning, storage.JobFailed,
+				storage.JobRunning, storage.JobFailed,
+				storage.JobRunning, storage.JobFailed,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemStore()
+			q := New(store, 3, time.Millisecond)
+
+			calls := 0
+			err := q.Run(t.Context(), "scrapes/example.com/1", "example", func(ctx context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return errBoom
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			job := store.jobs["scrapes/example.com/1"]
+			if job.Status != tt.wantStatus || job.Attempts != tt.wantAttempts {
+				t.Errorf("job = %s after %d attempts, want %s after %d", job.Status, job.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if job.Source != "example" {
+				t.Errorf("Source = %q, want example", job.Source)
+			}
+			if tt.wantErr && job.LastError != "boom" {
+				t.Errorf("LastError = %q, want boom", job.LastError)
+			}
+			if got := statuses(store.history); len(got) != len(tt.wantHistory) {
+				t.Errorf("history = %v, want %v", got, tt.wantHistory)
+			} else {
+				for i := range got {
+					if got[i] != tt.wantHistory[i] {
+						t.Errorf("history = %v, want %v", got, tt.wantHistory)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestQueue_RunInterrupted(t *testing.T) {
+	store := newMemStore()
+	q := New(store, 3, time.Hour)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	err := q.Run(ctx, "scrapes/example.com/1", "", func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if err == nil {
+		t.Fatal("Run() should return the interruption error")
+	}
+	if job := store.jobs["scrapes/example.com/1"]; job.Status != storage.JobPending {
+		t.Errorf("Status = %s, want pending so the job is resumed", job.Status)
+	}
+}
+
+func TestQueue_RunClaims(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemStore()
+	store.jobs["held"] = storage.Job{Prefix: "held", Status: storage.JobRunning, Owner: "other/1", LeaseUntil: now.Add(time.Minute)}
+	store.jobs["expired"] = storage.Job{Prefix: "expired", Status: storage.JobRunning, Attempts: 2, Owner: "other/1", LeaseUntil: now.Add(-time.Minute)}
+
+	q := New(store, 3, time.Millisecond)
+	q.now = func() time.Time { return now }
+	ran := func(ctx context.Context) error { return nil }
+
+	if err := q.Run(t.Context(), "held", "", ran); !errors.Is(err, ErrClaimed) {
+		t.Errorf("Run() on a held job error = %v, want ErrClaimed", err)
+	}
+
+	// An expired lease is taken over, and attempts keep counting
+	if err := q.Run(t.Context(), "expired", "", ran); err != nil {
+		t.Fatalf("Run() on an expired job error = %v", err)
+	}
+	if job := store.jobs["expired"]; job.Status != storage.JobDone || job.Attempts != 3 || job.Owner != "" {
+		t.Errorf("job = %+v, want done after 3 attempts without an owner", job)
+	}
+	if claim := store.history[0]; claim.Owner != q.owner || !claim.LeaseUntil.Equal(now.Add(leaseDuration)) {
+		t.Errorf("claim = %+v, want owned by %s with a lease", claim, q.owner)
+	}
+}
+
+func TestQueue_RunLeaseLost(t *testing.T) {
+	store := newMemStore()
+	q := New(store, 3, time.Millisecond)
+	q.lease = 30 * time.Millisecond
+
+	renewed := make(chan struct{})
+	err := q.Run(t.Context(), "scrapes/example.com/1", "", func(ctx context.Context) error {
+		// Another process takes the job over between renewals
+		store.mu.Lock()
+		job := store.jobs["scrapes/example.com/1"]
+		job.Owner, job.Version = "other/1", "taken"
+		store.jobs[job.Prefix] = job
+		store.mu.Unlock()
+		close(renewed)
+
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-renewed
+	if !errors.Is(err, ErrClaimed) {
+		t.Fatalf("Run() error = %v, want ErrClaimed", err)
+	}
+	if job := store.jobs["scrapes/example.com/1"]; job.Owner != "other/1" {
+		t.Errorf("Owner = %q, want the job left to other/1", job.Owner)
+	}
+}
+
+func TestQueue_Unfinished(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemStore()
+	for _, job := range []storage.Job{
+		{Prefix: "done", Status: storage.JobDone, UpdatedAt: now},
+		{Prefix: "failed", Status: storage.JobFailed, UpdatedAt: now},
+		{Prefix: "pending", Status: storage.JobPending, UpdatedAt: now},
+		{Prefix: "running", Status: storage.JobRunning, LeaseUntil: now.Add(time.Minute), UpdatedAt: now.Add(-time.Hour)},
+		{Prefix: "stale", Status: storage.JobRunning, LeaseUntil: now.Add(-time.Minute), UpdatedAt: now.Add(-2 * time.Hour)},
+	} {
+		store.jobs[job.Prefix] = job
+	}
+
+	q := New(store, 3, time.Second)
+	q.now = func() time.Time { return now }
+
+	jobs, err := q.Unfinished(t.Context())
+	if err != nil {
+		t.Fatalf("Unfinished() error = %v", err)
+	}
+	got := make(map[string]bool)
+	for _, job := range jobs {
+		got[job.Prefix] = true
+	}
+	if len(got) != 3 || !got["failed"] || !got["pending"] || !got["stale"] {
+		t.Errorf("Unfinished() = %v, want failed, pending, and stale", got)
+	}
+}
+
+func TestQueue_Due(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemStore()
+	for _, job := range []storage.Job{
+		{Prefix: "done", Status: storage.JobDone, UpdatedAt: now},
+		{Prefix: "pending", Status: storage.JobPending, UpdatedAt: now},
+		{Prefix: "retry-due", Status: storage.JobFailed, NextAttempt: now.Add(-time.Second), UpdatedAt: now},
+		{Prefix: "retry-later", Status: storage.JobFailed, NextAttempt: now.Add(time.Minute), UpdatedAt: now},
+		{Prefix: "exhausted", Status: storage.JobFailed, UpdatedAt: now},
+		{Prefix: "stale", Status: storage.JobRunning, UpdatedAt: now.Add(-2 * time.Hour)},
+	} {
+		store.jobs[job.Prefix] = job
+	}
+
+	q := New(store, 3, time.Second)
+	q.now = func() time.Time { return now }
+
+	jobs, err := q.Due(t.Context())
+	if err != nil {
+		t.Fatalf("Due() error = %v", err)
+	}
+	got := make(map[string]bool)
+	for _, job := range jobs {
+		got[job.Prefix] = true
+	}
+	if len(got) != 3 || !got["pending"] || !got["retry-due"] || !got["stale"] {
+		t.Errorf("Due() = %v, want pending, retry-due, and stale", got)
+	}
+}
@@ -10,18 +10,54 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/modelrunner"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // Config holds LLM client configuration.
 type Config struct {
 	SocketPath string // Unix socket path for Docker Model Runner
 	Model      string // Model name (e.g., "ai/gemma3")
+
+	// Connection tuning; zero values use the defaults below
+	MaxIdleConns    int           // Idle connections kept open for reuse
+	IdleConnTimeout time.Duration // How long an unused connection is kept open
+	Timeout         time.Duration // Limit on a single request, including reading the response
+
+	// Limiter is shared with the other clients of the same Docker Model
+	// Runner so their requests take turns; nil sends requests unrestricted
+	Limiter *modelrunner.Limiter
 }
 
+// Connection defaults. Long ingestions keep reusing connections to the
+// socket rather than redialing, which net/http's default of two idle
+// connections per host does not allow once requests overlap.
+const (
+	defaultMaxIdleConns    = 8
+	defaultIdleConnTimeout = 90 * time.Second
+	defaultTimeout         = 10 * time.Minute
+)
+
 // Client wraps the Docker Model Runner chat completions API.
 type Client struct {
 	httpClient *http.Client
 	model      string
+	limiter    *modelrunner.Limiter
+
+	// Tokens reported by the model since the client was created
+	promptTokens     atomic.Int64
+	completionTokens atomic.Int64
+}
+
+// Usage counts the tokens processed by the model.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
 }
 
 // New creates a new LLM client.
@@ -33,15 +69,30 @@ func New(config Config) (*Client, error) {
 		return nil, fmt.Errorf("model is required")
 	}
 
+	if config.MaxIdleConns == 0 {
+		config.MaxIdleConns = defaultMaxIdleConns
+	}
+	if config.IdleConnTimeout == 0 {
+		config.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	var dialer net.Dialer
 	transport := &http.Transport{
 		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-			return net.Dial("unix", config.SocketPath)
+			return dialer.DialContext(ctx, "unix", config.SocketPath)
 		},
+		MaxIdleConns:        config.MaxIdleConns,
+		MaxIdleConnsPerHost: config.MaxIdleConns,
+		IdleConnTimeout:     config.IdleConnTimeout,
 	}
 
 	return &Client{
-		httpClient: &http.Client{Transport: transport},
+		httpClient: &http.Client{Transport: transport, Timeout: config.Timeout},
 		model:      config.Model,
+		limiter:    config.Limiter,
 	}, nil
 }
 
@@ -64,6 +115,10 @@ type chatResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
@@ -76,7 +131,10 @@ func (c *Client) Complete(ctx context.Context, prompt string) (string, error) {
 
 // CompleteWithMaxTokens sends a prompt with a token limit on the response.
 // If maxTokens is 0, no limit is applied.
-func (c *Client) CompleteWithMaxTokens(ctx context.Context, prompt string, maxTokens int) (string, error) {
+func (c *Client) CompleteWithMaxTokens(ctx context.Context, prompt string, maxTokens int) (completion string, err error) {
+	ctx, span := telemetry.Start(ctx, "llm.complete", attribute.String("model", c.model), attribute.Int("prompt_chars", len(prompt)))
+	defer func() { telemetry.End(span, err) }()
+
 	req := chatRequest{
 		Model: c.model,
 		Messages: []chatMessage{
@@ -97,6 +155,12 @@ func (c *Client) CompleteWithMaxTokens(ctx context.Context, prompt string, maxTo
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	telemetry.InjectHeader(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	if err := c.limiter.Acquire(ctx, c.model); err != nil {
+		return "", err
+	}
+	defer c.limiter.Release()
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -122,6 +186,11 @@ func (c *Client) CompleteWithMaxTokens(ctx context.Context, prompt string, maxTo
 		return "", fmt.Errorf("API error: %s", chatResp.Error.Message)
 	}
 
+	if chatResp.Usage != nil {
+		c.promptTokens.Add(int64(chatResp.Usage.PromptTokens))
+		c.completionTokens.Add(int64(chatResp.Usage.CompletionTokens))
+	}
+
 	if len(chatResp.Choices) == 0 {
 		return "", fmt.Errorf("no response returned")
 	}
@@ -129,6 +198,23 @@ func (c *Client) CompleteWithMaxTokens(ctx context.Context, prompt string, maxTo
 	return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
 }
 
+// Warmup sends a minimal completion request so the model is loaded before
+// the first real document arrives. Repeated calls keep an idle model from
+// being evicted.
+func (c *Client) Warmup(ctx context.Context) error {
+	_, err := c.CompleteWithMaxTokens(ctx, "ping", 1)
+	return err
+}
+
+// Usage returns the tokens the model reported processing since the client
+// was created. Responses without usage information are not counted.
+func (c *Client) Usage() Usage {
+	return Usage{
+		PromptTokens:     int(c.promptTokens.Load()),
+		CompletionTokens: int(c.completionTokens.Load()),
+	}
+}
+
 // EnrichmentResult holds the generated tags and summary.
 type EnrichmentResult struct {
 	Tags    []string
@@ -142,7 +228,10 @@ const MaxContentForEnrichment = 20000
 
 // EnrichDocument generates tags and summary for a document.
 // Note: Runs sequentially because DMR can only handle one LLM request at a time.
-func (c *Client) EnrichDocument(ctx context.Context, title, content string) (*EnrichmentResult, error) {
+func (c *Client) EnrichDocument(ctx context.Context, title, content string) (_ *EnrichmentResult, err error) {
+	ctx, span := telemetry.Start(ctx, "llm.enrich", attribute.String("title", title))
+	defer func() { telemetry.End(span, err) }()
+
 	// Truncate content if needed
 	if len(content) > MaxContentForEnrichment {
 		content = content[:MaxContentForEnrichment]
@@ -226,3 +315,40 @@ OUTPUT FORMAT: Return ONLY the summary paragraphs. No headers, no bullet points,
 
 	return result, nil
 }
+
+// maxContextTokens limits the length of a chunk's situating context.
+const maxContextTokens = 100
+
+// SituateChunk generates one sentence situating a chunk within its
+// document, such as which product, page, and topic it belongs to, to be
+// prepended to the chunk before it is embedded and indexed. Chunks read
+// alone often lack the subject their document established earlier.
+func (c *Client) SituateChunk(ctx context.Context, title, document, chunk string) (_ string, err error) {
+	ctx, span := telemetry.Start(ctx, "llm.situate", attribute.String("title", title))
+	defer func() { telemetry.End(span, err) }()
+
+	if len(document) > MaxContentForEnrichment {
+		document = document[:MaxContentForEnrichment]
+	}
+
+	prompt := fmt.Sprintf(`<document>
+Title: %s
+
+%s
+</document>
+
+Here is a section we want to situate within the whole document:
+<chunk>
+%s
+</chunk>
+
+Write one short sentence giving the context of this section within the overall document, naming the product and topic it belongs to, to improve search retrieval of the section.
+
+OUTPUT FORMAT: Return ONLY the sentence, with no preamble.`, title, document, chunk)
+
+	situated, err := c.CompleteWithMaxTokens(ctx, prompt, maxContextTokens)
+	if err != nil {
+		return "", fmt.Errorf("failed to situate chunk: %w", err)
+	}
+	return strings.TrimSpace(situated), nil
+}
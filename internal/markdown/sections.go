@@ -0,0 +1,83 @@
+package markdown
+
+import "strings"
+
+// Section is a heading-delimited span of a markdown document.
+type Section struct {
+	Heading string `json:"heading"` // Heading text, empty for content before the first heading
+	Level   int    `json:"level"`   // Heading level 1-6, 0 for content before the first heading
+	Line    int    `json:"line"`    // 1-based line number where the section starts
+	Start   int    `json:"start"`   // Byte offset of the section start
+	End     int    `json:"end"`     // Byte offset just past the section end
+}
+
+// Sections splits content at ATX headings (# through ######), ignoring
+// headings inside fenced code blocks. Leading content before the first
+// heading becomes a level-0 section if it is not blank.
+func Sections(content string) []Section {
+	var sections []Section
+	current := Section{Line: 1}
+	inCode := false
+	offset := 0
+
+	for i, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+		} else if !inCode {
+			if level, heading, ok := parseHeading(trimmed); ok {
+				current.End = offset
+				if current.Level > 0 || strings.TrimSpace(content[current.Start:offset]) != "" {
+					sections = append(sections, current)
+				}
+				current = Section{Heading: heading, Level: level, Line: i + 1, Start: offset}
+			}
+		}
+
+		offset += len(line)
+	}
+
+	current.End = len(content)
+	if current.Level > 0 || strings.TrimSpace(content[current.Start:]) != "" {
+		sections = append(sections, current)
+	}
+
+	return sections
+}
+
+// Find returns the content of the first section whose heading matches
+// heading case-insensitively, including its subsections.
+func Find(content, heading string) (string, bool) {
+	sections := Sections(content)
+	for i, s := range sections {
+		if s.Level == 0 || !strings.EqualFold(s.Heading, strings.TrimSpace(heading)) {
+			continue
+		}
+		end := s.End
+		for _, next := range sections[i+1:] {
+			if next.Level <= s.Level {
+				break
+			}
+			end = next.End
+		}
+		return content[s.Start:end], true
+	}
+	return "", false
+}
+
+// parseHeading parses an ATX heading line such as "## Install".
+func parseHeading(line string) (level int, heading string, ok bool) {
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	heading = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(rest), "#"))
+	return level, heading, true
+}
@@ -0,0 +1,67 @@
+package markdown
+
+import "testing"
+
+func TestSections(t *testing.T) {
+	content := "Intro text.\n\n# Title\n\nBody.\n\n```sh\n# not a heading\n```\n\n## Install ##\n\nRun it.\n#hashtag\n"
+
+	sections := Sections(content)
+
+	want := []struct {
+		heading string
+		level   int
+		line    int
+	}{
+		{"", 0, 1},
+		{"Title", 1, 3},
+		{"Install", 2, 11},
+	}
+	if len(sections) != len(want) {
+		t.Fatalf("Sections() returned %d sections, want %d: %+v", len(sections), len(want), sections)
+	}
+	for i, w := range want {
+		s := sections[i]
+		if s.Heading != w.heading || s.Level != w.level || s.Line != w.line {
+			t.Errorf("section %d = %+v, want heading=%q level=%d line=%d", i, s, w.heading, w.level, w.line)
+		}
+	}
+
+	// Sections cover the whole document without gaps
+	for i := 1; i < len(sections); i++ {
+		if sections[i].Start != sections[i-1].End {
+			t.Errorf("gap between section %d and %d", i-1, i)
+		}
+	}
+	if last := sections[len(sections)-1]; last.End != len(content) {
+		t.Errorf("last section ends at %d, want %d", last.End, len(content))
+	}
+}
+
+func TestSections_NoLeadingContent(t *testing.T) {
+	sections := Sections("# Only\n\ntext")
+	if len(sections) != 1 || sections[0].Heading != "Only" || sections[0].Start != 0 {
+		t.Errorf("Sections() = %+v, want a single section starting at 0", sections)
+	}
+}
+
+func TestFind(t *testing.T) {
+	content := "# Guide\n\nIntro.\n\n## Install\n\nRun it.\n\n### macOS\n\nUse brew.\n\n## Usage\n\nCall it.\n"
+
+	tests := []struct {
+		heading string
+		want    string
+		found   bool
+	}{
+		{"install", "## Install\n\nRun it.\n\n### macOS\n\nUse brew.\n\n", true},
+		{"Usage", "## Usage\n\nCall it.\n", true},
+		{"Missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.heading, func(t *testing.T) {
+			got, found := Find(content, tt.heading)
+			if got != tt.want || found != tt.found {
+				t.Errorf("Find() = %q, %v, want %q, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
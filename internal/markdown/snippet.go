@@ -0,0 +1,173 @@
+package markdown
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Snippet is a short excerpt of a document around the terms of a query.
+type Snippet struct {
+	Text    string
+	Matches [][2]int // Byte ranges in Text of words matching a query term
+}
+
+// Highlight returns the snippet's text with each match wrapped in open and
+// close, e.g. "**" and "**" or ANSI escape sequences.
+func (s Snippet) Highlight(open, close string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range s.Matches {
+		b.WriteString(s.Text[last:m[0]])
+		b.WriteString(open)
+		b.WriteString(s.Text[m[0]:m[1]])
+		b.WriteString(close)
+		last = m[1]
+	}
+	b.WriteString(s.Text[last:])
+	return b.String()
+}
+
+// stopwords are query words too common to locate a snippet by.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "do": true, "for": true, "from": true, "how": true,
+	"i": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "the": true, "to": true, "what": true, "when": true,
+	"where": true, "which": true, "why": true, "with": true,
+}
+
+// word is a word of the flattened content and its byte range.
+type word struct {
+	start, end int
+	term       int // Index of the query term it matches, or -1
+}
+
+// Excerpt returns about size bytes of content centered on the passage
+// matching the most distinct terms of query, with whitespace collapsed. A
+// word matches a term it starts with, so "install" finds "installing".
+// Without any match, the excerpt is the start of content. Cut ends are
+// marked with "…".
+func Excerpt(content, query string, size int) Snippet {
+	text := strings.Join(strings.Fields(content), " ")
+	if size <= 0 {
+		size = 300
+	}
+	terms := queryTerms(query)
+	words := splitWords(text, terms)
+
+	// The window starting at each matching word, scored by distinct terms
+	// and then by matches
+	bestStart, bestDistinct, bestCount := 0, 0, 0
+	for i, w := range words {
+		if w.term < 0 {
+			continue
+		}
+		seen := make(map[int]bool)
+		count := 0
+		for _, v := range words[i:] {
+			if v.end-w.start > size {
+				break
+			}
+			if v.term >= 0 {
+				seen[v.term] = true
+				count++
+			}
+		}
+		if len(seen) > bestDistinct || (len(seen) == bestDistinct && count > bestCount) {
+			bestStart, bestDistinct, bestCount = w.start, len(seen), count
+		}
+	}
+
+	// Lead in with some context before the first match
+	start := 0
+	if bestCount > 0 {
+		start = max(bestStart-size/4, 0)
+	}
+	end := min(start+size, len(text))
+	if end == len(text) {
+		start = max(end-size, 0)
+	}
+	start, end = snapToWords(text, start, end)
+
+	var snippet Snippet
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	offset := b.Len() - start
+	b.WriteString(text[start:end])
+	if end < len(text) {
+		b.WriteString(" …")
+	}
+	snippet.Text = b.String()
+
+	for _, w := range words {
+		if w.term >= 0 && w.start >= start && w.end <= end {
+			snippet.Matches = append(snippet.Matches, [2]int{w.start + offset, w.end + offset})
+		}
+	}
+	return snippet
+}
+
+// queryTerms returns the lowercased words of query worth locating.
+func queryTerms(query string) []string {
+	var terms []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if len(f) < 2 || stopwords[f] {
+			continue
+		}
+		if !slices.Contains(terms, f) {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// splitWords returns the words of text and the query term each matches.
+func splitWords(text string, terms []string) []word {
+	var words []word
+	start := -1
+	for i, r := range text + " " {
+		if !isSeparator(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, word{start: start, end: i, term: matchTerm(strings.ToLower(text[start:i]), terms)})
+			start = -1
+		}
+	}
+	return words
+}
+
+// matchTerm returns the index of the term w starts with, or -1.
+func matchTerm(w string, terms []string) int {
+	for i, t := range terms {
+		if strings.HasPrefix(w, t) {
+			return i
+		}
+	}
+	return -1
+}
+
+// snapToWords moves start forward and end back so neither cuts a word.
+func snapToWords(text string, start, end int) (int, int) {
+	if start > 0 && text[start-1] != ' ' {
+		if i := strings.IndexByte(text[start:end], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	if end < len(text) && text[end] != ' ' {
+		if i := strings.LastIndexByte(text[start:end], ' '); i > 0 {
+			end = start + i
+		}
+	}
+	return start, end
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
@@ -0,0 +1,44 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExcerpt(t *testing.T) {
+	filler := strings.Repeat("Lorem ipsum dolor sit amet. ", 30)
+	content := "# Guide\n\n" + filler + "\n\nTo install Go, download the\narchive and run the installer.\n\n" + filler
+
+	s := Excerpt(content, "how to install go", 120)
+	if !strings.HasPrefix(s.Text, "… ") || !strings.HasSuffix(s.Text, " …") {
+		t.Errorf("Excerpt() = %q, want both ends marked as cut", s.Text)
+	}
+	if !strings.Contains(s.Text, "To install Go, download the archive") {
+		t.Errorf("Excerpt() = %q, want the passage about installing", s.Text)
+	}
+	if len(s.Text) > 120+len("… ")+len(" …") {
+		t.Errorf("Excerpt() is %d bytes, want about 120", len(s.Text))
+	}
+
+	got := s.Highlight("[", "]")
+	for _, want := range []string{"[install]", "[Go]", "[installer]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Highlight() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "[To]") || strings.Contains(got, "[the]") {
+		t.Errorf("Highlight() = %q, stopwords should not be highlighted", got)
+	}
+}
+
+func TestExcerpt_NoMatch(t *testing.T) {
+	s := Excerpt("Short page about   modules.", "kubernetes", 100)
+	if s.Text != "Short page about modules." || len(s.Matches) != 0 {
+		t.Errorf("Excerpt() = %+v, want the whole page without matches", s)
+	}
+
+	s = Excerpt(strings.Repeat("word ", 100), "kubernetes", 50)
+	if strings.HasPrefix(s.Text, "…") || !strings.HasSuffix(s.Text, " …") {
+		t.Errorf("Excerpt() = %q, want the start of the page", s.Text)
+	}
+}
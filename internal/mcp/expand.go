@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// How search_chunks expands matching chunks into the context returned.
+const (
+	expandNone      = "none"      // The matching chunks alone
+	expandNeighbors = "neighbors" // Each match stitched together with the chunks around it
+	expandDocuments = "documents" // The pages the matches belong to, once each
+)
+
+// passage is a matching chunk stitched together with its neighbors.
+type passage struct {
+	DocumentID  string   `json:"document_id"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	HeadingPath []string `json:"heading_path,omitempty"` // Headings enclosing the first matching chunk
+	ChunkIDs    []string `json:"chunk_ids"`              // Chunks stitched, in page order
+	Content     string   `json:"content"`
+}
+
+// expandNeighbors returns the chunks as passages including up to n chunks
+// before and after each, in the order of the chunks. Matches close enough
+// for their passages to overlap share one passage.
+func (s *Server) expandNeighbors(ctx context.Context, chunks []models.Chunk, n int) ([]passage, error) {
+	var ids []string
+	for _, chunk := range chunks {
+		for p := max(chunk.Position-n, 0); p <= chunk.Position+n; p++ {
+			ids = append(ids, models.GenerateChunkID(chunk.DocumentID, p))
+		}
+	}
+	neighbors, err := s.index.Load().GetChunks(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get neighboring chunks: %w", err)
+	}
+	return stitch(chunks, neighbors, n), nil
+}
+
+// stitch joins each chunk with the neighbors within n positions of it.
+func stitch(chunks, neighbors []models.Chunk, n int) []passage {
+	byID := make(map[string]models.Chunk, len(neighbors)+len(chunks))
+	for _, chunk := range neighbors {
+		byID[chunk.ID] = chunk
+	}
+
+	// Spans of positions per document, in the order of their first match
+	type span struct {
+		match       models.Chunk
+		first, last int
+	}
+	var spans []*span
+	for _, chunk := range chunks {
+		byID[chunk.ID] = chunk
+		first, last := max(chunk.Position-n, 0), chunk.Position+n
+		merged := false
+		for _, sp := range spans {
+			if sp.match.DocumentID == chunk.DocumentID && first <= sp.last+1 && last >= sp.first-1 {
+				sp.first, sp.last = min(sp.first, first), max(sp.last, last)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			spans = append(spans, &span{match: chunk, first: first, last: last})
+		}
+	}
+
+	passages := make([]passage, len(spans))
+	for i, sp := range spans {
+		p := passage{
+			DocumentID:  sp.match.DocumentID,
+			URL:         sp.match.URL,
+			Title:       sp.match.Title,
+			HeadingPath: sp.match.HeadingPath,
+		}
+		var parts []string
+		for pos := sp.first; pos <= sp.last; pos++ {
+			chunk, ok := byID[models.GenerateChunkID(sp.match.DocumentID, pos)]
+			if !ok {
+				continue
+			}
+			p.ChunkIDs = append(p.ChunkIDs, chunk.ID)
+			parts = append(parts, chunk.Content)
+		}
+		p.Content = strings.Join(parts, "\n\n")
+		passages[i] = p
+	}
+	return passages
+}
+
+// expandDocuments returns the pages the chunks belong to, in the order of
+// their first matching chunk, without embeddings.
+func (s *Server) expandDocuments(ctx context.Context, chunks []models.Chunk) ([]models.Document, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, chunk := range chunks {
+		if !seen[chunk.DocumentID] {
+			seen[chunk.DocumentID] = true
+			ids = append(ids, chunk.DocumentID)
+		}
+	}
+	docs, err := s.index.Load().GetDocuments(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	filter := s.filter(ctx, "")
+	visible := docs[:0]
+	for _, doc := range docs {
+		if filter.Allows(doc.AccessLabels) {
+			doc.Embedding = nil
+			visible = append(visible, doc)
+		}
+	}
+	return visible, nil
+}
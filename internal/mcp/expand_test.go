@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestStitch(t *testing.T) {
+	chunk := func(doc string, position int) models.Chunk {
+		return models.Chunk{
+			ID:         models.GenerateChunkID(doc, position),
+			DocumentID: doc,
+			Position:   position,
+			Content:    models.GenerateChunkID(doc, position),
+		}
+	}
+	var neighbors []models.Chunk
+	for p := range 8 {
+		neighbors = append(neighbors, chunk("a", p))
+	}
+	neighbors = append(neighbors, chunk("b", 0), chunk("b", 1))
+
+	// a-5 and a-3 overlap and share a passage; b-0 has no chunk before it
+	passages := stitch([]models.Chunk{chunk("a", 5), chunk("b", 0), chunk("a", 3)}, neighbors, 1)
+
+	want := [][]string{{"a-2", "a-3", "a-4", "a-5", "a-6"}, {"b-0", "b-1"}}
+	if len(passages) != len(want) {
+		t.Fatalf("stitch() = %d passages, want %d", len(passages), len(want))
+	}
+	for i, p := range passages {
+		if !slices.Equal(p.ChunkIDs, want[i]) {
+			t.Errorf("passage %d chunks = %v, want %v", i, p.ChunkIDs, want[i])
+		}
+	}
+	if passages[1].Content != "b-0\n\nb-1" {
+		t.Errorf("passage content = %q, want the chunks joined", passages[1].Content)
+	}
+}
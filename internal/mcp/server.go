@@ -4,10 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/mfenderov/bam-rag/internal/analytics"
+	"github.com/mfenderov/bam-rag/internal/apikey"
+	"github.com/mfenderov/bam-rag/internal/backend"
 	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/internal/query"
+	"github.com/mfenderov/bam-rag/internal/rerank"
 	"github.com/mfenderov/bam-rag/pkg/models"
 )
 
@@ -16,27 +28,57 @@ type Config struct {
 	Name        string
 	Version     string
 	ESAddresses []string
+	ESCloudID   string
 	ESIndex     string
 	ESUsername  string
 	ESPassword  string
+	ESAPIKey    string
+	ESRecency   elasticsearch.Recency // How much newer pages are favored
+	ESDedup     elasticsearch.Dedup   // Which copies of a page searches leave out
+
+	// ESSourceIndices are the indices sources are indexed into instead of
+	// ESIndex, searched along with it
+	ESSourceIndices []string
+
+	// ESFuzziness and ESFuzzyPrefixLength make searches tolerate typos
+	ESFuzziness         string
+	ESFuzzyPrefixLength int
+
+	// AccessLabels are granted to every client: results are limited to
+	// documents without access labels and those sharing one of these.
+	AccessLabels []string
+
+	// AnalyticsIndex, if set, is the index searches and the documents
+	// fetched after them are logged to.
+	AnalyticsIndex string
+
+	// Reranker, if set, reorders search results before they are returned.
+	Reranker *rerank.Client
+
+	// Backend, if set, is searched instead of the Elasticsearch index
+	// above; searches are then not logged to analytics.
+	Backend backend.SearchBackend
 }
 
-// Server wraps the MCP server with Elasticsearch integration.
+// searchBackend wraps the backend searched so it can be swapped atomically.
+type searchBackend struct {
+	backend.SearchBackend
+}
+
+// Server wraps the MCP server with search backend integration.
 type Server struct {
 	mcpServer *server.MCPServer
-	esClient  *elasticsearch.Client
+	index     atomic.Pointer[searchBackend] // Swapped on Reload
+	access    atomic.Pointer[[]string]      // Granted access labels; swapped on Reload
+	analytics atomic.Pointer[analytics.Log] // nil if analytics are disabled; swapped on Reload
+	reranker  atomic.Pointer[rerank.Client] // nil if results are not reranked; swapped on Reload
 }
 
 // NewServer creates a new MCP server with search tools.
 func NewServer(config Config) (*Server, error) {
-	esClient, err := elasticsearch.New(elasticsearch.Config{
-		Addresses: config.ESAddresses,
-		Index:     config.ESIndex,
-		Username:  config.ESUsername,
-		Password:  config.ESPassword,
-	})
+	index, log, err := newSearchBackend(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
+		return nil, err
 	}
 
 	mcpServer := server.NewMCPServer(
@@ -47,57 +89,201 @@ func NewServer(config Config) (*Server, error) {
 
 	s := &Server{
 		mcpServer: mcpServer,
-		esClient:  esClient,
 	}
+	s.index.Store(index)
+	s.access.Store(&config.AccessLabels)
+	s.analytics.Store(log)
+	s.reranker.Store(config.Reranker)
 
 	// Register search_documents tool
 	searchTool := mcp.NewTool("search_documents",
 		mcp.WithDescription("Search indexed documentation pages by query. Returns full page content in markdown format."),
 		mcp.WithString("query",
 			mcp.Required(),
-			mcp.Description("Search query string"),
+			mcp.Description("Search query string; prefix a term or \"quoted phrase\" with - or NOT to leave out results containing it, e.g. ingress -nginx; title:word, source:name-or-host, after:YYYY-MM-DD, and before:YYYY-MM-DD restrict results, e.g. title:install source:go.dev modules"),
 		),
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum number of results to return (default: 10)"),
 		),
+		mcp.WithString("language",
+			mcp.Description("Only return results in this language (ISO 639-1 code, e.g. en)"),
+		),
+		mcp.WithString("scope",
+			mcp.Description("What the query is matched against: full (default; page content, title, tags, and summary) or summary (title, tags, and summary only, for fewer but closer results)"),
+			mcp.Enum("full", "summary"),
+		),
+		mcp.WithNumber("per_source",
+			mcp.Description("Return at most this many results from each source site, so one large site does not take every slot (default: no limit, unless configured)"),
+		),
+		mcp.WithBoolean("snippets",
+			mcp.Description("Return a short excerpt of each page around the query terms instead of its full content; fetch whole pages with get_document"),
+		),
 	)
 	mcpServer.AddTool(searchTool, s.searchHandler)
 
 	// Register get_document tool
 	getDocTool := mcp.NewTool("get_document",
-		mcp.WithDescription("Get a specific documentation page by ID"),
+		mcp.WithDescription("Get a specific documentation page by ID. The page outline lists its H1-H3 headings; pass one as section to get only that part of the page."),
 		mcp.WithString("id",
 			mcp.Required(),
 			mcp.Description("Document ID to retrieve"),
 		),
+		mcp.WithString("section",
+			mcp.Description("Heading from the document outline; returns only that section and its subsections"),
+		),
 	)
 	mcpServer.AddTool(getDocTool, s.getDocumentHandler)
 
+	// Register search_chunks tool
+	searchChunksTool := mcp.NewTool("search_chunks",
+		mcp.WithDescription("Search indexed documentation sections by query. Returns matching sections with their heading path and the ID of the page they belong to, for use with get_document."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Search query string; prefix a term or \"quoted phrase\" with - or NOT to leave out results containing it, e.g. ingress -nginx; title:word, source:name-or-host, after:YYYY-MM-DD, and before:YYYY-MM-DD restrict results, e.g. title:install source:go.dev modules"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of results to return (default: 10)"),
+		),
+		mcp.WithString("language",
+			mcp.Description("Only return results in this language (ISO 639-1 code, e.g. en)"),
+		),
+		mcp.WithString("scope",
+			mcp.Description("What the query is matched against: full (default; page content, title, tags, and summary) or summary (title, tags, and summary only, for fewer but closer results)"),
+			mcp.Enum("full", "summary"),
+		),
+		mcp.WithNumber("per_source",
+			mcp.Description("Return at most this many results from each source site, so one large site does not take every slot (default: no limit, unless configured)"),
+		),
+		mcp.WithString("expand",
+			mcp.Description("What is returned for the matching sections: none (default; the sections alone), neighbors (each section stitched together with the sections around it, for coherent context), or documents (the pages they belong to, once each)"),
+			mcp.Enum(expandNone, expandNeighbors, expandDocuments),
+		),
+		mcp.WithNumber("neighbors",
+			mcp.Description("With expand=neighbors, how many sections before and after each match to include (default: 1)"),
+		),
+	)
+	mcpServer.AddTool(searchChunksTool, s.searchChunksHandler)
+
+	// Register search_sections tool
+	searchSectionsTool := mcp.NewTool("search_sections",
+		mcp.WithDescription("Search the sections of one documentation page, such as a long reference page found by search_documents. Returns its sections best matching the query, with their heading path."),
+		mcp.WithString("document",
+			mcp.Required(),
+			mcp.Description("ID or URL of the page to search"),
+		),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Search query string; prefix a term or \"quoted phrase\" with - or NOT to leave out sections containing it"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of sections to return (default: 5)"),
+		),
+	)
+	mcpServer.AddTool(searchSectionsTool, s.searchSectionsHandler)
+
+	// Register get_chunk tool
+	getChunkTool := mcp.NewTool("get_chunk",
+		mcp.WithDescription("Get a specific documentation section by ID"),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("Chunk ID to retrieve"),
+		),
+	)
+	mcpServer.AddTool(getChunkTool, s.getChunkHandler)
+
 	return s, nil
 }
 
 // searchHandler handles the search_documents tool call.
 func (s *Server) searchHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query, err := req.RequireString("query")
+	raw, err := req.RequireString("query")
 	if err != nil {
 		return mcp.NewToolResultError("query parameter is required"), nil
 	}
+	q, err := query.Parse(raw)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	limit := req.GetInt("limit", 10)
+	filter := s.filter(ctx, req.GetString("language", ""))
+	filter.Scope, err = elasticsearch.ParseScope(req.GetString("scope", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	filter.PerSource = max(req.GetInt("per_source", 0), 0)
 
-	docs, err := s.handleSearch(ctx, query, limit)
+	docs, err := s.handleSearch(ctx, q, limit, filter)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
 	}
+	if log := s.analytics.Load(); log != nil {
+		ids := make([]string, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc.ID
+		}
+		log.Search(ctx, "search_documents", raw, ids)
+	}
 
-	result, err := json.Marshal(docs)
+	var results any = docs
+	if req.GetBool("snippets", false) {
+		results = snippets(docs, q.Text)
+	}
+	result, err := json.Marshal(results)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal results: %v", err)), nil
 	}
 
+	if len(docs) == 0 {
+		return s.withSuggestion(ctx, string(result), q.Text, filter), nil
+	}
 	return mcp.NewToolResultText(string(result)), nil
 }
 
+// withSuggestion returns the result of a search that found nothing,
+// followed by a corrected query if there is one among the pages filter
+// lets the caller see.
+func (s *Server) withSuggestion(ctx context.Context, result, text string, filter elasticsearch.Filter) *mcp.CallToolResult {
+	toolResult := mcp.NewToolResultText(result)
+	suggestion, err := s.index.Load().Suggest(ctx, text, filter)
+	if err != nil {
+		slog.Debug("failed to suggest a correction", "query", text, "error", err)
+	}
+	if suggestion != "" {
+		toolResult.Content = append(toolResult.Content, mcp.NewTextContent(fmt.Sprintf("No results. Did you mean: %s", suggestion)))
+	}
+	return toolResult
+}
+
+// snippetSize is the length in bytes of the excerpts returned in snippet mode.
+const snippetSize = 400
+
+// searchSnippet is a search result in snippet mode.
+type searchSnippet struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Summary string `json:"summary,omitempty"`
+	License string `json:"license,omitempty"`
+	Snippet string `json:"snippet"` // Excerpt around the query terms, which are marked **like this**
+}
+
+// snippets returns docs as excerpts around the terms of query.
+func snippets(docs []models.Document, query string) []searchSnippet {
+	results := make([]searchSnippet, len(docs))
+	for i, doc := range docs {
+		results[i] = searchSnippet{
+			ID:      doc.ID,
+			URL:     doc.URL,
+			Title:   doc.Title,
+			Summary: doc.Summary,
+			License: doc.License,
+			Snippet: markdown.Excerpt(doc.Content, query, snippetSize).Highlight("**", "**"),
+		}
+	}
+	return results
+}
+
 // getDocumentHandler handles the get_document tool call.
 func (s *Server) getDocumentHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	id, err := req.RequireString("id")
@@ -113,6 +299,17 @@ func (s *Server) getDocumentHandler(ctx context.Context, req mcp.CallToolRequest
 	if doc == nil {
 		return mcp.NewToolResultError(fmt.Sprintf("document not found: %s", id)), nil
 	}
+	if log := s.analytics.Load(); log != nil {
+		log.Fetch(ctx, "get_document", id)
+	}
+
+	if section := req.GetString("section", ""); section != "" {
+		content, ok := markdown.Find(doc.Content, section)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("section not found: %s", section)), nil
+		}
+		doc.Content = content
+	}
 
 	result, err := json.Marshal(doc)
 	if err != nil {
@@ -122,17 +319,263 @@ func (s *Server) getDocumentHandler(ctx context.Context, req mcp.CallToolRequest
 	return mcp.NewToolResultText(string(result)), nil
 }
 
-// handleSearch searches for documents matching the query.
-func (s *Server) handleSearch(ctx context.Context, query string, limit int) ([]models.Document, error) {
-	return s.esClient.Search(ctx, query, limit)
+// searchChunksHandler handles the search_chunks tool call.
+func (s *Server) searchChunksHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	raw, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError("query parameter is required"), nil
+	}
+	q, err := query.Parse(raw)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	limit := req.GetInt("limit", 10)
+	filter := s.filter(ctx, req.GetString("language", ""))
+	filter.Scope, err = elasticsearch.ParseScope(req.GetString("scope", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	filter.PerSource = max(req.GetInt("per_source", 0), 0)
+
+	chunks, err := s.handleSearchChunks(ctx, q, limit, filter)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+	}
+	if log := s.analytics.Load(); log != nil {
+		// Chunks are fetched through their documents, so those are logged
+		var ids []string
+		for _, chunk := range chunks {
+			if !slices.Contains(ids, chunk.DocumentID) {
+				ids = append(ids, chunk.DocumentID)
+			}
+		}
+		log.Search(ctx, "search_chunks", raw, ids)
+	}
+
+	var results any = chunks
+	switch req.GetString("expand", expandNone) {
+	case expandNone:
+	case expandNeighbors:
+		results, err = s.expandNeighbors(ctx, chunks, max(req.GetInt("neighbors", 1), 0))
+	case expandDocuments:
+		results, err = s.expandDocuments(ctx, chunks)
+	default:
+		return mcp.NewToolResultError("expand must be none, neighbors, or documents"), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	result, err := json.Marshal(results)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal results: %v", err)), nil
+	}
+
+	if len(chunks) == 0 {
+		return s.withSuggestion(ctx, string(result), q.Text, filter), nil
+	}
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// searchSectionsHandler handles the search_sections tool call.
+func (s *Server) searchSectionsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	document, err := req.RequireString("document")
+	if err != nil {
+		return mcp.NewToolResultError("document parameter is required"), nil
+	}
+	raw, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError("query parameter is required"), nil
+	}
+	q, err := query.Parse(raw)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	id := documentID(document)
+	sections, err := s.handleSearchSections(ctx, id, q, req.GetInt("limit", 5))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+	}
+	if log := s.analytics.Load(); log != nil && len(sections) > 0 {
+		log.Search(ctx, "search_sections", raw, []string{id})
+	}
+
+	result, err := json.Marshal(sections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// documentID returns the ID of the document identified by its ID or URL.
+func documentID(document string) string {
+	if strings.Contains(document, "://") {
+		return models.GenerateDocumentID(document)
+	}
+	return document
+}
+
+// getChunkHandler handles the get_chunk tool call.
+func (s *Server) getChunkHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireString("id")
+	if err != nil {
+		return mcp.NewToolResultError("id parameter is required"), nil
+	}
+
+	chunk, err := s.index.Load().GetChunk(ctx, id)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("get chunk failed: %v", err)), nil
+	}
+
+	if chunk == nil || !s.filter(ctx, "").Allows(chunk.AccessLabels) {
+		return mcp.NewToolResultError(fmt.Sprintf("chunk not found: %s", id)), nil
+	}
+	if log := s.analytics.Load(); log != nil {
+		log.Fetch(ctx, "get_chunk", chunk.DocumentID)
+	}
+
+	result, err := json.Marshal(chunk)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal chunk: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// handleSearch searches for documents matching the query and filter.
+func (s *Server) handleSearch(ctx context.Context, q query.Query, limit int, filter elasticsearch.Filter) ([]models.Document, error) {
+	reranker := s.reranker.Load()
+	docs, err := s.index.Load().SearchFiltered(ctx, q.Text, reranker.Candidates(limit), q.Apply(filter))
+	if err != nil {
+		return nil, err
+	}
+	return reranker.Documents(ctx, q.Text, docs, limit), nil
+}
+
+// handleSearchChunks searches for chunks matching the query and filter.
+// Embeddings are omitted from the results to keep them small.
+func (s *Server) handleSearchChunks(ctx context.Context, q query.Query, limit int, filter elasticsearch.Filter) ([]models.Chunk, error) {
+	reranker := s.reranker.Load()
+	chunks, err := s.index.Load().SearchChunks(ctx, q.Text, nil, reranker.Candidates(limit), q.Apply(filter))
+	if err != nil {
+		return nil, err
+	}
+	chunks = reranker.Chunks(ctx, q.Text, chunks, limit)
+	for i := range chunks {
+		chunks[i].Embedding = nil
+	}
+	return chunks, nil
+}
+
+// handleSearchSections searches the sections of a document for those
+// matching the query. Embeddings are omitted from the results.
+func (s *Server) handleSearchSections(ctx context.Context, documentID string, q query.Query, limit int) ([]models.Chunk, error) {
+	reranker := s.reranker.Load()
+	sections, err := s.index.Load().SearchSections(ctx, documentID, q.Text, reranker.Candidates(limit), q.Apply(s.filter(ctx, "")))
+	if err != nil {
+		return nil, err
+	}
+	sections = reranker.Chunks(ctx, q.Text, sections, limit)
+	for i := range sections {
+		sections[i].Embedding = nil
+	}
+	return sections, nil
+}
+
+// filter restricts results to the documents clients may see and to
+// language, if one is given. Clients authenticated with an API key also
+// see the documents its access labels grant.
+func (s *Server) filter(ctx context.Context, language string) elasticsearch.Filter {
+	labels := *s.access.Load()
+	if key, ok := apikey.FromContext(ctx); ok && len(key.AccessLabels) > 0 {
+		labels = append(slices.Clip(labels), key.AccessLabels...)
+	}
+	filter := elasticsearch.Filter{EnforceAccess: true, AccessLabels: labels}
+	if language != "" {
+		filter.Languages = []string{processor.NormalizeLanguage(language)}
+	}
+	return filter
 }
 
-// handleGetDocument retrieves a document by ID.
+// handleGetDocument retrieves a document by ID. Documents clients may not
+// see are reported as missing.
 func (s *Server) handleGetDocument(ctx context.Context, id string) (*models.Document, error) {
-	return s.esClient.GetDocument(ctx, id)
+	doc, err := s.index.Load().GetDocument(ctx, id)
+	if err != nil || doc == nil || !s.filter(ctx, "").Allows(doc.AccessLabels) {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Reload applies new backend, access label, analytics, and reranking
+// settings to subsequent tool calls.
+// The server name and version are fixed once the server has started.
+func (s *Server) Reload(config Config) error {
+	index, log, err := newSearchBackend(config)
+	if err != nil {
+		return err
+	}
+	s.index.Store(index)
+	s.access.Store(&config.AccessLabels)
+	s.analytics.Store(log)
+	s.reranker.Store(config.Reranker)
+	return nil
+}
+
+// newSearchBackend returns the backend to search for the given settings,
+// and the analytics log searches are recorded in, or nil.
+func newSearchBackend(config Config) (*searchBackend, *analytics.Log, error) {
+	if config.Backend != nil {
+		return &searchBackend{config.Backend}, nil, nil
+	}
+	esClient, err := newESClient(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &searchBackend{esClient}, newAnalytics(config, esClient), nil
+}
+
+// newAnalytics creates the analytics log for the given settings, or
+// returns nil if analytics are disabled.
+func newAnalytics(config Config, esClient *elasticsearch.Client) *analytics.Log {
+	if config.AnalyticsIndex == "" {
+		return nil
+	}
+	return analytics.New(esClient, config.AnalyticsIndex)
+}
+
+// newESClient creates the Elasticsearch client for the given settings.
+func newESClient(config Config) (*elasticsearch.Client, error) {
+	esClient, err := elasticsearch.New(elasticsearch.Config{
+		Addresses: config.ESAddresses,
+		CloudID:   config.ESCloudID,
+		Index:     config.ESIndex,
+		Username:  config.ESUsername,
+		Password:  config.ESPassword,
+		APIKey:    config.ESAPIKey,
+		Recency:   config.ESRecency,
+		Dedup:     config.ESDedup,
+
+		SourceIndices:     config.ESSourceIndices,
+		Fuzziness:         config.ESFuzziness,
+		FuzzyPrefixLength: config.ESFuzzyPrefixLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
+	}
+	return esClient, nil
 }
 
 // ServeStdio starts the MCP server using stdio transport.
 func (s *Server) ServeStdio() error {
 	return server.ServeStdio(s.mcpServer)
 }
+
+// HTTPHandler returns a handler serving the MCP server over the streamable
+// HTTP transport at /mcp. Tool calls run in the context of their request,
+// so keys added by apikey.Keyring.Middleware apply to them.
+func (s *Server) HTTPHandler() http.Handler {
+	return server.NewStreamableHTTPServer(s.mcpServer, server.WithEndpointPath("/mcp"))
+}
@@ -2,11 +2,16 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/mfenderov/bam-rag/internal/apikey"
 	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/embedded"
+	"github.com/mfenderov/bam-rag/internal/query"
 	"github.com/mfenderov/bam-rag/pkg/models"
 )
 
@@ -99,7 +104,8 @@ func TestServer_SearchTool(t *testing.T) {
 	}
 
 	// Test search handler directly
-	results, err := s.handleSearch(ctx, "installation", 10)
+	q, _ := query.Parse("installation")
+	results, err := s.handleSearch(ctx, q, 10, elasticsearch.Filter{})
 	if err != nil {
 		t.Fatalf("handleSearch() error = %v", err)
 	}
@@ -167,3 +173,54 @@ func TestServer_GetDocumentTool(t *testing.T) {
 	// Cleanup
 	esClient.DeleteIndex(ctx)
 }
+
+func TestServer_Reload(t *testing.T) {
+	config := Config{
+		Name:        "test",
+		Version:     "1.0.0",
+		ESAddresses: []string{"http://localhost:9200"},
+		ESIndex:     "before",
+	}
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	before := server.index.Load()
+
+	config.ESIndex = "after"
+	if err := server.Reload(config); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if server.index.Load() == before {
+		t.Error("Reload() should replace the Elasticsearch client")
+	}
+}
+
+func TestServer_APIKeyAccessLabels(t *testing.T) {
+	ctx := context.Background()
+	store, err := embedded.Open(filepath.Join(t.TempDir(), "index.db"))
+	if errors.Is(err, embedded.ErrNoFTS5) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := models.Document{ID: "ledger", Title: "Ledger", Content: "Ledger internals", AccessLabels: []string{"finance"}}
+	if err := store.IndexDocument(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(Config{Name: "test", Version: "1.0.0", Backend: store})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	if got, _ := server.handleGetDocument(ctx, "ledger"); got != nil {
+		t.Error("handleGetDocument() without a key returned a labelled document")
+	}
+	keyCtx := apikey.NewContext(ctx, apikey.Key{Name: "payments", AccessLabels: []string{"finance"}})
+	if got, _ := server.handleGetDocument(keyCtx, "ledger"); got == nil {
+		t.Error("handleGetDocument() with a key granting finance = nil, want the document")
+	}
+}
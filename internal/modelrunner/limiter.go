@@ -0,0 +1,126 @@
+// Package modelrunner coordinates clients sharing a Docker Model Runner
+// instance.
+package modelrunner
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// maxRun is how many requests for the loaded model are admitted in a row
+// ahead of older requests for another model.
+const maxRun = 16
+
+// Limiter bounds the requests in flight to a Docker Model Runner instance.
+// Clients of different models that share it take turns instead of
+// interleaving requests, which makes the runner swap models in and out of
+// the GPU. A freed slot goes to the oldest request for the model last
+// admitted, so requests for one model run in batches; after maxRun of them
+// in a row the oldest waiting request goes first whatever its model, so
+// neither client starves the other.
+//
+// It is safe for concurrent use, and a nil Limiter admits every request.
+type Limiter struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	model   string    // Model of the request admitted last
+	run     int       // Requests for model admitted in a row
+	waiters list.List // *waiter per waiting request, oldest first
+}
+
+// waiter is a request waiting for a slot; ready is closed to admit it.
+type waiter struct {
+	model string
+	ready chan struct{}
+}
+
+// NewLimiter creates a limiter admitting limit requests at a time.
+// A limit below 1 is treated as 1.
+func NewLimiter(limit int) *Limiter {
+	return &Limiter{limit: max(limit, 1)}
+}
+
+// Acquire waits until a request for model may be sent, or returns ctx's
+// error if it is done first. Every successful Acquire must be followed by
+// Release.
+func (l *Limiter) Acquire(ctx context.Context, model string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.active < l.limit && l.waiters.Len() == 0 {
+		l.active++
+		l.admitted(model)
+		l.mu.Unlock()
+		return nil
+	}
+	w := &waiter{model: model, ready: make(chan struct{})}
+	e := l.waiters.PushBack(w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	select {
+	case <-w.ready:
+		// Admitted while giving up: pass the slot on
+		l.mu.Unlock()
+		l.Release()
+	default:
+		l.waiters.Remove(e)
+		l.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+// Release ends a request admitted by Acquire, admitting the next waiting
+// request if there is one.
+func (l *Limiter) Release() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.next()
+	if e == nil {
+		l.active--
+		return
+	}
+	l.waiters.Remove(e)
+	w := e.Value.(*waiter)
+	l.admitted(w.model)
+	close(w.ready)
+}
+
+// next returns the waiting request to admit next: the oldest for the model
+// last admitted while its run lasts, otherwise the oldest of all. Returns
+// nil if none is waiting. l.mu must be held.
+func (l *Limiter) next() *list.Element {
+	front := l.waiters.Front()
+	if l.run < maxRun {
+		for e := front; e != nil; e = e.Next() {
+			if e.Value.(*waiter).model == l.model {
+				return e
+			}
+		}
+	}
+	return front
+}
+
+// admitted records the admission of a request for model. l.mu must be
+// held.
+func (l *Limiter) admitted(model string) {
+	if model == l.model {
+		l.run++
+		return
+	}
+	l.model, l.run = model, 1
+}
@@ -0,0 +1,182 @@
+package modelrunner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter_AdmitsInArrivalOrder(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Acquire(context.Background(), "embed"); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Acquire(context.Background(), "embed"); err != nil {
+				t.Errorf("Acquire() error = %v", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			l.Release()
+		}()
+		waitForWaiters(t, l, i+1)
+	}
+
+	l.Release()
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("admission order = %v, want arrival order", order)
+		}
+	}
+}
+
+func TestLimiter_BatchesByModel(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Acquire(context.Background(), "embed"); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// Requests of pipelined stages arrive interleaved
+	models := []string{"enrich", "embed", "enrich", "embed"}
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Acquire(context.Background(), model); err != nil {
+				t.Errorf("Acquire() error = %v", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, model)
+			mu.Unlock()
+			l.Release()
+		}()
+		waitForWaiters(t, l, i+1)
+	}
+
+	l.Release()
+	wg.Wait()
+
+	want := []string{"embed", "embed", "enrich", "enrich"}
+	if !slices.Equal(order, want) {
+		t.Errorf("admission order = %v, want %v", order, want)
+	}
+}
+
+func TestLimiter_RunsEnd(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Acquire(context.Background(), "embed"); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// An enrichment waits behind more embeddings than a run admits
+	models := append([]string{"enrich"}, slices.Repeat([]string{"embed"}, maxRun)...)
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Acquire(context.Background(), model); err != nil {
+				t.Errorf("Acquire() error = %v", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, model)
+			mu.Unlock()
+			l.Release()
+		}()
+		waitForWaiters(t, l, i+1)
+	}
+
+	l.Release()
+	wg.Wait()
+
+	// The held request started the run
+	if i := slices.Index(order, "enrich"); i != maxRun-1 {
+		t.Errorf("enrichment admitted after %d embeddings, want %d", i, maxRun-1)
+	}
+}
+
+func TestLimiter_Limit(t *testing.T) {
+	l := NewLimiter(2)
+	for range 2 {
+		if err := l.Acquire(context.Background(), "embed"); err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(ctx, "embed"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() over the limit = %v, want deadline exceeded", err)
+	}
+
+	l.Release()
+	if err := l.Acquire(context.Background(), "embed"); err != nil {
+		t.Errorf("Acquire() after Release() error = %v", err)
+	}
+}
+
+func TestLimiter_CanceledWaiterGivesUpItsTurn(t *testing.T) {
+	l := NewLimiter(1)
+	l.Acquire(context.Background(), "embed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Acquire(ctx, "embed") }()
+	waitForWaiters(t, l, 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire() = %v, want canceled", err)
+	}
+
+	l.Release()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.Acquire(ctx, "embed"); err != nil {
+		t.Errorf("Acquire() after canceled waiter error = %v", err)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var l *Limiter
+	if err := l.Acquire(context.Background(), "embed"); err != nil {
+		t.Errorf("nil Acquire() error = %v", err)
+	}
+	l.Release()
+}
+
+// waitForWaiters waits until n requests are queued on l.
+func waitForWaiters(t *testing.T, l *Limiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		queued := l.waiters.Len()
+		l.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued requests", n)
+}
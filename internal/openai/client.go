@@ -0,0 +1,183 @@
+// Package openai uploads pages to OpenAI vector stores, so the corpus
+// bam-rag builds can back Assistants and Responses agents through
+// file_search.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config holds OpenAI API configuration.
+type Config struct {
+	APIKey  string        // Secret API key
+	BaseURL string        // API base URL; defaults to https://api.openai.com/v1
+	Timeout time.Duration // Limit on a single request; defaults to 2m
+}
+
+// Client calls the OpenAI files and vector store APIs.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// New creates an OpenAI client.
+func New(config Config) (*Client, error) {
+	if config.APIKey == "" {
+		return nil, errors.New("OpenAI API key is required")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.openai.com/v1"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &Client{config: config, httpClient: &http.Client{Timeout: config.Timeout}}, nil
+}
+
+// UploadFile uploads a file for use by assistants and returns its ID.
+func (c *Client) UploadFile(ctx context.Context, name string, content []byte) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("purpose", "assistants"); err != nil {
+		return "", err
+	}
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(content); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := c.send(ctx, http.MethodPost, "/files", w.FormDataContentType(), &body, &file); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	return file.ID, nil
+}
+
+// CreateVectorStore creates an empty vector store and returns its ID.
+func (c *Client) CreateVectorStore(ctx context.Context, name string) (string, error) {
+	var store struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/vector_stores", map[string]string{"name": name}, &store); err != nil {
+		return "", fmt.Errorf("failed to create vector store: %w", err)
+	}
+	return store.ID, nil
+}
+
+// FileBatch is a set of files being added to a vector store.
+type FileBatch struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"` // in_progress, completed, cancelled, or failed
+	FileCounts struct {
+		InProgress int `json:"in_progress"`
+		Completed  int `json:"completed"`
+		Failed     int `json:"failed"`
+		Cancelled  int `json:"cancelled"`
+		Total      int `json:"total"`
+	} `json:"file_counts"`
+}
+
+// Done reports whether the batch has stopped processing.
+func (b FileBatch) Done() bool {
+	return b.Status != "in_progress"
+}
+
+// MaxBatchFiles is the most files one batch can add.
+const MaxBatchFiles = 500
+
+// CreateFileBatch starts adding uploaded files to a vector store, which
+// chunks and embeds them.
+func (c *Client) CreateFileBatch(ctx context.Context, storeID string, fileIDs []string) (FileBatch, error) {
+	var batch FileBatch
+	body := map[string]interface{}{"file_ids": fileIDs}
+	if err := c.do(ctx, http.MethodPost, "/vector_stores/"+storeID+"/file_batches", body, &batch); err != nil {
+		return FileBatch{}, fmt.Errorf("failed to add files to vector store: %w", err)
+	}
+	return batch, nil
+}
+
+// WaitFileBatch polls a file batch every interval until it is done or ctx
+// ends.
+func (c *Client) WaitFileBatch(ctx context.Context, storeID string, batch FileBatch, interval time.Duration) (FileBatch, error) {
+	for !batch.Done() {
+		select {
+		case <-ctx.Done():
+			return batch, ctx.Err()
+		case <-time.After(interval):
+		}
+		if err := c.do(ctx, http.MethodGet, "/vector_stores/"+storeID+"/file_batches/"+batch.ID, nil, &batch); err != nil {
+			return batch, fmt.Errorf("failed to check file batch: %w", err)
+		}
+	}
+	return batch, nil
+}
+
+// do sends a request with a JSON body, if any, and decodes the JSON
+// response into out.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	return c.send(ctx, method, path, contentType, reader, out)
+}
+
+// send performs a request and decodes the JSON response into out.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("OpenAI API error %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return fmt.Errorf("OpenAI API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
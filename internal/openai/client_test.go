@@ -0,0 +1,118 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestClient_Upload(t *testing.T) {
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"error": {"message": "Incorrect API key provided"}}`)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/files":
+			if r.FormValue("purpose") != "assistants" {
+				t.Errorf("purpose = %q, want assistants", r.FormValue("purpose"))
+			}
+			_, header, err := r.FormFile("file")
+			if err != nil || header.Filename != "abc.md" {
+				t.Errorf("file = %v, %v; want abc.md", header, err)
+			}
+			io.WriteString(w, `{"id": "file-1"}`)
+		case "POST /v1/vector_stores":
+			io.WriteString(w, `{"id": "vs_1"}`)
+		case "POST /v1/vector_stores/vs_1/file_batches":
+			var body struct {
+				FileIDs []string `json:"file_ids"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body.FileIDs) != 1 || body.FileIDs[0] != "file-1" {
+				t.Errorf("file_ids = %v, want [file-1]", body.FileIDs)
+			}
+			io.WriteString(w, `{"id": "vsfb_1", "status": "in_progress"}`)
+		case "GET /v1/vector_stores/vs_1/file_batches/vsfb_1":
+			polls++
+			status := "in_progress"
+			if polls > 1 {
+				status = "completed"
+			}
+			io.WriteString(w, `{"id": "vsfb_1", "status": "`+status+`", "file_counts": {"completed": 1, "total": 1}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	client, err := New(Config{APIKey: "sk-test", BaseURL: srv.URL + "/v1/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileID, err := client.UploadFile(ctx, "abc.md", []byte("# Hello"))
+	if err != nil || fileID != "file-1" {
+		t.Fatalf("UploadFile() = %q, %v", fileID, err)
+	}
+	storeID, err := client.CreateVectorStore(ctx, "docs")
+	if err != nil || storeID != "vs_1" {
+		t.Fatalf("CreateVectorStore() = %q, %v", storeID, err)
+	}
+	batch, err := client.CreateFileBatch(ctx, storeID, []string{fileID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err = client.WaitFileBatch(ctx, storeID, batch, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != "completed" || batch.FileCounts.Completed != 1 || polls != 2 {
+		t.Errorf("WaitFileBatch() = %+v after %d polls, want completed after 2", batch, polls)
+	}
+
+	bad, _ := New(Config{APIKey: "sk-wrong", BaseURL: srv.URL + "/v1"})
+	if _, err := bad.CreateVectorStore(ctx, "docs"); err == nil || !strings.Contains(err.Error(), "Incorrect API key") {
+		t.Errorf("CreateVectorStore() with a bad key error = %v, want the API's message", err)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	doc := models.Document{
+		ID:        "abc",
+		URL:       "https://go.dev/doc",
+		Title:     `Go: "Getting started"`,
+		Content:   "# Getting started\n",
+		ScrapedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Source:    models.Source{Name: "go"},
+		Tags:      []string{"go", "install"},
+	}
+
+	want := `---
+title: "Go: \"Getting started\""
+url: "https://go.dev/doc"
+source: "go"
+scraped_at: "2025-03-01T00:00:00Z"
+tags: ["go","install"]
+---
+
+# Getting started
+`
+	if got := string(Markdown(doc)); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+	if FileName(doc) != "abc.md" {
+		t.Errorf("FileName() = %q, want abc.md", FileName(doc))
+	}
+}
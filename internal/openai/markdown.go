@@ -0,0 +1,52 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// FileName returns the name a page is uploaded under. file_search cites
+// files by name, so it is unique per page.
+func FileName(doc models.Document) string {
+	return doc.ID + ".md"
+}
+
+// Markdown renders a page as a markdown file led by YAML front matter with
+// its metadata, so answers citing the file can link back to the page.
+// Values are written as JSON strings, which YAML reads unchanged.
+func Markdown(doc models.Document) []byte {
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	field := func(key string, value interface{}) {
+		data, _ := json.Marshal(value)
+		b.WriteString(key + ": ")
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	field("title", doc.Title)
+	field("url", doc.URL)
+	if doc.Source.Name != "" {
+		field("source", doc.Source.Name)
+	}
+	if doc.Language != "" {
+		field("language", doc.Language)
+	}
+	if !doc.ScrapedAt.IsZero() {
+		field("scraped_at", doc.ScrapedAt.UTC().Format(time.RFC3339))
+	}
+	if doc.License != "" {
+		field("license", doc.License)
+	}
+	if len(doc.Tags) > 0 {
+		field("tags", doc.Tags)
+	}
+	if doc.Summary != "" {
+		field("summary", doc.Summary)
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(doc.Content)
+	return b.Bytes()
+}
@@ -3,16 +3,22 @@ package pipeline
 import (
 	"context"
 	"log/slog"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/mfenderov/bam-rag/internal/backend"
 	"github.com/mfenderov/bam-rag/internal/elasticsearch"
 	"github.com/mfenderov/bam-rag/internal/embeddings"
+	"github.com/mfenderov/bam-rag/internal/ingestion"
 	"github.com/mfenderov/bam-rag/internal/llm"
 	"github.com/mfenderov/bam-rag/internal/markdown"
 	"github.com/mfenderov/bam-rag/internal/processor"
 	"github.com/mfenderov/bam-rag/internal/scraper"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
 	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ScraperConfig holds scraper-specific configuration.
@@ -22,6 +28,17 @@ type ScraperConfig struct {
 	FollowLinks      bool
 	UserAgent        string
 	TryMarkdownFirst bool
+	ContentSelector  string
+	MaxParallel      int
+	RespectNoindex   bool
+	RespectRobots    bool
+	Incremental      bool
+	Retry            scraper.Retry
+	Proxies          []*url.URL
+	Include          []*regexp.Regexp
+	Exclude          []*regexp.Regexp
+	Auth             []scraper.Auth
+	Headers          map[string]string
 }
 
 // EmbeddingsConfig holds embeddings-specific configuration.
@@ -41,12 +58,22 @@ type LLMConfig struct {
 // Config holds pipeline configuration.
 type Config struct {
 	ESAddresses      []string
+	ESCloudID        string
 	ESIndex          string
 	ESUsername       string
 	ESPassword       string
+	ESAPIKey         string
+	ESMapping        elasticsearch.Mapping
 	ScraperConfig    ScraperConfig
 	EmbeddingsConfig EmbeddingsConfig
 	LLMConfig        LLMConfig
+	AccessLabels     []string            // Set on every indexed document
+	MaxChunkSize     int                 // Size in bytes above which sections are split into chunks; 0 uses the default
+	Redactor         *processor.Redactor // Masks sensitive strings in pages before indexing; may be nil
+
+	// Backend, if set, is indexed to instead of the Elasticsearch index
+	// above.
+	Backend backend.SearchBackend
 }
 
 // Result holds pipeline execution results.
@@ -54,13 +81,22 @@ type Result struct {
 	PagesScraped int
 	DocsIndexed  int
 	Duration     time.Duration
-	Errors       []error
+	Errors       []*models.PageError
+	Stages       map[string]time.Duration // Time spent in each stage (fetch, convert, enrich, embed, index), summed over documents
+}
+
+// track adds the time since start to the given stage.
+func (r *Result) track(stage string, start time.Time) {
+	if r.Stages == nil {
+		r.Stages = make(map[string]time.Duration)
+	}
+	r.Stages[stage] += time.Since(start)
 }
 
 // Pipeline orchestrates the scraping, processing, and indexing flow.
 type Pipeline struct {
 	config      Config
-	esClient    *elasticsearch.Client
+	index       backend.SearchBackend
 	scraper     *scraper.Scraper
 	processor   *processor.Processor
 	embedClient *embeddings.Client // nil if embeddings disabled
@@ -69,12 +105,7 @@ type Pipeline struct {
 
 // New creates a new Pipeline with the given configuration.
 func New(config Config) (*Pipeline, error) {
-	esClient, err := elasticsearch.New(elasticsearch.Config{
-		Addresses: config.ESAddresses,
-		Index:     config.ESIndex,
-		Username:  config.ESUsername,
-		Password:  config.ESPassword,
-	})
+	index, err := newIndex(config)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +116,17 @@ func New(config Config) (*Pipeline, error) {
 		FollowLinks:      config.ScraperConfig.FollowLinks,
 		UserAgent:        config.ScraperConfig.UserAgent,
 		TryMarkdownFirst: config.ScraperConfig.TryMarkdownFirst,
+		ContentSelector:  config.ScraperConfig.ContentSelector,
+		MaxParallel:      config.ScraperConfig.MaxParallel,
+		RespectNoindex:   config.ScraperConfig.RespectNoindex,
+		RespectRobots:    config.ScraperConfig.RespectRobots,
+		Incremental:      config.ScraperConfig.Incremental,
+		Retry:            config.ScraperConfig.Retry,
+		Proxies:          config.ScraperConfig.Proxies,
+		Include:          config.ScraperConfig.Include,
+		Exclude:          config.ScraperConfig.Exclude,
+		Auth:             config.ScraperConfig.Auth,
+		Headers:          config.ScraperConfig.Headers,
 	})
 
 	// Optionally create embeddings client
@@ -113,30 +155,53 @@ func New(config Config) (*Pipeline, error) {
 		slog.Info("LLM enrichment enabled", "model", config.LLMConfig.Model)
 	}
 
+	proc := processor.New()
+	proc.SetRedactor(config.Redactor)
 	return &Pipeline{
 		config:      config,
-		esClient:    esClient,
+		index:       index,
 		scraper:     scraperInstance,
-		processor:   processor.New(),
+		processor:   proc,
 		embedClient: embedClient,
 		llmClient:   llmClient,
 	}, nil
 }
 
+// newIndex returns the configured backend, or an Elasticsearch client.
+func newIndex(config Config) (backend.SearchBackend, error) {
+	if config.Backend != nil {
+		return config.Backend, nil
+	}
+	return elasticsearch.New(elasticsearch.Config{
+		Addresses: config.ESAddresses,
+		CloudID:   config.ESCloudID,
+		Index:     config.ESIndex,
+		Username:  config.ESUsername,
+		Password:  config.ESPassword,
+		APIKey:    config.ESAPIKey,
+		Mapping:   config.ESMapping,
+	})
+}
+
 // Run executes the full pipeline for a given URL.
-func (p *Pipeline) Run(ctx context.Context, startURL string) (*Result, error) {
+func (p *Pipeline) Run(ctx context.Context, startURL string) (_ *Result, err error) {
+	ctx, span := telemetry.Start(ctx, "pipeline", attribute.String("url", startURL))
+	defer func() { telemetry.End(span, err) }()
+
 	start := time.Now()
 	result := &Result{}
 
 	// Ensure index exists
-	if err := p.esClient.CreateIndex(ctx); err != nil {
+	if err := p.index.CreateIndex(ctx); err != nil {
 		return nil, err
 	}
 
 	// Scrape pages
+	fetchStart := time.Now()
 	scrapedDocs, err := p.scraper.Scrape(ctx, startURL)
+	result.track("fetch", fetchStart)
 	if err != nil {
-		result.Errors = append(result.Errors, err)
+		result.Errors = append(result.Errors, models.NewPageError(startURL, "fetch", err))
 	}
 	result.PagesScraped = len(scrapedDocs)
 
@@ -144,8 +209,10 @@ func (p *Pipeline) Run(ctx context.Context, startURL string) (*Result, error) {
 	for _, scraped := range scrapedDocs {
 		var mdContent string
 		var title string
+		var language string
 
 		// Check if content is already markdown
+		convertStart := time.Now()
 		isMarkdown := markdown.Detect(scraped.URL, scraped.ContentType, scraped.Content)
 
 		if isMarkdown {
@@ -155,33 +222,50 @@ func (p *Pipeline) Run(ctx context.Context, startURL string) (*Result, error) {
 			title = extractMarkdownTitle(scraped.Content)
 		} else {
 			// Content is HTML - extract title and convert
-			title = p.processor.ExtractTitle(scraped.Content)
-			var err error
-			mdContent, err = p.processor.Convert(scraped.Content)
+			page, err := p.processor.ConvertPage(scraped.Content)
+			result.track("convert", convertStart)
 			if err != nil {
-				result.Errors = append(result.Errors, err)
+				result.Errors = append(result.Errors, models.NewPageError(scraped.URL, "convert", err))
 				continue
 			}
+			mdContent, title, language = page.Markdown, page.Title, page.Language
 		}
+		mdContent = p.processor.Redact(mdContent)
+		title = p.processor.Redact(title)
 
 		if title == "" {
 			title = scraped.URL
 		}
+		if language == "" {
+			language = processor.DetectLanguage(mdContent)
+		}
 
 		// Create document with full markdown content
 		doc := models.Document{
-			ID:          models.GenerateDocumentID(scraped.URL),
-			URL:         scraped.URL,
-			Title:       title,
-			Content:     mdContent,
-			ContentType: scraped.ContentType,
-			ScrapedAt:   scraped.ScrapedAt,
+			ID:           models.GenerateDocumentID(scraped.URL),
+			URL:          scraped.URL,
+			Title:        title,
+			Content:      mdContent,
+			ContentType:  scraped.ContentType,
+			Language:     language,
+			ScrapedAt:    scraped.ScrapedAt,
+			Outline:      ingestion.Outline(mdContent),
+			WordCount:    ingestion.CountWords(mdContent),
+			TokenCount:   ingestion.EstimateTokens(mdContent),
+			Attachments:  ingestion.Attachments(mdContent, scraped.URL),
+			SiteName:     scraped.SiteName,
+			Favicon:      scraped.Favicon,
+			License:      scraped.License,
+			Source:       scraped.Source,
+			AccessLabels: p.config.AccessLabels,
 		}
 
 		// Generate tags and summary using LLM if enabled
 		// Note: Sequential execution is faster than parallel due to DMR GPU sharing
 		if p.llmClient != nil {
+			enrichStart := time.Now()
 			enrichment, err := p.llmClient.EnrichDocument(ctx, title, mdContent)
+			result.track("enrich", enrichStart)
 			if err != nil {
 				slog.Warn("failed to enrich document", "url", scraped.URL, "error", err)
 				// Continue without enrichment - basic BM25 will still work
@@ -193,6 +277,7 @@ func (p *Pipeline) Run(ctx context.Context, startURL string) (*Result, error) {
 		}
 
 		// Generate embedding of full content (qwen3-embedding supports ~24k chars)
+		embedStart := time.Now()
 		if p.embedClient != nil {
 			embedding, err := p.embedClient.Embed(ctx, mdContent)
 			if err != nil {
@@ -202,16 +287,38 @@ func (p *Pipeline) Run(ctx context.Context, startURL string) (*Result, error) {
 			}
 		}
 
-		// Index the full document
-		if err := p.esClient.IndexDocument(ctx, doc); err != nil {
-			result.Errors = append(result.Errors, err)
+		chunks := ingestion.ChunkDocument(&doc, p.config.MaxChunkSize)
+		for i := range chunks {
+			if p.embedClient == nil {
+				break
+			}
+			embedding, err := p.embedClient.Embed(ctx, chunks[i].Content)
+			if err != nil {
+				slog.Warn("failed to generate chunk embedding", "url", scraped.URL, "position", i, "error", err)
+				continue
+			}
+			chunks[i].Embedding = embedding
+		}
+		if p.embedClient != nil {
+			result.track("embed", embedStart)
+		}
+
+		// Index the full document, then its chunks for retrieval
+		indexStart := time.Now()
+		err := p.index.IndexDocument(ctx, doc)
+		if err == nil {
+			err = p.index.IndexChunks(ctx, doc.ID, chunks)
+		}
+		result.track("index", indexStart)
+		if err != nil {
+			result.Errors = append(result.Errors, models.NewPageError(scraped.URL, "index", err))
 		} else {
 			result.DocsIndexed++
 		}
 	}
 
 	// Refresh index to make documents searchable immediately
-	p.esClient.Refresh(ctx)
+	p.index.Refresh(ctx)
 
 	result.Duration = time.Since(start)
 	return result, nil
@@ -219,12 +326,12 @@ func (p *Pipeline) Run(ctx context.Context, startURL string) (*Result, error) {
 
 // Search queries the indexed documents.
 func (p *Pipeline) Search(ctx context.Context, query string, limit int) ([]models.Document, error) {
-	return p.esClient.Search(ctx, query, limit)
+	return p.index.SearchFiltered(ctx, query, limit, elasticsearch.Filter{})
 }
 
 // DeleteIndex removes the index (for testing/cleanup).
 func (p *Pipeline) DeleteIndex(ctx context.Context) error {
-	return p.esClient.DeleteIndex(ctx)
+	return p.index.DeleteIndex(ctx)
 }
 
 // extractMarkdownTitle extracts the first H1 heading from markdown content.
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// minStopwords is the number of stopword hits below which DetectLanguage
+// does not guess.
+const minStopwords = 5
+
+// stopwords holds frequent words that are distinctive for each language
+// DetectLanguage recognizes, keyed by ISO 639-1 code.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "with", "this", "that", "you", "for", "it"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "sie", "für", "auf"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "pour", "dans", "avec", "vous", "pas"},
+	"es": {"el", "los", "las", "es", "del", "una", "para", "con", "por", "que", "como", "más"},
+	"it": {"il", "della", "che", "di", "per", "con", "una", "sono", "gli", "non", "come", "nel"},
+	"pt": {"o", "os", "não", "uma", "para", "com", "do", "da", "em", "que", "você", "são"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "met", "voor", "je", "zijn", "dat"},
+}
+
+// stopwordLanguages indexes stopwords by word.
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, w := range words {
+			index[w] = append(index[w], lang)
+		}
+	}
+	return index
+}()
+
+// ExtractLanguage returns the language an HTML page declares in the lang
+// attribute of its <html> element, as a lowercase ISO 639-1 code.
+// Returns "" if the page declares none.
+func (p *Processor) ExtractLanguage(htmlContent string) string {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return ""
+	}
+
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type != html.ElementNode || n.Data != "html" {
+			continue
+		}
+		for _, attr := range n.Attr {
+			if attr.Key == "lang" {
+				return NormalizeLanguage(attr.Val)
+			}
+		}
+	}
+	return ""
+}
+
+// DetectLanguage guesses the language of text from its stopwords. Returns
+// the ISO 639-1 code of the best match, or "" if text is too short or
+// matches no language clearly.
+func DetectLanguage(text string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, lang := range stopwordLanguages[word] {
+			counts[lang]++
+		}
+	}
+
+	best, second := "", 0
+	for lang, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && lang < best) {
+			second = max(second, counts[best])
+			best = lang
+		} else {
+			second = max(second, n)
+		}
+	}
+	if counts[best] < minStopwords || counts[best] == second {
+		return ""
+	}
+	return best
+}
+
+// NormalizeLanguage reduces a language tag such as "en-US" to its
+// lowercase primary subtag.
+func NormalizeLanguage(tag string) string {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return strings.ToLower(primary)
+}
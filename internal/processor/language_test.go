@@ -0,0 +1,45 @@
+package processor
+
+import "testing"
+
+func TestProcessor_ExtractLanguage(t *testing.T) {
+	p := New()
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"region subtag", `<html lang="en-US"><head><title>T</title></head></html>`, "en"},
+		{"uppercase", `<html lang="DE"><body>Text</body></html>`, "de"},
+		{"undeclared", `<html><body>Text</body></html>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.ExtractLanguage(tt.html); got != tt.want {
+				t.Errorf("ExtractLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "This is the guide to install the tool and configure it for your project. You are ready.", "en"},
+		{"german", "Die Installation ist nicht schwer: der Befehl und die Konfiguration für das Projekt sind mit einer Datei erledigt.", "de"},
+		{"french", "La documentation est disponible pour les utilisateurs avec une installation dans le dossier et des exemples.", "fr"},
+		{"too short", "The tool.", ""},
+		{"code", "func main() { fmt.Println(x) }", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,7 +8,10 @@ import (
 )
 
 // Processor converts HTML content to Markdown.
-type Processor struct{}
+type Processor struct {
+	streamThreshold int64     // Size above which ConvertPage streams; 0 never streams
+	redactor        *Redactor // Masks sensitive strings; nil leaves content unchanged
+}
 
 // New creates a new HTML to Markdown processor.
 func New() *Processor {
@@ -0,0 +1,111 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Detector finds one kind of sensitive string.
+type Detector struct {
+	Name    string         // Shown in the mask, e.g. [REDACTED:email]
+	Pattern *regexp.Regexp // If it has a group named secret, only that group is masked
+}
+
+// builtinDetectors are the detectors available by name.
+var builtinDetectors = map[string]string{
+	"email":          `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"aws_access_key": `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"github_token":   `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`,
+	"slack_token":    `\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+	"jwt":            `\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`,
+	"private_key":    `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	"api_key":        `(?i)\b(?:api[_-]?key|secret|token|password|passwd)\b["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9_\-+/=.]{12,})`,
+}
+
+// BuiltinDetectors returns the names of the built-in detectors, sorted.
+func BuiltinDetectors() []string {
+	names := make([]string, 0, len(builtinDetectors))
+	for name := range builtinDetectors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// BuiltinDetector returns the built-in detector with the given name.
+func BuiltinDetector(name string) (Detector, bool) {
+	pattern, ok := builtinDetectors[name]
+	if !ok {
+		return Detector{}, false
+	}
+	return Detector{Name: name, Pattern: regexp.MustCompile(pattern)}, true
+}
+
+// NewDetector compiles a custom detector.
+func NewDetector(name, pattern string) (Detector, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Detector{}, fmt.Errorf("invalid pattern for %s: %w", name, err)
+	}
+	return Detector{Name: name, Pattern: re}, nil
+}
+
+// Redactor masks sensitive strings, such as email addresses and API keys,
+// with [REDACTED:<detector>]. A nil Redactor leaves text unchanged.
+type Redactor struct {
+	detectors []Detector
+}
+
+// NewRedactor creates a redactor applying detectors in order.
+func NewRedactor(detectors []Detector) *Redactor {
+	return &Redactor{detectors: detectors}
+}
+
+// Redact returns text with every match of the detectors masked, and the
+// number of strings masked.
+func (r *Redactor) Redact(text string) (string, int) {
+	if r == nil {
+		return text, 0
+	}
+	count := 0
+	for _, d := range r.detectors {
+		mask := "[REDACTED:" + d.Name + "]"
+		group := d.Pattern.SubexpIndex("secret")
+		matches := d.Pattern.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if group > 0 {
+				start, end = m[2*group], m[2*group+1]
+				if start < 0 {
+					continue
+				}
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(mask)
+			last = end
+			count++
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text, count
+}
+
+// SetRedactor sets the redactor Redact applies. Nil, the default, leaves
+// content unchanged.
+func (p *Processor) SetRedactor(r *Redactor) {
+	p.redactor = r
+}
+
+// Redact masks sensitive strings in content with the processor's redactor.
+func (p *Processor) Redact(content string) string {
+	redacted, _ := p.redactor.Redact(content)
+	return redacted
+}
@@ -0,0 +1,300 @@
+package processor
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Page is an HTML page converted to markdown.
+type Page struct {
+	Title    string // Content of <title>
+	Language string // Language declared by <html lang>, normalized
+	Markdown string
+}
+
+// SetStreamThreshold sets the size in bytes above which ConvertPage uses
+// ConvertStream. Zero, the default, always uses Convert.
+func (p *Processor) SetStreamThreshold(n int64) {
+	p.streamThreshold = n
+}
+
+// ConvertPage extracts the title and declared language of an HTML page
+// and converts it to markdown. Pages over the stream threshold are
+// converted in a single streaming pass instead of being parsed once for
+// each.
+func (p *Processor) ConvertPage(htmlContent string) (Page, error) {
+	if p.streamThreshold > 0 && int64(len(htmlContent)) > p.streamThreshold {
+		return ConvertStream(strings.NewReader(htmlContent))
+	}
+
+	md, err := p.Convert(htmlContent)
+	if err != nil {
+		return Page{}, err
+	}
+	return Page{
+		Title:    p.ExtractTitle(htmlContent),
+		Language: p.ExtractLanguage(htmlContent),
+		Markdown: md,
+	}, nil
+}
+
+// ConvertStream converts the HTML read from r to markdown in one pass over
+// its tokens, without building a document tree. It covers the structure
+// of documentation pages: headings, paragraphs, lists, links, images,
+// emphasis, and code. Other markup, such as tables, is reduced to its
+// text, so Convert gives better results for pages small enough for it.
+func ConvertStream(r io.Reader) (Page, error) {
+	c := &streamConverter{}
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return Page{}, err
+			}
+			return Page{
+				Title:    strings.TrimSpace(c.title.String()),
+				Language: c.language,
+				Markdown: strings.TrimSpace(c.out.String()),
+			}, nil
+		case html.TextToken:
+			c.text(string(z.Text()))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			var attrs map[string]string
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if attrs == nil {
+					attrs = make(map[string]string)
+				}
+				attrs[string(key)] = string(val)
+			}
+			c.start(atom.Lookup(name), attrs)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			c.end(atom.Lookup(name))
+		}
+	}
+}
+
+// streamConverter holds the state of ConvertStream.
+type streamConverter struct {
+	out      strings.Builder
+	newlines int  // Newlines at the end of out
+	space    bool // Whitespace is pending between inline content
+
+	title    strings.Builder
+	inTitle  bool
+	language string
+
+	skip  int      // Depth inside elements whose content is dropped
+	pre   int      // Depth inside <pre>
+	lists []int    // Open lists, innermost last: next item number, or 0 if unordered
+	links []string // Targets of open links; "" for anchors without one
+}
+
+// skipped are the elements whose content is not part of the page text.
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Iframe: true,
+}
+
+// blocks are the elements that start and end on a line of their own.
+var blocks = map[atom.Atom]bool{
+	atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Footer: true, atom.Nav: true, atom.Aside: true,
+	atom.Table: true, atom.Tr: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Details: true, atom.Summary: true,
+}
+
+func (c *streamConverter) start(a atom.Atom, attrs map[string]string) {
+	if skipped[a] {
+		c.skip++
+		return
+	}
+	if c.skip > 0 {
+		return
+	}
+
+	switch a {
+	case atom.Html:
+		if lang := attrs["lang"]; lang != "" {
+			c.language = NormalizeLanguage(lang)
+		}
+	case atom.Title:
+		c.inTitle = true
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		c.block(2)
+		c.write(strings.Repeat("#", int(a.String()[1]-'0')) + " ")
+	case atom.P, atom.Blockquote:
+		c.block(2)
+	case atom.Ul, atom.Ol:
+		next := 0
+		if a == atom.Ol {
+			next = 1
+		}
+		c.lists = append(c.lists, next)
+		c.block(1)
+	case atom.Li:
+		c.block(1)
+		marker := "- "
+		if n := len(c.lists); n > 0 {
+			c.write(strings.Repeat("  ", n-1))
+			if c.lists[n-1] > 0 {
+				marker = strconv.Itoa(c.lists[n-1]) + ". "
+				c.lists[n-1]++
+			}
+		}
+		c.write(marker)
+	case atom.Pre:
+		c.block(2)
+		c.write("```\n")
+		c.pre++
+	case atom.Code:
+		if c.pre == 0 {
+			c.inline("`")
+		}
+	case atom.Strong, atom.B:
+		c.inline("**")
+	case atom.Em, atom.I:
+		c.inline("*")
+	case atom.A:
+		href := attrs["href"]
+		c.links = append(c.links, href)
+		if href != "" {
+			c.inline("[")
+		}
+	case atom.Img:
+		if src := attrs["src"]; src != "" {
+			c.inline("![" + attrs["alt"] + "](" + src + ")")
+		}
+	case atom.Br:
+		c.write("\n")
+	case atom.Hr:
+		c.block(2)
+		c.write("---")
+		c.block(2)
+	default:
+		if blocks[a] {
+			c.block(1)
+		}
+	}
+}
+
+func (c *streamConverter) end(a atom.Atom) {
+	if skipped[a] {
+		c.skip = max(c.skip-1, 0)
+		return
+	}
+	if c.skip > 0 {
+		return
+	}
+
+	switch a {
+	case atom.Title:
+		c.inTitle = false
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.P, atom.Blockquote:
+		c.block(2)
+	case atom.Ul, atom.Ol:
+		if len(c.lists) > 0 {
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+		if len(c.lists) == 0 {
+			c.block(2)
+		}
+	case atom.Pre:
+		c.pre = max(c.pre-1, 0)
+		c.block(1)
+		c.write("```")
+		c.block(2)
+	case atom.Code:
+		if c.pre == 0 {
+			c.write("`")
+		}
+	case atom.Strong, atom.B:
+		c.write("**")
+	case atom.Em, atom.I:
+		c.write("*")
+	case atom.A:
+		if n := len(c.links); n > 0 {
+			if href := c.links[n-1]; href != "" {
+				c.write("](" + href + ")")
+			}
+			c.links = c.links[:n-1]
+		}
+	default:
+		if blocks[a] {
+			c.block(1)
+		}
+	}
+}
+
+// text adds text content, collapsing whitespace outside <pre>.
+func (c *streamConverter) text(s string) {
+	if c.skip > 0 {
+		return
+	}
+	if c.inTitle {
+		c.title.WriteString(s)
+		return
+	}
+	if c.pre > 0 {
+		c.write(s)
+		return
+	}
+
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		c.space = c.space || s != ""
+		return
+	}
+	first, _ := utf8.DecodeRuneInString(s)
+	last, _ := utf8.DecodeLastRuneInString(s)
+	c.space = c.space || unicode.IsSpace(first)
+	c.inline(strings.Join(words, " "))
+	c.space = unicode.IsSpace(last)
+}
+
+// inline writes inline content, preceded by any pending whitespace unless
+// it starts a line.
+func (c *streamConverter) inline(s string) {
+	if c.space && c.newlines == 0 && c.out.Len() > 0 {
+		c.out.WriteByte(' ')
+	}
+	c.space = false
+	c.write(s)
+}
+
+// block ends the current line and leaves n-1 blank lines after it, unless
+// nothing was written yet.
+func (c *streamConverter) block(n int) {
+	c.space = false
+	if c.out.Len() == 0 {
+		return
+	}
+	for c.newlines < n {
+		c.out.WriteByte('\n')
+		c.newlines++
+	}
+}
+
+// write appends s to the output as is.
+func (c *streamConverter) write(s string) {
+	if s == "" {
+		return
+	}
+	c.out.WriteString(s)
+	if trimmed := strings.TrimRight(s, "\n"); trimmed == "" {
+		c.newlines += len(s)
+	} else {
+		c.newlines = len(s) - len(trimmed)
+	}
+}
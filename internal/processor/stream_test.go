@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertStream(t *testing.T) {
+	page, err := ConvertStream(strings.NewReader(`<!DOCTYPE html>
+<html lang="de-DE">
+<head><title> Guide </title><style>body { color: red }</style></head>
+<body>
+  <script>alert("x")</script>
+  <h1>Getting   started</h1>
+  <p>Run <code>go run</code> in <a href="/docs">the <b>docs</b></a> folder.</p>
+  <ul><li>One</li><li>Two<ol><li>Nested</li></ol></li></ul>
+  <pre><code>func main() {
+	fmt.Println("hi")
+}</code></pre>
+  <p>Caf&eacute;<br>Next line <img src="/logo.png" alt="Logo"></p>
+</body>
+</html>`))
+	if err != nil {
+		t.Fatalf("ConvertStream() error = %v", err)
+	}
+
+	if page.Title != "Guide" {
+		t.Errorf("Title = %q, want Guide", page.Title)
+	}
+	if page.Language != "de" {
+		t.Errorf("Language = %q, want de", page.Language)
+	}
+
+	want := "# Getting started\n\n" +
+		"Run `go run` in [the **docs**](/docs) folder.\n\n" +
+		"- One\n- Two\n  1. Nested\n\n" +
+		"```\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\n" +
+		"Café\nNext line ![Logo](/logo.png)"
+	if page.Markdown != want {
+		t.Errorf("Markdown =\n%s\nwant\n%s", page.Markdown, want)
+	}
+}
+
+func TestProcessor_ConvertPage(t *testing.T) {
+	html := `<html lang="en"><head><title>Page</title></head><body><h2>Section</h2><p>Text.</p></body></html>`
+
+	for _, threshold := range []int64{0, 10} {
+		p := New()
+		p.SetStreamThreshold(threshold)
+		page, err := p.ConvertPage(html)
+		if err != nil {
+			t.Fatalf("threshold %d: ConvertPage() error = %v", threshold, err)
+		}
+		if page.Title != "Page" || page.Language != "en" {
+			t.Errorf("threshold %d: title %q, language %q", threshold, page.Title, page.Language)
+		}
+		if !strings.Contains(page.Markdown, "## Section") || !strings.Contains(page.Markdown, "Text.") {
+			t.Errorf("threshold %d: Markdown = %q", threshold, page.Markdown)
+		}
+	}
+}
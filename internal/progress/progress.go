@@ -0,0 +1,264 @@
+package progress
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// EventType identifies the kind of progress event.
+type EventType string
+
+const (
+	EventScrapeStart    EventType = "scrape_start"    // A source scrape started
+	EventPage           EventType = "page"            // A page was scraped
+	EventScrapeComplete EventType = "scrape_complete" // A source scrape finished
+	EventIngestStart    EventType = "ingest_start"    // Ingestion of a prefix started
+	EventDocument       EventType = "document"        // A document was processed during ingestion
+	EventIngestComplete EventType = "ingest_complete" // Ingestion of a prefix finished
+	EventDelete         EventType = "delete"          // Documents were removed from the index
+	EventBackfill       EventType = "backfill"        // Indexed documents were updated in place, e.g. embeddings backfilled
+	EventWarning        EventType = "warning"         // Non-fatal problem
+	EventError          EventType = "error"           // A source or prefix failed
+	EventInfo           EventType = "info"            // Informational message
+	EventSummary        EventType = "summary"         // Final totals for the run
+)
+
+// Stage identifies how far a document got through ingestion.
+type Stage string
+
+const (
+	StageProcessed Stage = "processed" // Converted to markdown
+	StageEnriched  Stage = "enriched"  // Tagged and summarized by the LLM
+	StageEmbedded  Stage = "embedded"  // Embedding generated
+	StageIndexed   Stage = "indexed"   // Written to the index; the document is done
+	StageFailed    Stage = "failed"    // Skipped after an error; the document is done
+)
+
+// Done reports whether the stage is the last one of its document.
+func (s Stage) Done() bool {
+	return s == StageIndexed || s == StageFailed
+}
+
+// Event is a single progress or result event.
+type Event struct {
+	Type      EventType     `json:"type"`
+	Time      time.Time     `json:"time"`
+	URL       string        `json:"url,omitempty"`
+	Prefix    string        `json:"prefix,omitempty"`
+	Stage     Stage         `json:"stage,omitempty"`   // Document events: the stage reached
+	Current   int           `json:"current,omitempty"` // Items processed so far
+	Total     int           `json:"total,omitempty"`   // Items expected (0 if unknown)
+	Pages     int           `json:"pages,omitempty"`
+	Docs      int           `json:"docs,omitempty"`
+	Unchanged int           `json:"unchanged,omitempty"` // Ingestion completion events: pages not reprocessed
+	Duration  time.Duration `json:"duration_ns,omitempty"`
+	Message   string        `json:"message,omitempty"`
+	Report    string        `json:"report,omitempty"` // Completion events: location of the run report
+	IDs       []string      `json:"ids,omitempty"`    // Delete and backfill events: the documents affected, if known
+	Actor     string        `json:"actor,omitempty"`  // Who caused the event if not whoever runs the command, e.g. webhook:<source>
+
+	// Stages is the time spent in each stage, summed over documents, for
+	// ingestion completion and summary events
+	Stages map[string]time.Duration `json:"stages_ns,omitempty"`
+
+	Error *models.PageError `json:"error,omitempty"` // Failed documents and page warnings: what failed
+}
+
+// Reporter receives progress events. Implementations must be safe for concurrent use.
+type Reporter interface {
+	Report(e Event)
+}
+
+// New returns a reporter for the given output format ("text" or "json").
+func New(format string, w io.Writer) (Reporter, error) {
+	switch format {
+	case "", "text":
+		return NewText(w, IsTerminal(w)), nil
+	case "json":
+		return NewJSON(w), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (want text or json)", format)
+	}
+}
+
+// Tee returns a reporter that forwards every event to each of reporters.
+func Tee(reporters ...Reporter) Reporter {
+	return tee(reporters)
+}
+
+type tee []Reporter
+
+func (t tee) Report(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, r := range t {
+		r.Report(e)
+	}
+}
+
+// IsTerminal reports whether w is an interactive terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// JSON writes each event as a single line of JSON (NDJSON).
+type JSON struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSON creates a reporter emitting newline-delimited JSON events.
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{enc: json.NewEncoder(w)}
+}
+
+// Report writes the event as a JSON line.
+func (j *JSON) Report(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.enc.Encode(e)
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// barWidth is the number of cells in the ingestion progress bar.
+const barWidth = 30
+
+// Text renders events as human-readable lines.
+// When interactive, page and document events update a spinner or progress bar in place.
+type Text struct {
+	mu          sync.Mutex
+	w           io.Writer
+	interactive bool
+	frame       int
+	liveLine    bool // A spinner/bar line is currently displayed
+}
+
+// NewText creates a human-readable reporter.
+func NewText(w io.Writer, interactive bool) *Text {
+	return &Text{w: w, interactive: interactive}
+}
+
+// Report renders the event.
+func (t *Text) Report(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch e.Type {
+	case EventPage:
+		if t.interactive {
+			t.frame = (t.frame + 1) % len(spinnerFrames)
+			t.live(fmt.Sprintf("  %s %d pages scraped  %s", spinnerFrames[t.frame], e.Current, e.URL))
+		}
+	case EventDocument:
+		if t.interactive {
+			if e.Stage != "" && !e.Stage.Done() {
+				t.live(fmt.Sprintf("  %s %d/%d  %s  %s", bar(e.Current, e.Total), e.Current, e.Total, e.Stage, e.URL))
+			} else {
+				t.live(fmt.Sprintf("  %s %d/%d  %s", bar(e.Current, e.Total), e.Current, e.Total, e.URL))
+			}
+		}
+	case EventScrapeStart:
+		t.line("Scraping: %s", e.URL)
+	case EventScrapeComplete:
+		t.line("  Pages: %d, Prefix: %s", e.Pages, e.Prefix)
+		t.report(e.Report)
+	case EventIngestStart:
+		if e.Total > 0 {
+			t.line("Ingesting: %s (%d pages)", e.Prefix, e.Total)
+		} else {
+			t.line("Ingesting: %s", e.Prefix)
+		}
+	case EventIngestComplete:
+		if e.Unchanged > 0 {
+			t.line("  Docs indexed: %d, Unchanged: %d, Duration: %v", e.Docs, e.Unchanged, e.Duration)
+		} else {
+			t.line("  Docs indexed: %d, Duration: %v", e.Docs, e.Duration)
+		}
+		t.stages("  ", e.Stages)
+		t.report(e.Report)
+	case EventSummary:
+		t.line("%s", e.Message)
+		t.stages("", e.Stages)
+	case EventWarning:
+		t.line("  Warning: %s", e.Message)
+	case EventError:
+		t.line("  Error: %s", e.Message)
+	default:
+		t.line("%s", e.Message)
+	}
+}
+
+// line prints a full line, clearing any live spinner/bar first.
+func (t *Text) line(format string, args ...any) {
+	if t.liveLine {
+		fmt.Fprint(t.w, "\r\033[K")
+		t.liveLine = false
+	}
+	fmt.Fprintf(t.w, format+"\n", args...)
+}
+
+// report prints the location of a run report, if one was written.
+func (t *Text) report(location string) {
+	if location != "" {
+		t.line("  Report: %s", location)
+	}
+}
+
+// stages prints the time spent in each stage, longest first, with its
+// share of the total.
+func (t *Text) stages(indent string, stages map[string]time.Duration) {
+	if len(stages) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, d := range stages {
+		total += d
+	}
+	names := slices.SortedFunc(maps.Keys(stages), func(a, b string) int {
+		return cmp.Or(cmp.Compare(stages[b], stages[a]), strings.Compare(a, b))
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		d := stages[name]
+		parts[i] = fmt.Sprintf("%s %v (%d%%)", name, d.Round(time.Millisecond), int(100*d/max(total, 1)))
+	}
+	t.line("%sTime by stage: %s", indent, strings.Join(parts, ", "))
+}
+
+// live replaces the current live line with s.
+func (t *Text) live(s string) {
+	fmt.Fprint(t.w, "\r\033[K"+s)
+	t.liveLine = true
+}
+
+// bar renders a fixed-width progress bar.
+func bar(current, total int) string {
+	if total <= 0 {
+		return "[" + strings.Repeat(" ", barWidth) + "]"
+	}
+	filled := min(current*barWidth/total, barWidth)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled) + "]"
+}
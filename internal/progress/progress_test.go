@@ -0,0 +1,156 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestNew_Formats(t *testing.T) {
+	var buf bytes.Buffer
+
+	if _, err := New("text", &buf); err != nil {
+		t.Errorf("New(text) error = %v", err)
+	}
+	if _, err := New("json", &buf); err != nil {
+		t.Errorf("New(json) error = %v", err)
+	}
+	if _, err := New("yaml", &buf); err == nil {
+		t.Error("New(yaml) should return error")
+	}
+}
+
+func TestJSON_Report(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSON(&buf)
+
+	r.Report(Event{Type: EventScrapeStart, URL: "https://example.com"})
+	r.Report(Event{Type: EventIngestComplete, Prefix: "scrapes/x", Docs: 3, Duration: time.Second})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var e Event
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatalf("invalid JSON line: %v", err)
+	}
+	if e.Type != EventIngestComplete || e.Docs != 3 || e.Duration != time.Second {
+		t.Errorf("decoded event = %+v", e)
+	}
+	if e.Time.IsZero() {
+		t.Error("Time should be set automatically")
+	}
+}
+
+func TestJSON_ReportError(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSON(&buf)
+
+	pageErr := &models.PageError{URL: "https://example.com/a", Stage: "index", Retryable: true, Message: "timeout"}
+	r.Report(Event{Type: EventWarning, URL: pageErr.URL, Message: pageErr.Error(), Error: pageErr})
+
+	want := `"error":{"url":"https://example.com/a","stage":"index","retryable":true,"message":"timeout"}`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("JSON = %s, want it to contain %s", buf.String(), want)
+	}
+}
+
+func TestText_NonInteractiveSkipsLiveEvents(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewText(&buf, false)
+
+	r.Report(Event{Type: EventScrapeStart, URL: "https://example.com"})
+	r.Report(Event{Type: EventPage, URL: "https://example.com/a", Current: 1})
+	r.Report(Event{Type: EventScrapeComplete, Pages: 1, Prefix: "scrapes/example.com/x"})
+
+	want := "Scraping: https://example.com\n  Pages: 1, Prefix: scrapes/example.com/x\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestText_InteractiveClearsLiveLine(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewText(&buf, true)
+
+	r.Report(Event{Type: EventDocument, URL: "https://example.com/a", Current: 1, Total: 2})
+	r.Report(Event{Type: EventIngestComplete, Docs: 2})
+
+	out := buf.String()
+	if !strings.Contains(out, "1/2") {
+		t.Errorf("expected progress bar in output: %q", out)
+	}
+	if !strings.HasSuffix(out, "\r\033[K  Docs indexed: 2, Duration: 0s\n") {
+		t.Errorf("expected live line to be cleared before summary: %q", out)
+	}
+}
+
+func TestText_DocumentStages(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewText(&buf, true)
+
+	r.Report(Event{Type: EventDocument, URL: "https://example.com/a", Stage: StageEmbedded, Current: 0, Total: 2})
+	if !strings.Contains(buf.String(), "0/2  embedded  https://example.com/a") {
+		t.Errorf("expected in-progress stage in output: %q", buf.String())
+	}
+
+	buf.Reset()
+	r.Report(Event{Type: EventDocument, URL: "https://example.com/a", Stage: StageIndexed, Current: 1, Total: 2})
+	if out := buf.String(); !strings.Contains(out, "1/2  https://example.com/a") || strings.Contains(out, "indexed") {
+		t.Errorf("finished documents should show progress only: %q", out)
+	}
+}
+
+func TestText_RunReport(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewText(&buf, false)
+
+	r.Report(Event{Type: EventIngestComplete, Docs: 3, Duration: time.Second, Report: "s3://bam-rag/scrapes/example.com/x/report.json"})
+	r.Report(Event{Type: EventIngestComplete, Docs: 0, Duration: time.Second})
+
+	want := "  Docs indexed: 3, Duration: 1s\n  Report: s3://bam-rag/scrapes/example.com/x/report.json\n  Docs indexed: 0, Duration: 1s\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestText_Stages(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewText(&buf, false)
+
+	stages := map[string]time.Duration{"embed": time.Second, "enrich": 3 * time.Second}
+	r.Report(Event{Type: EventIngestComplete, Docs: 3, Duration: 4 * time.Second, Stages: stages})
+	r.Report(Event{Type: EventSummary, Message: "Total: 3 docs", Stages: stages})
+
+	want := "  Docs indexed: 3, Duration: 4s\n  Time by stage: enrich 3s (75%), embed 1s (25%)\n" +
+		"Total: 3 docs\nTime by stage: enrich 3s (75%), embed 1s (25%)\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestBar(t *testing.T) {
+	if got := bar(5, 10); strings.Count(got, "█") != barWidth/2 {
+		t.Errorf("bar(5, 10) = %q, want half filled", got)
+	}
+	if got := bar(20, 10); strings.Count(got, "█") != barWidth {
+		t.Errorf("bar(20, 10) = %q, want fully filled", got)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var a, b bytes.Buffer
+	r := Tee(NewJSON(&a), NewJSON(&b))
+
+	r.Report(Event{Type: EventInfo, Message: "hello"})
+
+	if a.String() == "" || a.String() != b.String() {
+		t.Errorf("reporters got %q and %q, want the same event", a.String(), b.String())
+	}
+}
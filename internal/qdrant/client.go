@@ -0,0 +1,461 @@
+// Package qdrant is a search backend kept in a Qdrant vector database,
+// spoken to over its REST API. Pages and chunks are stored as points with
+// their fields as payload; embeddings are dense vectors, and text is
+// matched through sparse term vectors that Qdrant weighs by IDF, so
+// searches combine both with Qdrant's reciprocal rank fusion.
+package qdrant
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Config holds Qdrant connection configuration.
+type Config struct {
+	URL        string        // REST API base URL, e.g. http://localhost:6333
+	APIKey     string        // Sent as the api-key header; optional
+	Collection string        // Documents are kept in this collection and chunks in <collection>_chunks
+	VectorSize int           // Dimensions of embeddings; defaults to 2560, as in the Elasticsearch mapping
+	Timeout    time.Duration // Request timeout; defaults to 30s
+}
+
+// Vector names. Documents and chunks carry a dense embedding and sparse
+// term vectors; documents have a second one for summary-scope searches.
+const (
+	denseVector   = "dense"
+	textVector    = "text"
+	summaryVector = "summary"
+)
+
+// Client is a Qdrant search backend. Safe for concurrent use.
+type Client struct {
+	config          Config
+	chunkCollection string
+	httpClient      *http.Client
+}
+
+// New creates a Qdrant client. It does not contact the server; use Ping to
+// check that it is reachable.
+func New(config Config) (*Client, error) {
+	if config.URL == "" {
+		return nil, errors.New("qdrant URL is required")
+	}
+	if config.Collection == "" {
+		return nil, errors.New("qdrant collection is required")
+	}
+	if config.VectorSize == 0 {
+		config.VectorSize = 2560
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+
+	return &Client{
+		config:          config,
+		chunkCollection: config.Collection + "_chunks",
+		httpClient:      &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Close closes the idle connections of the client; requests made
+// afterwards open new ones.
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Ping reports whether Qdrant answers.
+func (c *Client) Ping(ctx context.Context) bool {
+	return c.do(ctx, http.MethodGet, "/healthz", nil, nil) == nil
+}
+
+// CreateIndex creates the document and chunk collections, with payload
+// indexes on the fields searches filter on. Existing collections are kept.
+func (c *Client) CreateIndex(ctx context.Context) error {
+	if err := c.createCollection(ctx, c.config.Collection, []string{textVector, summaryVector}, documentIndexes); err != nil {
+		return err
+	}
+	return c.createCollection(ctx, c.chunkCollection, []string{textVector}, chunkIndexes)
+}
+
+// Payload indexes of each collection, by field.
+var (
+	documentIndexes = map[string]string{
+		"id":            "keyword",
+		"source.name":   "keyword",
+		"source.host":   "keyword",
+		"language":      "keyword",
+		"access_labels": "keyword",
+		"scraped_at":    "datetime",
+		"title":         "text",
+		"content":       "text",
+		"tags":          "text",
+		"summary":       "text",
+	}
+	chunkIndexes = map[string]string{
+		"id":            "keyword",
+		"document_id":   "keyword",
+		"source.name":   "keyword",
+		"source.host":   "keyword",
+		"language":      "keyword",
+		"access_labels": "keyword",
+		"scraped_at":    "datetime",
+		"title":         "text",
+		"content":       "text",
+		"heading_path":  "text",
+	}
+)
+
+// createCollection creates a collection with a dense vector, the given
+// sparse vectors, and payload indexes, unless it exists.
+func (c *Client) createCollection(ctx context.Context, name string, sparse []string, indexes map[string]string) error {
+	var exists struct {
+		Result struct {
+			Exists bool `json:"exists"`
+		} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/collections/"+url.PathEscape(name)+"/exists", nil, &exists); err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", name, err)
+	}
+	if exists.Result.Exists {
+		return nil
+	}
+
+	sparseVectors := make(map[string]interface{}, len(sparse))
+	for _, v := range sparse {
+		sparseVectors[v] = map[string]interface{}{"modifier": "idf"}
+	}
+	body := map[string]interface{}{
+		"vectors": map[string]interface{}{
+			denseVector: map[string]interface{}{"size": c.config.VectorSize, "distance": "Cosine"},
+		},
+		"sparse_vectors": sparseVectors,
+	}
+	if err := c.do(ctx, http.MethodPut, "/collections/"+url.PathEscape(name), body, nil); err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", name, err)
+	}
+
+	for field, schema := range indexes {
+		body := map[string]interface{}{"field_name": field, "field_schema": schema}
+		if err := c.do(ctx, http.MethodPut, "/collections/"+url.PathEscape(name)+"/index?wait=true", body, nil); err != nil {
+			return fmt.Errorf("failed to index %s in collection %s: %w", field, name, err)
+		}
+	}
+	return nil
+}
+
+// DeleteIndex removes the document and chunk collections (for
+// testing/cleanup).
+func (c *Client) DeleteIndex(ctx context.Context) error {
+	for _, name := range []string{c.config.Collection, c.chunkCollection} {
+		if err := c.do(ctx, http.MethodDelete, "/collections/"+url.PathEscape(name), nil, nil); err != nil {
+			return fmt.Errorf("failed to delete collection %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Refresh does nothing: writes wait until Qdrant has applied them, so they
+// are searchable once they return.
+func (c *Client) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// SetRefreshInterval does nothing: Qdrant has no refresh interval.
+func (c *Client) SetRefreshInterval(ctx context.Context, interval string) (func(context.Context) error, error) {
+	return func(context.Context) error { return nil }, nil
+}
+
+// point is a page or chunk as stored in Qdrant.
+type point struct {
+	ID      string                 `json:"id"`
+	Vector  map[string]interface{} `json:"vector"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// pointID returns the UUID a page or chunk is stored under, derived from
+// its ID since Qdrant only accepts UUIDs and integers.
+func pointID(id string) string {
+	h := sha256.Sum256([]byte(id))
+	h[6] = h[6]&0x0f | 0x50 // Version 5 layout, name-based with SHA
+	h[8] = h[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+// payload returns v's JSON fields as a point payload, without the
+// embedding, which is stored as a vector.
+func payload(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var p map[string]interface{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	delete(p, "embedding")
+	return p, nil
+}
+
+// IndexDocument adds or replaces a page.
+func (c *Client) IndexDocument(ctx context.Context, doc models.Document) error {
+	p, err := payload(doc)
+	if err != nil {
+		return err
+	}
+	vectors := map[string]interface{}{
+		textVector:    termVector(documentFields(doc)),
+		summaryVector: termVector(summaryFields(doc)),
+	}
+	if len(doc.Embedding) > 0 {
+		vectors[denseVector] = doc.Embedding
+	}
+	return c.upsert(ctx, c.config.Collection, []point{{ID: pointID(doc.ID), Vector: vectors, Payload: p}})
+}
+
+// IndexChunks replaces the chunks of a document.
+func (c *Client) IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error {
+	if err := c.deleteChunks(ctx, documentID); err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	points := make([]point, len(chunks))
+	for i, chunk := range chunks {
+		p, err := payload(chunk)
+		if err != nil {
+			return err
+		}
+		vectors := map[string]interface{}{textVector: termVector(chunkFields(chunk))}
+		if len(chunk.Embedding) > 0 {
+			vectors[denseVector] = chunk.Embedding
+		}
+		points[i] = point{ID: pointID(chunk.ID), Vector: vectors, Payload: p}
+	}
+	return c.upsert(ctx, c.chunkCollection, points)
+}
+
+// upsert writes points to a collection.
+func (c *Client) upsert(ctx context.Context, collection string, points []point) error {
+	body := map[string]interface{}{"points": points}
+	if err := c.do(ctx, http.MethodPut, "/collections/"+url.PathEscape(collection)+"/points?wait=true", body, nil); err != nil {
+		return fmt.Errorf("failed to index into %s: %w", collection, err)
+	}
+	return nil
+}
+
+// DeleteDocument removes a document and its chunks.
+func (c *Client) DeleteDocument(ctx context.Context, id string) error {
+	body := map[string]interface{}{"points": []string{pointID(id)}}
+	if err := c.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(c.config.Collection)+"/points/delete?wait=true", body, nil); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	return c.deleteChunks(ctx, id)
+}
+
+// deleteChunks removes the chunks of a document.
+func (c *Client) deleteChunks(ctx context.Context, documentID string) error {
+	body := map[string]interface{}{
+		"filter": map[string]interface{}{"must": []interface{}{matchValue("document_id", documentID)}},
+	}
+	if err := c.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(c.chunkCollection)+"/points/delete?wait=true", body, nil); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return nil
+}
+
+// ContentHashes returns the content hash of each stored document among
+// ids. Documents that are not stored, or were stored without a hash, are
+// left out.
+func (c *Client) ContentHashes(ctx context.Context, ids []string) (map[string]string, error) {
+	var docs []models.Document
+	if err := c.retrieve(ctx, c.config.Collection, ids, []string{"id", "content_hash"}, &docs); err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string)
+	for _, doc := range docs {
+		if doc.ContentHash != "" {
+			hashes[doc.ID] = doc.ContentHash
+		}
+	}
+	return hashes, nil
+}
+
+// GetDocument returns a document by ID, or nil if it does not exist.
+func (c *Client) GetDocument(ctx context.Context, id string) (*models.Document, error) {
+	docs, err := c.GetDocuments(ctx, []string{id})
+	if err != nil || len(docs) == 0 {
+		return nil, err
+	}
+	return &docs[0], nil
+}
+
+// GetDocuments returns the documents with the given IDs, in order.
+// Documents that do not exist are left out.
+func (c *Client) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	var docs []models.Document
+	if err := c.retrieve(ctx, c.config.Collection, ids, nil, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// GetChunk returns a chunk by ID, or nil if it does not exist.
+func (c *Client) GetChunk(ctx context.Context, id string) (*models.Chunk, error) {
+	chunks, err := c.GetChunks(ctx, []string{id})
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	return &chunks[0], nil
+}
+
+// GetChunks returns the chunks with the given IDs, in order. Chunks that
+// do not exist are left out.
+func (c *Client) GetChunks(ctx context.Context, ids []string) ([]models.Chunk, error) {
+	var chunks []models.Chunk
+	if err := c.retrieve(ctx, c.chunkCollection, ids, nil, &chunks); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// scoredPoint is a point as Qdrant returns it.
+type scoredPoint struct {
+	ID      string                     `json:"id"`
+	Payload json.RawMessage            `json:"payload"`
+	Vector  map[string]json.RawMessage `json:"vector"`
+}
+
+// retrieve fetches the points with the given IDs and decodes them, in the
+// order of ids, into out, a pointer to a slice of documents or chunks.
+// fields limits the payload returned; nil returns all of it and the
+// embedding.
+func (c *Client) retrieve(ctx context.Context, collection string, ids []string, fields []string, out interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	pointIDs := make([]string, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = pointID(id)
+	}
+	body := map[string]interface{}{"ids": pointIDs, "with_payload": true, "with_vector": []string{denseVector}}
+	if fields != nil {
+		body["with_payload"] = fields
+		body["with_vector"] = false
+	}
+
+	var res struct {
+		Result []scoredPoint `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(collection)+"/points", body, &res); err != nil {
+		return fmt.Errorf("failed to get from %s: %w", collection, err)
+	}
+
+	byID := make(map[string]scoredPoint, len(res.Result))
+	for _, p := range res.Result {
+		byID[p.ID] = p
+	}
+	ordered := make([]scoredPoint, 0, len(res.Result))
+	for _, id := range pointIDs {
+		if p, ok := byID[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+	return decodePoints(ordered, out)
+}
+
+// decodePoints decodes the payload and embedding of points into out, a
+// pointer to a slice of documents or chunks.
+func decodePoints(points []scoredPoint, out interface{}) error {
+	entries := make([]json.RawMessage, len(points))
+	for i, p := range points {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(p.Payload, &fields); err != nil {
+			return fmt.Errorf("failed to decode payload: %w", err)
+		}
+		if fields == nil {
+			fields = make(map[string]json.RawMessage)
+		}
+		if v, ok := p.Vector[denseVector]; ok {
+			fields["embedding"] = v
+		}
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return fmt.Errorf("failed to decode payload: %w", err)
+		}
+		entries[i] = data
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to decode payload: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode payload: %w", err)
+	}
+	return nil
+}
+
+// do sends a request with a JSON body, if any, and decodes the JSON
+// response into out, if set.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.APIKey != "" {
+		req.Header.Set("api-key", c.config.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var qdrantErr struct {
+			Status struct {
+				Error string `json:"error"`
+			} `json:"status"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &qdrantErr) == nil && qdrantErr.Status.Error != "" {
+			return fmt.Errorf("qdrant error %d: %s", resp.StatusCode, qdrantErr.Status.Error)
+		}
+		return fmt.Errorf("qdrant returned status %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// request is a call the fake Qdrant server received.
+type request struct {
+	method, path string
+	body         map[string]interface{}
+}
+
+// fakeQdrant serves canned responses by path and records requests.
+func fakeQdrant(t *testing.T, responses map[string]string) (*Client, *[]request) {
+	t.Helper()
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		req := request{method: r.Method, path: r.URL.Path}
+		json.Unmarshal(data, &req.body)
+		requests = append(requests, req)
+		if resp, ok := responses[r.Method+" "+r.URL.Path]; ok {
+			io.WriteString(w, resp)
+			return
+		}
+		io.WriteString(w, `{"result": true, "status": "ok"}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := New(Config{URL: srv.URL + "/", APIKey: "secret", Collection: "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &requests
+}
+
+// pointJSON returns a point as Qdrant returns it, with v as its payload.
+func pointJSON(t *testing.T, id string, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"id": pointID(id), "payload": v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPointID(t *testing.T) {
+	id := pointID("abc123")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("pointID() = %q, want a version 5 UUID", id)
+	}
+	if pointID("abc123") != id || pointID("abc124") == id {
+		t.Error("pointID() must be deterministic and distinct per ID")
+	}
+}
+
+func TestTermVector(t *testing.T) {
+	v := termVector([]weighted{{"Go go GO", 1}, {"modules", 2}})
+	if len(v.Indices) != 2 || len(v.Values) != 2 {
+		t.Fatalf("termVector() = %+v, want 2 terms", v)
+	}
+	if v.Indices[0] > v.Indices[1] {
+		t.Errorf("indices %v not sorted", v.Indices)
+	}
+	weights := map[uint32]float32{v.Indices[0]: v.Values[0], v.Indices[1]: v.Values[1]}
+	goWeight, modulesWeight := weights[termIndex("go")], weights[termIndex("modules")]
+	// Three repeats weigh more than a boost of two, but less than three
+	// times a single occurrence
+	if goWeight <= modulesWeight || goWeight >= 3*float32(termSaturation+1)/float32(1+termSaturation) {
+		t.Errorf("weights go=%v modules=%v, want saturated frequencies", goWeight, modulesWeight)
+	}
+
+	if q := queryVector("go Go modules"); len(q.Indices) != 2 || q.Values[0] != 1 {
+		t.Errorf("queryVector() = %+v, want 2 terms of weight 1", q)
+	}
+}
+
+func TestPayloadFilter(t *testing.T) {
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter elasticsearch.Filter
+		want   string
+	}{
+		{name: "zero", want: `{}`},
+		{
+			name:   "sources and languages",
+			filter: elasticsearch.Filter{Sources: []string{"go"}, Languages: []string{"en"}},
+			want:   `{"must":[{"key":"source.name","match":{"any":["go"]}},{"key":"language","match":{"any":["en"]}}]}`,
+		},
+		{
+			name:   "sources with url prefixes are matched afterwards",
+			filter: elasticsearch.Filter{Sources: []string{"go"}, URLPrefixes: []string{"https://go.dev/"}},
+			want:   `{}`,
+		},
+		{
+			name:   "dates and access",
+			filter: elasticsearch.Filter{After: after, EnforceAccess: true, AccessLabels: []string{"staff"}},
+			want:   `{"must":[{"key":"scraped_at","range":{"gte":"2025-01-01T00:00:00Z"}},{"should":[{"is_empty":{"key":"access_labels"}},{"key":"access_labels","match":{"any":["staff"]}}]}]}`,
+		},
+		{
+			name:   "titles and exclusions",
+			filter: elasticsearch.Filter{Titles: []string{"install"}, Exclude: []string{"nginx"}},
+			want:   `{"must":[{"key":"title","match":{"text":"install"}}],"must_not":[{"key":"title","match":{"text":"nginx"}},{"key":"content","match":{"text":"nginx"}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(payloadFilter(tt.filter, []string{"title", "content"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("payloadFilter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_CreateIndex(t *testing.T) {
+	client, requests := fakeQdrant(t, map[string]string{
+		"GET /collections/docs/exists":        `{"result": {"exists": true}}`,
+		"GET /collections/docs_chunks/exists": `{"result": {"exists": false}}`,
+	})
+
+	if err := client.CreateIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var created map[string]interface{}
+	indexes := 0
+	for _, r := range *requests {
+		switch {
+		case r.method == http.MethodPut && r.path == "/collections/docs_chunks":
+			created = r.body
+		case r.path == "/collections/docs_chunks/index":
+			indexes++
+		case r.method == http.MethodPut && r.path == "/collections/docs":
+			t.Error("existing document collection was recreated")
+		}
+	}
+	if created == nil {
+		t.Fatal("chunk collection was not created")
+	}
+	dense := created["vectors"].(map[string]interface{})[denseVector].(map[string]interface{})
+	if dense["size"] != float64(2560) || dense["distance"] != "Cosine" {
+		t.Errorf("dense vector = %v, want 2560 cosine dimensions", dense)
+	}
+	if indexes != len(chunkIndexes) {
+		t.Errorf("created %d payload indexes, want %d", indexes, len(chunkIndexes))
+	}
+}
+
+func TestClient_Search(t *testing.T) {
+	goDoc := models.Document{ID: "a", URL: "https://go.dev/doc", Title: "Go"}
+	otherDoc := models.Document{ID: "b", URL: "https://example.com/go", Title: "Go elsewhere"}
+	client, requests := fakeQdrant(t, map[string]string{
+		"POST /collections/docs/points/query": `{"result": {"points": [` + pointJSON(t, "b", otherDoc) + `,` + pointJSON(t, "a", goDoc) + `]}}`,
+	})
+
+	filter := elasticsearch.Filter{URLPrefixes: []string{"https://go.dev/"}}
+	docs, err := client.HybridSearchFiltered(context.Background(), "go", []float32{0.1, 0.2}, 2, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].ID != "a" {
+		t.Errorf("HybridSearchFiltered() = %+v, want only the go.dev page", docs)
+	}
+
+	body := (*requests)[0].body
+	if body["limit"] != float64(2*prefixWindow) {
+		t.Errorf("limit = %v, want widened to %d for URL prefixes", body["limit"], 2*prefixWindow)
+	}
+	if fusion := body["query"].(map[string]interface{}); fusion["fusion"] != "rrf" {
+		t.Errorf("query = %v, want rrf fusion", fusion)
+	}
+	if prefetch := body["prefetch"].([]interface{}); len(prefetch) != 2 {
+		t.Errorf("prefetch = %v, want text and vector searches", prefetch)
+	}
+}
+
+func TestClient_GetDocuments(t *testing.T) {
+	client, _ := fakeQdrant(t, map[string]string{
+		"POST /collections/docs/points": `{"result": [` +
+			pointJSON(t, "b", models.Document{ID: "b", ContentHash: "hb"}) + `,` +
+			`{"id": "` + pointID("a") + `", "payload": {"id": "a"}, "vector": {"dense": [0.5, 0.5]}}]}`,
+	})
+	ctx := context.Background()
+
+	docs, err := client.GetDocuments(ctx, []string{"a", "missing", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].ID != "a" || docs[1].ID != "b" {
+		t.Fatalf("GetDocuments() = %+v, want a then b", docs)
+	}
+	if len(docs[0].Embedding) != 2 {
+		t.Errorf("embedding = %v, want the dense vector", docs[0].Embedding)
+	}
+
+	hashes, err := client.ContentHashes(ctx, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 || hashes["b"] != "hb" {
+		t.Errorf("ContentHashes() = %v, want only b", hashes)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"status": {"error": "Wrong input: Vector dimension error"}}`)
+	}))
+	defer srv.Close()
+
+	client, err := New(Config{URL: srv.URL, Collection: "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.IndexDocument(context.Background(), models.Document{ID: "a", Embedding: []float32{1}})
+	if err == nil || !regexp.MustCompile(`qdrant error 400: Wrong input`).MatchString(err.Error()) {
+		t.Errorf("IndexDocument() error = %v, want Qdrant's message", err)
+	}
+	if client.Ping(context.Background()) {
+		t.Error("Ping() = true for a failing server")
+	}
+}
@@ -0,0 +1,311 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// termSaturation is BM25's k1: how quickly repeats of a term stop adding
+// to its weight in an entry. Qdrant supplies the IDF.
+const termSaturation = 1.2
+
+// prefixWindow is how many more results are fetched when a filter has
+// URL prefixes, which Qdrant cannot match, so enough remain once they are
+// applied.
+const prefixWindow = 5
+
+// weighted is a field's text and the boost of its terms.
+type weighted struct {
+	text  string
+	boost float64
+}
+
+// documentFields returns the fields a full-scope search matches, boosted
+// as in the Elasticsearch queries.
+func documentFields(doc models.Document) []weighted {
+	fields := []weighted{
+		{doc.Title, 3},
+		{doc.Content, 1},
+		{strings.Join(doc.Tags, " "), 2},
+		{doc.Summary, 1},
+	}
+	var walk func([]models.Heading)
+	walk = func(headings []models.Heading) {
+		for _, h := range headings {
+			fields = append(fields, weighted{h.Text, 2})
+			walk(h.Children)
+		}
+	}
+	walk(doc.Outline)
+	return fields
+}
+
+// summaryFields returns the fields a summary-scope search matches.
+func summaryFields(doc models.Document) []weighted {
+	return []weighted{{doc.Title, 1}, {strings.Join(doc.Tags, " "), 2}, {doc.Summary, 1}}
+}
+
+// chunkFields returns the fields a chunk search matches.
+func chunkFields(chunk models.Chunk) []weighted {
+	return []weighted{
+		{chunk.Content, 1},
+		{chunk.Context, 1},
+		{chunk.Title, 1},
+		{strings.Join(chunk.HeadingPath, " "), 2},
+	}
+}
+
+// sparseVector is a Qdrant sparse vector: term hashes and their weights.
+type sparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// termVector returns the sparse vector of fields: each term weighs its
+// boosted frequency, saturated as in BM25.
+func termVector(fields []weighted) sparseVector {
+	tf := make(map[uint32]float64)
+	for _, f := range fields {
+		for _, term := range tokenize(f.text) {
+			tf[termIndex(term)] += f.boost
+		}
+	}
+	v := sparseVector{Indices: make([]uint32, 0, len(tf)), Values: make([]float32, 0, len(tf))}
+	for _, index := range sortedIndices(tf) {
+		freq := tf[index]
+		v.Indices = append(v.Indices, index)
+		v.Values = append(v.Values, float32(freq*(termSaturation+1)/(freq+termSaturation)))
+	}
+	return v
+}
+
+// queryVector returns the sparse vector of a query: each distinct term
+// weighs 1.
+func queryVector(query string) sparseVector {
+	terms := make(map[uint32]float64)
+	for _, term := range tokenize(query) {
+		terms[termIndex(term)] = 1
+	}
+	v := sparseVector{Indices: sortedIndices(terms), Values: make([]float32, len(terms))}
+	for i := range v.Values {
+		v.Values[i] = 1
+	}
+	return v
+}
+
+// sortedIndices returns the keys of m in order, as Qdrant expects them.
+func sortedIndices(m map[uint32]float64) []uint32 {
+	indices := make([]uint32, 0, len(m))
+	for index := range m {
+		indices = append(indices, index)
+	}
+	slices.Sort(indices)
+	return indices
+}
+
+// termIndex returns the sparse vector dimension of a term.
+func termIndex(term string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(term))
+	return h.Sum32()
+}
+
+// tokenize returns the lowercase words of s.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchValue returns a condition matching a keyword field's value.
+func matchValue(key, value string) map[string]interface{} {
+	return map[string]interface{}{"key": key, "match": map[string]interface{}{"value": value}}
+}
+
+// matchAny returns a condition matching any of a keyword field's values.
+func matchAny(key string, values []string) map[string]interface{} {
+	return map[string]interface{}{"key": key, "match": map[string]interface{}{"any": values}}
+}
+
+// matchText returns a condition matching a full-text field containing all
+// the words of text.
+func matchText(key, text string) map[string]interface{} {
+	return map[string]interface{}{"key": key, "match": map[string]interface{}{"text": text}}
+}
+
+// payloadFilter returns the filter as a Qdrant filter over the given text
+// fields, searched for excluded terms. URL prefixes are left out, as
+// Qdrant cannot match them; results are checked against them afterwards.
+// Excluded phrases drop entries containing all their words, anywhere.
+func payloadFilter(f elasticsearch.Filter, textFields []string) map[string]interface{} {
+	var must, mustNot []interface{}
+	if len(f.Sources) > 0 && len(f.URLPrefixes) == 0 {
+		must = append(must, matchAny("source.name", f.Sources))
+	}
+	if len(f.Languages) > 0 {
+		must = append(must, matchAny("language", f.Languages))
+	}
+	if len(f.Origins) > 0 {
+		must = append(must, map[string]interface{}{
+			"should": []interface{}{matchAny("source.name", f.Origins), matchAny("source.host", f.Origins)},
+		})
+	}
+	if !f.After.IsZero() || !f.Before.IsZero() {
+		dates := make(map[string]interface{})
+		if !f.After.IsZero() {
+			dates["gte"] = f.After.UTC().Format(time.RFC3339)
+		}
+		if !f.Before.IsZero() {
+			dates["lt"] = f.Before.UTC().Format(time.RFC3339)
+		}
+		must = append(must, map[string]interface{}{"key": "scraped_at", "range": dates})
+	}
+	if f.EnforceAccess {
+		public := map[string]interface{}{"is_empty": map[string]interface{}{"key": "access_labels"}}
+		should := []interface{}{public}
+		if len(f.AccessLabels) > 0 {
+			should = append(should, matchAny("access_labels", f.AccessLabels))
+		}
+		must = append(must, map[string]interface{}{"should": should})
+	}
+	for _, title := range f.Titles {
+		must = append(must, matchText("title", title))
+	}
+	for _, term := range f.Exclude {
+		for _, field := range textFields {
+			mustNot = append(mustNot, matchText(field, term))
+		}
+	}
+
+	filter := make(map[string]interface{})
+	if len(must) > 0 {
+		filter["must"] = must
+	}
+	if len(mustNot) > 0 {
+		filter["must_not"] = mustNot
+	}
+	return filter
+}
+
+// Text fields searched for excluded terms, in documents and chunks.
+var (
+	documentTextFields = []string{"title", "content", "tags", "summary"}
+	chunkTextFields    = []string{"title", "content", "heading_path"}
+)
+
+// SearchFiltered returns the documents best matching query.
+func (c *Client) SearchFiltered(ctx context.Context, query string, limit int, filter elasticsearch.Filter) ([]models.Document, error) {
+	return c.HybridSearchFiltered(ctx, query, nil, limit, filter)
+}
+
+// HybridSearchFiltered returns the documents best matching query, fused
+// with their ranking by embedding similarity if queryEmbedding is set.
+func (c *Client) HybridSearchFiltered(ctx context.Context, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter) ([]models.Document, error) {
+	using := textVector
+	if filter.Scope == elasticsearch.ScopeSummary {
+		using = summaryVector
+	}
+	points, err := c.query(ctx, c.config.Collection, using, query, queryEmbedding, window(limit, filter), payloadFilter(filter, documentTextFields))
+	if err != nil {
+		return nil, err
+	}
+	var docs []models.Document
+	if err := decodePoints(points, &docs); err != nil {
+		return nil, err
+	}
+	docs = slices.DeleteFunc(docs, func(doc models.Document) bool {
+		return !filter.Match(doc.URL, doc.Source, doc.Language, doc.ScrapedAt, doc.AccessLabels)
+	})
+	return docs[:min(limit, len(docs))], nil
+}
+
+// SearchChunks returns the chunks best matching query, fused with their
+// ranking by embedding similarity if queryEmbedding is set.
+func (c *Client) SearchChunks(ctx context.Context, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter) ([]models.Chunk, error) {
+	return c.searchChunks(ctx, query, queryEmbedding, limit, filter, payloadFilter(filter, chunkTextFields))
+}
+
+// SearchSections returns the chunks of one document best matching query.
+func (c *Client) SearchSections(ctx context.Context, documentID, query string, limit int, filter elasticsearch.Filter) ([]models.Chunk, error) {
+	qf := payloadFilter(filter, chunkTextFields)
+	must, _ := qf["must"].([]interface{})
+	qf["must"] = append(must, matchValue("document_id", documentID))
+	return c.searchChunks(ctx, query, nil, limit, filter, qf)
+}
+
+// searchChunks runs a chunk search with a prepared payload filter.
+func (c *Client) searchChunks(ctx context.Context, query string, queryEmbedding []float32, limit int, filter elasticsearch.Filter, qf map[string]interface{}) ([]models.Chunk, error) {
+	points, err := c.query(ctx, c.chunkCollection, textVector, query, queryEmbedding, window(limit, filter), qf)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []models.Chunk
+	if err := decodePoints(points, &chunks); err != nil {
+		return nil, err
+	}
+	chunks = slices.DeleteFunc(chunks, func(chunk models.Chunk) bool {
+		return !filter.Match(chunk.URL, chunk.Source, chunk.Language, chunk.ScrapedAt, chunk.AccessLabels)
+	})
+	return chunks[:min(limit, len(chunks))], nil
+}
+
+// window returns how many results to fetch for a search returning limit.
+func window(limit int, filter elasticsearch.Filter) int {
+	if len(filter.URLPrefixes) > 0 {
+		return limit * prefixWindow
+	}
+	return limit
+}
+
+// query runs a search of a collection: by the sparse vector using for the
+// query text, by the dense vector for queryEmbedding, or both fused with
+// reciprocal rank fusion. Without either, it lists matching points.
+func (c *Client) query(ctx context.Context, collection, using, text string, queryEmbedding []float32, limit int, qf map[string]interface{}) ([]scoredPoint, error) {
+	var searches []map[string]interface{}
+	if terms := queryVector(text); len(terms.Indices) > 0 {
+		searches = append(searches, map[string]interface{}{"query": terms, "using": using})
+	}
+	if len(queryEmbedding) > 0 {
+		searches = append(searches, map[string]interface{}{"query": queryEmbedding, "using": denseVector})
+	}
+
+	body := map[string]interface{}{"limit": limit, "with_payload": true}
+	switch len(searches) {
+	case 0:
+		body["filter"] = qf
+	case 1:
+		body["query"], body["using"], body["filter"] = searches[0]["query"], searches[0]["using"], qf
+	default:
+		for _, s := range searches {
+			s["filter"], s["limit"] = qf, limit
+		}
+		body["prefetch"] = searches
+		body["query"] = map[string]interface{}{"fusion": "rrf"}
+	}
+
+	var res struct {
+		Result struct {
+			Points []scoredPoint `json:"points"`
+		} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(collection)+"/points/query", body, &res); err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	return res.Result.Points, nil
+}
+
+// Suggest returns "": Qdrant has no term suggester, so searches that find
+// nothing offer no correction.
+func (c *Client) Suggest(ctx context.Context, query string, filter elasticsearch.Filter) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,138 @@
+// Package query parses the search syntax shared by the search command and
+// the MCP tools.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+)
+
+// Query is a parsed search query.
+type Query struct {
+	Text    string    // What results are matched and ranked against
+	Exclude []string  // Terms and phrases results must not contain
+	Titles  []string  // Terms and phrases results must have in their title
+	Sources []string  // Source names or hosts results may come from; any of them
+	After   time.Time // Start of the first day results may be scraped on
+	Before  time.Time // Start of the day results must be scraped before
+}
+
+// Parse parses a search query.
+//
+// A term or "quoted phrase" prefixed with "-" or preceded by NOT is
+// excluded from the results rather than searched for, so "ingress -nginx"
+// finds pages about ingress that don't mention nginx. Hyphenated words and
+// negative numbers are searched as written.
+//
+// Fields restrict the results instead of being searched for:
+//
+//	title:install            the title contains install
+//	title:"getting started"  the title contains both words
+//	source:go.dev            from the source named go.dev or scraped from that host
+//	after:2025-01-01         scraped on or after that day (UTC)
+//	before:2025-06-01        scraped before that day
+//
+// Other words containing a colon, such as URLs, are searched as written.
+func Parse(s string) (Query, error) {
+	var q Query
+	var text []string
+	tokens := tokenize(s)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		field, value, _ := strings.Cut(tok, ":")
+		var err error
+		switch {
+		case tok == "NOT" && i+1 < len(tokens):
+			i++
+			q.Exclude = appendTerm(q.Exclude, tokens[i])
+		case excluded(tok):
+			q.Exclude = appendTerm(q.Exclude, tok[1:])
+		case field == "title":
+			q.Titles = appendTerm(q.Titles, value)
+		case field == "source":
+			q.Sources = appendTerm(q.Sources, value)
+		case field == "after":
+			q.After, err = parseDay(field, value)
+		case field == "before":
+			q.Before, err = parseDay(field, value)
+		default:
+			text = append(text, tok)
+		}
+		if err != nil {
+			return Query{}, err
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// Apply returns filter restricted to the results the query allows.
+func (q Query) Apply(filter elasticsearch.Filter) elasticsearch.Filter {
+	filter.Exclude = append(filter.Exclude, q.Exclude...)
+	filter.Titles = append(filter.Titles, q.Titles...)
+	filter.Origins = append(filter.Origins, q.Sources...)
+	if q.After.After(filter.After) {
+		filter.After = q.After
+	}
+	if !q.Before.IsZero() && (filter.Before.IsZero() || q.Before.Before(filter.Before)) {
+		filter.Before = q.Before
+	}
+	return filter
+}
+
+// parseDay parses the YYYY-MM-DD value of a date field.
+func parseDay(field, value string) (time.Time, error) {
+	day, err := time.Parse(time.DateOnly, strings.Trim(value, `"`))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: expected a date like 2025-01-31, got %q", field, value)
+	}
+	return day, nil
+}
+
+// excluded reports whether tok is a "-" followed by a term or phrase other
+// than a number.
+func excluded(tok string) bool {
+	if len(tok) < 2 || tok[0] != '-' {
+		return false
+	}
+	next := rune(tok[1])
+	return next != '-' && !unicode.IsDigit(next) && next != '.'
+}
+
+// appendTerm appends tok to terms without its quotes, unless it is empty.
+func appendTerm(terms []string, tok string) []string {
+	if t := strings.Trim(tok, `"`); strings.TrimSpace(t) != "" {
+		return append(terms, t)
+	}
+	return terms
+}
+
+// tokenize splits s at whitespace outside double quotes. Quotes are kept,
+// and an unterminated quote runs to the end of s.
+func tokenize(s string) []string {
+	var tokens []string
+	var tok strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			tok.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if tok.Len() > 0 {
+				tokens = append(tokens, tok.String())
+				tok.Reset()
+			}
+		default:
+			tok.WriteRune(r)
+		}
+	}
+	if tok.Len() > 0 {
+		tokens = append(tokens, tok.String())
+	}
+	return tokens
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Query
+	}{
+		{"ingress controller", Query{Text: "ingress controller"}},
+		{"ingress -nginx", Query{Text: "ingress", Exclude: []string{"nginx"}}},
+		{"ingress NOT nginx -traefik", Query{Text: "ingress", Exclude: []string{"nginx", "traefik"}}},
+		{`deploy -"helm chart" now`, Query{Text: "deploy now", Exclude: []string{"helm chart"}}},
+		{`NOT "service mesh"`, Query{Exclude: []string{"service mesh"}}},
+		{"e-mail setup -1 - --flag", Query{Text: "e-mail setup -1 - --flag"}},
+		{`"quoted -phrase" kept`, Query{Text: `"quoted -phrase" kept`}},
+		{"trailing NOT", Query{Text: "trailing NOT"}},
+		{"not lowercase", Query{Text: "not lowercase"}},
+		{`title:"getting started" source:go.dev modules`, Query{Text: "modules", Titles: []string{"getting started"}, Sources: []string{"go.dev"}}},
+		{"after:2025-01-01 before:2025-02-01", Query{After: day(2025, 1, 1), Before: day(2025, 2, 1)}},
+		{"https://go.dev/doc tag:go", Query{Text: "https://go.dev/doc tag:go"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := Parse("after:yesterday"); err == nil {
+		t.Error("Parse(after:yesterday) should fail")
+	}
+}
+
+func TestQuery_Apply(t *testing.T) {
+	base := elasticsearch.Filter{Sources: []string{"kubernetes"}, After: day(2025, 3, 1)}
+	q, _ := Parse("title:pods source:k8s.io after:2025-01-01 before:2025-06-01 -draft")
+	got := q.Apply(base)
+
+	if !reflect.DeepEqual(got.Sources, base.Sources) || !reflect.DeepEqual(got.Origins, []string{"k8s.io"}) {
+		t.Errorf("Apply() sources = %v, origins = %v; want both kept apart", got.Sources, got.Origins)
+	}
+	if !got.After.Equal(day(2025, 3, 1)) || !got.Before.Equal(day(2025, 6, 1)) {
+		t.Errorf("Apply() range = %v to %v, want the narrower bounds", got.After, got.Before)
+	}
+	if !reflect.DeepEqual(got.Titles, []string{"pods"}) || !reflect.DeepEqual(got.Exclude, []string{"draft"}) {
+		t.Errorf("Apply() titles = %v, exclude = %v", got.Titles, got.Exclude)
+	}
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
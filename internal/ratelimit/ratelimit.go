@@ -0,0 +1,126 @@
+// Package ratelimit keeps clients of the HTTP servers from overwhelming
+// the search backend and the local models behind them: each client gets a
+// token bucket of requests, and the requests handled at once are capped.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config holds the limits. Zero values disable their limit.
+type Config struct {
+	Rate          float64 // Requests per second each client may make on average
+	Burst         int     // Requests a client may make at once; defaults to Rate rounded up
+	MaxConcurrent int     // Requests handled at once across all clients
+}
+
+// maxIdleBuckets is how many client buckets are kept before those that
+// have refilled, and so carry no state, are dropped.
+const maxIdleBuckets = 1024
+
+// Limiter enforces a Config. It is safe for concurrent use.
+type Limiter struct {
+	config  Config
+	mu      sync.Mutex
+	clients map[string]*bucket
+	slots   chan struct{} // Holds a token per request being handled; nil without a cap
+	now     func() time.Time
+}
+
+// bucket holds the requests a client has left.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter.
+func New(config Config) *Limiter {
+	if config.Rate > 0 && config.Burst <= 0 {
+		config.Burst = int(math.Ceil(config.Rate))
+	}
+	l := &Limiter{config: config, clients: make(map[string]*bucket), now: time.Now}
+	if config.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	return l
+}
+
+// Allow takes a request from client's bucket. If the bucket is empty it
+// returns false and how long until a request is available.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	if l.config.Rate <= 0 {
+		return true, 0
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.clients[client]
+	if !ok {
+		if len(l.clients) >= maxIdleBuckets {
+			l.dropIdle(now)
+		}
+		b = &bucket{tokens: float64(l.config.Burst), last: now}
+		l.clients[client] = b
+	}
+	b.tokens = min(float64(l.config.Burst), b.tokens+now.Sub(b.last).Seconds()*l.config.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.config.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// dropIdle forgets the clients whose buckets have refilled.
+func (l *Limiter) dropIdle(now time.Time) {
+	for client, b := range l.clients {
+		if b.tokens+now.Sub(b.last).Seconds()*l.config.Rate >= float64(l.config.Burst) {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// acquire takes a slot for a request, reporting false if all are taken.
+func (l *Limiter) acquire() bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees the slot of a finished request.
+func (l *Limiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// Middleware answers 429 to clients over their rate and 503 while
+// MaxConcurrent requests are being handled, both with a Retry-After
+// header. client names the client a request comes from.
+func (l *Limiter) Middleware(client func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.Allow(client(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		if !l.acquire() {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+		next.ServeHTTP(w, r)
+	})
+}
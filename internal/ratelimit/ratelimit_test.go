@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	l := New(Config{Rate: 2, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within the burst was refused", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Allow() past the burst = %v, %v; want false, 500ms", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("another client was refused; buckets should be per client")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("Allow() after refilling one request was refused")
+	}
+
+	if ok, _ := New(Config{}).Allow("a"); !ok {
+		t.Error("Allow() without a rate was refused")
+	}
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	l := New(Config{Rate: 1, MaxConcurrent: 1})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := l.Middleware(func(r *http.Request) string { return r.Header.Get("X-Client") }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+	}))
+	serve := func(path, client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("X-Client", client)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	done := make(chan struct{})
+	go func() {
+		serve("/slow", "a")
+		close(done)
+	}()
+	<-started
+
+	if rec := serve("/", "b"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status while at the concurrency cap = %d, want 503", rec.Code)
+	}
+	close(release)
+	<-done
+
+	rec := serve("/", "a")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("status over the rate = %d (Retry-After %q), want 429 after 1", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,243 @@
+// Package rerank reorders search results with a cross-encoder model served
+// by Docker Model Runner.
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Config holds reranking client configuration.
+type Config struct {
+	SocketPath string // Unix socket path for Docker Model Runner
+	Model      string // Cross-encoder model name (e.g., "ai/qwen3-reranker")
+
+	// Candidates is how many results are fetched from the index and
+	// scored before the requested number is kept; 0 uses the default
+	Candidates int
+
+	Timeout time.Duration // Limit on a single request; 0 uses the default
+}
+
+// Defaults for Config fields left zero.
+const (
+	defaultCandidates = 30
+	defaultTimeout    = 30 * time.Second
+)
+
+// maxInputChars limits the text of each result sent to the model. The
+// beginning of a page is the part most indicative of what it covers.
+const maxInputChars = 2000
+
+// Client scores results against a query with a cross-encoder. A nil
+// *Client leaves results in their original order.
+type Client struct {
+	httpClient *http.Client
+	model      string
+	candidates int
+}
+
+// New creates a new reranking client.
+func New(config Config) (*Client, error) {
+	if config.SocketPath == "" {
+		return nil, fmt.Errorf("socket path is required")
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	if config.Candidates == 0 {
+		config.Candidates = defaultCandidates
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	var dialer net.Dialer
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", config.SocketPath)
+		},
+	}
+
+	return &Client{
+		httpClient: &http.Client{Transport: transport, Timeout: config.Timeout},
+		model:      config.Model,
+		candidates: config.Candidates,
+	}, nil
+}
+
+// Candidates returns how many results to fetch for a search returning
+// limit results, so the reranker has more than those to choose from.
+func (c *Client) Candidates(limit int) int {
+	if c == nil {
+		return limit
+	}
+	return max(limit, c.candidates)
+}
+
+// rerankRequest is the request payload of the llama.cpp rerank API.
+type rerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+// rerankResponse is the response of the llama.cpp rerank API.
+type rerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Scores returns the relevance of each text to query, in the order of
+// texts.
+func (c *Client) Scores(ctx context.Context, query string, texts []string) (scores []float64, err error) {
+	ctx, span := telemetry.Start(ctx, "rerank.scores", attribute.String("model", c.model), attribute.Int("candidates", len(texts)))
+	defer func() { telemetry.End(span, err) }()
+
+	documents := make([]string, len(texts))
+	for i, text := range texts {
+		if len(text) > maxInputChars {
+			text = text[:maxInputChars]
+		}
+		documents[i] = text
+	}
+
+	body, err := json.Marshal(rerankRequest{Model: c.model, Query: query, Documents: documents})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST",
+		"http://localhost/exp/vDD4.40/engines/llama.cpp/v1/rerank",
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	telemetry.InjectHeader(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var rerankResp rerankResponse
+	if err := json.Unmarshal(respBody, &rerankResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if rerankResp.Error != nil {
+		return nil, fmt.Errorf("API error: %s", rerankResp.Error.Message)
+	}
+
+	scores = make([]float64, len(texts))
+	seen := make([]bool, len(texts))
+	for _, r := range rerankResp.Results {
+		if r.Index < 0 || r.Index >= len(texts) {
+			return nil, fmt.Errorf("result index %d out of range", r.Index)
+		}
+		scores[r.Index] = r.RelevanceScore
+		seen[r.Index] = true
+	}
+	for i, ok := range seen {
+		if !ok {
+			return nil, fmt.Errorf("no score returned for document %d", i)
+		}
+	}
+	return scores, nil
+}
+
+// Documents orders docs by their relevance to query and keeps the first
+// limit. If scoring fails, docs keep the order of the search.
+func (c *Client) Documents(ctx context.Context, query string, docs []models.Document, limit int) []models.Document {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = documentText(doc.Title, doc.Summary, doc.Content)
+	}
+	order := c.order(ctx, query, texts)
+
+	ranked := make([]models.Document, 0, min(limit, len(docs)))
+	for _, i := range order[:min(limit, len(order))] {
+		ranked = append(ranked, docs[i])
+	}
+	return ranked
+}
+
+// Chunks orders chunks by their relevance to query and keeps the first
+// limit. If scoring fails, chunks keep the order of the search.
+func (c *Client) Chunks(ctx context.Context, query string, chunks []models.Chunk, limit int) []models.Chunk {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = documentText(strings.Join(chunk.HeadingPath, " > "), "", chunk.Content)
+	}
+	order := c.order(ctx, query, texts)
+
+	ranked := make([]models.Chunk, 0, min(limit, len(chunks)))
+	for _, i := range order[:min(limit, len(order))] {
+		ranked = append(ranked, chunks[i])
+	}
+	return ranked
+}
+
+// order returns the indexes of texts from most to least relevant to
+// query, or in their original order if c is nil or scoring fails.
+func (c *Client) order(ctx context.Context, query string, texts []string) []int {
+	order := make([]int, len(texts))
+	for i := range order {
+		order[i] = i
+	}
+	if c == nil || len(texts) < 2 {
+		return order
+	}
+
+	scores, err := c.Scores(ctx, query, texts)
+	if err != nil {
+		slog.Warn("reranking failed, keeping search order", "error", err)
+		return order
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	return order
+}
+
+// documentText is the text of a result the model scores.
+func documentText(title, summary, content string) string {
+	var b strings.Builder
+	for _, part := range []string{title, summary, content} {
+		if part == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// serve starts a rerank API on a Unix socket scoring each document by
+// handler, and returns a client for it.
+func serve(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create Unix socket: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	client, err := New(Config{SocketPath: socketPath, Model: "test-reranker"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return client
+}
+
+func TestClient_Documents(t *testing.T) {
+	client := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		var req rerankRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Query != "install go" || req.Model != "test-reranker" {
+			t.Errorf("request = %+v", req)
+		}
+
+		// Documents mentioning "install" are the relevant ones
+		var resp rerankResponse
+		for i, doc := range req.Documents {
+			score := 0.1
+			if strings.Contains(doc, "install") {
+				score = 0.9
+			}
+			resp.Results = append(resp.Results, struct {
+				Index          int     `json:"index"`
+				RelevanceScore float64 `json:"relevance_score"`
+			}{i, score})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	docs := []models.Document{
+		{ID: "a", Title: "Tour", Content: "A tour of Go"},
+		{ID: "b", Title: "Download", Content: "How to install Go"},
+		{ID: "c", Title: "Spec", Content: "The language spec"},
+	}
+	ranked := client.Documents(context.Background(), "install go", docs, 2)
+	if len(ranked) != 2 || ranked[0].ID != "b" || ranked[1].ID != "a" {
+		t.Errorf("Documents() = %v, want b then a", ids(ranked))
+	}
+}
+
+func TestClient_FailureKeepsOrder(t *testing.T) {
+	client := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	})
+
+	docs := []models.Document{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	ranked := client.Documents(context.Background(), "q", docs, 2)
+	if got := ids(ranked); got != "a,b" {
+		t.Errorf("Documents() = %s, want a,b", got)
+	}
+}
+
+func TestClient_Nil(t *testing.T) {
+	var client *Client
+	if got := client.Candidates(10); got != 10 {
+		t.Errorf("Candidates() = %d, want 10", got)
+	}
+	chunks := client.Chunks(context.Background(), "q", []models.Chunk{{ID: "a"}, {ID: "b"}}, 5)
+	if len(chunks) != 2 || chunks[0].ID != "a" {
+		t.Errorf("Chunks() = %+v, want the input", chunks)
+	}
+}
+
+func ids(docs []models.Document) string {
+	var out []string
+	for _, d := range docs {
+		out = append(out, d.ID)
+	}
+	return strings.Join(out, ",")
+}
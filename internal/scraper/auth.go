@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Auth holds credentials sent with every request to a domain.
+type Auth struct {
+	Domain   string            // Host name; subdomains match too
+	Headers  map[string]string // Extra request headers, e.g. an API key
+	Username string            // Basic auth user
+	Password string            // Basic auth password
+	Token    string            // Bearer token
+	Cookies  http.CookieJar    // Cookies sent with requests, e.g. an SSO session; may be nil
+}
+
+// matches reports whether host is the auth's domain or one of its subdomains.
+func (a Auth) matches(host string) bool {
+	domain := strings.ToLower(strings.TrimSuffix(a.Domain, "."))
+	host = strings.ToLower(host)
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
+
+// apply sets the auth's credentials for a request to u on h.
+func (a Auth) apply(h http.Header, u *url.URL) {
+	for name, value := range a.Headers {
+		h.Set(name, value)
+	}
+	if a.Cookies != nil {
+		var pairs []string
+		for _, c := range a.Cookies.Cookies(u) {
+			pairs = append(pairs, c.String())
+		}
+		if len(pairs) > 0 {
+			h.Set("Cookie", strings.Join(pairs, "; "))
+		}
+	}
+	switch {
+	case a.Token != "":
+		h.Set("Authorization", "Bearer "+a.Token)
+	case a.Username != "":
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password)))
+	}
+}
+
+// remove deletes the headers apply sets.
+func (a Auth) remove(h http.Header) {
+	for name := range a.Headers {
+		h.Del(name)
+	}
+	if a.Token != "" || a.Username != "" {
+		h.Del("Authorization")
+	}
+	if a.Cookies != nil {
+		h.Del("Cookie")
+	}
+}
+
+// authFor returns the most specific auth entry matching host.
+func authFor(auths []Auth, host string) (Auth, bool) {
+	var best Auth
+	found := false
+	for _, a := range auths {
+		if a.matches(host) && (!found || len(a.Domain) > len(best.Domain)) {
+			best, found = a, true
+		}
+	}
+	return best, found
+}
+
+// applyHeaders sets the configured headers and the credentials for a
+// request to u on h. Credentials win over headers of the same name.
+func (s *Scraper) applyHeaders(h http.Header, u *url.URL) {
+	for name, value := range s.config.Headers {
+		h.Set(name, value)
+	}
+	if a, ok := authFor(s.config.Auth, u.Hostname()); ok {
+		a.apply(h, u)
+	}
+}
+
+// checkRedirect swaps credentials when a redirect changes host, so headers
+// configured for one domain are never sent to another.
+func (s *Scraper) checkRedirect(req *http.Request, via []*http.Request) error {
+	// Honour the standard library's redirect limit
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if a, ok := authFor(s.config.Auth, via[len(via)-1].URL.Hostname()); ok {
+		a.remove(req.Header)
+	}
+	s.applyHeaders(req.Header, req.URL)
+	return nil
+}
@@ -0,0 +1,183 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// cacheTransport keeps responses on disk, keyed by URL and the request
+// headers named by the response's Vary header, so repeated scrapes of a
+// site don't download unchanged pages again and sources sending different
+// headers, such as Accept-Language, don't share entries. A cached response
+// is revalidated with its ETag and Last-Modified validators and served
+// from disk when the server answers 304 Not Modified. Responses without
+// validators, or varying on every header, are not cached. Requests that
+// carry validators of their own are sent as they are, so their caller sees
+// the server's 304.
+type cacheTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	var cached *http.Response
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	if !conditional {
+		cached = t.load(t.path(req, t.vary(req.URL.String())), req)
+	}
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		slog.Debug("serving cached response", "url", req.URL.String())
+		return cached, nil
+	}
+
+	if resp.StatusCode != http.StatusOK || (resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+	vary := varyHeaders(resp.Header)
+	if slices.Contains(vary, "*") {
+		return resp, nil
+	}
+	t.setVary(req.URL.String(), vary)
+	return t.store(t.path(req, vary), resp)
+}
+
+// varyHeaders returns the canonical, sorted names of the request headers
+// a response varies on.
+func varyHeaders(h http.Header) []string {
+	var names []string
+	for _, value := range h.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// path returns the cache file of req's response, given the request headers
+// it varies on.
+func (t *cacheTransport) path(req *http.Request, vary []string) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.String())
+	for _, name := range vary {
+		fmt.Fprintf(h, "\n%s: %s", name, strings.Join(req.Header.Values(name), ", "))
+	}
+	return filepath.Join(t.dir, hex.EncodeToString(h.Sum(nil))+".http")
+}
+
+// varyPath returns the file listing the headers the responses of a URL
+// vary on.
+func (t *cacheTransport) varyPath(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".vary")
+}
+
+// vary returns the headers the last cached response of a URL varied on.
+func (t *cacheTransport) vary(rawURL string) []string {
+	data, err := os.ReadFile(t.varyPath(rawURL))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// setVary records the headers the responses of a URL vary on, if they
+// changed. A failed write only loses the cache entry.
+func (t *cacheTransport) setVary(rawURL string, vary []string) {
+	if slices.Equal(t.vary(rawURL), vary) {
+		return
+	}
+	path := t.varyPath(rawURL)
+	if len(vary) == 0 {
+		os.Remove(path)
+		return
+	}
+	if err := t.write(path, []byte(strings.Join(vary, "\n"))); err != nil {
+		slog.Debug("failed to cache response", "url", rawURL, "error", err)
+	}
+}
+
+// load returns the cached response for req, or nil if there is none.
+func (t *cacheTransport) load(path string, req *http.Request) *http.Response {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		slog.Debug("ignoring unreadable cache entry", "path", path, "error", err)
+		return nil
+	}
+	return resp
+}
+
+// store writes resp to the cache and returns it with its body read into
+// memory. A failed write only loses the cache entry.
+func (t *cacheTransport) store(path string, resp *http.Response) (*http.Response, error) {
+	// Reads the body and replaces it with an in-memory copy
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	if err := t.write(path, dump); err != nil {
+		slog.Debug("failed to cache response", "url", resp.Request.URL.String(), "error", err)
+	}
+	return resp, nil
+}
+
+// write replaces the file at path with data, atomically.
+func (t *cacheTransport) write(path string, data []byte) error {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(t.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestScraper_CacheRevalidates(t *testing.T) {
+	var bodies, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		bodies.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Cached</title></head><body><p>Hello</p></body></html>`))
+	}))
+	defer server.Close()
+
+	s := New(Config{MaxDepth: 1, CacheDir: t.TempDir()})
+	for range 2 {
+		docs, err := s.Scrape(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Scrape() error = %v", err)
+		}
+		if len(docs) != 1 || docs[0].Content == "" {
+			t.Fatalf("Scrape() = %+v, want the page", docs)
+		}
+	}
+
+	if got := bodies.Load(); got != 1 {
+		t.Errorf("page downloaded %d times, want 1", got)
+	}
+	if got := notModified.Load(); got != 1 {
+		t.Errorf("page revalidated %d times, want 1", got)
+	}
+}
+
+func TestCacheTransport_SkipsResponsesWithoutValidators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("no validators"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: &cacheTransport{dir: dir, next: http.DefaultTransport}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("cache has %d entries, want none", len(entries))
+	}
+}
+
+func TestCacheTransport_VariesOnHeaders(t *testing.T) {
+	var bodies atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := r.Header.Get("Accept-Language")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Header().Set("ETag", `"`+lang+`"`)
+		if r.Header.Get("If-None-Match") == `"`+lang+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		bodies.Add(1)
+		w.Write([]byte(lang))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &cacheTransport{dir: t.TempDir(), next: http.DefaultTransport}}
+	get := func(lang string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Accept-Language", lang)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	for _, lang := range []string{"en", "de", "en", "de"} {
+		if got := get(lang); got != lang {
+			t.Errorf("response for %s = %q, want %q", lang, got, lang)
+		}
+	}
+	if got := bodies.Load(); got != 2 {
+		t.Errorf("page downloaded %d times, want once per language", got)
+	}
+}
+
+func TestCacheTransport_PassesCallerValidators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &cacheTransport{dir: t.TempDir(), next: http.DefaultTransport}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	// A caller revalidating its own copy sees the 304, not the cached page
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional request status = %d, want 304", resp.StatusCode)
+	}
+}
@@ -0,0 +1,127 @@
+package scraper
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mfenderov/bam-rag/internal/markdown"
+)
+
+// selectContent narrows an HTML page to the elements matching selector,
+// keeping the page title and language so ingestion can still extract them.
+// Returns false if the page cannot be parsed or nothing matches.
+func selectContent(page, selector string) (string, bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return "", false
+	}
+
+	selection := doc.Find(selector)
+	if selection.Length() == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString("<html")
+	if lang, ok := doc.Find("html").First().Attr("lang"); ok {
+		b.WriteString(` lang="` + html.EscapeString(lang) + `"`)
+	}
+	b.WriteString("><head><title>")
+	b.WriteString(html.EscapeString(strings.TrimSpace(doc.Find("title").First().Text())))
+	b.WriteString("</title></head><body>")
+	selection.Each(func(_ int, s *goquery.Selection) {
+		if h, err := goquery.OuterHtml(s); err == nil {
+			b.WriteString(h)
+		}
+	})
+	b.WriteString("</body></html>")
+
+	return b.String(), true
+}
+
+// siteInfo extracts the site name (og:site_name) and the absolute favicon
+// URL from an HTML page. Pages that declare no icon get the conventional
+// /favicon.ico of their host.
+func siteInfo(page string, pageURL *url.URL) (name, favicon string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return "", ""
+	}
+
+	name, _ = doc.Find(`meta[property="og:site_name"]`).First().Attr("content")
+	name = strings.TrimSpace(name)
+
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			if rel == "icon" {
+				if ref, err := url.Parse(strings.TrimSpace(s.AttrOr("href", ""))); err == nil {
+					favicon = pageURL.ResolveReference(ref).String()
+				}
+				return false
+			}
+		}
+		return true
+	})
+	if favicon == "" && (pageURL.Scheme == "http" || pageURL.Scheme == "https") {
+		favicon = pageURL.Scheme + "://" + pageURL.Host + "/favicon.ico"
+	}
+
+	return name, favicon
+}
+
+// valuedDirectives are the robots directives taking a value after a colon,
+// which would otherwise be mistaken for a crawler name.
+var valuedDirectives = map[string]bool{
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+	"unavailable_after": true,
+}
+
+// noindex reports whether a page asks not to be indexed, with a noindex or
+// none directive in an X-Robots-Tag header or, for HTML pages, a robots
+// meta tag. Header values addressed to a named crawler, such as
+// "googlebot: noindex", are ignored.
+func noindex(header http.Header, pageURL, contentType, page string) bool {
+	for _, value := range header.Values("X-Robots-Tag") {
+		if agent, _, ok := strings.Cut(value, ":"); ok {
+			agent = strings.ToLower(strings.TrimSpace(agent))
+			if !strings.ContainsAny(agent, ", ") && !valuedDirectives[agent] {
+				continue
+			}
+		}
+		if hasNoindex(value) {
+			return true
+		}
+	}
+
+	// Only parse HTML pages that could carry a robots meta tag
+	if markdown.Detect(pageURL, contentType, page) || !strings.Contains(strings.ToLower(page), "robots") {
+		return false
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return false
+	}
+	found := false
+	doc.Find("meta[name][content]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		found = strings.EqualFold(strings.TrimSpace(s.AttrOr("name", "")), "robots") && hasNoindex(s.AttrOr("content", ""))
+		return !found
+	})
+	return found
+}
+
+// hasNoindex reports whether a comma-separated list of robots directives
+// includes noindex or none.
+func hasNoindex(directives string) bool {
+	for _, d := range strings.Split(directives, ",") {
+		switch strings.ToLower(strings.TrimSpace(d)) {
+		case "noindex", "none":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package scraper
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpOnlyPrefix marks HttpOnly cookies in cookies.txt files; the lines are
+// cookies, not comments.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// LoadCookies reads a cookies.txt file in the Netscape format that browser
+// extensions export and curl writes. Each line holds a cookie's domain,
+// whether subdomains match, path, whether it is secure, expiry in Unix
+// seconds (0 for a session cookie), name, and value, separated by tabs.
+// Expired cookies are dropped.
+func LoadCookies(path string) (http.CookieJar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookies file: %w", err)
+	}
+	defer f.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		line, httpOnly := strings.CutPrefix(line, httpOnlyPrefix)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			fields = append(fields, "") // A cookie with an empty value
+		}
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: want 7 tab-separated fields, got %d", path, n, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid expiry %q", path, n, fields[4])
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		jar.SetCookies(&url.URL{Scheme: "https", Host: host, Path: fields[2]}, []*http.Cookie{cookie})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookies file: %w", err)
+	}
+	return jar, nil
+}
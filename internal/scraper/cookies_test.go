@@ -0,0 +1,99 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeCookies(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCookies(t *testing.T) {
+	path := writeCookies(t, "# Netscape HTTP Cookie File\n\n"+
+		".example.com\tTRUE\t/\tFALSE\t0\tsite\tall\n"+
+		"#HttpOnly_docs.example.com\tFALSE\t/\tTRUE\t0\tsso\ttoken\n"+
+		"docs.example.com\tFALSE\t/private\tFALSE\t0\tarea\tprivate\n"+
+		"docs.example.com\tFALSE\t/\tFALSE\t1\tstale\told\n")
+
+	jar, err := LoadCookies(path)
+	if err != nil {
+		t.Fatalf("LoadCookies() error = %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://docs.example.com/guide", []string{"site", "sso"}},
+		{"https://docs.example.com/private/page", []string{"area", "site", "sso"}},
+		{"http://docs.example.com/guide", []string{"site"}}, // sso is secure
+		{"https://api.example.com/", []string{"site"}},
+		{"https://example.org/", nil},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		var names []string
+		for _, c := range jar.Cookies(u) {
+			names = append(names, c.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("cookies for %s = %v, want %v", tt.url, names, tt.want)
+		}
+	}
+}
+
+func TestLoadCookies_Malformed(t *testing.T) {
+	for _, content := range []string{
+		"example.com\tTRUE\t/\n",
+		"example.com\tTRUE\t/\tFALSE\tsoon\tname\tvalue\n",
+	} {
+		if _, err := LoadCookies(writeCookies(t, content)); err == nil {
+			t.Errorf("LoadCookies(%q) error = nil, want an error", content)
+		}
+	}
+}
+
+func TestScraper_SendsAuthCookies(t *testing.T) {
+	received := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie("session")
+		if cookie == nil {
+			received <- ""
+		} else {
+			received <- cookie.Value
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Portal</body></html>`))
+	}))
+	defer server.Close()
+
+	jar, err := LoadCookies(writeCookies(t, "127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tabc\n"))
+	if err != nil {
+		t.Fatalf("LoadCookies() error = %v", err)
+	}
+	s := New(Config{
+		Delay:    10 * time.Millisecond,
+		MaxDepth: 1,
+		Auth:     []Auth{{Domain: "127.0.0.1", Cookies: jar}},
+	})
+	if _, err := s.Scrape(t.Context(), server.URL); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+
+	if got := <-received; got != "abc" {
+		t.Errorf("session cookie = %q, want %q", got, "abc")
+	}
+}
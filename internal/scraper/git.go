@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/internal/markdown"
+)
+
+// PullCheckout fast-forwards the git checkout containing dir to its
+// upstream branch and returns the root directory of the checkout.
+func PullCheckout(ctx context.Context, dir string) (string, error) {
+	if _, err := git(ctx, dir, "pull", "--ff-only", "--quiet"); err != nil {
+		return "", err
+	}
+	root, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(root), nil
+}
+
+// git runs a git command in dir and returns its output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s in %s failed: %w: %s", args[0], dir, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// CheckoutFiles returns the markdown files among files, given relative to
+// the checkout root, that lie under dir, as paths within dir. Files in
+// hidden directories are skipped, as ListMarkdownFiles skips them.
+func CheckoutFiles(root, dir string, files []string) []string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	// git reports the root with symlinks resolved
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		resolved = abs
+	}
+
+	var paths []string
+	for _, file := range files {
+		rel, err := filepath.Rel(resolved, filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil || !markdown.IsMarkdownURL(rel) || hiddenOrOutside(rel) {
+			continue
+		}
+		paths = append(paths, filepath.Join(abs, rel))
+	}
+	return paths
+}
+
+// hiddenOrOutside reports whether a relative path leaves its directory or
+// passes through a hidden one.
+func hiddenOrOutside(rel string) bool {
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == ".." || isHidden(part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package scraper
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckoutFiles(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "docs")
+
+	got := CheckoutFiles(root, dir, []string{
+		"docs/index.md",
+		"docs/guide/install.markdown",
+		"docs/diagram.png",
+		"docs/.drafts/wip.md",
+		"README.md",
+		"docsite/other.md",
+	})
+	want := []string{filepath.Join(dir, "index.md"), filepath.Join(dir, "guide", "install.markdown")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckoutFiles() = %v, want %v", got, want)
+	}
+}
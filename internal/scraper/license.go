@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// licenseMetaNames are the meta tag names whose content states a page's
+// license or rights.
+var licenseMetaNames = []string{"license", "dcterms.license", "dc.rights", "dcterms.rights", "copyright"}
+
+var (
+	// creativeCommons matches Creative Commons license names such as
+	// "CC BY-SA 4.0".
+	creativeCommons = regexp.MustCompile(`(?i)\bCC[ -]BY((?:[ -](?:NC|SA|ND))*)(?:[ -](\d\.\d))?\b`)
+	// licensedUnder matches notices such as "licensed under the Apache
+	// License 2.0".
+	licensedUnder = regexp.MustCompile(`(?i)licensed under (?:the )?([A-Za-z0-9 .-]{2,40}?licen[cs]e(?: v?\d(?:\.\d)?)?)`)
+	// allRightsReserved matches copyright notices reserving all rights.
+	allRightsReserved = regexp.MustCompile(`(?i)all rights reserved`)
+	// llmsLicenseField matches a "License: ..." line of an llms.txt file.
+	llmsLicenseField = regexp.MustCompile(`(?i)^(?:[-*]\s*)?\**licen[cs]e\**\s*:\s*\**\s*(.+?)\s*\**$`)
+	// llmsLicenseLink matches a markdown link to a license in an llms.txt
+	// file.
+	llmsLicenseLink = regexp.MustCompile(`(?i)\[[^\]]*licen[cs]e[^\]]*\]\(([^)\s]+)\)`)
+)
+
+// pageLicense returns the license an HTML page declares: the target of a
+// rel="license" link, a license or rights meta tag, or a license notice in
+// its footer. Returns "" if the page declares none.
+func pageLicense(page string, pageURL *url.URL) string {
+	lower := strings.ToLower(page)
+	if !strings.Contains(lower, "licen") && !strings.Contains(lower, "rights") && !strings.Contains(lower, "cc by") && !strings.Contains(lower, "cc-by") {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return ""
+	}
+
+	var license string
+	doc.Find("[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			if rel == "license" {
+				if ref, err := url.Parse(strings.TrimSpace(s.AttrOr("href", ""))); err == nil {
+					license = pageURL.ResolveReference(ref).String()
+				}
+				return false
+			}
+		}
+		return true
+	})
+	if license != "" {
+		return license
+	}
+
+	for _, name := range licenseMetaNames {
+		doc.Find("meta[name][content]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if strings.EqualFold(strings.TrimSpace(s.AttrOr("name", "")), name) {
+				license = strings.TrimSpace(s.AttrOr("content", ""))
+			}
+			return license == ""
+		})
+		if license != "" {
+			return license
+		}
+	}
+
+	return licenseNotice(doc.Find(`footer, [role="contentinfo"], #footer, .footer`).Text())
+}
+
+// licenseNotice returns the license named by a notice such as a page
+// footer, or "" if it names none.
+func licenseNotice(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if m := creativeCommons.FindStringSubmatch(text); m != nil {
+		name := "CC BY" + strings.ToUpper(strings.ReplaceAll(m[1], " ", "-"))
+		if m[2] != "" {
+			name += " " + m[2]
+		}
+		return name
+	}
+	if m := licensedUnder.FindStringSubmatch(text); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	if allRightsReserved.MatchString(text) {
+		return "All rights reserved"
+	}
+	return ""
+}
+
+// siteLicense returns the license the llms.txt file of site declares for
+// the whole site, in a "License:" line or a link to the license. Returns ""
+// if the site has no llms.txt or it declares none.
+func (s *Scraper) siteLicense(ctx context.Context, site *url.URL) string {
+	llmsURL := &url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/llms.txt"}
+	req, err := http.NewRequestWithContext(ctx, "GET", llmsURL.String(), nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	s.applyHeaders(req.Header, req.URL)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return ""
+	}
+	return llmsLicense(string(body), llmsURL)
+}
+
+// llmsLicense returns the license an llms.txt file declares, resolving
+// links against its URL.
+func llmsLicense(content string, llmsURL *url.URL) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if m := llmsLicenseLink.FindStringSubmatch(line); m != nil {
+			if ref, err := url.Parse(m[1]); err == nil {
+				return llmsURL.ResolveReference(ref).String()
+			}
+		}
+		if m := llmsLicenseField.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPageLicense(t *testing.T) {
+	pageURL, _ := url.Parse("https://docs.example.com/guide/intro")
+
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{"rel license link", `<html><body><a rel="license" href="/LICENSE">License</a></body></html>`, "https://docs.example.com/LICENSE"},
+		{"meta tag", `<html><head><meta name="dcterms.license" content="MIT"></head></html>`, "MIT"},
+		{"creative commons footer", `<html><body><footer>Content is available under cc by-sa 4.0 unless noted.</footer></body></html>`, "CC BY-SA 4.0"},
+		{"licensed under footer", `<html><body><footer>Documentation licensed under the Apache License 2.0.</footer></body></html>`, "Apache License 2.0"},
+		{"all rights reserved", `<html><body><div class="footer">© 2025 Example Corp. All rights reserved.</div></body></html>`, "All rights reserved"},
+		{"notice outside the footer", `<html><body><p>This project is licensed under the MIT License.</p></body></html>`, ""},
+		{"none", `<html><body><p>Hello</p></body></html>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageLicense(tt.page, pageURL); got != tt.want {
+				t.Errorf("pageLicense() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLLMSLicense(t *testing.T) {
+	llmsURL, _ := url.Parse("https://docs.example.com/llms.txt")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"field", "# Example\n\n> Docs for Example.\n\nLicense: CC BY 4.0\n", "CC BY 4.0"},
+		{"bold list field", "- **License**: MIT\n", "MIT"},
+		{"link", "## Optional\n\n- [License](/legal/license.md): terms of reuse\n", "https://docs.example.com/legal/license.md"},
+		{"none", "# Example\n\n- [Guide](/guide.md)\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := llmsLicense(tt.content, llmsURL); got != tt.want {
+				t.Errorf("llmsLicense() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
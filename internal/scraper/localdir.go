@@ -0,0 +1,142 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FileURL returns the file:// URL used as the document URL for a local file.
+func FileURL(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// ListMarkdownFiles returns all markdown files under dir, recursively.
+// Hidden directories (e.g. .git) are skipped.
+func ListMarkdownFiles(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && isHidden(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if markdown.IsMarkdownURL(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+
+	return files, nil
+}
+
+// ScrapeFiles reads the given local markdown files into documents.
+// Files that cannot be read are skipped.
+func (s *Scraper) ScrapeFiles(ctx context.Context, files []string) ([]models.Document, error) {
+	var docs []models.Document
+	_, err := s.scrapeFiles(ctx, files, &storage.ScrapeReport{}, func(doc models.Document) {
+		docs = append(docs, doc)
+	})
+	return docs, err
+}
+
+// scrapeFiles implements ScrapeFiles, passing each file to emit as it is
+// read and counting unreadable files in report. Returns the number of
+// files emitted.
+func (s *Scraper) scrapeFiles(ctx context.Context, files []string, report *storage.ScrapeReport, emit func(models.Document)) (_ int, err error) {
+	ctx, span := telemetry.Start(ctx, "scrape.files", attribute.Int("files", len(files)))
+	defer func() { telemetry.End(span, err) }()
+
+	pages := 0
+
+	for _, path := range files {
+		if ctx.Err() != nil {
+			return pages, ctx.Err()
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			slog.Warn("failed to read file", "path", path, "error", err)
+			report.Fail("read")
+			continue
+		}
+
+		emit(models.Document{
+			URL:         FileURL(path),
+			Content:     string(content),
+			ContentType: "text/markdown",
+			ScrapedAt:   time.Now(),
+			Source:      models.Source{Name: s.config.Source},
+		})
+		pages++
+
+		if s.config.Progress != nil {
+			s.config.Progress.Report(progress.Event{Type: progress.EventPage, URL: FileURL(path), Current: pages, Total: len(files)})
+		}
+	}
+
+	return pages, nil
+}
+
+// ScrapeDirToS3 reads markdown files from a local directory and writes them to S3.
+// If files is empty, every markdown file under dir is read.
+func (s *Scraper) ScrapeDirToS3(ctx context.Context, dir string, files []string, storageClient *storage.Client) (_ *ScrapeResult, err error) {
+	ctx, span := telemetry.Start(ctx, "scrape.dir", attribute.String("dir", dir))
+	defer func() { telemetry.End(span, err) }()
+
+	if len(files) == 0 {
+		files, err = ListMarkdownFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sourceURL := FileURL(dir)
+	prefix := NewPrefix(DirPrefixHost(dir), sourceURL)
+
+	slog.Info("starting directory scrape to S3", "dir", dir, "prefix", prefix, "files", len(files))
+
+	report := &storage.ScrapeReport{StartedAt: time.Now().UTC()}
+	writer := newPageWriter(ctx, storageClient, prefix, s.config.MemoryBudget, s.config.Uploads, s.config.Redactor)
+	pages, err := s.scrapeFiles(ctx, files, report, writer.Put)
+	writer.Close()
+	if err != nil && pages == 0 {
+		return nil, fmt.Errorf("scrape failed: %w", err)
+	}
+
+	return writer.finish(ctx, sourceURL, s.config.Source, report, err != nil)
+}
+
+// DirPrefixHost returns the host segment used in S3 prefixes for a local directory.
+func DirPrefixHost(dir string) string {
+	return "local/" + filepath.Base(dir)
+}
+
+// isHidden reports whether a file or directory name starts with a dot.
+func isHidden(name string) bool {
+	return len(name) > 1 && name[0] == '.'
+}
@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListMarkdownFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "index.md"), "# Index")
+	writeFile(t, filepath.Join(dir, "guide", "install.markdown"), "# Install")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not markdown")
+	writeFile(t, filepath.Join(dir, ".git", "README.md"), "# Hidden")
+
+	files, err := ListMarkdownFiles(dir)
+	if err != nil {
+		t.Fatalf("ListMarkdownFiles() error = %v", err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("ListMarkdownFiles() returned %d files, want 2: %v", len(files), files)
+	}
+	for _, f := range files {
+		if strings.Contains(f, ".git") {
+			t.Errorf("hidden directory should be skipped: %s", f)
+		}
+	}
+}
+
+func TestScraper_ScrapeFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.md")
+	writeFile(t, path, "# Page\n\nLocal content.")
+
+	s := New(Config{})
+	docs, err := s.ScrapeFiles(t.Context(), []string{path, filepath.Join(dir, "missing.md")})
+	if err != nil {
+		t.Fatalf("ScrapeFiles() error = %v", err)
+	}
+
+	if len(docs) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(docs))
+	}
+	if docs[0].URL != FileURL(path) {
+		t.Errorf("URL = %q, want %q", docs[0].URL, FileURL(path))
+	}
+	if !strings.HasPrefix(docs[0].URL, "file://") {
+		t.Errorf("URL = %q, want file:// scheme", docs[0].URL)
+	}
+	if docs[0].ContentType != "text/markdown" {
+		t.Errorf("ContentType = %q, want text/markdown", docs[0].ContentType)
+	}
+}
+
+func TestWatchDir_ReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.md")
+	writeFile(t, existing, "# Existing")
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	type batch struct{ changed, removed []string }
+	batches := make(chan batch, 1)
+
+	go WatchDir(ctx, dir, 50*time.Millisecond, func(changed, removed []string) {
+		batches <- batch{changed, removed}
+	})
+
+	// Give the watcher time to register
+	time.Sleep(100 * time.Millisecond)
+
+	created := filepath.Join(dir, "new.md")
+	writeFile(t, created, "# New")
+	writeFile(t, filepath.Join(dir, "ignored.txt"), "ignored")
+	if err := os.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case b := <-batches:
+		if len(b.changed) != 1 || b.changed[0] != created {
+			t.Errorf("changed = %v, want [%s]", b.changed, created)
+		}
+		if len(b.removed) != 1 || b.removed[0] != existing {
+			t.Errorf("removed = %v, want [%s]", b.removed, existing)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for change batch")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,23 @@
+package scraper
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+// baseTransport returns the transport requests are sent with. Without
+// proxies it is http.DefaultTransport, which honors the HTTP_PROXY,
+// HTTPS_PROXY, and NO_PROXY environment variables; otherwise requests go
+// through proxies in turn, so a retried request tries the next one.
+func baseTransport(proxies []*url.URL) http.RoundTripper {
+	if len(proxies) == 0 {
+		return http.DefaultTransport
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	var next atomic.Uint64
+	t.Proxy = func(*http.Request) (*url.URL, error) {
+		return proxies[(next.Add(1)-1)%uint64(len(proxies))], nil
+	}
+	return t
+}
@@ -0,0 +1,47 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestScraper_RotatesProxies(t *testing.T) {
+	// Plain HTTP proxies receive the absolute URL of each request
+	var hits [2]atomic.Int32
+	var proxies []*url.URL
+	for i := range hits {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Host != "docs.invalid" {
+				t.Errorf("proxy %d got request for %q, want docs.invalid", i, r.URL.Host)
+			}
+			hits[i].Add(1)
+			w.Header().Set("Content-Type", "text/html")
+			if r.URL.Path == "/" {
+				w.Write([]byte(`<html><body><a href="/next">Next</a></body></html>`))
+				return
+			}
+			w.Write([]byte(`<html><body><p>Next</p></body></html>`))
+		}))
+		defer server.Close()
+		proxy, _ := url.Parse(server.URL)
+		proxies = append(proxies, proxy)
+	}
+
+	s := New(Config{MaxDepth: 2, FollowLinks: true, Proxies: proxies})
+	docs, err := s.Scrape(context.Background(), "http://docs.invalid/")
+	if err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Scrape() returned %d pages, want 2", len(docs))
+	}
+	for i := range hits {
+		if got := hits[i].Load(); got != 1 {
+			t.Errorf("proxy %d got %d requests, want 1", i, got)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry configures how requests failing transiently are retried: those
+// answered with 429 Too Many Requests or a 5xx status, and those failing
+// with a network error or timing out.
+type Retry struct {
+	MaxAttempts int           // Tries per request, including the first; 0 or 1 disables retries
+	Backoff     time.Duration // Wait before the first retry; doubles after each failure
+	MaxBackoff  time.Duration // Longest wait, and the longest Retry-After honored; 0 for no limit
+	Jitter      float64       // Fraction of each wait randomized, from 0 to 1, so parallel requests spread out
+}
+
+// retryTransport retries transient failures of idempotent requests with
+// exponential backoff, waiting as long as a Retry-After header asks. Each
+// attempt gets its own timeout, covering the response body too, so a
+// request that timed out can be tried again.
+type retryTransport struct {
+	next    http.RoundTripper
+	retry   Retry
+	timeout time.Duration // Per attempt; 0 for none
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := (req.Method == http.MethodGet || req.Method == http.MethodHead) && (req.Body == nil || req.Body == http.NoBody)
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(req)
+		if attempt >= t.retry.MaxAttempts || !idempotent || !transient(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		delay := t.delay(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if t.retry.MaxBackoff > 0 && after > t.retry.MaxBackoff {
+					slog.Debug("not retrying, Retry-After exceeds the longest wait", "url", req.URL.String(), "retry_after", after)
+					return resp, nil
+				}
+				delay = max(delay, after)
+			}
+			// Drained so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			slog.Debug("request failed, retrying", "url", req.URL.String(), "attempt", attempt, "status", resp.StatusCode, "delay", delay)
+		} else {
+			slog.Debug("request failed, retrying", "url", req.URL.String(), "attempt", attempt, "delay", delay, "error", err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// attempt makes one try of req, cancelling it once the timeout passes or
+// the response body is closed.
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// delay returns the wait before retrying after the given attempt.
+func (t *retryTransport) delay(attempt int) time.Duration {
+	delay := t.retry.Backoff << (attempt - 1)
+	if delay < 0 || t.retry.MaxBackoff > 0 && delay > t.retry.MaxBackoff {
+		delay = t.retry.MaxBackoff
+	}
+	if jitter := min(max(t.retry.Jitter, 0), 1); jitter > 0 {
+		delay -= time.Duration(jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// transient reports whether a request that got resp or failed with err is
+// worth trying again. Failures caused by cancelling ctx are not.
+func transient(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+	}
+	switch code := resp.StatusCode; {
+	case code == http.StatusTooManyRequests:
+		return true
+	case code == http.StatusNotImplemented || code == http.StatusHTTPVersionNotSupported:
+		return false
+	default:
+		return code >= 500
+	}
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP
+// date, into the wait it asks for from now.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// cancelBody releases the context of a request attempt once its response
+// body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
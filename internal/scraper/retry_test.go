@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScraper_RetriesTransientFailures(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch hits.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Flaky</title></head><body><p>Hello</p></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	s := New(Config{MaxDepth: 1, Retry: Retry{MaxAttempts: 3, Backoff: time.Millisecond}})
+	docs, err := s.Scrape(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if len(docs) != 1 || !strings.Contains(docs[0].Content, "Hello") {
+		t.Errorf("Scrape() = %+v, want the page", docs)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("page requested %d times, want 3", got)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  func(w http.ResponseWriter, attempt int32)
+		retry    Retry
+		timeout  time.Duration
+		wantCode int
+		wantHits int32
+	}{
+		{
+			name:     "gives up after max attempts",
+			handler:  func(w http.ResponseWriter, _ int32) { w.WriteHeader(http.StatusBadGateway) },
+			retry:    Retry{MaxAttempts: 2, Backoff: time.Millisecond},
+			wantCode: http.StatusBadGateway,
+			wantHits: 2,
+		},
+		{
+			name:     "does not retry client errors",
+			handler:  func(w http.ResponseWriter, _ int32) { w.WriteHeader(http.StatusNotFound) },
+			retry:    Retry{MaxAttempts: 3, Backoff: time.Millisecond},
+			wantCode: http.StatusNotFound,
+			wantHits: 1,
+		},
+		{
+			name: "gives up on a Retry-After beyond max backoff",
+			handler: func(w http.ResponseWriter, _ int32) {
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			retry:    Retry{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Second},
+			wantCode: http.StatusTooManyRequests,
+			wantHits: 1,
+		},
+		{
+			name: "retries a timed out attempt",
+			handler: func(w http.ResponseWriter, attempt int32) {
+				if attempt == 1 {
+					time.Sleep(200 * time.Millisecond)
+				}
+				w.Write([]byte("ok"))
+			},
+			retry:    Retry{MaxAttempts: 2, Backoff: time.Millisecond},
+			timeout:  50 * time.Millisecond,
+			wantCode: http.StatusOK,
+			wantHits: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(w, hits.Add(1))
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &retryTransport{next: http.DefaultTransport, retry: tt.retry, timeout: tt.timeout}}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("requested %d times, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
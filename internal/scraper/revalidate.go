@@ -0,0 +1,107 @@
+package scraper
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// revalidation carries page validators from one scrape of a URL to the
+// next. Pages of the previous scrape are requested conditionally, and
+// those the server reports unchanged are passed to unchanged instead of
+// being fetched. A nil revalidation requests every page in full.
+type revalidation struct {
+	previous  map[string]storage.Validator // From the previous scrape, by page URL
+	unchanged func(pageURL string)         // Called for each page answered with 304 Not Modified
+
+	mu      sync.Mutex
+	current map[string]storage.Validator // Of the pages seen by this scrape, by page URL
+}
+
+// newRevalidation creates a revalidation of the pages validated by the
+// previous scrape.
+func newRevalidation(previous map[string]storage.Validator, unchanged func(pageURL string)) *revalidation {
+	return &revalidation{previous: previous, unchanged: unchanged, current: make(map[string]storage.Validator)}
+}
+
+// condition sets the conditional request headers of a page validated by
+// the previous scrape.
+func (rv *revalidation) condition(header http.Header, pageURL string) {
+	if rv == nil {
+		return
+	}
+	v, ok := rv.previous[pageURL]
+	if !ok {
+		return
+	}
+	if v.ETag != "" {
+		header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// notModified records a page answered with 304 Not Modified and returns
+// the links to follow from it, those followed by the previous scrape. ok is
+// false if the page was not requested conditionally.
+func (rv *revalidation) notModified(pageURL string) (links []string, ok bool) {
+	if rv == nil {
+		return nil, false
+	}
+	v, ok := rv.previous[pageURL]
+	if !ok {
+		return nil, false
+	}
+	rv.mu.Lock()
+	rv.current[pageURL] = v
+	rv.mu.Unlock()
+	rv.unchanged(pageURL)
+	return v.Links, true
+}
+
+// fetched records the validators of a page fetched in full.
+func (rv *revalidation) fetched(pageURL string, header http.Header) {
+	if rv == nil {
+		return
+	}
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	v := rv.current[pageURL]
+	v.ETag = header.Get("ETag")
+	v.LastModified = header.Get("Last-Modified")
+	rv.current[pageURL] = v
+}
+
+// link records a link followed from a fetched page.
+func (rv *revalidation) link(pageURL, link string) {
+	if rv == nil {
+		return
+	}
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	v := rv.current[pageURL]
+	if !slices.Contains(v.Links, link) {
+		v.Links = append(v.Links, link)
+	}
+	rv.current[pageURL] = v
+}
+
+// validators returns the validators to store for pages, leaving out those
+// the server sent none for, since they cannot be revalidated.
+func (rv *revalidation) validators(pages []string) map[string]storage.Validator {
+	if rv == nil {
+		return nil
+	}
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	validators := make(map[string]storage.Validator)
+	for _, pageURL := range pages {
+		if v := rv.current[pageURL]; v.ETag != "" || v.LastModified != "" {
+			validators[pageURL] = v
+		}
+	}
+	return validators
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// maxRobotsSize bounds the robots.txt read; Google ignores rules past 500 KiB.
+const maxRobotsSize = 500 << 10
+
+// robotsPolicy holds the robots.txt rules of the hosts a scrape visits,
+// fetched once per host. A nil policy allows everything.
+type robotsPolicy struct {
+	s     *Scraper
+	mu    sync.Mutex
+	rules map[string]*robotstxt.RobotsData // By scheme and host; nil allows everything
+}
+
+// newRobotsPolicy returns the policy of a scrape, or nil if robots.txt is
+// not respected.
+func (s *Scraper) newRobotsPolicy() *robotsPolicy {
+	if !s.config.RespectRobots {
+		return nil
+	}
+	return &robotsPolicy{s: s, rules: make(map[string]*robotstxt.RobotsData)}
+}
+
+// allowed reports whether robots.txt allows the scraper's user agent to
+// fetch u.
+func (p *robotsPolicy) allowed(ctx context.Context, u *url.URL) bool {
+	if p == nil {
+		return true
+	}
+	rules := p.fetch(ctx, u)
+	return rules == nil || rules.TestAgent(u.RequestURI(), p.s.config.UserAgent)
+}
+
+// crawlDelay returns the Crawl-delay robots.txt sets for u's host, or 0.
+func (p *robotsPolicy) crawlDelay(ctx context.Context, u *url.URL) time.Duration {
+	if p == nil {
+		return 0
+	}
+	if rules := p.fetch(ctx, u); rules != nil {
+		return rules.FindGroup(p.s.config.UserAgent).CrawlDelay
+	}
+	return 0
+}
+
+// fetch returns the robots.txt rules of u's host, fetching them on first
+// use. The lock is held while fetching, so concurrent requests wait for the
+// rules instead of fetching them again.
+func (p *robotsPolicy) fetch(ctx context.Context, u *url.URL) *robotstxt.RobotsData {
+	origin := u.Scheme + "://" + u.Host
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if rules, ok := p.rules[origin]; ok {
+		return rules
+	}
+
+	data, err := p.s.fetchRobots(ctx, origin)
+	if err != nil {
+		// An unreachable robots.txt is treated as a missing one
+		slog.Debug("robots.txt unavailable, allowing all paths", "origin", origin, "error", err)
+		p.rules[origin] = nil
+		return nil
+	}
+	p.rules[origin] = data
+	return data
+}
+
+// fetchRobots fetches and parses the robots.txt of origin. As the
+// standard asks, a missing file allows everything and a server error
+// disallows everything.
+func (s *Scraper) fetchRobots(ctx context.Context, origin string) (*robotstxt.RobotsData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	s.applyHeaders(req.Header, req.URL)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		return nil, err
+	}
+	return robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+}
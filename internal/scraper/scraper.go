@@ -2,18 +2,30 @@ package scraper
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/mfenderov/bam-rag/internal/markdown"
+	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/internal/progress"
+	"github.com/mfenderov/bam-rag/internal/slowops"
 	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
 	"github.com/mfenderov/bam-rag/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Config holds scraper configuration.
@@ -23,13 +35,35 @@ type Config struct {
 	FollowLinks      bool
 	UserAgent        string
 	Timeout          time.Duration
-	TryMarkdownFirst bool // Try to fetch markdown version of pages
+	TryMarkdownFirst bool              // Try to fetch markdown version of pages
+	ContentSelector  string            // CSS selector for the main content of HTML pages; empty keeps the whole page
+	MaxParallel      int               // Concurrent requests per origin; defaults to 2
+	Source           string            // Config source name, recorded in scrape metadata
+	Auth             []Auth            // Credentials applied to requests by domain
+	Headers          map[string]string // Sent with every request, e.g. Accept-Language; credentials belong in Auth
+	Progress         progress.Reporter // Optional, receives an event per scraped page
+	SlowOps          *slowops.Tracker  // Optional, observes the duration of each page fetch
+	MemoryBudget     int64             // Bytes of scraped pages held while waiting to be written to S3; 0 for no limit
+	CacheDir         string            // Directory of cached responses, revalidated on each fetch; empty disables the cache
+	Uploads          int               // Pages written to S3 at once; defaults to 4
+	RespectNoindex   bool              // Skip pages marked noindex by a robots meta tag or X-Robots-Tag header
+	RespectRobots    bool              // Skip paths robots.txt disallows and wait at least its Crawl-delay between requests
+	Incremental      bool              // Revalidate the pages of the previous scrape of a URL, copying unchanged ones instead of fetching them
+	Retry            Retry             // Retries of requests failing transiently; none by default
+	Proxies          []*url.URL        // HTTP(S) or SOCKS5 proxies requests go through in turn; none uses the proxy environment variables
+	Include          []*regexp.Regexp  // Links followed must match one of these, if any are set
+	Exclude          []*regexp.Regexp  // Links matching any of these are not followed
+
+	// Redactor, if set, masks sensitive strings in pages before they are
+	// written to S3
+	Redactor *processor.Redactor
 }
 
 // Scraper fetches web pages and returns their content.
 type Scraper struct {
 	config     Config
 	httpClient *http.Client
+	transport  http.RoundTripper // Shared by page and markdown-variant fetches; times out and retries each attempt
 }
 
 // New creates a new Scraper with the given configuration.
@@ -40,12 +74,24 @@ func New(config Config) *Scraper {
 	if config.UserAgent == "" {
 		config.UserAgent = "BAM-RAG/1.0"
 	}
-	return &Scraper{
-		config: config,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+	if config.MaxParallel <= 0 {
+		config.MaxParallel = 2
 	}
+	if config.Uploads <= 0 {
+		config.Uploads = 4
+	}
+	s := &Scraper{config: config}
+	// Timeouts are applied per attempt by the transport; a client timeout
+	// would span the retries
+	s.transport = &retryTransport{next: baseTransport(config.Proxies), retry: config.Retry, timeout: config.Timeout}
+	if config.CacheDir != "" {
+		s.transport = &cacheTransport{dir: config.CacheDir, next: s.transport}
+	}
+	s.httpClient = &http.Client{
+		Transport:     s.transport,
+		CheckRedirect: s.checkRedirect,
+	}
+	return s
 }
 
 // Scrape fetches the given URL and optionally follows links.
@@ -54,7 +100,26 @@ func New(config Config) *Scraper {
 func (s *Scraper) Scrape(ctx context.Context, startURL string) ([]models.Document, error) {
 	var docs []models.Document
 	var mu sync.Mutex
-	var cancelled bool
+	_, err := s.scrape(ctx, startURL, &storage.ScrapeReport{}, func(doc models.Document) {
+		mu.Lock()
+		defer mu.Unlock()
+		docs = append(docs, doc)
+	}, nil)
+	return docs, err
+}
+
+// scrape implements Scrape, passing each page to emit as it arrives and
+// counting skipped pages and failed requests in report. emit is called
+// concurrently. Pages are requested conditionally if rv is set. Returns
+// the number of pages emitted or reported unchanged.
+func (s *Scraper) scrape(ctx context.Context, startURL string, report *storage.ScrapeReport, emit func(models.Document), rv *revalidation) (_ int, err error) {
+	ctx, span := telemetry.Start(ctx, "scrape", attribute.String("url", startURL))
+	defer func() { telemetry.End(span, err) }()
+
+	var pages atomic.Int64
+	var mu sync.Mutex // Guards report and contents
+	contents := make(contentIndex)
+	var cancelled atomic.Bool
 
 	slog.Debug("starting scrape", "url", startURL, "max_depth", s.config.MaxDepth)
 
@@ -62,35 +127,133 @@ func (s *Scraper) Scrape(ctx context.Context, startURL string) ([]models.Documen
 	parsedURL, err := url.Parse(startURL)
 	if err != nil {
 		slog.Error("failed to parse URL", "url", startURL, "error", err)
-		return nil, err
+		return 0, err
 	}
 
+	// Async so the limit rule's parallelism takes effect; c.Wait below joins the workers
 	c := colly.NewCollector(
 		colly.MaxDepth(s.config.MaxDepth),
 		colly.UserAgent(s.config.UserAgent),
+		colly.Async(true),
 	)
 
-	// Set rate limiting
+	// Set rate limiting, slowed to the start host's Crawl-delay. It asks
+	// for a pause between any two requests, so they are made one at a time
+	robots := s.newRobotsPolicy()
+	delay, parallel := s.config.Delay, s.config.MaxParallel
+	if crawlDelay := robots.crawlDelay(ctx, parsedURL); crawlDelay > 0 {
+		slog.Debug("honoring robots.txt crawl delay", "host", parsedURL.Host, "delay", crawlDelay)
+		delay, parallel = max(delay, crawlDelay), 1
+	}
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
-		Delay:       s.config.Delay,
-		Parallelism: 2,
+		Delay:       delay,
+		Parallelism: parallel,
 	})
 
-	// Set timeout
-	c.SetRequestTimeout(s.config.Timeout)
+	// The transport times out each attempt of a request
+	c.SetRequestTimeout(0)
+	c.WithTransport(s.transport)
+	c.SetRedirectHandler(func(req *http.Request, via []*http.Request) error {
+		mu.Lock()
+		report.Redirects++
+		mu.Unlock()
+		return s.checkRedirect(req, via)
+	})
+
+	// Time each fetch by request ID; colly shares request contexts
+	// between a page and the links followed from it
+	var fetchStarts sync.Map
+	observeFetch := func(r *colly.Response) {
+		if start, ok := fetchStarts.LoadAndDelete(r.Request.ID); ok {
+			s.config.SlowOps.Since(slowops.OpFetch, r.Request.URL.String(), start.(time.Time))
+		}
+	}
+
+	// visit follows a link from the page of req, counting it if the link
+	// patterns or the depth limit exclude it
+	visit := func(req *colly.Request, link string) {
+		if !s.followable(link) {
+			mu.Lock()
+			report.Exclude("pattern")
+			mu.Unlock()
+			return
+		}
+		if err := req.Visit(link); errors.Is(err, colly.ErrMaxDepth) {
+			mu.Lock()
+			report.Exclude("depth")
+			mu.Unlock()
+		}
+	}
 
 	// Check for cancellation before each request
 	c.OnRequest(func(r *colly.Request) {
 		if ctx.Err() != nil {
 			slog.Debug("scrape cancelled", "url", r.URL.String())
 			r.Abort()
-			cancelled = true
+			cancelled.Store(true)
+			return
+		}
+		if !robots.allowed(ctx, r.URL) {
+			if r.URL.String() == startURL {
+				slog.Warn("robots.txt disallows the start URL", "url", startURL)
+			}
+			slog.Debug("skipping page disallowed by robots.txt", "url", r.URL.String())
+			r.Abort()
+			mu.Lock()
+			report.Exclude("robots")
+			mu.Unlock()
+			return
+		}
+		s.applyHeaders(*r.Headers, r.URL)
+		rv.condition(*r.Headers, r.URL.String())
+		fetchStarts.Store(r.ID, time.Now())
+	})
+
+	// Count pages that could not be scraped
+	c.OnError(func(r *colly.Response, err error) {
+		observeFetch(r)
+		if r.StatusCode == http.StatusNotModified {
+			if links, ok := rv.notModified(r.Request.URL.String()); ok {
+				slog.Debug("page unchanged since the previous scrape", "url", r.Request.URL.String())
+				mu.Lock()
+				report.Status(r.StatusCode)
+				report.Unchanged++
+				mu.Unlock()
+				pages.Add(1)
+				if s.config.FollowLinks {
+					for _, link := range links {
+						visit(r.Request, link)
+					}
+				}
+				return
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.StatusCode > 0 {
+			slog.Debug("skipping page with error status", "url", r.Request.URL.String(), "status", r.StatusCode)
+			report.Status(r.StatusCode)
+			report.Skipped++
+			return
+		}
+		var visited *colly.AlreadyVisitedError
+		switch {
+		case errors.As(err, &visited):
+			slog.Debug("not following redirect to visited page", "url", r.Request.URL.String(), "target", visited.Destination)
+			report.Exclude("visited")
+		case !errors.Is(err, context.Canceled):
+			slog.Debug("request failed", "url", r.Request.URL.String(), "error", err)
+			report.Fail(requestError(err))
 		}
 	})
 
 	// Handle responses
 	c.OnResponse(func(r *colly.Response) {
+		observeFetch(r)
+		mu.Lock()
+		report.Status(r.StatusCode)
+		mu.Unlock()
 		if r.StatusCode >= 400 {
 			slog.Debug("skipping page with error status", "url", r.Request.URL.String(), "status", r.StatusCode)
 			return
@@ -102,12 +265,41 @@ func (s *Scraper) Scrape(ctx context.Context, startURL string) ([]models.Documen
 
 		slog.Debug("scraped page", "url", pageURL, "content_type", contentType, "size", len(content))
 
+		// Links of noindex pages are still followed; noindex doesn't
+		// imply nofollow
+		if s.config.RespectNoindex && noindex(*r.Headers, pageURL, contentType, content) {
+			slog.Debug("skipping noindex page", "url", pageURL)
+			mu.Lock()
+			report.Noindex++
+			mu.Unlock()
+			return
+		}
+
+		// Read the site branding before the page is replaced by its
+		// markdown variant or narrowed to its main content
+		var siteName, favicon, license string
+		if !markdown.Detect(pageURL, contentType, content) {
+			siteName, favicon = siteInfo(content, r.Request.URL)
+			license = pageLicense(content, r.Request.URL)
+		}
+
 		// Try markdown variants if enabled
+		usedMarkdown := false
 		if s.config.TryMarkdownFirst {
-			if mdContent, mdContentType, ok := s.tryMarkdownVariants(ctx, pageURL); ok {
+			if mdContent, mdContentType, ok := s.tryMarkdownVariants(ctx, pageURL, robots); ok {
 				slog.Debug("using markdown variant", "url", pageURL)
 				content = mdContent
 				contentType = mdContentType
+				usedMarkdown = true
+			}
+		}
+
+		// Narrow HTML pages to the configured main content
+		if s.config.ContentSelector != "" && !usedMarkdown && !markdown.Detect(pageURL, contentType, content) {
+			if selected, ok := selectContent(content, s.config.ContentSelector); ok {
+				content = selected
+			} else {
+				slog.Debug("content selector matched nothing, keeping full page", "url", pageURL, "selector", s.config.ContentSelector)
 			}
 		}
 
@@ -116,11 +308,26 @@ func (s *Scraper) Scrape(ctx context.Context, startURL string) ([]models.Documen
 			Content:     content,
 			ContentType: contentType,
 			ScrapedAt:   time.Now(),
+			SiteName:    siteName,
+			Favicon:     favicon,
+			License:     license,
+			Source:      models.Source{Name: s.config.Source, Host: PrefixHost(r.Request.URL)},
 		}
 
 		mu.Lock()
-		docs = append(docs, doc)
+		contents.add(pageURL, content)
 		mu.Unlock()
+
+		rv.fetched(pageURL, *r.Headers)
+
+		// Blocks while earlier pages wait to be written, holding back
+		// this worker's next request
+		emit(doc)
+
+		count := pages.Add(1)
+		if s.config.Progress != nil {
+			s.config.Progress.Report(progress.Event{Type: progress.EventPage, URL: pageURL, Current: int(count)})
+		}
 	})
 
 	// Follow links if enabled
@@ -134,9 +341,14 @@ func (s *Scraper) Scrape(ctx context.Context, startURL string) ([]models.Documen
 			if err != nil {
 				return
 			}
-			if linkURL.Host == parsedURL.Host {
-				e.Request.Visit(absoluteURL)
+			if linkURL.Host != parsedURL.Host {
+				mu.Lock()
+				report.Exclude("external")
+				mu.Unlock()
+				return
 			}
+			rv.link(e.Request.URL.String(), absoluteURL)
+			visit(e.Request, absoluteURL)
 		})
 	}
 
@@ -144,30 +356,70 @@ func (s *Scraper) Scrape(ctx context.Context, startURL string) ([]models.Documen
 	err = c.Visit(startURL)
 	if err != nil {
 		slog.Debug("visit error (continuing)", "url", startURL, "error", err)
-		return docs, nil
+		return 0, nil
 	}
 
 	// Wait for all requests to finish
 	c.Wait()
 
-	if cancelled {
-		slog.Info("scrape cancelled by context", "pages_scraped", len(docs))
-		return docs, ctx.Err()
+	report.Duplicates = contents.duplicates()
+
+	if cancelled.Load() {
+		slog.Info("scrape cancelled by context", "pages_scraped", pages.Load())
+		return int(pages.Load()), ctx.Err()
+	}
+
+	slog.Debug("scrape complete", "url", startURL, "pages", pages.Load())
+	span.SetAttributes(attribute.Int("pages", int(pages.Load())))
+	return int(pages.Load()), nil
+}
+
+// followable reports whether a link passes the include and exclude
+// patterns.
+func (s *Scraper) followable(link string) bool {
+	if len(s.config.Include) > 0 && !slices.ContainsFunc(s.config.Include, func(re *regexp.Regexp) bool { return re.MatchString(link) }) {
+		return false
 	}
+	return !slices.ContainsFunc(s.config.Exclude, func(re *regexp.Regexp) bool { return re.MatchString(link) })
+}
+
+// contentIndex groups page URLs by a hash of their content, to find pages
+// that repeat another.
+type contentIndex map[[sha256.Size]byte][]string
+
+// add records the content of a page.
+func (c contentIndex) add(pageURL, content string) {
+	sum := sha256.Sum256([]byte(content))
+	c[sum] = append(c[sum], pageURL)
+}
 
-	slog.Debug("scrape complete", "url", startURL, "pages", len(docs))
-	return docs, nil
+// duplicates returns the pages with the same content as another page. Each
+// is attributed to the first page of its content by URL order.
+func (c contentIndex) duplicates() []storage.Duplicate {
+	var dups []storage.Duplicate
+	for _, urls := range c {
+		slices.Sort(urls)
+		for _, u := range urls[1:] {
+			dups = append(dups, storage.Duplicate{URL: u, Of: urls[0]})
+		}
+	}
+	slices.SortFunc(dups, func(a, b storage.Duplicate) int { return strings.Compare(a.URL, b.URL) })
+	return dups
 }
 
-// tryMarkdownVariants attempts to fetch markdown versions of the URL.
+// tryMarkdownVariants attempts to fetch markdown versions of the URL,
+// skipping those robots disallows.
 // Returns the content, content-type, and success flag.
-func (s *Scraper) tryMarkdownVariants(ctx context.Context, pageURL string) (string, string, bool) {
+func (s *Scraper) tryMarkdownVariants(ctx context.Context, pageURL string, robots *robotsPolicy) (string, string, bool) {
 	variants := markdown.MarkdownURLVariants(pageURL)
 
 	for _, variantURL := range variants {
 		if ctx.Err() != nil {
 			return "", "", false
 		}
+		if u, err := url.Parse(variantURL); err != nil || !robots.allowed(ctx, u) {
+			continue
+		}
 		if content, contentType, ok := s.tryFetchMarkdown(ctx, variantURL); ok {
 			return content, contentType, true
 		}
@@ -183,6 +435,7 @@ func (s *Scraper) tryFetchMarkdown(ctx context.Context, url string) (string, str
 		return "", "", false
 	}
 	req.Header.Set("User-Agent", s.config.UserAgent)
+	s.applyHeaders(req.Header, req.URL)
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
@@ -211,72 +464,103 @@ func (s *Scraper) tryFetchMarkdown(ctx context.Context, url string) (string, str
 
 // ScrapeResult holds the result of a ScrapeToS3 operation.
 type ScrapeResult struct {
-	Prefix    string // S3 prefix where files were written
-	PageCount int    // Number of pages scraped
-	SourceURL string // Original URL that was scraped
+	Prefix      string                // S3 prefix where files were written
+	PageCount   int                   // Number of pages scraped
+	SourceURL   string                // Original URL that was scraped
+	Report      string                // Location of the run report; empty if it could not be written
+	Crawl       *storage.ScrapeReport // Scrape section of the run report
+	Traceparent string                // Trace context of the scrape, so its ingestion joins the trace
+	Interrupted bool                  // Cancelled before every page was fetched; the pages fetched are kept
 }
 
 // ScrapeToS3 scrapes the given URL and writes results to S3.
 // Returns the S3 prefix where the scrape was stored.
-func (s *Scraper) ScrapeToS3(ctx context.Context, startURL string, storageClient *storage.Client) (*ScrapeResult, error) {
+func (s *Scraper) ScrapeToS3(ctx context.Context, startURL string, storageClient *storage.Client) (_ *ScrapeResult, err error) {
+	ctx, span := telemetry.Start(ctx, "scrape.s3", attribute.String("url", startURL))
+	defer func() { telemetry.End(span, err) }()
+
 	// Parse the start URL to get the host for the prefix
 	parsedURL, err := url.Parse(startURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	// Generate unique prefix: scrapes/{host}/{timestamp}-{shortid}
-	timestamp := time.Now().UTC().Format("2006-01-02T15-04-05")
-	shortID := models.GenerateDocumentID(fmt.Sprintf("%s-%d", startURL, time.Now().UnixNano()))[:8]
-	prefix := fmt.Sprintf("scrapes/%s/%s-%s", parsedURL.Host, timestamp, shortID)
+	prefix := NewPrefix(PrefixHost(parsedURL), startURL)
 
 	slog.Info("starting scrape to S3", "url", startURL, "prefix", prefix)
 
-	// Scrape pages using existing method
-	docs, err := s.Scrape(ctx, startURL)
-	if err != nil && len(docs) == 0 {
+	report := &storage.ScrapeReport{StartedAt: time.Now().UTC()}
+	writer := newPageWriter(ctx, storageClient, prefix, s.config.MemoryBudget, s.config.Uploads, s.config.Redactor)
+
+	// Pages validated by the previous scrape of the URL are revalidated,
+	// and copied from it while unchanged
+	var rv *revalidation
+	if s.config.Incremental {
+		previousPrefix, previous, err := storageClient.LatestScrape(ctx, PrefixHost(parsedURL), startURL)
+		var validators map[string]storage.Validator
+		if err != nil {
+			slog.Warn("previous scrape unavailable, fetching every page", "url", startURL, "error", err)
+		} else if previous != nil {
+			slog.Debug("revalidating pages of the previous scrape", "prefix", previousPrefix, "pages", len(previous.Validators))
+			validators = previous.Validators
+		}
+		rv = newRevalidation(validators, func(pageURL string) {
+			writer.Copy(previousPrefix, previous, pageURL)
+		})
+	}
+
+	pages, err := s.scrape(ctx, startURL, report, writer.Put, rv)
+	writer.Close()
+	if err != nil && pages == 0 {
 		return nil, fmt.Errorf("scrape failed: %w", err)
 	}
+	writer.validators = rv.validators(writer.pageURLs)
 
-	// Write each page to S3
-	var pageURLs []string
-	for _, doc := range docs {
-		// Generate filename from URL hash
-		filename := models.GenerateDocumentID(doc.URL) + ".md"
-
-		// Get markdown content (already markdown or needs conversion)
-		mdContent := doc.Content
-		if !markdown.Detect(doc.URL, doc.ContentType, doc.Content) {
-			// Content is HTML - for now just store as-is
-			// The ingestion engine will handle conversion
-			slog.Debug("storing HTML content", "url", doc.URL)
-		}
+	// Pages declaring no license fall back to the one in the site's llms.txt
+	if len(writer.licenses) < len(writer.pageURLs) && ctx.Err() == nil {
+		writer.license = s.siteLicense(ctx, parsedURL)
+	}
 
-		if err := storageClient.PutMarkdown(ctx, prefix, filename, mdContent); err != nil {
-			slog.Error("failed to write to S3", "url", doc.URL, "error", err)
-			continue
-		}
+	return writer.finish(ctx, startURL, s.config.Source, report, err != nil)
+}
+
+// PrefixHost returns the host segment used in S3 prefixes for a scraped URL.
+func PrefixHost(u *url.URL) string {
+	return u.Host
+}
 
-		pageURLs = append(pageURLs, doc.URL)
-		slog.Debug("wrote page to S3", "url", doc.URL, "filename", filename)
+// NewPrefix generates a unique prefix: scrapes/{host}/{timestamp}-{shortid}
+func NewPrefix(host, sourceURL string) string {
+	timestamp := time.Now().UTC().Format("2006-01-02T15-04-05")
+	shortID := models.GenerateDocumentID(fmt.Sprintf("%s-%d", sourceURL, time.Now().UnixNano()))[:8]
+	return fmt.Sprintf("scrapes/%s/%s-%s", host, timestamp, shortID)
+}
+
+// requestError classifies a failed request for the scrape report.
+func requestError(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
 	}
+	return "network"
+}
 
-	// Write metadata
-	meta := storage.ScrapeMetadata{
-		SourceURL: startURL,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		PageCount: len(pageURLs),
-		Pages:     pageURLs,
+// siteBranding picks the site name and favicon of a scrape, preferring
+// those of the start page and falling back to the first page declaring them.
+func siteBranding(docs []models.Document, startURL string) (name, favicon string) {
+	for _, doc := range docs {
+		if doc.URL == startURL {
+			name, favicon = doc.SiteName, doc.Favicon
+			break
+		}
 	}
-	if err := storageClient.PutMetadata(ctx, prefix, meta); err != nil {
-		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	for _, doc := range docs {
+		if name == "" {
+			name = doc.SiteName
+		}
+		if favicon == "" {
+			favicon = doc.Favicon
+		}
 	}
-
-	slog.Info("scrape to S3 complete", "url", startURL, "prefix", prefix, "pages", len(pageURLs))
-
-	return &ScrapeResult{
-		Prefix:    prefix,
-		PageCount: len(pageURLs),
-		SourceURL: startURL,
-	}, nil
+	return name, favicon
 }
@@ -1,11 +1,22 @@
 package scraper
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/mfenderov/bam-rag/internal/slowops"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/pkg/models"
 )
 
 func TestScraper_FetchSingleURL(t *testing.T) {
@@ -173,6 +184,313 @@ func TestScraper_HandlesErrors(t *testing.T) {
 	}
 }
 
+func TestScraper_ReportsSkippedPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/missing">Missing</a><a href="/broken">Broken</a></body></html>`))
+		case "/broken":
+			http.Error(w, "Internal Error", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := New(Config{MaxDepth: 2, FollowLinks: true})
+
+	report := &storage.ScrapeReport{}
+	pages, err := s.scrape(t.Context(), server.URL, report, func(models.Document) {}, nil)
+	if err != nil {
+		t.Fatalf("scrape() error = %v", err)
+	}
+	if pages != 1 {
+		t.Errorf("got %d documents, want 1", pages)
+	}
+	if report.Skipped != 2 {
+		t.Errorf("Skipped = %d, want 2", report.Skipped)
+	}
+	if len(report.Errors) != 0 {
+		t.Errorf("Errors = %v, want none", report.Errors)
+	}
+}
+
+func TestScraper_SkipsNoindexPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><head><meta name="robots" content="noindex, follow"></head><body><a href="/a">A</a><a href="/b">B</a></body></html>`))
+		case "/a":
+			w.Header().Set("X-Robots-Tag", "none")
+			w.Write([]byte(`<html><body>Private</body></html>`))
+		default:
+			w.Write([]byte(`<html><body>Public</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	for _, respect := range []bool{true, false} {
+		s := New(Config{MaxDepth: 2, FollowLinks: true, RespectNoindex: respect})
+
+		report := &storage.ScrapeReport{}
+		var mu sync.Mutex
+		var urls []string
+		emit := func(doc models.Document) {
+			mu.Lock()
+			defer mu.Unlock()
+			urls = append(urls, doc.URL)
+		}
+		if _, err := s.scrape(t.Context(), server.URL, report, emit, nil); err != nil {
+			t.Fatalf("scrape() error = %v", err)
+		}
+
+		if respect && (len(urls) != 1 || urls[0] != server.URL+"/b" || report.Noindex != 2) {
+			t.Errorf("respecting noindex: scraped %v (%d noindex), want only /b", urls, report.Noindex)
+		}
+		if !respect && (len(urls) != 3 || report.Noindex != 0) {
+			t.Errorf("ignoring noindex: scraped %v (%d noindex), want all 3 pages", urls, report.Noindex)
+		}
+	}
+}
+
+func TestScraper_RespectsRobotsTxt(t *testing.T) {
+	var mu sync.Mutex
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched = append(fetched, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /\n\nUser-agent: bam-rag\nDisallow: /private\nCrawl-delay: 0.1\n"))
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/a">A</a><a href="/private/b">B</a></body></html>`))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>Page</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	s := New(Config{MaxDepth: 2, FollowLinks: true, MaxParallel: 4, UserAgent: "BAM-RAG/1.0", RespectRobots: true})
+
+	report := &storage.ScrapeReport{}
+	start := time.Now()
+	pages, err := s.scrape(t.Context(), server.URL, report, func(models.Document) {}, nil)
+	if err != nil {
+		t.Fatalf("scrape() error = %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if pages != 2 || report.Excluded["robots"] != 1 {
+		t.Errorf("scraped %d pages (%d excluded by robots), want 2 and 1", pages, report.Excluded["robots"])
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if slices.Contains(fetched, "/private/b") {
+		t.Error("fetched /private/b, which robots.txt disallows")
+	}
+	if elapsed < 100*time.Millisecond {
+		t.Errorf("scrape took %v, want at least the 100ms crawl delay between pages", elapsed)
+	}
+}
+
+func TestScraper_RevalidatesUnchangedPages(t *testing.T) {
+	etags := map[string]string{"/": `"home-1"`, "/a": `"a-1"`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := etags[r.URL.Path]
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/a">A</a></body></html>`))
+	}))
+	defer server.Close()
+
+	s := New(Config{MaxDepth: 2, FollowLinks: true})
+	scrape := func(rv *revalidation) (emitted []string, report *storage.ScrapeReport) {
+		var mu sync.Mutex
+		report = &storage.ScrapeReport{}
+		emit := func(doc models.Document) {
+			mu.Lock()
+			defer mu.Unlock()
+			emitted = append(emitted, doc.URL)
+		}
+		if _, err := s.scrape(t.Context(), server.URL, report, emit, rv); err != nil {
+			t.Fatalf("scrape() error = %v", err)
+		}
+		return emitted, report
+	}
+
+	first := newRevalidation(nil, func(string) { t.Error("unchanged called without a previous scrape") })
+	emitted, _ := scrape(first)
+	validators := first.validators(emitted)
+	if home := validators[server.URL+"/"]; home.ETag != `"home-1"` || !slices.Equal(home.Links, []string{server.URL + "/a"}) {
+		t.Fatalf("validators of / = %+v", home)
+	}
+
+	// The home page is unchanged, but the page it links to changed
+	etags["/a"] = `"a-2"`
+	var unchanged []string
+	emitted, report := scrape(newRevalidation(validators, func(pageURL string) { unchanged = append(unchanged, pageURL) }))
+	if !slices.Equal(unchanged, []string{server.URL + "/"}) || report.Unchanged != 1 {
+		t.Errorf("unchanged = %v (%d reported), want only /", unchanged, report.Unchanged)
+	}
+	if !slices.Equal(emitted, []string{server.URL + "/a"}) {
+		t.Errorf("emitted %v, want only /a, found through the unchanged page's links", emitted)
+	}
+}
+
+func TestScraper_FollowsLinksMatchingPatterns(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path != "/" {
+			w.Write([]byte(`<html><body>Page</body></html>`))
+			return
+		}
+		w.Write([]byte(`<html><body>
+			<a href="/docs/install">Install</a><a href="/docs/de/install">Installieren</a>
+			<a href="/blog/release">Release</a><a href="/pricing">Pricing</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	s := New(Config{
+		MaxDepth:    2,
+		FollowLinks: true,
+		Include:     []*regexp.Regexp{regexp.MustCompile(`/docs/`), regexp.MustCompile(`/blog/`)},
+		Exclude:     []*regexp.Regexp{regexp.MustCompile(`/blog/`), regexp.MustCompile(`/docs/(de|fr)/`)},
+	})
+
+	report := &storage.ScrapeReport{}
+	var mu sync.Mutex
+	var urls []string
+	emit := func(doc models.Document) {
+		mu.Lock()
+		defer mu.Unlock()
+		urls = append(urls, doc.URL)
+	}
+	if _, err := s.scrape(t.Context(), server.URL+"/", report, emit, nil); err != nil {
+		t.Fatalf("scrape() error = %v", err)
+	}
+
+	slices.Sort(urls)
+	want := []string{server.URL + "/", server.URL + "/docs/install"}
+	if !slices.Equal(urls, want) {
+		t.Errorf("scraped %v, want %v", urls, want)
+	}
+	if report.Excluded["pattern"] != 3 {
+		t.Errorf("Excluded = %v, want 3 by pattern", report.Excluded)
+	}
+}
+
+func TestNoindex_Header(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"noindex", true},
+		{"NoIndex, nofollow", true},
+		{"max-snippet: 20, noindex", true},
+		{"googlebot: noindex", false},
+		{"unavailable_after: 25 Jun 2030 15:00:00 PST", false},
+		{"nofollow", false},
+	}
+	for _, tt := range tests {
+		header := http.Header{"X-Robots-Tag": {tt.value}}
+		if got := noindex(header, "https://example.com/", "text/html", "<html></html>"); got != tt.want {
+			t.Errorf("noindex(X-Robots-Tag: %s) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestScraper_ReportsCrawlQuality(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body>
+				<a href="/a">A</a><a href="/copy">Copy</a><a href="/moved">Moved</a><a href="/missing">Missing</a>
+				<a href="https://elsewhere.example.com/">Elsewhere</a>
+			</body></html>`))
+		case "/a", "/copy":
+			w.Write([]byte(`<html><body>Same page <a href="/deeper">Deeper</a></body></html>`))
+		case "/moved":
+			http.Redirect(w, r, "/target", http.StatusMovedPermanently)
+		case "/target":
+			w.Write([]byte(`<html><body>Target</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := New(Config{MaxDepth: 2, FollowLinks: true})
+
+	report := &storage.ScrapeReport{}
+	if _, err := s.scrape(t.Context(), server.URL, report, func(models.Document) {}, nil); err != nil {
+		t.Fatalf("scrape() error = %v", err)
+	}
+
+	if report.Statuses[200] != 4 || report.Statuses[404] != 1 {
+		t.Errorf("Statuses = %v, want 4 x 200 and 1 x 404", report.Statuses)
+	}
+	if report.Redirects != 1 {
+		t.Errorf("Redirects = %d, want 1", report.Redirects)
+	}
+	if report.Excluded["external"] != 1 || report.Excluded["depth"] != 2 {
+		t.Errorf("Excluded = %v, want 1 external and 2 depth", report.Excluded)
+	}
+	want := []storage.Duplicate{{URL: server.URL + "/copy", Of: server.URL + "/a"}}
+	if !reflect.DeepEqual(report.Duplicates, want) {
+		t.Errorf("Duplicates = %+v, want %+v", report.Duplicates, want)
+	}
+}
+
+func TestScraper_ObservesFetches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<html><body><a href="/slow">Slow</a></body></html>`))
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`<html><body>Slow page</body></html>`))
+	}))
+	defer server.Close()
+
+	tracker := slowops.New(nil, nil, 5)
+	s := New(Config{MaxDepth: 2, FollowLinks: true, SlowOps: tracker})
+
+	if _, err := s.Scrape(t.Context(), server.URL); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+
+	slowest := tracker.Slowest()
+	if len(slowest) != 2 {
+		t.Fatalf("Slowest() = %+v, want both fetches", slowest)
+	}
+	if slowest[0].Op != slowops.OpFetch || slowest[0].Target != server.URL+"/slow" || slowest[0].Duration < 20*time.Millisecond {
+		t.Errorf("Slowest()[0] = %+v, want the slow page", slowest[0])
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	timeout := &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}
+	if got := requestError(timeout); got != "timeout" {
+		t.Errorf("requestError(timeout) = %q, want timeout", got)
+	}
+	refused := &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection refused")}
+	if got := requestError(refused); got != "network" {
+		t.Errorf("requestError(refused) = %q, want network", got)
+	}
+}
+
 func TestScraper_SetsUserAgent(t *testing.T) {
 	var receivedUA string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -197,3 +515,289 @@ func TestScraper_SetsUserAgent(t *testing.T) {
 		t.Errorf("User-Agent = %q, want %q", receivedUA, "BAM-RAG/1.0")
 	}
 }
+
+func TestScraper_ContentSelector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html lang="en"><head><title>Release Notes</title></head><body>
+			<nav>Home | Docs | Blog</nav>
+			<article><h1>v1.2</h1><p>Fixed a bug.</p></article>
+			<footer>Copyright</footer>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	s := New(Config{
+		Delay:           10 * time.Millisecond,
+		MaxDepth:        1,
+		ContentSelector: "article",
+	})
+
+	docs, err := s.Scrape(t.Context(), server.URL)
+	if err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(docs))
+	}
+
+	content := docs[0].Content
+	if !strings.Contains(content, "Fixed a bug.") {
+		t.Error("Content should contain the selected article")
+	}
+	if strings.Contains(content, "Home | Docs") || strings.Contains(content, "Copyright") {
+		t.Error("Content should not contain navigation or footer")
+	}
+	if !strings.Contains(content, "<title>Release Notes</title>") {
+		t.Error("Content should keep the page title")
+	}
+	if !strings.Contains(content, `<html lang="en">`) {
+		t.Error("Content should keep the page language")
+	}
+}
+
+func TestSelectContent_NoMatchKeepsPage(t *testing.T) {
+	if _, ok := selectContent("<html><body><p>text</p></body></html>", "main"); ok {
+		t.Error("selectContent() should report no match")
+	}
+}
+
+func TestSiteInfo(t *testing.T) {
+	pageURL, _ := url.Parse("https://docs.example.com/guide/intro")
+
+	tests := []struct {
+		name        string
+		page        string
+		wantName    string
+		wantFavicon string
+	}{
+		{
+			name: "declared",
+			page: `<html><head>
+				<meta property="og:site_name" content=" Example Docs ">
+				<link rel="stylesheet" href="/style.css">
+				<link rel="shortcut icon" href="/static/fav.png">
+			</head></html>`,
+			wantName:    "Example Docs",
+			wantFavicon: "https://docs.example.com/static/fav.png",
+		},
+		{
+			name:        "fallback",
+			page:        `<html><head><title>Intro</title></head></html>`,
+			wantFavicon: "https://docs.example.com/favicon.ico",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, favicon := siteInfo(tt.page, pageURL)
+			if name != tt.wantName || favicon != tt.wantFavicon {
+				t.Errorf("siteInfo() = %q, %q, want %q, %q", name, favicon, tt.wantName, tt.wantFavicon)
+			}
+		})
+	}
+}
+
+func TestSiteBranding(t *testing.T) {
+	docs := []models.Document{
+		{URL: "https://example.com/a", SiteName: "Section A", Favicon: "https://example.com/a.png"},
+		{URL: "https://example.com/", Favicon: "https://example.com/favicon.ico"},
+	}
+
+	name, favicon := siteBranding(docs, "https://example.com/")
+	if name != "Section A" || favicon != "https://example.com/favicon.ico" {
+		t.Errorf("siteBranding() = %q, %q", name, favicon)
+	}
+}
+
+func TestScraper_LimitsParallelRequests(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<html><body><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="/d">d</a></body></html>`))
+			return
+		}
+		w.Write([]byte(`<html><body>leaf</body></html>`))
+	}))
+	defer server.Close()
+
+	s := New(Config{
+		MaxDepth:    2,
+		FollowLinks: true,
+		MaxParallel: 1,
+	})
+
+	docs, err := s.Scrape(t.Context(), server.URL)
+	if err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if len(docs) != 5 {
+		t.Errorf("expected 5 documents, got %d", len(docs))
+	}
+	if peak > 1 {
+		t.Errorf("peak concurrent requests = %d, want at most 1", peak)
+	}
+}
+
+func TestScraper_AppliesDomainAuth(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+		if r.URL.Path == "/guide.md" {
+			w.Header().Set("Content-Type", "text/markdown")
+			w.Write([]byte("# Guide"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Guide</body></html>`))
+	}))
+	defer server.Close()
+
+	s := New(Config{
+		Delay:            10 * time.Millisecond,
+		MaxDepth:         1,
+		TryMarkdownFirst: true,
+		Auth: []Auth{
+			{Domain: "127.0.0.1", Token: "secret", Headers: map[string]string{"X-Api-Key": "key"}},
+			{Domain: "example.com", Token: "other"},
+		},
+	})
+
+	if _, err := s.Scrape(t.Context(), server.URL+"/guide"); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+
+	for _, path := range []string{"/guide", "/guide.md"} {
+		h, ok := received[path]
+		if !ok {
+			t.Fatalf("no request for %s", path)
+		}
+		if got := h.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("%s Authorization = %q, want %q", path, got, "Bearer secret")
+		}
+		if got := h.Get("X-Api-Key"); got != "key" {
+			t.Errorf("%s X-Api-Key = %q, want %q", path, got, "key")
+		}
+	}
+}
+
+func TestScraper_SendsConfiguredHeaders(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+		if r.URL.Path == "/guide.md" {
+			w.Header().Set("Content-Type", "text/markdown")
+			w.Write([]byte("# Guide"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Guide</body></html>`))
+	}))
+	defer server.Close()
+
+	s := New(Config{
+		Delay:            10 * time.Millisecond,
+		MaxDepth:         1,
+		TryMarkdownFirst: true,
+		Headers:          map[string]string{"accept-language": "de", "X-Api-Key": "header"},
+		Auth:             []Auth{{Domain: "127.0.0.1", Headers: map[string]string{"X-Api-Key": "auth"}}},
+	})
+
+	if _, err := s.Scrape(t.Context(), server.URL+"/guide"); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+
+	for _, path := range []string{"/guide", "/guide.md"} {
+		h, ok := received[path]
+		if !ok {
+			t.Fatalf("no request for %s", path)
+		}
+		if got := h.Get("Accept-Language"); got != "de" {
+			t.Errorf("%s Accept-Language = %q, want %q", path, got, "de")
+		}
+		if got := h.Get("X-Api-Key"); got != "auth" {
+			t.Errorf("%s X-Api-Key = %q, want the auth's %q", path, got, "auth")
+		}
+	}
+}
+
+func TestAuthFor(t *testing.T) {
+	auths := []Auth{
+		{Domain: "example.com", Token: "parent"},
+		{Domain: "docs.example.com", Token: "docs"},
+	}
+
+	tests := []struct {
+		host  string
+		token string
+		found bool
+	}{
+		{"example.com", "parent", true},
+		{"api.example.com", "parent", true},
+		{"docs.example.com", "docs", true},
+		{"v2.docs.example.com", "docs", true},
+		{"DOCS.EXAMPLE.COM", "docs", true},
+		{"notexample.com", "", false},
+		{"example.org", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			a, ok := authFor(auths, tt.host)
+			if ok != tt.found || a.Token != tt.token {
+				t.Errorf("authFor(%q) = %q, %v, want %q, %v", tt.host, a.Token, ok, tt.token, tt.found)
+			}
+		})
+	}
+}
+
+func TestScraper_RedirectDropsForeignAuth(t *testing.T) {
+	var got http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`<html><body>Moved</body></html>`))
+	}))
+	defer other.Close()
+
+	// Redirect from 127.0.0.1 to localhost, a different host
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	s := New(Config{
+		Delay:    10 * time.Millisecond,
+		MaxDepth: 1,
+		Auth:     []Auth{{Domain: "127.0.0.1", Headers: map[string]string{"X-Api-Key": "key"}}},
+	})
+
+	if _, err := s.Scrape(t.Context(), server.URL); err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if got == nil {
+		t.Fatal("redirect target was not requested")
+	}
+	if got.Get("X-Api-Key") != "" {
+		t.Error("credentials for 127.0.0.1 were sent to localhost")
+	}
+}
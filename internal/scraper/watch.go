@@ -0,0 +1,150 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mfenderov/bam-rag/internal/markdown"
+)
+
+// DefaultWatchDebounce is how long WatchDir waits for changes to settle
+// before reporting a batch. Editors often write a file several times on save.
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+// WatchDir monitors dir recursively for markdown file changes.
+// After changes settle for the debounce interval, onChange is called with
+// the created/modified files and the removed files. Blocks until ctx is done.
+func WatchDir(ctx context.Context, dir string, debounce time.Duration, onChange func(changed, removed []string)) error {
+	if debounce <= 0 {
+		debounce = DefaultWatchDebounce
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := addWatchDirs(watcher, dir); err != nil {
+		return err
+	}
+
+	slog.Debug("watching directory", "dir", dir)
+
+	changed := make(map[string]bool)
+	removed := make(map[string]bool)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("watch error", "dir", dir, "error", err)
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !handleWatchEvent(watcher, event, changed, removed) {
+				continue
+			}
+			timer.Reset(debounce)
+
+		case <-timer.C:
+			if len(changed) == 0 && len(removed) == 0 {
+				continue
+			}
+			onChange(sortedKeys(changed), sortedKeys(removed))
+			clear(changed)
+			clear(removed)
+		}
+	}
+}
+
+// handleWatchEvent records a filesystem event in the pending change sets.
+// Returns false if the event is irrelevant.
+func handleWatchEvent(watcher *fsnotify.Watcher, event fsnotify.Event, changed, removed map[string]bool) bool {
+	if isHidden(filepath.Base(event.Name)) {
+		return false
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(event.Name)
+		if err != nil {
+			return false
+		}
+		if info.IsDir() {
+			// New directory: watch it and pick up files created before the watch was added
+			if err := addWatchDirs(watcher, event.Name); err != nil {
+				slog.Warn("failed to watch new directory", "dir", event.Name, "error", err)
+			}
+			files, _ := ListMarkdownFiles(event.Name)
+			for _, f := range files {
+				changed[f] = true
+			}
+			return len(files) > 0
+		}
+		fallthrough
+
+	case event.Has(fsnotify.Write):
+		if !markdown.IsMarkdownURL(event.Name) {
+			return false
+		}
+		changed[event.Name] = true
+		delete(removed, event.Name)
+		return true
+
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		if !markdown.IsMarkdownURL(event.Name) {
+			return false
+		}
+		removed[event.Name] = true
+		delete(changed, event.Name)
+		return true
+	}
+
+	return false
+}
+
+// addWatchDirs adds dir and all of its non-hidden subdirectories to the watcher.
+func addWatchDirs(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && isHidden(d.Name()) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// sortedKeys returns the keys of a set in sorted order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
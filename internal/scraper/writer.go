@@ -0,0 +1,224 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/internal/telemetry"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// pageWriter writes scraped pages to S3 as they arrive, several at a time,
+// so a scrape holds at most budget bytes of page content in memory. Put
+// blocks once the budget is spent, holding back the scrape until earlier
+// pages are written. Pages are written even once the scrape is cancelled,
+// so an interrupted scrape keeps what it fetched.
+type pageWriter struct {
+	ctx           context.Context
+	storageClient *storage.Client
+	prefix        string
+	budget        int64               // 0 for no limit
+	redactor      *processor.Redactor // Masks sensitive strings before pages are written; may be nil
+
+	mu     sync.Mutex // Guards the queue and the written pages below
+	cond   *sync.Cond
+	held   int64 // Bytes of queued and in-flight pages
+	closed bool
+	queue  []models.Document
+
+	workers  sync.WaitGroup
+	pageURLs []string
+	hashes   map[string]string
+	licenses map[string]string // Licenses declared by pages, by URL
+	branding []models.Document // Pages declaring a site name or favicon
+	failed   int
+
+	license    string                       // Site-wide license for pages declaring none; set once closed
+	validators map[string]storage.Validator // Validators of the written pages; set once closed
+}
+
+// newPageWriter creates a writer uploading up to uploads pages at once.
+func newPageWriter(ctx context.Context, storageClient *storage.Client, prefix string, budget int64, uploads int, redactor *processor.Redactor) *pageWriter {
+	w := &pageWriter{
+		ctx:           context.WithoutCancel(ctx),
+		storageClient: storageClient,
+		prefix:        prefix,
+		budget:        max(budget, 0),
+		redactor:      redactor,
+		hashes:        make(map[string]string),
+		licenses:      make(map[string]string),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	for range max(uploads, 1) {
+		w.workers.Add(1)
+		go w.run()
+	}
+	return w
+}
+
+// Put queues a page for writing, blocking while the budget is spent. A page
+// larger than the whole budget waits for the queue to empty.
+func (w *pageWriter) Put(doc models.Document) {
+	size := int64(len(doc.Content))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.budget > 0 && w.held > 0 && w.held+size > w.budget {
+		w.cond.Wait()
+	}
+	w.held += size
+	w.queue = append(w.queue, doc)
+	w.cond.Broadcast()
+}
+
+// Close waits for the queued pages to be written.
+func (w *pageWriter) Close() {
+	w.mu.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	w.workers.Wait()
+}
+
+// run writes queued pages, oldest first, until the writer is closed.
+func (w *pageWriter) run() {
+	defer w.workers.Done()
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		doc := w.queue[0]
+		w.queue[0] = models.Document{}
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+
+		w.write(doc)
+
+		w.mu.Lock()
+		w.held -= int64(len(doc.Content))
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// write stores one page, keeping only what the metadata needs.
+func (w *pageWriter) write(doc models.Document) {
+	// Generate filename from URL hash. HTML content is stored as-is; the
+	// ingestion engine handles conversion.
+	filename := models.GenerateDocumentID(doc.URL) + ".md"
+	content, redacted := w.redactor.Redact(doc.Content)
+	if redacted > 0 {
+		slog.Debug("redacted sensitive strings", "url", doc.URL, "count", redacted)
+	}
+	err := w.storageClient.PutMarkdown(w.ctx, w.prefix, filename, content)
+	hash := models.ContentHash(content)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		slog.Error("failed to write to S3", "url", doc.URL, "error", err)
+		w.failed++
+		return
+	}
+	w.pageURLs = append(w.pageURLs, doc.URL)
+	w.hashes[doc.URL] = hash
+	if doc.License != "" {
+		w.licenses[doc.URL] = doc.License
+	}
+	if doc.SiteName != "" || doc.Favicon != "" {
+		w.branding = append(w.branding, models.Document{URL: doc.URL, SiteName: doc.SiteName, Favicon: doc.Favicon})
+	}
+	slog.Debug("wrote page to S3", "url", doc.URL, "filename", filename)
+}
+
+// Copy copies a page the server reported unchanged from the previous
+// scrape, stored under previousPrefix with metadata previous.
+func (w *pageWriter) Copy(previousPrefix string, previous *storage.ScrapeMetadata, pageURL string) {
+	filename := models.GenerateDocumentID(pageURL) + ".md"
+	err := w.storageClient.CopyMarkdown(w.ctx, previousPrefix, w.prefix, filename)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		slog.Error("failed to copy unchanged page", "url", pageURL, "from", previousPrefix, "error", err)
+		w.failed++
+		return
+	}
+	w.pageURLs = append(w.pageURLs, pageURL)
+	if hash, ok := previous.Hashes[pageURL]; ok {
+		w.hashes[pageURL] = hash
+	}
+	if license, ok := previous.Licenses[pageURL]; ok {
+		w.licenses[pageURL] = license
+	}
+	if previous.SiteName != "" || previous.Favicon != "" {
+		w.branding = append(w.branding, models.Document{URL: pageURL, SiteName: previous.SiteName, Favicon: previous.Favicon})
+	}
+	slog.Debug("copied unchanged page", "url", pageURL, "filename", filename)
+}
+
+// finish writes the scrape metadata and the run report once the writer is
+// closed, even if ctx is cancelled. interrupted marks a scrape cancelled
+// before it fetched every page.
+func (w *pageWriter) finish(ctx context.Context, sourceURL, source string, report *storage.ScrapeReport, interrupted bool) (*ScrapeResult, error) {
+	ctx = context.WithoutCancel(ctx)
+	report.Interrupted = interrupted
+	for range w.failed {
+		report.Fail("storage")
+	}
+
+	// Write metadata
+	siteName, favicon := siteBranding(w.branding, sourceURL)
+	meta := storage.ScrapeMetadata{
+		SourceURL: sourceURL,
+		Source:    source,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		PageCount: len(w.pageURLs),
+		Pages:     w.pageURLs,
+		SiteName:  siteName,
+		Favicon:   favicon,
+		Hashes:    w.hashes,
+		Licenses:  w.licenses,
+		License:   w.license,
+
+		Validators: w.validators,
+	}
+	if err := w.storageClient.PutMetadata(ctx, w.prefix, meta); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	slog.Info("scrape to S3 complete", "url", sourceURL, "prefix", w.prefix, "pages", len(w.pageURLs))
+
+	// The report is informational; a scrape is not failed for lack of it
+	report.Pages = len(w.pageURLs)
+	report.Duration = time.Since(report.StartedAt)
+	location, err := w.storageClient.PutRunReport(ctx, storage.RunReport{
+		Prefix:    w.prefix,
+		SourceURL: sourceURL,
+		Source:    source,
+		Scrape:    report,
+	})
+	if err != nil {
+		slog.Warn("failed to write run report", "prefix", w.prefix, "error", err)
+	}
+
+	return &ScrapeResult{
+		Prefix:      w.prefix,
+		PageCount:   len(w.pageURLs),
+		SourceURL:   sourceURL,
+		Report:      location,
+		Crawl:       report,
+		Traceparent: telemetry.Traceparent(ctx),
+		Interrupted: interrupted,
+	}, nil
+}
@@ -0,0 +1,111 @@
+// Package slowops warns about single operations that take longer than a
+// configured threshold and keeps the slowest operations of a run for an
+// end-of-run summary.
+package slowops
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/progress"
+)
+
+// Op is a kind of timed operation.
+type Op string
+
+const (
+	OpFetch  Op = "fetch"  // Fetching a single page
+	OpEnrich Op = "enrich" // LLM enrichment of a document
+	OpEmbed  Op = "embed"  // A single embedding call
+	OpIndex  Op = "index"  // Indexing a document and its chunks
+)
+
+// Thresholds are the durations above which an operation is reported as
+// slow. A zero threshold disables warnings for its operation.
+type Thresholds map[Op]time.Duration
+
+// Item is a timed operation.
+type Item struct {
+	Op       Op            `json:"op"`
+	Target   string        `json:"target"` // Page URL the operation worked on
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Tracker observes operation durations. It is safe for concurrent use,
+// and a nil Tracker observes nothing.
+type Tracker struct {
+	thresholds Thresholds
+	reporter   progress.Reporter // nil if warnings are not reported
+	keep       int
+
+	mu      sync.Mutex
+	slowest []Item // Slowest first, at most keep
+}
+
+// New creates a tracker reporting operations over their threshold to
+// reporter as warnings and keeping the keep slowest operations.
+func New(thresholds Thresholds, reporter progress.Reporter, keep int) *Tracker {
+	return &Tracker{thresholds: thresholds, reporter: reporter, keep: keep}
+}
+
+// Observe records that op on target took d.
+func (t *Tracker) Observe(op Op, target string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	if limit := t.thresholds[op]; limit > 0 && d > limit && t.reporter != nil {
+		t.reporter.Report(progress.Event{
+			Type:     progress.EventWarning,
+			URL:      target,
+			Duration: d,
+			Message:  fmt.Sprintf("slow %s took %v (threshold %v): %s", op, d.Round(time.Millisecond), limit, target),
+		})
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.slowest) == t.keep && (t.keep == 0 || d <= t.slowest[len(t.slowest)-1].Duration) {
+		return
+	}
+	i, _ := slices.BinarySearchFunc(t.slowest, d, func(item Item, d time.Duration) int {
+		return cmp.Compare(d, item.Duration) // Descending order
+	})
+	t.slowest = slices.Insert(t.slowest, i, Item{Op: op, Target: target, Duration: d})
+	if len(t.slowest) > t.keep {
+		t.slowest = t.slowest[:t.keep]
+	}
+}
+
+// Since observes op on target as taking the time since start.
+func (t *Tracker) Since(op Op, target string, start time.Time) {
+	t.Observe(op, target, time.Since(start))
+}
+
+// Slowest returns the slowest operations observed, slowest first.
+func (t *Tracker) Slowest() []Item {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.slowest)
+}
+
+// Summary describes the slowest operations observed, one per line, or
+// returns "" if none were observed.
+func (t *Tracker) Summary() string {
+	items := t.Slowest()
+	if len(items) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Slowest operations:")
+	for _, item := range items {
+		fmt.Fprintf(&b, "\n  %10v  %-6s  %s", item.Duration.Round(time.Millisecond), item.Op, item.Target)
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package slowops
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/progress"
+)
+
+// recorder collects reported events.
+type recorder struct {
+	mu     sync.Mutex
+	events []progress.Event
+}
+
+func (r *recorder) Report(e progress.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestTracker_WarnsOverThreshold(t *testing.T) {
+	rec := &recorder{}
+	tracker := New(Thresholds{OpEmbed: time.Second, OpFetch: 0}, rec, 5)
+
+	tracker.Observe(OpEmbed, "https://example.com/fast", 500*time.Millisecond)
+	tracker.Observe(OpEmbed, "https://example.com/slow", 2*time.Second)
+	tracker.Observe(OpFetch, "https://example.com/unlimited", time.Hour)
+	tracker.Observe(OpIndex, "https://example.com/unset", time.Hour)
+
+	if len(rec.events) != 1 {
+		t.Fatalf("got %d warnings, want 1: %+v", len(rec.events), rec.events)
+	}
+	e := rec.events[0]
+	if e.Type != progress.EventWarning || e.URL != "https://example.com/slow" || e.Duration != 2*time.Second {
+		t.Errorf("warning = %+v", e)
+	}
+	if !strings.Contains(e.Message, "slow embed took 2s (threshold 1s)") {
+		t.Errorf("warning message = %q", e.Message)
+	}
+}
+
+func TestTracker_KeepsSlowest(t *testing.T) {
+	tracker := New(nil, nil, 3)
+	for i, d := range []time.Duration{3, 1, 5, 2, 4} {
+		tracker.Observe(OpFetch, string(rune('a'+i)), d*time.Second)
+	}
+
+	got := tracker.Slowest()
+	want := []string{"c", "e", "a"}
+	if len(got) != len(want) {
+		t.Fatalf("Slowest() = %+v, want %d items", got, len(want))
+	}
+	for i, item := range got {
+		if item.Target != want[i] {
+			t.Errorf("Slowest()[%d] = %+v, want target %s", i, item, want[i])
+		}
+	}
+
+	summary := tracker.Summary()
+	if !strings.HasPrefix(summary, "Slowest operations:") || strings.Count(summary, "\n") != 3 {
+		t.Errorf("Summary() = %q", summary)
+	}
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+	tracker.Observe(OpEnrich, "https://example.com", time.Hour)
+	if tracker.Slowest() != nil || tracker.Summary() != "" {
+		t.Error("nil tracker should observe nothing")
+	}
+}
@@ -0,0 +1,113 @@
+// Package sourcematch tells which configured source stored scrapes and
+// indexed documents came from.
+package sourcematch
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/scraper"
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+// PrefixHost returns the host segment of S3 prefixes written for a source.
+func PrefixHost(source config.Source) string {
+	if source.Path != "" {
+		return scraper.DirPrefixHost(source.Path)
+	}
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return ""
+	}
+	return scraper.PrefixHost(u)
+}
+
+// DirURL returns the URL of the directory a source starts in, with a
+// trailing slash, or "" if the source has no valid URL or path.
+func DirURL(source config.Source) string {
+	if source.Path != "" {
+		dir := source.Path
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		return strings.TrimSuffix(scraper.FileURL(dir), "/") + "/"
+	}
+	u, err := url.Parse(source.URL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	dir := u.Path
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	return u.Scheme + "://" + u.Host + strings.TrimSuffix(dir, "/") + "/"
+}
+
+// URLPrefix returns the URL prefix of the documents a source produces, for
+// matching those indexed before documents recorded their source: the
+// directory of the start page for websites, or the directory of local
+// sources. Links followed outside that directory are not matched.
+// It returns "" if the prefix also covers another of the configured
+// sources, such as a second source on the same host, since their documents
+// could not be told apart.
+func URLPrefix(source config.Source, configured []config.Source) string {
+	prefix := DirURL(source)
+	if prefix == "" {
+		return ""
+	}
+	for _, other := range configured {
+		if other.Name != source.Name && strings.HasPrefix(DirURL(other), prefix) {
+			return ""
+		}
+	}
+	return prefix
+}
+
+// Candidates returns the scrapes stored under the prefix host of any of the
+// sources, the only ones that can belong to them.
+func Candidates(sources []config.Source, scrapes []storage.ScrapeInfo) []storage.ScrapeInfo {
+	hosts := make(map[string]bool)
+	for _, source := range sources {
+		hosts[PrefixHost(source)] = true
+	}
+	var candidates []storage.ScrapeInfo
+	for _, s := range scrapes {
+		if hosts[s.Host] {
+			candidates = append(candidates, s)
+		}
+	}
+	return candidates
+}
+
+// Group returns the scrapes of each of the sources by source name, in the
+// order given. metas holds the metadata of each scrape, in the same order.
+// Scrapes that belong to none of the sources are left out.
+func Group(sources []config.Source, scrapes []storage.ScrapeInfo, metas []*storage.ScrapeMetadata) map[string][]storage.ScrapeInfo {
+	bySource := make(map[string][]storage.ScrapeInfo)
+	for i, s := range scrapes {
+		for _, source := range sources {
+			if ScrapeOf(source, s.Host, metas[i]) {
+				bySource[source.Name] = append(bySource[source.Name], s)
+			}
+		}
+	}
+	return bySource
+}
+
+// ScrapeOf reports whether the scrape stored under host with metadata meta
+// belongs to source. A scrape belongs to the source its metadata records;
+// one from before sources were recorded belongs to the source it started
+// from: the same URL, or the same directory for local sources.
+func ScrapeOf(source config.Source, host string, meta *storage.ScrapeMetadata) bool {
+	switch {
+	case meta.Source != "":
+		return meta.Source == source.Name
+	case source.Path != "":
+		return host == PrefixHost(source)
+	default:
+		return meta.SourceURL == source.URL
+	}
+}
@@ -0,0 +1,130 @@
+package sourcematch
+
+import (
+	"testing"
+
+	"github.com/mfenderov/bam-rag/internal/config"
+	"github.com/mfenderov/bam-rag/internal/scraper"
+	"github.com/mfenderov/bam-rag/internal/storage"
+)
+
+func TestURLPrefix(t *testing.T) {
+	docsDir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		configured []config.Source
+		want       string
+	}{
+		{
+			name:       "only source on its host",
+			configured: []config.Source{{Name: "go", URL: "https://go.dev/doc/"}},
+			want:       "https://go.dev/doc/",
+		},
+		{
+			name:       "start page is not a directory",
+			configured: []config.Source{{Name: "go", URL: "https://go.dev/doc/install"}},
+			want:       "https://go.dev/doc/",
+		},
+		{
+			name: "sibling directory on the same host",
+			configured: []config.Source{
+				{Name: "go", URL: "https://example.com/docs/"},
+				{Name: "other", URL: "https://example.com/docs2/"},
+			},
+			want: "https://example.com/docs/",
+		},
+		{
+			name: "shared host with a source below it",
+			configured: []config.Source{
+				{Name: "go", URL: "https://example.com/"},
+				{Name: "other", URL: "https://example.com/docs/"},
+			},
+			want: "",
+		},
+		{
+			name: "start page in the root covers the other source",
+			configured: []config.Source{
+				{Name: "go", URL: "https://example.com/docs"},
+				{Name: "other", URL: "https://example.com/docs2/"},
+			},
+			want: "",
+		},
+		{
+			name:       "local directory",
+			configured: []config.Source{{Name: "go", Path: docsDir}},
+			want:       scraper.FileURL(docsDir) + "/",
+		},
+		{
+			name:       "invalid URL",
+			configured: []config.Source{{Name: "go", URL: "://nope"}},
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := URLPrefix(tt.configured[0], tt.configured); got != tt.want {
+				t.Errorf("URLPrefix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	docsDir := t.TempDir()
+	sources := []config.Source{
+		{Name: "docs", URL: "https://example.com/docs/"},
+		{Name: "docs2", URL: "https://example.com/docs2/"},
+		{Name: "local", Path: docsDir},
+	}
+	localHost := scraper.DirPrefixHost(docsDir)
+
+	scrapes := []storage.ScrapeInfo{
+		{Prefix: "scrapes/example.com/1", Host: "example.com"},
+		{Prefix: "scrapes/example.com/2", Host: "example.com"},
+		{Prefix: "scrapes/example.com/3", Host: "example.com"},
+		{Prefix: "scrapes/example.com/4", Host: "example.com"},
+		{Prefix: "scrapes/example.com/5", Host: "example.com"},
+		{Prefix: "scrapes/" + localHost + "/1", Host: localHost},
+		{Prefix: "scrapes/go.dev/1", Host: "go.dev"},
+	}
+	metas := map[string]*storage.ScrapeMetadata{
+		"scrapes/example.com/1":       {Source: "docs", SourceURL: "https://example.com/docs/"},
+		"scrapes/example.com/2":       {Source: "docs2", SourceURL: "https://example.com/docs2/"},
+		"scrapes/example.com/3":       {SourceURL: "https://example.com/docs2/"},
+		"scrapes/example.com/4":       {SourceURL: "https://example.com/blog/"},
+		"scrapes/example.com/5":       {Source: "removed", SourceURL: "https://example.com/docs/"},
+		"scrapes/" + localHost + "/1": {SourceURL: scraper.FileURL(docsDir)},
+	}
+
+	candidates := Candidates(sources, scrapes)
+	if len(candidates) != 6 {
+		t.Fatalf("Candidates() returned %d scrapes, want 6", len(candidates))
+	}
+	candidateMetas := make([]*storage.ScrapeMetadata, len(candidates))
+	for i, s := range candidates {
+		candidateMetas[i] = metas[s.Prefix]
+	}
+
+	got := Group(sources, candidates, candidateMetas)
+	want := map[string][]string{
+		"docs":  {"scrapes/example.com/1"},
+		"docs2": {"scrapes/example.com/2", "scrapes/example.com/3"},
+		"local": {"scrapes/" + localHost + "/1"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("Group() = %+v, want sources %v", got, want)
+	}
+	for name, prefixes := range want {
+		if len(got[name]) != len(prefixes) {
+			t.Errorf("Group()[%q] = %+v, want %v", name, got[name], prefixes)
+			continue
+		}
+		for i, prefix := range prefixes {
+			if got[name][i].Prefix != prefix {
+				t.Errorf("Group()[%q][%d] = %q, want %q", name, i, got[name][i].Prefix, prefix)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// auditPrefix holds audit log objects, one NDJSON object per command run,
+// under a directory per day: audit/2024-12-04/17-30-00-abc123.ndjson.
+const auditPrefix = "audit/"
+
+// PutAuditLog writes one run's audit records, already encoded as NDJSON.
+func (c *Client) PutAuditLog(ctx context.Context, name string, data []byte) error {
+	_, err := c.minioClient.PutObject(ctx, c.bucket, c.key(auditPrefix+name), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/x-ndjson",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put audit log: %w", err)
+	}
+	return nil
+}
+
+// ListAuditLogs returns the names of audit log objects that sort after the
+// given name, e.g. a day such as "2024-12-04", newest first.
+func (c *Client) ListAuditLogs(ctx context.Context, after string) ([]string, error) {
+	var names []string
+
+	objectCh := c.minioClient.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{
+		Prefix:     c.key(auditPrefix),
+		Recursive:  true,
+		StartAfter: c.key(auditPrefix + after),
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list audit logs: %w", object.Err)
+		}
+		names = append(names, strings.TrimPrefix(object.Key, c.key(auditPrefix)))
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// GetAuditLog reads one audit log object.
+func (c *Client) GetAuditLog(ctx context.Context, name string) ([]byte, error) {
+	object, err := c.minioClient.GetObject(ctx, c.bucket, c.key(auditPrefix+name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return data, nil
+}
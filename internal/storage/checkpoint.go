@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// checkpointFile is the object under a prefix recording the progress of an
+// unfinished ingestion.
+const checkpointFile = "checkpoint.json"
+
+// Checkpoint records the files of a prefix indexed by an ingestion that has
+// not finished, so a later run can resume where it stopped.
+type Checkpoint struct {
+	Files     []string  `json:"files"` // Markdown files indexed so far
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PutCheckpoint writes a prefix's ingestion checkpoint.
+func (c *Client) PutCheckpoint(ctx context.Context, prefix string, checkpoint Checkpoint) error {
+	if err := c.putJSON(ctx, path.Join(prefix, checkpointFile), checkpoint); err != nil {
+		return fmt.Errorf("failed to put checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GetCheckpoint reads a prefix's ingestion checkpoint. Returns nil if there
+// is no unfinished ingestion.
+func (c *Client) GetCheckpoint(ctx context.Context, prefix string) (*Checkpoint, error) {
+	object, err := c.minioClient.GetObject(ctx, c.bucket, c.key(path.Join(prefix, checkpointFile)), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+// DeleteCheckpoint removes a prefix's ingestion checkpoint once the
+// ingestion has finished. Deleting a missing checkpoint is not an error.
+func (c *Client) DeleteCheckpoint(ctx context.Context, prefix string) error {
+	if err := c.minioClient.RemoveObject(ctx, c.bucket, c.key(path.Join(prefix, checkpointFile)), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
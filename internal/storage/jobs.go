@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// jobFile is the object under a prefix that tracks its ingestion job.
+const jobFile = "job.json"
+
+// JobStatus is the state of an ingestion job.
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending" // Queued or interrupted; will be run again
+	JobRunning JobStatus = "running"
+	JobFailed  JobStatus = "failed"
+	JobDone    JobStatus = "done"
+)
+
+// ErrJobChanged is returned by UpdateJob when the stored job is no longer
+// the version that was read.
+var ErrJobChanged = errors.New("job changed since it was read")
+
+// Job tracks the ingestion of one scrape prefix across attempts and processes.
+type Job struct {
+	Prefix      string    `json:"prefix"`
+	Source      string    `json:"source,omitempty"`
+	Status      JobStatus `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitzero"` // When a failed job is retried
+	Owner       string    `json:"owner,omitempty"`       // Process running the job
+	LeaseUntil  time.Time `json:"lease_until,omitzero"`  // When a running job's claim expires unless renewed
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Version string `json:"-"` // ETag of the stored job; empty if not stored yet
+}
+
+// PutJob writes a job next to the scrape it ingests.
+func (c *Client) PutJob(ctx context.Context, job Job) error {
+	if err := c.putJSON(ctx, path.Join(job.Prefix, jobFile), job); err != nil {
+		return fmt.Errorf("failed to put job: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob writes job if the stored job is still job.Version, or does not
+// exist when Version is empty, and returns it with its new version. Returns
+// ErrJobChanged if another process wrote the job in the meantime.
+func (c *Client) UpdateJob(ctx context.Context, job Job) (Job, error) {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return job, fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	opts := minio.PutObjectOptions{ContentType: "application/json"}
+	if job.Version == "" {
+		opts.SetMatchETagExcept("*")
+	} else {
+		opts.SetMatchETag(job.Version)
+	}
+	info, err := c.minioClient.PutObject(ctx, c.bucket, c.key(path.Join(job.Prefix, jobFile)), bytes.NewReader(data), int64(len(data)), opts)
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusPreconditionFailed {
+			return job, ErrJobChanged
+		}
+		return job, fmt.Errorf("failed to put job: %w", err)
+	}
+	job.Version = info.ETag
+	return job, nil
+}
+
+// GetJob reads a prefix's job. Returns nil if the prefix has no job.
+func (c *Client) GetJob(ctx context.Context, prefix string) (*Job, error) {
+	object, err := c.minioClient.GetObject(ctx, c.bucket, c.key(path.Join(prefix, jobFile)), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+
+	stat, err := object.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat job: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	job.Version = stat.ETag
+	return &job, nil
+}
+
+// ListJobs returns all jobs in the bucket, most recently updated first.
+func (c *Client) ListJobs(ctx context.Context) ([]Job, error) {
+	var jobs []Job
+
+	objectCh := c.minioClient.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{
+		Prefix:    c.key("scrapes/"),
+		Recursive: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", object.Err)
+		}
+		if path.Base(object.Key) != jobFile {
+			continue
+		}
+		job, err := c.GetJob(ctx, path.Dir(c.unkey(object.Key)))
+		if err != nil {
+			return nil, err
+		}
+		if job != nil {
+			jobs = append(jobs, *job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].UpdatedAt.After(jobs[j].UpdatedAt)
+	})
+	return jobs, nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// reportFile is the object under a prefix holding its run report.
+const reportFile = "report.json"
+
+// RunReport records what the scrape and the ingestion of a prefix did, so
+// runs can be audited after the fact. Each run fills in its own section.
+type RunReport struct {
+	Prefix    string        `json:"prefix"`
+	SourceURL string        `json:"source_url,omitempty"`
+	Source    string        `json:"source,omitempty"`
+	Scrape    *ScrapeReport `json:"scrape,omitempty"`
+	Ingest    *IngestReport `json:"ingest,omitempty"`
+}
+
+// ScrapeReport summarizes the scrape that produced a prefix.
+type ScrapeReport struct {
+	StartedAt time.Time      `json:"started_at"`
+	Duration  time.Duration  `json:"duration"`         // Nanoseconds
+	Pages     int            `json:"pages"`            // Pages written to the prefix
+	Skipped   int            `json:"skipped"`          // Pages fetched with an error status
+	Noindex   int            `json:"noindex"`          // Pages not stored because they ask not to be indexed
+	Unchanged int            `json:"unchanged"`        // Pages the server reported unchanged, copied from the previous scrape
+	Errors    map[string]int `json:"errors,omitempty"` // Failures by type, e.g. network or storage
+
+	// Interrupted is set if the scrape was cancelled before it fetched
+	// every page; the pages it fetched are kept
+	Interrupted bool `json:"interrupted,omitempty"`
+
+	// Crawl quality, for tuning depth and link rules
+	Statuses   map[int]int    `json:"statuses,omitempty"`   // Responses by HTTP status code
+	Redirects  int            `json:"redirects"`            // Redirects followed
+	Excluded   map[string]int `json:"excluded,omitempty"`   // Links not followed, by rule: external, pattern, depth, visited, or robots
+	Duplicates []Duplicate    `json:"duplicates,omitempty"` // Pages whose content repeats another page
+}
+
+// Duplicate is a page with the same content as another page of the scrape.
+type Duplicate struct {
+	URL string `json:"url"`
+	Of  string `json:"of"` // The page it repeats
+}
+
+// IngestReport summarizes the latest ingestion of a prefix. Fail and Track
+// are safe for concurrent use.
+type IngestReport struct {
+	mu sync.Mutex
+
+	StartedAt time.Time                `json:"started_at"`
+	Duration  time.Duration            `json:"duration"` // Nanoseconds
+	Documents int                      `json:"documents"`
+	Indexed   int                      `json:"indexed"`
+	Skipped   int                      `json:"skipped"`             // Not indexed without failing: oversized pages, or those not attempted after cancellation
+	Unchanged int                      `json:"unchanged"`           // Not reprocessed: already indexed with the same content
+	Resumed   int                      `json:"resumed,omitempty"`   // Not reprocessed: indexed by an interrupted earlier run
+	Remaining int                      `json:"remaining,omitempty"` // Not attempted because the run was interrupted; the next run resumes with them
+	Errors    map[string]int           `json:"errors,omitempty"`    // Failures by stage; enrich and embed failures are not fatal
+	Stages    map[string]time.Duration `json:"stages,omitempty"`    // Time spent in each stage, in nanoseconds
+	Tokens    TokenUsage               `json:"tokens"`
+}
+
+// TokenUsage counts the tokens an ingestion processed.
+type TokenUsage struct {
+	Indexed    int `json:"indexed"`    // Estimated tokens of the indexed content
+	Embedding  int `json:"embedding"`  // Input tokens reported by the embedding model
+	Prompt     int `json:"prompt"`     // Prompt tokens reported by the LLM
+	Completion int `json:"completion"` // Completion tokens reported by the LLM
+}
+
+// Fail counts a failure of the given type.
+func (r *ScrapeReport) Fail(kind string) {
+	if r.Errors == nil {
+		r.Errors = make(map[string]int)
+	}
+	r.Errors[kind]++
+}
+
+// Status counts a response with the given HTTP status code.
+func (r *ScrapeReport) Status(code int) {
+	if r.Statuses == nil {
+		r.Statuses = make(map[int]int)
+	}
+	r.Statuses[code]++
+}
+
+// Exclude counts a link not followed because of the given rule.
+func (r *ScrapeReport) Exclude(rule string) {
+	if r.Excluded == nil {
+		r.Excluded = make(map[string]int)
+	}
+	r.Excluded[rule]++
+}
+
+// Fail counts a failure in the given stage.
+func (r *IngestReport) Fail(stage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Errors == nil {
+		r.Errors = make(map[string]int)
+	}
+	r.Errors[stage]++
+}
+
+// Track adds the time since start to the given stage.
+func (r *IngestReport) Track(stage string, start time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Stages == nil {
+		r.Stages = make(map[string]time.Duration)
+	}
+	r.Stages[stage] += time.Since(start)
+}
+
+// PutRunReport writes a prefix's run report. Returns the report's location
+// as an s3:// URL.
+func (c *Client) PutRunReport(ctx context.Context, report RunReport) (string, error) {
+	objectName := path.Join(report.Prefix, reportFile)
+	if err := c.putJSON(ctx, objectName, report); err != nil {
+		return "", fmt.Errorf("failed to put run report: %w", err)
+	}
+	return fmt.Sprintf("s3://%s/%s", c.bucket, c.key(objectName)), nil
+}
+
+// GetRunReport reads a prefix's run report. Returns nil if the prefix has
+// no report.
+func (c *Client) GetRunReport(ctx context.Context, prefix string) (*RunReport, error) {
+	object, err := c.minioClient.GetObject(ctx, c.bucket, c.key(path.Join(prefix, reportFile)), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run report: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read run report: %w", err)
+	}
+
+	var report RunReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run report: %w", err)
+	}
+	return &report, nil
+}
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"path"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -20,12 +25,23 @@ type Config struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	UseSSL          bool
+	MaxObjectSize   int64 // Largest page read, in bytes; 0 for no limit
+
+	// Namespace keeps every object under namespaces/<namespace>/, so
+	// tenants sharing a bucket never see each other's scrapes. Prefixes
+	// passed to and returned by the client stay relative to it.
+	Namespace string
 }
 
+// ErrObjectTooLarge is returned for pages over the configured MaxObjectSize.
+var ErrObjectTooLarge = errors.New("object exceeds max object size")
+
 // Client wraps the MinIO/S3 client for bam-rag operations.
 type Client struct {
-	minioClient *minio.Client
-	bucket      string
+	minioClient   *minio.Client
+	bucket        string
+	root          string // Key prefix of the namespace; empty for the bucket root
+	maxObjectSize int64
 }
 
 // New creates a new S3/MinIO client.
@@ -45,10 +61,37 @@ func New(config Config) (*Client, error) {
 		return nil, fmt.Errorf("failed to create minio client: %w", err)
 	}
 
-	return &Client{
-		minioClient: minioClient,
-		bucket:      config.Bucket,
-	}, nil
+	client := &Client{
+		minioClient:   minioClient,
+		bucket:        config.Bucket,
+		maxObjectSize: config.MaxObjectSize,
+	}
+	if config.Namespace != "" {
+		client.root = "namespaces/" + config.Namespace + "/"
+	}
+	return client, nil
+}
+
+// key returns the object key of a name relative to the namespace.
+func (c *Client) key(name string) string {
+	return c.root + name
+}
+
+// unkey returns the name relative to the namespace of an object key.
+func (c *Client) unkey(key string) string {
+	return strings.TrimPrefix(key, c.root)
+}
+
+// BucketExists checks that the bucket exists and is reachable.
+func (c *Client) BucketExists(ctx context.Context) error {
+	exists, err := c.minioClient.BucketExists(ctx, c.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", c.bucket)
+	}
+	return nil
 }
 
 // EnsureBucket creates the bucket if it doesn't exist.
@@ -71,9 +114,33 @@ func (c *Client) EnsureBucket(ctx context.Context) error {
 // ScrapeMetadata holds information about a scrape operation.
 type ScrapeMetadata struct {
 	SourceURL string   `json:"source_url"`
+	Source    string   `json:"source,omitempty"` // Config source name; empty for ad-hoc --url scrapes
 	Timestamp string   `json:"timestamp"`
 	PageCount int      `json:"page_count"`
-	Pages     []string `json:"pages"` // List of page URLs scraped
+	Pages     []string `json:"pages"`               // List of page URLs scraped
+	SiteName  string   `json:"site_name,omitempty"` // og:site_name of the scraped site
+	Favicon   string   `json:"favicon,omitempty"`   // Absolute URL of the site icon
+
+	// Hashes maps page URLs to the models.ContentHash of their content.
+	// Scrapes written before hashes were recorded have none.
+	Hashes map[string]string `json:"hashes,omitempty"`
+
+	// Licenses maps page URLs to the license they declare; License is the
+	// site-wide license from llms.txt, for pages declaring none.
+	Licenses map[string]string `json:"licenses,omitempty"`
+	License  string            `json:"license,omitempty"`
+
+	// Validators maps page URLs to the validators their responses carried,
+	// so the next scrape of SourceURL can skip pages that are unchanged.
+	Validators map[string]Validator `json:"validators,omitempty"`
+}
+
+// Validator holds what a later scrape needs to revalidate a page instead
+// of fetching it again.
+type Validator struct {
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Links        []string `json:"links,omitempty"` // Links followed from the page, followed again while it is unchanged
 }
 
 // PutMarkdown writes a markdown file to S3.
@@ -81,7 +148,7 @@ func (c *Client) PutMarkdown(ctx context.Context, prefix, filename, content stri
 	objectName := path.Join(prefix, "pages", filename)
 	reader := strings.NewReader(content)
 
-	_, err := c.minioClient.PutObject(ctx, c.bucket, objectName, reader, int64(len(content)), minio.PutObjectOptions{
+	_, err := c.minioClient.PutObject(ctx, c.bucket, c.key(objectName), reader, int64(len(content)), minio.PutObjectOptions{
 		ContentType: "text/markdown",
 	})
 	if err != nil {
@@ -90,6 +157,17 @@ func (c *Client) PutMarkdown(ctx context.Context, prefix, filename, content stri
 	return nil
 }
 
+// CopyMarkdown copies a markdown file from one prefix to another within
+// the bucket, without downloading it.
+func (c *Client) CopyMarkdown(ctx context.Context, fromPrefix, toPrefix, filename string) error {
+	src := minio.CopySrcOptions{Bucket: c.bucket, Object: c.key(path.Join(fromPrefix, "pages", filename))}
+	dst := minio.CopyDestOptions{Bucket: c.bucket, Object: c.key(path.Join(toPrefix, "pages", filename))}
+	if _, err := c.minioClient.CopyObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("failed to copy markdown: %w", err)
+	}
+	return nil
+}
+
 // PutMetadata writes the scrape metadata JSON to S3.
 func (c *Client) PutMetadata(ctx context.Context, prefix string, meta ScrapeMetadata) error {
 	objectName := path.Join(prefix, "metadata.json")
@@ -100,7 +178,7 @@ func (c *Client) PutMetadata(ctx context.Context, prefix string, meta ScrapeMeta
 	}
 
 	reader := bytes.NewReader(data)
-	_, err = c.minioClient.PutObject(ctx, c.bucket, objectName, reader, int64(len(data)), minio.PutObjectOptions{
+	_, err = c.minioClient.PutObject(ctx, c.bucket, c.key(objectName), reader, int64(len(data)), minio.PutObjectOptions{
 		ContentType: "application/json",
 	})
 	if err != nil {
@@ -115,7 +193,7 @@ func (c *Client) ListMarkdownFiles(ctx context.Context, prefix string) ([]string
 	var files []string
 
 	objectCh := c.minioClient.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{
-		Prefix:    pagesPrefix,
+		Prefix:    c.key(pagesPrefix),
 		Recursive: true,
 	})
 
@@ -132,29 +210,50 @@ func (c *Client) ListMarkdownFiles(ctx context.Context, prefix string) ([]string
 	return files, nil
 }
 
-// GetMarkdown reads a markdown file from S3.
+// GetMarkdown reads a markdown file from S3. Pages larger than the
+// configured MaxObjectSize are not read and return ErrObjectTooLarge.
 func (c *Client) GetMarkdown(ctx context.Context, prefix, filename string) (string, error) {
-	objectName := path.Join(prefix, "pages", filename)
-
-	object, err := c.minioClient.GetObject(ctx, c.bucket, objectName, minio.GetObjectOptions{})
+	object, size, err := c.OpenMarkdown(ctx, prefix, filename)
 	if err != nil {
-		return "", fmt.Errorf("failed to get markdown: %w", err)
+		return "", err
 	}
 	defer object.Close()
 
-	data, err := io.ReadAll(object)
-	if err != nil {
+	var content strings.Builder
+	content.Grow(int(size))
+	if _, err := io.Copy(&content, object); err != nil {
 		return "", fmt.Errorf("failed to read markdown: %w", err)
 	}
+	return content.String(), nil
+}
 
-	return string(data), nil
+// OpenMarkdown opens a markdown file in S3 for streaming and returns its
+// size. Pages larger than the configured MaxObjectSize are not opened and
+// return ErrObjectTooLarge. The caller must close the reader.
+func (c *Client) OpenMarkdown(ctx context.Context, prefix, filename string) (io.ReadCloser, int64, error) {
+	objectName := path.Join(prefix, "pages", filename)
+
+	object, err := c.minioClient.GetObject(ctx, c.bucket, c.key(objectName), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get markdown: %w", err)
+	}
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, 0, fmt.Errorf("failed to get markdown: %w", err)
+	}
+	if c.maxObjectSize > 0 && info.Size > c.maxObjectSize {
+		object.Close()
+		return nil, 0, fmt.Errorf("%s is %d bytes, over the limit of %d: %w", filename, info.Size, c.maxObjectSize, ErrObjectTooLarge)
+	}
+	return object, info.Size, nil
 }
 
 // GetMetadata reads the scrape metadata from S3.
 func (c *Client) GetMetadata(ctx context.Context, prefix string) (*ScrapeMetadata, error) {
 	objectName := path.Join(prefix, "metadata.json")
 
-	object, err := c.minioClient.GetObject(ctx, c.bucket, objectName, minio.GetObjectOptions{})
+	object, err := c.minioClient.GetObject(ctx, c.bucket, c.key(objectName), minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get metadata: %w", err)
 	}
@@ -173,6 +272,228 @@ func (c *Client) GetMetadata(ctx context.Context, prefix string) (*ScrapeMetadat
 	return &meta, nil
 }
 
+// metadataReads is how many metadata objects GetMetadataBatch reads at once.
+const metadataReads = 8
+
+// GetMetadataBatch reads the metadata of each of the scrapes, a few at a
+// time, returning it in the same order.
+func (c *Client) GetMetadataBatch(ctx context.Context, scrapes []ScrapeInfo) ([]*ScrapeMetadata, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	metas := make([]*ScrapeMetadata, len(scrapes))
+	slots := make(chan struct{}, metadataReads)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i, s := range scrapes {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			meta, err := c.GetMetadata(ctx, s.Prefix)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", s.Prefix, err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			metas[i] = meta
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return metas, nil
+}
+
+// ScrapeInfo describes a scrape stored under the scrapes/ prefix.
+type ScrapeInfo struct {
+	Prefix    string    // e.g. "scrapes/go.dev/2024-12-04T17-30-00-abc123"
+	Host      string    // e.g. "go.dev" or "local/docs"
+	CreatedAt time.Time // When the scrape metadata was written
+	Ingested  bool      // Whether an ingestion state marker exists
+}
+
+// ListScrapes returns all scrapes in the bucket, oldest first.
+func (c *Client) ListScrapes(ctx context.Context) ([]ScrapeInfo, error) {
+	var scrapes []ScrapeInfo
+	ingested := make(map[string]bool)
+
+	objectCh := c.minioClient.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{
+		Prefix:    c.key("scrapes/"),
+		Recursive: true,
+	})
+
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", object.Err)
+		}
+		key := c.unkey(object.Key)
+		if path.Base(key) == ingestionStateFile {
+			ingested[path.Dir(key)] = true
+			continue
+		}
+		if info, ok := scrapeInfoFromKey(key); ok {
+			info.CreatedAt = object.LastModified
+			scrapes = append(scrapes, info)
+		}
+	}
+
+	for i := range scrapes {
+		scrapes[i].Ingested = ingested[scrapes[i].Prefix]
+	}
+
+	sort.Slice(scrapes, func(i, j int) bool {
+		return scrapes[i].CreatedAt.Before(scrapes[j].CreatedAt)
+	})
+
+	return scrapes, nil
+}
+
+// LatestScrape returns the prefix and metadata of the most recent scrape
+// of sourceURL stored under host, or "" and nil if there is none.
+func (c *Client) LatestScrape(ctx context.Context, host, sourceURL string) (string, *ScrapeMetadata, error) {
+	scrapes, err := c.ListScrapes(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, s := range slices.Backward(scrapes) {
+		if s.Host != host {
+			continue
+		}
+		meta, err := c.GetMetadata(ctx, s.Prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		if meta.SourceURL == sourceURL {
+			return s.Prefix, meta, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// scrapeInfoFromKey extracts the scrape prefix and host from a metadata.json key.
+func scrapeInfoFromKey(key string) (ScrapeInfo, bool) {
+	if path.Base(key) != "metadata.json" {
+		return ScrapeInfo{}, false
+	}
+	prefix := path.Dir(key)
+	host := HostFromPrefix(prefix)
+	if host == "" {
+		return ScrapeInfo{}, false
+	}
+	return ScrapeInfo{Prefix: prefix, Host: host}, true
+}
+
+// HostFromPrefix returns the host segment of a scrape prefix
+// (scrapes/<host>/<timestamp>), or "" if prefix is not a scrape prefix.
+func HostFromPrefix(prefix string) string {
+	rest, ok := strings.CutPrefix(prefix, "scrapes/")
+	if !ok {
+		return ""
+	}
+	if host := path.Dir(rest); host != "." {
+		return host
+	}
+	return ""
+}
+
+// Pending returns the scrapes that have not been ingested, preserving order.
+func Pending(scrapes []ScrapeInfo) []ScrapeInfo {
+	var pending []ScrapeInfo
+	for _, s := range scrapes {
+		if !s.Ingested {
+			pending = append(pending, s)
+		}
+	}
+	return pending
+}
+
+// LatestPerHost returns the most recent scrape for each host, ordered by host.
+func LatestPerHost(scrapes []ScrapeInfo) []ScrapeInfo {
+	latest := make(map[string]ScrapeInfo)
+	for _, s := range scrapes {
+		if cur, ok := latest[s.Host]; !ok || s.CreatedAt.After(cur.CreatedAt) {
+			latest[s.Host] = s
+		}
+	}
+
+	result := make([]ScrapeInfo, 0, len(latest))
+	for _, s := range latest {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Host < result[j].Host
+	})
+	return result
+}
+
+// ingestionStateFile is the object written under a prefix once it is ingested.
+const ingestionStateFile = "ingested.json"
+
+// IngestionState records the outcome of ingesting a scrape prefix.
+type IngestionState struct {
+	IngestedAt  string `json:"ingested_at"`
+	DocsIndexed int    `json:"docs_indexed"`
+	Errors      int    `json:"errors"`
+}
+
+// PutIngestionState marks a prefix as ingested.
+func (c *Client) PutIngestionState(ctx context.Context, prefix string, state IngestionState) error {
+	if err := c.putJSON(ctx, path.Join(prefix, ingestionStateFile), state); err != nil {
+		return fmt.Errorf("failed to put ingestion state: %w", err)
+	}
+	return nil
+}
+
+// GetIngestionState reads a prefix's ingestion state.
+// Returns nil if the prefix has not been ingested.
+func (c *Client) GetIngestionState(ctx context.Context, prefix string) (*IngestionState, error) {
+	objectName := path.Join(prefix, ingestionStateFile)
+
+	object, err := c.minioClient.GetObject(ctx, c.bucket, c.key(objectName), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingestion state: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ingestion state: %w", err)
+	}
+
+	var state IngestionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ingestion state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// putJSON writes v as an indented JSON object.
+func (c *Client) putJSON(ctx context.Context, objectName string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path.Base(objectName), err)
+	}
+
+	_, err = c.minioClient.PutObject(ctx, c.bucket, c.key(objectName), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	})
+	return err
+}
+
 // Bucket returns the bucket name.
 func (c *Client) Bucket() string {
 	return c.bucket
@@ -2,8 +2,11 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"os"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestNew_Validation(t *testing.T) {
@@ -94,6 +97,15 @@ func TestIntegration_S3Operations(t *testing.T) {
 		}
 	})
 
+	// Test GetMarkdown with a size limit
+	t.Run("GetMarkdownTooLarge", func(t *testing.T) {
+		limited := *client
+		limited.maxObjectSize = 10
+		if _, err := limited.GetMarkdown(ctx, prefix, "abc123.md"); !errors.Is(err, ErrObjectTooLarge) {
+			t.Errorf("GetMarkdown() error = %v, want ErrObjectTooLarge", err)
+		}
+	})
+
 	// Test PutMetadata
 	t.Run("PutMetadata", func(t *testing.T) {
 		meta := ScrapeMetadata{
@@ -122,6 +134,21 @@ func TestIntegration_S3Operations(t *testing.T) {
 		}
 	})
 
+	// Test GetMetadataBatch
+	t.Run("GetMetadataBatch", func(t *testing.T) {
+		metas, err := client.GetMetadataBatch(ctx, []ScrapeInfo{{Prefix: prefix}, {Prefix: prefix}})
+		if err != nil {
+			t.Fatalf("GetMetadataBatch() error = %v", err)
+		}
+		if len(metas) != 2 || metas[1].SourceURL != "https://test.example.com/docs" {
+			t.Errorf("GetMetadataBatch() = %+v", metas)
+		}
+
+		if _, err := client.GetMetadataBatch(ctx, []ScrapeInfo{{Prefix: prefix}, {Prefix: prefix + "-missing"}}); err == nil {
+			t.Error("GetMetadataBatch() with a missing scrape should fail")
+		}
+	})
+
 	// Test ListMarkdownFiles
 	t.Run("ListMarkdownFiles", func(t *testing.T) {
 		files, err := client.ListMarkdownFiles(ctx, prefix)
@@ -135,4 +162,137 @@ func TestIntegration_S3Operations(t *testing.T) {
 			t.Errorf("ListMarkdownFiles()[0] = %q, want %q", files[0], "abc123.md")
 		}
 	})
+
+	// Test PutJob and ListJobs
+	t.Run("Jobs", func(t *testing.T) {
+		job := Job{Prefix: prefix, Status: JobFailed, Attempts: 2, LastError: "boom", UpdatedAt: time.Now().UTC()}
+		if err := client.PutJob(ctx, job); err != nil {
+			t.Fatalf("PutJob() error = %v", err)
+		}
+
+		jobs, err := client.ListJobs(ctx)
+		if err != nil {
+			t.Fatalf("ListJobs() error = %v", err)
+		}
+		i := slices.IndexFunc(jobs, func(j Job) bool { return j.Prefix == prefix })
+		if i < 0 {
+			t.Fatalf("ListJobs() missing job for %s", prefix)
+		}
+		if jobs[i].Status != JobFailed || jobs[i].Attempts != 2 || jobs[i].LastError != "boom" {
+			t.Errorf("ListJobs() job = %+v", jobs[i])
+		}
+
+		// Only the first of two writers holding the same version succeeds
+		read := jobs[i]
+		read.Status = JobRunning
+		if _, err := client.UpdateJob(ctx, read); err != nil {
+			t.Fatalf("UpdateJob() error = %v", err)
+		}
+		if _, err := client.UpdateJob(ctx, read); !errors.Is(err, ErrJobChanged) {
+			t.Errorf("UpdateJob() with a stale version error = %v, want ErrJobChanged", err)
+		}
+	})
+
+	// Test PutRunReport and GetRunReport
+	t.Run("RunReport", func(t *testing.T) {
+		report := RunReport{Prefix: prefix, Scrape: &ScrapeReport{Pages: 1, Skipped: 2}}
+		report.Scrape.Fail("network")
+		report.Scrape.Status(404)
+		location, err := client.PutRunReport(ctx, report)
+		if err != nil {
+			t.Fatalf("PutRunReport() error = %v", err)
+		}
+		if want := "s3://bam-rag-test/" + prefix + "/report.json"; location != want {
+			t.Errorf("PutRunReport() = %q, want %q", location, want)
+		}
+
+		got, err := client.GetRunReport(ctx, prefix)
+		if err != nil {
+			t.Fatalf("GetRunReport() error = %v", err)
+		}
+		if got == nil || got.Scrape == nil || got.Scrape.Skipped != 2 || got.Scrape.Errors["network"] != 1 || got.Scrape.Statuses[404] != 1 || got.Ingest != nil {
+			t.Errorf("GetRunReport() = %+v", got)
+		}
+	})
+}
+
+func TestScrapeInfoFromKey(t *testing.T) {
+	tests := []struct {
+		key        string
+		wantOK     bool
+		wantPrefix string
+		wantHost   string
+	}{
+		{"scrapes/go.dev/2024-12-04T17-30-00-abc123/metadata.json", true, "scrapes/go.dev/2024-12-04T17-30-00-abc123", "go.dev"},
+		{"scrapes/local/docs/2024-12-04T17-30-00-abc123/metadata.json", true, "scrapes/local/docs/2024-12-04T17-30-00-abc123", "local/docs"},
+		{"scrapes/go.dev/2024-12-04T17-30-00-abc123/pages/abc.md", false, "", ""},
+		{"scrapes/metadata.json", false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			info, ok := scrapeInfoFromKey(tt.key)
+			if ok != tt.wantOK {
+				t.Fatalf("scrapeInfoFromKey() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if info.Prefix != tt.wantPrefix || info.Host != tt.wantHost {
+				t.Errorf("scrapeInfoFromKey() = %+v, want prefix %q host %q", info, tt.wantPrefix, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestClient_NamespaceKeys(t *testing.T) {
+	c, err := New(Config{Endpoint: "localhost:9000", Bucket: "test", Namespace: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := c.key("scrapes/go.dev/2024-12-04T17-30-00-abc123/metadata.json")
+	if key != "namespaces/team-a/scrapes/go.dev/2024-12-04T17-30-00-abc123/metadata.json" {
+		t.Errorf("key() = %q", key)
+	}
+	if got := c.unkey(key); got != "scrapes/go.dev/2024-12-04T17-30-00-abc123/metadata.json" {
+		t.Errorf("unkey() = %q, want the key relative to the namespace", got)
+	}
+
+	root, _ := New(Config{Endpoint: "localhost:9000", Bucket: "test"})
+	if got := root.key("scrapes/"); got != "scrapes/" {
+		t.Errorf("key() without a namespace = %q, want scrapes/", got)
+	}
+}
+
+func TestHostFromPrefix(t *testing.T) {
+	tests := map[string]string{
+		"scrapes/go.dev/2024-12-04T17-30-00-abc123":     "go.dev",
+		"scrapes/local/docs/2024-12-04T17-30-00-abc123": "local/docs",
+		"scrapes":       "",
+		"elsewhere/x/y": "",
+	}
+	for prefix, want := range tests {
+		if got := HostFromPrefix(prefix); got != want {
+			t.Errorf("HostFromPrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestPendingAndLatestPerHost(t *testing.T) {
+	base := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	scrapes := []ScrapeInfo{
+		{Prefix: "scrapes/go.dev/1", Host: "go.dev", CreatedAt: base, Ingested: true},
+		{Prefix: "scrapes/go.dev/2", Host: "go.dev", CreatedAt: base.Add(time.Hour)},
+		{Prefix: "scrapes/example.com/1", Host: "example.com", CreatedAt: base.Add(30 * time.Minute)},
+	}
+
+	pending := Pending(scrapes)
+	if len(pending) != 2 || pending[0].Prefix != "scrapes/go.dev/2" || pending[1].Prefix != "scrapes/example.com/1" {
+		t.Errorf("Pending() = %+v", pending)
+	}
+
+	latest := LatestPerHost(scrapes)
+	if len(latest) != 2 {
+		t.Fatalf("LatestPerHost() returned %d scrapes, want 2", len(latest))
+	}
+	if latest[0].Prefix != "scrapes/example.com/1" || latest[1].Prefix != "scrapes/go.dev/2" {
+		t.Errorf("LatestPerHost() = %+v", latest)
+	}
 }
@@ -0,0 +1,132 @@
+// Package telemetry traces scraping and ingestion with OpenTelemetry.
+//
+// Spans are created through the global OpenTelemetry API, so they are
+// no-ops until Setup installs a provider exporting them over OTLP/HTTP.
+// Trace context is propagated as W3C traceparent headers.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scope is the instrumentation scope of every span bam-rag creates.
+const scope = "github.com/mfenderov/bam-rag"
+
+// traceparentHeader is the W3C trace context header.
+const traceparentHeader = "traceparent"
+
+// Config holds trace export settings.
+type Config struct {
+	Endpoint      string            // OTLP/HTTP base URL; defaults to $OTEL_EXPORTER_OTLP_ENDPOINT
+	ServiceName   string            // Reported as service.name; default "bam-rag"
+	Headers       map[string]string // Sent with every export, e.g. for authentication
+	FlushInterval time.Duration     // How often finished spans are exported; default 5s
+
+	// SampleRatio is the share of traces recorded, between 0 and 1. Spans
+	// continuing a remote trace follow its sampling decision instead.
+	// 0 records every trace.
+	SampleRatio float64
+}
+
+// Setup installs a tracer provider exporting spans to the configured
+// endpoint and the W3C trace context propagator. Without an endpoint,
+// tracing stays disabled. The returned function flushes pending spans and
+// must be called before the process exits.
+func Setup(config Config) (shutdown func(context.Context) error, err error) {
+	provider, err := newProvider(config)
+	if err != nil || provider == nil {
+		return func(context.Context) error { return nil }, err
+	}
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// newProvider creates a tracer provider batching spans to the configured
+// endpoint, or returns nil if there is none. Failed exports are retried
+// with backoff by the exporter.
+func newProvider(config Config) (*sdktrace.TracerProvider, error) {
+	if config.Endpoint == "" {
+		config.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if config.Endpoint == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(config.Endpoint, "http://") && !strings.HasPrefix(config.Endpoint, "https://") {
+		return nil, fmt.Errorf("telemetry endpoint must be an http(s) URL: %s", config.Endpoint)
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "bam-rag"
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+	if config.SampleRatio <= 0 || config.SampleRatio > 1 {
+		config.SampleRatio = 1
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(config.Endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithHeaders(config.Headers),
+		otlptracehttp.WithTimeout(10*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(config.FlushInterval)),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(config.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	), nil
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Traceparent returns the W3C traceparent of the span in ctx, for passing
+// the trace context along with messages. Returns "" if ctx carries no span.
+func Traceparent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier.Get(traceparentHeader)
+}
+
+// WithTraceparent returns ctx with the trace context of a traceparent
+// returned by Traceparent, so spans started from it continue that trace.
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{traceparentHeader: traceparent}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// InjectHeader adds the trace context of ctx to outgoing request headers.
+func InjectHeader(ctx context.Context, header propagation.HeaderCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, header)
+}
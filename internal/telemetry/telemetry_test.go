@@ -0,0 +1,174 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProvider_Export(t *testing.T) {
+	var got coltracepb.ExportTraceServiceRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %q, want /v1/traces", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, &got); err != nil {
+			t.Errorf("failed to decode export: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	provider, err := newProvider(Config{
+		Endpoint:    srv.URL,
+		ServiceName: "bam-rag-test",
+		Headers:     map[string]string{"Authorization": "Bearer secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := provider.Tracer(scope)
+
+	ctx, parent := tracer.Start(context.Background(), "ingest", trace.WithAttributes(attribute.String("prefix", "scrapes/go.dev/x")))
+	_, child := tracer.Start(ctx, "llm.enrich")
+	End(child, errors.New("model unavailable"))
+	End(parent, nil)
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want configured header", auth)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("got %+v, want one resource with one scope", &got)
+	}
+	if attrs := got.ResourceSpans[0].Resource.Attributes; len(attrs) == 0 || attrs[0].Value.GetStringValue() != "bam-rag-test" {
+		t.Errorf("resource attributes = %+v, want service.name", attrs)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	// Spans are exported in the order they ended
+	enrich, ingest := spans[0], spans[1]
+	if enrich.Name != "llm.enrich" || ingest.Name != "ingest" {
+		t.Fatalf("span names = %q, %q", enrich.Name, ingest.Name)
+	}
+	if !bytes.Equal(enrich.TraceId, ingest.TraceId) {
+		t.Errorf("child trace ID = %x, want %x", enrich.TraceId, ingest.TraceId)
+	}
+	if !bytes.Equal(enrich.ParentSpanId, ingest.SpanId) {
+		t.Errorf("child parent ID = %x, want %x", enrich.ParentSpanId, ingest.SpanId)
+	}
+	if len(ingest.ParentSpanId) != 0 {
+		t.Errorf("root parent ID = %x, want none", ingest.ParentSpanId)
+	}
+	if enrich.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || enrich.Status.GetMessage() != "model unavailable" {
+		t.Errorf("child status = %+v, want error", enrich.Status)
+	}
+	if len(enrich.Events) != 1 || enrich.Events[0].Name != "exception" {
+		t.Errorf("child events = %+v, want one exception", enrich.Events)
+	}
+	if ingest.Status.GetCode() != tracepb.Status_STATUS_CODE_UNSET {
+		t.Errorf("root status = %+v, want unset", ingest.Status)
+	}
+	if len(ingest.Attributes) != 1 || ingest.Attributes[0].Key != "prefix" {
+		t.Errorf("root attributes = %+v, want prefix", ingest.Attributes)
+	}
+}
+
+func TestProvider_Sampling(t *testing.T) {
+	provider, err := newProvider(Config{Endpoint: "http://localhost:4318", SampleRatio: 0.000001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Shutdown(context.Background())
+	tracer := provider.Tracer(scope)
+
+	var sampled int
+	for range 100 {
+		_, span := tracer.Start(context.Background(), "search")
+		if span.SpanContext().IsSampled() {
+			sampled++
+		}
+		span.End()
+	}
+	if sampled > 1 {
+		t.Errorf("%d of 100 root spans sampled at a ratio of 0.000001", sampled)
+	}
+
+	// A remote parent's decision is followed
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	_, span := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "ingest")
+	defer span.End()
+	if !span.SpanContext().IsSampled() {
+		t.Error("span of a sampled remote trace was not sampled")
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+
+	if got := Traceparent(context.Background()); got != "" {
+		t.Errorf("Traceparent() without span = %q, want empty", got)
+	}
+
+	ctx, span := provider.Tracer(scope).Start(context.Background(), "scrape.s3")
+	defer span.End()
+
+	traceparent := Traceparent(ctx)
+	if traceparent == "" {
+		t.Fatal("Traceparent() = empty, want trace context")
+	}
+
+	remote := trace.SpanContextFromContext(WithTraceparent(context.Background(), traceparent))
+	if remote.TraceID() != span.SpanContext().TraceID() || remote.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("WithTraceparent() = %v, want span context of %v", remote, span.SpanContext())
+	}
+	if !remote.IsRemote() {
+		t.Error("WithTraceparent() span context is not remote")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+
+	shutdown, err := Setup(Config{})
+	if err != nil {
+		t.Fatalf("Setup() without endpoint error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+
+	if _, err := Setup(Config{Endpoint: "localhost:4318"}); err == nil {
+		t.Error("Setup() with schemeless endpoint error = nil, want error")
+	}
+}
@@ -0,0 +1,272 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Key is a decoded keypress.
+type Key struct {
+	Rune rune    // Printable character, 0 for special keys
+	Code KeyCode // Special key, KeyNone for printable characters
+}
+
+// KeyCode identifies a special (non-printable) key.
+type KeyCode int
+
+const (
+	KeyNone KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyBackspace
+	KeyEnter
+	KeyEscape
+	KeyCtrlC
+	KeyCtrlU
+)
+
+// ANSI styles used when rendering.
+const (
+	styleReset   = "\033[0m"
+	styleBold    = "\033[1m"
+	styleDim     = "\033[2m"
+	styleReverse = "\033[7m"
+	styleCyan    = "\033[36m"
+)
+
+// model holds the search UI state. It is updated by keys and search results
+// and rendered to a string; it performs no I/O itself.
+type model struct {
+	query    string
+	results  []models.Document
+	selected int
+	scroll   int // Preview scroll offset in lines
+	status   string
+}
+
+// update applies a keypress. Returns true if the query changed and a new
+// search should be issued, and quit=true if the UI should exit.
+func (m *model) update(k Key) (queryChanged, quit bool) {
+	switch k.Code {
+	case KeyCtrlC, KeyEscape:
+		return false, true
+	case KeyUp:
+		if m.selected > 0 {
+			m.selected--
+			m.scroll = 0
+		}
+	case KeyDown:
+		if m.selected < len(m.results)-1 {
+			m.selected++
+			m.scroll = 0
+		}
+	case KeyPageDown:
+		m.scroll += 10
+	case KeyPageUp:
+		m.scroll = max(m.scroll-10, 0)
+	case KeyBackspace:
+		if m.query != "" {
+			_, size := utf8.DecodeLastRuneInString(m.query)
+			m.query = m.query[:len(m.query)-size]
+			return true, false
+		}
+	case KeyCtrlU:
+		if m.query != "" {
+			m.query = ""
+			return true, false
+		}
+	case KeyNone:
+		if k.Rune != 0 {
+			m.query += string(k.Rune)
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// setResults replaces the result list and resets the selection.
+func (m *model) setResults(docs []models.Document, err error) {
+	m.results = docs
+	m.selected = 0
+	m.scroll = 0
+	switch {
+	case err != nil:
+		m.status = "error: " + err.Error()
+	case m.query == "":
+		m.status = ""
+	default:
+		m.status = fmt.Sprintf("%d results", len(docs))
+	}
+}
+
+// view renders the full screen for the given terminal size.
+// Lines are separated by \r\n so the output works in raw mode.
+func (m *model) view(width, height int) string {
+	if width < 20 || height < 5 {
+		return "terminal too small"
+	}
+
+	listWidth := width * 2 / 5
+	previewWidth := width - listWidth - 3
+	bodyHeight := height - 3
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&b, "%sSearch:%s %s\r\n", styleBold, styleReset, m.query)
+	fmt.Fprintf(&b, "%s%s%s\r\n", styleDim, truncate(m.status+"  (↑/↓ select, PgUp/PgDn scroll, Esc quit)", width), styleReset)
+
+	list := m.listLines(listWidth, bodyHeight)
+	preview := m.previewLines(previewWidth, bodyHeight)
+
+	for i := range bodyHeight {
+		left := ""
+		if i < len(list) {
+			left = list[i]
+		}
+		right := ""
+		if i < len(preview) {
+			right = preview[i]
+		}
+		b.WriteString(left)
+		b.WriteString(strings.Repeat(" ", max(listWidth-visibleLen(left), 0)))
+		fmt.Fprintf(&b, " %s│%s %s\r\n", styleDim, styleReset, right)
+	}
+
+	return b.String()
+}
+
+// listLines renders the result list, keeping the selection visible.
+func (m *model) listLines(width, height int) []string {
+	start := 0
+	if m.selected >= height {
+		start = m.selected - height + 1
+	}
+
+	var lines []string
+	for i := start; i < len(m.results) && len(lines) < height; i++ {
+		title := m.results[i].Title
+		if title == "" {
+			title = m.results[i].URL
+		}
+		line := truncate(fmt.Sprintf("%2d. %s", i+1, title), width)
+		if i == m.selected {
+			line = styleReverse + line + strings.Repeat(" ", width-visibleLen(line)) + styleReset
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// previewLines renders the selected document starting at the scroll offset.
+func (m *model) previewLines(width, height int) []string {
+	if len(m.results) == 0 {
+		return nil
+	}
+	doc := m.results[m.selected]
+
+	lines := []string{
+		styleBold + truncate(doc.Title, width) + styleReset,
+		styleCyan + truncate(doc.URL, width) + styleReset,
+		"",
+	}
+	lines = append(lines, renderMarkdown(doc.Content, width)...)
+
+	if m.scroll >= len(lines) {
+		m.scroll = max(len(lines)-1, 0)
+	}
+	lines = lines[m.scroll:]
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lines
+}
+
+// renderMarkdown renders markdown as styled, wrapped terminal lines.
+// Headings are bold, code blocks are dimmed, everything else is wrapped text.
+func renderMarkdown(content string, width int) []string {
+	var lines []string
+	inCode := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+
+		switch {
+		case inCode:
+			lines = append(lines, styleDim+truncate("  "+line, width)+styleReset)
+		case strings.HasPrefix(trimmed, "#"):
+			heading := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			for _, w := range wrap(heading, width) {
+				lines = append(lines, styleBold+w+styleReset)
+			}
+		default:
+			lines = append(lines, wrap(line, width)...)
+		}
+	}
+
+	return lines
+}
+
+// wrap splits text into lines of at most width runes, breaking at spaces when possible.
+func wrap(text string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	if text == "" {
+		return []string{""}
+	}
+
+	var lines []string
+	runes := []rune(text)
+	for len(runes) > width {
+		cut := width
+		for i := width; i > width/2; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+	}
+	return append(lines, string(runes))
+}
+
+// truncate shortens text to at most width runes, adding an ellipsis when cut.
+func truncate(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string([]rune(text)[:width])
+	}
+	return string([]rune(text)[:width-1]) + "…"
+}
+
+// visibleLen returns the display length of s, ignoring ANSI escape sequences.
+func visibleLen(s string) int {
+	n := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case r == '\033':
+			inEscape = true
+		case inEscape:
+			if r == 'm' {
+				inEscape = false
+			}
+		default:
+			n++
+		}
+	}
+	return n
+}
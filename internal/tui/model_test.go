@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+func TestModel_UpdateQuery(t *testing.T) {
+	m := &model{}
+
+	for _, r := range "gö" {
+		if changed, _ := m.update(Key{Rune: r}); !changed {
+			t.Errorf("typing %q should change the query", r)
+		}
+	}
+	if m.query != "gö" {
+		t.Errorf("query = %q, want %q", m.query, "gö")
+	}
+
+	m.update(Key{Code: KeyBackspace})
+	if m.query != "g" {
+		t.Errorf("query after backspace = %q, want %q", m.query, "g")
+	}
+
+	m.update(Key{Code: KeyCtrlU})
+	if m.query != "" {
+		t.Errorf("query after Ctrl+U = %q, want empty", m.query)
+	}
+
+	if changed, _ := m.update(Key{Code: KeyBackspace}); changed {
+		t.Error("backspace on empty query should not trigger a search")
+	}
+
+	if _, quit := m.update(Key{Code: KeyEscape}); !quit {
+		t.Error("Esc should quit")
+	}
+}
+
+func TestModel_Selection(t *testing.T) {
+	m := &model{query: "install"}
+	m.setResults([]models.Document{{Title: "A"}, {Title: "B"}}, nil)
+
+	if m.status != "2 results" {
+		t.Errorf("status = %q, want %q", m.status, "2 results")
+	}
+
+	m.update(Key{Code: KeyDown})
+	m.update(Key{Code: KeyDown})
+	if m.selected != 1 {
+		t.Errorf("selected = %d, want 1 (clamped)", m.selected)
+	}
+
+	m.update(Key{Code: KeyUp})
+	m.update(Key{Code: KeyUp})
+	if m.selected != 0 {
+		t.Errorf("selected = %d, want 0 (clamped)", m.selected)
+	}
+}
+
+func TestModel_View(t *testing.T) {
+	m := &model{query: "install"}
+	m.setResults([]models.Document{
+		{Title: "Installation Guide", URL: "https://example.com/install", Content: "# Install\n\nRun go install."},
+		{Title: "Config", URL: "https://example.com/config"},
+	}, nil)
+
+	out := m.view(80, 10)
+
+	for _, want := range []string{"install", "Installation Guide", "https://example.com/install", "Run go install."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view should contain %q", want)
+		}
+	}
+	if strings.Count(out, "\r\n") != 10-1 {
+		t.Errorf("view should render %d lines, got %d", 10-1, strings.Count(out, "\r\n"))
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	content := "# Title\n\nSome text.\n\n```go\nfmt.Println()\n```"
+	lines := renderMarkdown(content, 40)
+
+	if lines[0] != styleBold+"Title"+styleReset {
+		t.Errorf("heading = %q, want bold Title", lines[0])
+	}
+	joined := strings.Join(lines, "\n")
+	if strings.Contains(joined, "```") {
+		t.Error("code fences should not be rendered")
+	}
+	if !strings.Contains(joined, styleDim+"  fmt.Println()"+styleReset) {
+		t.Error("code should be dimmed and indented")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap("the quick brown fox jumps", 10)
+	for _, l := range lines {
+		if len([]rune(l)) > 10 {
+			t.Errorf("line %q exceeds width", l)
+		}
+	}
+	if strings.Join(lines, " ") != "the quick brown fox jumps" {
+		t.Errorf("wrap lost text: %q", lines)
+	}
+}
+
+func TestDecodeKey(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Key
+	}{
+		{"a", Key{Rune: 'a'}},
+		{"\x1b[A", Key{Code: KeyUp}},
+		{"\x1b[B", Key{Code: KeyDown}},
+		{"\x1b[6~", Key{Code: KeyPageDown}},
+		{"\x7f", Key{Code: KeyBackspace}},
+		{"\x03", Key{Code: KeyCtrlC}},
+		{"\x1b", Key{Code: KeyEscape}},
+	}
+
+	for _, tt := range tests {
+		got, err := decodeKey(bufio.NewReader(strings.NewReader(tt.input)))
+		if err != nil {
+			t.Fatalf("decodeKey(%q) error = %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("decodeKey(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
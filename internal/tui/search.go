@@ -0,0 +1,175 @@
+package tui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+	"golang.org/x/term"
+)
+
+// SearchFunc runs a query and returns matching documents.
+type SearchFunc func(ctx context.Context, query string) ([]models.Document, error)
+
+// debounce is how long typing must pause before a search is issued.
+const debounce = 200 * time.Millisecond
+
+// searchResult is a completed search, tagged with the query it answers.
+type searchResult struct {
+	query string
+	docs  []models.Document
+	err   error
+}
+
+// RunSearch runs the interactive search UI on the given terminal until the
+// user quits or ctx is cancelled. initialQuery is searched immediately.
+func RunSearch(ctx context.Context, in, out *os.File, search SearchFunc, initialQuery string) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("interactive search requires a terminal")
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	defer term.Restore(fd, oldState)
+
+	// Alternate screen buffer; restored on exit so the shell scrollback is untouched
+	fmt.Fprint(out, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(out, "\033[?25h\033[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan Key)
+	go readKeys(ctx, in, keys)
+
+	results := make(chan searchResult, 1)
+	runSearch := func(query string) {
+		go func() {
+			if query == "" {
+				results <- searchResult{query: query}
+				return
+			}
+			docs, err := search(ctx, query)
+			results <- searchResult{query: query, docs: docs, err: err}
+		}()
+	}
+
+	m := &model{query: initialQuery}
+	timer := time.NewTimer(debounce)
+	if initialQuery == "" {
+		timer.Stop()
+	}
+
+	redraw := func() {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		fmt.Fprint(out, m.view(width, height))
+	}
+	redraw()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			changed, quit := m.update(k)
+			if quit {
+				return nil
+			}
+			if changed {
+				m.status = "searching..."
+				timer.Reset(debounce)
+			}
+			redraw()
+
+		case <-timer.C:
+			runSearch(m.query)
+
+		case r := <-results:
+			// Drop results for queries the user has already typed past
+			if r.query != m.query {
+				continue
+			}
+			m.setResults(r.docs, r.err)
+			redraw()
+		}
+	}
+}
+
+// readKeys decodes keypresses from a raw-mode terminal until ctx is done.
+func readKeys(ctx context.Context, in io.Reader, keys chan<- Key) {
+	defer close(keys)
+	r := bufio.NewReader(in)
+
+	for {
+		k, err := decodeKey(r)
+		if err != nil {
+			return
+		}
+		select {
+		case keys <- k:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// decodeKey reads one keypress, translating common escape sequences.
+func decodeKey(r *bufio.Reader) (Key, error) {
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch ch {
+	case 3:
+		return Key{Code: KeyCtrlC}, nil
+	case 21:
+		return Key{Code: KeyCtrlU}, nil
+	case '\r', '\n':
+		return Key{Code: KeyEnter}, nil
+	case 127, 8:
+		return Key{Code: KeyBackspace}, nil
+	case 27:
+		// A lone ESC has nothing buffered after it; arrows arrive as ESC [ X
+		if r.Buffered() == 0 {
+			return Key{Code: KeyEscape}, nil
+		}
+		if next, _ := r.Peek(1); len(next) == 0 || next[0] != '[' {
+			return Key{Code: KeyEscape}, nil
+		}
+		r.ReadByte()
+		code, _ := r.ReadByte()
+		switch code {
+		case 'A':
+			return Key{Code: KeyUp}, nil
+		case 'B':
+			return Key{Code: KeyDown}, nil
+		case '5':
+			r.ReadByte() // trailing ~
+			return Key{Code: KeyPageUp}, nil
+		case '6':
+			r.ReadByte() // trailing ~
+			return Key{Code: KeyPageDown}, nil
+		}
+		return Key{}, nil
+	}
+
+	if ch < 32 {
+		return Key{}, nil
+	}
+	return Key{Rune: ch}, nil
+}
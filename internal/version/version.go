@@ -0,0 +1,84 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Build metadata, set at link time:
+//
+//	go build -ldflags "-X github.com/mfenderov/bam-rag/internal/version.Version=v1.2.3 \
+//	  -X github.com/mfenderov/bam-rag/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/mfenderov/bam-rag/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	Date            string `json:"date"`
+	GoVersion       string `json:"go_version"`
+	Platform        string `json:"platform"`
+	ESClientVersion string `json:"es_client_version"`
+}
+
+// Get returns build metadata. Values not set via ldflags fall back to the
+// VCS information the Go toolchain embeds (go build in a git checkout, or
+// go install of a tagged module).
+func Get() Info {
+	info := Info{
+		Version:         Version,
+		Commit:          Commit,
+		Date:            Date,
+		GoVersion:       runtime.Version(),
+		Platform:        runtime.GOOS + "/" + runtime.GOARCH,
+		ESClientVersion: elasticsearch.Version,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+
+	modified := false
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+				if len(info.Commit) > 12 {
+					info.Commit = info.Commit[:12]
+				}
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified && Commit == "" && info.Commit != "" {
+		info.Commit += "-dirty"
+	}
+
+	return info
+}
+
+// String returns the short version string, e.g. "v1.2.3 (abc123)".
+func (i Info) String() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	return i.Version + " (" + i.Commit + ")"
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Push is a GitHub push: the files it changed on a branch of a repository,
+// relative to the repository root.
+type Push struct {
+	Repo    string    // owner/name
+	Branch  string    // Branch pushed to
+	Changed []string  // Files added or modified
+	Removed []string  // Files deleted
+	Pushed  time.Time // When the branch was pushed
+}
+
+// githubPush is the part of a GitHub push delivery a Push is read from.
+type githubPush struct {
+	Ref        string `json:"ref"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		PushedAt int64  `json:"pushed_at"` // Unix time
+	} `json:"repository"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+}
+
+// ParsePush reads a GitHub push delivery. Files changed by several commits
+// are reported once, as their last commit left them. Returns false for
+// pushes that change no branch files, such as tag pushes and branch
+// deletions.
+func ParsePush(body []byte) (Push, bool, error) {
+	var delivery githubPush
+	if err := json.Unmarshal(body, &delivery); err != nil {
+		return Push{}, false, fmt.Errorf("invalid push payload: %w", err)
+	}
+	branch, ok := strings.CutPrefix(delivery.Ref, "refs/heads/")
+	if !ok || delivery.Deleted {
+		return Push{}, false, nil
+	}
+
+	// Later commits override earlier ones
+	state := make(map[string]bool) // File to whether it still exists
+	for _, commit := range delivery.Commits {
+		for _, file := range slices.Concat(commit.Added, commit.Modified) {
+			state[file] = true
+		}
+		for _, file := range commit.Removed {
+			state[file] = false
+		}
+	}
+
+	push := Push{
+		Repo:   delivery.Repository.FullName,
+		Branch: branch,
+		Pushed: time.Unix(delivery.Repository.PushedAt, 0),
+	}
+	for _, file := range slices.Sorted(maps.Keys(state)) {
+		if state[file] {
+			push.Changed = append(push.Changed, file)
+		} else {
+			push.Removed = append(push.Removed, file)
+		}
+	}
+	return push, len(state) > 0, nil
+}
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxTriggerBytes bounds the body of a request, as GitHub bounds its
+// deliveries.
+const maxTriggerBytes = 25 << 20
+
+// ErrQueueFull is returned by an enqueue function that cannot take more
+// work; the sender is told to retry later.
+var ErrQueueFull = errors.New("trigger queue is full")
+
+// maxRequestAge is how far the time a request was sent may be from now
+// for it to be accepted. Signatures accepted within it are remembered, so
+// a captured request cannot be replayed.
+const maxRequestAge = 5 * time.Minute
+
+// now returns the current time; tests replace it.
+var now = time.Now
+
+// Trigger is the body of a request to refresh the corpus: the name of a
+// configured source, or a URL under one, to scrape and ingest again.
+type Trigger struct {
+	Source string `json:"source,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Receiver returns a handler for POST /trigger, which passes signed
+// triggers to enqueue and answers 202 Accepted once they are queued.
+// Requests must carry the Unix time they were sent in X-BamRag-Timestamp,
+// and X-BamRag-Signature as SignTrigger computes it over that time and the
+// raw body with the secret. Requests sent more than five minutes from now,
+// and signatures already accepted, get 401. Triggers enqueue rejects get
+// 422, or 503 for ErrQueueFull.
+//
+// With a push function, POST /github takes GitHub webhook deliveries
+// signed with the same secret and passes push events that change branch
+// files to it, answering likewise; the time of the push stands in for
+// the timestamp. Other events are acknowledged and ignored.
+//
+// secret is called for every request, so a changed secret applies at
+// once; while it returns "", every request is rejected.
+func Receiver(secret func() string, enqueue func(Trigger) error, push func(Push) error) http.Handler {
+	seen := &replays{seen: make(map[string]time.Time)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /trigger", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readBody(w, r)
+		if !ok {
+			return
+		}
+		timestamp := r.Header.Get("X-BamRag-Timestamp")
+		sent, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			http.Error(w, "missing or invalid X-BamRag-Timestamp", http.StatusUnauthorized)
+			return
+		}
+		signature := r.Header.Get("X-BamRag-Signature")
+		if !Verify(secret(), []byte(timestamp+"."+string(body)), signature) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var trigger Trigger
+		if err := json.Unmarshal(body, &trigger); err != nil {
+			http.Error(w, fmt.Sprintf("invalid trigger: %v", err), http.StatusBadRequest)
+			return
+		}
+		if (trigger.Source == "") == (trigger.URL == "") {
+			http.Error(w, "invalid trigger: set one of source and url", http.StatusBadRequest)
+			return
+		}
+		if !seen.claim(w, signature, time.Unix(sent, 0)) {
+			return
+		}
+
+		slog.Debug("trigger received", "source", trigger.Source, "url", trigger.URL)
+		seen.accept(w, signature, enqueue(trigger))
+	})
+
+	if push != nil {
+		mux.HandleFunc("POST /github", func(w http.ResponseWriter, r *http.Request) {
+			body, ok := readBody(w, r)
+			if !ok {
+				return
+			}
+			signature := r.Header.Get("X-Hub-Signature-256")
+			if !Verify(secret(), body, signature) {
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+			if event := r.Header.Get("X-GitHub-Event"); event != "push" {
+				fmt.Fprintf(w, "ignored %s event\n", event)
+				return
+			}
+
+			p, ok, err := ParsePush(body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if !ok {
+				fmt.Fprintln(w, "ignored push without branch file changes")
+				return
+			}
+			if !seen.claim(w, signature, p.Pushed) {
+				return
+			}
+
+			slog.Debug("github push received", "repo", p.Repo, "branch", p.Branch, "changed", len(p.Changed), "removed", len(p.Removed))
+			seen.accept(w, signature, push(p))
+		})
+	}
+	return mux
+}
+
+// readBody returns the body of r, and answers the request with an error
+// if it cannot be read.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTriggerBytes))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	return body, true
+}
+
+// replays holds the signatures of requests accepted within maxRequestAge.
+type replays struct {
+	mu   sync.Mutex
+	seen map[string]time.Time // Signature to when its request expires
+}
+
+// claim records the signature of a request sent at sent, and answers the
+// request with an error if it was sent too far from now or its signature
+// was already recorded.
+func (r *replays) claim(w http.ResponseWriter, signature string, sent time.Time) bool {
+	t := now()
+	if d := t.Sub(sent); d > maxRequestAge || d < -maxRequestAge {
+		http.Error(w, "request expired: its timestamp is more than 5 minutes from now", http.StatusUnauthorized)
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	maps.DeleteFunc(r.seen, func(_ string, expires time.Time) bool {
+		return t.After(expires)
+	})
+	if _, ok := r.seen[signature]; ok {
+		http.Error(w, "request replayed", http.StatusUnauthorized)
+		return false
+	}
+	r.seen[signature] = sent.Add(maxRequestAge)
+	return true
+}
+
+// accept answers a request whose work was queued with err. A request
+// that was not queued is forgotten, so the sender can retry it.
+func (r *replays) accept(w http.ResponseWriter, signature string, err error) {
+	if err != nil {
+		r.mu.Lock()
+		delete(r.seen, signature)
+		r.mu.Unlock()
+	}
+
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// SignTrigger returns the X-BamRag-Signature value of a trigger sent at
+// timestamp, a Unix time in seconds: the signature Sign computes over the
+// timestamp, a dot, and the body.
+func SignTrigger(secret string, timestamp int64, body []byte) string {
+	return Sign(secret, []byte(strconv.FormatInt(timestamp, 10)+"."+string(body)))
+}
+
+// Verify reports whether signature is the signature Sign computes over
+// message for secret. An empty secret verifies nothing.
+func Verify(secret string, message []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, message)), []byte(signature))
+}
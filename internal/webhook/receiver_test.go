@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceiver(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		signature  string        // Defaults to the body's signature
+		age        time.Duration // How long ago the request was sent
+		enqueueErr error
+		wantStatus int
+		want       Trigger
+	}{
+		{name: "queues source", body: `{"source":"go-docs"}`, wantStatus: http.StatusAccepted, want: Trigger{Source: "go-docs"}},
+		{name: "queues url", body: `{"url":"https://go.dev/doc/"}`, wantStatus: http.StatusAccepted, want: Trigger{URL: "https://go.dev/doc/"}},
+		{name: "rejects bad signature", body: `{"source":"go-docs"}`, signature: "sha256=00", wantStatus: http.StatusUnauthorized},
+		{name: "rejects body signature", body: `{"source":"go-docs"}`, signature: Sign("s3cret", []byte(`{"source":"go-docs"}`)), wantStatus: http.StatusUnauthorized},
+		{name: "rejects expired request", body: `{"source":"go-docs"}`, age: 10 * time.Minute, wantStatus: http.StatusUnauthorized},
+		{name: "rejects future request", body: `{"source":"go-docs"}`, age: -10 * time.Minute, wantStatus: http.StatusUnauthorized},
+		{name: "tolerates clock skew", body: `{"source":"go-docs"}`, age: -time.Minute, wantStatus: http.StatusAccepted, want: Trigger{Source: "go-docs"}},
+		{name: "rejects empty trigger", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "rejects source and url", body: `{"source":"a","url":"https://a"}`, wantStatus: http.StatusBadRequest},
+		{name: "reports unknown source", body: `{"source":"nope"}`, enqueueErr: errors.New(`source "nope" not found`), wantStatus: http.StatusUnprocessableEntity},
+		{name: "reports full queue", body: `{"source":"go-docs"}`, enqueueErr: ErrQueueFull, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Trigger
+			handler := Receiver(secret("s3cret"), func(trigger Trigger) error {
+				got = trigger
+				return tt.enqueueErr
+			}, nil)
+
+			sent := time.Now().Add(-tt.age).Unix()
+			signature := tt.signature
+			if signature == "" {
+				signature = SignTrigger("s3cret", sent, []byte(tt.body))
+			}
+			rec := postTrigger(handler, tt.body, sent, signature)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusAccepted && got != tt.want {
+				t.Errorf("enqueued %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceiver_Replay(t *testing.T) {
+	enqueueErr := ErrQueueFull
+	var queued int
+	handler := Receiver(secret("s3cret"), func(Trigger) error {
+		if enqueueErr != nil {
+			return enqueueErr
+		}
+		queued++
+		return nil
+	}, nil)
+
+	body := `{"source":"go-docs"}`
+	sent := time.Now().Unix()
+	signature := SignTrigger("s3cret", sent, []byte(body))
+
+	// A request that was not queued may be sent again
+	if rec := postTrigger(handler, body, sent, signature); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusServiceUnavailable, rec.Body)
+	}
+	enqueueErr = nil
+	if rec := postTrigger(handler, body, sent, signature); rec.Code != http.StatusAccepted {
+		t.Fatalf("retried status = %d, want %d (%s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	if rec := postTrigger(handler, body, sent, signature); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed status = %d, want %d (%s)", rec.Code, http.StatusUnauthorized, rec.Body)
+	}
+	if queued != 1 {
+		t.Errorf("queued %d triggers, want 1", queued)
+	}
+
+	// The same trigger sent again later is signed differently
+	if rec := postTrigger(handler, body, sent+1, SignTrigger("s3cret", sent+1, []byte(body))); rec.Code != http.StatusAccepted {
+		t.Errorf("resent status = %d, want %d (%s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+}
+
+func TestReceiver_Secret(t *testing.T) {
+	key := ""
+	handler := Receiver(func() string { return key }, func(Trigger) error { return nil }, nil)
+
+	body := `{"source":"go-docs"}`
+	sent := time.Now().Unix()
+	if rec := postTrigger(handler, body, sent, SignTrigger("", sent, []byte(body))); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status without a secret = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// A changed secret applies to the next request
+	key = "rotated"
+	if rec := postTrigger(handler, body, sent, SignTrigger("rotated", sent, []byte(body))); rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d (%s)", rec.Code, http.StatusAccepted, rec.Body)
+	}
+}
+
+// secret returns a Receiver secret function returning key.
+func secret(key string) func() string {
+	return func() string { return key }
+}
+
+// postTrigger sends a trigger request with body to handler.
+func postTrigger(handler http.Handler, body string, sent int64, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/trigger", strings.NewReader(body))
+	req.Header.Set("X-BamRag-Timestamp", strconv.FormatInt(sent, 10))
+	req.Header.Set("X-BamRag-Signature", signature)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReceiver_GitHub(t *testing.T) {
+	pushed := time.Now().Unix()
+	push := `{"ref":"refs/heads/main","repository":{"full_name":"acme/docs","pushed_at":` + strconv.FormatInt(pushed, 10) + `},"commits":[` +
+		`{"added":["docs/new.md"],"modified":["docs/a.md"],"removed":["docs/old.md"]},` +
+		`{"added":[],"modified":["docs/b.md"],"removed":["docs/a.md"]}]}`
+	tests := []struct {
+		name       string
+		event      string
+		body       string
+		wantStatus int
+		want       *Push
+	}{
+		{
+			name: "queues push", event: "push", body: push, wantStatus: http.StatusAccepted,
+			want: &Push{Repo: "acme/docs", Branch: "main", Changed: []string{"docs/b.md", "docs/new.md"}, Removed: []string{"docs/a.md", "docs/old.md"}, Pushed: time.Unix(pushed, 0)},
+		},
+		{name: "rejects old push", event: "push", body: `{"ref":"refs/heads/main","repository":{"pushed_at":1700000000},"commits":[{"added":["a.md"]}]}`, wantStatus: http.StatusUnauthorized},
+		{name: "acknowledges ping", event: "ping", body: `{"zen":"hi"}`, wantStatus: http.StatusOK},
+		{name: "ignores tag push", event: "push", body: `{"ref":"refs/tags/v1","commits":[{"added":["a.md"]}]}`, wantStatus: http.StatusOK},
+		{name: "ignores branch deletion", event: "push", body: `{"ref":"refs/heads/old","deleted":true,"commits":[]}`, wantStatus: http.StatusOK},
+		{name: "rejects malformed push", event: "push", body: `{"ref":`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Push
+			handler := Receiver(secret("s3cret"), nil, func(p Push) error {
+				got = &p
+				return nil
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/github", strings.NewReader(tt.body))
+			req.Header.Set("X-GitHub-Event", tt.event)
+			req.Header.Set("X-Hub-Signature-256", Sign("s3cret", []byte(tt.body)))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("push = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"source":"go-docs"}`)
+	if !Verify("key", body, Sign("key", body)) {
+		t.Error("Verify() rejected a valid signature")
+	}
+	if Verify("", body, Sign("", body)) {
+		t.Error("Verify() accepted a signature without a secret")
+	}
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Event names sent in the payload and the X-BamRag-Event header.
+const (
+	EventScrapeComplete    = "scrape.complete"
+	EventIngestionComplete = "ingestion.complete"
+)
+
+// Events lists every event a hook can subscribe to.
+var Events = []string{EventScrapeComplete, EventIngestionComplete}
+
+// Delivery settings.
+const (
+	timeout     = 10 * time.Second
+	maxAttempts = 3
+)
+
+// retryBackoff is the delay before the first redelivery; later ones wait
+// proportionally longer.
+var retryBackoff = time.Second
+
+// Hook is an HTTP endpoint notified of events.
+type Hook struct {
+	URL     string
+	Secret  string            // Signs payloads with HMAC-SHA256 when set
+	Events  []string          // Events to send; empty sends all
+	Headers map[string]string // Extra request headers
+}
+
+// Payload is the JSON body POSTed to hooks.
+type Payload struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// Notifier delivers events to the configured hooks.
+type Notifier struct {
+	hooks      []Hook
+	httpClient *http.Client
+}
+
+// New creates a notifier for hooks.
+func New(hooks []Hook) *Notifier {
+	return &Notifier{
+		hooks:      hooks,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify POSTs event with data to every hook subscribed to it. Failed
+// deliveries are retried a few times; the errors of hooks that never
+// accepted the event are returned together.
+func (n *Notifier) Notify(ctx context.Context, event string, data any) error {
+	if n == nil || len(n.hooks) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(Payload{Event: event, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	var errs []error
+	for _, hook := range n.hooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, event) {
+			continue
+		}
+		if err := n.deliver(ctx, hook, event, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", hook.URL, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends body to hook, retrying network errors and 5xx responses.
+func (n *Notifier) deliver(ctx context.Context, hook Hook, event string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		retry, err = n.post(ctx, hook, event, body)
+		if err == nil || !retry || attempt == maxAttempts {
+			break
+		}
+		slog.Debug("webhook delivery failed, retrying", "url", hook.URL, "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// post sends one delivery attempt. It reports whether a failure is worth
+// retrying.
+func (n *Notifier) post(ctx context.Context, hook Hook, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bam-rag-webhook")
+	req.Header.Set("X-BamRag-Event", event)
+	if hook.Secret != "" {
+		req.Header.Set("X-BamRag-Signature", Sign(hook.Secret, body))
+	}
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
+			fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// Sign returns the X-BamRag-Signature value for body: "sha256=" followed by
+// the hex HMAC-SHA256 of body keyed with secret. Receivers recompute it over
+// the raw request body to verify the sender.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver records webhook deliveries, failing the first failures of them.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	status   int // Status returned for failures
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(r.status)
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = time.Second })
+
+	tests := []struct {
+		name         string
+		events       []string
+		failures     int
+		status       int
+		wantRequests int
+		wantErr      bool
+	}{
+		{name: "delivers", wantRequests: 1},
+		{name: "skips unsubscribed event", events: []string{EventIngestionComplete}, wantRequests: 0},
+		{name: "retries server errors", failures: 2, status: http.StatusBadGateway, wantRequests: 3},
+		{name: "gives up after max attempts", failures: 5, status: http.StatusServiceUnavailable, wantRequests: 3, wantErr: true},
+		{name: "does not retry client errors", failures: 1, status: http.StatusUnauthorized, wantRequests: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recv := &receiver{failures: tt.failures, status: tt.status}
+			server := httptest.NewServer(recv)
+			defer server.Close()
+
+			n := New([]Hook{{
+				URL:     server.URL,
+				Secret:  "s3cret",
+				Events:  tt.events,
+				Headers: map[string]string{"X-Team": "docs"},
+			}})
+			err := n.Notify(t.Context(), EventScrapeComplete, map[string]string{"prefix": "scrapes/a/1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			recv.mu.Lock()
+			defer recv.mu.Unlock()
+			if len(recv.requests) != tt.wantRequests {
+				t.Fatalf("got %d requests, want %d", len(recv.requests), tt.wantRequests)
+			}
+			if tt.wantRequests == 0 {
+				return
+			}
+
+			req, body := recv.requests[0], recv.bodies[0]
+			if got := req.Header.Get("X-BamRag-Signature"); got != Sign("s3cret", body) {
+				t.Errorf("signature = %q, want %q", got, Sign("s3cret", body))
+			}
+			if got := req.Header.Get("X-BamRag-Event"); got != EventScrapeComplete {
+				t.Errorf("X-BamRag-Event = %q", got)
+			}
+			if got := req.Header.Get("X-Team"); got != "docs" {
+				t.Errorf("X-Team = %q, want custom header", got)
+			}
+			if !strings.Contains(string(body), `"event":"scrape.complete"`) || !strings.Contains(string(body), `"prefix":"scrapes/a/1"`) {
+				t.Errorf("body = %s", body)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"event":"x"}' | openssl dgst -sha256 -hmac key
+	want := "sha256=774be471c03553e6ffcb7759081933cd2e124c9c7992b81f64c20005644925fa"
+	if got := Sign("key", []byte(`{"event":"x"}`)); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
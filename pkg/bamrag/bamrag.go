@@ -0,0 +1,56 @@
+// Package bamrag embeds bam-rag's RAG pipeline in other Go programs: pages
+// are scraped, kept in storage, converted to markdown, split into chunks,
+// optionally enriched and embedded, then indexed and searched, without
+// invoking the CLI.
+//
+// Each step is an interface, so a program can swap in its own scraper,
+// storage, index, or models. New* functions create the implementations the
+// CLI uses: the web scraper, S3 storage, Elasticsearch, embedded, and
+// Qdrant indexes, and Docker Model Runner models.
+package bamrag
+
+import (
+	"context"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Scraper fetches the pages of a site. Documents carry the fetched HTML
+// or markdown as Content; the pipeline converts them.
+type Scraper interface {
+	Scrape(ctx context.Context, url string) ([]models.Document, error)
+}
+
+// Storage keeps scraped pages, so they can be indexed again without
+// fetching them.
+type Storage interface {
+	// SavePages stores the pages scraped from sourceURL and returns the
+	// prefix they are stored under.
+	SavePages(ctx context.Context, sourceURL string, docs []models.Document) (prefix string, err error)
+	// LoadPages returns the pages stored under prefix.
+	LoadPages(ctx context.Context, prefix string) ([]models.Document, error)
+}
+
+// Indexer stores documents and their chunks, and searches the chunks.
+type Indexer interface {
+	// CreateIndex prepares the index; existing entries are kept.
+	CreateIndex(ctx context.Context) error
+	IndexDocument(ctx context.Context, doc models.Document) error
+	// IndexChunks replaces the chunks of a document.
+	IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error
+	// Refresh makes everything indexed so far searchable.
+	Refresh(ctx context.Context) error
+	// Search returns the chunks best matching query, also ranked by the
+	// similarity of their embeddings to queryEmbedding if it is set.
+	Search(ctx context.Context, query string, queryEmbedding []float32, limit int) ([]models.Chunk, error)
+}
+
+// Embedder turns text into a vector embedding.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// Enricher generates search keywords and a summary of a document.
+type Enricher interface {
+	Enrich(ctx context.Context, title, content string) (tags []string, summary string, err error)
+}
@@ -0,0 +1,248 @@
+package bamrag
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/backend"
+	"github.com/mfenderov/bam-rag/internal/elasticsearch"
+	"github.com/mfenderov/bam-rag/internal/embedded"
+	"github.com/mfenderov/bam-rag/internal/embeddings"
+	"github.com/mfenderov/bam-rag/internal/llm"
+	"github.com/mfenderov/bam-rag/internal/qdrant"
+	"github.com/mfenderov/bam-rag/internal/scraper"
+	"github.com/mfenderov/bam-rag/internal/storage"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// ScraperConfig holds the settings of the web scraper. Zero values use the
+// scraper's defaults, except for the booleans, which are off.
+type ScraperConfig struct {
+	Delay            time.Duration // Between requests to the same site
+	MaxDepth         int           // Links followed from the start page
+	FollowLinks      bool          // Follow links within the start page's host
+	UserAgent        string
+	Timeout          time.Duration // Per request
+	TryMarkdownFirst bool          // Fetch markdown versions of pages, such as page.md, where sites offer them
+	ContentSelector  string        // CSS selector for the main content of HTML pages; empty keeps the whole page
+	MaxParallel      int           // Concurrent requests per origin; defaults to 2
+	RespectNoindex   bool          // Skip pages marked noindex by a robots meta tag or X-Robots-Tag header
+	RespectRobots    bool          // Skip paths robots.txt disallows and wait at least its Crawl-delay between requests
+	MaxAttempts      int           // Tries per request failing with 429, 5xx, or a network error; 0 or 1 disables retries
+	RetryBackoff     time.Duration // Wait before the first retry, or as long as Retry-After asks; doubles after each failure
+	Proxies          []*url.URL    // HTTP(S) or SOCKS5 proxies requests go through in turn; none uses HTTP_PROXY/HTTPS_PROXY
+}
+
+// NewScraper creates the web scraper the CLI uses.
+func NewScraper(config ScraperConfig) Scraper {
+	return scraper.New(scraper.Config{
+		Delay:            config.Delay,
+		MaxDepth:         config.MaxDepth,
+		FollowLinks:      config.FollowLinks,
+		UserAgent:        config.UserAgent,
+		Timeout:          config.Timeout,
+		TryMarkdownFirst: config.TryMarkdownFirst,
+		ContentSelector:  config.ContentSelector,
+		MaxParallel:      config.MaxParallel,
+		RespectNoindex:   config.RespectNoindex,
+		RespectRobots:    config.RespectRobots,
+		Retry:            scraper.Retry{MaxAttempts: config.MaxAttempts, Backoff: config.RetryBackoff},
+		Proxies:          config.Proxies,
+	})
+}
+
+// S3Config holds the connection settings of S3 or MinIO storage.
+type S3Config struct {
+	Endpoint        string // "localhost:9000" for MinIO
+	Bucket          string // Created on the first SavePages if missing
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+	Namespace       string // Keeps every object under namespaces/<namespace>/; empty for the shared corpus
+}
+
+// NewS3Storage creates storage keeping pages in S3 the way 'bam-rag scrape'
+// does, so the CLI can ingest its prefixes and the pipeline can reingest
+// the CLI's.
+func NewS3Storage(config S3Config) (Storage, error) {
+	client, err := storage.New(storage.Config{
+		Endpoint:        config.Endpoint,
+		Bucket:          config.Bucket,
+		AccessKeyID:     config.AccessKeyID,
+		SecretAccessKey: config.SecretAccessKey,
+		UseSSL:          config.UseSSL,
+		Namespace:       config.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3Storage{client}, nil
+}
+
+// s3Storage adapts a storage client to Storage.
+type s3Storage struct {
+	client *storage.Client
+}
+
+func (s s3Storage) SavePages(ctx context.Context, sourceURL string, docs []models.Document) (string, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid source URL: %w", err)
+	}
+	if err := s.client.EnsureBucket(ctx); err != nil {
+		return "", err
+	}
+
+	prefix := scraper.NewPrefix(scraper.PrefixHost(u), sourceURL)
+	meta := storage.ScrapeMetadata{
+		SourceURL: sourceURL,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Hashes:    make(map[string]string, len(docs)),
+		Licenses:  make(map[string]string),
+	}
+	for _, doc := range docs {
+		if err := s.client.PutMarkdown(ctx, prefix, models.GenerateDocumentID(doc.URL)+".md", doc.Content); err != nil {
+			return "", err
+		}
+		meta.Pages = append(meta.Pages, doc.URL)
+		meta.Hashes[doc.URL] = models.ContentHash(doc.Content)
+		if doc.License != "" {
+			meta.Licenses[doc.URL] = doc.License
+		}
+		if meta.SiteName == "" {
+			meta.SiteName, meta.Favicon = doc.SiteName, doc.Favicon
+		}
+	}
+	meta.PageCount = len(meta.Pages)
+	if err := s.client.PutMetadata(ctx, prefix, meta); err != nil {
+		return "", err
+	}
+	return prefix, nil
+}
+
+func (s s3Storage) LoadPages(ctx context.Context, prefix string) ([]models.Document, error) {
+	meta, err := s.client.GetMetadata(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]models.Document, 0, len(meta.Pages))
+	for _, pageURL := range meta.Pages {
+		content, err := s.client.GetMarkdown(ctx, prefix, models.GenerateDocumentID(pageURL)+".md")
+		if err != nil {
+			return nil, err
+		}
+		license := meta.Licenses[pageURL]
+		if license == "" {
+			license = meta.License
+		}
+		docs = append(docs, models.Document{
+			URL:      pageURL,
+			Content:  content,
+			SiteName: meta.SiteName,
+			Favicon:  meta.Favicon,
+			License:  license,
+			Source:   models.Source{Name: meta.Source, Host: storage.HostFromPrefix(prefix)},
+		})
+	}
+	return docs, nil
+}
+
+// ElasticsearchConfig holds the connection settings of an Elasticsearch
+// index.
+type ElasticsearchConfig struct {
+	Addresses []string
+	CloudID   string // Elastic Cloud deployment; replaces Addresses when set
+	Index     string // Chunks are kept in <index>_chunks
+	Username  string
+	Password  string
+	APIKey    string // Base64-encoded API key; replaces Username and Password when set
+}
+
+// NewElasticsearchIndexer creates an indexer storing pages in
+// Elasticsearch, with the CLI's default mapping.
+func NewElasticsearchIndexer(config ElasticsearchConfig) (Indexer, error) {
+	client, err := elasticsearch.New(elasticsearch.Config{
+		Addresses: config.Addresses,
+		CloudID:   config.CloudID,
+		Index:     config.Index,
+		Username:  config.Username,
+		Password:  config.Password,
+		APIKey:    config.APIKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return backendIndexer{client}, nil
+}
+
+// NewEmbeddedIndexer creates an indexer keeping pages in a file at path,
+// with no server to run.
+func NewEmbeddedIndexer(path string) (Indexer, error) {
+	store, err := embedded.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return backendIndexer{store}, nil
+}
+
+// QdrantConfig holds the connection settings of a Qdrant collection.
+type QdrantConfig struct {
+	URL        string // REST API base URL, e.g. http://localhost:6333
+	APIKey     string // Optional
+	Collection string // Chunks are kept in <collection>_chunks
+	VectorSize int    // Dimensions of embeddings; defaults to 2560
+}
+
+// NewQdrantIndexer creates an indexer storing pages in Qdrant.
+func NewQdrantIndexer(config QdrantConfig) (Indexer, error) {
+	client, err := qdrant.New(qdrant.Config{
+		URL:        config.URL,
+		APIKey:     config.APIKey,
+		Collection: config.Collection,
+		VectorSize: config.VectorSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return backendIndexer{client}, nil
+}
+
+// backendIndexer adapts a search backend to Indexer.
+type backendIndexer struct {
+	backend.SearchBackend
+}
+
+func (b backendIndexer) Search(ctx context.Context, query string, queryEmbedding []float32, limit int) ([]models.Chunk, error) {
+	return b.SearchChunks(ctx, query, queryEmbedding, limit, elasticsearch.Filter{})
+}
+
+// NewEmbedder creates an embedder using a Docker Model Runner model, such
+// as "ai/embeddinggemma", reached through the socket at socketPath.
+func NewEmbedder(socketPath, model string) (Embedder, error) {
+	return embeddings.New(embeddings.Config{SocketPath: socketPath, Model: model})
+}
+
+// NewEnricher creates an enricher using a Docker Model Runner model, such
+// as "ai/gemma3", reached through the socket at socketPath.
+func NewEnricher(socketPath, model string) (Enricher, error) {
+	client, err := llm.New(llm.Config{SocketPath: socketPath, Model: model})
+	if err != nil {
+		return nil, err
+	}
+	return llmEnricher{client}, nil
+}
+
+// llmEnricher adapts an LLM client to Enricher.
+type llmEnricher struct {
+	client *llm.Client
+}
+
+func (e llmEnricher) Enrich(ctx context.Context, title, content string) ([]string, string, error) {
+	result, err := e.client.EnrichDocument(ctx, title, content)
+	if err != nil {
+		return nil, "", err
+	}
+	return result.Tags, result.Summary, nil
+}
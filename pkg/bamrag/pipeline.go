@@ -0,0 +1,168 @@
+package bamrag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mfenderov/bam-rag/internal/ingestion"
+	"github.com/mfenderov/bam-rag/internal/llm"
+	"github.com/mfenderov/bam-rag/internal/processor"
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+// Config holds the parts of a Pipeline. Only Indexer is required.
+type Config struct {
+	Scraper  Scraper  // Fetches the pages Run indexes
+	Storage  Storage  // Keeps scraped pages for Reingest; nil keeps none
+	Indexer  Indexer  // Stores and searches documents and chunks
+	Embedder Embedder // nil indexes without embeddings, so searches match text only
+	Enricher Enricher // nil indexes documents without tags and summaries
+
+	// AccessLabels are set on every indexed document; a search needs one
+	// of them to see it. Empty indexes public documents.
+	AccessLabels []string
+
+	// Redact masks email addresses, API keys, and the other strings the
+	// CLI's built-in redaction detectors find before pages are indexed.
+	Redact bool
+
+	// MaxChunkSize is the size in bytes above which a section of a page is
+	// split into several chunks; 0 uses the CLI's default.
+	MaxChunkSize int
+}
+
+// Result holds the outcome of indexing a set of pages.
+type Result struct {
+	Prefix   string              // Where Storage kept the scraped pages; empty without Storage
+	Pages    int                 // Pages scraped or loaded
+	Indexed  int                 // Documents indexed
+	Duration time.Duration       // Time taken, including the scrape for Run
+	Errors   []*models.PageError // Pages that failed, and pages indexed without their enrichment or some embeddings
+}
+
+// Pipeline scrapes, indexes, and searches pages. Pages go through the
+// stages 'bam-rag ingest' runs them through. It is safe for concurrent use
+// if its parts are.
+type Pipeline struct {
+	config Config
+	engine *ingestion.Engine
+}
+
+// New creates a pipeline from its parts.
+func New(config Config) (*Pipeline, error) {
+	if config.Indexer == nil {
+		return nil, errors.New("indexer is required")
+	}
+	var enricher ingestion.Enricher
+	if config.Enricher != nil {
+		enricher = enricherStage{config.Enricher}
+	}
+	engine := ingestion.NewPages(config.Indexer, config.Embedder, enricher)
+	engine.SetAccessLabels(config.AccessLabels)
+	engine.SetMaxChunkSize(config.MaxChunkSize)
+	if config.Redact {
+		var detectors []processor.Detector
+		for _, name := range processor.BuiltinDetectors() {
+			d, _ := processor.BuiltinDetector(name)
+			detectors = append(detectors, d)
+		}
+		engine.SetRedactor(processor.NewRedactor(detectors))
+	}
+	return &Pipeline{config: config, engine: engine}, nil
+}
+
+// Run scrapes url, keeps the pages in Storage if set, and indexes them.
+func (p *Pipeline) Run(ctx context.Context, url string) (*Result, error) {
+	if p.config.Scraper == nil {
+		return nil, errors.New("scraper is required to run the pipeline")
+	}
+	start := time.Now()
+
+	docs, err := p.config.Scraper.Scrape(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("scrape failed: %w", err)
+	}
+
+	var prefix string
+	if p.config.Storage != nil {
+		if prefix, err = p.config.Storage.SavePages(ctx, url, docs); err != nil {
+			return nil, fmt.Errorf("failed to store pages: %w", err)
+		}
+	}
+
+	result, err := p.Ingest(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	result.Prefix = prefix
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// Reingest indexes the pages Storage keeps under prefix again, such as
+// after changing models, without fetching them.
+func (p *Pipeline) Reingest(ctx context.Context, prefix string) (*Result, error) {
+	if p.config.Storage == nil {
+		return nil, errors.New("storage is required to reingest")
+	}
+	docs, err := p.config.Storage.LoadPages(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pages: %w", err)
+	}
+	result, err := p.Ingest(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	result.Prefix = prefix
+	return result, nil
+}
+
+// Ingest converts pages to markdown, splits them into chunks, enriches and
+// embeds them if the models are set, and indexes them. Pages that fail are
+// recorded in the result; the error is only set if the index could not be
+// prepared.
+func (p *Pipeline) Ingest(ctx context.Context, docs []models.Document) (*Result, error) {
+	ingested, err := p.engine.IngestPages(ctx, docs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+	return &Result{
+		Pages:    len(docs),
+		Indexed:  ingested.DocsIndexed,
+		Duration: ingested.Duration,
+		Errors:   append(ingested.Errors, ingested.Warnings...),
+	}, nil
+}
+
+// enricherStage adapts an Enricher to the ingestion stages. Chunks are
+// not situated, so SituateChunk is never called.
+type enricherStage struct {
+	Enricher
+}
+
+func (e enricherStage) EnrichDocument(ctx context.Context, title, content string) (*llm.EnrichmentResult, error) {
+	tags, summary, err := e.Enrich(ctx, title, content)
+	if err != nil {
+		return nil, err
+	}
+	return &llm.EnrichmentResult{Tags: tags, Summary: summary}, nil
+}
+
+func (e enricherStage) SituateChunk(ctx context.Context, title, document, chunk string) (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+// Search returns the chunks best matching query, ranked by the similarity
+// of their embeddings to the query's too if an Embedder is set.
+func (p *Pipeline) Search(ctx context.Context, query string, limit int) ([]models.Chunk, error) {
+	var embedding []float32
+	if p.config.Embedder != nil {
+		var err error
+		if embedding, err = p.config.Embedder.Embed(ctx, query); err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+	}
+	return p.config.Indexer.Search(ctx, query, embedding, limit)
+}
@@ -0,0 +1,182 @@
+package bamrag
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/bam-rag/pkg/models"
+)
+
+type fakeScraper struct {
+	docs []models.Document
+}
+
+func (s fakeScraper) Scrape(ctx context.Context, url string) ([]models.Document, error) {
+	return s.docs, nil
+}
+
+type fakeStorage struct {
+	pages map[string][]models.Document
+}
+
+func (s *fakeStorage) SavePages(ctx context.Context, sourceURL string, docs []models.Document) (string, error) {
+	prefix := "example.com/1"
+	s.pages[prefix] = docs
+	return prefix, nil
+}
+
+func (s *fakeStorage) LoadPages(ctx context.Context, prefix string) ([]models.Document, error) {
+	docs, ok := s.pages[prefix]
+	if !ok {
+		return nil, errors.New("no such prefix")
+	}
+	return docs, nil
+}
+
+type fakeIndexer struct {
+	docs   map[string]models.Document
+	chunks map[string][]models.Chunk
+}
+
+func newFakeIndexer() *fakeIndexer {
+	return &fakeIndexer{docs: map[string]models.Document{}, chunks: map[string][]models.Chunk{}}
+}
+
+func (i *fakeIndexer) CreateIndex(ctx context.Context) error { return nil }
+func (i *fakeIndexer) Refresh(ctx context.Context) error     { return nil }
+
+func (i *fakeIndexer) IndexDocument(ctx context.Context, doc models.Document) error {
+	i.docs[doc.ID] = doc
+	return nil
+}
+
+func (i *fakeIndexer) IndexChunks(ctx context.Context, documentID string, chunks []models.Chunk) error {
+	i.chunks[documentID] = chunks
+	return nil
+}
+
+func (i *fakeIndexer) Search(ctx context.Context, query string, queryEmbedding []float32, limit int) ([]models.Chunk, error) {
+	var results []models.Chunk
+	for _, chunks := range i.chunks {
+		for _, c := range chunks {
+			if strings.Contains(c.Content, query) && (queryEmbedding == nil || c.Embedding != nil) {
+				results = append(results, c)
+			}
+		}
+	}
+	return results, nil
+}
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{float32(len(text))}, nil
+}
+
+type failingEnricher struct{}
+
+func (failingEnricher) Enrich(ctx context.Context, title, content string) ([]string, string, error) {
+	return nil, "", errors.New("model unavailable")
+}
+
+var testPages = []models.Document{
+	{URL: "https://example.com/docs/install", ContentType: "text/markdown", Content: "# Install\n\nRun the installer to set up bam-rag."},
+	{URL: "https://example.com/docs/usage", ContentType: "text/markdown", Content: "# Usage\n\nSearch the index from the command line."},
+}
+
+func TestPipeline_Run(t *testing.T) {
+	indexer := newFakeIndexer()
+	storage := &fakeStorage{pages: map[string][]models.Document{}}
+	p, err := New(Config{
+		Scraper:      fakeScraper{docs: testPages},
+		Storage:      storage,
+		Indexer:      indexer,
+		Embedder:     fakeEmbedder{},
+		AccessLabels: []string{"team-docs"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.Run(context.Background(), "https://example.com/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Prefix != "example.com/1" || result.Pages != 2 || result.Indexed != 2 || len(result.Errors) != 0 {
+		t.Fatalf("Run() = %+v", result)
+	}
+
+	doc, ok := indexer.docs[models.GenerateDocumentID("https://example.com/docs/install")]
+	if !ok {
+		t.Fatal("install page not indexed")
+	}
+	if doc.Title != "Install" {
+		t.Errorf("Title = %q, want the first heading", doc.Title)
+	}
+	if doc.Embedding == nil {
+		t.Error("document not embedded")
+	}
+	if len(doc.AccessLabels) != 1 || doc.AccessLabels[0] != "team-docs" {
+		t.Errorf("AccessLabels = %v", doc.AccessLabels)
+	}
+	if len(indexer.chunks[doc.ID]) == 0 {
+		t.Error("install page has no chunks")
+	}
+
+	chunks, err := p.Search(context.Background(), "installer", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].URL != "https://example.com/docs/install" {
+		t.Errorf("Search() = %+v", chunks)
+	}
+}
+
+func TestPipeline_Reingest(t *testing.T) {
+	storage := &fakeStorage{pages: map[string][]models.Document{"example.com/1": testPages}}
+	indexer := newFakeIndexer()
+	p, err := New(Config{Storage: storage, Indexer: indexer, Enricher: failingEnricher{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.Reingest(context.Background(), "example.com/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enrichment failures are recorded but do not stop indexing.
+	if result.Indexed != 2 || len(result.Errors) != 2 || result.Errors[0].Stage != "enrich" {
+		t.Errorf("Reingest() = %+v", result)
+	}
+
+	if _, err := p.Reingest(context.Background(), "missing/1"); err == nil {
+		t.Error("Reingest() of a missing prefix = nil error")
+	}
+	if _, err := p.Run(context.Background(), "https://example.com"); err == nil {
+		t.Error("Run() without a scraper = nil error")
+	}
+}
+
+func TestPipeline_Redact(t *testing.T) {
+	indexer := newFakeIndexer()
+	p, err := New(Config{Indexer: indexer, Redact: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := models.Document{URL: "https://example.com/contact", ContentType: "text/markdown", Content: "# Contact\n\nWrite to ops@example.com."}
+	if _, err := p.Ingest(context.Background(), []models.Document{page}); err != nil {
+		t.Fatal(err)
+	}
+	doc := indexer.docs[models.GenerateDocumentID(page.URL)]
+	if strings.Contains(doc.Content, "ops@example.com") || !strings.Contains(doc.Content, "[REDACTED:") {
+		t.Errorf("Content = %q, want the email address masked", doc.Content)
+	}
+}
+
+func TestNew_RequiresIndexer(t *testing.T) {
+	if _, err := New(Config{Scraper: fakeScraper{}}); err == nil {
+		t.Error("New() without an indexer = nil error")
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Chunk is a heading-delimited section of a Document and the unit of
+// retrieval. Chunks are indexed separately from their parent document,
+// which stays retrievable by DocumentID.
+type Chunk struct {
+	ID           string    `json:"id"`
+	DocumentID   string    `json:"document_id"`             // ID of the parent Document
+	URL          string    `json:"url"`                     // URL of the parent Document
+	Title        string    `json:"title"`                   // Title of the parent Document
+	Source       Source    `json:"source,omitzero"`         // Source of the parent Document
+	Language     string    `json:"language,omitempty"`      // Language of the parent Document
+	ScrapedAt    time.Time `json:"scraped_at,omitzero"`     // Scrape time of the parent Document
+	AccessLabels []string  `json:"access_labels,omitempty"` // Access labels of the parent Document
+	License      string    `json:"license,omitempty"`       // License of the parent Document
+	HeadingPath  []string  `json:"heading_path,omitempty"`  // Headings enclosing the chunk, outermost first
+	Position     int       `json:"position"`                // 0-based order of the chunk within its document
+	Content      string    `json:"content"`
+	Context      string    `json:"context,omitempty"` // LLM-generated sentence situating the chunk within its document
+	WordCount    int       `json:"word_count,omitempty"`
+	TokenCount   int       `json:"token_count,omitempty"` // Estimated from content length
+	Embedding    []float32 `json:"embedding,omitempty"`   // Vector embedding of content
+}
+
+// GenerateChunkID creates a deterministic ID for the chunk at position
+// within a document, so re-ingesting a page overwrites its chunks.
+func GenerateChunkID(documentID string, position int) string {
+	return fmt.Sprintf("%s-%d", documentID, position)
+}
@@ -8,15 +8,63 @@ import (
 
 // Document represents a scraped web page.
 type Document struct {
-	ID          string    `json:"id"`
-	URL         string    `json:"url"`
-	Title       string    `json:"title"`
-	Content     string    `json:"content"`
-	ContentType string    `json:"content_type"` // HTTP Content-Type header
-	ScrapedAt   time.Time `json:"scraped_at"`
-	Tags        []string  `json:"tags,omitempty"`      // LLM-generated search keywords
-	Summary     string    `json:"summary,omitempty"`   // LLM-generated summary
-	Embedding   []float32 `json:"embedding,omitempty"` // Vector embedding of summary
+	ID           string       `json:"id"`
+	URL          string       `json:"url"`
+	Title        string       `json:"title"`
+	Content      string       `json:"content"`
+	ContentType  string       `json:"content_type"`       // HTTP Content-Type header
+	Language     string       `json:"language,omitempty"` // ISO 639-1 code, e.g. "en"; empty if unknown
+	ScrapedAt    time.Time    `json:"scraped_at"`
+	Outline      []Heading    `json:"outline,omitempty"` // H1-H3 heading tree
+	WordCount    int          `json:"word_count,omitempty"`
+	TokenCount   int          `json:"token_count,omitempty"`   // Estimated from content length
+	Attachments  []Attachment `json:"attachments,omitempty"`   // Images and files the page links to
+	SiteName     string       `json:"site_name,omitempty"`     // Name of the site (og:site_name)
+	Favicon      string       `json:"favicon,omitempty"`       // Absolute URL of the site icon
+	License      string       `json:"license,omitempty"`       // License the page declares, e.g. "CC BY-SA 4.0" or a license URL; empty if unknown
+	ContentHash  string       `json:"content_hash,omitempty"`  // Hash of the scraped page and the fields set from its source; empty if it must be reprocessed
+	Source       Source       `json:"source,omitzero"`         // Configured source the page was scraped from
+	AccessLabels []string     `json:"access_labels,omitempty"` // Labels a search needs to see the page; empty for public pages
+	Tags         []string     `json:"tags,omitempty"`          // LLM-generated search keywords
+	Summary      string       `json:"summary,omitempty"`       // LLM-generated summary
+	Embedding    []float32    `json:"embedding,omitempty"`     // Vector embedding of summary
+}
+
+// Source identifies where a document came from.
+type Source struct {
+	Name string `json:"name,omitempty"` // Config source name; empty for ad-hoc --url scrapes
+	Host string `json:"host,omitempty"` // Host segment of the scrape prefix, e.g. "go.dev" or "local/docs"
+}
+
+// Heading is an entry in a document outline.
+type Heading struct {
+	Text     string    `json:"text"`
+	Level    int       `json:"level"`              // 1-3
+	Children []Heading `json:"children,omitempty"` // Deeper headings up to the next heading of this level
+}
+
+// AttachmentKind classifies an attachment.
+type AttachmentKind string
+
+// Attachment kinds.
+const (
+	AttachmentImage AttachmentKind = "image"
+	AttachmentPDF   AttachmentKind = "pdf"
+	AttachmentFile  AttachmentKind = "file" // Other downloads, e.g. archives and office documents
+)
+
+// Attachment is an image or downloadable file referenced by a document.
+type Attachment struct {
+	URL     string         `json:"url"` // Absolute URL
+	Kind    AttachmentKind `json:"kind"`
+	Caption string         `json:"caption,omitempty"` // Alt text, link text, or title
+}
+
+// ContentHash returns the hex SHA-256 hash of scraped page content, used to
+// tell whether a page changed since it was indexed.
+func ContentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
 }
 
 // GenerateDocumentID creates a deterministic ID from URL.
@@ -122,3 +122,28 @@ func TestGenerateDocumentID_UniqueForDifferentURLs(t *testing.T) {
 		t.Errorf("Different URLs should generate different IDs: %q", id1)
 	}
 }
+
+func TestGenerateChunkID(t *testing.T) {
+	docID := GenerateDocumentID("https://example.com/docs")
+
+	if got := GenerateChunkID(docID, 3); got != docID+"-3" {
+		t.Errorf("GenerateChunkID() = %q, want %q", got, docID+"-3")
+	}
+	if GenerateChunkID(docID, 0) == GenerateChunkID(docID, 1) {
+		t.Error("chunks at different positions should have different IDs")
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	hash := ContentHash("# Page\n\nBody")
+
+	if len(hash) != 64 {
+		t.Errorf("hash length should be 64, got %d", len(hash))
+	}
+	if ContentHash("# Page\n\nBody") != hash {
+		t.Error("hash should be deterministic")
+	}
+	if ContentHash("# Page\n\nChanged body") == hash {
+		t.Error("different content should have a different hash")
+	}
+}
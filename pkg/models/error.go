@@ -0,0 +1,54 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// PageError is a failure to scrape or ingest one page. It is recorded in
+// results without stopping the run.
+type PageError struct {
+	URL       string `json:"url,omitempty"`   // Page that failed; empty if the run as a whole failed
+	Stage     string `json:"stage,omitempty"` // Stage that failed, e.g. "read", "convert", or "index"
+	Retryable bool   `json:"retryable"`       // Whether the failure was a timeout or network error that may pass on retry
+	Message   string `json:"message"`
+
+	err error // Underlying error, for errors.Is and errors.As
+}
+
+// NewPageError records that stage failed for url with err.
+func NewPageError(url, stage string, err error) *PageError {
+	return &PageError{
+		URL:       url,
+		Stage:     stage,
+		Retryable: transient(err),
+		Message:   err.Error(),
+		err:       err,
+	}
+}
+
+// Error formats the error as "<stage> <url>: <message>", leaving out
+// whichever of stage and url is empty.
+func (e *PageError) Error() string {
+	prefix := strings.TrimSpace(e.Stage + " " + e.URL)
+	if prefix == "" {
+		return e.Message
+	}
+	return prefix + ": " + e.Message
+}
+
+// Unwrap returns the underlying error.
+func (e *PageError) Unwrap() error {
+	return e.err
+}
+
+// transient reports whether err is a timeout or network failure.
+func transient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
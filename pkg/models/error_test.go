@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestNewPageError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name          string
+		url, stage    string
+		err           error
+		wantRetryable bool
+		wantError     string
+	}{
+		{"content", "https://go.dev/a", "convert", errors.New("bad html"), false, "convert https://go.dev/a: bad html"},
+		{"network", "https://go.dev/a", "index", fmt.Errorf("failed to index: %w", dialErr), true, "index https://go.dev/a: failed to index: dial tcp: connection refused"},
+		{"timeout", "", "read", context.DeadlineExceeded, true, "read: context deadline exceeded"},
+		{"run", "", "", errors.New("scrape failed"), false, "scrape failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPageError(tt.url, tt.stage, tt.err)
+			if got.Retryable != tt.wantRetryable {
+				t.Errorf("Retryable = %v, want %v", got.Retryable, tt.wantRetryable)
+			}
+			if got.Error() != tt.wantError {
+				t.Errorf("Error() = %q, want %q", got.Error(), tt.wantError)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Error("PageError should unwrap to the underlying error")
+			}
+		})
+	}
+}